@@ -14,17 +14,33 @@ import (
 
 	mcpserver "github.com/mark3labs/mcp-go/server"
 
+	"github.com/deliveryhero/asya/asya-gateway/internal/audit"
 	"github.com/deliveryhero/asya/asya-gateway/internal/config"
+	"github.com/deliveryhero/asya/asya-gateway/internal/consumer"
 	"github.com/deliveryhero/asya/asya-gateway/internal/envelopestore"
+	"github.com/deliveryhero/asya/asya-gateway/internal/health"
+	"github.com/deliveryhero/asya/asya-gateway/internal/idgen"
+	"github.com/deliveryhero/asya/asya-gateway/internal/logging"
 	"github.com/deliveryhero/asya/asya-gateway/internal/mcp"
+	"github.com/deliveryhero/asya/asya-gateway/internal/metrics"
+	"github.com/deliveryhero/asya/asya-gateway/internal/middleware"
+	"github.com/deliveryhero/asya/asya-gateway/internal/payloadstore"
+	"github.com/deliveryhero/asya/asya-gateway/internal/profiling"
 	"github.com/deliveryhero/asya/asya-gateway/internal/queue"
+	"github.com/deliveryhero/asya/asya-gateway/internal/scaler"
 )
 
+// syncResponseMargin is the time a REST sync tool call keeps to write its response
+// before the server's WriteTimeout
+const syncResponseMargin = 5 * time.Second
+
 func main() {
 	// Set up structured logging with level control
 	logLevel := getEnv("ASYA_LOG_LEVEL", "INFO")
 	var level slog.Level
 	switch strings.ToUpper(logLevel) {
+	case "TRACE":
+		level = logging.LevelTrace
 	case "DEBUG":
 		level = slog.LevelDebug
 	case "INFO":
@@ -37,81 +53,197 @@ func main() {
 		level = slog.LevelInfo
 	}
 
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: level,
-	}))
+	// Log records of envelope requests carry the envelope's job_id
+	logger := slog.New(logging.NewHandler(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: logging.ReplaceLevelName,
+	})))
 	slog.SetDefault(logger)
 
+	// Payload contents are kept out of logs unless explicitly enabled
+	if err := logging.SetPayloadMode(getEnv("ASYA_LOG_REDACT_PAYLOADS", logging.RedactOmit)); err != nil {
+		slog.Error("Invalid ASYA_LOG_REDACT_PAYLOADS", "error", err)
+		os.Exit(1)
+	}
+
 	// Load configuration from environment
 	port := getEnv("ASYA_GATEWAY_PORT", "8080")
 	dbURL := getEnv("ASYA_DATABASE_URL", "")
 	configPath := getEnv("ASYA_CONFIG_PATH", "")
+	readOnly := getEnvBool("ASYA_READ_ONLY", false)
+	basePath := mcp.NormalizeBasePath(getEnv("ASYA_BASE_PATH", ""))
+	corsOrigins := getEnvList("ASYA_CORS_ORIGINS")
 
-	slog.Info("Starting Asya Gateway", "port", port, "logLevel", logLevel)
+	slog.Info("Starting Asya Gateway", "port", port, "logLevel", logLevel, "readOnly", readOnly, "basePath", basePath)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	// Initialize envelope store (PostgreSQL or in-memory)
 	var envelopeStore envelopestore.EnvelopeStore
+	var pgStore *envelopestore.PgStore
 	if dbURL != "" {
 		slog.Info("Using PostgreSQL envelope store")
-		pgStore, err := envelopestore.NewPgStore(ctx, dbURL)
-		if err != nil {
+
+		// Encrypt payloads and results at rest
+		var cipher *envelopestore.Cipher
+		if key := getEnv("ASYA_ENCRYPTION_KEY", ""); key != "" {
+			var err error
+			if cipher, err = envelopestore.ParseCipher(key); err != nil {
+				slog.Error("Invalid ASYA_ENCRYPTION_KEY", "error", err)
+				os.Exit(1)
+			}
+			slog.Info("Encrypting stored payloads and results", "keyVersion", cipher.KeyVersion())
+		}
+
+		// Offload status reads to a read replica
+		readURL := getEnv("ASYA_DATABASE_READ_URL", "")
+		maxLag := getEnvDuration("ASYA_DATABASE_READ_MAX_LAG", 5*time.Second)
+
+		connectPostgres := func(ctx context.Context) (*envelopestore.PgStore, error) {
+			store, err := envelopestore.NewPgStore(ctx, dbURL)
+			if err != nil {
+				return nil, err
+			}
+			if cipher != nil {
+				store.SetCipher(cipher)
+			}
+			if readURL != "" {
+				if err := store.ConnectReadReplica(ctx, readURL, maxLag); err != nil {
+					store.Close()
+					return nil, fmt.Errorf("failed to connect to PostgreSQL read replica: %w", err)
+				}
+				slog.Info("Reading envelope status from PostgreSQL read replica", "maxLag", maxLag)
+			}
+			return store, nil
+		}
+
+		var err error
+		pgStore, err = connectPostgres(ctx)
+		switch {
+		case err == nil:
+			defer pgStore.Close()
+			envelopeStore = pgStore
+		case getEnvBool("ASYA_DB_FALLBACK_MEMORY", false) && !readOnly:
+			// Degraded but up beats crash-looping while PostgreSQL comes back
+			interval := getEnvDuration("ASYA_DB_FALLBACK_RETRY_INTERVAL", 10*time.Second)
+			slog.Error("PostgreSQL unavailable, FALLING BACK TO IN-MEMORY ENVELOPE STORE: envelopes created before it reconnects are lost on restart and invisible to other gateways",
+				"error", err, "retryInterval", interval)
+			fallback := envelopestore.NewFallbackStore(func(ctx context.Context) (envelopestore.EnvelopeStore, error) {
+				return connectPostgres(ctx)
+			})
+			fallback.Reconnect(ctx, interval)
+			defer fallback.Close()
+			envelopeStore = fallback
+		default:
 			slog.Error("Failed to create PostgreSQL store", "error", err)
 			os.Exit(1)
 		}
-		defer pgStore.Close()
-		envelopeStore = pgStore
 	} else {
 		slog.Info("Using in-memory envelope store (not recommended for production)")
 		envelopeStore = envelopestore.NewStore()
+		if getEnv("ASYA_ENCRYPTION_KEY", "") != "" {
+			slog.Warn("ASYA_ENCRYPTION_KEY is ignored by the in-memory envelope store, which persists nothing")
+		}
+	}
+	baseStore := envelopeStore // Before wrapping, for subscriber and status transition metrics
+
+	// Offload large final results to object storage (read-only replicas still need it to serve them)
+	var resultStore payloadstore.ObjectStore
+	resultBackend := strings.ToLower(getEnv("ASYA_RESULT_STORE", payloadstore.BackendNone))
+	if err := payloadstore.ValidateBackendName(resultBackend); err != nil {
+		slog.Error("Invalid result store configuration", "error", err)
+		os.Exit(1)
+	}
+	if resultBackend == payloadstore.BackendS3 {
+		s3Store, err := payloadstore.NewS3Store(ctx, payloadstore.S3Config{
+			Bucket:   getEnv("ASYA_RESULT_STORE_BUCKET", ""),
+			Prefix:   getEnv("ASYA_RESULT_STORE_PREFIX", ""),
+			Region:   getEnv("ASYA_RESULT_STORE_REGION", "us-east-1"),
+			Endpoint: getEnv("ASYA_RESULT_STORE_ENDPOINT", ""),
+		})
+		if err != nil {
+			slog.Error("Failed to create result store", "error", err)
+			os.Exit(1)
+		}
+		threshold := getEnvInt("ASYA_RESULT_OFFLOAD_THRESHOLD_BYTES", payloadstore.DefaultThresholdBytes)
+		slog.Info("Offloading large results to object storage", "backend", resultBackend, "thresholdBytes", threshold)
+		resultStore = s3Store
+		offloadStore := payloadstore.NewStore(envelopeStore, resultStore, threshold)
+		offloadStore.SetPutTimeout(getEnvDuration("ASYA_RESULT_STORE_PUT_TIMEOUT", payloadstore.DefaultPutTimeout))
+		envelopeStore = offloadStore
 	}
 
-	// Initialize queue client (RabbitMQ or SQS)
-	var queueClient queue.Client
-	var err error
-
-	// Check which transport is configured (SQS takes precedence if both are set)
-	sqsEndpoint := getEnv("ASYA_SQS_ENDPOINT", "")
-	rabbitmqURL := getEnv("ASYA_RABBITMQ_URL", "")
-
-	if sqsEndpoint != "" || rabbitmqURL == "" {
-		// Use SQS transport
-		sqsRegion := getEnv("ASYA_SQS_REGION", "us-east-1")
-		slog.Info("Using SQS transport", "region", sqsRegion, "endpoint", sqsEndpoint)
+	// Remove envelopes that finished longer ago than the retention window (read-only replicas never write)
+	retention := envelopestore.RetentionPolicy{
+		After:   getEnvDuration("ASYA_ENVELOPE_RETENTION", 0),
+		Archive: getEnvBool("ASYA_ENVELOPE_RETENTION_ARCHIVE", false),
+	}
+	if resultStore != nil {
+		// Offloaded results go with their envelopes
+		retention.DeleteResult = resultStore.Delete
+	}
+	if retention.After > 0 && !readOnly {
+		if dbURL == "" && retention.Archive {
+			slog.Warn("ASYA_ENVELOPE_RETENTION_ARCHIVE is ignored by the in-memory envelope store, which persists nothing")
+		}
+		if sweeper, ok := baseStore.(envelopestore.RetentionSweeper); ok {
+			slog.Info("Removing finished envelopes after retention window", "retention", retention.After, "archive", retention.Archive)
+			sweeper.StartRetention(ctx, retention)
+		}
+	}
 
-		visibilityTimeout := getEnvInt("ASYA_SQS_VISIBILITY_TIMEOUT", 300)
-		waitTimeSeconds := getEnvInt("ASYA_SQS_WAIT_TIME_SECONDS", 20)
+	// Observers of status changes in the base store (concurrency limit, audit completions, metrics)
+	var transitionObservers envelopestore.TransitionObservers
 
-		queueClient, err = queue.NewSQSClient(ctx, queue.SQSConfig{
-			Region:            sqsRegion,
-			Endpoint:          sqsEndpoint,
-			VisibilityTimeout: int32(visibilityTimeout), // #nosec G115 - config values bounded by reasonable defaults
-			WaitTimeSeconds:   int32(waitTimeSeconds),   // #nosec G115 - config values bounded by reasonable defaults
-		})
+	// Limit of active envelopes per replica, counted from the envelopes already in the store
+	if maxConcurrent := getEnvInt("ASYA_MAX_CONCURRENT", 0); maxConcurrent > 0 && !readOnly {
+		limiter, err := envelopestore.NewConcurrencyLimiter(envelopeStore, maxConcurrent)
 		if err != nil {
-			slog.Error("Failed to create SQS client", "error", err)
+			slog.Error("Failed to set up concurrency limit", "error", err)
 			os.Exit(1)
 		}
-	} else {
-		// Use RabbitMQ transport
-		rabbitmqExchange := getEnv("ASYA_RABBITMQ_EXCHANGE", "asya")
-		rabbitmqPoolSize := getEnvInt("ASYA_RABBITMQ_POOL_SIZE", 20)
-		slog.Info("Using RabbitMQ transport", "url", rabbitmqURL, "exchange", rabbitmqExchange, "poolSize", rabbitmqPoolSize)
+		slog.Info("Limiting active envelopes", "max_concurrent", maxConcurrent)
+		transitionObservers = append(transitionObservers, limiter)
+		envelopeStore = limiter
+	}
 
-		queueClient, err = queue.NewRabbitMQClientPooled(rabbitmqURL, rabbitmqExchange, rabbitmqPoolSize)
-		if err != nil {
-			slog.Error("Failed to create RabbitMQ client", "error", err)
+	// Audit trail of envelope creation and final status
+	auditSink := strings.ToLower(getEnv("ASYA_AUDIT_SINK", audit.SinkNone))
+	if err := audit.ValidateSinkName(auditSink); err != nil {
+		slog.Error("Invalid audit configuration", "error", err)
+		os.Exit(1)
+	}
+	switch auditSink {
+	case audit.SinkStdout:
+		slog.Info("Writing audit log to stdout")
+		auditStore := audit.NewStore(envelopeStore, audit.NewWriterSink(os.Stdout))
+		transitionObservers = append(transitionObservers, auditStore)
+		envelopeStore = auditStore
+	case audit.SinkPostgres:
+		if dbURL == "" {
+			slog.Error("ASYA_AUDIT_SINK=postgres requires ASYA_DATABASE_URL")
 			os.Exit(1)
 		}
+		if pgStore == nil {
+			slog.Error("ASYA_AUDIT_SINK=postgres cannot write the audit log while PostgreSQL is unavailable, ASYA_DB_FALLBACK_MEMORY does not apply")
+			os.Exit(1)
+		}
+		slog.Info("Writing audit log to PostgreSQL audit_log table")
+		auditStore := audit.NewStore(envelopeStore, audit.NewPgSink(pgStore.Pool()))
+		transitionObservers = append(transitionObservers, auditStore)
+		envelopeStore = auditStore
 	}
-	defer func() { _ = queueClient.Close() }()
 
-	// End queue consumers removed - use standalone end actors instead
-	// Deploy happy-end and error-end actors to handle end queue processing
-	slog.Info("Gateway uses standalone end actors for final status reporting",
-		"info", "Deploy happy-end and error-end actors to handle end queues")
+	// Initialize queue client (RabbitMQ or SQS)
+	// Read-only replicas serve status and streams from the shared store and never publish
+	var queueClient queue.Client
+	if readOnly {
+		slog.Info("Read-only mode: skipping queue client creation")
+	} else {
+		queueClient = newQueueClient(ctx)
+		defer func() { _ = queueClient.Close() }()
+	}
 
 	// Load tool configuration if provided
 	var toolConfig *config.Config
@@ -128,48 +260,204 @@ func main() {
 		slog.Info("No ASYA_CONFIG_PATH provided, using default tools")
 	}
 
+	// Tools routing through their own exchange (one per pipeline or team) publish there,
+	// and terminal queues consumed by the gateway are bound to these exchanges too
+	if exchanges := toolConfig.Exchanges(); len(exchanges) > 0 && queueClient != nil {
+		declarer, ok := queueClient.(queue.ExchangeDeclarer)
+		if !ok {
+			slog.Warn("Tool exchanges are only supported by the RabbitMQ transport, ignoring them", "exchanges", exchanges)
+		} else if err := declarer.DeclareExchanges(ctx, exchanges); err != nil {
+			slog.Error("Failed to declare tool exchanges", "error", err)
+			os.Exit(1)
+		} else {
+			slog.Info("Declared tool exchanges", "exchanges", exchanges)
+		}
+	}
+
+	// Envelope metrics labeled by tool, only configured tools get their own label value
+	var gatewayMetrics *metrics.Metrics
+	if getEnvBool("ASYA_METRICS_ENABLED", true) {
+		toolNames := []string{"processImageWorkflow"} // Default tool without config
+		if toolConfig != nil {
+			toolNames = toolNames[:0]
+			for _, tool := range toolConfig.Tools {
+				toolNames = append(toolNames, tool.Name)
+			}
+		}
+		// ASYA_METRICS_NAMESPACE is the older name, shared with the sidecar
+		namespace := getEnv("ASYA_GATEWAY_METRICS_NAMESPACE", getEnv("ASYA_METRICS_NAMESPACE", metrics.DefaultNamespace))
+		subsystem := getEnv("ASYA_GATEWAY_METRICS_SUBSYSTEM", "")
+		slog.Info("Metrics enabled", "namespace", namespace, "subsystem", subsystem)
+		gatewayMetrics = metrics.NewMetrics(namespace, subsystem, toolNames)
+		envelopeStore = metrics.NewStore(envelopeStore, gatewayMetrics)
+		if counter, ok := baseStore.(envelopestore.SubscriberCounter); ok {
+			gatewayMetrics.RegisterSubscribers(counter.Subscribers)
+		}
+		transitionObservers = append(transitionObservers, gatewayMetrics)
+
+		if pooledClient, ok := queueClient.(*queue.RabbitMQClientPooled); ok {
+			pool := pooledClient.ChannelPool()
+			gatewayMetrics.RegisterChannelPool(pool.Size, pool.Capacity)
+			pool.SetObserver(gatewayMetrics)
+		}
+	}
+	if tracker, ok := baseStore.(envelopestore.TransitionTracker); ok && len(transitionObservers) > 0 {
+		tracker.SetTransitionObserver(transitionObservers)
+	}
+
+	// Report envelopes no actor picked up within the window (actor down or not scaled up)
+	if pickupWindow := getEnvDuration("ASYA_PICKUP_ALERT_AFTER", 0); pickupWindow > 0 && !readOnly {
+		watchdog := envelopestore.NewPickupWatchdog(baseStore, pickupWindow)
+		if gatewayMetrics != nil {
+			// Replicas sharing a store all count the same envelopes, so each reports under its own label
+			replica, _ := os.Hostname()
+			gatewayMetrics.SetReplica(replica)
+			watchdog.SetObserver(gatewayMetrics)
+		}
+		watchdog.Start(ctx)
+		slog.Info("Reporting envelopes not picked up in time", "window", pickupWindow)
+	}
+
+	// Finalization: either the gateway consumes the terminal queues (terminal.consume in the
+	// tool config) or standalone happy-end/error-end actors report via /envelopes/{id}/final
+	if toolConfig.ConsumesTerminalQueues() && queueClient != nil {
+		terminal := config.TerminalActorsFromEnv()
+		slog.Info("Gateway consumes terminal queues for final status", "queues", []string{terminal.HappyEnd, terminal.ErrorEnd})
+		resultConsumer := consumer.NewResultConsumer(queueClient, envelopeStore, terminal)
+		resultConsumer.SetWorkers(getEnvInt("ASYA_RESULT_CONSUMER_WORKERS", 1))
+		resultConsumer.SetUpdateRetries(getEnvInt("ASYA_RESULT_CONSUMER_UPDATE_RETRIES", 3), getEnvDuration("ASYA_RESULT_CONSUMER_UPDATE_BACKOFF", 200*time.Millisecond))
+		resultConsumer.SetRequeueDelay(getEnvDuration("ASYA_RESULT_CONSUMER_REQUEUE_DELAY", time.Second), getEnvDuration("ASYA_RESULT_CONSUMER_MAX_REQUEUE_DELAY", time.Minute))
+		if gatewayMetrics != nil {
+			resultConsumer.SetObserver(gatewayMetrics)
+		}
+		if err := resultConsumer.Start(ctx); err != nil {
+			slog.Error("Failed to start result consumer", "error", err)
+			os.Exit(1)
+		}
+	} else {
+		slog.Info("Gateway uses standalone end actors for final status reporting",
+			"info", "Deploy happy-end and error-end actors to handle end queues")
+	}
+
 	// Create MCP server with mark3labs/mcp-go (minimal boilerplate!)
 	mcpServer := mcp.NewServer(envelopeStore, queueClient, toolConfig)
+	mcpServer.SetMaxRouteSteps(getEnvInt("ASYA_MAX_ROUTE_STEPS", mcp.DefaultMaxRouteSteps))
+	mcpServer.SetMaxPendingPublishes(getEnvInt("ASYA_MAX_PENDING_PUBLISHES", mcp.DefaultMaxPendingPublishes))
+	mcpServer.SetCheckQueues(getEnvBool("ASYA_CHECK_FIRST_QUEUE", true))
+	mcpServer.SetBasePath(basePath)
+
+	// Synchronous tools receive the outcome on a reply queue exclusive to this gateway instance
+	if toolConfig.HasSyncTools() && queueClient != nil {
+		if provider, ok := queueClient.(queue.ReplyQueueProvider); ok {
+			replyQueue, err := provider.ReplyQueue(ctx)
+			if err != nil {
+				slog.Error("Failed to create reply queue", "error", err)
+				os.Exit(1)
+			}
+			mcpServer.SetReplyQueue(replyQueue)
+			slog.Info("Synchronous tools wait for replies", "queue", replyQueue.Name())
+		} else {
+			slog.Warn("Queue transport does not support reply queues, synchronous tools respond asynchronously")
+		}
+	}
+
+	idFormat := getEnv("ASYA_ENVELOPE_ID_FORMAT", idgen.FormatUUID)
+	newID, err := idgen.New(idFormat, getEnv("ASYA_ENVELOPE_ID_PREFIX", idgen.DefaultPrefix))
+	if err != nil {
+		slog.Error("Invalid envelope ID configuration", "error", err)
+		os.Exit(1)
+	}
+	mcpServer.SetIDGenerator(newID)
+	slog.Info("Envelope ID format", "format", idFormat)
 
 	// Create envelope handler for custom endpoints
 	envelopeHandler := mcp.NewHandler(envelopeStore)
 	envelopeHandler.SetServer(mcpServer) // For REST tool calls
+	envelopeHandler.SetReadOnly(readOnly)
+	envelopeHandler.SetCompression(getEnvBool("ASYA_GZIP_ENABLED", true))
+	envelopeHandler.SetMaxBodyBytes(int64(getEnvInt("ASYA_MAX_REQUEST_BODY_BYTES", mcp.DefaultMaxBodyBytes)))
+	envelopeHandler.SetResultStore(resultStore)
+	envelopeHandler.SetMaxPartialResultBytes(int64(getEnvInt("ASYA_MAX_PARTIAL_RESULT_BYTES", mcp.DefaultMaxPartialResultBytes)))
+	envelopeHandler.SetMaxStreams(getEnvInt("ASYA_MAX_SSE_STREAMS", mcp.DefaultMaxStreams))
+	// REST sync tool calls respond before the server's WriteTimeout cuts them off
+	writeTimeout := time.Duration(getEnvInt("ASYA_HTTP_WRITE_TIMEOUT", 60)) * time.Second
+	envelopeHandler.SetMaxSyncWait(max(writeTimeout-syncResponseMargin, writeTimeout/2))
 
 	// Setup routes
 	mux := http.NewServeMux()
 
-	// MCP streamable HTTP endpoint (recommended, per MCP spec)
-	mux.Handle("/mcp", mcpserver.NewStreamableHTTPServer(mcpServer.GetMCPServer()))
-
-	// MCP SSE endpoint (deprecated but kept for backward compatibility with older clients)
-	mux.Handle("/mcp/sse", mcpserver.NewSSEServer(mcpServer.GetMCPServer()))
-
-	// REST endpoint for tool calls (simpler alternative to SSE-based MCP)
-	mux.HandleFunc("/tools/call", envelopeHandler.HandleToolCall)
-
-	// Envelope status endpoints (custom functionality)
-	mux.HandleFunc("/envelopes/", func(w http.ResponseWriter, r *http.Request) {
-		if strings.HasSuffix(r.URL.Path, "/stream") {
-			envelopeHandler.HandleEnvelopeStream(w, r)
-		} else if strings.HasSuffix(r.URL.Path, "/active") {
-			envelopeHandler.HandleEnvelopeActive(w, r)
-		} else if strings.HasSuffix(r.URL.Path, "/progress") {
-			envelopeHandler.HandleEnvelopeProgress(w, r)
-		} else if strings.HasSuffix(r.URL.Path, "/final") {
-			envelopeHandler.HandleEnvelopeFinal(w, r)
-		} else {
-			envelopeHandler.HandleEnvelopeStatus(w, r)
-		}
-	})
+	// MCP endpoints publish envelopes, so they are not served by read-only replicas
+	if !readOnly {
+		// MCP streamable HTTP endpoint (recommended, per MCP spec)
+		mux.Handle("/mcp", middleware.Streaming(mcpserver.NewStreamableHTTPServer(mcpServer.GetMCPServer())))
 
-	// Envelope creation endpoint (for fanout child envelopes from sidecar)
-	mux.HandleFunc("/envelopes", envelopeHandler.HandleEnvelopeCreate)
+		// MCP SSE endpoint (deprecated but kept for backward compatibility with older clients)
+		mux.Handle("/mcp/sse", middleware.Streaming(mcpserver.NewSSEServer(mcpServer.GetMCPServer())))
+	}
+
+	// REST tool calls and envelope endpoints (/envelopes/{id}/...)
+	envelopeHandler.RegisterRoutes(mux)
+
+	// Prometheus metrics
+	if gatewayMetrics != nil {
+		mux.Handle("/metrics", gatewayMetrics.Handler())
+	}
+
+	// Queue and actor health of the pipeline (AsyncActor status when running in Kubernetes with RBAC)
+	var inspector queue.Inspector
+	if i, ok := queueClient.(queue.Inspector); ok {
+		inspector = i
+	}
+	var actorStatuses health.ActorStatusLister
+	if kubeClient, err := health.NewInClusterKubeClient(getEnv("ASYA_NAMESPACE", "")); err != nil {
+		slog.Info("AsyncActor status not available for queue health", "reason", err)
+	} else {
+		actorStatuses = kubeClient
+	}
+	terminal := config.TerminalActorsFromEnv()
+	healthActors := append(toolConfig.Actors(), terminal.HappyEnd, terminal.ErrorEnd)
+	mux.Handle("/admin/health/queues", health.NewQueuesHandler(healthActors, inspector, actorStatuses))
 
 	// Health check
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+	healthCheck := func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = fmt.Fprintln(w, "OK")
-	})
+	}
+	mux.HandleFunc("/health", healthCheck)
+
+	// Mount all routes under ASYA_BASE_PATH for path-routing ingresses that do not strip the prefix.
+	// /health stays available at the root for kubelet probes.
+	var rootHandler http.Handler = mux
+	if basePath != "" {
+		root := http.NewServeMux()
+		root.Handle(basePath+"/", http.StripPrefix(basePath, mux))
+		root.HandleFunc("/health", healthCheck)
+		rootHandler = root
+	}
+
+	// CORS for browser clients (disabled unless ASYA_CORS_ORIGINS is set)
+	if len(corsOrigins) > 0 {
+		slog.Info("CORS enabled", "origins", corsOrigins)
+	}
+	rootHandler = middleware.CORS(corsOrigins, rootHandler)
+
+	// KEDA external scaler (gRPC) reporting actor queue activity, for ScaledObjects with an external trigger
+	if getEnvBool("ASYA_ENABLE_SCALER", false) {
+		if inspector == nil {
+			slog.Warn("KEDA external scaler needs a queue client that reports queue statistics, not starting it")
+		} else {
+			scalerServer := scaler.NewServer(inspector)
+			scalerServer.SetStreamInterval(getEnvDuration("ASYA_SCALER_STREAM_INTERVAL", scaler.DefaultStreamInterval))
+			go func() {
+				if err := scaler.Start(ctx, getEnv("ASYA_SCALER_ADDR", scaler.DefaultAddr), scalerServer); err != nil {
+					slog.Error("KEDA external scaler error", "error", err)
+				}
+			}()
+		}
+	}
+
+	// Profiling on a separate listener, never on the public mux
+	startProfiling(ctx)
 
 	// Setup graceful shutdown
 	sigChan := make(chan os.Signal, 1)
@@ -177,12 +465,16 @@ func main() {
 
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%s", port),
-		Handler: mux,
+		Handler: rootHandler,
+		// Bound slow clients; SSE and MCP streams clear the write deadline themselves
+		ReadHeaderTimeout: time.Duration(getEnvInt("ASYA_HTTP_READ_HEADER_TIMEOUT", 10)) * time.Second,
+		ReadTimeout:       time.Duration(getEnvInt("ASYA_HTTP_READ_TIMEOUT", 30)) * time.Second,
+		WriteTimeout:      writeTimeout,
 	}
 
 	// Start server in goroutine
 	go func() {
-		slog.Info("Server listening", "addr", server.Addr)
+		slog.Info("Server listening", "addr", server.Addr, "basePath", basePath)
 		slog.Info("MCP endpoint (streamable HTTP): POST /mcp (recommended)")
 		slog.Info("MCP endpoint (SSE): /mcp/sse (deprecated, for backward compatibility)")
 		slog.Info("REST tool endpoint: POST /tools/call (simple JSON API)")
@@ -190,6 +482,7 @@ func main() {
 		slog.Info("Envelope stream: GET /envelopes/{id}/stream (SSE)")
 		slog.Info("Envelope active check: GET /envelopes/{id}/active (for actors)")
 		slog.Info("Envelope progress: POST /envelopes/{id}/progress (from sidecar)")
+		slog.Info("Envelope progress batch: POST /envelopes/progress/batch (from sidecar)")
 		slog.Info("Envelope final status: POST /envelopes/{id}/final (for end actors)")
 
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -212,6 +505,85 @@ func main() {
 	slog.Info("Gateway shutdown complete")
 }
 
+// startProfiling serves the pprof endpoints on ASYA_PPROF_ADDR until ctx is done when
+// ASYA_ENABLE_PPROF is set. It returns the address, empty when profiling is disabled.
+func startProfiling(ctx context.Context) string {
+	if !getEnvBool("ASYA_ENABLE_PPROF", false) {
+		return ""
+	}
+
+	addr := getEnv("ASYA_PPROF_ADDR", profiling.DefaultAddr)
+	go func() {
+		if err := profiling.Start(ctx, addr); err != nil {
+			slog.Error("Profiling server error", "error", err)
+		}
+	}()
+	return addr
+}
+
+// newQueueClient creates the queue client for the configured transport (exits on failure)
+func newQueueClient(ctx context.Context) queue.Client {
+	var queueClient queue.Client
+	var err error
+
+	// Check which transport is configured (SQS takes precedence if both are set)
+	sqsEndpoint := getEnv("ASYA_SQS_ENDPOINT", "")
+	rabbitmqURL := getEnv("ASYA_RABBITMQ_URL", "")
+
+	if sqsEndpoint != "" || rabbitmqURL == "" {
+		// Use SQS transport
+		sqsRegion := getEnv("ASYA_SQS_REGION", "us-east-1")
+		slog.Info("Using SQS transport", "region", sqsRegion, "endpoint", sqsEndpoint)
+
+		visibilityTimeout := getEnvInt("ASYA_SQS_VISIBILITY_TIMEOUT", 300)
+		waitTimeSeconds := getEnvInt("ASYA_SQS_WAIT_TIME_SECONDS", 20)
+
+		queueClient, err = queue.NewSQSClient(ctx, queue.SQSConfig{
+			Region:            sqsRegion,
+			Endpoint:          sqsEndpoint,
+			VisibilityTimeout: int32(visibilityTimeout), // #nosec G115 - config values bounded by reasonable defaults
+			WaitTimeSeconds:   int32(waitTimeSeconds),   // #nosec G115 - config values bounded by reasonable defaults
+		})
+		if err != nil {
+			slog.Error("Failed to create SQS client", "error", err)
+			os.Exit(1)
+		}
+	} else {
+		// Use RabbitMQ transport
+		rabbitmqExchange := getEnv("ASYA_RABBITMQ_EXCHANGE", "asya")
+		rabbitmqPoolSize := getEnvInt("ASYA_RABBITMQ_POOL_SIZE", 20)
+		slog.Info("Using RabbitMQ transport", "url", rabbitmqURL, "exchange", rabbitmqExchange, "poolSize", rabbitmqPoolSize)
+
+		// Wait for a broker that is still starting instead of crash-looping
+		connectTimeout := getEnvDuration("ASYA_RABBITMQ_CONNECT_TIMEOUT", 2*time.Minute)
+		connectCtx, cancel := context.WithTimeout(ctx, connectTimeout)
+		pooledClient, err := queue.NewRabbitMQClientPooled(connectCtx, rabbitmqURL, rabbitmqExchange, rabbitmqPoolSize)
+		cancel()
+		if err != nil {
+			slog.Error("Failed to create RabbitMQ client", "error", err)
+			os.Exit(1)
+		}
+		pooledClient.ChannelPool().SetAcquireTimeout(getEnvDuration("ASYA_POOL_ACQUIRE_TIMEOUT", 0))
+		// Prefetch defaults to the result consumer workers so each worker has a delivery to process
+		pooledClient.SetConsumerPrefetch(getEnvInt("ASYA_RABBITMQ_CONSUMER_PREFETCH", getEnvInt("ASYA_RESULT_CONSUMER_WORKERS", 1)))
+		queueClient = pooledClient
+	}
+
+	messageFormat, err := queue.ParseMessageFormat(getEnv("ASYA_MESSAGE_FORMAT", ""))
+	if err != nil {
+		slog.Error("Invalid ASYA_MESSAGE_FORMAT", "error", err)
+		os.Exit(1)
+	}
+	if formatter, ok := queueClient.(queue.MessageFormatter); ok {
+		formatter.SetMessageFormat(messageFormat)
+	}
+	if messageFormat == queue.FormatCloudEvents {
+		slog.Info("Publishing envelopes as CloudEvents, actors need ASYA_INBOUND_ADAPTER=cloudevents")
+	}
+
+	return queueClient
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -219,6 +591,17 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvList returns the non-empty comma-separated values of key
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 func getEnvInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
 		if parsed, err := strconv.Atoi(value); err == nil {
@@ -228,3 +611,24 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvDuration parses a Go duration (e.g. "500ms", "2s")
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed >= 0 {
+			return parsed
+		}
+		slog.Warn("Invalid duration value, using default", "key", key, "value", value, "default", defaultValue)
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+		slog.Warn("Invalid boolean value, using default", "key", key, "value", value, "default", defaultValue)
+	}
+	return defaultValue
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// freeLoopbackAddr returns a loopback address nothing listens on
+func freeLoopbackAddr(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+	return addr
+}
+
+func TestStartProfiling_Enabled(t *testing.T) {
+	addr := freeLoopbackAddr(t)
+	t.Setenv("ASYA_ENABLE_PPROF", "true")
+	t.Setenv("ASYA_PPROF_ADDR", addr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if got := startProfiling(ctx); got != addr {
+		t.Fatalf("startProfiling() = %q, want %q", got, addr)
+	}
+
+	// The listener starts in the background
+	var resp *http.Response
+	var err error
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		resp, err = http.Get("http://" + addr + "/debug/pprof/")
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Profiling endpoint not reachable: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /debug/pprof/ = %d, want 200", resp.StatusCode)
+	}
+
+	// Cancelling ctx stops the listener
+	cancel()
+	deadline = time.Now().Add(7 * time.Second)
+	for {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			break
+		}
+		_ = conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("Profiling listener still accepts connections after shutdown")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStartProfiling_DisabledByDefault(t *testing.T) {
+	addr := freeLoopbackAddr(t)
+	t.Setenv("ASYA_ENABLE_PPROF", "")
+	t.Setenv("ASYA_PPROF_ADDR", addr)
+
+	if got := startProfiling(context.Background()); got != "" {
+		t.Fatalf("startProfiling() = %q, want disabled", got)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if conn, err := net.Dial("tcp", addr); err == nil {
+		_ = conn.Close()
+		t.Error("Profiling endpoint should not listen unless ASYA_ENABLE_PPROF is set")
+	}
+}
@@ -0,0 +1,10 @@
+-- Deploy asya-gateway:005_add_queued_status to pg
+-- Add 'queued' status for envelopes published to a queue but not yet picked up by an actor
+
+BEGIN;
+
+ALTER TABLE envelopes DROP CONSTRAINT IF EXISTS envelopes_status_check;
+ALTER TABLE envelopes ADD CONSTRAINT envelopes_status_check
+    CHECK (status IN ('pending', 'queued', 'running', 'succeeded', 'failed', 'unknown'));
+
+COMMIT;
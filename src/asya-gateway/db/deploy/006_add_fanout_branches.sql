@@ -0,0 +1,10 @@
+-- Deploy asya-gateway:006_add_fanout_branches to pg
+-- Track fanout branches on the parent envelope for result aggregation
+
+BEGIN;
+
+ALTER TABLE envelopes
+ADD COLUMN fanout_branches INTEGER NOT NULL DEFAULT 0 CHECK (fanout_branches >= 0),
+ADD COLUMN branches_completed INTEGER NOT NULL DEFAULT 0 CHECK (branches_completed >= 0);
+
+COMMIT;
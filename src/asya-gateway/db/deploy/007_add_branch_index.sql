@@ -0,0 +1,9 @@
+-- Deploy asya-gateway:007_add_branch_index to pg
+-- Record each fanout child's position within its parent's fanout
+
+BEGIN;
+
+ALTER TABLE envelopes
+ADD COLUMN branch_index INTEGER NOT NULL DEFAULT 0 CHECK (branch_index >= 0);
+
+COMMIT;
@@ -0,0 +1,38 @@
+-- Deploy asya-gateway:008_add_audit_log to pg
+-- Append-only audit trail of envelope creation and final status (ASYA_AUDIT_SINK=postgres)
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    event TEXT NOT NULL CHECK (event IN ('created', 'completed')),
+    envelope_id TEXT NOT NULL,
+    parent_id TEXT,
+    caller TEXT,
+    tool TEXT,
+    route JSONB,
+    input_hash TEXT,
+    status TEXT NOT NULL,
+    error TEXT
+);
+
+-- No foreign key to envelopes: audit entries outlive envelope cleanup
+CREATE INDEX IF NOT EXISTS idx_audit_log_envelope_id ON audit_log(envelope_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_occurred_at ON audit_log(occurred_at DESC);
+
+-- Reject updates and deletes to keep the log append-only
+CREATE OR REPLACE FUNCTION reject_audit_log_modification()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ language 'plpgsql';
+
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW
+    EXECUTE FUNCTION reject_audit_log_modification();
+
+COMMIT;
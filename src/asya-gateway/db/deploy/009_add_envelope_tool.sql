@@ -0,0 +1,9 @@
+-- Deploy asya-gateway:009_add_envelope_tool to pg
+-- Record the tool that created each envelope for per-tool metrics and auditing
+
+BEGIN;
+
+ALTER TABLE envelopes
+ADD COLUMN tool TEXT;
+
+COMMIT;
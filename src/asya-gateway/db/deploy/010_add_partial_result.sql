@@ -0,0 +1,12 @@
+-- Deploy asya-gateway:010_add_partial_result to pg
+-- Store intermediate output reported by long-running actors alongside progress updates
+
+BEGIN;
+
+ALTER TABLE envelopes
+ADD COLUMN partial_result JSONB;
+
+ALTER TABLE envelope_updates
+ADD COLUMN partial_result JSONB;
+
+COMMIT;
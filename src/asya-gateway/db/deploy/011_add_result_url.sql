@@ -0,0 +1,12 @@
+-- Deploy asya-gateway:011_add_result_url to pg
+-- Reference large final results offloaded to object storage instead of storing them inline
+
+BEGIN;
+
+ALTER TABLE envelopes
+ADD COLUMN result_url TEXT;
+
+ALTER TABLE envelope_updates
+ADD COLUMN result_url TEXT;
+
+COMMIT;
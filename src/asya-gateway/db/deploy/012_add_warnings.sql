@@ -0,0 +1,12 @@
+-- Deploy asya-gateway:012_add_warnings to pg
+-- Accumulate warnings reported by actors that succeeded, without failing the envelope
+
+BEGIN;
+
+ALTER TABLE envelopes
+ADD COLUMN warnings TEXT[];
+
+ALTER TABLE envelope_updates
+ADD COLUMN warnings TEXT[];
+
+COMMIT;
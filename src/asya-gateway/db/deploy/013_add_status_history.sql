@@ -0,0 +1,9 @@
+-- Deploy asya-gateway:013_add_status_history to pg
+-- Record when each envelope entered each status, to measure time spent per status
+
+BEGIN;
+
+ALTER TABLE envelopes
+ADD COLUMN status_history JSONB NOT NULL DEFAULT '[]';
+
+COMMIT;
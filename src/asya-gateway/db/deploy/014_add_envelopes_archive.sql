@@ -0,0 +1,17 @@
+-- Deploy asya-gateway:014_add_envelopes_archive to pg
+-- Keep envelopes removed by the retention sweeper (ASYA_ENVELOPE_RETENTION_ARCHIVE) instead of deleting them.
+-- Rows are stored as JSON so the archive survives later changes to the envelopes table.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS envelopes_archive (
+    id BIGSERIAL PRIMARY KEY,
+    envelope_id TEXT NOT NULL,
+    envelope JSONB NOT NULL,
+    archived_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_envelopes_archive_envelope_id ON envelopes_archive(envelope_id);
+CREATE INDEX IF NOT EXISTS idx_envelopes_archive_archived_at ON envelopes_archive(archived_at DESC);
+
+COMMIT;
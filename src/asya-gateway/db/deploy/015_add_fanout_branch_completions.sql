@@ -0,0 +1,13 @@
+-- Deploy asya-gateway:015_add_fanout_branch_completions to pg
+-- Record which fanout branches completed, so a redelivered branch final is counted once
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS fanout_branch_completions (
+    parent_id TEXT NOT NULL REFERENCES envelopes(id) ON DELETE CASCADE,
+    branch_index INTEGER NOT NULL CHECK (branch_index >= 0),
+    completed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (parent_id, branch_index)
+);
+
+COMMIT;
@@ -0,0 +1,13 @@
+-- Revert asya-gateway:005_add_queued_status from pg
+
+BEGIN;
+
+-- Queued envelopes fall back to pending (created but not yet running)
+UPDATE envelopes SET status = 'pending' WHERE status = 'queued';
+UPDATE envelope_updates SET status = 'pending' WHERE status = 'queued';
+
+ALTER TABLE envelopes DROP CONSTRAINT IF EXISTS envelopes_status_check;
+ALTER TABLE envelopes ADD CONSTRAINT envelopes_status_check
+    CHECK (status IN ('pending', 'running', 'succeeded', 'failed', 'unknown'));
+
+COMMIT;
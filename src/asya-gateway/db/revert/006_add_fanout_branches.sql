@@ -0,0 +1,8 @@
+-- Revert asya-gateway:006_add_fanout_branches from pg
+
+BEGIN;
+
+ALTER TABLE envelopes DROP COLUMN IF EXISTS branches_completed;
+ALTER TABLE envelopes DROP COLUMN IF EXISTS fanout_branches;
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Revert asya-gateway:007_add_branch_index from pg
+
+BEGIN;
+
+ALTER TABLE envelopes DROP COLUMN IF EXISTS branch_index;
+
+COMMIT;
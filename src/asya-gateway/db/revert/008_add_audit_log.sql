@@ -0,0 +1,8 @@
+-- Revert asya-gateway:008_add_audit_log from pg
+
+BEGIN;
+
+DROP TABLE IF EXISTS audit_log;
+DROP FUNCTION IF EXISTS reject_audit_log_modification();
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Revert asya-gateway:009_add_envelope_tool from pg
+
+BEGIN;
+
+ALTER TABLE envelopes DROP COLUMN IF EXISTS tool;
+
+COMMIT;
@@ -0,0 +1,8 @@
+-- Revert asya-gateway:010_add_partial_result from pg
+
+BEGIN;
+
+ALTER TABLE envelope_updates DROP COLUMN IF EXISTS partial_result;
+ALTER TABLE envelopes DROP COLUMN IF EXISTS partial_result;
+
+COMMIT;
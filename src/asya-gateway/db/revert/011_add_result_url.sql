@@ -0,0 +1,8 @@
+-- Revert asya-gateway:011_add_result_url from pg
+
+BEGIN;
+
+ALTER TABLE envelope_updates DROP COLUMN IF EXISTS result_url;
+ALTER TABLE envelopes DROP COLUMN IF EXISTS result_url;
+
+COMMIT;
@@ -0,0 +1,8 @@
+-- Revert asya-gateway:012_add_warnings from pg
+
+BEGIN;
+
+ALTER TABLE envelope_updates DROP COLUMN IF EXISTS warnings;
+ALTER TABLE envelopes DROP COLUMN IF EXISTS warnings;
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Revert asya-gateway:013_add_status_history from pg
+
+BEGIN;
+
+ALTER TABLE envelopes DROP COLUMN IF EXISTS status_history;
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Revert asya-gateway:014_add_envelopes_archive from pg
+
+BEGIN;
+
+DROP TABLE IF EXISTS envelopes_archive;
+
+COMMIT;
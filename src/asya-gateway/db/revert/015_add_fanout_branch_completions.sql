@@ -0,0 +1,7 @@
+-- Revert asya-gateway:015_add_fanout_branch_completions from pg
+
+BEGIN;
+
+DROP TABLE IF EXISTS fanout_branch_completions;
+
+COMMIT;
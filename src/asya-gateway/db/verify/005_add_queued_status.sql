@@ -0,0 +1,11 @@
+-- Verify asya-gateway:005_add_queued_status on pg
+
+BEGIN;
+
+-- Verify constraint allows queued status
+SELECT 1/COUNT(*)
+FROM pg_constraint
+WHERE conname = 'envelopes_status_check'
+AND pg_get_constraintdef(oid) LIKE '%queued%';
+
+ROLLBACK;
@@ -0,0 +1,10 @@
+-- Verify asya-gateway:006_add_fanout_branches on pg
+
+BEGIN;
+
+-- Verify fanout tracking columns exist
+SELECT fanout_branches, branches_completed
+FROM envelopes
+WHERE FALSE;
+
+ROLLBACK;
@@ -0,0 +1,10 @@
+-- Verify asya-gateway:007_add_branch_index on pg
+
+BEGIN;
+
+-- Verify branch index column exists
+SELECT branch_index
+FROM envelopes
+WHERE FALSE;
+
+ROLLBACK;
@@ -0,0 +1,13 @@
+-- Verify asya-gateway:008_add_audit_log on pg
+
+BEGIN;
+
+-- Verify audit table exists
+SELECT id, occurred_at, event, envelope_id, parent_id, caller, tool, route, input_hash, status, error
+FROM audit_log
+WHERE FALSE;
+
+-- Verify append-only trigger function exists
+SELECT 1/COUNT(*) FROM pg_proc WHERE proname = 'reject_audit_log_modification';
+
+ROLLBACK;
@@ -0,0 +1,10 @@
+-- Verify asya-gateway:009_add_envelope_tool on pg
+
+BEGIN;
+
+-- Verify tool column exists
+SELECT tool
+FROM envelopes
+WHERE FALSE;
+
+ROLLBACK;
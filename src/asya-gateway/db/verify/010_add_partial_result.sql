@@ -0,0 +1,14 @@
+-- Verify asya-gateway:010_add_partial_result on pg
+
+BEGIN;
+
+-- Verify partial_result columns exist
+SELECT partial_result
+FROM envelopes
+WHERE FALSE;
+
+SELECT partial_result
+FROM envelope_updates
+WHERE FALSE;
+
+ROLLBACK;
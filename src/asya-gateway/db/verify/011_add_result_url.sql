@@ -0,0 +1,14 @@
+-- Verify asya-gateway:011_add_result_url on pg
+
+BEGIN;
+
+-- Verify result_url columns exist
+SELECT result_url
+FROM envelopes
+WHERE FALSE;
+
+SELECT result_url
+FROM envelope_updates
+WHERE FALSE;
+
+ROLLBACK;
@@ -0,0 +1,14 @@
+-- Verify asya-gateway:012_add_warnings on pg
+
+BEGIN;
+
+-- Verify warnings columns exist
+SELECT warnings
+FROM envelopes
+WHERE FALSE;
+
+SELECT warnings
+FROM envelope_updates
+WHERE FALSE;
+
+ROLLBACK;
@@ -0,0 +1,10 @@
+-- Verify asya-gateway:013_add_status_history on pg
+
+BEGIN;
+
+-- Verify status_history column exists
+SELECT status_history
+FROM envelopes
+WHERE FALSE;
+
+ROLLBACK;
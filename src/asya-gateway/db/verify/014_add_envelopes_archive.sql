@@ -0,0 +1,10 @@
+-- Verify asya-gateway:014_add_envelopes_archive on pg
+
+BEGIN;
+
+-- Verify envelopes_archive table exists
+SELECT id, envelope_id, envelope, archived_at
+FROM envelopes_archive
+WHERE FALSE;
+
+ROLLBACK;
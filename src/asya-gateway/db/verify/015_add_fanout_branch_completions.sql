@@ -0,0 +1,10 @@
+-- Verify asya-gateway:015_add_fanout_branch_completions on pg
+
+BEGIN;
+
+-- Verify fanout_branch_completions table exists
+SELECT parent_id, branch_index, completed_at
+FROM fanout_branch_completions
+WHERE FALSE;
+
+ROLLBACK;
@@ -0,0 +1,138 @@
+// Package audit records an append-only trail of envelope lifecycle events
+// (creation and final status) for compliance.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+)
+
+// Audit sink names (ASYA_AUDIT_SINK)
+const (
+	SinkNone     = "none"
+	SinkStdout   = "stdout"
+	SinkPostgres = "postgres"
+)
+
+// Lifecycle events
+const (
+	EventCreated   = "created"
+	EventCompleted = "completed"
+)
+
+// Entry is a single audit record
+type Entry struct {
+	Time       time.Time `json:"time"`
+	Event      string    `json:"event"`
+	EnvelopeID string    `json:"envelope_id"`
+	ParentID   string    `json:"parent_id,omitempty"`
+	Caller     string    `json:"caller,omitempty"` // Authenticated caller identity, empty until the gateway has auth
+	Tool       string    `json:"tool,omitempty"`
+	Route      []string  `json:"route"`
+	InputHash  string    `json:"input_hash,omitempty"` // SHA-256 of the JSON-encoded input payload
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Sink persists audit entries
+type Sink interface {
+	Write(ctx context.Context, entry Entry) error
+}
+
+// NewEntry builds an audit entry for the current state of an envelope
+func NewEntry(event string, envelope *types.Envelope) Entry {
+	entry := Entry{
+		Time:       time.Now().UTC(),
+		Event:      event,
+		EnvelopeID: envelope.ID,
+		Tool:       envelope.Tool,
+		Route:      envelope.Route.Actors,
+		Status:     string(envelope.Status),
+		Error:      envelope.Error,
+	}
+	if envelope.ParentID != nil {
+		entry.ParentID = *envelope.ParentID
+	}
+	if event == EventCreated && envelope.Payload != nil {
+		entry.InputHash = hashPayload(envelope.Payload)
+	}
+	return entry
+}
+
+// hashPayload returns the hex SHA-256 of the JSON-encoded payload, so the trail
+// identifies inputs without storing them
+func hashPayload(payload any) string {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// WriterSink writes one JSON object per line (stdout sink)
+type WriterSink struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewWriterSink creates a sink writing JSON lines to w
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{encoder: json.NewEncoder(w)}
+}
+
+func (s *WriterSink) Write(ctx context.Context, entry Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.encoder.Encode(struct {
+		Type string `json:"type"`
+		Entry
+	}{Type: "audit", Entry: entry})
+}
+
+// PgSink inserts entries into the audit_log table (db/deploy/008_add_audit_log.sql)
+type PgSink struct {
+	pool *pgxpool.Pool
+}
+
+// NewPgSink creates a sink using the gateway's PostgreSQL pool
+func NewPgSink(pool *pgxpool.Pool) *PgSink {
+	return &PgSink{pool: pool}
+}
+
+func (s *PgSink) Write(ctx context.Context, entry Entry) error {
+	route, err := json.Marshal(entry.Route)
+	if err != nil {
+		return fmt.Errorf("failed to marshal route: %w", err)
+	}
+
+	_, err = s.pool.Exec(ctx, `
+		INSERT INTO audit_log (occurred_at, event, envelope_id, parent_id, caller, tool, route, input_hash, status, error)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), $7, NULLIF($8, ''), $9, NULLIF($10, ''))
+	`, entry.Time, entry.Event, entry.EnvelopeID, entry.ParentID, entry.Caller, entry.Tool, route, entry.InputHash, entry.Status, entry.Error)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit entry: %w", err)
+	}
+	return nil
+}
+
+// ValidateSinkName checks an ASYA_AUDIT_SINK value
+func ValidateSinkName(name string) error {
+	switch strings.ToLower(name) {
+	case "", SinkNone, SinkStdout, SinkPostgres:
+		return nil
+	default:
+		return fmt.Errorf("unknown audit sink %q (supported: %s, %s, %s)", name, SinkNone, SinkStdout, SinkPostgres)
+	}
+}
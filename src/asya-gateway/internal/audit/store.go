@@ -0,0 +1,47 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/deliveryhero/asya/asya-gateway/internal/envelopestore"
+	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+)
+
+// Store wraps an envelope store and writes an audit entry when an envelope is
+// created, and as a TransitionObserver of the wrapped store when it first reaches a
+// final status (including timeouts). Audit failures are logged and never fail the
+// envelope operation.
+type Store struct {
+	envelopestore.EnvelopeStore
+	sink Sink
+}
+
+// NewStore wraps store so lifecycle transitions are written to sink.
+// The Store must also observe the transitions of the base store (SetTransitionObserver).
+func NewStore(store envelopestore.EnvelopeStore, sink Sink) *Store {
+	return &Store{EnvelopeStore: store, sink: sink}
+}
+
+// Create creates the envelope and records the creation
+func (s *Store) Create(envelope *types.Envelope) error {
+	if err := s.EnvelopeStore.Create(envelope); err != nil {
+		return err
+	}
+	s.write(NewEntry(EventCreated, envelope))
+	return nil
+}
+
+// StatusChanged records the first final status of an envelope. Redelivered final
+// updates do not change the status and are not recorded again.
+func (s *Store) StatusChanged(transition envelopestore.Transition) {
+	if transition.Completed() {
+		s.write(NewEntry(EventCompleted, transition.Envelope))
+	}
+}
+
+func (s *Store) write(entry Entry) {
+	if err := s.sink.Write(context.Background(), entry); err != nil {
+		slog.Error("Failed to write audit entry", "id", entry.EnvelopeID, "event", entry.Event, "error", err)
+	}
+}
@@ -0,0 +1,174 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deliveryhero/asya/asya-gateway/internal/envelopestore"
+	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+)
+
+// recordingSink keeps written entries in memory
+type recordingSink struct {
+	entries []Entry
+	err     error
+}
+
+func (s *recordingSink) Write(ctx context.Context, entry Entry) error {
+	s.entries = append(s.entries, entry)
+	return s.err
+}
+
+func newEnvelope(id string) *types.Envelope {
+	return &types.Envelope{
+		ID:      id,
+		Status:  types.EnvelopeStatusPending,
+		Tool:    "summarize",
+		Route:   types.Route{Actors: []string{"prep", "infer"}},
+		Payload: map[string]any{"text": "hello"},
+	}
+}
+
+// newAuditedStore returns an in-memory store audited into sink
+func newAuditedStore(sink Sink) *Store {
+	base := envelopestore.NewStore()
+	store := NewStore(base, sink)
+	base.SetTransitionObserver(store)
+	return store
+}
+
+func TestStore_Lifecycle(t *testing.T) {
+	sink := &recordingSink{}
+	store := newAuditedStore(sink)
+
+	if err := store.Create(newEnvelope("env-1")); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := store.Update(types.EnvelopeUpdate{ID: "env-1", Status: types.EnvelopeStatusRunning}); err != nil {
+		t.Fatalf("Update running failed: %v", err)
+	}
+	if err := store.Update(types.EnvelopeUpdate{ID: "env-1", Status: types.EnvelopeStatusFailed, Error: "boom"}); err != nil {
+		t.Fatalf("Update failed failed: %v", err)
+	}
+	// Redelivered final status
+	if err := store.Update(types.EnvelopeUpdate{ID: "env-1", Status: types.EnvelopeStatusFailed, Error: "boom"}); err != nil {
+		t.Fatalf("Duplicate update failed: %v", err)
+	}
+
+	if len(sink.entries) != 2 {
+		t.Fatalf("Expected 2 audit entries (created, completed), got %d: %+v", len(sink.entries), sink.entries)
+	}
+
+	created := sink.entries[0]
+	if created.Event != EventCreated || created.EnvelopeID != "env-1" || created.Tool != "summarize" || created.Status != "pending" {
+		t.Errorf("Unexpected created entry: %+v", created)
+	}
+	if len(created.InputHash) != 64 {
+		t.Errorf("Expected SHA-256 input hash, got %q", created.InputHash)
+	}
+	if strings.Join(created.Route, ",") != "prep,infer" {
+		t.Errorf("Route = %v, want [prep infer]", created.Route)
+	}
+
+	completed := sink.entries[1]
+	if completed.Event != EventCompleted || completed.Status != "failed" || completed.Error != "boom" {
+		t.Errorf("Unexpected completed entry: %+v", completed)
+	}
+	if completed.InputHash != "" {
+		t.Errorf("Completed entry should not repeat the input hash, got %q", completed.InputHash)
+	}
+}
+
+func TestStore_TimeoutAudited(t *testing.T) {
+	sink := &recordingSink{}
+	store := newAuditedStore(sink)
+
+	envelope := newEnvelope("env-timeout")
+	envelope.TimeoutSec = 1
+	if err := store.Create(envelope); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// The store notifies with its lock held, so once the envelope failed the entry is written
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		got, err := store.Get("env-timeout")
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if got.Status == types.EnvelopeStatusFailed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the envelope to time out")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if len(sink.entries) != 2 {
+		t.Fatalf("Expected 2 audit entries (created, completed), got %d: %+v", len(sink.entries), sink.entries)
+	}
+	if completed := sink.entries[1]; completed.Event != EventCompleted || completed.Status != "failed" || completed.Error != "envelope timed out" {
+		t.Errorf("Unexpected completed entry: %+v", completed)
+	}
+}
+
+func TestStore_SinkErrorDoesNotFailOperation(t *testing.T) {
+	sink := &recordingSink{err: errors.New("sink unavailable")}
+	store := newAuditedStore(sink)
+
+	if err := store.Create(newEnvelope("env-2")); err != nil {
+		t.Fatalf("Create should succeed despite audit failure: %v", err)
+	}
+	if err := store.Update(types.EnvelopeUpdate{ID: "env-2", Status: types.EnvelopeStatusSucceeded}); err != nil {
+		t.Fatalf("Update should succeed despite audit failure: %v", err)
+	}
+}
+
+func TestStore_FailedCreateNotAudited(t *testing.T) {
+	sink := &recordingSink{}
+	store := newAuditedStore(sink)
+
+	if err := store.Create(newEnvelope("env-3")); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := store.Create(newEnvelope("env-3")); err == nil {
+		t.Fatal("Expected duplicate create to fail")
+	}
+
+	if len(sink.entries) != 1 {
+		t.Errorf("Expected only the successful create to be audited, got %d entries", len(sink.entries))
+	}
+}
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewWriterSink(&buf)
+
+	if err := sink.Write(context.Background(), NewEntry(EventCreated, newEnvelope("env-4"))); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("Expected a JSON line, got %q: %v", buf.String(), err)
+	}
+	if line["type"] != "audit" || line["event"] != EventCreated || line["envelope_id"] != "env-4" {
+		t.Errorf("Unexpected audit line: %v", line)
+	}
+}
+
+func TestValidateSinkName(t *testing.T) {
+	for _, name := range []string{"", "none", "stdout", "postgres", "STDOUT"} {
+		if err := ValidateSinkName(name); err != nil {
+			t.Errorf("ValidateSinkName(%q) = %v, want nil", name, err)
+		}
+	}
+	if err := ValidateSinkName("kafka"); err == nil {
+		t.Error("ValidateSinkName(\"kafka\") should fail")
+	}
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+)
+
+// WeightedActor is one target of a canary route step with its share of the messages
+type WeightedActor struct {
+	Actor  string `yaml:"actor"`
+	Weight int    `yaml:"weight"` // Relative weight; 0 stops sending messages to the actor
+}
+
+// validateCanary checks the weighted targets of every canary route step
+func validateCanary(canary map[string][]WeightedActor) error {
+	for step, targets := range canary {
+		if strings.TrimSpace(step) == "" {
+			return fmt.Errorf("canary step name cannot be empty")
+		}
+		if len(targets) == 0 {
+			return fmt.Errorf("canary step %q: no targets", step)
+		}
+
+		total := 0
+		actors := make([]string, len(targets))
+		for i, target := range targets {
+			if strings.TrimSpace(target.Actor) == "" {
+				return fmt.Errorf("canary step %q: target %d has no actor", step, i)
+			}
+			if target.Weight < 0 {
+				return fmt.Errorf("canary step %q: target %q has negative weight", step, target.Actor)
+			}
+			total += target.Weight
+			actors[i] = target.Actor
+		}
+		if total == 0 {
+			return fmt.Errorf("canary step %q: weights sum to 0", step)
+		}
+		if err := validateNoTerminalActors(actors); err != nil {
+			return fmt.Errorf("canary step %q: %w", step, err)
+		}
+	}
+	return nil
+}
+
+// PickCanaryActors returns the actors of a route with every canary step replaced by one of
+// its targets, chosen by weighted random per call. Routes without canary steps are returned as is.
+func (c *Config) PickCanaryActors(actors []string) []string {
+	if c == nil || len(c.Canary) == 0 {
+		return actors
+	}
+
+	var picked []string
+	for i, actor := range actors {
+		targets, ok := c.Canary[actor]
+		if !ok {
+			continue
+		}
+		if picked == nil {
+			picked = append([]string(nil), actors...)
+		}
+		picked[i] = pickWeighted(targets, rand.IntN)
+	}
+
+	if picked == nil {
+		return actors
+	}
+	return picked
+}
+
+// pickWeighted chooses a target with probability proportional to its weight; intn returns a
+// random number in [0, n). Targets are validated to have a positive total weight.
+func pickWeighted(targets []WeightedActor, intn func(n int) int) string {
+	total := 0
+	for _, target := range targets {
+		total += target.Weight
+	}
+
+	n := intn(total)
+	for _, target := range targets {
+		if n < target.Weight {
+			return target.Actor
+		}
+		n -= target.Weight
+	}
+	return targets[len(targets)-1].Actor
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoad_Canary(t *testing.T) {
+	tests := []struct {
+		name    string
+		canary  string
+		wantErr string
+	}{
+		{name: "valid split", canary: "  infer:\n    - {actor: infer-v1, weight: 90}\n    - {actor: infer-v2, weight: 10}\n"},
+		{name: "zero weight target", canary: "  infer:\n    - {actor: infer-v1, weight: 100}\n    - {actor: infer-v2, weight: 0}\n"},
+		{name: "no targets", canary: "  infer: []\n", wantErr: "no targets"},
+		{name: "missing actor", canary: "  infer:\n    - {weight: 10}\n", wantErr: "has no actor"},
+		{name: "negative weight", canary: "  infer:\n    - {actor: infer-v1, weight: -1}\n", wantErr: "negative weight"},
+		{name: "zero total weight", canary: "  infer:\n    - {actor: infer-v1, weight: 0}\n", wantErr: "weights sum to 0"},
+		{name: "terminal target", canary: "  infer:\n    - {actor: happy-end, weight: 1}\n", wantErr: "must not be listed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yaml := "tools:\n  - name: test\n    route: [prep, infer]\ncanary:\n" + tt.canary
+			cfg, err := Load(strings.NewReader(yaml))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Load() error = %v", err)
+				}
+				if got := strings.Join(cfg.Actors(), ","); got != "infer-v1,infer-v2,prep" {
+					t.Errorf("Actors() = %s, want canary step replaced by its targets", got)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPickCanaryActors(t *testing.T) {
+	cfg := &Config{Canary: map[string][]WeightedActor{
+		"infer": {{Actor: "infer-v1", Weight: 1}, {Actor: "infer-v2", Weight: 0}},
+	}}
+
+	actors := []string{"prep", "infer", "post"}
+	got := cfg.PickCanaryActors(actors)
+	if strings.Join(got, ",") != "prep,infer-v1,post" {
+		t.Errorf("PickCanaryActors() = %v, want prep,infer-v1,post", got)
+	}
+	if actors[1] != "infer" {
+		t.Errorf("PickCanaryActors() modified the route: %v", actors)
+	}
+
+	other := []string{"prep", "post"}
+	if got := cfg.PickCanaryActors(other); strings.Join(got, ",") != "prep,post" {
+		t.Errorf("PickCanaryActors() = %v, want route without canary steps unchanged", got)
+	}
+
+	var nilConfig *Config
+	if got := nilConfig.PickCanaryActors(actors); strings.Join(got, ",") != "prep,infer,post" {
+		t.Errorf("PickCanaryActors() of nil config = %v, want route unchanged", got)
+	}
+}
+
+func TestPickWeighted(t *testing.T) {
+	targets := []WeightedActor{{Actor: "v1", Weight: 90}, {Actor: "v2", Weight: 0}, {Actor: "v3", Weight: 10}}
+
+	counts := make(map[string]int)
+	for n := 0; n < 100; n++ {
+		counts[pickWeighted(targets, func(int) int { return n })]++
+	}
+	if counts["v1"] != 90 || counts["v2"] != 0 || counts["v3"] != 10 {
+		t.Errorf("pickWeighted() counts = %v, want v1:90 v3:10", counts)
+	}
+}
+
+func TestMergeConfigs_Canary(t *testing.T) {
+	tool := func(name string) Tool {
+		return Tool{Name: name, Route: RouteSpec{Actors: []string{"infer"}}}
+	}
+	canary := map[string][]WeightedActor{"infer": {{Actor: "infer-v2", Weight: 1}}}
+
+	merged, err := MergeConfigs(
+		&Config{Tools: []Tool{tool("tool1")}, Canary: canary},
+		&Config{Tools: []Tool{tool("tool2")}},
+	)
+	if err != nil {
+		t.Fatalf("MergeConfigs() error = %v", err)
+	}
+	if len(merged.Canary["infer"]) != 1 {
+		t.Errorf("Canary = %v, want the infer step", merged.Canary)
+	}
+
+	_, err = MergeConfigs(
+		&Config{Tools: []Tool{tool("tool1")}, Canary: canary},
+		&Config{Tools: []Tool{tool("tool2")}, Canary: canary},
+	)
+	if err == nil {
+		t.Error("Expected error for duplicate canary step")
+	}
+}
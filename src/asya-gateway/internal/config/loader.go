@@ -77,7 +77,7 @@ func LoadFromDir(dirPath string) (*Config, error) {
 // MergeConfigs merges multiple configurations into one
 // Later configs override earlier ones for defaults
 // Tools are combined (duplicates cause error)
-// Route templates are combined (duplicates cause error)
+// Route templates and canary steps are combined (duplicates cause error)
 func MergeConfigs(configs ...*Config) (*Config, error) {
 	if len(configs) == 0 {
 		return nil, fmt.Errorf("no configs to merge")
@@ -108,10 +108,24 @@ func MergeConfigs(configs ...*Config) (*Config, error) {
 			merged.Routes[name] = actors
 		}
 
-		// Use last config's defaults (if set)
+		// Merge canary steps (no duplicates allowed)
+		for step, targets := range config.Canary {
+			if _, exists := merged.Canary[step]; exists {
+				return nil, fmt.Errorf("duplicate canary step across configs: %q", step)
+			}
+			if merged.Canary == nil {
+				merged.Canary = make(map[string][]WeightedActor)
+			}
+			merged.Canary[step] = targets
+		}
+
+		// Use last config's defaults and terminal settings (if set)
 		if config.Defaults != nil {
 			merged.Defaults = config.Defaults
 		}
+		if config.Terminal != nil {
+			merged.Terminal = config.Terminal
+		}
 	}
 
 	// Validate merged config
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -108,6 +109,48 @@ tools:
 `,
 			wantErr: true,
 		},
+		{
+			name: "invalid - terminal actor in route",
+			yaml: `
+tools:
+  - name: test
+    parameters:
+      input:
+        type: string
+    route: [actor, happy-end]
+`,
+			wantErr: true,
+		},
+		{
+			name: "invalid - terminal actor in template",
+			yaml: `
+routes:
+  pipeline: [actor, error-end]
+
+tools:
+  - name: test
+    parameters:
+      input:
+        type: string
+    route: [actor]
+`,
+			wantErr: true,
+		},
+		{
+			name: "config with terminal consumption",
+			yaml: `
+terminal:
+  consume: true
+
+tools:
+  - name: test
+    parameters:
+      input:
+        type: string
+    route: [actor]
+`,
+			wantErr: false,
+		},
 		{
 			name: "all parameter types",
 			yaml: `
@@ -134,6 +177,173 @@ tools:
 `,
 			wantErr: false,
 		},
+		{
+			name: "payload template",
+			yaml: `
+tools:
+  - name: generate
+    parameters:
+      description: {type: string, required: true}
+      count: {type: integer}
+    payload_template:
+      request:
+        prompt: ${description}
+        n: ${count}
+      source: gateway
+    route: [actor]
+`,
+			wantErr: false,
+		},
+		{
+			name: "defaults matching declared types",
+			yaml: `
+tools:
+  - name: defaults
+    parameters:
+      count: {type: integer, default: 5}
+      ratio: {type: number, default: 0.5}
+      whole: {type: integer, default: 2.0}
+      style: {type: string, options: [photo, anime], default: photo}
+      tags: {type: array, items: {type: string}, default: [a, b]}
+      options: {type: object, properties: {hd: {type: boolean}}, default: {hd: true}}
+    route: [actor]
+`,
+			wantErr: false,
+		},
+		{
+			name: "constraints",
+			yaml: `
+tools:
+  - name: constrained
+    parameters:
+      count: {type: integer, minimum: 1, maximum: 100}
+      name: {type: string, min_length: 1, max_length: 64, pattern: "^[a-z-]+$"}
+    route: [actor]
+`,
+			wantErr: false,
+		},
+		{
+			name: "nested object parameters",
+			yaml: `
+tools:
+  - name: render
+    parameters:
+      options:
+        type: object
+        properties:
+          quality: {type: string, required: true, enum: [low, high]}
+          size:
+            type: object
+            properties:
+              width: {type: integer, minimum: 1}
+        default: {quality: low, size: {width: 512}}
+    route: [actor]
+`,
+			wantErr: false,
+		},
+		{
+			name: "nested default missing required property",
+			yaml: `
+tools:
+  - name: render
+    parameters:
+      options:
+        type: object
+        properties:
+          quality: {type: string, required: true}
+        default: {}
+    route: [actor]
+`,
+			wantErr: true,
+		},
+		{
+			name: "default of wrong type",
+			yaml: `
+tools:
+  - name: defaults
+    parameters:
+      count: {type: integer, default: "5"}
+    route: [actor]
+`,
+			wantErr: true,
+		},
+		{
+			name: "fractional default of integer",
+			yaml: `
+tools:
+  - name: defaults
+    parameters:
+      count: {type: integer, default: 1.5}
+    route: [actor]
+`,
+			wantErr: true,
+		},
+		{
+			name: "tool with exchange",
+			yaml: `
+tools:
+  - name: team-a
+    route: [actor]
+    exchange: team-a.pipelines
+`,
+			wantErr: false,
+		},
+		{
+			name: "invalid exchange",
+			yaml: `
+tools:
+  - name: team-a
+    route: [actor]
+    exchange: team a
+`,
+			wantErr: true,
+		},
+		{
+			name: "default not among options",
+			yaml: `
+tools:
+  - name: defaults
+    parameters:
+      style: {type: string, options: [photo, anime], default: sketch}
+    route: [actor]
+`,
+			wantErr: true,
+		},
+		{
+			name: "default with array item of wrong type",
+			yaml: `
+tools:
+  - name: defaults
+    parameters:
+      sizes: {type: array, items: {type: number}, default: [1, large]}
+    route: [actor]
+`,
+			wantErr: true,
+		},
+		{
+			name: "default with object property of wrong type",
+			yaml: `
+tools:
+  - name: defaults
+    parameters:
+      options: {type: object, properties: {hd: {type: boolean}}, default: {hd: "yes"}}
+    route: [actor]
+`,
+			wantErr: true,
+		},
+		{
+			name: "payload template referencing undeclared parameter",
+			yaml: `
+tools:
+  - name: generate
+    parameters:
+      description: {type: string}
+    payload_template:
+      prompt: ${prompt}
+    route: [actor]
+`,
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -302,6 +512,100 @@ func TestMergeConfigs(t *testing.T) {
 	}
 }
 
+func TestConfigConsumesTerminalQueues(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+		want   bool
+	}{
+		{name: "nil config", config: nil, want: false},
+		{name: "no terminal section", config: &Config{}, want: false},
+		{name: "consume disabled", config: &Config{Terminal: &TerminalConfig{}}, want: false},
+		{name: "consume enabled", config: &Config{Terminal: &TerminalConfig{Consume: true}}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.ConsumesTerminalQueues(); got != tt.want {
+				t.Errorf("ConsumesTerminalQueues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigExchanges(t *testing.T) {
+	var nilConfig *Config
+	if got := nilConfig.Exchanges(); got != nil {
+		t.Errorf("Exchanges() of nil config = %v, want nil", got)
+	}
+
+	cfg := &Config{Tools: []Tool{
+		{Name: "b-infer", Exchange: "team-b"},
+		{Name: "a-prep", Exchange: "team-a"},
+		{Name: "a-infer", Exchange: "team-a"},
+		{Name: "shared"},
+	}}
+	if got := strings.Join(cfg.Exchanges(), ","); got != "team-a,team-b" {
+		t.Errorf("Exchanges() = %s, want team-a,team-b", got)
+	}
+}
+
+func TestTerminalActorsFromEnv(t *testing.T) {
+	t.Setenv("ASYA_ACTOR_HAPPY_END", "")
+	t.Setenv("ASYA_ACTOR_ERROR_END", "")
+	if got, want := TerminalActorsFromEnv(), (TerminalActors{HappyEnd: "happy-end", ErrorEnd: "error-end"}); got != want {
+		t.Errorf("TerminalActorsFromEnv() = %+v, want %+v", got, want)
+	}
+
+	t.Setenv("ASYA_ACTOR_HAPPY_END", "done")
+	t.Setenv("ASYA_ACTOR_ERROR_END", "failed")
+	if got, want := TerminalActorsFromEnv(), (TerminalActors{HappyEnd: "done", ErrorEnd: "failed"}); got != want {
+		t.Errorf("TerminalActorsFromEnv() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoad_CustomTerminalActors(t *testing.T) {
+	t.Setenv("ASYA_ACTOR_HAPPY_END", "done")
+	t.Setenv("ASYA_ACTOR_ERROR_END", "failed")
+
+	tests := []struct {
+		name    string
+		route   string
+		wantErr bool
+	}{
+		{name: "custom happy end", route: "[actor, done]", wantErr: true},
+		{name: "custom error end", route: "[failed]", wantErr: true},
+		{name: "default names are regular actors", route: "[happy-end]", wantErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yaml := "tools:\n  - name: test\n    route: " + tt.route + "\n"
+			_, err := Load(strings.NewReader(yaml))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMergeConfigs_Terminal(t *testing.T) {
+	tool := func(name string) Tool {
+		return Tool{Name: name, Route: RouteSpec{Actors: []string{"actor"}}}
+	}
+
+	merged, err := MergeConfigs(
+		&Config{Tools: []Tool{tool("tool1")}, Terminal: &TerminalConfig{Consume: true}},
+		&Config{Tools: []Tool{tool("tool2")}},
+	)
+	if err != nil {
+		t.Fatalf("MergeConfigs() error = %v", err)
+	}
+	if !merged.ConsumesTerminalQueues() {
+		t.Error("Expected terminal settings to be kept when later configs omit them")
+	}
+}
+
 func TestMergeConfigs_DuplicateTool(t *testing.T) {
 	config1 := &Config{
 		Tools: []Tool{
@@ -408,6 +712,30 @@ func TestToolGetOptions(t *testing.T) {
 	}
 }
 
+func TestToolApplyDefaults(t *testing.T) {
+	tool := Tool{
+		Parameters: map[string]Parameter{
+			"description": {Type: "string", Required: true},
+			"count":       {Type: "integer", Default: 5},
+			"style":       {Type: "string", Default: "photo"},
+		},
+	}
+
+	arguments := map[string]any{"description": "cat", "style": "anime"}
+	got := tool.ApplyDefaults(arguments)
+	want := map[string]any{"description": "cat", "count": 5, "style": "anime"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ApplyDefaults() = %v, want %v", got, want)
+	}
+	if _, ok := arguments["count"]; ok {
+		t.Error("ApplyDefaults() modified the caller's arguments")
+	}
+
+	if got := tool.ApplyDefaults(nil); !reflect.DeepEqual(got, map[string]any{"count": 5, "style": "photo"}) {
+		t.Errorf("ApplyDefaults(nil) = %v", got)
+	}
+}
+
 func TestRouteSpecUnmarshalYAML(t *testing.T) {
 	tests := []struct {
 		name  string
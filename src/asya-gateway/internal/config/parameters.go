@@ -0,0 +1,253 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ValidateArguments checks the arguments of a tool call against the declared parameters,
+// in parameter name order. Arguments without a declared parameter are not checked.
+func (t *Tool) ValidateArguments(arguments map[string]any) error {
+	names := make([]string, 0, len(t.Parameters))
+	for name := range t.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value, ok := arguments[name]
+		if !ok {
+			continue
+		}
+		param := t.Parameters[name]
+		if err := param.ValidateArgument(value); err != nil {
+			return fmt.Errorf("invalid parameter %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ValidateArgument checks a caller's argument against the parameter's type, enum and
+// constraints, including array items and object properties (required ones must be present).
+// A value of another type (e.g. "500" for a number) is rejected rather than let past
+// the range and length constraints.
+func (p *Parameter) ValidateArgument(value any) error {
+	return p.check(value, true)
+}
+
+// check checks value against the enum and constraints (and the type when strict),
+// recursing into array items and object properties
+func (p *Parameter) check(value any, strict bool) error {
+	if strict {
+		if err := p.checkType(value); err != nil {
+			return err
+		}
+	}
+	if err := p.checkEnum(value); err != nil {
+		return err
+	}
+	if err := p.checkConstraints(value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case []any:
+		if p.Items == nil {
+			return nil
+		}
+		for i, item := range v {
+			if err := p.Items.check(item, strict); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+	case map[string]any:
+		names := make([]string, 0, len(p.Properties))
+		for name := range p.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop := p.Properties[name]
+			item, ok := v[name]
+			if !ok {
+				if prop.Required {
+					return fmt.Errorf("missing required property %q", name)
+				}
+				continue
+			}
+			if err := prop.check(item, strict); err != nil {
+				return fmt.Errorf("property %q: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkType checks that a value has the parameter's declared type
+func (p *Parameter) checkType(value any) error {
+	var ok bool
+	switch p.Type {
+	case "string":
+		_, ok = value.(string)
+	case "number":
+		_, ok = toFloat(value)
+	case "integer":
+		var f float64
+		f, ok = toFloat(value)
+		ok = ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok = value.(bool)
+	case "array":
+		_, ok = value.([]any)
+	case "object":
+		_, ok = value.(map[string]any)
+	default:
+		ok = true
+	}
+	if !ok {
+		return fmt.Errorf("%s is not of type %s", formatValue(value), p.Type)
+	}
+	return nil
+}
+
+// validateConstraints checks that the constraints fit the parameter's type and each other
+func (p *Parameter) validateConstraints() error {
+	numeric := p.Type == "number" || p.Type == "integer"
+	if (p.Minimum != nil || p.Maximum != nil) && !numeric {
+		return fmt.Errorf("minimum and maximum only apply to number and integer parameters")
+	}
+	if p.Minimum != nil && p.Maximum != nil && *p.Minimum > *p.Maximum {
+		return fmt.Errorf("minimum %v is greater than maximum %v", *p.Minimum, *p.Maximum)
+	}
+
+	if (p.MinLength != nil || p.MaxLength != nil || p.Pattern != "") && p.Type != "string" {
+		return fmt.Errorf("min_length, max_length and pattern only apply to string parameters")
+	}
+	if (p.MinLength != nil && *p.MinLength < 0) || (p.MaxLength != nil && *p.MaxLength < 0) {
+		return fmt.Errorf("min_length and max_length cannot be negative")
+	}
+	if p.MinLength != nil && p.MaxLength != nil && *p.MinLength > *p.MaxLength {
+		return fmt.Errorf("min_length %d is greater than max_length %d", *p.MinLength, *p.MaxLength)
+	}
+	if p.Pattern != "" {
+		if _, err := compilePattern(p.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkConstraints checks a value against the range, length and pattern constraints.
+// Values of another kind than the constraint (e.g. a string for a minimum) are left to the type check.
+func (p *Parameter) checkConstraints(value any) error {
+	if f, ok := toFloat(value); ok {
+		if p.Minimum != nil && f < *p.Minimum {
+			return fmt.Errorf("%v is less than the minimum of %v", f, *p.Minimum)
+		}
+		if p.Maximum != nil && f > *p.Maximum {
+			return fmt.Errorf("%v is greater than the maximum of %v", f, *p.Maximum)
+		}
+		return nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return nil
+	}
+	length := utf8.RuneCountInString(s)
+	if p.MinLength != nil && length < *p.MinLength {
+		return fmt.Errorf("length %d is less than the minimum of %d", length, *p.MinLength)
+	}
+	if p.MaxLength != nil && length > *p.MaxLength {
+		return fmt.Errorf("length %d is greater than the maximum of %d", length, *p.MaxLength)
+	}
+	if p.Pattern != "" {
+		re, err := compilePattern(p.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+		if !re.MatchString(s) {
+			return fmt.Errorf("%s does not match pattern %q", formatValue(s), p.Pattern)
+		}
+	}
+	return nil
+}
+
+// compiledPatterns caches compiled parameter patterns (pattern -> *regexp.Regexp)
+var compiledPatterns sync.Map
+
+// compilePattern compiles a parameter pattern once
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := compiledPatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	compiledPatterns.Store(pattern, re)
+	return re, nil
+}
+
+// AllowedValues returns the values advertised to clients (Enum, or Options),
+// or nil when any value is allowed. Only Enum is enforced.
+func (p *Parameter) AllowedValues() []any {
+	if len(p.Enum) > 0 {
+		return p.Enum
+	}
+	if len(p.Options) == 0 {
+		return nil
+	}
+	allowed := make([]any, len(p.Options))
+	for i, option := range p.Options {
+		allowed[i] = option
+	}
+	return allowed
+}
+
+// checkEnum checks that a value is one of the parameter's enum values
+func (p *Parameter) checkEnum(value any) error {
+	if len(p.Enum) == 0 {
+		return nil
+	}
+	for _, candidate := range p.Enum {
+		if valuesEqual(candidate, value) {
+			return nil
+		}
+	}
+
+	formatted := make([]string, len(p.Enum))
+	for i, candidate := range p.Enum {
+		formatted[i] = formatValue(candidate)
+	}
+	return fmt.Errorf("%s is not one of %s", formatValue(value), strings.Join(formatted, ", "))
+}
+
+// valuesEqual compares scalars decoded from YAML or JSON, numbers by value
+// (YAML decodes 5 as int, JSON as float64)
+func valuesEqual(a, b any) bool {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		return ok && fa == fb
+	}
+	switch a.(type) {
+	case string, bool:
+		return a == b
+	}
+	return false
+}
+
+// formatValue formats a value for error messages as JSON
+func formatValue(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToolValidateArguments_Enum(t *testing.T) {
+	tool := Tool{
+		Parameters: map[string]Parameter{
+			"model":   {Type: "string", Enum: []any{"fast", "accurate"}},
+			"format":  {Type: "string", Options: []string{"pdf", "txt"}},
+			"size":    {Type: "integer", Enum: []any{256, 512}},
+			"formats": {Type: "array", Items: &Parameter{Type: "string", Enum: []any{"png", "jpg"}}},
+			"render": {Type: "object", Properties: map[string]Parameter{
+				"quality": {Type: "string", Enum: []any{"low", "high"}},
+			}},
+			"free": {Type: "string"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		arguments map[string]any
+		wantErr   string // "" for valid arguments
+	}{
+		{name: "allowed values", arguments: map[string]any{"model": "fast", "format": "txt", "size": 512.0}},
+		{name: "omitted parameters", arguments: map[string]any{}},
+		{name: "unrestricted parameter", arguments: map[string]any{"free": "anything"}},
+		{name: "undeclared argument", arguments: map[string]any{"other": 1}},
+		{name: "enum value out of range", arguments: map[string]any{"model": "slow"}, wantErr: `invalid parameter model: "slow" is not one of "fast", "accurate"`},
+		{name: "options are advertised, not enforced", arguments: map[string]any{"format": "docx"}},
+		{name: "number out of range", arguments: map[string]any{"size": 300.0}, wantErr: `invalid parameter size: 300 is not one of 256, 512`},
+		{name: "number of another type", arguments: map[string]any{"size": "512"}, wantErr: `invalid parameter size: "512" is not of type integer`},
+		{name: "array items", arguments: map[string]any{"formats": []any{"png", "gif"}}, wantErr: `invalid parameter formats: item 1: "gif" is not one of "png", "jpg"`},
+		{name: "object properties", arguments: map[string]any{"render": map[string]any{"quality": "max"}}, wantErr: `invalid parameter render: property "quality": "max" is not one of "low", "high"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tool.ValidateArguments(tt.arguments)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateArguments() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateArguments() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestToolValidateArguments_NestedObject(t *testing.T) {
+	tool := Tool{
+		Parameters: map[string]Parameter{
+			"render": {Type: "object", Properties: map[string]Parameter{
+				"quality": {Type: "string", Required: true},
+				"size": {Type: "object", Properties: map[string]Parameter{
+					"width": {Type: "integer", Required: true, Minimum: floatPtr(1)},
+				}},
+				"layers": {Type: "array", Items: &Parameter{Type: "object", Properties: map[string]Parameter{
+					"name": {Type: "string", Required: true, MaxLength: intPtr(8)},
+				}}},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		render  any
+		wantErr string // "" for valid arguments
+	}{
+		{name: "valid", render: map[string]any{"quality": "high", "size": map[string]any{"width": 64.0}, "layers": []any{map[string]any{"name": "base"}}}},
+		{name: "optional object omitted", render: map[string]any{"quality": "high"}},
+		{name: "missing required property", render: map[string]any{}, wantErr: `invalid parameter render: missing required property "quality"`},
+		{name: "missing nested required property", render: map[string]any{"quality": "high", "size": map[string]any{}}, wantErr: `property "size": missing required property "width"`},
+		{name: "nested constraint", render: map[string]any{"quality": "high", "size": map[string]any{"width": 0.0}}, wantErr: `property "size": property "width": 0 is less than the minimum of 1`},
+		{name: "object in array", render: map[string]any{"quality": "high", "layers": []any{map[string]any{"name": "background"}}}, wantErr: `property "layers": item 0: property "name": length 10 is greater than the maximum of 8`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tool.ValidateArguments(map[string]any{"render": tt.render})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateArguments() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateArguments() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParameterValidate_Enum(t *testing.T) {
+	tests := []struct {
+		name    string
+		param   Parameter
+		wantErr bool
+	}{
+		{name: "string enum", param: Parameter{Type: "string", Enum: []any{"a", "b"}}},
+		{name: "integer enum", param: Parameter{Type: "integer", Enum: []any{1, 2}}},
+		{name: "enum value of wrong type", param: Parameter{Type: "integer", Enum: []any{1, "2"}}, wantErr: true},
+		{name: "enum and options", param: Parameter{Type: "string", Enum: []any{"a"}, Options: []string{"a"}}, wantErr: true},
+		{name: "default in enum", param: Parameter{Type: "number", Enum: []any{0.5, 1}, Default: 1}},
+		{name: "default not in enum", param: Parameter{Type: "number", Enum: []any{0.5, 1}, Default: 2}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.param.Validate("param"); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestToolValidateArguments_Constraints(t *testing.T) {
+	tool := Tool{
+		Parameters: map[string]Parameter{
+			"count": {Type: "integer", Minimum: floatPtr(1), Maximum: floatPtr(100)},
+			"name":  {Type: "string", MinLength: intPtr(2), MaxLength: intPtr(5)},
+			"sku":   {Type: "string", Pattern: `^[A-Z]{3}-\d+$`},
+			"sizes": {Type: "array", Items: &Parameter{Type: "number", Minimum: floatPtr(0)}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		arguments map[string]any
+		wantErr   string // "" for valid arguments
+	}{
+		{name: "within bounds", arguments: map[string]any{"count": 1.0, "name": "ab", "sku": "ABC-12", "sizes": []any{0.0, 2.5}}},
+		{name: "upper bounds inclusive", arguments: map[string]any{"count": 100.0, "name": "abcde"}},
+		{name: "length counts characters", arguments: map[string]any{"name": "ééééé"}},
+		{name: "below minimum", arguments: map[string]any{"count": 0.0}, wantErr: "invalid parameter count: 0 is less than the minimum of 1"},
+		{name: "above maximum", arguments: map[string]any{"count": 101.0}, wantErr: "invalid parameter count: 101 is greater than the maximum of 100"},
+		{name: "too short", arguments: map[string]any{"name": "a"}, wantErr: "invalid parameter name: length 1 is less than the minimum of 2"},
+		{name: "too long", arguments: map[string]any{"name": "abcdef"}, wantErr: "invalid parameter name: length 6 is greater than the maximum of 5"},
+		{name: "pattern mismatch", arguments: map[string]any{"sku": "abc-12"}, wantErr: `invalid parameter sku: "abc-12" does not match pattern`},
+		{name: "array item below minimum", arguments: map[string]any{"sizes": []any{1.0, -1.0}}, wantErr: "invalid parameter sizes: item 1: -1 is less than the minimum of 0"},
+		{name: "number sent as string", arguments: map[string]any{"count": "500"}, wantErr: `invalid parameter count: "500" is not of type integer`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tool.ValidateArguments(tt.arguments)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateArguments() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateArguments() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParameterValidate_Constraints(t *testing.T) {
+	tests := []struct {
+		name    string
+		param   Parameter
+		wantErr bool
+	}{
+		{name: "number range", param: Parameter{Type: "number", Minimum: floatPtr(0), Maximum: floatPtr(1)}},
+		{name: "string length and pattern", param: Parameter{Type: "string", MinLength: intPtr(1), MaxLength: intPtr(10), Pattern: `^\w+$`}},
+		{name: "minimum above maximum", param: Parameter{Type: "integer", Minimum: floatPtr(10), Maximum: floatPtr(1)}, wantErr: true},
+		{name: "minimum on string", param: Parameter{Type: "string", Minimum: floatPtr(1)}, wantErr: true},
+		{name: "length on number", param: Parameter{Type: "number", MaxLength: intPtr(3)}, wantErr: true},
+		{name: "negative length", param: Parameter{Type: "string", MinLength: intPtr(-1)}, wantErr: true},
+		{name: "min_length above max_length", param: Parameter{Type: "string", MinLength: intPtr(5), MaxLength: intPtr(2)}, wantErr: true},
+		{name: "invalid pattern", param: Parameter{Type: "string", Pattern: `(`}, wantErr: true},
+		{name: "default within range", param: Parameter{Type: "integer", Minimum: floatPtr(1), Default: 5}},
+		{name: "default out of range", param: Parameter{Type: "integer", Minimum: floatPtr(1), Default: 0}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.param.Validate("param"); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// payloadRefPattern matches a payload template value that refers to a tool parameter ("${name}")
+var payloadRefPattern = regexp.MustCompile(`^\$\{([^{}]+)\}$`)
+
+// payloadRef returns the parameter name a payload template string refers to
+func payloadRef(value string) (string, bool) {
+	match := payloadRefPattern.FindStringSubmatch(value)
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
+// BuildPayload returns the envelope payload for the arguments of a tool call.
+// Without a payload template the arguments are the payload. Otherwise the template is
+// copied with every "${name}" string replaced by the argument name, keeping its type;
+// map keys and array items referring to an omitted argument are left out.
+func (t *Tool) BuildPayload(arguments map[string]any) any {
+	if t.PayloadTemplate == nil {
+		return arguments
+	}
+	payload, _ := fillPayloadTemplate(t.PayloadTemplate, arguments)
+	return payload
+}
+
+// fillPayloadTemplate returns a copy of template with parameter references replaced by
+// arguments. ok is false when template itself refers to an omitted argument.
+func fillPayloadTemplate(template any, arguments map[string]any) (value any, ok bool) {
+	switch v := template.(type) {
+	case string:
+		if name, isRef := payloadRef(v); isRef {
+			value, ok = arguments[name]
+			return value, ok
+		}
+		return v, true
+	case map[string]any:
+		filled := make(map[string]any, len(v))
+		for key, item := range v {
+			if value, ok := fillPayloadTemplate(item, arguments); ok {
+				filled[key] = value
+			}
+		}
+		return filled, true
+	case []any:
+		filled := make([]any, 0, len(v))
+		for _, item := range v {
+			if value, ok := fillPayloadTemplate(item, arguments); ok {
+				filled = append(filled, value)
+			}
+		}
+		return filled, true
+	default:
+		return v, true
+	}
+}
+
+// validatePayloadTemplate checks that every parameter referenced by the payload template is declared
+func (t *Tool) validatePayloadTemplate() error {
+	var unknown []string
+	walkPayloadRefs(t.PayloadTemplate, func(name string) {
+		if _, ok := t.Parameters[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	})
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("references undeclared parameters: %v", unknown)
+	}
+	return nil
+}
+
+// walkPayloadRefs calls fn with the name of every parameter referenced by template
+func walkPayloadRefs(template any, fn func(name string)) {
+	switch v := template.(type) {
+	case string:
+		if name, ok := payloadRef(v); ok {
+			fn(name)
+		}
+	case map[string]any:
+		for _, item := range v {
+			walkPayloadRefs(item, fn)
+		}
+	case []any:
+		for _, item := range v {
+			walkPayloadRefs(item, fn)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToolBuildPayload(t *testing.T) {
+	tests := []struct {
+		name      string
+		template  string // YAML of payload_template, "" for none
+		arguments map[string]any
+		want      any
+	}{
+		{
+			name:      "no template uses arguments",
+			arguments: map[string]any{"description": "cat", "count": 2},
+			want:      map[string]any{"description": "cat", "count": 2},
+		},
+		{
+			name: "nesting and renaming",
+			template: `
+image:
+  prompt: ${description}
+  n: ${count}
+`,
+			arguments: map[string]any{"description": "cat", "count": 2},
+			want:      map[string]any{"image": map[string]any{"prompt": "cat", "n": 2}},
+		},
+		{
+			name: "literals are kept",
+			template: `
+prompt: ${description}
+model: sdxl
+steps: 30
+tags: [gateway, "${description}"]
+`,
+			arguments: map[string]any{"description": "cat"},
+			want: map[string]any{
+				"prompt": "cat",
+				"model":  "sdxl",
+				"steps":  30,
+				"tags":   []any{"gateway", "cat"},
+			},
+		},
+		{
+			name: "values keep their type",
+			template: `
+options: ${options}
+`,
+			arguments: map[string]any{"options": map[string]any{"seed": 1.0, "hd": true}},
+			want:      map[string]any{"options": map[string]any{"seed": 1.0, "hd": true}},
+		},
+		{
+			name: "omitted arguments are left out",
+			template: `
+prompt: ${description}
+n: ${count}
+sizes: ["${size}", 512]
+`,
+			arguments: map[string]any{"description": "cat"},
+			want:      map[string]any{"prompt": "cat", "sizes": []any{512}},
+		},
+		{
+			name:      "whole payload from one parameter",
+			template:  `${items}`,
+			arguments: map[string]any{"items": []any{"a", "b"}},
+			want:      []any{"a", "b"},
+		},
+		{
+			name: "only whole values are references",
+			template: `
+prompt: "a ${description}"
+`,
+			arguments: map[string]any{"description": "cat"},
+			want:      map[string]any{"prompt": "a ${description}"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := loadPayloadTool(t, tt.template)
+			if got := tool.BuildPayload(tt.arguments); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildPayload() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+// loadPayloadTool loads a tool declaring description, count, size, options and items,
+// with the given payload template
+func loadPayloadTool(t *testing.T, template string) Tool {
+	t.Helper()
+
+	yaml := `
+tools:
+  - name: generate
+    parameters:
+      description: {type: string}
+      count: {type: integer}
+      size: {type: integer}
+      options: {type: object}
+      items: {type: array}
+    route: [actor]
+`
+	if template != "" {
+		yaml += "    payload_template:\n"
+		for _, line := range strings.Split(strings.Trim(template, "\n"), "\n") {
+			yaml += "      " + line + "\n"
+		}
+	}
+
+	cfg, err := Load(strings.NewReader(yaml))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return cfg.Tools[0]
+}
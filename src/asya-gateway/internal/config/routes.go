@@ -2,14 +2,26 @@ package config
 
 import (
 	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
 	"time"
 )
 
+// exchangeNamePattern matches the exchange names RabbitMQ accepts
+var exchangeNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.:-]{1,255}$`)
+
 // Config represents the complete tool routes configuration
 type Config struct {
 	Tools    []Tool              `yaml:"tools"`
 	Routes   map[string][]string `yaml:"routes,omitempty"`   // Named route templates
 	Defaults *ToolDefaults       `yaml:"defaults,omitempty"` // Global defaults
+	Terminal *TerminalConfig     `yaml:"terminal,omitempty"` // How envelopes are finalized
+
+	// Canary splits the messages of a route step between actor versions: every route listing
+	// the step's actor sends each envelope to one of the targets, chosen by weight
+	Canary map[string][]WeightedActor `yaml:"canary,omitempty"`
 }
 
 // Tool represents a single MCP tool definition
@@ -20,7 +32,13 @@ type Tool struct {
 	Route       RouteSpec            `yaml:"route"` // Can be array or string (template)
 	Progress    *bool                `yaml:"progress,omitempty"`
 	Timeout     *int                 `yaml:"timeout,omitempty"` // seconds
+	Sync        bool                 `yaml:"sync,omitempty"`    // Wait for the pipeline's outcome on a reply queue
 	Metadata    map[string]string    `yaml:"metadata,omitempty"`
+	Exchange    string               `yaml:"exchange,omitempty"` // RabbitMQ exchange of the tool's pipeline (default ASYA_RABBITMQ_EXCHANGE)
+
+	// PayloadTemplate arranges the parameters into the payload the first actor expects
+	// ("${name}" strings are replaced by parameter values); nil uses the parameters as payload
+	PayloadTemplate any `yaml:"payload_template,omitempty"`
 }
 
 // Parameter represents a tool parameter definition
@@ -28,10 +46,18 @@ type Parameter struct {
 	Type        string               `yaml:"type"` // string, number, boolean, object, array
 	Description string               `yaml:"description,omitempty"`
 	Required    bool                 `yaml:"required,omitempty"`
-	Default     interface{}          `yaml:"default,omitempty"`
-	Options     []string             `yaml:"options,omitempty"`    // enum values
+	Default     interface{}          `yaml:"default,omitempty"`    // Used when the caller omits the parameter
+	Options     []string             `yaml:"options,omitempty"`    // Suggested string values, advertised but not enforced
+	Enum        []any                `yaml:"enum,omitempty"`       // Allowed values of any type, enforced
 	Properties  map[string]Parameter `yaml:"properties,omitempty"` // for object type
 	Items       *Parameter           `yaml:"items,omitempty"`      // for array type
+
+	// Constraints, checked like the JSON Schema keywords of the same name
+	Minimum   *float64 `yaml:"minimum,omitempty"`    // number and integer
+	Maximum   *float64 `yaml:"maximum,omitempty"`    // number and integer
+	MinLength *int     `yaml:"min_length,omitempty"` // string, in characters
+	MaxLength *int     `yaml:"max_length,omitempty"` // string, in characters
+	Pattern   string   `yaml:"pattern,omitempty"`    // string, RE2 syntax, unanchored
 }
 
 // RouteSpec can be either a string (template reference) or array of strings (explicit actors)
@@ -74,7 +100,8 @@ func (r RouteSpec) MarshalYAML() (interface{}, error) {
 	return r.Actors, nil
 }
 
-// GetActors resolves the route actors, using templates if specified
+// GetActors resolves the route actors, using templates if specified.
+// A route with neither actors nor template resolves to no actors; callers reject empty routes.
 func (r *RouteSpec) GetActors(templates map[string][]string) ([]string, error) {
 	if len(r.Actors) > 0 {
 		return r.Actors, nil
@@ -88,13 +115,14 @@ func (r *RouteSpec) GetActors(templates map[string][]string) ([]string, error) {
 		return actors, nil
 	}
 
-	return nil, fmt.Errorf("route has no actors or template")
+	return nil, nil
 }
 
 // ToolOptions represents runtime options for a tool
 type ToolOptions struct {
 	Progress bool
 	Timeout  time.Duration
+	Sync     bool
 	Metadata map[string]string
 }
 
@@ -103,6 +131,7 @@ func (t *Tool) GetOptions(defaults *ToolDefaults) ToolOptions {
 	opts := ToolOptions{
 		Progress: false,           // default to false
 		Timeout:  5 * time.Minute, // default 5 minutes
+		Sync:     t.Sync,
 		Metadata: t.Metadata,
 	}
 
@@ -127,12 +156,87 @@ func (t *Tool) GetOptions(defaults *ToolDefaults) ToolOptions {
 	return opts
 }
 
+// Actors returns the distinct actors of all tool routes and route templates, sorted, with
+// canary steps replaced by their targets
+func (c *Config) Actors() []string {
+	if c == nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	for _, actors := range c.Routes {
+		for _, actor := range actors {
+			seen[actor] = true
+		}
+	}
+	for _, tool := range c.Tools {
+		for _, actor := range tool.Route.Actors {
+			seen[actor] = true
+		}
+	}
+	// Canary steps are replaced by their targets, the actors that receive messages
+	for step := range c.Canary {
+		delete(seen, step)
+	}
+	for _, targets := range c.Canary {
+		for _, target := range targets {
+			seen[target.Actor] = true
+		}
+	}
+
+	actors := make([]string, 0, len(seen))
+	for actor := range seen {
+		actors = append(actors, actor)
+	}
+	sort.Strings(actors)
+	return actors
+}
+
+// HasSyncTools reports whether any tool waits for its outcome synchronously
+func (c *Config) HasSyncTools() bool {
+	if c == nil {
+		return false
+	}
+	for _, tool := range c.Tools {
+		if tool.Sync {
+			return true
+		}
+	}
+	return false
+}
+
+// Exchanges returns the RabbitMQ exchanges set on tools, sorted
+func (c *Config) Exchanges() []string {
+	if c == nil {
+		return nil
+	}
+
+	var exchanges []string
+	for _, tool := range c.Tools {
+		if tool.Exchange != "" && !slices.Contains(exchanges, tool.Exchange) {
+			exchanges = append(exchanges, tool.Exchange)
+		}
+	}
+	sort.Strings(exchanges)
+	return exchanges
+}
+
 // Validate validates the configuration
 func (c *Config) Validate() error {
 	if len(c.Tools) == 0 {
 		return fmt.Errorf("no tools defined")
 	}
 
+	for name, actors := range c.Routes {
+		if err := validateNoTerminalActors(actors); err != nil {
+			return fmt.Errorf("route template %q: %w", name, err)
+		}
+	}
+
+	if err := validateCanary(c.Canary); err != nil {
+		return err
+	}
+
 	// Check for duplicate tool names
 	seen := make(map[string]bool)
 	for _, tool := range c.Tools {
@@ -167,6 +271,9 @@ func (t *Tool) Validate(templates map[string][]string) error {
 	if len(actors) == 0 {
 		return fmt.Errorf("route cannot be empty")
 	}
+	if err := validateNoTerminalActors(actors); err != nil {
+		return fmt.Errorf("invalid route: %w", err)
+	}
 
 	// Validate parameters
 	for name, param := range t.Parameters {
@@ -175,11 +282,19 @@ func (t *Tool) Validate(templates map[string][]string) error {
 		}
 	}
 
+	if t.Exchange != "" && !exchangeNamePattern.MatchString(t.Exchange) {
+		return fmt.Errorf("invalid exchange %q: use up to 255 letters, digits, '-', '_', '.' or ':'", t.Exchange)
+	}
+
 	// Validate timeout
 	if t.Timeout != nil && *t.Timeout < 0 {
 		return fmt.Errorf("timeout cannot be negative")
 	}
 
+	if err := t.validatePayloadTemplate(); err != nil {
+		return fmt.Errorf("payload_template: %w", err)
+	}
+
 	return nil
 }
 
@@ -207,6 +322,19 @@ func (p *Parameter) Validate(name string) error {
 		}
 	}
 
+	if len(p.Enum) > 0 && len(p.Options) > 0 {
+		return fmt.Errorf("options and enum cannot both be set")
+	}
+	for i, value := range p.Enum {
+		if err := p.checkType(value); err != nil {
+			return fmt.Errorf("enum value %d: %w", i, err)
+		}
+	}
+
+	if err := p.validateConstraints(); err != nil {
+		return err
+	}
+
 	// Validate array items
 	if p.Type == "array" && p.Items != nil {
 		if err := p.Items.Validate("items"); err != nil {
@@ -214,5 +342,108 @@ func (p *Parameter) Validate(name string) error {
 		}
 	}
 
+	if p.Default != nil {
+		if err := p.checkValue(p.Default); err != nil {
+			return fmt.Errorf("default: %w", err)
+		}
+		// The default is passed on like a caller's argument, so it must pass the same checks
+		if err := p.ValidateArgument(p.Default); err != nil {
+			return fmt.Errorf("default: %w", err)
+		}
+	}
+
 	return nil
 }
+
+// checkValue checks that a value (as decoded from YAML or JSON) matches the parameter's
+// type, options, array items and object properties
+func (p *Parameter) checkValue(value any) error {
+	switch p.Type {
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%v is not a string", value)
+		}
+		if len(p.Options) > 0 && !slices.Contains(p.Options, s) {
+			return fmt.Errorf("%q is not one of %v", s, p.Options)
+		}
+	case "number":
+		if _, ok := toFloat(value); !ok {
+			return fmt.Errorf("%v is not a number", value)
+		}
+	case "integer":
+		if f, ok := toFloat(value); !ok || f != math.Trunc(f) {
+			return fmt.Errorf("%v is not an integer", value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%v is not a boolean", value)
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%v is not an array", value)
+		}
+		if p.Items != nil {
+			for i, item := range items {
+				if err := p.Items.checkValue(item); err != nil {
+					return fmt.Errorf("item %d: %w", i, err)
+				}
+			}
+		}
+	case "object":
+		properties, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%v is not an object", value)
+		}
+		for name, prop := range p.Properties {
+			if v, ok := properties[name]; ok {
+				if err := prop.checkValue(v); err != nil {
+					return fmt.Errorf("property %q: %w", name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// toFloat converts the numeric types produced by YAML and JSON decoding to float64
+func toFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// ApplyDefaults returns the arguments of a tool call with the declared default of every
+// omitted parameter added. The caller's map is not modified.
+func (t *Tool) ApplyDefaults(arguments map[string]any) map[string]any {
+	var withDefaults map[string]any
+	for name, param := range t.Parameters {
+		if param.Default == nil {
+			continue
+		}
+		if _, ok := arguments[name]; ok {
+			continue
+		}
+		if withDefaults == nil {
+			withDefaults = make(map[string]any, len(arguments)+1)
+			for k, v := range arguments {
+				withDefaults[k] = v
+			}
+		}
+		withDefaults[name] = param.Default
+	}
+
+	if withDefaults == nil {
+		return arguments
+	}
+	return withDefaults
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// Default terminal actor names, matching the sidecar and operator defaults
+const (
+	DefaultHappyEndActor = "happy-end"
+	DefaultErrorEndActor = "error-end"
+)
+
+// TerminalConfig declares how envelopes reach a final status after their route.
+// By default standalone happy-end/error-end actors report it via POST /envelopes/{id}/final.
+type TerminalConfig struct {
+	// Consume makes the gateway consume the happy-end and error-end queues itself.
+	// Do not deploy the happy-end/error-end actors as well, they would compete for messages.
+	Consume bool `yaml:"consume,omitempty"`
+}
+
+// ConsumesTerminalQueues reports whether the gateway finalizes envelopes from the terminal queues
+func (c *Config) ConsumesTerminalQueues() bool {
+	return c != nil && c.Terminal != nil && c.Terminal.Consume
+}
+
+// TerminalActors names the actors (and queues) the sidecar routes envelopes to after their route
+type TerminalActors struct {
+	HappyEnd string
+	ErrorEnd string
+}
+
+// TerminalActorsFromEnv reads the terminal actor names from ASYA_ACTOR_HAPPY_END and
+// ASYA_ACTOR_ERROR_END, the same variables the sidecar reads, so both always agree
+func TerminalActorsFromEnv() TerminalActors {
+	terminal := TerminalActors{
+		HappyEnd: os.Getenv("ASYA_ACTOR_HAPPY_END"),
+		ErrorEnd: os.Getenv("ASYA_ACTOR_ERROR_END"),
+	}
+	if terminal.HappyEnd == "" {
+		terminal.HappyEnd = DefaultHappyEndActor
+	}
+	if terminal.ErrorEnd == "" {
+		terminal.ErrorEnd = DefaultErrorEndActor
+	}
+	return terminal
+}
+
+// Contains reports whether actor is one of the terminal actors
+func (t TerminalActors) Contains(actor string) bool {
+	return actor == t.HappyEnd || actor == t.ErrorEnd
+}
+
+// validateNoTerminalActors rejects routes listing a terminal actor. The sidecar routes to
+// happy-end/error-end after the last actor, so listing them would finalize envelopes twice.
+func validateNoTerminalActors(actors []string) error {
+	terminal := TerminalActorsFromEnv()
+	for _, actor := range actors {
+		if terminal.Contains(actor) {
+			return fmt.Errorf("terminal actor %q must not be listed in a route (added automatically by the sidecar)", actor)
+		}
+	}
+	return nil
+}
@@ -3,131 +3,262 @@ package consumer
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/deliveryhero/asya/asya-gateway/internal/config"
 	"github.com/deliveryhero/asya/asya-gateway/internal/envelopestore"
+	"github.com/deliveryhero/asya/asya-gateway/internal/logging"
 	"github.com/deliveryhero/asya/asya-gateway/internal/queue"
 	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
 )
 
+const (
+	// Back-off bounds for polling an empty or failing queue
+	defaultMinBackoff = 100 * time.Millisecond
+	defaultMaxBackoff = 5 * time.Second
+
+	// Retries of a failed final status update before the message is requeued
+	defaultUpdateRetries = 3
+	defaultUpdateBackoff = 200 * time.Millisecond
+
+	// Wait before requeueing a message whose update kept failing, doubled while the store
+	// keeps failing, so a store outage does not turn into a redelivery loop
+	defaultRequeueDelay    = time.Second
+	defaultMaxRequeueDelay = time.Minute
+)
+
 // ResultConsumer consumes envelopes from happy-end and error-end queues
 // and updates job status accordingly
 type ResultConsumer struct {
 	queueClient queue.Client
 	jobStore    envelopestore.EnvelopeStore
+	terminal    config.TerminalActors
+	workers     int // Messages processed concurrently per queue
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+
+	updateRetries int           // Retries of a failed store update before nacking
+	updateBackoff time.Duration // Wait before the first retry, doubled for each further one
+
+	requeueDelay    time.Duration // Wait before nacking a message whose update kept failing
+	maxRequeueDelay time.Duration
+	requeueFailures atomic.Int32 // Messages nacked in a row, reset once an update succeeds
+
+	observer ConsumerObserver
+}
+
+// ConsumerObserver is notified when a queue consumer starts and stops, e.g. to export
+// active consumer gauges that reveal consumers which died or were started twice
+type ConsumerObserver interface {
+	ConsumerStarted(queue string)
+	ConsumerStopped(queue string)
 }
 
-// NewResultConsumer creates a new result consumer
-func NewResultConsumer(queueClient queue.Client, jobStore envelopestore.EnvelopeStore) *ResultConsumer {
+// NewResultConsumer creates a new result consumer for the given terminal queues
+func NewResultConsumer(queueClient queue.Client, jobStore envelopestore.EnvelopeStore, terminal config.TerminalActors) *ResultConsumer {
 	return &ResultConsumer{
 		queueClient: queueClient,
 		jobStore:    jobStore,
+		terminal:    terminal,
+		workers:     1,
+		minBackoff:  defaultMinBackoff,
+		maxBackoff:  defaultMaxBackoff,
+
+		updateRetries: defaultUpdateRetries,
+		updateBackoff: defaultUpdateBackoff,
+
+		requeueDelay:    defaultRequeueDelay,
+		maxRequeueDelay: defaultMaxRequeueDelay,
+	}
+}
+
+// SetWorkers sets how many messages of each terminal queue are processed concurrently (default 1).
+// The queue client must prefetch at least as many messages for the workers to be busy.
+func (c *ResultConsumer) SetWorkers(n int) {
+	if n < 1 {
+		n = 1
 	}
+	c.workers = n
+}
+
+// SetUpdateRetries sets how often a failed final status update is retried (default 3),
+// waiting backoff before the first retry and doubling it for each further one.
+// Messages whose update still fails are nacked and redelivered.
+func (c *ResultConsumer) SetUpdateRetries(retries int, backoff time.Duration) {
+	c.updateRetries = max(retries, 0)
+	c.updateBackoff = backoff
+}
+
+// SetRequeueDelay sets how long a message whose update kept failing is held before it is
+// nacked (default 1s), doubled for each further message nacked in a row up to maxDelay (default 1m)
+func (c *ResultConsumer) SetRequeueDelay(delay, maxDelay time.Duration) {
+	c.requeueDelay = max(delay, 0)
+	c.maxRequeueDelay = max(maxDelay, c.requeueDelay)
+}
+
+// SetObserver sets the observer of consumer starts and stops. Must be called before Start.
+func (c *ResultConsumer) SetObserver(observer ConsumerObserver) {
+	c.observer = observer
 }
 
 // Start starts consuming from happy-end and error-end queues
 func (c *ResultConsumer) Start(ctx context.Context) error {
-	slog.Info("Starting result consumer for end queues")
+	if c.terminal.HappyEnd == "" || c.terminal.ErrorEnd == "" {
+		return fmt.Errorf("terminal queue names must not be empty")
+	}
+
+	slog.Info("Starting result consumer for end queues", "happyEnd", c.terminal.HappyEnd, "errorEnd", c.terminal.ErrorEnd)
 
 	// Start consumer for happy-end queue
-	go c.consumeQueue(ctx, "happy-end", types.EnvelopeStatusSucceeded)
+	go c.consumeQueue(ctx, c.terminal.HappyEnd, types.EnvelopeStatusSucceeded)
 
 	// Start consumer for error-end queue
-	go c.consumeQueue(ctx, "error-end", types.EnvelopeStatusFailed)
+	go c.consumeQueue(ctx, c.terminal.ErrorEnd, types.EnvelopeStatusFailed)
 
 	return nil
 }
 
 // consumeQueue consumes envelopes from a specific queue and updates envelope status
 func (c *ResultConsumer) consumeQueue(ctx context.Context, queueName string, status types.EnvelopeStatus) {
-	slog.Info("Starting consumer", "queue", queueName)
+	slog.Info("Starting consumer", "queue", queueName, "workers", c.workers)
+	if c.observer != nil {
+		c.observer.ConsumerStarted(queueName)
+		defer c.observer.ConsumerStopped(queueName)
+	}
+
+	// Back off exponentially while the queue is empty or failing, reset on success
+	backoff := time.Duration(0)
+
+	// Bounded worker pool: a slot is taken before receiving, so no more than
+	// c.workers messages are held at once; wait for them before returning
+	slots := make(chan struct{}, c.workers)
+	var wg sync.WaitGroup
+	defer wg.Wait()
 
 	for {
 		select {
 		case <-ctx.Done():
 			slog.Info("Stopping consumer", "queue", queueName)
 			return
-		default:
+		case slots <- struct{}{}:
 			// Receive envelope from queue (blocks until envelope available or context cancelled)
 			msg, err := c.queueClient.Receive(ctx, queueName)
 			if err != nil {
+				<-slots
 				// Check if context was cancelled
 				if ctx.Err() != nil {
 					return
 				}
-				slog.Error("Error receiving from queue", "queue", queueName, "error", err)
+				if !errors.Is(err, queue.ErrNoEnvelope) {
+					slog.Error("Error receiving from queue", "queue", queueName, "error", err)
+				}
+
+				backoff = c.nextBackoff(backoff)
+				select {
+				case <-time.After(backoff):
+				case <-ctx.Done():
+					slog.Info("Stopping consumer", "queue", queueName)
+					return
+				}
 				continue
 			}
 
-			slog.Debug("Received envelope", "queue", queueName, "body", string(msg.Body()[:min(len(msg.Body()), 200)]))
+			backoff = 0
+
+			slog.Debug("Received envelope", "queue", queueName, "body", logging.Payload(msg.Body()))
 
-			// Process the envelope
-			c.processMessage(ctx, msg, status)
+			// Process the envelope; each message is acked individually on the channel it came from
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-slots
+					wg.Done()
+				}()
+				c.processMessage(ctx, msg, status)
+			}()
 		}
 	}
 }
 
-// processMessage processes a envelope and updates the envelope status
+// nextBackoff doubles the previous back-off, starting at minBackoff and capped at maxBackoff
+func (c *ResultConsumer) nextBackoff(previous time.Duration) time.Duration {
+	if previous <= 0 {
+		return c.minBackoff
+	}
+	return min(previous*2, c.maxBackoff)
+}
+
+// processMessage updates the envelope status from a terminal message. The message is acked
+// once the update is stored (or can never be), and nacked for redelivery when the store keeps
+// failing, after the requeue delay.
 func (c *ResultConsumer) processMessage(ctx context.Context, msg queue.QueueMessage, status types.EnvelopeStatus) {
-	defer func() {
-		if err := c.queueClient.Ack(ctx, msg); err != nil {
-			slog.Error("Failed to ack envelope", "error", err)
+	if err := c.storeFinalStatus(ctx, msg, status); err != nil {
+		delay := c.nextRequeueDelay()
+		slog.Error("Failed to store final status, requeueing terminal message", "error", err, "delay", delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+		if err := c.queueClient.Nack(ctx, msg); err != nil {
+			slog.Error("Failed to nack envelope", "error", err)
 		}
-	}()
-
-	slog.Debug("Processing envelope", "status", status)
-
-	// Parse the envelope to extract envelope ID, result, and error (flat format)
-	var parsedMsg struct {
-		ID      string `json:"id"`
-		Error   string `json:"error,omitempty"`
-		Details struct {
-			Message   string `json:"message,omitempty"`
-			Type      string `json:"type,omitempty"`
-			Traceback string `json:"traceback,omitempty"`
-		} `json:"details,omitempty"`
-		Route struct {
-			Actors   []string               `json:"actors"`
-			Current  int                    `json:"current"`
-			Metadata map[string]interface{} `json:"metadata"`
-		} `json:"route"`
-		Payload map[string]interface{} `json:"payload"` // Result payload
-	}
-
-	if err := json.Unmarshal(msg.Body(), &parsedMsg); err != nil {
-		slog.Error("Failed to parse envelope", "error", err)
 		return
 	}
+	c.requeueFailures.Store(0)
 
-	// Extract envelope ID - try top-level first, then route metadata
-	envelopeID := parsedMsg.ID
-	if envelopeID == "" && parsedMsg.Route.Metadata != nil {
-		if id, ok := parsedMsg.Route.Metadata["job_id"].(string); ok {
-			envelopeID = id
-		}
+	if err := c.queueClient.Ack(ctx, msg); err != nil {
+		slog.Error("Failed to ack envelope", "error", err)
 	}
+}
 
-	if envelopeID == "" {
-		slog.Error("No envelope ID found, skipping", "body", string(msg.Body()[:min(len(msg.Body()), 200)]))
-		return
+// nextRequeueDelay counts a message nacked in a row and returns how long to hold it:
+// requeueDelay, doubled for each message nacked before it, capped at maxRequeueDelay
+func (c *ResultConsumer) nextRequeueDelay() time.Duration {
+	failures := c.requeueFailures.Add(1)
+	delay := c.requeueDelay
+	for i := int32(1); i < failures && delay < c.maxRequeueDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, c.maxRequeueDelay)
+}
+
+// storeFinalStatus parses a terminal message and stores the envelope's final status.
+// Malformed messages and unknown envelopes are logged and skipped (nil error),
+// since redelivering them cannot succeed.
+func (c *ResultConsumer) storeFinalStatus(ctx context.Context, msg queue.QueueMessage, status types.EnvelopeStatus) error {
+	slog.Debug("Processing envelope", "status", status)
+
+	terminal, err := parseTerminalMessage(msg.Body())
+	if err != nil {
+		slog.Error("Failed to parse terminal message, skipping", "error", err, "body", logging.Payload(msg.Body()))
+		return nil
 	}
 
-	slog.Debug("Extracted envelope ID", "id", envelopeID)
+	envelopeID := terminal.ID
 
 	// Extract result payload
-	var result interface{} = parsedMsg.Payload
-	if parsedMsg.Payload == nil {
+	result := terminal.Payload
+	if result == nil {
 		result = map[string]interface{}{}
 	}
 
+	// Prefer the completion time recorded by the sidecar over the time we consumed the message
+	timestamp := time.Now()
+	if !terminal.CompletedAt.IsZero() {
+		timestamp = terminal.CompletedAt
+	}
+
 	// Update envelope status
 	update := types.EnvelopeUpdate{
 		ID:        envelopeID,
 		Status:    status,
 		Result:    result,
-		Timestamp: time.Now(),
+		Timestamp: timestamp,
 	}
 
 	if status == types.EnvelopeStatusSucceeded {
@@ -135,25 +266,77 @@ func (c *ResultConsumer) processMessage(ctx context.Context, msg queue.QueueMess
 		slog.Debug("Marking envelope as Succeeded", "id", envelopeID)
 	} else {
 		update.Message = "Envelope failed"
-		// Extract error from top level (flat format)
-		if parsedMsg.Error != "" {
-			update.Error = parsedMsg.Error
-			// Include error details if available
-			if parsedMsg.Details.Message != "" {
-				update.Error = fmt.Sprintf("%s: %s", parsedMsg.Error, parsedMsg.Details.Message)
-			}
+		update.Error = terminal.Error
+		// Include error details if available
+		if terminal.Error != "" && terminal.Details != nil && terminal.Details.Message != "" {
+			update.Error = fmt.Sprintf("%s: %s", terminal.Error, terminal.Details.Message)
 		}
 		slog.Debug("Marking envelope as Failed", "id", envelopeID, "error", update.Error)
 	}
 
-	slog.Debug("Updating envelope with final status", "id", envelopeID, "status", status, "result", result)
+	slog.Debug("Updating envelope with final status", "id", envelopeID, "status", status, "result", logging.Payload(result))
 
-	if err := c.jobStore.Update(update); err != nil {
-		slog.Error("Failed to update envelope", "id", envelopeID, "error", err)
-		return
+	if err := c.updateWithRetry(ctx, update); err != nil {
+		if errors.Is(err, envelopestore.ErrNotFound) {
+			slog.Error("Envelope of terminal message not found, skipping", "id", envelopeID, "error", err)
+			return nil
+		}
+		return fmt.Errorf("failed to update envelope %s: %w", envelopeID, err)
 	}
 
 	slog.Debug("Envelope successfully updated to final status", "id", envelopeID, "status", status)
 
-	slog.Info("Envelope marked as final status", "id", envelopeID, "status", status)
+	slog.Info("Envelope marked as final status", "id", envelopeID, "status", status, "tool", terminal.Tool)
+	return nil
+}
+
+// updateWithRetry stores an update, retrying failures other than unknown envelopes with exponential back-off
+func (c *ResultConsumer) updateWithRetry(ctx context.Context, update types.EnvelopeUpdate) error {
+	backoff := c.updateBackoff
+	for retry := 0; ; retry++ {
+		err := c.jobStore.Update(update)
+		if err == nil || errors.Is(err, envelopestore.ErrNotFound) || retry >= c.updateRetries {
+			return err
+		}
+
+		slog.Warn("Failed to update envelope, retrying", "id", update.ID, "retry", retry+1, "backoff", backoff, "error", err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return fmt.Errorf("%w (retry interrupted: %v)", err, ctx.Err())
+		}
+		backoff *= 2
+	}
+}
+
+// parseTerminalMessage parses a happy-end or error-end message.
+// Errors of legacy (unversioned) messages are read from the wrapped payload
+// ({"error", "details", "original_payload"}) when not set at the top level.
+func parseTerminalMessage(body []byte) (*types.TerminalMessage, error) {
+	var terminal types.TerminalMessage
+	if err := json.Unmarshal(body, &terminal); err != nil {
+		return nil, fmt.Errorf("invalid terminal message: %w", err)
+	}
+
+	id, err := types.ParseEnvelopeID(terminal.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid terminal message: %w", err)
+	}
+	terminal.ID = id
+
+	if terminal.Error == "" {
+		var wrapped struct {
+			Payload struct {
+				Error   string                      `json:"error"`
+				Details *types.TerminalErrorDetails `json:"details"`
+			} `json:"payload"`
+		}
+		// Payloads that are not objects carry no wrapped error
+		if err := json.Unmarshal(body, &wrapped); err == nil && wrapped.Payload.Error != "" {
+			terminal.Error = wrapped.Payload.Error
+			terminal.Details = wrapped.Payload.Details
+		}
+	}
+
+	return &terminal, nil
 }
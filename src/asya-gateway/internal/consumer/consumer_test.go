@@ -0,0 +1,571 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/deliveryhero/asya/asya-gateway/internal/config"
+	"github.com/deliveryhero/asya/asya-gateway/internal/envelopestore"
+	"github.com/deliveryhero/asya/asya-gateway/internal/queue"
+	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+)
+
+// emptyQueueClient always reports an empty queue and records Receive calls
+type emptyQueueClient struct {
+	mu       sync.Mutex
+	receives int
+	queues   map[string]bool
+	err      error
+}
+
+func (c *emptyQueueClient) SendEnvelope(ctx context.Context, envelope *types.Envelope) error {
+	return nil
+}
+
+func (c *emptyQueueClient) Receive(ctx context.Context, queueName string) (queue.QueueMessage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.receives++
+	if c.queues == nil {
+		c.queues = make(map[string]bool)
+	}
+	c.queues[queueName] = true
+	return nil, c.err
+}
+
+func (c *emptyQueueClient) Ack(ctx context.Context, msg queue.QueueMessage) error {
+	return nil
+}
+
+func (c *emptyQueueClient) Nack(ctx context.Context, msg queue.QueueMessage) error {
+	return nil
+}
+
+func (c *emptyQueueClient) Close() error {
+	return nil
+}
+
+func (c *emptyQueueClient) receiveCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.receives
+}
+
+func (c *emptyQueueClient) receivedFrom(queueName string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.queues[queueName]
+}
+
+func TestStart_TerminalQueues(t *testing.T) {
+	client := &emptyQueueClient{err: queue.ErrNoEnvelope}
+	c := NewResultConsumer(client, envelopestore.NewStore(), config.TerminalActors{HappyEnd: "done", ErrorEnd: "failed"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := c.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for !(client.receivedFrom("done") && client.receivedFrom("failed")) {
+		if time.Now().After(deadline) {
+			t.Fatal("consumer did not poll the configured terminal queues")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if client.receivedFrom("happy-end") || client.receivedFrom("error-end") {
+		t.Error("consumer polled default terminal queues despite configured names")
+	}
+}
+
+func TestStart_EmptyTerminalQueue(t *testing.T) {
+	c := NewResultConsumer(&emptyQueueClient{}, envelopestore.NewStore(), config.TerminalActors{HappyEnd: "happy-end"})
+	if err := c.Start(context.Background()); err == nil {
+		t.Error("Start() expected error for empty error-end queue name")
+	}
+}
+
+func TestNextBackoff(t *testing.T) {
+	c := &ResultConsumer{minBackoff: 100 * time.Millisecond, maxBackoff: time.Second}
+
+	tests := []struct {
+		previous time.Duration
+		want     time.Duration
+	}{
+		{previous: 0, want: 100 * time.Millisecond},
+		{previous: 100 * time.Millisecond, want: 200 * time.Millisecond},
+		{previous: 400 * time.Millisecond, want: 800 * time.Millisecond},
+		{previous: 800 * time.Millisecond, want: time.Second},
+		{previous: time.Second, want: time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := c.nextBackoff(tt.previous); got != tt.want {
+			t.Errorf("nextBackoff(%v) = %v, want %v", tt.previous, got, tt.want)
+		}
+	}
+}
+
+func TestConsumeQueue_BacksOffWhenIdle(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "empty queue", err: queue.ErrNoEnvelope},
+		{name: "receive error", err: errors.New("connection lost")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &emptyQueueClient{err: tt.err}
+			c := NewResultConsumer(client, envelopestore.NewStore(), config.TerminalActorsFromEnv())
+			c.minBackoff = 10 * time.Millisecond
+			c.maxBackoff = 40 * time.Millisecond
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+
+			done := make(chan struct{})
+			go func() {
+				c.consumeQueue(ctx, "happy-end", types.EnvelopeStatusSucceeded)
+				close(done)
+			}()
+
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("consumeQueue did not stop after context cancellation")
+			}
+
+			// 10+20+40+40+40+... ms within 200ms allows only a handful of polls
+			if got := client.receiveCount(); got == 0 || got > 10 {
+				t.Errorf("Receive called %d times, want between 1 and 10", got)
+			}
+		})
+	}
+}
+
+// countingObserver tracks running consumers per queue
+type countingObserver struct {
+	mu     sync.Mutex
+	active map[string]int
+}
+
+func (o *countingObserver) ConsumerStarted(queue string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.active[queue]++
+}
+
+func (o *countingObserver) ConsumerStopped(queue string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.active[queue]--
+}
+
+func (o *countingObserver) count(queue string) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.active[queue]
+}
+
+func TestConsumeQueue_ObservesActiveConsumers(t *testing.T) {
+	observer := &countingObserver{active: make(map[string]int)}
+	c := NewResultConsumer(&emptyQueueClient{err: queue.ErrNoEnvelope}, envelopestore.NewStore(), config.TerminalActorsFromEnv())
+	c.SetObserver(observer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.consumeQueue(ctx, "happy-end", types.EnvelopeStatusSucceeded)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for observer.count("happy-end") != 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := observer.count("happy-end"); got != 1 {
+		t.Fatalf("active consumers = %d while running, want 1", got)
+	}
+
+	cancel()
+	<-done
+	if got := observer.count("happy-end"); got != 0 {
+		t.Errorf("active consumers = %d after stop, want 0", got)
+	}
+}
+
+// bodyMessage is a queue message with a fixed body
+type bodyMessage []byte
+
+func (m bodyMessage) Body() []byte        { return m }
+func (m bodyMessage) DeliveryTag() uint64 { return 1 }
+
+func TestProcessMessage_TerminalCompletedAt(t *testing.T) {
+	store := envelopestore.NewStore()
+	if err := store.Create(&types.Envelope{ID: "env-1", Status: types.EnvelopeStatusRunning}); err != nil {
+		t.Fatalf("Failed to create envelope: %v", err)
+	}
+
+	c := NewResultConsumer(&emptyQueueClient{}, store, config.TerminalActorsFromEnv())
+	body := `{"id":"env-1","version":1,"status":"succeeded","completed_at":"2025-11-18T12:01:30Z","route":{"actors":["a"],"current":1},"payload":{"answer":42}}`
+	c.processMessage(context.Background(), bodyMessage(body), types.EnvelopeStatusSucceeded)
+
+	envelope, err := store.Get("env-1")
+	if err != nil {
+		t.Fatalf("Envelope not found: %v", err)
+	}
+	if envelope.Status != types.EnvelopeStatusSucceeded {
+		t.Errorf("Status = %v, want %v", envelope.Status, types.EnvelopeStatusSucceeded)
+	}
+
+	want := time.Date(2025, 11, 18, 12, 1, 30, 0, time.UTC)
+	if !envelope.UpdatedAt.Equal(want) {
+		t.Errorf("UpdatedAt = %v, want completed_at %v", envelope.UpdatedAt, want)
+	}
+}
+
+func TestParseTerminalMessage(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantID      string
+		wantVersion int
+		wantError   string
+		wantDetails string
+		wantPayload bool
+		expectError bool
+	}{
+		{
+			name:        "happy v1",
+			body:        `{"id":"env-1","version":1,"status":"succeeded","completed_at":"2025-11-18T12:01:30Z","route":{"actors":["a"],"current":1},"payload":{"answer":42}}`,
+			wantID:      "env-1",
+			wantVersion: 1,
+			wantPayload: true,
+		},
+		{
+			name:        "error v1",
+			body:        `{"id":"env-2","version":1,"status":"failed","error":"boom","details":{"message":"bad input","type":"ValueError"},"route":{"actors":["a"],"current":0},"payload":{"error":"boom"}}`,
+			wantID:      "env-2",
+			wantVersion: 1,
+			wantError:   "boom",
+			wantDetails: "bad input",
+			wantPayload: true,
+		},
+		{
+			name:        "legacy error wrapped in payload",
+			body:        `{"id":"env-3","route":{"actors":["a"],"current":0},"payload":{"error":"Runtime timeout exceeded","details":{"message":"after 5m"},"original_payload":{"x":1}}}`,
+			wantID:      "env-3",
+			wantError:   "Runtime timeout exceeded",
+			wantDetails: "after 5m",
+			wantPayload: true,
+		},
+		{
+			name:        "legacy happy envelope",
+			body:        `{"id":"env-4","route":{"actors":["a"],"current":1},"payload":{"answer":1}}`,
+			wantID:      "env-4",
+			wantPayload: true,
+		},
+		{
+			name:   "legacy happy envelope with array result",
+			body:   `{"id":"env-5","route":{"actors":["a"],"current":1},"payload":[1,2]}`,
+			wantID: "env-5",
+		},
+		{
+			name:        "ID only in route metadata",
+			body:        `{"route":{"actors":["a"],"current":1,"metadata":{"job_id":"env-6"}},"payload":{}}`,
+			expectError: true,
+		},
+		{
+			name:        "invalid json",
+			body:        `{not json`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			terminal, err := parseTerminalMessage([]byte(tt.body))
+			if (err != nil) != tt.expectError {
+				t.Fatalf("parseTerminalMessage() error = %v, expectError %v", err, tt.expectError)
+			}
+			if tt.expectError {
+				return
+			}
+
+			if terminal.ID != tt.wantID {
+				t.Errorf("ID = %q, want %q", terminal.ID, tt.wantID)
+			}
+			if terminal.Version != tt.wantVersion {
+				t.Errorf("Version = %d, want %d", terminal.Version, tt.wantVersion)
+			}
+			if terminal.Error != tt.wantError {
+				t.Errorf("Error = %q, want %q", terminal.Error, tt.wantError)
+			}
+			if tt.wantDetails != "" && (terminal.Details == nil || terminal.Details.Message != tt.wantDetails) {
+				t.Errorf("Details = %+v, want message %q", terminal.Details, tt.wantDetails)
+			}
+			if _, isMap := terminal.Payload.(map[string]any); isMap != tt.wantPayload {
+				t.Errorf("Payload = %v (map: %v), want map: %v", terminal.Payload, isMap, tt.wantPayload)
+			}
+		})
+	}
+}
+
+func TestProcessMessage_LegacyErrorWrapped(t *testing.T) {
+	store := envelopestore.NewStore()
+	if err := store.Create(&types.Envelope{ID: "env-1", Status: types.EnvelopeStatusRunning}); err != nil {
+		t.Fatalf("Failed to create envelope: %v", err)
+	}
+
+	c := NewResultConsumer(&emptyQueueClient{}, store, config.TerminalActorsFromEnv())
+	body := `{"id":"env-1","route":{"actors":["a"],"current":0},"payload":{"error":"processing_error","details":{"message":"Invalid input"}}}`
+	c.processMessage(context.Background(), bodyMessage(body), types.EnvelopeStatusFailed)
+
+	envelope, err := store.Get("env-1")
+	if err != nil {
+		t.Fatalf("Envelope not found: %v", err)
+	}
+	if envelope.Status != types.EnvelopeStatusFailed {
+		t.Errorf("Status = %v, want %v", envelope.Status, types.EnvelopeStatusFailed)
+	}
+	if envelope.Error != "processing_error: Invalid input" {
+		t.Errorf("Error = %q, want %q", envelope.Error, "processing_error: Invalid input")
+	}
+}
+
+// listQueueClient delivers a fixed list of messages, then reports an empty queue
+type listQueueClient struct {
+	emptyQueueClient
+	messages []queue.QueueMessage
+	acks     int
+	nacks    int
+}
+
+func (c *listQueueClient) Receive(ctx context.Context, queueName string) (queue.QueueMessage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.messages) == 0 {
+		return nil, queue.ErrNoEnvelope
+	}
+	msg := c.messages[0]
+	c.messages = c.messages[1:]
+	return msg, nil
+}
+
+func (c *listQueueClient) Ack(ctx context.Context, msg queue.QueueMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.acks++
+	return nil
+}
+
+func (c *listQueueClient) Nack(ctx context.Context, msg queue.QueueMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nacks++
+	return nil
+}
+
+func (c *listQueueClient) nackCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.nacks
+}
+
+func (c *listQueueClient) ackCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.acks
+}
+
+// blockingStore holds Update calls until released and records the peak concurrency
+type blockingStore struct {
+	envelopestore.EnvelopeStore
+	release chan struct{}
+	mu      sync.Mutex
+	active  int
+	peak    int
+}
+
+func (s *blockingStore) Update(update types.EnvelopeUpdate) error {
+	s.mu.Lock()
+	s.active++
+	s.peak = max(s.peak, s.active)
+	s.mu.Unlock()
+
+	<-s.release
+
+	s.mu.Lock()
+	s.active--
+	s.mu.Unlock()
+	return s.EnvelopeStore.Update(update)
+}
+
+func (s *blockingStore) peakConcurrency() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.peak
+}
+
+func TestConsumeQueue_Workers(t *testing.T) {
+	tests := []struct {
+		name     string
+		workers  int
+		messages int
+		wantPeak int
+	}{
+		{name: "sequential by default", workers: 0, messages: 3, wantPeak: 1},
+		{name: "bounded by workers", workers: 2, messages: 5, wantPeak: 2},
+		{name: "fewer messages than workers", workers: 4, messages: 3, wantPeak: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &listQueueClient{}
+			for i := 0; i < tt.messages; i++ {
+				client.messages = append(client.messages, bodyMessage(fmt.Sprintf(`{"id":"env-%d","route":{"actors":["a"],"current":1},"payload":{}}`, i)))
+			}
+			store := &blockingStore{EnvelopeStore: envelopestore.NewStore(), release: make(chan struct{})}
+
+			c := NewResultConsumer(client, store, config.TerminalActorsFromEnv())
+			c.SetWorkers(tt.workers)
+			c.minBackoff = 5 * time.Millisecond
+			c.maxBackoff = 5 * time.Millisecond
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				c.consumeQueue(ctx, "happy-end", types.EnvelopeStatusSucceeded)
+				close(done)
+			}()
+
+			// Release one update at a time once the workers are saturated
+			for i := 0; i < tt.messages; i++ {
+				time.Sleep(20 * time.Millisecond)
+				store.release <- struct{}{}
+			}
+			deadline := time.Now().Add(time.Second)
+			for client.ackCount() < tt.messages && time.Now().Before(deadline) {
+				time.Sleep(5 * time.Millisecond)
+			}
+			cancel()
+			<-done
+
+			if got := client.ackCount(); got != tt.messages {
+				t.Errorf("acked %d messages, want %d", got, tt.messages)
+			}
+			if got := store.peakConcurrency(); got != tt.wantPeak {
+				t.Errorf("peak concurrent updates = %d, want %d", got, tt.wantPeak)
+			}
+		})
+	}
+}
+
+// failingStore fails the first failures Update calls with err
+type failingStore struct {
+	envelopestore.EnvelopeStore
+	failures int
+	err      error
+	calls    int
+}
+
+func (s *failingStore) Update(update types.EnvelopeUpdate) error {
+	s.calls++
+	if s.calls <= s.failures {
+		return s.err
+	}
+	return s.EnvelopeStore.Update(update)
+}
+
+func TestProcessMessage_UpdateRetry(t *testing.T) {
+	dbErr := errors.New("connection reset")
+	tests := []struct {
+		name       string
+		id         string
+		failures   int
+		err        error
+		wantCalls  int
+		wantAcks   int
+		wantNacks  int
+		wantStatus types.EnvelopeStatus
+	}{
+		{name: "stored first time", id: "env-1", wantCalls: 1, wantAcks: 1, wantStatus: types.EnvelopeStatusSucceeded},
+		{name: "transient failure", id: "env-1", failures: 2, err: dbErr, wantCalls: 3, wantAcks: 1, wantStatus: types.EnvelopeStatusSucceeded},
+		{name: "persistent failure", id: "env-1", failures: 10, err: dbErr, wantCalls: 4, wantNacks: 1, wantStatus: types.EnvelopeStatusPending},
+		{name: "unknown envelope", id: "env-unknown", wantCalls: 1, wantAcks: 1, wantStatus: types.EnvelopeStatusPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memory := envelopestore.NewStore()
+			if err := memory.Create(&types.Envelope{ID: "env-1"}); err != nil {
+				t.Fatalf("Failed to create envelope: %v", err)
+			}
+			store := &failingStore{EnvelopeStore: memory, failures: tt.failures, err: tt.err}
+			client := &listQueueClient{}
+
+			c := NewResultConsumer(client, store, config.TerminalActorsFromEnv())
+			c.SetUpdateRetries(3, time.Millisecond)
+			c.SetRequeueDelay(time.Millisecond, time.Millisecond)
+			body := fmt.Sprintf(`{"id":%q,"route":{"actors":["a"],"current":1},"payload":{"ok":true}}`, tt.id)
+			c.processMessage(context.Background(), bodyMessage(body), types.EnvelopeStatusSucceeded)
+
+			if store.calls != tt.wantCalls {
+				t.Errorf("Update calls = %d, want %d", store.calls, tt.wantCalls)
+			}
+			if client.ackCount() != tt.wantAcks || client.nackCount() != tt.wantNacks {
+				t.Errorf("acks = %d, nacks = %d, want %d and %d", client.ackCount(), client.nackCount(), tt.wantAcks, tt.wantNacks)
+			}
+			envelope, err := memory.Get("env-1")
+			if err != nil {
+				t.Fatalf("Envelope not found: %v", err)
+			}
+			if envelope.Status != tt.wantStatus {
+				t.Errorf("Status = %v, want %v", envelope.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestProcessMessage_RequeueDelay(t *testing.T) {
+	memory := envelopestore.NewStore()
+	if err := memory.Create(&types.Envelope{ID: "env-1"}); err != nil {
+		t.Fatalf("Failed to create envelope: %v", err)
+	}
+	store := &failingStore{EnvelopeStore: memory, failures: 2, err: errors.New("connection reset")}
+	client := &listQueueClient{}
+
+	c := NewResultConsumer(client, store, config.TerminalActorsFromEnv())
+	c.SetUpdateRetries(0, 0)
+	c.SetRequeueDelay(20*time.Millisecond, 30*time.Millisecond)
+	body := `{"id":"env-1","route":{"actors":["a"],"current":1},"payload":{"ok":true}}`
+
+	// Requeued messages are held for the delay, doubled while updates keep failing
+	for i, want := range []time.Duration{20 * time.Millisecond, 30 * time.Millisecond} {
+		start := time.Now()
+		c.processMessage(context.Background(), bodyMessage(body), types.EnvelopeStatusSucceeded)
+		if elapsed := time.Since(start); elapsed < want {
+			t.Errorf("Requeue %d held the message for %v, want at least %v", i+1, elapsed, want)
+		}
+	}
+	if client.nackCount() != 2 {
+		t.Fatalf("nacks = %d, want 2", client.nackCount())
+	}
+
+	// A stored update resets the delay
+	c.processMessage(context.Background(), bodyMessage(body), types.EnvelopeStatusSucceeded)
+	if client.ackCount() != 1 {
+		t.Fatalf("acks = %d, want 1", client.ackCount())
+	}
+	if got := c.nextRequeueDelay(); got != 20*time.Millisecond {
+		t.Errorf("Requeue delay after a stored update = %v, want 20ms", got)
+	}
+}
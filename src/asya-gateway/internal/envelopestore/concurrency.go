@@ -0,0 +1,139 @@
+package envelopestore
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+)
+
+// ErrTooManyActive is returned by ConcurrencyLimiter.Create when the gateway already has its
+// maximum number of active envelopes; the call should be retried later
+var ErrTooManyActive = errors.New("too many active envelopes")
+
+// ConcurrencyLimiter wraps an envelope store and rejects new envelopes with ErrTooManyActive
+// while maxActive envelopes are active (not in a final status). Fanout children count as part
+// of their parent and are never rejected.
+//
+// Envelopes are counted when created and released as the TransitionObserver of the base store
+// when they first reach a final status (including timeouts). Replicas sharing a store each
+// count the envelopes they created, so the limit applies per replica; envelopes finished
+// through another replica are released once the limit is reached and the store reports
+// them no longer active.
+type ConcurrencyLimiter struct {
+	EnvelopeStore
+	maxActive int
+
+	mu       sync.Mutex
+	active   map[string]struct{} // Active envelope IDs
+	creating map[string]struct{} // Envelopes counted but not stored yet
+}
+
+// NewConcurrencyLimiter wraps store so at most maxActive envelopes are active, counting the
+// active envelopes already in store. The ConcurrencyLimiter must also observe the transitions
+// of the base store (SetTransitionObserver).
+func NewConcurrencyLimiter(store EnvelopeStore, maxActive int) (*ConcurrencyLimiter, error) {
+	l := &ConcurrencyLimiter{
+		EnvelopeStore: store,
+		maxActive:     maxActive,
+		active:        make(map[string]struct{}),
+		creating:      make(map[string]struct{}),
+	}
+
+	ids, err := store.ListActive(EnvelopeFilter{Roots: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count active envelopes: %w", err)
+	}
+	for _, id := range ids {
+		l.active[id] = struct{}{}
+	}
+	return l, nil
+}
+
+// Create creates the envelope unless the limit of active envelopes is reached
+func (l *ConcurrencyLimiter) Create(envelope *types.Envelope) error {
+	if envelope.ParentID != nil {
+		return l.EnvelopeStore.Create(envelope)
+	}
+
+	if !l.reserve(envelope.ID) {
+		l.release(l.finished()...)
+		if !l.reserve(envelope.ID) {
+			return fmt.Errorf("%w (limit %d)", ErrTooManyActive, l.maxActive)
+		}
+	}
+
+	err := l.EnvelopeStore.Create(envelope)
+
+	l.mu.Lock()
+	delete(l.creating, envelope.ID)
+	l.mu.Unlock()
+	if err != nil {
+		l.release(envelope.ID)
+		return err
+	}
+	return nil
+}
+
+// StatusChanged releases an envelope when it first reaches a final status
+func (l *ConcurrencyLimiter) StatusChanged(transition Transition) {
+	if transition.Completed() {
+		l.release(transition.Envelope.ID)
+	}
+}
+
+// Active returns the number of active envelopes counted
+func (l *ConcurrencyLimiter) Active() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.active)
+}
+
+// reserve counts envelope id, about to be created, if the limit is not reached
+func (l *ConcurrencyLimiter) reserve(id string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.maxActive > 0 && len(l.active) >= l.maxActive {
+		return false
+	}
+	l.active[id] = struct{}{}
+	l.creating[id] = struct{}{}
+	return true
+}
+
+func (l *ConcurrencyLimiter) release(ids ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, id := range ids {
+		delete(l.active, id)
+	}
+}
+
+// finished returns the envelopes counted that the store no longer reports active, e.g.
+// finished through another replica. The store is queried without holding the lock, since
+// the in-memory store reports transitions to StatusChanged with its own lock held.
+func (l *ConcurrencyLimiter) finished() []string {
+	l.mu.Lock()
+	ids := make([]string, 0, len(l.active))
+	for id := range l.active {
+		if _, creating := l.creating[id]; !creating {
+			ids = append(ids, id)
+		}
+	}
+	l.mu.Unlock()
+
+	var finished []string
+	for _, id := range ids {
+		if !l.EnvelopeStore.IsActive(id) {
+			finished = append(finished, id)
+		}
+	}
+	if len(finished) > 0 {
+		slog.Debug("Released envelopes finished outside this gateway", "count", len(finished))
+	}
+	return finished
+}
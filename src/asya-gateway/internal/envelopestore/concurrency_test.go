@@ -0,0 +1,132 @@
+package envelopestore
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+)
+
+func newLimitedStore(t *testing.T, base *Store, maxActive int) *ConcurrencyLimiter {
+	t.Helper()
+	limiter, err := NewConcurrencyLimiter(base, maxActive)
+	if err != nil {
+		t.Fatalf("NewConcurrencyLimiter() error = %v", err)
+	}
+	base.SetTransitionObserver(limiter)
+	return limiter
+}
+
+func createEnvelope(store EnvelopeStore, id string) error {
+	return store.Create(&types.Envelope{ID: id, Tool: "summarize", Route: types.Route{Actors: []string{"prep"}}})
+}
+
+func TestConcurrencyLimiter_Create(t *testing.T) {
+	base := NewStore()
+	limiter := newLimitedStore(t, base, 2)
+
+	for _, id := range []string{"env-1", "env-2"} {
+		if err := createEnvelope(limiter, id); err != nil {
+			t.Fatalf("Create(%s) error = %v", id, err)
+		}
+	}
+	if err := createEnvelope(limiter, "env-3"); !errors.Is(err, ErrTooManyActive) {
+		t.Fatalf("Create over the limit error = %v, want ErrTooManyActive", err)
+	}
+	if _, err := base.Get("env-3"); err == nil {
+		t.Error("Rejected envelope was stored")
+	}
+
+	// Fanout children run as part of their parent
+	parentID := "env-1"
+	if err := limiter.Create(&types.Envelope{ID: "env-1-1", ParentID: &parentID, Route: types.Route{Actors: []string{"prep"}}}); err != nil {
+		t.Fatalf("Create(child) error = %v", err)
+	}
+
+	// Running envelopes still count, a final status releases them once
+	if err := base.Update(types.EnvelopeUpdate{ID: "env-1", Status: types.EnvelopeStatusRunning, Timestamp: time.Now()}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got := limiter.Active(); got != 2 {
+		t.Errorf("Active() with running envelope = %d, want 2", got)
+	}
+	for range 2 {
+		if err := base.Update(types.EnvelopeUpdate{ID: "env-1", Status: types.EnvelopeStatusSucceeded, Timestamp: time.Now()}); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+	}
+	if got := limiter.Active(); got != 1 {
+		t.Errorf("Active() after completion = %d, want 1", got)
+	}
+	if err := createEnvelope(limiter, "env-3"); err != nil {
+		t.Fatalf("Create after completion error = %v", err)
+	}
+}
+
+func TestConcurrencyLimiter_Limits(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxActive int
+		want      int // Envelopes accepted out of 5
+	}{
+		{name: "unlimited", maxActive: 0, want: 5},
+		{name: "limited", maxActive: 3, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := newLimitedStore(t, NewStore(), tt.maxActive)
+			accepted := 0
+			for _, id := range []string{"env-1", "env-2", "env-3", "env-4", "env-5"} {
+				if err := createEnvelope(limiter, id); err == nil {
+					accepted++
+				}
+			}
+			if accepted != tt.want {
+				t.Errorf("accepted %d envelopes, want %d", accepted, tt.want)
+			}
+		})
+	}
+}
+
+// TestConcurrencyLimiter_CountsStoredEnvelopes tests that active envelopes already in the store
+// count after a restart, and that envelopes finished without a transition seen by this
+// limiter (e.g. through another replica) are released once the limit is reached
+func TestConcurrencyLimiter_CountsStoredEnvelopes(t *testing.T) {
+	base := NewStore()
+	for _, id := range []string{"env-1", "env-2", "env-done"} {
+		if err := createEnvelope(base, id); err != nil {
+			t.Fatalf("Create(%s) error = %v", id, err)
+		}
+	}
+	if err := base.Update(types.EnvelopeUpdate{ID: "env-done", Status: types.EnvelopeStatusFailed, Timestamp: time.Now()}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	parentID := "env-1"
+	if err := base.Create(&types.Envelope{ID: "env-1-1", ParentID: &parentID, Route: types.Route{Actors: []string{"prep"}}}); err != nil {
+		t.Fatalf("Create(child) error = %v", err)
+	}
+
+	limiter, err := NewConcurrencyLimiter(base, 2)
+	if err != nil {
+		t.Fatalf("NewConcurrencyLimiter() error = %v", err)
+	}
+	if got := limiter.Active(); got != 2 {
+		t.Fatalf("Active() = %d, want 2 (active root envelopes)", got)
+	}
+	if err := createEnvelope(limiter, "env-3"); !errors.Is(err, ErrTooManyActive) {
+		t.Fatalf("Create over the limit error = %v, want ErrTooManyActive", err)
+	}
+
+	// The limiter does not observe the base store here
+	if err := base.Update(types.EnvelopeUpdate{ID: "env-2", Status: types.EnvelopeStatusSucceeded, Timestamp: time.Now()}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := createEnvelope(limiter, "env-3"); err != nil {
+		t.Fatalf("Create after an unobserved completion error = %v", err)
+	}
+	if got := limiter.Active(); got != 2 {
+		t.Errorf("Active() = %d, want 2", got)
+	}
+}
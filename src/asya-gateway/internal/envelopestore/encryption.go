@@ -0,0 +1,173 @@
+package envelopestore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// encryptedMarker is the JSON key identifying a stored value as ciphertext.
+// Encrypted values are stored as {"asya_encrypted": "<key version>", "ciphertext": "<base64>"},
+// so they remain valid JSONB and plaintext values written before encryption was enabled stay readable.
+const encryptedMarker = "asya_encrypted"
+
+// encryptedValue is what an encrypted payload or result is stored as
+type encryptedValue struct {
+	Version    string `json:"asya_encrypted"` // Version of the key the value was encrypted with
+	Ciphertext []byte `json:"ciphertext"`     // Nonce followed by the sealed JSON
+}
+
+// Cipher encrypts envelope payloads and results at rest with AES-256-GCM.
+// New values are encrypted with the current key; every configured key decrypts,
+// so keys can be rotated by prepending a new one and dropping the old one once
+// nothing encrypted with it is retained.
+type Cipher struct {
+	current string                 // Version of the key used for encryption
+	keys    map[string]cipher.AEAD // Key version -> AEAD
+}
+
+// ParseCipher parses a key list of the form "version:base64key[,version:base64key...]"
+// (ASYA_ENCRYPTION_KEY). The first key encrypts new values. Keys are 32 bytes (AES-256).
+func ParseCipher(spec string) (*Cipher, error) {
+	c := &Cipher{keys: make(map[string]cipher.AEAD)}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		version, encoded, ok := strings.Cut(entry, ":")
+		if !ok || version == "" {
+			return nil, fmt.Errorf("encryption key must be version:base64key, got %q", redactKey(entry))
+		}
+		if _, exists := c.keys[version]; exists {
+			return nil, fmt.Errorf("duplicate encryption key version %q", version)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q is not valid base64: %w", version, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("encryption key %q must be 32 bytes, got %d", version, len(key))
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", version, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", version, err)
+		}
+
+		if c.current == "" {
+			c.current = version
+		}
+		c.keys[version] = aead
+	}
+
+	if c.current == "" {
+		return nil, fmt.Errorf("no encryption key configured")
+	}
+	return c, nil
+}
+
+// redactKey keeps the version of a malformed key entry for error messages, hiding the key itself
+func redactKey(entry string) string {
+	if version, _, ok := strings.Cut(entry, ":"); ok {
+		return version + ":***"
+	}
+	return "***"
+}
+
+// KeyVersion returns the version of the key new values are encrypted with
+func (c *Cipher) KeyVersion() string {
+	return c.current
+}
+
+// encrypt seals plaintext JSON with the current key. The envelope ID is bound as
+// additional data, so a ciphertext copied to another envelope does not decrypt.
+func (c *Cipher) encrypt(envelopeID string, plaintext []byte) ([]byte, error) {
+	aead := c.keys[c.current]
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return json.Marshal(encryptedValue{
+		Version:    c.current,
+		Ciphertext: aead.Seal(nonce, nonce, plaintext, []byte(envelopeID)),
+	})
+}
+
+// decrypt opens an encrypted value with the key of its version
+func (c *Cipher) decrypt(envelopeID string, value encryptedValue) ([]byte, error) {
+	aead, ok := c.keys[value.Version]
+	if !ok {
+		return nil, fmt.Errorf("value is encrypted with unknown key version %q", value.Version)
+	}
+	if len(value.Ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("encrypted value is too short")
+	}
+
+	nonce, sealed := value.Ciphertext[:aead.NonceSize()], value.Ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, []byte(envelopeID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value with key version %q: %w", value.Version, err)
+	}
+	return plaintext, nil
+}
+
+// parseEncrypted reports whether stored JSON is an encrypted value, i.e. an object with
+// exactly the marker and ciphertext keys, and returns it
+func parseEncrypted(data []byte) (encryptedValue, bool) {
+	var value encryptedValue
+	if !bytes.Contains(data, []byte(`"`+encryptedMarker+`"`)) {
+		return value, false
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil || len(fields) != 2 {
+		return value, false
+	}
+	if err := json.Unmarshal(data, &value); err != nil || value.Version == "" || value.Ciphertext == nil {
+		return value, false
+	}
+	return value, true
+}
+
+// encodeJSON marshals a payload or result for storage, encrypting it when c is set
+func encodeJSON(c *Cipher, envelopeID string, v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return data, nil
+	}
+	return c.encrypt(envelopeID, data)
+}
+
+// decodeJSON unmarshals a stored payload or result into v, decrypting encrypted values.
+// Plaintext values are read as they are, whether or not c is set.
+func decodeJSON(c *Cipher, envelopeID string, data []byte, v any) error {
+	if value, ok := parseEncrypted(data); ok {
+		if c == nil {
+			return fmt.Errorf("value is encrypted but no encryption key is configured (ASYA_ENCRYPTION_KEY)")
+		}
+		plaintext, err := c.decrypt(envelopeID, value)
+		if err != nil {
+			return err
+		}
+		data = plaintext
+	}
+	return json.Unmarshal(data, v)
+}
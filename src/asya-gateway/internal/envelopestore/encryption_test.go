@@ -0,0 +1,132 @@
+package envelopestore
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+}
+
+func TestParseCipher(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		version string
+		wantErr string
+	}{
+		{name: "single key", spec: "v1:" + testKey(1), version: "v1"},
+		{name: "first key encrypts", spec: "v2:" + testKey(2) + ", v1:" + testKey(1), version: "v2"},
+		{name: "empty", spec: " , ", wantErr: "no encryption key"},
+		{name: "missing version", spec: testKey(1), wantErr: "version:base64key"},
+		{name: "invalid base64", spec: "v1:not-base64!", wantErr: "not valid base64"},
+		{name: "short key", spec: "v1:" + base64.StdEncoding.EncodeToString([]byte("short")), wantErr: "must be 32 bytes"},
+		{name: "duplicate version", spec: "v1:" + testKey(1) + ",v1:" + testKey(2), wantErr: "duplicate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseCipher(tt.spec)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				assert.NotContains(t, err.Error(), testKey(1), "key must not be logged")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.version, c.KeyVersion())
+		})
+	}
+}
+
+func TestCipher_RoundTrip(t *testing.T) {
+	c, err := ParseCipher("v1:" + testKey(1))
+	require.NoError(t, err)
+
+	payload := map[string]interface{}{"secret": "patient record"}
+	stored, err := encodeJSON(c, "env-1", payload)
+	require.NoError(t, err)
+	assert.NotContains(t, string(stored), "patient record")
+	assert.Contains(t, string(stored), `"asya_encrypted":"v1"`)
+
+	var decoded map[string]interface{}
+	require.NoError(t, decodeJSON(c, "env-1", stored, &decoded))
+	assert.Equal(t, payload, decoded)
+
+	// Bound to the envelope ID
+	err = decodeJSON(c, "env-2", stored, &decoded)
+	assert.Error(t, err)
+}
+
+func TestCipher_ReordersKeysLikeJSONB(t *testing.T) {
+	c, err := ParseCipher("v1:" + testKey(1))
+	require.NoError(t, err)
+
+	stored, err := encodeJSON(c, "env-1", "hello")
+	require.NoError(t, err)
+
+	// PostgreSQL returns JSONB objects with its own key order and spacing
+	var value encryptedValue
+	require.NoError(t, json.Unmarshal(stored, &value))
+	reordered := `{"ciphertext": "` + base64.StdEncoding.EncodeToString(value.Ciphertext) + `", "asya_encrypted": "v1"}`
+
+	var decoded string
+	require.NoError(t, decodeJSON(c, "env-1", []byte(reordered), &decoded))
+	assert.Equal(t, "hello", decoded)
+}
+
+func TestCipher_Rotation(t *testing.T) {
+	old, err := ParseCipher("v1:" + testKey(1))
+	require.NoError(t, err)
+	stored, err := encodeJSON(old, "env-1", "old value")
+	require.NoError(t, err)
+
+	rotated, err := ParseCipher("v2:" + testKey(2) + ",v1:" + testKey(1))
+	require.NoError(t, err)
+
+	var decoded string
+	require.NoError(t, decodeJSON(rotated, "env-1", stored, &decoded))
+	assert.Equal(t, "old value", decoded)
+
+	fresh, err := encodeJSON(rotated, "env-1", "new value")
+	require.NoError(t, err)
+	assert.Contains(t, string(fresh), `"asya_encrypted":"v2"`)
+
+	// Once the old key is dropped, its values are no longer readable
+	err = decodeJSON(mustCipher(t, "v2:"+testKey(2)), "env-1", stored, &decoded)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown key version "v1"`)
+}
+
+func TestCipher_Plaintext(t *testing.T) {
+	c := mustCipher(t, "v1:"+testKey(1))
+
+	// Values stored before encryption was enabled are read as they are
+	var decoded map[string]interface{}
+	require.NoError(t, decodeJSON(c, "env-1", []byte(`{"asya_encrypted": "user field", "other": 1}`), &decoded))
+	assert.Equal(t, "user field", decoded["asya_encrypted"])
+
+	// Without a cipher nothing is encrypted, and encrypted values fail loudly
+	stored, err := encodeJSON(nil, "env-1", map[string]int{"a": 1})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a": 1}`, string(stored))
+
+	encrypted, err := encodeJSON(c, "env-1", "secret")
+	require.NoError(t, err)
+	err = decodeJSON(nil, "env-1", encrypted, &decoded)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ASYA_ENCRYPTION_KEY")
+}
+
+func mustCipher(t *testing.T, spec string) *Cipher {
+	t.Helper()
+	c, err := ParseCipher(spec)
+	require.NoError(t, err)
+	return c
+}
@@ -0,0 +1,244 @@
+package envelopestore
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+)
+
+// FallbackStore keeps the gateway serving while its primary store (PostgreSQL) cannot be
+// connected at startup (ASYA_DB_FALLBACK_MEMORY). Envelopes are stored in memory until a
+// periodic reconnection succeeds; from then on new envelopes go to the primary store.
+// Envelopes created in memory stay there until retention removes them, and are lost
+// when the gateway restarts.
+type FallbackStore struct {
+	memory  *Store
+	connect func(ctx context.Context) (EnvelopeStore, error)
+
+	mu        sync.RWMutex
+	primary   EnvelopeStore // nil until connected
+	observer  TransitionObserver
+	retention *RetentionPolicy
+	retCtx    context.Context
+}
+
+// NewFallbackStore creates a store that serves from memory until connect succeeds (see Reconnect)
+func NewFallbackStore(connect func(ctx context.Context) (EnvelopeStore, error)) *FallbackStore {
+	return &FallbackStore{memory: NewStore(), connect: connect}
+}
+
+// Reconnect tries to connect the primary store every interval until it succeeds or ctx is done
+func (s *FallbackStore) Reconnect(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			primary, err := s.connect(ctx)
+			if err != nil {
+				slog.Warn("Envelope store still unavailable, envelopes are kept in memory", "error", err)
+				continue
+			}
+			s.promote(primary)
+			return
+		}
+	}()
+}
+
+// promote switches new envelopes to the connected primary store
+func (s *FallbackStore) promote(primary EnvelopeStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if tracker, ok := primary.(TransitionTracker); ok && s.observer != nil {
+		tracker.SetTransitionObserver(s.observer)
+	}
+	if sweeper, ok := primary.(RetentionSweeper); ok && s.retention != nil {
+		sweeper.StartRetention(s.retCtx, *s.retention)
+	}
+	s.primary = primary
+	slog.Info("Envelope store connected, leaving in-memory fallback")
+}
+
+// Primary returns the primary store, nil while envelopes are stored in memory
+func (s *FallbackStore) Primary() EnvelopeStore {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.primary
+}
+
+// current returns the store new envelopes are created in
+func (s *FallbackStore) current() EnvelopeStore {
+	if primary := s.Primary(); primary != nil {
+		return primary
+	}
+	return s.memory
+}
+
+// storeFor returns the store holding an envelope: envelopes created during the fallback stay in memory
+func (s *FallbackStore) storeFor(id string) EnvelopeStore {
+	primary := s.Primary()
+	if primary == nil {
+		return s.memory
+	}
+	if _, err := s.memory.Get(id); err == nil {
+		return s.memory
+	}
+	return primary
+}
+
+// Create creates a new envelope
+func (s *FallbackStore) Create(envelope *types.Envelope) error {
+	return s.current().Create(envelope)
+}
+
+// Get retrieves an envelope by ID
+func (s *FallbackStore) Get(id string) (*types.Envelope, error) {
+	return s.storeFor(id).Get(id)
+}
+
+// Update updates an envelope's status
+func (s *FallbackStore) Update(update types.EnvelopeUpdate) error {
+	return s.storeFor(update.ID).Update(update)
+}
+
+// UpdateIfStatus updates an envelope's status if it is in status expected
+func (s *FallbackStore) UpdateIfStatus(update types.EnvelopeUpdate, expected types.EnvelopeStatus) (bool, error) {
+	return s.storeFor(update.ID).UpdateIfStatus(update, expected)
+}
+
+// UpdateProgress updates envelope progress
+func (s *FallbackStore) UpdateProgress(update types.EnvelopeUpdate) error {
+	return s.storeFor(update.ID).UpdateProgress(update)
+}
+
+// GetUpdates retrieves all updates for an envelope
+func (s *FallbackStore) GetUpdates(id string, since *time.Time) ([]types.EnvelopeUpdate, error) {
+	return s.storeFor(id).GetUpdates(id, since)
+}
+
+// Subscribe creates a listener channel for envelope updates
+func (s *FallbackStore) Subscribe(id string) chan types.EnvelopeUpdate {
+	return s.storeFor(id).Subscribe(id)
+}
+
+// Unsubscribe removes a listener channel
+func (s *FallbackStore) Unsubscribe(id string, ch chan types.EnvelopeUpdate) {
+	s.storeFor(id).Unsubscribe(id, ch)
+}
+
+// IsActive checks if an envelope is still active
+func (s *FallbackStore) IsActive(id string) bool {
+	return s.storeFor(id).IsActive(id)
+}
+
+// GetChildren retrieves fanout children of an envelope from both stores, in branch order
+func (s *FallbackStore) GetChildren(parentID string) ([]*types.Envelope, error) {
+	children, err := s.memory.GetChildren(parentID)
+	if err != nil {
+		return nil, err
+	}
+	if primary := s.Primary(); primary != nil {
+		primaryChildren, err := primary.GetChildren(parentID)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, primaryChildren...)
+		sort.SliceStable(children, func(i, j int) bool { return children[i].BranchIndex < children[j].BranchIndex })
+	}
+	return children, nil
+}
+
+// AddFanoutBranch registers a fanout child branch on the parent envelope
+func (s *FallbackStore) AddFanoutBranch(parentID string, branches int) error {
+	return s.storeFor(parentID).AddFanoutBranch(parentID, branches)
+}
+
+// CompleteFanoutBranch records a completed branch on the parent envelope
+func (s *FallbackStore) CompleteFanoutBranch(parentID string, branchIndex int) (int, int, error) {
+	return s.storeFor(parentID).CompleteFanoutBranch(parentID, branchIndex)
+}
+
+// ListActive returns the IDs of active envelopes matching filter in both stores
+func (s *FallbackStore) ListActive(filter EnvelopeFilter) ([]string, error) {
+	ids, err := s.memory.ListActive(filter)
+	if err != nil {
+		return nil, err
+	}
+	if primary := s.Primary(); primary != nil {
+		primaryIDs, err := primary.ListActive(filter)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, primaryIDs...)
+		sort.Strings(ids)
+	}
+	return ids, nil
+}
+
+// ListQueuedBefore returns the queued envelopes of both stores that entered queued before cutoff
+func (s *FallbackStore) ListQueuedBefore(cutoff time.Time) ([]QueuedEnvelope, error) {
+	queued, err := s.memory.ListQueuedBefore(cutoff)
+	if err != nil {
+		return nil, err
+	}
+	if primary := s.Primary(); primary != nil {
+		primaryQueued, err := primary.ListQueuedBefore(cutoff)
+		if err != nil {
+			return nil, err
+		}
+		queued = append(queued, primaryQueued...)
+		sort.Slice(queued, func(i, j int) bool { return queued[i].ID < queued[j].ID })
+	}
+	return queued, nil
+}
+
+// Subscribers returns the open update listeners of both stores
+func (s *FallbackStore) Subscribers() int {
+	count := s.memory.Subscribers()
+	if counter, ok := s.Primary().(SubscriberCounter); ok {
+		count += counter.Subscribers()
+	}
+	return count
+}
+
+// SetTransitionObserver sets the observer of status changes, also for the primary store once connected
+func (s *FallbackStore) SetTransitionObserver(observer TransitionObserver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.observer = observer
+	s.memory.SetTransitionObserver(observer)
+	if tracker, ok := s.primary.(TransitionTracker); ok {
+		tracker.SetTransitionObserver(observer)
+	}
+}
+
+// StartRetention removes expired envelopes from memory, and from the primary store once connected
+func (s *FallbackStore) StartRetention(ctx context.Context, policy RetentionPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.memory.StartRetention(ctx, policy)
+	s.retention, s.retCtx = &policy, ctx
+	if sweeper, ok := s.primary.(RetentionSweeper); ok {
+		sweeper.StartRetention(ctx, policy)
+	}
+}
+
+// Close closes the primary store if it is connected
+func (s *FallbackStore) Close() {
+	if closer, ok := s.Primary().(interface{ Close() }); ok {
+		closer.Close()
+	}
+}
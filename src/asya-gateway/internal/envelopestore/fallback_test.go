@@ -0,0 +1,98 @@
+package envelopestore
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+)
+
+func TestFallbackStore_Promote(t *testing.T) {
+	primary := NewStore()
+	var attempts atomic.Int32
+	store := NewFallbackStore(func(ctx context.Context) (EnvelopeStore, error) {
+		if attempts.Add(1) < 3 {
+			return nil, errors.New("connection refused")
+		}
+		return primary, nil
+	})
+
+	newEnvelope := func(id string) *types.Envelope {
+		return &types.Envelope{ID: id, Status: types.EnvelopeStatusPending, Route: types.Route{Actors: []string{"actor1"}}}
+	}
+
+	// While the primary store is unreachable, envelopes are kept in memory
+	if err := store.Create(newEnvelope("during-outage")); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := primary.Get("during-outage"); err == nil {
+		t.Fatal("envelope created during the outage must not be in the primary store")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store.Reconnect(ctx, 10*time.Millisecond)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for store.Primary() == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("primary store not connected after %d attempts", attempts.Load())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// New envelopes go to the primary store once connected
+	if err := store.Create(newEnvelope("after-outage")); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := primary.Get("after-outage"); err != nil {
+		t.Errorf("envelope created after reconnecting is not in the primary store: %v", err)
+	}
+
+	// Envelopes created during the outage stay readable and updatable in memory
+	if err := store.Update(types.EnvelopeUpdate{ID: "during-outage", Status: types.EnvelopeStatusRunning, Timestamp: time.Now()}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	envelope, err := store.Get("during-outage")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if envelope.Status != types.EnvelopeStatusRunning {
+		t.Errorf("Status = %s, want %s", envelope.Status, types.EnvelopeStatusRunning)
+	}
+
+	ids, err := store.ListActive(EnvelopeFilter{})
+	if err != nil {
+		t.Fatalf("ListActive() error = %v", err)
+	}
+	if want := []string{"after-outage", "during-outage"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("ListActive() = %v, want %v", ids, want)
+	}
+}
+
+func TestFallbackStore_StopsReconnectingWithContext(t *testing.T) {
+	var attempts atomic.Int32
+	store := NewFallbackStore(func(ctx context.Context) (EnvelopeStore, error) {
+		attempts.Add(1)
+		return nil, errors.New("connection refused")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	store.Reconnect(ctx, 5*time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+
+	stopped := attempts.Load()
+	time.Sleep(30 * time.Millisecond)
+	if attempts.Load() != stopped {
+		t.Error("reconnection attempts continued after the context was cancelled")
+	}
+	if store.Primary() != nil {
+		t.Error("primary store connected although every attempt failed")
+	}
+}
@@ -1,11 +1,65 @@
 package envelopestore
 
 import (
+	"errors"
 	"time"
 
 	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
 )
 
+// ErrNotFound is wrapped by store errors about envelopes that do not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrBranchCompleted is returned when a fanout branch is completed again, e.g. for a redelivered final status
+var ErrBranchCompleted = errors.New("fanout branch already completed")
+
+// SubscriberCounter is implemented by stores that can report their open update listeners
+type SubscriberCounter interface {
+	Subscribers() int
+}
+
+// TransitionObserver is notified when an envelope changes status, e.g. to export
+// how long envelopes wait in each status or to record completions. Redelivered updates
+// that do not change the status are not reported. The in-memory store reports transitions
+// with its lock held, so observers must not call back into the store.
+type TransitionObserver interface {
+	StatusChanged(transition Transition)
+}
+
+// Transition is a status change of an envelope
+type Transition struct {
+	// Envelope after the change. PgStore only fills ID, ParentID, Tool, Status, Route.Actors,
+	// Error and CreatedAt.
+	Envelope *types.Envelope
+	From     types.EnvelopeStatus
+	To       types.EnvelopeStatus
+	Elapsed  time.Duration // Time spent in From, negative when unknown
+}
+
+// Completed reports whether the transition is the first to a final status
+func (t Transition) Completed() bool {
+	return !isFinalStatus(t.From) && isFinalStatus(t.To)
+}
+
+// TransitionObservers notifies several observers of each transition, in order
+type TransitionObservers []TransitionObserver
+
+func (o TransitionObservers) StatusChanged(transition Transition) {
+	for _, observer := range o {
+		observer.StatusChanged(transition)
+	}
+}
+
+// isFinalStatus reports whether status is final
+func isFinalStatus(status types.EnvelopeStatus) bool {
+	return status == types.EnvelopeStatusSucceeded || status == types.EnvelopeStatusFailed
+}
+
+// TransitionTracker is implemented by stores that can notify a TransitionObserver
+type TransitionTracker interface {
+	SetTransitionObserver(observer TransitionObserver)
+}
+
 // EnvelopeStore defines the interface for envelope storage
 type EnvelopeStore interface {
 	// Create creates a new envelope
@@ -17,6 +71,10 @@ type EnvelopeStore interface {
 	// Update updates a envelope's status
 	Update(update types.EnvelopeUpdate) error
 
+	// UpdateIfStatus atomically applies the update only if the envelope is in status expected,
+	// and reports whether it was applied
+	UpdateIfStatus(update types.EnvelopeUpdate, expected types.EnvelopeStatus) (bool, error)
+
 	// UpdateProgress updates envelope progress (lighter weight than Update)
 	UpdateProgress(update types.EnvelopeUpdate) error
 
@@ -31,4 +89,57 @@ type EnvelopeStore interface {
 
 	// IsActive checks if a envelope is still active
 	IsActive(id string) bool
+
+	// GetChildren retrieves fanout children of an envelope (in branch order)
+	GetChildren(parentID string) ([]*types.Envelope, error)
+
+	// AddFanoutBranch registers a fanout child branch on the parent envelope, whose fanout
+	// has at least branches branches (the parent's own branch included)
+	AddFanoutBranch(parentID string, branches int) error
+
+	// CompleteFanoutBranch records the completion of branch branchIndex (0 for the parent's own
+	// branch) on the parent envelope and returns the completed and total branch counts.
+	// Completing a branch twice returns ErrBranchCompleted.
+	CompleteFanoutBranch(parentID string, branchIndex int) (completed int, total int, err error)
+
+	// ListActive returns the IDs of envelopes that are not in a final state and match filter
+	ListActive(filter EnvelopeFilter) ([]string, error)
+
+	// ListQueuedBefore returns the envelopes still queued that entered the queued status
+	// before cutoff, in ID order
+	ListQueuedBefore(cutoff time.Time) ([]QueuedEnvelope, error)
+}
+
+// QueuedEnvelope is an envelope waiting for its first actor to pick it up
+type QueuedEnvelope struct {
+	ID       string
+	Tool     string
+	Actor    string // Actor the envelope is queued for
+	QueuedAt time.Time
+}
+
+// EnvelopeFilter selects envelopes for bulk operations. Empty fields match every envelope.
+type EnvelopeFilter struct {
+	Statuses []types.EnvelopeStatus // Envelopes in any of these statuses
+	Tool     string                 // Envelopes created by this tool
+	Roots    bool                   // Only envelopes that are not fanout children
+}
+
+// Matches reports whether an envelope matches the filter
+func (f EnvelopeFilter) Matches(envelope *types.Envelope) bool {
+	if f.Tool != "" && envelope.Tool != f.Tool {
+		return false
+	}
+	if f.Roots && envelope.ParentID != nil {
+		return false
+	}
+	if len(f.Statuses) == 0 {
+		return true
+	}
+	for _, status := range f.Statuses {
+		if envelope.Status == status {
+			return true
+		}
+	}
+	return false
 }
@@ -3,6 +3,7 @@ package envelopestore
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
@@ -22,6 +23,17 @@ type PgStore struct {
 	timers    map[string]*time.Timer
 	ctx       context.Context
 	cancel    context.CancelFunc
+	cipher    *Cipher // Encrypts payloads and results at rest (nil: stored as plain JSON)
+	observer  TransitionObserver
+
+	// Read replica for status reads (nil: all queries use pool). Envelopes written by
+	// this gateway within readMaxLag are read from the primary, as the replica may lag.
+	// recentWrites is per gateway process: writes by other gateway replicas are not tracked.
+	readPool     *pgxpool.Pool
+	readMaxLag   time.Duration
+	recentMu     sync.Mutex
+	recentWrites map[string]time.Time // Last write per envelope ID, pruned after readMaxLag
+	lastPrune    time.Time
 }
 
 // getEnvInt reads an integer from environment variable with default value
@@ -46,6 +58,29 @@ func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 
 // NewPgStore creates a new PostgreSQL-backed envelope store
 func NewPgStore(ctx context.Context, connString string) (*PgStore, error) {
+	pool, err := newPool(ctx, connString)
+	if err != nil {
+		return nil, err
+	}
+
+	storeCtx, cancel := context.WithCancel(ctx)
+
+	s := &PgStore{
+		pool:      pool,
+		listeners: make(map[string][]chan types.EnvelopeUpdate),
+		timers:    make(map[string]*time.Timer),
+		ctx:       storeCtx,
+		cancel:    cancel,
+	}
+
+	// Start background cleanup goroutine
+	go s.cleanupOldUpdates()
+
+	return s, nil
+}
+
+// newPool connects a connection pool configured from environment variables
+func newPool(ctx context.Context, connString string) (*pgxpool.Pool, error) {
 	config, err := pgxpool.ParseConfig(connString)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse connection string: %w", err)
@@ -68,20 +103,38 @@ func NewPgStore(ctx context.Context, connString string) (*PgStore, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	storeCtx, cancel := context.WithCancel(ctx)
+	return pool, nil
+}
 
-	s := &PgStore{
-		pool:      pool,
-		listeners: make(map[string][]chan types.EnvelopeUpdate),
-		timers:    make(map[string]*time.Timer),
-		ctx:       storeCtx,
-		cancel:    cancel,
+// ConnectReadReplica sends Get, GetUpdates, IsActive and ListActive queries to a read replica
+// with its own connection pool. Envelopes written by this gateway within maxLag are read from
+// the primary, and envelopes the replica does not have yet are looked up on the primary.
+// Must be called before the store is used.
+func (s *PgStore) ConnectReadReplica(ctx context.Context, connString string, maxLag time.Duration) error {
+	pool, err := newPool(ctx, connString)
+	if err != nil {
+		return fmt.Errorf("read replica: %w", err)
 	}
+	s.readPool = pool
+	s.readMaxLag = maxLag
+	s.recentWrites = make(map[string]time.Time)
+	return nil
+}
 
-	// Start background cleanup goroutine
-	go s.cleanupOldUpdates()
+// SetCipher enables encryption of payloads, results and partial results written from now on.
+// Values already stored in plain JSON remain readable.
+func (s *PgStore) SetCipher(c *Cipher) {
+	s.cipher = c
+}
 
-	return s, nil
+// SetTransitionObserver sets the observer of status changes. Must be called before the store is used.
+func (s *PgStore) SetTransitionObserver(observer TransitionObserver) {
+	s.observer = observer
+}
+
+// Pool returns the connection pool, for components sharing the gateway database (audit log)
+func (s *PgStore) Pool() *pgxpool.Pool {
+	return s.pool
 }
 
 // Close closes the database connection pool
@@ -103,6 +156,9 @@ func (s *PgStore) Close() {
 		delete(s.listeners, id)
 	}
 
+	if s.readPool != nil {
+		s.readPool.Close()
+	}
 	s.pool.Close()
 }
 
@@ -112,11 +168,16 @@ func (s *PgStore) Create(envelope *types.Envelope) error {
 	envelope.CreatedAt = now
 	envelope.UpdatedAt = now
 	envelope.Status = types.EnvelopeStatusPending
+	envelope.StatusHistory = []types.StatusTransition{{Status: types.EnvelopeStatusPending, At: now}}
 
 	// Initialize progress tracking
 	envelope.TotalActors = len(envelope.Route.Actors)
 	envelope.ActorsCompleted = 0
 	envelope.ProgressPercent = 0.0
+	envelope.CurrentActorIdx = envelope.Route.Current
+	if envelope.Route.Current >= 0 && envelope.Route.Current < len(envelope.Route.Actors) {
+		envelope.CurrentActorName = envelope.Route.Actors[envelope.Route.Current]
+	}
 
 	var deadline *time.Time
 	if envelope.TimeoutSec > 0 {
@@ -125,20 +186,28 @@ func (s *PgStore) Create(envelope *types.Envelope) error {
 		deadline = &d
 	}
 
-	payloadJSON, err := json.Marshal(envelope.Payload)
+	payloadJSON, err := encodeJSON(s.cipher, envelope.ID, envelope.Payload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
+	statusHistoryJSON, err := json.Marshal(envelope.StatusHistory)
+	if err != nil {
+		return fmt.Errorf("failed to marshal status history: %w", err)
+	}
+
 	query := `
-		INSERT INTO envelopes (id, parent_id, status, route_actors, route_current, payload, timeout_sec, deadline,
-		                 progress_percent, total_actors, actors_completed, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		INSERT INTO envelopes (id, parent_id, branch_index, tool, status, route_actors, route_current, payload, timeout_sec, deadline,
+		                 progress_percent, total_actors, actors_completed, current_actor_idx, current_actor_name,
+		                 created_at, updated_at, status_history)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NULLIF($15, ''), $16, $17, $18)
 	`
 
 	_, err = s.pool.Exec(s.ctx, query,
 		envelope.ID,
 		envelope.ParentID,
+		envelope.BranchIndex,
+		envelope.Tool,
 		envelope.Status,
 		envelope.Route.Actors,
 		envelope.Route.Current,
@@ -148,18 +217,23 @@ func (s *PgStore) Create(envelope *types.Envelope) error {
 		envelope.ProgressPercent,
 		envelope.TotalActors,
 		envelope.ActorsCompleted,
+		envelope.CurrentActorIdx,
+		envelope.CurrentActorName,
 		envelope.CreatedAt,
 		envelope.UpdatedAt,
+		statusHistoryJSON,
 	)
 
 	if err != nil {
 		return fmt.Errorf("failed to create envelope: %w", err)
 	}
 
+	s.noteWrite(envelope.ID)
+
 	// Set timeout timer if specified
 	if envelope.TimeoutSec > 0 {
 		s.mu.Lock()
-		s.timers[envelope.ID] = time.AfterFunc(time.Duration(envelope.TimeoutSec)*time.Second, func() {
+		s.timers[envelope.ID] = time.AfterFunc(timeoutDelay(time.Duration(envelope.TimeoutSec)*time.Second), func() {
 			s.handleTimeout(envelope.ID)
 		})
 		s.mu.Unlock()
@@ -170,27 +244,44 @@ func (s *PgStore) Create(envelope *types.Envelope) error {
 
 // Get retrieves a envelope by ID
 func (s *PgStore) Get(id string) (*types.Envelope, error) {
+	db := s.readerFor(id)
+	envelope, err := s.get(db, id)
+	if errors.Is(err, ErrNotFound) && db != s.pool {
+		// Not replicated yet
+		return s.get(s.pool, id)
+	}
+	return envelope, err
+}
+
+// get retrieves a envelope by ID from the primary or the read replica
+func (s *PgStore) get(db *pgxpool.Pool, id string) (*types.Envelope, error) {
 	query := `
-		SELECT id, parent_id, status, route_actors, route_current, payload, result, error, message, timeout_sec, deadline,
-		       progress_percent, current_actor_idx, current_actor_name, actors_completed, total_actors, created_at, updated_at
+		SELECT id, parent_id, branch_index, tool, status, route_actors, route_current, payload, result, result_url, partial_result, warnings, error, message, timeout_sec, deadline,
+		       progress_percent, current_actor_idx, current_actor_name, actors_completed, total_actors,
+		       fanout_branches, branches_completed, status_history, created_at, updated_at
 		FROM envelopes
 		WHERE id = $1
 	`
 
 	var envelope types.Envelope
-	var payloadJSON, resultJSON []byte
+	var payloadJSON, resultJSON, partialResultJSON, statusHistoryJSON []byte
 	var deadline *time.Time
-	var errorStr, messageStr, currentActorName *string
+	var errorStr, messageStr, currentActorName, tool, resultURL *string
 	var timeoutSec *int
 
-	err := s.pool.QueryRow(s.ctx, query, id).Scan(
+	err := db.QueryRow(s.ctx, query, id).Scan(
 		&envelope.ID,
 		&envelope.ParentID,
+		&envelope.BranchIndex,
+		&tool,
 		&envelope.Status,
 		&envelope.Route.Actors,
 		&envelope.Route.Current,
 		&payloadJSON,
 		&resultJSON,
+		&resultURL,
+		&partialResultJSON,
+		&envelope.Warnings,
 		&errorStr,
 		&messageStr,
 		&timeoutSec,
@@ -200,12 +291,15 @@ func (s *PgStore) Get(id string) (*types.Envelope, error) {
 		&currentActorName,
 		&envelope.ActorsCompleted,
 		&envelope.TotalActors,
+		&envelope.FanoutBranches,
+		&envelope.BranchesCompleted,
+		&statusHistoryJSON,
 		&envelope.CreatedAt,
 		&envelope.UpdatedAt,
 	)
 
 	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("envelope %s not found", id)
+		return nil, fmt.Errorf("envelope %s %w", id, ErrNotFound)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get envelope: %w", err)
@@ -232,52 +326,124 @@ func (s *PgStore) Get(id string) (*types.Envelope, error) {
 		envelope.CurrentActorName = *currentActorName
 	}
 
+	if tool != nil {
+		envelope.Tool = *tool
+	}
+
 	if payloadJSON != nil {
-		if err := json.Unmarshal(payloadJSON, &envelope.Payload); err != nil {
+		if err := decodeJSON(s.cipher, envelope.ID, payloadJSON, &envelope.Payload); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal payload: %w", err)
 		}
 	}
 
+	if resultURL != nil {
+		envelope.ResultURL = *resultURL
+	}
+
 	if resultJSON != nil {
-		if err := json.Unmarshal(resultJSON, &envelope.Result); err != nil {
+		if err := decodeJSON(s.cipher, envelope.ID, resultJSON, &envelope.Result); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal result: %w", err)
 		}
-	} else {
+	} else if envelope.ResultURL == "" {
 		envelope.Result = map[string]interface{}{}
 	}
 
+	if partialResultJSON != nil {
+		if err := decodeJSON(s.cipher, envelope.ID, partialResultJSON, &envelope.PartialResult); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal partial result: %w", err)
+		}
+	}
+
+	if statusHistoryJSON != nil {
+		if err := json.Unmarshal(statusHistoryJSON, &envelope.StatusHistory); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal status history: %w", err)
+		}
+	}
+
 	return &envelope, nil
 }
 
 // Update updates a envelope's status
 func (s *PgStore) Update(update types.EnvelopeUpdate) error {
+	_, err := s.update(update, nil)
+	return err
+}
+
+// UpdateIfStatus applies the update only if the envelope is in status expected.
+// The status is checked under the row lock held by the update's transaction.
+func (s *PgStore) UpdateIfStatus(update types.EnvelopeUpdate, expected types.EnvelopeStatus) (bool, error) {
+	return s.update(update, func(status types.EnvelopeStatus) bool { return status == expected })
+}
+
+// update applies an update, if allowed is not nil only while it allows the envelope's status,
+// and reports whether it was applied. The status check, the envelope row and its update record
+// are written in one transaction.
+func (s *PgStore) update(update types.EnvelopeUpdate, allowed func(types.EnvelopeStatus) bool) (bool, error) {
 	tx, err := s.pool.Begin(s.ctx)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer func() { _ = tx.Rollback(s.ctx) }()
 
+	if allowed != nil {
+		var status types.EnvelopeStatus
+		err := tx.QueryRow(s.ctx, `SELECT status FROM envelopes WHERE id = $1 FOR UPDATE`, update.ID).Scan(&status)
+		if err == pgx.ErrNoRows {
+			return false, fmt.Errorf("envelope %s %w", update.ID, ErrNotFound)
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to lock envelope: %w", err)
+		}
+		if !allowed(status) {
+			return false, nil
+		}
+	}
+
 	// Update main envelope record
 	var resultJSON []byte
 	if update.Result != nil {
-		resultJSON, err = json.Marshal(update.Result)
+		resultJSON, err = encodeJSON(s.cipher, update.ID, update.Result)
 		if err != nil {
-			return fmt.Errorf("failed to marshal result: %w", err)
+			return false, fmt.Errorf("failed to marshal result: %w", err)
 		}
 	}
 
+	// An offloaded result replaces any result stored inline
+	var resultURL *string
+	if update.ResultURL != "" {
+		resultURL = &update.ResultURL
+	}
+
+	transition := pgTransition{at: transitionTime(update)}
+	transitionJSON, err := statusTransitionJSON(update.Status, transition.at)
+	if err != nil {
+		return false, err
+	}
+
+	// The previous status and when it was entered are read under the row lock,
+	// so concurrent updates record each transition once
 	updateQuery := `
+		WITH previous AS (
+			SELECT status AS previous_status, (status_history -> -1 ->> 'at')::timestamptz AS entered_at
+			FROM envelopes
+			WHERE id = $7
+			FOR UPDATE
+		)
 		UPDATE envelopes
 		SET status = $1,
-		    result = COALESCE($2, result),
+		    result = CASE WHEN $8::text IS NULL THEN COALESCE($2, result) END,
+		    result_url = COALESCE($8, result_url),
 		    error = COALESCE($3, error),
 		    message = COALESCE(NULLIF($4, ''), message),
 		    progress_percent = COALESCE($5, progress_percent),
-		    updated_at = $6
+		    updated_at = $6,
+		    status_history = CASE WHEN previous_status = $1 THEN status_history ELSE status_history || $9::jsonb END
+		FROM previous
 		WHERE id = $7
+		RETURNING previous_status, entered_at, id, parent_id, tool, route_actors, error, created_at
 	`
 
-	result, err := tx.Exec(s.ctx, updateQuery,
+	err = tx.QueryRow(s.ctx, updateQuery,
 		update.Status,
 		resultJSON,
 		update.Error,
@@ -285,14 +451,15 @@ func (s *PgStore) Update(update types.EnvelopeUpdate) error {
 		update.ProgressPercent,
 		update.Timestamp,
 		update.ID,
-	)
+		resultURL,
+		transitionJSON,
+	).Scan(transition.dest()...)
 
-	if err != nil {
-		return fmt.Errorf("failed to update envelope: %w", err)
+	if err == pgx.ErrNoRows {
+		return false, fmt.Errorf("envelope %s %w", update.ID, ErrNotFound)
 	}
-
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("envelope %s not found", update.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to update envelope: %w", err)
 	}
 
 	// Insert update record for SSE streaming
@@ -304,8 +471,8 @@ func (s *PgStore) Update(update types.EnvelopeUpdate) error {
 	}
 
 	insertUpdateQuery := `
-		INSERT INTO envelope_updates (envelope_id, status, message, result, error, progress_percent, actor, envelope_state, timestamp)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO envelope_updates (envelope_id, status, message, result, result_url, error, progress_percent, actor, envelope_state, timestamp)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	`
 
 	// EnvelopeState is already nullable (*string), pass directly
@@ -319,6 +486,7 @@ func (s *PgStore) Update(update types.EnvelopeUpdate) error {
 		update.Status,
 		update.Message,
 		resultJSON,
+		resultURL,
 		update.Error,
 		update.ProgressPercent,
 		currentActorName,
@@ -327,13 +495,16 @@ func (s *PgStore) Update(update types.EnvelopeUpdate) error {
 	)
 
 	if err != nil {
-		return fmt.Errorf("failed to insert envelope update: %w", err)
+		return false, fmt.Errorf("failed to insert envelope update: %w", err)
 	}
 
 	if err := tx.Commit(s.ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	s.noteWrite(update.ID)
+	s.observeTransition(transition, update)
+
 	// Cancel timeout timer if envelope reaches final state
 	if s.isFinal(update.Status) {
 		s.mu.Lock()
@@ -346,7 +517,7 @@ func (s *PgStore) Update(update types.EnvelopeUpdate) error {
 	s.notifyListeners(update)
 	s.mu.RUnlock()
 
-	return nil
+	return true, nil
 }
 
 // UpdateProgress updates envelope progress (more frequent, lighter update)
@@ -372,20 +543,49 @@ func (s *PgStore) UpdateProgress(update types.EnvelopeUpdate) error {
 		totalActors = &total
 	}
 
+	var partialResultJSON []byte
+	if update.PartialResult != nil {
+		partialResultJSON, err = encodeJSON(s.cipher, update.ID, update.PartialResult)
+		if err != nil {
+			return fmt.Errorf("failed to marshal partial result: %w", err)
+		}
+	}
+
+	transition := pgTransition{at: transitionTime(update)}
+	transitionJSON, err := statusTransitionJSON(update.Status, transition.at)
+	if err != nil {
+		return err
+	}
+
+	// When the actors list is omitted, current_actor_name is derived from the stored route
+	// (route_actors is 1-based in PostgreSQL); route_current follows the reported position.
+	// The previous status is read under the row lock, as in Update.
 	updateQuery := `
+		WITH previous AS (
+			SELECT status AS previous_status, (status_history -> -1 ->> 'at')::timestamptz AS entered_at
+			FROM envelopes
+			WHERE id = $9
+			FOR UPDATE
+		)
 		UPDATE envelopes
 		SET progress_percent = COALESCE($1, progress_percent),
 		    current_actor_idx = COALESCE($2, current_actor_idx),
-		    current_actor_name = COALESCE($3, current_actor_name),
+		    route_current = COALESCE($2, route_current),
+		    current_actor_name = COALESCE($3, route_actors[$2::int + 1], current_actor_name),
 		    message = COALESCE(NULLIF($4, ''), message),
 		    route_actors = COALESCE($5, route_actors),
 		    total_actors = COALESCE($6, total_actors),
 		    status = $7,
-		    updated_at = $8
+		    updated_at = $8,
+		    partial_result = COALESCE($10, partial_result),
+		    warnings = array_cat(warnings, $11::text[]),
+		    status_history = CASE WHEN previous_status = $7 THEN status_history ELSE status_history || $12::jsonb END
+		FROM previous
 		WHERE id = $9
+		RETURNING previous_status, entered_at, id, parent_id, tool, route_actors, error, created_at
 	`
 
-	_, err = tx.Exec(s.ctx, updateQuery,
+	err = tx.QueryRow(s.ctx, updateQuery,
 		update.ProgressPercent,
 		update.CurrentActorIdx,
 		currentActorName,
@@ -395,16 +595,22 @@ func (s *PgStore) UpdateProgress(update types.EnvelopeUpdate) error {
 		update.Status,
 		update.Timestamp,
 		update.ID,
-	)
+		partialResultJSON,
+		update.Warnings,
+		transitionJSON,
+	).Scan(transition.dest()...)
 
+	if err == pgx.ErrNoRows {
+		return fmt.Errorf("envelope %s %w", update.ID, ErrNotFound)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update envelope progress: %w", err)
 	}
 
 	// Insert progress update record (uses derived current_actor_name for SSE streaming)
 	insertUpdateQuery := `
-		INSERT INTO envelope_updates (envelope_id, status, message, progress_percent, actor, envelope_state, timestamp)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO envelope_updates (envelope_id, status, message, progress_percent, actor, envelope_state, partial_result, warnings, timestamp)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	`
 
 	// EnvelopeState is already nullable (*string), pass directly
@@ -420,6 +626,8 @@ func (s *PgStore) UpdateProgress(update types.EnvelopeUpdate) error {
 		update.ProgressPercent,
 		currentActorName,
 		envelopeState,
+		partialResultJSON,
+		update.Warnings,
 		update.Timestamp,
 	)
 
@@ -431,6 +639,9 @@ func (s *PgStore) UpdateProgress(update types.EnvelopeUpdate) error {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	s.noteWrite(update.ID)
+	s.observeTransition(transition, update)
+
 	// Notify SSE listeners
 	s.mu.RLock()
 	s.notifyListeners(update)
@@ -441,12 +652,23 @@ func (s *PgStore) UpdateProgress(update types.EnvelopeUpdate) error {
 
 // GetUpdates retrieves all updates for a envelope (for SSE streaming)
 func (s *PgStore) GetUpdates(id string, since *time.Time) ([]types.EnvelopeUpdate, error) {
+	db := s.readerFor(id)
+	updates, err := s.getUpdates(db, id, since)
+	if err == nil && len(updates) == 0 && db != s.pool {
+		// Not replicated yet
+		return s.getUpdates(s.pool, id, since)
+	}
+	return updates, err
+}
+
+// getUpdates retrieves updates for a envelope from the primary or the read replica
+func (s *PgStore) getUpdates(db *pgxpool.Pool, id string, since *time.Time) ([]types.EnvelopeUpdate, error) {
 	var query string
 	var args []interface{}
 
 	if since != nil {
 		query = `
-			SELECT envelope_id, status, message, result, error, progress_percent, actor, envelope_state, timestamp
+			SELECT envelope_id, status, message, result, result_url, partial_result, warnings, error, progress_percent, actor, envelope_state, timestamp
 			FROM envelope_updates
 			WHERE envelope_id = $1 AND timestamp > $2
 			ORDER BY timestamp ASC
@@ -454,7 +676,7 @@ func (s *PgStore) GetUpdates(id string, since *time.Time) ([]types.EnvelopeUpdat
 		args = []interface{}{id, since}
 	} else {
 		query = `
-			SELECT envelope_id, status, message, result, error, progress_percent, actor, envelope_state, timestamp
+			SELECT envelope_id, status, message, result, result_url, partial_result, warnings, error, progress_percent, actor, envelope_state, timestamp
 			FROM envelope_updates
 			WHERE envelope_id = $1
 			ORDER BY timestamp ASC
@@ -462,7 +684,7 @@ func (s *PgStore) GetUpdates(id string, since *time.Time) ([]types.EnvelopeUpdat
 		args = []interface{}{id}
 	}
 
-	rows, err := s.pool.Query(s.ctx, query, args...)
+	rows, err := db.Query(s.ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query updates: %w", err)
 	}
@@ -471,8 +693,8 @@ func (s *PgStore) GetUpdates(id string, since *time.Time) ([]types.EnvelopeUpdat
 	var updates []types.EnvelopeUpdate
 	for rows.Next() {
 		var update types.EnvelopeUpdate
-		var resultJSON []byte
-		var errorStr *string
+		var resultJSON, partialResultJSON []byte
+		var errorStr, resultURL *string
 		var actorName *string
 
 		err := rows.Scan(
@@ -480,6 +702,9 @@ func (s *PgStore) GetUpdates(id string, since *time.Time) ([]types.EnvelopeUpdat
 			&update.Status,
 			&update.Message,
 			&resultJSON,
+			&resultURL,
+			&partialResultJSON,
+			&update.Warnings,
 			&errorStr,
 			&update.ProgressPercent,
 			&actorName,
@@ -494,16 +719,26 @@ func (s *PgStore) GetUpdates(id string, since *time.Time) ([]types.EnvelopeUpdat
 			update.Error = *errorStr
 		}
 
+		if resultURL != nil {
+			update.ResultURL = *resultURL
+		}
+
 		if actorName != nil {
 			update.Actor = *actorName
 		}
 
 		if resultJSON != nil {
-			if err := json.Unmarshal(resultJSON, &update.Result); err != nil {
+			if err := decodeJSON(s.cipher, update.ID, resultJSON, &update.Result); err != nil {
 				return nil, fmt.Errorf("failed to unmarshal result: %w", err)
 			}
 		}
 
+		if partialResultJSON != nil {
+			if err := decodeJSON(s.cipher, update.ID, partialResultJSON, &update.PartialResult); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal partial result: %w", err)
+			}
+		}
+
 		updates = append(updates, update)
 	}
 
@@ -540,6 +775,18 @@ func (s *PgStore) Unsubscribe(id string, ch chan types.EnvelopeUpdate) {
 	}
 }
 
+// Subscribers returns the number of open update listeners, to detect listeners that are never unsubscribed
+func (s *PgStore) Subscribers() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := 0
+	for _, listeners := range s.listeners {
+		n += len(listeners)
+	}
+	return n
+}
+
 // notifyListeners sends updates to all listeners (must hold read lock)
 func (s *PgStore) notifyListeners(update types.EnvelopeUpdate) {
 	listeners := s.listeners[update.ID]
@@ -563,7 +810,12 @@ func (s *PgStore) IsActive(id string) bool {
 	var status types.EnvelopeStatus
 	var deadline *time.Time
 
-	err := s.pool.QueryRow(s.ctx, query, id).Scan(&status, &deadline)
+	db := s.readerFor(id)
+	err := db.QueryRow(s.ctx, query, id).Scan(&status, &deadline)
+	if err == pgx.ErrNoRows && db != s.pool {
+		// Not replicated yet
+		err = s.pool.QueryRow(s.ctx, query, id).Scan(&status, &deadline)
+	}
 	if err != nil {
 		return false
 	}
@@ -581,26 +833,167 @@ func (s *PgStore) IsActive(id string) bool {
 	return true
 }
 
-// handleTimeout handles envelope timeout (called by timer)
-func (s *PgStore) handleTimeout(id string) {
-	// Check if envelope is already in final state before marking as timed out
-	envelope, err := s.Get(id)
+// ListActive returns the IDs of envelopes that are not in a final state and match filter
+func (s *PgStore) ListActive(filter EnvelopeFilter) ([]string, error) {
+	query := `
+		SELECT id
+		FROM envelopes
+		WHERE status NOT IN ('succeeded', 'failed')
+		  AND (cardinality($1::text[]) = 0 OR status = ANY($1::text[]))
+		  AND ($2 = '' OR tool = $2)
+		  AND (NOT $3 OR parent_id IS NULL)
+		ORDER BY id ASC
+	`
+
+	statuses := make([]string, len(filter.Statuses))
+	for i, status := range filter.Statuses {
+		statuses[i] = string(status)
+	}
+
+	rows, err := s.reader().Query(s.ctx, query, statuses, filter.Tool, filter.Roots)
 	if err != nil {
-		fmt.Printf("Failed to get envelope %s for timeout check: %v\n", id, err)
-		s.mu.Lock()
-		delete(s.timers, id)
-		s.mu.Unlock()
-		return
+		return nil, fmt.Errorf("failed to query active envelopes: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan active envelopes: %w", err)
 	}
+	return ids, nil
+}
 
-	// Don't overwrite final states
-	if s.isFinal(envelope.Status) {
-		s.mu.Lock()
-		delete(s.timers, id)
-		s.mu.Unlock()
-		return
+// ListQueuedBefore returns the envelopes still queued that entered the queued status before cutoff.
+// A queued envelope's last status transition is the one to queued.
+func (s *PgStore) ListQueuedBefore(cutoff time.Time) ([]QueuedEnvelope, error) {
+	query := `
+		SELECT id, COALESCE(tool, ''), COALESCE(current_actor_name, ''), queued_at
+		FROM (
+			SELECT id, tool, current_actor_name,
+			       COALESCE((status_history -> -1 ->> 'at')::timestamptz, updated_at) AS queued_at
+			FROM envelopes
+			WHERE status = 'queued'
+		) queued
+		WHERE queued_at < $1
+		ORDER BY id ASC
+	`
+
+	rows, err := s.reader().Query(s.ctx, query, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query queued envelopes: %w", err)
+	}
+	queued, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (QueuedEnvelope, error) {
+		var envelope QueuedEnvelope
+		err := row.Scan(&envelope.ID, &envelope.Tool, &envelope.Actor, &envelope.QueuedAt)
+		return envelope, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan queued envelopes: %w", err)
+	}
+	return queued, nil
+}
+
+// GetChildren retrieves fanout children of an envelope (in branch order)
+func (s *PgStore) GetChildren(parentID string) ([]*types.Envelope, error) {
+	query := `
+		SELECT id
+		FROM envelopes
+		WHERE parent_id = $1
+		ORDER BY branch_index ASC, created_at ASC, id ASC
+	`
+
+	rows, err := s.pool.Query(s.ctx, query, parentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query children: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan children: %w", err)
+	}
+
+	// Read from the primary: children are aggregated right after their final update
+	children := make([]*types.Envelope, 0, len(ids))
+	for _, id := range ids {
+		child, err := s.get(s.pool, id)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+
+	return children, nil
+}
+
+// AddFanoutBranch registers a fanout child branch on the parent envelope.
+// Registering the same fanout again (e.g. a retried create) does not add branches.
+func (s *PgStore) AddFanoutBranch(parentID string, branches int) error {
+	query := `
+		UPDATE envelopes
+		SET fanout_branches = GREATEST(fanout_branches, $2)
+		WHERE id = $1
+	`
+
+	result, err := s.pool.Exec(s.ctx, query, parentID, branches)
+	if err != nil {
+		return fmt.Errorf("failed to add fanout branch: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("envelope %s %w", parentID, ErrNotFound)
+	}
+
+	s.noteWrite(parentID)
+	return nil
+}
+
+// CompleteFanoutBranch records a completed branch on the parent envelope. Completed branch
+// indexes are kept in fanout_branch_completions, so a branch is only counted once.
+func (s *PgStore) CompleteFanoutBranch(parentID string, branchIndex int) (int, int, error) {
+	tx, err := s.pool.Begin(s.ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(s.ctx) }()
+
+	var completed, total int
+	err = tx.QueryRow(s.ctx, `SELECT branches_completed, fanout_branches FROM envelopes WHERE id = $1 FOR UPDATE`, parentID).Scan(&completed, &total)
+	if err == pgx.ErrNoRows {
+		return 0, 0, fmt.Errorf("envelope %s %w", parentID, ErrNotFound)
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to lock envelope: %w", err)
+	}
+
+	insertQuery := `
+		INSERT INTO fanout_branch_completions (parent_id, branch_index)
+		VALUES ($1, $2)
+		ON CONFLICT (parent_id, branch_index) DO NOTHING
+	`
+	result, err := tx.Exec(s.ctx, insertQuery, parentID, branchIndex)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to record fanout branch: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return completed, total, fmt.Errorf("branch %d of envelope %s: %w", branchIndex, parentID, ErrBranchCompleted)
+	}
+
+	updateQuery := `
+		UPDATE envelopes
+		SET branches_completed = branches_completed + 1
+		WHERE id = $1
+		RETURNING branches_completed, fanout_branches
+	`
+	if err := tx.QueryRow(s.ctx, updateQuery, parentID).Scan(&completed, &total); err != nil {
+		return 0, 0, fmt.Errorf("failed to complete fanout branch: %w", err)
 	}
 
+	if err := tx.Commit(s.ctx); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.noteWrite(parentID)
+	return completed, total, nil
+}
+
+// handleTimeout handles envelope timeout (called by timer)
+func (s *PgStore) handleTimeout(id string) {
 	update := types.EnvelopeUpdate{
 		ID:        id,
 		Status:    types.EnvelopeStatusFailed,
@@ -608,7 +1001,10 @@ func (s *PgStore) handleTimeout(id string) {
 		Timestamp: time.Now(),
 	}
 
-	if err := s.Update(update); err != nil {
+	// Final states are not overwritten: the status is checked in the update's transaction,
+	// so a final update committed meanwhile is kept
+	notFinal := func(status types.EnvelopeStatus) bool { return !s.isFinal(status) }
+	if _, err := s.update(update, notFinal); err != nil {
 		fmt.Printf("Failed to update timed out envelope %s: %v\n", id, err)
 	}
 
@@ -625,11 +1021,190 @@ func (s *PgStore) cancelTimer(id string) {
 	}
 }
 
+// reader returns the pool for queries that tolerate replica lag
+func (s *PgStore) reader() *pgxpool.Pool {
+	if s.readPool != nil {
+		return s.readPool
+	}
+	return s.pool
+}
+
+// readerFor returns the pool to read an envelope from: the primary while a recent
+// write by this gateway may not have reached the replica yet. Only this process's writes
+// are known, so an envelope updated through another gateway replica (e.g. a sidecar report
+// balanced to it) is read from the read replica and may be up to the replica lag stale.
+func (s *PgStore) readerFor(id string) *pgxpool.Pool {
+	if s.readPool == nil {
+		return s.pool
+	}
+
+	s.recentMu.Lock()
+	defer s.recentMu.Unlock()
+	if written, ok := s.recentWrites[id]; ok && time.Since(written) < s.readMaxLag {
+		return s.pool
+	}
+	return s.readPool
+}
+
+// noteWrite records a write of an envelope, so it is read from the primary for readMaxLag
+func (s *PgStore) noteWrite(id string) {
+	if s.readPool == nil {
+		return
+	}
+
+	now := time.Now()
+	s.recentMu.Lock()
+	defer s.recentMu.Unlock()
+	s.recentWrites[id] = now
+
+	// Drop writes the replica has caught up with
+	if now.Sub(s.lastPrune) >= s.readMaxLag {
+		for writtenID, written := range s.recentWrites {
+			if now.Sub(written) >= s.readMaxLag {
+				delete(s.recentWrites, writtenID)
+			}
+		}
+		s.lastPrune = now
+	}
+}
+
+// pgTransition is the state of an envelope before an update, returned by the update query
+type pgTransition struct {
+	at        time.Time // When the update's status is entered, if it changes
+	previous  types.EnvelopeStatus
+	enteredAt *time.Time     // When the previous status was entered (nil for envelopes without status history)
+	envelope  types.Envelope // Updated envelope fields reported to the observer
+	tool      *string
+	errorStr  *string
+}
+
+// dest returns the scan destinations of the columns returned by the update queries
+func (t *pgTransition) dest() []any {
+	return []any{
+		&t.previous,
+		&t.enteredAt,
+		&t.envelope.ID,
+		&t.envelope.ParentID,
+		&t.tool,
+		&t.envelope.Route.Actors,
+		&t.errorStr,
+		&t.envelope.CreatedAt,
+	}
+}
+
+// transitionTime returns when the status of an update is entered
+func transitionTime(update types.EnvelopeUpdate) time.Time {
+	if update.Timestamp.IsZero() {
+		return time.Now()
+	}
+	return update.Timestamp
+}
+
+// statusTransitionJSON encodes a status history entry, appended to the stored history
+func statusTransitionJSON(status types.EnvelopeStatus, at time.Time) ([]byte, error) {
+	transitionJSON, err := json.Marshal([]types.StatusTransition{{Status: status, At: at}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal status transition: %w", err)
+	}
+	return transitionJSON, nil
+}
+
+// observeTransition reports a status change of a committed update to the observer
+func (s *PgStore) observeTransition(transition pgTransition, update types.EnvelopeUpdate) {
+	if s.observer == nil || transition.previous == update.Status {
+		return
+	}
+
+	envelope := transition.envelope
+	envelope.Status = update.Status
+	if transition.tool != nil {
+		envelope.Tool = *transition.tool
+	}
+	if transition.errorStr != nil {
+		envelope.Error = *transition.errorStr
+	}
+
+	elapsed := time.Duration(-1)
+	if transition.enteredAt != nil {
+		elapsed = transition.at.Sub(*transition.enteredAt)
+	}
+	s.observer.StatusChanged(Transition{Envelope: &envelope, From: transition.previous, To: update.Status, Elapsed: elapsed})
+}
+
 // isFinal checks if a status is final
 func (s *PgStore) isFinal(status types.EnvelopeStatus) bool {
 	return status == types.EnvelopeStatusSucceeded || status == types.EnvelopeStatusFailed
 }
 
+// StartRetention periodically deletes (or archives) envelopes that finished before the retention window
+func (s *PgStore) StartRetention(ctx context.Context, policy RetentionPolicy) {
+	go runRetention(ctx, policy, func(cutoff time.Time) (int, []string, error) {
+		return s.sweepExpired(cutoff, policy.Archive)
+	})
+}
+
+// sweepExpired removes envelopes in a final state last updated before cutoff in batches;
+// their updates are deleted by cascade. Envelopes streamed by this gateway and children
+// of active parents are kept. With archive, removed rows are copied to envelopes_archive.
+// Returns the offloaded result URLs of the removed envelopes.
+func (s *PgStore) sweepExpired(cutoff time.Time, archive bool) (int, []string, error) {
+	// Only listeners of this replica are known; streams of finished envelopes elsewhere
+	// have received their final update unless opened after the envelope finished
+	s.mu.RLock()
+	streamed := make([]string, 0, len(s.listeners))
+	for id := range s.listeners {
+		streamed = append(streamed, id)
+	}
+	s.mu.RUnlock()
+
+	// Deletes one batch of expired envelopes, locked rows are left to concurrent sweepers
+	expired := `
+		WITH expired AS (
+			DELETE FROM envelopes
+			WHERE id IN (
+				SELECT e.id
+				FROM envelopes e
+				WHERE e.status IN ('succeeded', 'failed')
+				AND e.updated_at < $1
+				AND NOT (e.id = ANY($2::text[]))
+				AND NOT EXISTS (
+					SELECT 1 FROM envelopes p
+					WHERE p.id = e.parent_id
+					AND p.status NOT IN ('succeeded', 'failed')
+				)
+				LIMIT $3
+				FOR UPDATE OF e SKIP LOCKED
+			)
+			RETURNING *
+		)`
+	removed := `
+		SELECT count(*), COALESCE(array_agg(result_url) FILTER (WHERE result_url IS NOT NULL), '{}')
+		FROM expired
+	`
+	removeQuery := expired + removed
+	if archive {
+		removeQuery = expired + `, archived AS (
+			INSERT INTO envelopes_archive (envelope_id, envelope, archived_at)
+			SELECT id, to_jsonb(expired), NOW() FROM expired
+		)` + removed
+	}
+
+	total := 0
+	var resultURLs []string
+	for {
+		var n int
+		var urls []string
+		if err := s.pool.QueryRow(s.ctx, removeQuery, cutoff, streamed, retentionBatchSize).Scan(&n, &urls); err != nil {
+			return total, resultURLs, fmt.Errorf("failed to remove expired envelopes: %w", err)
+		}
+		total += n
+		resultURLs = append(resultURLs, urls...)
+		if n < retentionBatchSize {
+			return total, resultURLs, nil
+		}
+	}
+}
+
 // cleanupOldUpdates periodically removes old job updates (keep last 24 hours)
 func (s *PgStore) cleanupOldUpdates() {
 	ticker := time.NewTicker(1 * time.Hour)
@@ -2,8 +2,10 @@ package envelopestore
 
 import (
 	"testing"
+	"time"
 
 	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -222,3 +224,32 @@ func TestProgressUpdate_TransformToEnvelopeUpdate(t *testing.T) {
 func strPtr(s string) *string {
 	return &s
 }
+
+// TestReaderFor_ReadReplica verifies that envelopes written within the replica lag are read from the primary
+func TestReaderFor_ReadReplica(t *testing.T) {
+	primary, replica := &pgxpool.Pool{}, &pgxpool.Pool{}
+
+	withoutReplica := &PgStore{pool: primary}
+	withoutReplica.noteWrite("env-1")
+	assert.Same(t, primary, withoutReplica.readerFor("env-1"), "without replica, reads should use the primary")
+	assert.Same(t, primary, withoutReplica.reader())
+
+	s := &PgStore{
+		pool:         primary,
+		readPool:     replica,
+		readMaxLag:   50 * time.Millisecond,
+		recentWrites: make(map[string]time.Time),
+	}
+	assert.Same(t, replica, s.readerFor("env-1"), "envelope never written by this gateway should be read from the replica")
+	assert.Same(t, replica, s.reader(), "listings should be read from the replica")
+
+	s.noteWrite("env-1")
+	assert.Same(t, primary, s.readerFor("env-1"), "recently written envelope should be read from the primary")
+	assert.Same(t, replica, s.readerFor("env-2"), "other envelopes should still be read from the replica")
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Same(t, replica, s.readerFor("env-1"), "envelope written longer ago than the lag should be read from the replica")
+
+	s.noteWrite("env-2")
+	assert.NotContains(t, s.recentWrites, "env-1", "writes older than the lag should be pruned")
+}
@@ -0,0 +1,98 @@
+package envelopestore
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+)
+
+// PickupObserver is notified of envelopes that no actor picked up in time, e.g. to export
+// them as a metric to alert on
+type PickupObserver interface {
+	// PickupOverdue reports how many envelopes of each tool are queued longer than the pickup window
+	PickupOverdue(overdue map[string]int)
+}
+
+// PickupWatchdog reports envelopes that stay queued (published, no progress report from an
+// actor yet) longer than a window: their actor is down, not scaled up or far behind.
+// An envelope leaves queued with the first progress report of an actor picking it up.
+type PickupWatchdog struct {
+	store    EnvelopeStore
+	window   time.Duration
+	observer PickupObserver
+	reported map[string]bool // Overdue envelopes already logged
+}
+
+// NewPickupWatchdog creates a watchdog for envelopes queued longer than window
+func NewPickupWatchdog(store EnvelopeStore, window time.Duration) *PickupWatchdog {
+	return &PickupWatchdog{store: store, window: window, reported: make(map[string]bool)}
+}
+
+// SetObserver sets the observer of overdue envelopes
+func (w *PickupWatchdog) SetObserver(observer PickupObserver) {
+	w.observer = observer
+}
+
+// pickupInterval returns how often queued envelopes are checked: a fifth of the window,
+// between a second and a minute
+func pickupInterval(window time.Duration) time.Duration {
+	return min(max(window/5, time.Second), time.Minute)
+}
+
+// Start checks queued envelopes periodically until ctx is done
+func (w *PickupWatchdog) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(pickupInterval(w.window))
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				w.check(time.Now())
+			}
+		}
+	}()
+}
+
+// check logs envelopes that became overdue since the last check and reports all overdue
+// envelopes to the observer. It returns the IDs of the overdue envelopes.
+func (w *PickupWatchdog) check(now time.Time) []string {
+	queued, err := w.store.ListQueuedBefore(now.Add(-w.window))
+	if err != nil {
+		slog.Error("Failed to list queued envelopes", "error", err)
+		return nil
+	}
+
+	overdueIDs := make([]string, 0, len(queued))
+	overdue := make(map[string]int)
+	reported := make(map[string]bool, len(queued))
+	for _, envelope := range queued {
+		overdueIDs = append(overdueIDs, envelope.ID)
+		overdue[envelope.Tool]++
+		reported[envelope.ID] = true
+		if !w.reported[envelope.ID] {
+			slog.Warn("Envelope not picked up by an actor, check that it is running and scaled up",
+				"id", envelope.ID, "tool", envelope.Tool, "actor", envelope.Actor, "waited", now.Sub(envelope.QueuedAt).Round(time.Second))
+		}
+	}
+	w.reported = reported // Forget envelopes that left queued
+
+	if w.observer != nil {
+		w.observer.PickupOverdue(overdue)
+	}
+	return overdueIDs
+}
+
+// QueuedAt returns when an envelope entered the queued status
+func QueuedAt(envelope *types.Envelope) time.Time {
+	for i := len(envelope.StatusHistory) - 1; i >= 0; i-- {
+		if envelope.StatusHistory[i].Status == types.EnvelopeStatusQueued {
+			return envelope.StatusHistory[i].At
+		}
+	}
+	return envelope.UpdatedAt
+}
@@ -0,0 +1,83 @@
+package envelopestore
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+)
+
+// overdueRecorder records the last report of a PickupWatchdog
+type overdueRecorder struct {
+	overdue map[string]int
+}
+
+func (r *overdueRecorder) PickupOverdue(overdue map[string]int) {
+	r.overdue = overdue
+}
+
+func TestPickupWatchdog_Check(t *testing.T) {
+	store := NewStore()
+	now := time.Now()
+
+	create := func(id string, queuedFor time.Duration) {
+		t.Helper()
+		envelope := &types.Envelope{ID: id, Tool: "summarize", Status: types.EnvelopeStatusPending, Route: types.Route{Actors: []string{"prep"}}}
+		if err := store.Create(envelope); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if queuedFor < 0 {
+			return // Never published
+		}
+		if err := store.Update(types.EnvelopeUpdate{ID: id, Status: types.EnvelopeStatusQueued, Timestamp: now.Add(-queuedFor)}); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+	}
+	create("waiting-long", 5*time.Minute)
+	create("waiting-short", 10*time.Second)
+	create("picked-up", 5*time.Minute)
+	create("not-published", -1)
+
+	if err := store.UpdateProgress(types.EnvelopeUpdate{ID: "picked-up", Status: types.EnvelopeStatusRunning, Timestamp: now}); err != nil {
+		t.Fatalf("UpdateProgress() error = %v", err)
+	}
+
+	recorder := &overdueRecorder{}
+	watchdog := NewPickupWatchdog(store, time.Minute)
+	watchdog.SetObserver(recorder)
+
+	if got := watchdog.check(now); !reflect.DeepEqual(got, []string{"waiting-long"}) {
+		t.Errorf("check() = %v, want [waiting-long]", got)
+	}
+	if want := map[string]int{"summarize": 1}; !reflect.DeepEqual(recorder.overdue, want) {
+		t.Errorf("PickupOverdue() = %v, want %v", recorder.overdue, want)
+	}
+
+	// Once an actor reports progress the envelope is no longer overdue
+	if err := store.UpdateProgress(types.EnvelopeUpdate{ID: "waiting-long", Status: types.EnvelopeStatusRunning, Timestamp: now}); err != nil {
+		t.Fatalf("UpdateProgress() error = %v", err)
+	}
+	if got := watchdog.check(now.Add(time.Minute)); !reflect.DeepEqual(got, []string{"waiting-short"}) {
+		t.Errorf("check() = %v, want [waiting-short]", got)
+	}
+	if len(watchdog.reported) != 1 || !watchdog.reported["waiting-short"] {
+		t.Errorf("reported = %v, want only waiting-short", watchdog.reported)
+	}
+}
+
+func TestPickupInterval(t *testing.T) {
+	tests := []struct {
+		window time.Duration
+		want   time.Duration
+	}{
+		{window: 2 * time.Second, want: time.Second},
+		{window: time.Minute, want: 12 * time.Second},
+		{window: time.Hour, want: time.Minute},
+	}
+	for _, tt := range tests {
+		if got := pickupInterval(tt.window); got != tt.want {
+			t.Errorf("pickupInterval(%s) = %s, want %s", tt.window, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package envelopestore
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Envelopes removed per retention query, so a large backlog is swept in short transactions
+const retentionBatchSize = 1000
+
+// RetentionPolicy controls how long envelopes in a final state are kept
+type RetentionPolicy struct {
+	After   time.Duration // Envelopes not updated for this long after finishing are removed
+	Archive bool          // Move removed envelopes to the archive instead of deleting them (PostgreSQL only)
+
+	// DeleteResult removes the offloaded result (result_url) of a removed envelope from object
+	// storage. Nil keeps the objects; archived envelopes keep theirs since the archive references them.
+	DeleteResult func(ctx context.Context, url string) error
+}
+
+// RetentionSweeper is implemented by stores that can remove envelopes that finished long ago
+type RetentionSweeper interface {
+	// StartRetention periodically removes expired envelopes until ctx is done.
+	// Envelopes with open update listeners (SSE streams) are kept until the listeners leave,
+	// and finished fanout children are kept while their parent is still active.
+	StartRetention(ctx context.Context, policy RetentionPolicy)
+}
+
+// retentionInterval returns how often envelopes are swept: a tenth of the retention,
+// between a second and an hour
+func retentionInterval(after time.Duration) time.Duration {
+	return min(max(after/10, time.Second), time.Hour)
+}
+
+// sweepFunc removes envelopes that finished before cutoff and returns how many were removed
+// and the offloaded result URLs to delete
+type sweepFunc func(cutoff time.Time) (removed int, resultURLs []string, err error)
+
+// runRetention calls sweep every interval until ctx is done
+func runRetention(ctx context.Context, policy RetentionPolicy, sweep sweepFunc) {
+	ticker := time.NewTicker(retentionInterval(policy.After))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			removed, resultURLs, err := sweep(time.Now().Add(-policy.After))
+			// Envelopes removed before an error still release their results
+			deleteResults(ctx, policy, resultURLs)
+			if err != nil {
+				slog.Error("Failed to remove expired envelopes", "error", err)
+				continue
+			}
+			if removed > 0 {
+				slog.Info("Removed expired envelopes", "count", removed, "archived", policy.Archive)
+			}
+		}
+	}
+}
+
+// deleteResults removes offloaded results of removed envelopes. Failures are logged:
+// the envelopes are gone, so a bucket lifecycle rule has to catch objects left behind.
+func deleteResults(ctx context.Context, policy RetentionPolicy, resultURLs []string) {
+	if policy.DeleteResult == nil || policy.Archive {
+		return
+	}
+	for _, url := range resultURLs {
+		if err := policy.DeleteResult(ctx, url); err != nil {
+			slog.Warn("Failed to delete offloaded result of expired envelope", "url", url, "error", err)
+		}
+	}
+}
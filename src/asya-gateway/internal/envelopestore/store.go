@@ -1,7 +1,9 @@
 package envelopestore
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -15,6 +17,8 @@ type Store struct {
 	listeners map[string][]chan types.EnvelopeUpdate
 	timers    map[string]*time.Timer
 	updates   map[string][]types.EnvelopeUpdate // Historical updates for SSE replay
+	branches  map[string]map[int]bool           // Completed fanout branch indexes per parent
+	observer  TransitionObserver
 }
 
 // NewStore creates a new envelope store
@@ -24,9 +28,15 @@ func NewStore() *Store {
 		listeners: make(map[string][]chan types.EnvelopeUpdate),
 		timers:    make(map[string]*time.Timer),
 		updates:   make(map[string][]types.EnvelopeUpdate),
+		branches:  make(map[string]map[int]bool),
 	}
 }
 
+// SetTransitionObserver sets the observer of status changes. Must be called before the store is used.
+func (s *Store) SetTransitionObserver(observer TransitionObserver) {
+	s.observer = observer
+}
+
 // Create creates a new envelope
 func (s *Store) Create(envelope *types.Envelope) error {
 	s.mu.Lock()
@@ -40,18 +50,20 @@ func (s *Store) Create(envelope *types.Envelope) error {
 	envelope.CreatedAt = now
 	envelope.UpdatedAt = now
 	envelope.Status = types.EnvelopeStatusPending
+	envelope.StatusHistory = []types.StatusTransition{{Status: types.EnvelopeStatusPending, At: now}}
 
 	// Initialize progress tracking
 	envelope.TotalActors = len(envelope.Route.Actors)
 	envelope.ActorsCompleted = 0
 	envelope.ProgressPercent = 0.0
+	setCurrentActor(envelope, envelope.Route.Current)
 
 	// Set deadline if timeout specified
 	if envelope.TimeoutSec > 0 {
 		envelope.Deadline = now.Add(time.Duration(envelope.TimeoutSec) * time.Second)
 
 		// Start timeout timer
-		s.timers[envelope.ID] = time.AfterFunc(time.Duration(envelope.TimeoutSec)*time.Second, func() {
+		s.timers[envelope.ID] = time.AfterFunc(timeoutDelay(time.Duration(envelope.TimeoutSec)*time.Second), func() {
 			s.handleTimeout(envelope.ID)
 		})
 	}
@@ -67,10 +79,12 @@ func (s *Store) Get(id string) (*types.Envelope, error) {
 
 	envelope, exists := s.envelopes[id]
 	if !exists {
-		return nil, fmt.Errorf("envelope %s not found", id)
+		return nil, fmt.Errorf("envelope %s %w", id, ErrNotFound)
 	}
 
-	return envelope, nil
+	// Return a copy: the stored envelope keeps changing under the lock after Get returns
+	snapshot := *envelope
+	return &snapshot, nil
 }
 
 // Update updates a envelope's status
@@ -80,16 +94,46 @@ func (s *Store) Update(update types.EnvelopeUpdate) error {
 
 	envelope, exists := s.envelopes[update.ID]
 	if !exists {
-		return fmt.Errorf("envelope %s not found", update.ID)
+		return fmt.Errorf("envelope %s %w", update.ID, ErrNotFound)
 	}
 
-	envelope.Status = update.Status
+	s.applyUpdate(envelope, update)
+	return nil
+}
+
+// UpdateIfStatus applies the update only while the envelope is in status expected,
+// checked and applied under the lock
+func (s *Store) UpdateIfStatus(update types.EnvelopeUpdate, expected types.EnvelopeStatus) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	envelope, exists := s.envelopes[update.ID]
+	if !exists {
+		return false, fmt.Errorf("envelope %s %w", update.ID, ErrNotFound)
+	}
+	if envelope.Status != expected {
+		return false, nil
+	}
+
+	s.applyUpdate(envelope, update)
+	return true, nil
+}
+
+// applyUpdate applies an update to a stored envelope and notifies listeners (must hold lock)
+func (s *Store) applyUpdate(envelope *types.Envelope, update types.EnvelopeUpdate) {
+	transition := s.setStatus(envelope, update.Status, update.Timestamp)
 	envelope.UpdatedAt = update.Timestamp
 
 	if update.Result != nil {
 		envelope.Result = update.Result
 	}
 
+	// An offloaded result replaces any result stored inline
+	if update.ResultURL != "" {
+		envelope.ResultURL = update.ResultURL
+		envelope.Result = nil
+	}
+
 	if update.Error != "" {
 		envelope.Error = update.Error
 	}
@@ -98,18 +142,15 @@ func (s *Store) Update(update types.EnvelopeUpdate) error {
 		envelope.ProgressPercent = *update.ProgressPercent
 	}
 
-	if update.CurrentActorIdx != nil {
-		envelope.CurrentActorIdx = *update.CurrentActorIdx
-		if *update.CurrentActorIdx >= 0 && *update.CurrentActorIdx < len(update.Actors) {
-			envelope.CurrentActorName = update.Actors[*update.CurrentActorIdx]
-		}
-	}
-
 	if len(update.Actors) > 0 {
 		envelope.Route.Actors = update.Actors
 		envelope.TotalActors = len(update.Actors)
 	}
 
+	if update.CurrentActorIdx != nil {
+		setCurrentActor(envelope, *update.CurrentActorIdx)
+	}
+
 	// Cancel timeout timer if envelope reaches final state
 	if s.isFinal(update.Status) {
 		s.cancelTimer(update.ID)
@@ -118,10 +159,10 @@ func (s *Store) Update(update types.EnvelopeUpdate) error {
 	// Store update in history
 	s.updates[update.ID] = append(s.updates[update.ID], update)
 
+	s.observe(transition, envelope)
+
 	// Notify listeners
 	s.notifyListeners(update)
-
-	return nil
 }
 
 // UpdateProgress updates envelope progress (lighter weight update for frequent progress reports)
@@ -131,23 +172,16 @@ func (s *Store) UpdateProgress(update types.EnvelopeUpdate) error {
 
 	envelope, exists := s.envelopes[update.ID]
 	if !exists {
-		return fmt.Errorf("envelope %s not found", update.ID)
+		return fmt.Errorf("envelope %s %w", update.ID, ErrNotFound)
 	}
 
-	envelope.Status = update.Status
+	transition := s.setStatus(envelope, update.Status, update.Timestamp)
 	envelope.UpdatedAt = update.Timestamp
 
 	if update.ProgressPercent != nil {
 		envelope.ProgressPercent = *update.ProgressPercent
 	}
 
-	if update.CurrentActorIdx != nil {
-		envelope.CurrentActorIdx = *update.CurrentActorIdx
-		if *update.CurrentActorIdx >= 0 && *update.CurrentActorIdx < len(update.Actors) {
-			envelope.CurrentActorName = update.Actors[*update.CurrentActorIdx]
-		}
-	}
-
 	if update.Message != "" {
 		envelope.Message = update.Message
 	}
@@ -157,9 +191,21 @@ func (s *Store) UpdateProgress(update types.EnvelopeUpdate) error {
 		envelope.TotalActors = len(update.Actors)
 	}
 
+	if update.CurrentActorIdx != nil {
+		setCurrentActor(envelope, *update.CurrentActorIdx)
+	}
+
+	if update.PartialResult != nil {
+		envelope.PartialResult = update.PartialResult
+	}
+
+	envelope.Warnings = append(envelope.Warnings, update.Warnings...)
+
 	// Store update in history
 	s.updates[update.ID] = append(s.updates[update.ID], update)
 
+	s.observe(transition, envelope)
+
 	// Notify listeners
 	s.notifyListeners(update)
 
@@ -220,6 +266,18 @@ func (s *Store) Unsubscribe(id string, ch chan types.EnvelopeUpdate) {
 	}
 }
 
+// Subscribers returns the number of open update listeners, to detect listeners that are never unsubscribed
+func (s *Store) Subscribers() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := 0
+	for _, listeners := range s.listeners {
+		n += len(listeners)
+	}
+	return n
+}
+
 // notifyListeners sends updates to all listeners (must hold lock)
 func (s *Store) notifyListeners(update types.EnvelopeUpdate) {
 	listeners := s.listeners[update.ID]
@@ -255,6 +313,140 @@ func (s *Store) IsActive(id string) bool {
 	return true
 }
 
+// ListActive returns the IDs of envelopes that are not in a final state and match filter
+func (s *Store) ListActive(filter EnvelopeFilter) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var ids []string
+	for id, envelope := range s.envelopes {
+		if !s.isFinal(envelope.Status) && filter.Matches(envelope) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// ListQueuedBefore returns the envelopes still queued that entered the queued status before cutoff
+func (s *Store) ListQueuedBefore(cutoff time.Time) ([]QueuedEnvelope, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var queued []QueuedEnvelope
+	for id, envelope := range s.envelopes {
+		if envelope.Status != types.EnvelopeStatusQueued {
+			continue
+		}
+		if queuedAt := QueuedAt(envelope); queuedAt.Before(cutoff) {
+			queued = append(queued, QueuedEnvelope{ID: id, Tool: envelope.Tool, Actor: envelope.CurrentActorName, QueuedAt: queuedAt})
+		}
+	}
+	sort.Slice(queued, func(i, j int) bool { return queued[i].ID < queued[j].ID })
+	return queued, nil
+}
+
+// GetChildren retrieves fanout children of an envelope (in branch order)
+func (s *Store) GetChildren(parentID string) ([]*types.Envelope, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var children []*types.Envelope
+	for _, envelope := range s.envelopes {
+		if envelope.ParentID != nil && *envelope.ParentID == parentID {
+			children = append(children, envelope)
+		}
+	}
+
+	sort.Slice(children, func(i, j int) bool {
+		if children[i].BranchIndex != children[j].BranchIndex {
+			return children[i].BranchIndex < children[j].BranchIndex
+		}
+		if children[i].CreatedAt.Equal(children[j].CreatedAt) {
+			return children[i].ID < children[j].ID
+		}
+		return children[i].CreatedAt.Before(children[j].CreatedAt)
+	})
+
+	return children, nil
+}
+
+// AddFanoutBranch registers a fanout child branch on the parent envelope.
+// Registering the same fanout again (e.g. a retried create) does not add branches.
+func (s *Store) AddFanoutBranch(parentID string, branches int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	envelope, exists := s.envelopes[parentID]
+	if !exists {
+		return fmt.Errorf("envelope %s %w", parentID, ErrNotFound)
+	}
+
+	envelope.FanoutBranches = max(envelope.FanoutBranches, branches)
+	return nil
+}
+
+// CompleteFanoutBranch records a completed branch on the parent envelope
+func (s *Store) CompleteFanoutBranch(parentID string, branchIndex int) (int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	envelope, exists := s.envelopes[parentID]
+	if !exists {
+		return 0, 0, fmt.Errorf("envelope %s %w", parentID, ErrNotFound)
+	}
+
+	completed := s.branches[parentID]
+	if completed[branchIndex] {
+		return envelope.BranchesCompleted, envelope.FanoutBranches, fmt.Errorf("branch %d of envelope %s: %w", branchIndex, parentID, ErrBranchCompleted)
+	}
+	if completed == nil {
+		completed = make(map[int]bool)
+		s.branches[parentID] = completed
+	}
+	completed[branchIndex] = true
+
+	envelope.BranchesCompleted = len(completed)
+	return envelope.BranchesCompleted, envelope.FanoutBranches, nil
+}
+
+// StartRetention periodically deletes envelopes that finished before the retention window.
+// The in-memory store keeps no archive, so policy.Archive is ignored.
+func (s *Store) StartRetention(ctx context.Context, policy RetentionPolicy) {
+	go runRetention(ctx, policy, s.sweepExpired)
+}
+
+// sweepExpired deletes envelopes in a final state last updated before cutoff, with their update
+// history, and returns their offloaded result URLs. Envelopes with listeners and children
+// of active parents are kept.
+func (s *Store) sweepExpired(cutoff time.Time) (int, []string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	var resultURLs []string
+	for id, envelope := range s.envelopes {
+		if !s.isFinal(envelope.Status) || !envelope.UpdatedAt.Before(cutoff) || len(s.listeners[id]) > 0 {
+			continue
+		}
+		if envelope.ParentID != nil {
+			if parent, exists := s.envelopes[*envelope.ParentID]; exists && !s.isFinal(parent.Status) {
+				continue
+			}
+		}
+
+		delete(s.envelopes, id)
+		delete(s.updates, id)
+		delete(s.branches, id)
+		s.cancelTimer(id)
+		removed++
+		if envelope.ResultURL != "" {
+			resultURLs = append(resultURLs, envelope.ResultURL)
+		}
+	}
+	return removed, resultURLs, nil
+}
+
 // handleTimeout handles envelope timeout (called by timer)
 func (s *Store) handleTimeout(id string) {
 	s.mu.Lock()
@@ -270,16 +462,18 @@ func (s *Store) handleTimeout(id string) {
 		return
 	}
 
-	envelope.Status = types.EnvelopeStatusFailed
+	now := time.Now()
+	transition := s.setStatus(envelope, types.EnvelopeStatusFailed, now)
 	envelope.Error = "envelope timed out"
-	envelope.UpdatedAt = time.Now()
+	envelope.UpdatedAt = now
+	s.observe(transition, envelope)
 
 	// Notify listeners
 	update := types.EnvelopeUpdate{
 		ID:        id,
 		Status:    types.EnvelopeStatusFailed,
 		Error:     "envelope timed out",
-		Timestamp: time.Now(),
+		Timestamp: now,
 	}
 	s.notifyListeners(update)
 
@@ -295,6 +489,46 @@ func (s *Store) cancelTimer(id string) {
 	}
 }
 
+// setStatus moves the envelope to status, recording when it entered a new status (must hold lock).
+// It returns the transition to report once the rest of the update is applied, nil if the
+// status did not change.
+func (s *Store) setStatus(envelope *types.Envelope, status types.EnvelopeStatus, at time.Time) *Transition {
+	if envelope.Status == status {
+		return nil
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	transition := &Transition{From: envelope.Status, To: status, Elapsed: -1}
+	if n := len(envelope.StatusHistory); n > 0 {
+		transition.Elapsed = at.Sub(envelope.StatusHistory[n-1].At)
+	}
+	envelope.Status = status
+	envelope.StatusHistory = append(envelope.StatusHistory, types.StatusTransition{Status: status, At: at})
+	return transition
+}
+
+// observe reports a transition to the observer with a copy of the updated envelope (must hold lock)
+func (s *Store) observe(transition *Transition, envelope *types.Envelope) {
+	if transition == nil || s.observer == nil {
+		return
+	}
+	snapshot := *envelope
+	transition.Envelope = &snapshot
+	s.observer.StatusChanged(*transition)
+}
+
+// setCurrentActor moves the envelope to the given route position and derives the
+// current actor name from the route, so it stays accurate for pending/queued envelopes
+// and when progress reports omit the actors list (must hold lock)
+func setCurrentActor(envelope *types.Envelope, idx int) {
+	envelope.CurrentActorIdx = idx
+	envelope.Route.Current = idx
+	if idx >= 0 && idx < len(envelope.Route.Actors) {
+		envelope.CurrentActorName = envelope.Route.Actors[idx]
+	}
+}
+
 // isFinal checks if a status is final (must hold lock)
 func (s *Store) isFinal(status types.EnvelopeStatus) bool {
 	return status == types.EnvelopeStatusSucceeded || status == types.EnvelopeStatusFailed
@@ -1,6 +1,9 @@
 package envelopestore
 
 import (
+	"context"
+	"errors"
+	"reflect"
 	"testing"
 	"time"
 
@@ -520,6 +523,48 @@ func TestUpdate(t *testing.T) {
 	}
 }
 
+// TestCurrentActor_DerivedFromRoute tests that the current actor name follows the route position
+func TestCurrentActor_DerivedFromRoute(t *testing.T) {
+	store := NewStore()
+
+	job := &types.Envelope{
+		ID: "test-job-current-actor",
+		Route: types.Route{
+			Actors:  []string{"actor1", "actor2", "actor3"},
+			Current: 1,
+		},
+	}
+
+	if err := store.Create(job); err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+
+	created, _ := store.Get("test-job-current-actor")
+	if created.CurrentActorIdx != 1 || created.CurrentActorName != "actor2" {
+		t.Errorf("After create: CurrentActorIdx = %d, CurrentActorName = %q, want 1, actor2",
+			created.CurrentActorIdx, created.CurrentActorName)
+	}
+
+	// Progress report without actors list: name derived from stored route
+	update := types.EnvelopeUpdate{
+		ID:              "test-job-current-actor",
+		Status:          types.EnvelopeStatusRunning,
+		CurrentActorIdx: intPtr(2),
+		Timestamp:       time.Now(),
+	}
+	if err := store.UpdateProgress(update); err != nil {
+		t.Fatalf("UpdateProgress failed: %v", err)
+	}
+
+	updated, _ := store.Get("test-job-current-actor")
+	if updated.CurrentActorName != "actor3" {
+		t.Errorf("CurrentActorName = %q, want actor3", updated.CurrentActorName)
+	}
+	if updated.Route.Current != 2 {
+		t.Errorf("Route.Current = %d, want 2", updated.Route.Current)
+	}
+}
+
 // TestIsActive tests the IsActive method
 func TestIsActive(t *testing.T) {
 	tests := []struct {
@@ -654,6 +699,28 @@ func TestHandleTimeout(t *testing.T) {
 	}
 }
 
+// TestTimeoutDelay tests that timeout timers are jittered within bounds
+func TestTimeoutDelay(t *testing.T) {
+	tests := []struct {
+		timeout   time.Duration
+		maxJitter time.Duration
+	}{
+		{0, 0},
+		{time.Second, 50 * time.Millisecond},
+		{time.Minute, 3 * time.Second},
+		{time.Hour, maxTimeoutJitter},
+	}
+
+	for _, tt := range tests {
+		for range 100 {
+			delay := timeoutDelay(tt.timeout)
+			if delay < tt.timeout || delay > tt.timeout+tt.maxJitter {
+				t.Fatalf("timeoutDelay(%v) = %v, want within [%v, %v]", tt.timeout, delay, tt.timeout, tt.timeout+tt.maxJitter)
+			}
+		}
+	}
+}
+
 // TestCancelTimer tests timer cancellation on final status
 func TestCancelTimer(t *testing.T) {
 	store := NewStore()
@@ -691,6 +758,41 @@ func TestCancelTimer(t *testing.T) {
 	}
 }
 
+// TestUpdateIfStatus tests that conditional updates only apply from the expected status
+func TestUpdateIfStatus(t *testing.T) {
+	store := NewStore()
+
+	env := &types.Envelope{
+		ID:    "test-update-if-status",
+		Route: types.Route{Actors: []string{"actor1"}},
+	}
+	if err := store.Create(env); err != nil {
+		t.Fatalf("Failed to create envelope: %v", err)
+	}
+
+	queued := types.EnvelopeUpdate{ID: env.ID, Status: types.EnvelopeStatusQueued, Timestamp: time.Now()}
+	applied, err := store.UpdateIfStatus(queued, types.EnvelopeStatusPending)
+	if err != nil || !applied {
+		t.Fatalf("UpdateIfStatus from pending = %v, %v, want applied", applied, err)
+	}
+
+	// An actor reports running before a second queued update arrives: it must not regress
+	if err := store.Update(types.EnvelopeUpdate{ID: env.ID, Status: types.EnvelopeStatusRunning, Timestamp: time.Now()}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	applied, err = store.UpdateIfStatus(queued, types.EnvelopeStatusPending)
+	if err != nil || applied {
+		t.Fatalf("UpdateIfStatus from running = %v, %v, want not applied", applied, err)
+	}
+	if got, _ := store.Get(env.ID); got.Status != types.EnvelopeStatusRunning {
+		t.Errorf("Status = %v, want %v", got.Status, types.EnvelopeStatusRunning)
+	}
+
+	if _, err := store.UpdateIfStatus(types.EnvelopeUpdate{ID: "nonexistent"}, types.EnvelopeStatusPending); err == nil {
+		t.Error("Expected error for non-existent envelope, got nil")
+	}
+}
+
 // TestCreateDuplicate tests creating duplicate envelopes
 func TestCreateDuplicate(t *testing.T) {
 	store := NewStore()
@@ -807,3 +909,395 @@ func floatPtr(f float64) *float64 {
 func intPtr(i int) *int {
 	return &i
 }
+
+func TestFanoutBranches(t *testing.T) {
+	store := NewStore()
+
+	parentID := "fanout-parent"
+	if err := store.Create(&types.Envelope{ID: parentID, Route: types.Route{Actors: []string{"actor1"}}}); err != nil {
+		t.Fatalf("Failed to create parent: %v", err)
+	}
+
+	for i, childID := range []string{"fanout-parent-1", "fanout-parent-2"} {
+		if err := store.Create(&types.Envelope{ID: childID, ParentID: &parentID, BranchIndex: i + 1, Route: types.Route{Actors: []string{"actor1"}}}); err != nil {
+			t.Fatalf("Failed to create child: %v", err)
+		}
+		if err := store.AddFanoutBranch(parentID, 3); err != nil {
+			t.Fatalf("AddFanoutBranch failed: %v", err)
+		}
+	}
+
+	children, err := store.GetChildren(parentID)
+	if err != nil {
+		t.Fatalf("GetChildren failed: %v", err)
+	}
+	if len(children) != 2 || children[0].ID != "fanout-parent-1" || children[1].ID != "fanout-parent-2" {
+		t.Errorf("GetChildren returned unexpected children: %v", children)
+	}
+
+	// Parent's own branch plus two children, completing in any order
+	for i, branchIndex := range []int{2, 0, 1} {
+		completed, total, err := store.CompleteFanoutBranch(parentID, branchIndex)
+		if err != nil {
+			t.Fatalf("CompleteFanoutBranch failed: %v", err)
+		}
+		if completed != i+1 || total != 3 {
+			t.Errorf("CompleteFanoutBranch = (%d, %d), want (%d, 3)", completed, total, i+1)
+		}
+	}
+
+	// A redelivered branch final is not counted again
+	completed, _, err := store.CompleteFanoutBranch(parentID, 1)
+	if !errors.Is(err, ErrBranchCompleted) {
+		t.Errorf("Completing branch 1 twice: error = %v, want ErrBranchCompleted", err)
+	}
+	if completed != 3 {
+		t.Errorf("Completed branches after duplicate = %d, want 3", completed)
+	}
+
+	if err := store.AddFanoutBranch("nonexistent", 2); err == nil {
+		t.Error("Expected error for nonexistent parent")
+	}
+}
+
+func TestAddFanoutBranch_Idempotent(t *testing.T) {
+	store := NewStore()
+
+	parentID := "fanout-parent"
+	if err := store.Create(&types.Envelope{ID: parentID, Route: types.Route{Actors: []string{"actor1"}}}); err != nil {
+		t.Fatalf("Failed to create parent: %v", err)
+	}
+
+	// A retried create and children announced one at a time do not grow the fanout past its branches
+	for _, branches := range []int{3, 3, 2} {
+		if err := store.AddFanoutBranch(parentID, branches); err != nil {
+			t.Fatalf("AddFanoutBranch failed: %v", err)
+		}
+	}
+
+	parent, _ := store.Get(parentID)
+	if parent.FanoutBranches != 3 {
+		t.Errorf("FanoutBranches = %d, want 3", parent.FanoutBranches)
+	}
+}
+
+func TestSubscribers_InMemoryStore(t *testing.T) {
+	store := NewStore()
+
+	first := store.Subscribe("job-1")
+	second := store.Subscribe("job-1")
+	third := store.Subscribe("job-2")
+	if got := store.Subscribers(); got != 3 {
+		t.Fatalf("Subscribers() = %d, want 3", got)
+	}
+
+	store.Unsubscribe("job-1", first)
+	store.Unsubscribe("job-2", third)
+	if got := store.Subscribers(); got != 1 {
+		t.Errorf("Subscribers() = %d after unsubscribing two, want 1", got)
+	}
+
+	store.Unsubscribe("job-1", second)
+	if got := store.Subscribers(); got != 0 {
+		t.Errorf("Subscribers() = %d after unsubscribing all, want 0", got)
+	}
+}
+
+func TestListActive_InMemoryStore(t *testing.T) {
+	store := NewStore()
+
+	envelopes := []struct {
+		id     string
+		tool   string
+		status types.EnvelopeStatus
+	}{
+		{"env-1", "summarize", types.EnvelopeStatusPending},
+		{"env-2", "summarize", types.EnvelopeStatusRunning},
+		{"env-3", "summarize", types.EnvelopeStatusSucceeded},
+		{"env-4", "translate", types.EnvelopeStatusQueued},
+		{"env-5", "translate", types.EnvelopeStatusFailed},
+	}
+	for _, e := range envelopes {
+		if err := store.Create(&types.Envelope{ID: e.id, Tool: e.tool, Route: types.Route{Actors: []string{"actor1"}}}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if e.status != types.EnvelopeStatusPending {
+			if err := store.Update(types.EnvelopeUpdate{ID: e.id, Status: e.status, Timestamp: time.Now()}); err != nil {
+				t.Fatalf("Update failed: %v", err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter EnvelopeFilter
+		want   []string
+	}{
+		{name: "all active", filter: EnvelopeFilter{}, want: []string{"env-1", "env-2", "env-4"}},
+		{name: "by tool", filter: EnvelopeFilter{Tool: "summarize"}, want: []string{"env-1", "env-2"}},
+		{name: "by status", filter: EnvelopeFilter{Statuses: []types.EnvelopeStatus{types.EnvelopeStatusPending, types.EnvelopeStatusQueued}}, want: []string{"env-1", "env-4"}},
+		{name: "by tool and status", filter: EnvelopeFilter{Tool: "translate", Statuses: []types.EnvelopeStatus{types.EnvelopeStatusRunning}}, want: nil},
+		{name: "final statuses never match", filter: EnvelopeFilter{Statuses: []types.EnvelopeStatus{types.EnvelopeStatusSucceeded}}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.ListActive(tt.filter)
+			if err != nil {
+				t.Fatalf("ListActive failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListActive() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListActive_Roots(t *testing.T) {
+	store := NewStore()
+	parentID := "env-1"
+	for _, envelope := range []*types.Envelope{
+		{ID: "env-1", Route: types.Route{Actors: []string{"actor1"}}},
+		{ID: "env-1-1", ParentID: &parentID, Route: types.Route{Actors: []string{"actor1"}}},
+		{ID: "env-2", Route: types.Route{Actors: []string{"actor1"}}},
+	} {
+		if err := store.Create(envelope); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	got, err := store.ListActive(EnvelopeFilter{Roots: true})
+	if err != nil {
+		t.Fatalf("ListActive failed: %v", err)
+	}
+	if want := []string{"env-1", "env-2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListActive() = %v, want %v", got, want)
+	}
+}
+
+type recordedTransition struct {
+	tool     string
+	from, to types.EnvelopeStatus
+	elapsed  time.Duration
+}
+
+type transitionRecorder struct {
+	transitions []recordedTransition
+	envelopes   []types.Envelope
+}
+
+func (r *transitionRecorder) StatusChanged(transition Transition) {
+	r.transitions = append(r.transitions, recordedTransition{tool: transition.Envelope.Tool, from: transition.From, to: transition.To, elapsed: transition.Elapsed})
+	r.envelopes = append(r.envelopes, *transition.Envelope)
+}
+
+func TestStatusHistory_InMemoryStore(t *testing.T) {
+	store := NewStore()
+	recorder := &transitionRecorder{}
+	store.SetTransitionObserver(recorder)
+
+	envelope := &types.Envelope{ID: "env-1", Tool: "summarize", Route: types.Route{Actors: []string{"actor1"}}}
+	if err := store.Create(envelope); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	created := envelope.CreatedAt
+
+	queuedAt := created.Add(100 * time.Millisecond)
+	runningAt := created.Add(2 * time.Second)
+	doneAt := created.Add(5 * time.Second)
+	idx := 0
+	updates := []struct {
+		update   types.EnvelopeUpdate
+		progress bool
+	}{
+		{update: types.EnvelopeUpdate{ID: "env-1", Status: types.EnvelopeStatusQueued, Timestamp: queuedAt}},
+		{update: types.EnvelopeUpdate{ID: "env-1", Status: types.EnvelopeStatusRunning, CurrentActorIdx: &idx, Timestamp: runningAt}, progress: true},
+		{update: types.EnvelopeUpdate{ID: "env-1", Status: types.EnvelopeStatusRunning, CurrentActorIdx: &idx, Timestamp: runningAt.Add(time.Second)}, progress: true},
+		{update: types.EnvelopeUpdate{ID: "env-1", Status: types.EnvelopeStatusSucceeded, Timestamp: doneAt}},
+		{update: types.EnvelopeUpdate{ID: "env-1", Status: types.EnvelopeStatusSucceeded, Timestamp: doneAt.Add(time.Second)}}, // Redelivered final
+	}
+	for _, u := range updates {
+		var err error
+		if u.progress {
+			err = store.UpdateProgress(u.update)
+		} else {
+			err = store.Update(u.update)
+		}
+		if err != nil {
+			t.Fatalf("update to %s failed: %v", u.update.Status, err)
+		}
+	}
+
+	got, err := store.Get("env-1")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	wantHistory := []types.StatusTransition{
+		{Status: types.EnvelopeStatusPending, At: created},
+		{Status: types.EnvelopeStatusQueued, At: queuedAt},
+		{Status: types.EnvelopeStatusRunning, At: runningAt},
+		{Status: types.EnvelopeStatusSucceeded, At: doneAt},
+	}
+	if !reflect.DeepEqual(got.StatusHistory, wantHistory) {
+		t.Errorf("StatusHistory = %+v, want %+v", got.StatusHistory, wantHistory)
+	}
+
+	wantTransitions := []recordedTransition{
+		{tool: "summarize", from: types.EnvelopeStatusPending, to: types.EnvelopeStatusQueued, elapsed: 100 * time.Millisecond},
+		{tool: "summarize", from: types.EnvelopeStatusQueued, to: types.EnvelopeStatusRunning, elapsed: 1900 * time.Millisecond},
+		{tool: "summarize", from: types.EnvelopeStatusRunning, to: types.EnvelopeStatusSucceeded, elapsed: 3 * time.Second},
+	}
+	if !reflect.DeepEqual(recorder.transitions, wantTransitions) {
+		t.Errorf("observed transitions = %+v, want %+v", recorder.transitions, wantTransitions)
+	}
+}
+
+func TestStatusHistory_Timeout(t *testing.T) {
+	store := NewStore()
+	recorder := &transitionRecorder{}
+	store.SetTransitionObserver(recorder)
+
+	if err := store.Create(&types.Envelope{ID: "env-1", Route: types.Route{Actors: []string{"actor1"}}}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	store.handleTimeout("env-1")
+
+	got, _ := store.Get("env-1")
+	if len(got.StatusHistory) != 2 || got.StatusHistory[1].Status != types.EnvelopeStatusFailed {
+		t.Errorf("StatusHistory = %+v, want pending then failed", got.StatusHistory)
+	}
+	if len(recorder.transitions) != 1 || recorder.transitions[0].from != types.EnvelopeStatusPending {
+		t.Errorf("observed transitions = %+v, want one from pending", recorder.transitions)
+	}
+	if len(recorder.envelopes) == 1 && recorder.envelopes[0].Error != "envelope timed out" {
+		t.Errorf("observed envelope error = %q, want the timeout error", recorder.envelopes[0].Error)
+	}
+}
+
+func TestSweepExpired_InMemoryStore(t *testing.T) {
+	store := NewStore()
+	now := time.Now()
+	old := now.Add(-2 * time.Hour)
+
+	parentID := "parent"
+	envelopes := []struct {
+		envelope  *types.Envelope
+		status    types.EnvelopeStatus
+		updatedAt time.Time
+		resultURL string
+	}{
+		{envelope: &types.Envelope{ID: "old-succeeded"}, status: types.EnvelopeStatusSucceeded, updatedAt: old, resultURL: "s3://results/old-succeeded.json"},
+		{envelope: &types.Envelope{ID: "old-failed"}, status: types.EnvelopeStatusFailed, updatedAt: old},
+		{envelope: &types.Envelope{ID: "recent-succeeded"}, status: types.EnvelopeStatusSucceeded, updatedAt: now},
+		{envelope: &types.Envelope{ID: "old-running"}, status: types.EnvelopeStatusRunning, updatedAt: old},
+		{envelope: &types.Envelope{ID: "streamed"}, status: types.EnvelopeStatusSucceeded, updatedAt: old},
+		{envelope: &types.Envelope{ID: parentID}, status: types.EnvelopeStatusRunning, updatedAt: now},
+		{envelope: &types.Envelope{ID: "parent-1", ParentID: &parentID, BranchIndex: 1}, status: types.EnvelopeStatusSucceeded, updatedAt: old},
+	}
+	for _, e := range envelopes {
+		e.envelope.Route = types.Route{Actors: []string{"actor1"}}
+		if err := store.Create(e.envelope); err != nil {
+			t.Fatalf("Create %s failed: %v", e.envelope.ID, err)
+		}
+		if err := store.Update(types.EnvelopeUpdate{ID: e.envelope.ID, Status: e.status, ResultURL: e.resultURL, Timestamp: e.updatedAt}); err != nil {
+			t.Fatalf("Update %s failed: %v", e.envelope.ID, err)
+		}
+	}
+
+	ch := store.Subscribe("streamed")
+
+	removed, resultURLs, err := store.sweepExpired(now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("sweepExpired failed: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("removed = %d, want 2", removed)
+	}
+	if !reflect.DeepEqual(resultURLs, []string{"s3://results/old-succeeded.json"}) {
+		t.Errorf("resultURLs = %v, want the offloaded result of old-succeeded", resultURLs)
+	}
+
+	for _, id := range []string{"old-succeeded", "old-failed"} {
+		if _, err := store.Get(id); err == nil {
+			t.Errorf("%s should be removed", id)
+		}
+		if updates, _ := store.GetUpdates(id, nil); len(updates) != 0 {
+			t.Errorf("%s updates should be removed, got %d", id, len(updates))
+		}
+	}
+	for _, id := range []string{"recent-succeeded", "old-running", "streamed", parentID, "parent-1"} {
+		if _, err := store.Get(id); err != nil {
+			t.Errorf("%s should be kept: %v", id, err)
+		}
+	}
+
+	// Once the stream closes and the parent finishes, both are swept
+	store.Unsubscribe("streamed", ch)
+	if err := store.Update(types.EnvelopeUpdate{ID: parentID, Status: types.EnvelopeStatusSucceeded, Timestamp: old}); err != nil {
+		t.Fatalf("Update parent failed: %v", err)
+	}
+	removed, resultURLs, err = store.sweepExpired(now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("sweepExpired failed: %v", err)
+	}
+	if removed != 3 {
+		t.Errorf("removed = %d, want 3 (streamed, parent and child)", removed)
+	}
+	if len(resultURLs) != 0 {
+		t.Errorf("resultURLs = %v, want none", resultURLs)
+	}
+}
+
+func TestDeleteResults(t *testing.T) {
+	urls := []string{"s3://results/a.json", "s3://results/b.json", "s3://results/c.json"}
+
+	tests := []struct {
+		name    string
+		archive bool
+		want    []string
+	}{
+		{name: "deleted", want: urls},
+		{name: "kept for the archive", archive: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deleted []string
+			policy := RetentionPolicy{
+				Archive: tt.archive,
+				DeleteResult: func(ctx context.Context, url string) error {
+					deleted = append(deleted, url)
+					if url == "s3://results/a.json" {
+						return errors.New("access denied")
+					}
+					return nil
+				},
+			}
+
+			// A failed deletion does not stop the others
+			deleteResults(context.Background(), policy, urls)
+			if !reflect.DeepEqual(deleted, tt.want) {
+				t.Errorf("deleted = %v, want %v", deleted, tt.want)
+			}
+		})
+	}
+
+	// Without an object store there is nothing to delete
+	deleteResults(context.Background(), RetentionPolicy{}, urls)
+}
+
+func TestRetentionInterval(t *testing.T) {
+	tests := []struct {
+		after time.Duration
+		want  time.Duration
+	}{
+		{after: 5 * time.Second, want: time.Second},
+		{after: 10 * time.Minute, want: time.Minute},
+		{after: 720 * time.Hour, want: time.Hour},
+	}
+	for _, tt := range tests {
+		if got := retentionInterval(tt.after); got != tt.want {
+			t.Errorf("retentionInterval(%v) = %v, want %v", tt.after, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package envelopestore
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Timeout timers fire up to this fraction of the timeout (capped) after the deadline,
+// so envelopes created together with the same timeout do not all expire at once
+const (
+	timeoutJitterFraction = 0.05
+	maxTimeoutJitter      = 10 * time.Second
+)
+
+// timeoutDelay returns when the timeout timer of an envelope fires: the timeout plus a
+// random jitter. The envelope's deadline is not changed, only the time it is marked failed.
+func timeoutDelay(timeout time.Duration) time.Duration {
+	maxJitter := min(time.Duration(float64(timeout)*timeoutJitterFraction), maxTimeoutJitter)
+	if maxJitter <= 0 {
+		return timeout
+	}
+	return timeout + rand.N(maxJitter)
+}
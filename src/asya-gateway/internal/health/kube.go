@@ -0,0 +1,139 @@
+package health
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// In-cluster service account files mounted into every pod
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	asyncActorsPath   = "/apis/asya.sh/v1alpha1/namespaces/%s/asyncactors"
+)
+
+// ErrForbidden is returned when the gateway's service account may not list AsyncActors
+var ErrForbidden = errors.New("not allowed to list AsyncActors")
+
+// ActorStatus is the part of an AsyncActor status relevant to pipeline health
+type ActorStatus struct {
+	Status        string `json:"status,omitempty"` // Running, Napping, Degraded, ...
+	ReadyReplicas int32  `json:"ready_replicas"`
+	TotalReplicas int32  `json:"total_replicas"`
+}
+
+// ActorStatusLister reports AsyncActor status by actor name
+type ActorStatusLister interface {
+	ListActorStatuses(ctx context.Context) (map[string]ActorStatus, error)
+}
+
+// KubeClient lists AsyncActors of the gateway's namespace through the Kubernetes API,
+// authenticated with the pod's service account
+type KubeClient struct {
+	baseURL   string
+	namespace string
+	tokenPath string
+	client    *http.Client
+}
+
+// NewInClusterKubeClient creates a client from the in-cluster environment and service account.
+// namespace defaults to the gateway's own namespace.
+func NewInClusterKubeClient(namespace string) (*KubeClient, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not running in a Kubernetes cluster")
+	}
+
+	if namespace == "" {
+		data, err := os.ReadFile(serviceAccountDir + "/namespace")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read namespace: %w", err)
+		}
+		namespace = strings.TrimSpace(string(data))
+	}
+
+	ca, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cluster CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("invalid cluster CA")
+	}
+
+	return &KubeClient{
+		baseURL:   "https://" + net.JoinHostPort(host, port),
+		namespace: namespace,
+		tokenPath: serviceAccountDir + "/token",
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}},
+		},
+	}, nil
+}
+
+// ListActorStatuses returns the status of every AsyncActor in the namespace, keyed by name
+func (k *KubeClient) ListActorStatuses(ctx context.Context) (map[string]ActorStatus, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.baseURL+fmt.Sprintf(asyncActorsPath, k.namespace), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Projected service account tokens are rotated, so read the token per request
+	if k.tokenPath != "" {
+		token, err := os.ReadFile(k.tokenPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read service account token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list AsyncActors: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, ErrForbidden
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("failed to list AsyncActors: status %d", resp.StatusCode)
+	}
+
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Status struct {
+				Status        string `json:"status"`
+				ReadyReplicas *int32 `json:"readyReplicas"`
+				TotalReplicas *int32 `json:"totalReplicas"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode AsyncActors: %w", err)
+	}
+
+	statuses := make(map[string]ActorStatus, len(list.Items))
+	for _, item := range list.Items {
+		status := ActorStatus{Status: item.Status.Status}
+		if item.Status.ReadyReplicas != nil {
+			status.ReadyReplicas = *item.Status.ReadyReplicas
+		}
+		if item.Status.TotalReplicas != nil {
+			status.TotalReplicas = *item.Status.TotalReplicas
+		}
+		statuses[item.Metadata.Name] = status
+	}
+	return statuses, nil
+}
@@ -0,0 +1,52 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKubeClient_ListActorStatuses(t *testing.T) {
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenPath, []byte("secret-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/apis/asya.sh/v1alpha1/namespaces/pipelines/asyncactors" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"items":[
+			{"metadata":{"name":"prep"},"status":{"status":"Running","readyReplicas":2,"totalReplicas":3}},
+			{"metadata":{"name":"infer"},"status":{}}
+		]}`))
+	}))
+	defer server.Close()
+
+	k := &KubeClient{baseURL: server.URL, namespace: "pipelines", tokenPath: tokenPath, client: server.Client()}
+	statuses, err := k.ListActorStatuses(context.Background())
+	if err != nil {
+		t.Fatalf("ListActorStatuses() error = %v", err)
+	}
+
+	if got := statuses["prep"]; got != (ActorStatus{Status: "Running", ReadyReplicas: 2, TotalReplicas: 3}) {
+		t.Errorf("prep = %+v", got)
+	}
+	if got, ok := statuses["infer"]; !ok || got != (ActorStatus{}) {
+		t.Errorf("infer = %+v, present %v, want empty status", got, ok)
+	}
+
+	k.tokenPath = ""
+	if _, err := k.ListActorStatuses(context.Background()); !errors.Is(err, ErrForbidden) {
+		t.Errorf("ListActorStatuses() without token error = %v, want ErrForbidden", err)
+	}
+}
@@ -0,0 +1,197 @@
+// Package health serves the consolidated queue and actor health view of a pipeline.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/deliveryhero/asya/asya-gateway/internal/queue"
+)
+
+// Kubernetes availability reported in QueuesReport
+const (
+	KubernetesOK          = "ok"
+	KubernetesDisabled    = "disabled"    // Not running in a cluster
+	KubernetesForbidden   = "forbidden"   // RBAC does not allow listing AsyncActors
+	KubernetesUnavailable = "unavailable" // API request failed
+)
+
+// inspectTimeout bounds the inspection of a single queue
+const inspectTimeout = 5 * time.Second
+
+// QueueHealth is the health of one actor queue
+type QueueHealth struct {
+	Actor     string       `json:"actor"`
+	Queue     string       `json:"queue"`
+	Messages  *int         `json:"messages,omitempty"`
+	InFlight  *int         `json:"in_flight,omitempty"`
+	Consumers *int         `json:"consumers,omitempty"`
+	Status    *ActorStatus `json:"actor_status,omitempty"`
+	Healthy   bool         `json:"healthy"`
+	Reason    string       `json:"reason,omitempty"` // Why the queue is not healthy
+}
+
+// QueuesReport is the response of GET /admin/health/queues
+type QueuesReport struct {
+	Healthy    bool          `json:"healthy"`
+	Kubernetes string        `json:"kubernetes"`
+	CheckedAt  time.Time     `json:"checked_at"`
+	Queues     []QueueHealth `json:"queues"`
+}
+
+// Actor statuses that mean the actor is running or about to; anything else is reported unhealthy
+var healthyActorStatuses = map[string]bool{
+	"Running":     true,
+	"Napping":     true,
+	"Creating":    true,
+	"ScalingUp":   true,
+	"ScalingDown": true,
+	"Updating":    true,
+}
+
+// Actor statuses in which an actor without consumers is expected to pick up waiting messages soon
+var startingActorStatuses = map[string]bool{
+	"Napping":   true,
+	"Creating":  true,
+	"ScalingUp": true,
+}
+
+// QueuesHandler serves GET /admin/health/queues
+type QueuesHandler struct {
+	actors    []string
+	inspector queue.Inspector   // nil without a queue connection
+	statuses  ActorStatusLister // nil outside Kubernetes
+}
+
+// NewQueuesHandler reports on the given actors' queues, plus any AsyncActor found in the namespace.
+// inspector and statuses may be nil.
+func NewQueuesHandler(actors []string, inspector queue.Inspector, statuses ActorStatusLister) *QueuesHandler {
+	return &QueuesHandler{actors: actors, inspector: inspector, statuses: statuses}
+}
+
+func (h *QueuesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report := h.Report(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		slog.Error("Failed to encode queue health", "error", err)
+	}
+}
+
+// Report inspects every known queue and combines it with the AsyncActor status
+func (h *QueuesHandler) Report(ctx context.Context) QueuesReport {
+	report := QueuesReport{Healthy: true, Kubernetes: KubernetesDisabled, CheckedAt: time.Now().UTC()}
+
+	var statuses map[string]ActorStatus
+	if h.statuses != nil {
+		var err error
+		statuses, err = h.statuses.ListActorStatuses(ctx)
+		switch {
+		case err == nil:
+			report.Kubernetes = KubernetesOK
+		case errors.Is(err, ErrForbidden):
+			report.Kubernetes = KubernetesForbidden
+		default:
+			slog.Warn("Failed to list AsyncActors", "error", err)
+			report.Kubernetes = KubernetesUnavailable
+		}
+	}
+
+	for _, actor := range mergeActors(h.actors, statuses) {
+		health := QueueHealth{Actor: actor, Queue: queue.ActorQueueName(actor)}
+		if status, ok := statuses[actor]; ok {
+			health.Status = &status
+		}
+		h.inspect(ctx, &health)
+		assess(&health)
+
+		report.Healthy = report.Healthy && health.Healthy
+		report.Queues = append(report.Queues, health)
+	}
+
+	return report
+}
+
+// inspect fills in the queue statistics, or the reason they are missing
+func (h *QueuesHandler) inspect(ctx context.Context, health *QueueHealth) {
+	if h.inspector == nil {
+		health.Reason = "queue inspection not available"
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, inspectTimeout)
+	defer cancel()
+
+	stats, err := h.inspector.InspectQueue(ctx, health.Queue)
+	switch {
+	case errors.Is(err, queue.ErrQueueNotFound):
+		health.Reason = "queue does not exist"
+		return
+	case err != nil:
+		slog.Warn("Failed to inspect queue", "queue", health.Queue, "error", err)
+		health.Reason = "queue inspection failed"
+		return
+	}
+
+	health.Messages = &stats.Messages
+	if stats.InFlight >= 0 {
+		health.InFlight = &stats.InFlight
+	}
+	if stats.Consumers >= 0 {
+		health.Consumers = &stats.Consumers
+	}
+}
+
+// assess decides whether a queue is healthy: it can be inspected, its actor (when known)
+// is running or starting, and waiting messages have a consumer or one on the way
+func assess(health *QueueHealth) {
+	if health.Messages == nil {
+		return // Reason set by inspect
+	}
+
+	status := ""
+	if health.Status != nil {
+		status = health.Status.Status
+		if status != "" && !healthyActorStatuses[status] {
+			health.Reason = "actor status " + status
+			return
+		}
+	}
+
+	if *health.Messages > 0 && health.Consumers != nil && *health.Consumers == 0 && !startingActorStatuses[status] {
+		health.Reason = "messages waiting without consumers"
+		return
+	}
+
+	health.Healthy = true
+}
+
+// mergeActors returns the configured actors plus actors known only to Kubernetes, sorted
+func mergeActors(actors []string, statuses map[string]ActorStatus) []string {
+	seen := make(map[string]bool, len(actors)+len(statuses))
+	var merged []string
+	for _, actor := range actors {
+		if !seen[actor] {
+			seen[actor] = true
+			merged = append(merged, actor)
+		}
+	}
+	for actor := range statuses {
+		if !seen[actor] {
+			seen[actor] = true
+			merged = append(merged, actor)
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
@@ -0,0 +1,146 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/deliveryhero/asya/asya-gateway/internal/queue"
+)
+
+// fakeInspector returns fixed stats or errors per queue
+type fakeInspector struct {
+	stats map[string]queue.QueueStats
+	errs  map[string]error
+}
+
+func (f *fakeInspector) InspectQueue(ctx context.Context, queueName string) (queue.QueueStats, error) {
+	if err, ok := f.errs[queueName]; ok {
+		return queue.QueueStats{}, err
+	}
+	return f.stats[queueName], nil
+}
+
+// fakeLister returns fixed AsyncActor statuses
+type fakeLister struct {
+	statuses map[string]ActorStatus
+	err      error
+}
+
+func (f *fakeLister) ListActorStatuses(ctx context.Context) (map[string]ActorStatus, error) {
+	return f.statuses, f.err
+}
+
+func TestQueuesHandler_Report(t *testing.T) {
+	inspector := &fakeInspector{
+		stats: map[string]queue.QueueStats{
+			"asya-prep":     {Messages: 0, InFlight: -1, Consumers: 2},
+			"asya-infer":    {Messages: 40, InFlight: -1, Consumers: 0},
+			"asya-post":     {Messages: 3, InFlight: -1, Consumers: 0},
+			"asya-k8s-only": {Messages: 0, InFlight: -1, Consumers: 1},
+		},
+		errs: map[string]error{
+			"asya-missing": queue.ErrQueueNotFound,
+			"asya-broken":  errors.New("channel closed"),
+		},
+	}
+	lister := &fakeLister{statuses: map[string]ActorStatus{
+		"prep":     {Status: "Running", ReadyReplicas: 2, TotalReplicas: 2},
+		"post":     {Status: "Napping"},
+		"k8s-only": {Status: "ImagePullError"},
+	}}
+
+	h := NewQueuesHandler([]string{"prep", "infer", "post", "missing", "broken"}, inspector, lister)
+	report := h.Report(context.Background())
+
+	if report.Kubernetes != KubernetesOK {
+		t.Errorf("Kubernetes = %q, want %q", report.Kubernetes, KubernetesOK)
+	}
+	if report.Healthy {
+		t.Error("Healthy = true, want false")
+	}
+
+	want := map[string]struct {
+		healthy bool
+		reason  string
+	}{
+		"broken":   {reason: "queue inspection failed"},
+		"infer":    {reason: "messages waiting without consumers"},
+		"k8s-only": {reason: "actor status ImagePullError"},
+		"missing":  {reason: "queue does not exist"},
+		"post":     {healthy: true}, // napping actor is woken up by the waiting messages
+		"prep":     {healthy: true},
+	}
+	if len(report.Queues) != len(want) {
+		t.Fatalf("got %d queues, want %d", len(report.Queues), len(want))
+	}
+	for i, q := range report.Queues {
+		if i > 0 && report.Queues[i-1].Actor > q.Actor {
+			t.Errorf("queues not sorted: %q before %q", report.Queues[i-1].Actor, q.Actor)
+		}
+		w := want[q.Actor]
+		if q.Healthy != w.healthy || q.Reason != w.reason {
+			t.Errorf("%s: healthy=%v reason=%q, want healthy=%v reason=%q", q.Actor, q.Healthy, q.Reason, w.healthy, w.reason)
+		}
+	}
+
+	prep := report.Queues[5]
+	if prep.Status == nil || prep.Status.ReadyReplicas != 2 || prep.Consumers == nil || *prep.Consumers != 2 {
+		t.Errorf("prep = %+v, want actor status and consumer count", prep)
+	}
+	if prep.InFlight != nil {
+		t.Errorf("prep.InFlight = %v, want omitted when not reported", *prep.InFlight)
+	}
+}
+
+func TestQueuesHandler_Kubernetes(t *testing.T) {
+	tests := []struct {
+		name   string
+		lister ActorStatusLister
+		want   string
+	}{
+		{name: "outside cluster", lister: nil, want: KubernetesDisabled},
+		{name: "forbidden", lister: &fakeLister{err: ErrForbidden}, want: KubernetesForbidden},
+		{name: "api error", lister: &fakeLister{err: errors.New("timeout")}, want: KubernetesUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inspector := &fakeInspector{stats: map[string]queue.QueueStats{"asya-a": {Consumers: 1}}}
+			report := NewQueuesHandler([]string{"a"}, inspector, tt.lister).Report(context.Background())
+			if report.Kubernetes != tt.want {
+				t.Errorf("Kubernetes = %q, want %q", report.Kubernetes, tt.want)
+			}
+			if !report.Healthy {
+				t.Errorf("Healthy = false, want true (queues: %+v)", report.Queues)
+			}
+		})
+	}
+}
+
+func TestQueuesHandler_ServeHTTP(t *testing.T) {
+	h := NewQueuesHandler([]string{"a"}, nil, nil)
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/health/queues", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rr.Code)
+	}
+
+	var report QueuesReport
+	if err := json.NewDecoder(rr.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(report.Queues) != 1 || report.Queues[0].Reason != "queue inspection not available" {
+		t.Errorf("Queues = %+v, want one queue without inspection", report.Queues)
+	}
+
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/health/queues", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want 405", rr.Code)
+	}
+}
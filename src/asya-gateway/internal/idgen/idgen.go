@@ -0,0 +1,97 @@
+package idgen
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+)
+
+// Envelope ID formats (ASYA_ENVELOPE_ID_FORMAT)
+const (
+	FormatUUID     = "uuid"     // Random UUID, e.g. 5e6fdb2d-1d6b-4e91-baef-73e825434e7b
+	FormatULID     = "ulid"     // Time-ordered ULID, e.g. 01ARYZ6S41TSV4RRFFQ69G5FAV
+	FormatPrefixed = "prefixed" // Prefix followed by a ULID, e.g. env_01ARYZ6S41TSV4RRFFQ69G5FAV
+)
+
+// DefaultPrefix is the prefix of prefixed envelope IDs
+const DefaultPrefix = "env_"
+
+// ulidLength is the length of an encoded ULID
+const ulidLength = 26
+
+// Crockford's base32 alphabet used by ULIDs
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// Generator returns a new unique envelope ID
+type Generator func() string
+
+// New returns a generator for the given format. The prefix is only used by FormatPrefixed.
+// Generated IDs always pass types.ValidateEnvelopeID, also with fanout suffixes appended.
+func New(format, prefix string) (Generator, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", FormatUUID:
+		return NewUUID, nil
+	case FormatULID:
+		return NewULID, nil
+	case FormatPrefixed:
+		if prefix == "" {
+			return nil, fmt.Errorf("envelope ID prefix cannot be empty")
+		}
+		// Prefixed IDs are the prefix followed by a fixed-length ULID
+		if err := types.ValidateEnvelopeID(prefix + strings.Repeat("0", ulidLength)); err != nil {
+			return nil, fmt.Errorf("invalid envelope ID prefix %q: %w", prefix, err)
+		}
+		return func() string { return prefix + NewULID() }, nil
+	default:
+		return nil, fmt.Errorf("unknown envelope ID format %q (must be %s, %s or %s)", format, FormatUUID, FormatULID, FormatPrefixed)
+	}
+}
+
+// NewUUID returns a random (version 4) UUID
+func NewUUID() string {
+	return uuid.New().String()
+}
+
+// NewULID returns a ULID for the current time: 48 bits of millisecond timestamp followed by
+// 80 random bits. ULIDs sort by creation time, which keeps Postgres index inserts local.
+func NewULID() string {
+	id, err := newULID(time.Now(), rand.Reader)
+	if err != nil {
+		// crypto/rand does not fail on supported platforms
+		panic(fmt.Sprintf("failed to generate ULID: %v", err))
+	}
+	return id
+}
+
+// newULID encodes t and 80 bits read from entropy as a 26 character ULID
+func newULID(t time.Time, entropy io.Reader) (string, error) {
+	var b [16]byte
+	ms := uint64(t.UnixMilli())
+	b[0] = byte(ms >> 40)
+	b[1] = byte(ms >> 32)
+	b[2] = byte(ms >> 24)
+	b[3] = byte(ms >> 16)
+	b[4] = byte(ms >> 8)
+	b[5] = byte(ms)
+	if _, err := io.ReadFull(entropy, b[6:]); err != nil {
+		return "", err
+	}
+
+	// 26 characters of 5 bits encode the 128 bits, the first character holds the top 3 bits
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+	var out [ulidLength]byte
+	for i := ulidLength - 1; i >= 0; i-- {
+		out[i] = crockfordAlphabet[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:]), nil
+}
@@ -0,0 +1,122 @@
+package idgen
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name       string
+		format     string
+		prefix     string
+		wantLen    int
+		wantPrefix string
+		wantErr    bool
+	}{
+		{name: "default is uuid", format: "", wantLen: 36},
+		{name: "uuid", format: "uuid", wantLen: 36},
+		{name: "ulid", format: "ULID", wantLen: ulidLength},
+		{name: "prefixed", format: "prefixed", prefix: "job_", wantLen: 4 + ulidLength, wantPrefix: "job_"},
+		{name: "prefixed with default prefix", format: "prefixed", prefix: DefaultPrefix, wantLen: len(DefaultPrefix) + ulidLength, wantPrefix: DefaultPrefix},
+		{name: "empty prefix", format: "prefixed", prefix: "", wantErr: true},
+		{name: "prefix with slash", format: "prefixed", prefix: "jobs/", wantErr: true},
+		{name: "prefix starting with dash", format: "prefixed", prefix: "-job", wantErr: true},
+		{name: "prefix too long", format: "prefixed", prefix: strings.Repeat("a", types.MaxEnvelopeIDLength), wantErr: true},
+		{name: "unknown format", format: "snowflake", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen, err := New(tt.format, tt.prefix)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			seen := make(map[string]bool)
+			for i := 0; i < 1000; i++ {
+				id := gen()
+				if len(id) != tt.wantLen {
+					t.Fatalf("ID %q has length %d, want %d", id, len(id), tt.wantLen)
+				}
+				if !strings.HasPrefix(id, tt.wantPrefix) {
+					t.Fatalf("ID %q does not start with %q", id, tt.wantPrefix)
+				}
+				// Generated IDs must round-trip through the same validation used for incoming IDs
+				if parsed, err := types.ParseEnvelopeID(id + "-1"); err != nil || parsed != id+"-1" {
+					t.Fatalf("ParseEnvelopeID(%q) = %q, %v", id+"-1", parsed, err)
+				}
+				if seen[id] {
+					t.Fatalf("Duplicate ID %q", id)
+				}
+				seen[id] = true
+			}
+		})
+	}
+}
+
+func TestNewULID_Encoding(t *testing.T) {
+	tests := []struct {
+		name    string
+		time    time.Time
+		entropy []byte
+		want    string
+	}{
+		{name: "zero", time: time.UnixMilli(0), entropy: make([]byte, 10), want: "00000000000000000000000000"},
+		{name: "spec timestamp", time: time.UnixMilli(1469918176385), entropy: make([]byte, 10), want: "01ARYZ6S410000000000000000"},
+		{name: "max entropy", time: time.UnixMilli(0), entropy: bytes.Repeat([]byte{0xff}, 10), want: "0000000000ZZZZZZZZZZZZZZZZ"},
+		{name: "max timestamp", time: time.UnixMilli(1<<48 - 1), entropy: make([]byte, 10), want: "7ZZZZZZZZZ0000000000000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newULID(tt.time, bytes.NewReader(tt.entropy))
+			if err != nil {
+				t.Fatalf("newULID() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("newULID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewULID_SortsByTime(t *testing.T) {
+	start := time.Now()
+	ids := make([]string, 0, 5)
+	for i := 0; i < 5; i++ {
+		// Maximum entropy for earlier IDs still sorts before minimum entropy for later ones
+		entropy := bytes.Repeat([]byte{byte(0xff - i)}, 10)
+		id, err := newULID(start.Add(time.Duration(i)*time.Millisecond), bytes.NewReader(entropy))
+		if err != nil {
+			t.Fatalf("newULID() error = %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if !sort.StringsAreSorted(ids) {
+		t.Errorf("ULIDs not sorted by creation time: %v", ids)
+	}
+}
+
+func TestNewULID_EntropyError(t *testing.T) {
+	if _, err := newULID(time.Now(), bytes.NewReader(nil)); err == nil {
+		t.Error("newULID() expected error for exhausted entropy")
+	}
+	if _, err := newULID(time.Now(), errReader{}); err == nil {
+		t.Error("newULID() expected error for failing entropy source")
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("no entropy") }
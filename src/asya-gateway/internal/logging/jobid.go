@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+// JobIDKey is the log attribute holding the ID of the envelope a request is about,
+// the same attribute the sidecar logs envelopes with
+const JobIDKey = "job_id"
+
+type jobIDKey struct{}
+
+// ContextWithJobID returns a context whose log records carry jobID as JobIDKey attribute,
+// when logged through a NewHandler handler with a *Context slog function.
+// An empty jobID returns ctx unchanged.
+func ContextWithJobID(ctx context.Context, jobID string) context.Context {
+	if jobID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, jobIDKey{}, jobID)
+}
+
+// JobIDFromContext returns the job ID set by ContextWithJobID, or ""
+func JobIDFromContext(ctx context.Context) string {
+	jobID, _ := ctx.Value(jobIDKey{}).(string)
+	return jobID
+}
+
+// NewHandler wraps a handler so that records logged with a context carrying a job ID
+// get the JobIDKey attribute
+func NewHandler(h slog.Handler) slog.Handler {
+	return &jobIDHandler{Handler: h}
+}
+
+type jobIDHandler struct {
+	slog.Handler
+}
+
+func (h *jobIDHandler) Handle(ctx context.Context, record slog.Record) error {
+	if jobID := JobIDFromContext(ctx); jobID != "" {
+		record.AddAttrs(slog.String(JobIDKey, jobID))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *jobIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &jobIDHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *jobIDHandler) WithGroup(name string) slog.Handler {
+	return &jobIDHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,41 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestHandler_JobIDWithGatewayOptions logs through the handler as cmd/gateway builds it:
+// TRACE level names and redacted payloads must survive the job_id wrapper
+func TestHandler_JobIDWithGatewayOptions(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level:       LevelTrace,
+		ReplaceAttr: ReplaceLevelName,
+	})))
+
+	ctx := ContextWithJobID(context.Background(), "env-1")
+	logger.Log(ctx, LevelTrace, "Publishing envelope", "payload", Payload([]byte(`{"secret":true}`)))
+	logger.InfoContext(ContextWithJobID(ctx, ""), "Same job")
+	logger.InfoContext(context.Background(), "No envelope")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d log lines, want 3: %q", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[0], "level=TRACE") || !strings.Contains(lines[0], "job_id=env-1") {
+		t.Errorf("line %q should carry the TRACE level and job_id", lines[0])
+	}
+	if strings.Contains(lines[0], "secret") {
+		t.Errorf("line %q should not carry the redacted payload", lines[0])
+	}
+	if !strings.Contains(lines[1], "job_id=env-1") {
+		t.Errorf("line %q should keep the job_id of the context", lines[1])
+	}
+	if strings.Contains(lines[2], "job_id") {
+		t.Errorf("line %q should not carry job_id", lines[2])
+	}
+}
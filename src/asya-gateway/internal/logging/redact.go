@@ -0,0 +1,89 @@
+package logging
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+)
+
+// LevelTrace is below slog.LevelDebug (ASYA_LOG_LEVEL=TRACE), the only level that may log full payloads
+const LevelTrace = slog.LevelDebug - 4
+
+// Payload redaction modes (ASYA_LOG_REDACT_PAYLOADS)
+const (
+	RedactOmit = "omit" // Log payload sizes only
+	RedactHash = "hash" // Log sizes and a SHA-256 prefix, to tell payloads apart without revealing them
+	RedactNone = "none" // Log full payloads at TRACE level, sizes otherwise
+)
+
+// hashLength is the number of SHA-256 bytes logged in RedactHash mode
+const hashLength = 8
+
+var payloadMode atomic.Value
+
+func init() {
+	payloadMode.Store(RedactOmit)
+}
+
+// SetPayloadMode sets how payloads are logged (default RedactOmit)
+func SetPayloadMode(mode string) error {
+	switch mode = strings.ToLower(strings.TrimSpace(mode)); mode {
+	case "":
+		mode = RedactOmit
+	case RedactOmit, RedactHash, RedactNone:
+	default:
+		return fmt.Errorf("unknown payload redaction mode %q (supported: %s, %s, %s)", mode, RedactOmit, RedactHash, RedactNone)
+	}
+	payloadMode.Store(mode)
+	return nil
+}
+
+// Payload returns a log value for a message body or payload, e.g. slog.Debug("msg", "body", Payload(body)).
+// Raw bytes are logged as they are, other values as JSON. Unless redaction is disabled and TRACE
+// logging is enabled, only the size (and in RedactHash mode a hash) is logged, never the content.
+// The value is resolved only when the record is logged, so disabled levels cost nothing.
+func Payload(v any) slog.LogValuer {
+	return payloadValue{v: v}
+}
+
+// payloadValue is the slog.LogValuer returned by Payload
+type payloadValue struct {
+	v any
+}
+
+func (p payloadValue) LogValue() slog.Value {
+	data, ok := p.v.([]byte)
+	if !ok {
+		var err error
+		if data, err = json.Marshal(p.v); err != nil {
+			return slog.StringValue(fmt.Sprintf("<unloggable %T>", p.v))
+		}
+	}
+
+	mode := payloadMode.Load().(string)
+	if mode == RedactNone && slog.Default().Enabled(context.Background(), LevelTrace) {
+		return slog.StringValue(string(data))
+	}
+
+	attrs := []slog.Attr{slog.Int("size", len(data))}
+	if mode == RedactHash {
+		sum := sha256.Sum256(data)
+		attrs = append(attrs, slog.String("sha256", hex.EncodeToString(sum[:hashLength])))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// ReplaceLevelName is a slog.HandlerOptions.ReplaceAttr function that names LevelTrace TRACE
+func ReplaceLevelName(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.LevelKey && len(groups) == 0 {
+		if level, ok := a.Value.Any().(slog.Level); ok && level == LevelTrace {
+			a.Value = slog.StringValue("TRACE")
+		}
+	}
+	return a
+}
@@ -0,0 +1,118 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logPayload logs a payload with the given handler level and redaction mode, returning the output
+func logPayload(t *testing.T, level slog.Level, mode string, v any) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: level, ReplaceAttr: ReplaceLevelName})))
+	t.Cleanup(func() {
+		slog.SetDefault(previous)
+		require.NoError(t, SetPayloadMode(RedactOmit))
+	})
+
+	require.NoError(t, SetPayloadMode(mode))
+	slog.Log(t.Context(), LevelTrace, "payload", "id", "env-1", "body", Payload(v))
+	return buf.String()
+}
+
+func TestPayload(t *testing.T) {
+	body := []byte(`{"ssn":"123-45-6789"}`)
+
+	tests := []struct {
+		name     string
+		mode     string
+		level    slog.Level
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "default omits content",
+			mode:     "",
+			level:    LevelTrace,
+			contains: []string{"id=env-1", "body.size=21"},
+			excludes: []string{"123-45-6789", "sha256"},
+		},
+		{
+			name:     "hash",
+			mode:     RedactHash,
+			level:    LevelTrace,
+			contains: []string{"body.size=21", "body.sha256="},
+			excludes: []string{"123-45-6789"},
+		},
+		{
+			name:     "none at trace logs content",
+			mode:     RedactNone,
+			level:    LevelTrace,
+			contains: []string{"level=TRACE", "123-45-6789"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := logPayload(t, tt.level, tt.mode, body)
+			for _, s := range tt.contains {
+				assert.Contains(t, out, s)
+			}
+			for _, s := range tt.excludes {
+				assert.NotContains(t, out, s)
+			}
+		})
+	}
+}
+
+func TestPayload_NoneBelowTrace(t *testing.T) {
+	// Disabling redaction alone does not log payloads at DEBUG
+	require.NoError(t, SetPayloadMode(RedactNone))
+	t.Cleanup(func() { require.NoError(t, SetPayloadMode(RedactOmit)) })
+
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	slog.Debug("result", "result", Payload(map[string]string{"secret": "value"}))
+	assert.Contains(t, buf.String(), "result.size=18")
+	assert.NotContains(t, buf.String(), "value")
+}
+
+func TestSetPayloadMode_Invalid(t *testing.T) {
+	err := SetPayloadMode("mask")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown payload redaction mode")
+}
+
+// countingPayload counts how often it is marshaled
+type countingPayload struct {
+	calls *int
+}
+
+func (c countingPayload) MarshalJSON() ([]byte, error) {
+	*c.calls++
+	return []byte(`{}`), nil
+}
+
+func TestPayload_ResolvedOnlyWhenLogged(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	calls := 0
+	slog.Debug("dropped", "body", Payload(countingPayload{calls: &calls}))
+	assert.Equal(t, 0, calls, "payload of a disabled level should not be marshaled")
+
+	slog.Info("logged", "body", Payload(countingPayload{calls: &calls}))
+	assert.Equal(t, 1, calls)
+	assert.Contains(t, buf.String(), "body.size=2")
+}
@@ -0,0 +1,102 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/deliveryhero/asya/asya-gateway/internal/config"
+	"github.com/deliveryhero/asya/asya-gateway/internal/queue"
+	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+)
+
+// MaxBatchSize is the maximum number of items accepted by a single batch submission
+const MaxBatchSize = 1000
+
+// BatchItem is one tool call in a batch submission
+type BatchItem struct {
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments"`
+	StartStep int            `json:"start_step,omitempty"` // Route index to start at (resume)
+	Metadata  map[string]any `json:"metadata,omitempty"`   // Route metadata passed to every actor
+}
+
+// BatchItemResult reports the outcome of one batch item, in request order.
+// Status is the envelope status after submission (pending, queued or failed),
+// or "rejected" when the item was invalid and no envelope was created.
+type BatchItemResult struct {
+	Index      int    `json:"index"`
+	EnvelopeID string `json:"envelope_id,omitempty"`
+	Status     string `json:"status"`
+	StatusURL  string `json:"status_url,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// batchStatusRejected marks batch items that failed validation before an envelope was created
+const batchStatusRejected = "rejected"
+
+// SubmitBatch creates an envelope for every valid item and publishes all of them
+// in one batch. Invalid items and publish failures are reported per item
+// without affecting the rest of the batch. The error is queue.ErrPoolExhausted when
+// publishing failed because no queue channel was available.
+func (r *Registry) SubmitBatch(ctx context.Context, items []BatchItem) ([]BatchItemResult, error) {
+	results := make([]BatchItemResult, len(items))
+	var envelopes []*types.Envelope
+	var indexes []int
+
+	for i, item := range items {
+		results[i].Index = i
+
+		toolDef, ok := r.findTool(item.Name)
+		if !ok {
+			results[i].Status = batchStatusRejected
+			results[i].Error = fmt.Sprintf("tool %q not found", item.Name)
+			continue
+		}
+
+		envelope, err := r.createEnvelope(ctx, toolDef, item.Arguments, item.StartStep, item.Metadata)
+		if err != nil {
+			results[i].Status = batchStatusRejected
+			results[i].Error = err.Error()
+			continue
+		}
+
+		results[i].EnvelopeID = envelope.ID
+		results[i].StatusURL = r.envelopeURL(envelope.ID, "")
+		envelopes = append(envelopes, envelope)
+		indexes = append(indexes, i)
+	}
+
+	if len(envelopes) == 0 {
+		return results, nil
+	}
+
+	var exhausted error
+	errs := enqueueEnvelopes(ctx, r.jobStore, r.queueClient, envelopes)
+	for j, i := range indexes {
+		if errors.Is(errs[j], queue.ErrPoolExhausted) {
+			exhausted = queue.ErrPoolExhausted
+		}
+		switch {
+		case errs[j] != nil:
+			results[i].Status = string(types.EnvelopeStatusFailed)
+			results[i].Error = fmt.Sprintf("failed to send envelope: %v", errs[j])
+		case r.queueClient == nil:
+			results[i].Status = string(types.EnvelopeStatusPending)
+		default:
+			results[i].Status = string(types.EnvelopeStatusQueued)
+		}
+	}
+
+	return results, exhausted
+}
+
+// findTool returns the configured tool definition with the given name
+func (r *Registry) findTool(name string) (config.Tool, bool) {
+	for _, tool := range r.config.Tools {
+		if tool.Name == name {
+			return tool, true
+		}
+	}
+	return config.Tool{}, false
+}
@@ -0,0 +1,113 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/deliveryhero/asya/asya-gateway/internal/envelopestore"
+	"github.com/deliveryhero/asya/asya-gateway/internal/logging"
+	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+)
+
+// CancelRequest is the body of POST /admin/envelopes/cancel
+type CancelRequest struct {
+	Status []types.EnvelopeStatus `json:"status,omitempty"` // Non-final statuses to cancel (default: all of them)
+	Tool   string                 `json:"tool,omitempty"`
+	Reason string                 `json:"reason,omitempty"` // Added to the error of cancelled envelopes
+
+	// Envelopes carry no tenant or labels yet; the fields are decoded to reject them explicitly
+	Tenant string            `json:"tenant,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// CancelResponse reports the outcome of POST /admin/envelopes/cancel
+type CancelResponse struct {
+	Matched   int `json:"matched"`   // Active envelopes matching the filter
+	Cancelled int `json:"cancelled"` // Envelopes marked as failed
+}
+
+// cancelableStatuses are the statuses an envelope can be cancelled in
+var cancelableStatuses = map[types.EnvelopeStatus]bool{
+	types.EnvelopeStatusPending: true,
+	types.EnvelopeStatusQueued:  true,
+	types.EnvelopeStatusRunning: true,
+	types.EnvelopeStatusUnknown: true,
+}
+
+// HandleEnvelopesCancel handles POST /admin/envelopes/cancel: every active envelope
+// matching the filter is marked as failed, as if it had timed out.
+// Sidecars check /envelopes/{id}/active before calling the runtime, so queued messages
+// are dropped; late reports of calls already running are stored as for timed-out envelopes.
+// Like every /admin path, the endpoint is unauthenticated.
+func (h *Handler) HandleEnvelopesCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.rejectReadOnly(w) {
+		return
+	}
+
+	var req CancelRequest
+	if status := h.decodeBody(w, r, &req); status != 0 {
+		http.Error(w, bodyErrorMessage(status), status)
+		return
+	}
+
+	if req.Tenant != "" || len(req.Labels) > 0 {
+		http.Error(w, "Filtering by tenant or labels is not supported: envelopes carry neither", http.StatusBadRequest)
+		return
+	}
+	for _, status := range req.Status {
+		if !cancelableStatuses[status] {
+			http.Error(w, fmt.Sprintf("Invalid status %q: only pending, queued, running and unknown envelopes can be cancelled", status), http.StatusBadRequest)
+			return
+		}
+	}
+	if len(req.Status) == 0 && req.Tool == "" {
+		http.Error(w, "Filter must set status or tool", http.StatusBadRequest)
+		return
+	}
+
+	ids, err := h.jobStore.ListActive(envelopestore.EnvelopeFilter{Statuses: req.Status, Tool: req.Tool})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to list envelopes to cancel", "error", err)
+		http.Error(w, "Failed to list envelopes", http.StatusInternalServerError)
+		return
+	}
+
+	errMsg := "envelope cancelled"
+	if req.Reason != "" {
+		errMsg = fmt.Sprintf("envelope cancelled: %s", req.Reason)
+	}
+
+	response := CancelResponse{Matched: len(ids)}
+	for _, id := range ids {
+		// Skip envelopes that reached a final status since they were listed
+		if !h.jobStore.IsActive(id) {
+			continue
+		}
+		if err := h.jobStore.Update(types.EnvelopeUpdate{
+			ID:        id,
+			Status:    types.EnvelopeStatusFailed,
+			Message:   "Envelope cancelled",
+			Error:     errMsg,
+			Timestamp: time.Now(),
+		}); err != nil {
+			slog.ErrorContext(logging.ContextWithJobID(r.Context(), id), "Failed to cancel envelope", "id", id, "error", err)
+			continue
+		}
+		response.Cancelled++
+	}
+
+	slog.WarnContext(r.Context(), "Cancelled envelopes", "tool", req.Tool, "status", req.Status, "matched", response.Matched, "cancelled", response.Cancelled, "reason", req.Reason)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to encode cancel response", "error", err)
+	}
+}
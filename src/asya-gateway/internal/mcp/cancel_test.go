@@ -0,0 +1,117 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deliveryhero/asya/asya-gateway/internal/envelopestore"
+	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+)
+
+func TestHandleEnvelopesCancel(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		readOnly      bool
+		wantStatus    int
+		wantCancelled []string
+	}{
+		{name: "by tool", body: `{"tool": "summarize", "reason": "incident 42"}`, wantStatus: http.StatusOK, wantCancelled: []string{"env-1", "env-2"}},
+		{name: "by status", body: `{"status": ["pending", "queued"]}`, wantStatus: http.StatusOK, wantCancelled: []string{"env-1", "env-4"}},
+		{name: "by tool and status", body: `{"tool": "summarize", "status": ["running"]}`, wantStatus: http.StatusOK, wantCancelled: []string{"env-2"}},
+		{name: "no match", body: `{"tool": "unknown"}`, wantStatus: http.StatusOK},
+		{name: "empty filter", body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "final status", body: `{"status": ["succeeded"]}`, wantStatus: http.StatusBadRequest},
+		{name: "tenant", body: `{"tool": "summarize", "tenant": "acme"}`, wantStatus: http.StatusBadRequest},
+		{name: "labels", body: `{"labels": {"team": "search"}}`, wantStatus: http.StatusBadRequest},
+		{name: "malformed body", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "read-only", body: `{"tool": "summarize"}`, readOnly: true, wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := envelopestore.NewStore()
+			for _, e := range []struct {
+				id, tool string
+				status   types.EnvelopeStatus
+			}{
+				{"env-1", "summarize", types.EnvelopeStatusPending},
+				{"env-2", "summarize", types.EnvelopeStatusRunning},
+				{"env-3", "summarize", types.EnvelopeStatusSucceeded},
+				{"env-4", "translate", types.EnvelopeStatusQueued},
+			} {
+				if err := store.Create(&types.Envelope{ID: e.id, Tool: e.tool, Route: types.Route{Actors: []string{"actor1"}}}); err != nil {
+					t.Fatalf("Create failed: %v", err)
+				}
+				if e.status != types.EnvelopeStatusPending {
+					if err := store.Update(types.EnvelopeUpdate{ID: e.id, Status: e.status, Timestamp: time.Now()}); err != nil {
+						t.Fatalf("Update failed: %v", err)
+					}
+				}
+			}
+
+			handler := NewHandler(store)
+			handler.SetReadOnly(tt.readOnly)
+
+			rr := httptest.NewRecorder()
+			serveRoutes(handler, rr, httptest.NewRequest(http.MethodPost, "/admin/envelopes/cancel", bytes.NewReader([]byte(tt.body))))
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("Status = %d, want %d: %s", rr.Code, tt.wantStatus, rr.Body.String())
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+
+			var response CancelResponse
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Matched != len(tt.wantCancelled) || response.Cancelled != len(tt.wantCancelled) {
+				t.Errorf("Response = %+v, want %d matched and cancelled", response, len(tt.wantCancelled))
+			}
+
+			for _, id := range tt.wantCancelled {
+				envelope, err := store.Get(id)
+				if err != nil {
+					t.Fatalf("Get(%s) failed: %v", id, err)
+				}
+				if envelope.Status != types.EnvelopeStatusFailed || !strings.HasPrefix(envelope.Error, "envelope cancelled") {
+					t.Errorf("Envelope %s = %s (%q), want failed as cancelled", id, envelope.Status, envelope.Error)
+				}
+			}
+			if envelope, _ := store.Get("env-3"); envelope.Status != types.EnvelopeStatusSucceeded {
+				t.Errorf("Succeeded envelope changed to %s", envelope.Status)
+			}
+		})
+	}
+}
+
+func TestHandleEnvelopesCancel_NotifiesStreams(t *testing.T) {
+	store := envelopestore.NewStore()
+	if err := store.Create(&types.Envelope{ID: "env-1", Tool: "summarize", Route: types.Route{Actors: []string{"actor1"}}}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	updates := store.Subscribe("env-1")
+	defer store.Unsubscribe("env-1", updates)
+
+	rr := httptest.NewRecorder()
+	serveRoutes(NewHandler(store), rr, httptest.NewRequest(http.MethodPost, "/admin/envelopes/cancel",
+		bytes.NewReader([]byte(`{"tool": "summarize", "reason": "bad deploy"}`))))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Status = %d: %s", rr.Code, rr.Body.String())
+	}
+
+	select {
+	case update := <-updates:
+		if update.Status != types.EnvelopeStatusFailed || update.Error != "envelope cancelled: bad deploy" {
+			t.Errorf("Update = %s (%q), want failed with cancel reason", update.Status, update.Error)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("No update sent to subscriber")
+	}
+}
@@ -0,0 +1,92 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/deliveryhero/asya/asya-gateway/internal/envelopestore"
+	"github.com/deliveryhero/asya/asya-gateway/internal/logging"
+	"github.com/deliveryhero/asya/asya-gateway/internal/queue"
+	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+)
+
+// ErrPublishSaturated is returned by tool handlers when the gateway is already publishing
+// its maximum number of envelopes in the background; the call should be retried later
+var ErrPublishSaturated = errors.New("too many envelopes pending publish")
+
+// enqueueEnvelope publishes a freshly created envelope to the first actor's queue
+// in the background and marks it as queued once the publish succeeds.
+//
+// Until the publish completes the envelope stays pending (created, not yet published),
+// so a client that immediately fetches the envelope never sees a running status
+// for a message that has not reached the queue yet. release, if not nil, is called
+// once the publish has finished (see Registry.acquirePublish).
+func enqueueEnvelope(jobStore envelopestore.EnvelopeStore, queueClient queue.Client, envelope *types.Envelope, release func()) {
+	go func() {
+		if release != nil {
+			defer release()
+		}
+
+		ctx := logging.ContextWithJobID(context.Background(), envelope.ID)
+
+		// Skip sending to queue if queue client is not configured
+		if queueClient == nil {
+			slog.WarnContext(ctx, "Queue client not configured, skipping envelope send", "id", envelope.ID)
+			return
+		}
+
+		sendCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+
+		recordPublish(ctx, jobStore, envelope.ID, queueClient.SendEnvelope(sendCtx, envelope))
+	}()
+}
+
+// enqueueEnvelopes publishes a batch of freshly created envelopes synchronously,
+// using the queue client's batch path when it has one. Each envelope ends up queued
+// or failed; the returned slice holds the publish error (or nil) per envelope.
+func enqueueEnvelopes(ctx context.Context, jobStore envelopestore.EnvelopeStore, queueClient queue.Client, envelopes []*types.Envelope) []error {
+	errs := make([]error, len(envelopes))
+	if queueClient == nil {
+		slog.WarnContext(ctx, "Queue client not configured, skipping batch send", "count", len(envelopes))
+		return errs
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	errs = queue.SendEnvelopes(sendCtx, queueClient, envelopes)
+	for i, envelope := range envelopes {
+		recordPublish(logging.ContextWithJobID(ctx, envelope.ID), jobStore, envelope.ID, errs[i])
+	}
+	return errs
+}
+
+// recordPublish moves an envelope to queued after a successful publish, or to failed
+// when the publish returned an error. ctx carries the envelope's job ID for logging.
+func recordPublish(ctx context.Context, jobStore envelopestore.EnvelopeStore, envelopeID string, sendErr error) {
+	if sendErr != nil {
+		slog.ErrorContext(ctx, "Failed to send envelope to queue", "id", envelopeID, "error", sendErr)
+		_ = jobStore.Update(types.EnvelopeUpdate{
+			ID:        envelopeID,
+			Status:    types.EnvelopeStatusFailed,
+			Error:     fmt.Sprintf("failed to send envelope: %v", sendErr),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	// An actor may have already reported progress (running) before we get here;
+	// only move forward from pending so the status never regresses
+	if _, err := jobStore.UpdateIfStatus(types.EnvelopeUpdate{
+		ID:        envelopeID,
+		Status:    types.EnvelopeStatusQueued,
+		Message:   "Envelope sent to first actor queue",
+		Timestamp: time.Now(),
+	}, types.EnvelopeStatusPending); err != nil {
+		slog.WarnContext(ctx, "Failed to mark envelope as queued", "id", envelopeID, "error", err)
+	}
+}
@@ -0,0 +1,146 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/deliveryhero/asya/asya-gateway/internal/envelopestore"
+	"github.com/deliveryhero/asya/asya-gateway/internal/logging"
+	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+)
+
+// applyFinalUpdate stores a final status update reported for an envelope, taking fanout into account.
+//
+// Envelopes without fanout branches are finalized directly. An envelope that fanned out only
+// records its own branch result and stays running until all branches complete, at which point
+// the branch results are aggregated. Completing an envelope also completes its branch on the
+// parent, so nested fanouts aggregate bottom-up. Branches are completed once: a redelivered
+// final status of a branch is ignored. ctx carries the envelope's job ID for logging.
+func (h *Handler) applyFinalUpdate(ctx context.Context, envelope *types.Envelope, update types.EnvelopeUpdate) error {
+	if envelope.FanoutBranches == 0 {
+		if err := h.jobStore.Update(update); err != nil {
+			return err
+		}
+		h.completeParentBranch(ctx, envelope)
+		return nil
+	}
+
+	// Own branch (index 0 of its fanout) finished
+	completed, total, err := h.jobStore.CompleteFanoutBranch(envelope.ID, 0)
+	if errors.Is(err, envelopestore.ErrBranchCompleted) {
+		slog.DebugContext(ctx, "Ignoring duplicate final status of fanout branch", "id", envelope.ID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Keep result/error, but the envelope is not final yet
+	update.Status = types.EnvelopeStatusRunning
+	update.Message = "Fanout branch completed, waiting for remaining branches"
+	if err := h.jobStore.Update(update); err != nil {
+		return err
+	}
+
+	return h.branchCompleted(ctx, envelope.ID, completed, total)
+}
+
+// completeParentBranch completes the fanout branch of a finalized envelope on its parent
+func (h *Handler) completeParentBranch(ctx context.Context, envelope *types.Envelope) {
+	if envelope.ParentID == nil || *envelope.ParentID == "" {
+		return
+	}
+
+	parentID := *envelope.ParentID
+	completed, total, err := h.jobStore.CompleteFanoutBranch(parentID, envelope.BranchIndex)
+	if errors.Is(err, envelopestore.ErrBranchCompleted) {
+		slog.DebugContext(ctx, "Ignoring duplicate final status of fanout branch", "id", envelope.ID, "parent_id", parentID)
+		return
+	}
+	if err == nil {
+		err = h.branchCompleted(ctx, parentID, completed, total)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to complete fanout branch", "id", envelope.ID, "parent_id", parentID, "error", err)
+	}
+}
+
+// branchCompleted aggregates the parent's fanout once all of its branches have completed.
+// Its logs carry the parent's job ID.
+func (h *Handler) branchCompleted(ctx context.Context, parentID string, completed, total int) error {
+	ctx = logging.ContextWithJobID(ctx, parentID)
+	slog.DebugContext(ctx, "Fanout branch completed", "parent_id", parentID, "completed", completed, "total", total)
+	if completed < total {
+		return nil
+	}
+
+	return h.finalizeFanout(ctx, parentID)
+}
+
+// finalizeFanout aggregates branch results on the parent envelope and marks it final.
+// Its logs carry the parent's job ID.
+func (h *Handler) finalizeFanout(ctx context.Context, parentID string) error {
+	ctx = logging.ContextWithJobID(ctx, parentID)
+	parent, err := h.jobStore.Get(parentID)
+	if err != nil {
+		return err
+	}
+
+	children, err := h.jobStore.GetChildren(parentID)
+	if err != nil {
+		return err
+	}
+
+	// Parent's own branch first; its error is set if the branch itself failed
+	results := []any{branchResult(parent)}
+	failed := 0
+	if parent.Error != "" {
+		failed++
+	}
+	for _, child := range children {
+		results = append(results, branchResult(child))
+		if child.Status == types.EnvelopeStatusFailed {
+			failed++
+		}
+	}
+
+	progressPercent := 100.0
+	update := types.EnvelopeUpdate{
+		ID:              parentID,
+		Status:          types.EnvelopeStatusSucceeded,
+		Message:         fmt.Sprintf("Fanout completed: %d branches", len(results)),
+		Result:          results,
+		ProgressPercent: &progressPercent,
+		Timestamp:       time.Now(),
+	}
+	if failed > 0 {
+		update.Status = types.EnvelopeStatusFailed
+		update.Error = fmt.Sprintf("%d of %d fanout branches failed", failed, len(results))
+		update.Message = fmt.Sprintf("Fanout failed: %s", update.Error)
+	}
+
+	if err := h.jobStore.Update(update); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Fanout results aggregated", "id", parentID, "branches", len(results), "failed", failed)
+
+	h.completeParentBranch(ctx, parent)
+	return nil
+}
+
+// branchResult returns a branch's result, or its error wrapped in a map for failed branches.
+// Offloaded results are referenced as {"result_url": ...} instead of being loaded.
+func branchResult(envelope *types.Envelope) any {
+	result := envelope.Result
+	if envelope.ResultURL != "" {
+		result = map[string]any{"result_url": envelope.ResultURL}
+	}
+	if envelope.Error == "" {
+		return result
+	}
+	return map[string]any{"error": envelope.Error, "result": result}
+}
@@ -3,44 +3,210 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
-	"regexp"
 	"time"
 
 	"github.com/deliveryhero/asya/asya-gateway/internal/envelopestore"
+	"github.com/deliveryhero/asya/asya-gateway/internal/logging"
+	"github.com/deliveryhero/asya/asya-gateway/internal/middleware"
+	"github.com/deliveryhero/asya/asya-gateway/internal/payloadstore"
+	"github.com/deliveryhero/asya/asya-gateway/internal/queue"
 	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
-var (
-	envelopePathRegex         = regexp.MustCompile(`^/envelopes/([^/]+)$`)
-	envelopeStreamPathRegex   = regexp.MustCompile(`^/envelopes/([^/]+)/stream$`)
-	envelopeActivePathRegex   = regexp.MustCompile(`^/envelopes/([^/]+)/active$`)
-	envelopeProgressPathRegex = regexp.MustCompile(`^/envelopes/([^/]+)/progress$`)
-	envelopeFinalPathRegex    = regexp.MustCompile(`^/envelopes/([^/]+)/final$`)
-)
+// DefaultMaxBodyBytes is the default request body size limit (fits a full batch or a large final result)
+const DefaultMaxBodyBytes = 10 << 20
+
+// DefaultMaxPartialResultBytes is the default size limit of a partial result in a progress update
+const DefaultMaxPartialResultBytes = 64 << 10
+
+// DefaultMaxStreams is the default limit of concurrently open SSE envelope streams
+const DefaultMaxStreams = 1000
+
+// envelopeIDFromPath extracts and validates the {id} path value of the request.
+// ServeMux matches patterns against the escaped path and returns the decoded
+// segment, so an encoded "/" stays inside the ID (and fails validation) rather
+// than selecting another endpoint such as /stream or /active.
+// Writes 400 and returns false if the ID is missing or malformed.
+func envelopeIDFromPath(w http.ResponseWriter, r *http.Request) (string, bool) {
+	rawID := r.PathValue("id")
+	if rawID == "" {
+		http.Error(w, "Missing envelope ID", http.StatusBadRequest)
+		return "", false
+	}
+
+	envelopeID, err := types.ParseEnvelopeID(rawID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid envelope ID: %v", err), http.StatusBadRequest)
+		return "", false
+	}
+	return envelopeID, true
+}
+
+// withJobID tags the logs of requests about one envelope ({id} path value) with its ID,
+// see logging.ContextWithJobID. Malformed IDs are left to the handler to reject.
+func withJobID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if envelopeID, err := types.ParseEnvelopeID(r.PathValue("id")); err == nil {
+			r = r.WithContext(logging.ContextWithJobID(r.Context(), envelopeID))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
 
 // Handler provides HTTP endpoints for envelope management
 // MCP endpoints are now handled directly by mark3labs/mcp-go server
 type Handler struct {
 	jobStore envelopestore.EnvelopeStore
-	server   *Server // For direct tool calls
+	server   *Server                  // For direct tool calls
+	readOnly bool                     // Reject endpoints that publish to queues or mutate envelopes
+	compress bool                     // Gzip JSON status and result responses
+	results  payloadstore.ObjectStore // Object store holding offloaded results (optional)
+
+	maxBodyBytes          int64 // Request body size limit for POST endpoints
+	maxPartialResultBytes int64 // Size limit of partial results in progress updates
+
+	streamSlots slots // One slot per open SSE stream (nil: unlimited)
+
+	maxSyncWait time.Duration // Longest wait of a REST tool call for a sync reply (0: the tool's timeout)
 }
 
 // NewHandler creates a new HTTP handler for envelope management
 func NewHandler(jobStore envelopestore.EnvelopeStore) *Handler {
 	return &Handler{
-		jobStore: jobStore,
+		jobStore:              jobStore,
+		maxBodyBytes:          DefaultMaxBodyBytes,
+		maxPartialResultBytes: DefaultMaxPartialResultBytes,
+		streamSlots:           newSlots(DefaultMaxStreams),
 	}
 }
 
+// RegisterRoutes mounts the REST and envelope endpoints on mux.
+// Handlers check the method themselves so unsupported methods get 405.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// REST endpoint for tool calls (simpler alternative to SSE-based MCP)
+	mux.HandleFunc("/tools/call", h.HandleToolCall)
+
+	// Envelope creation (fanout child envelopes from sidecar) and bulk tool calls
+	mux.HandleFunc("/envelopes", h.HandleEnvelopeCreate)
+	mux.Handle("/envelopes/batch", h.compressed(h.HandleEnvelopeBatch))
+
+	// Envelope status endpoints, logging with the envelope's job ID
+	mux.Handle("/envelopes/{id}", withJobID(h.compressed(h.HandleEnvelopeStatus)))
+	mux.Handle("/envelopes/{id}/stream", withJobID(middleware.Streaming(http.HandlerFunc(h.HandleEnvelopeStream))))
+	mux.Handle("/envelopes/{id}/active", withJobID(http.HandlerFunc(h.HandleEnvelopeActive)))
+	mux.Handle("/envelopes/{id}/progress", withJobID(http.HandlerFunc(h.HandleEnvelopeProgress)))
+	mux.HandleFunc("/envelopes/progress/batch", h.HandleEnvelopeProgressBatch)
+	mux.Handle("/envelopes/{id}/final", withJobID(http.HandlerFunc(h.HandleEnvelopeFinal)))
+	// Offloaded results are streamed from the object store and may take longer than WriteTimeout
+	mux.Handle("/envelopes/{id}/result", withJobID(middleware.Streaming(h.compressed(h.HandleEnvelopeResult))))
+
+	// Incident tooling
+	mux.HandleFunc("/admin/envelopes/cancel", h.HandleEnvelopesCancel)
+}
+
+// compressed wraps JSON endpoints with gzip compression when enabled (never SSE streams)
+func (h *Handler) compressed(handler http.HandlerFunc) http.Handler {
+	if !h.compress {
+		return handler
+	}
+	return middleware.Gzip(handler)
+}
+
 // SetServer sets the MCP server for direct tool calls
 func (h *Handler) SetServer(server *Server) {
 	h.server = server
 }
 
+// SetReadOnly enables read-only mode (monitoring replicas without a queue connection).
+// In read-only mode, tool calls and envelope mutations are rejected with 503.
+func (h *Handler) SetReadOnly(readOnly bool) {
+	h.readOnly = readOnly
+}
+
+// SetCompression enables gzip compression of status, result and batch responses
+// for clients that accept it. Must be called before RegisterRoutes.
+func (h *Handler) SetCompression(enabled bool) {
+	h.compress = enabled
+}
+
+// SetResultStore sets the object store that offloaded results are read from
+func (h *Handler) SetResultStore(results payloadstore.ObjectStore) {
+	h.results = results
+}
+
+// SetMaxSyncWait limits how long a REST tool call waits for the reply of a synchronous tool,
+// e.g. to stay within the server's WriteTimeout; without a reply by then it responds
+// asynchronously. 0 waits for the tool's timeout.
+func (h *Handler) SetMaxSyncWait(maxWait time.Duration) {
+	h.maxSyncWait = maxWait
+}
+
+// SetMaxBodyBytes sets the request body size limit; larger bodies are rejected with 413
+func (h *Handler) SetMaxBodyBytes(maxBytes int64) {
+	h.maxBodyBytes = maxBytes
+}
+
+// SetMaxPartialResultBytes sets the size limit of partial results; progress updates
+// carrying a larger partial result are rejected with 413
+func (h *Handler) SetMaxPartialResultBytes(maxBytes int64) {
+	h.maxPartialResultBytes = maxBytes
+}
+
+// SetMaxStreams sets how many SSE envelope streams may be open at once; further stream
+// requests are rejected with 503. A limit of 0 or less means unlimited. Must not be called
+// while streams are being served.
+func (h *Handler) SetMaxStreams(maxStreams int) {
+	h.streamSlots = newSlots(maxStreams)
+}
+
+// acquireStream takes a stream slot, returning a function that releases it.
+// Writes 503 with Retry-After and returns false if all slots are taken.
+func (h *Handler) acquireStream(w http.ResponseWriter) (func(), bool) {
+	release, ok := h.streamSlots.tryAcquire()
+	if !ok {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "Too many open streams", http.StatusServiceUnavailable)
+	}
+	return release, ok
+}
+
+// decodeBody decodes the JSON request body into v, reading at most maxBodyBytes.
+// Returns 0 on success, or the status to reject the request with (413 or 400).
+func (h *Handler) decodeBody(w http.ResponseWriter, r *http.Request, v any) int {
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxBodyBytes)
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return http.StatusRequestEntityTooLarge
+		}
+		return http.StatusBadRequest
+	}
+	return 0
+}
+
+// bodyErrorMessage describes a decodeBody failure status for clients
+func bodyErrorMessage(status int) string {
+	if status == http.StatusRequestEntityTooLarge {
+		return "Request body too large"
+	}
+	return "Invalid request body"
+}
+
+// rejectReadOnly writes 503 and returns true if the gateway is in read-only mode
+func (h *Handler) rejectReadOnly(w http.ResponseWriter) bool {
+	if !h.readOnly {
+		return false
+	}
+	http.Error(w, "Gateway is running in read-only mode", http.StatusServiceUnavailable)
+	return true
+}
+
 // HandleToolCall handles POST /tools/call (REST endpoint for MCP tool calls)
 // This provides a simpler REST interface without requiring SSE session management
 func (h *Handler) HandleToolCall(w http.ResponseWriter, r *http.Request) {
@@ -53,15 +219,29 @@ func (h *Handler) HandleToolCall(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Name      string         `json:"name"`
 		Arguments map[string]any `json:"arguments"`
+		StartStep int            `json:"start_step"` // Route index to start at, skipping earlier actors
+		DryRun    bool           `json:"dry_run"`    // Return the route and payload without creating the envelope
+		Metadata  map[string]any `json:"metadata"`   // Route metadata passed to every actor alongside the payload
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if status := h.decodeBody(w, r, &req); status != 0 {
+		writeToolError(w, status, bodyErrorMessage(status))
+		return
+	}
+
+	// Dry runs create nothing, so read-only gateways can serve them
+	if h.readOnly && !req.DryRun {
+		writeToolError(w, http.StatusServiceUnavailable, "gateway is running in read-only mode")
 		return
 	}
 
 	if req.Name == "" {
-		http.Error(w, "Tool name is required", http.StatusBadRequest)
+		writeToolError(w, http.StatusBadRequest, "tool name is required")
+		return
+	}
+
+	if req.StartStep < 0 {
+		writeToolError(w, http.StatusBadRequest, "start_step cannot be negative")
 		return
 	}
 
@@ -75,28 +255,102 @@ func (h *Handler) HandleToolCall(w http.ResponseWriter, r *http.Request) {
 
 	// Get the tool handler from registry
 	if h.server == nil || h.server.registry == nil {
-		http.Error(w, "MCP server not initialized", http.StatusInternalServerError)
+		writeToolError(w, http.StatusInternalServerError, "MCP server not initialized")
 		return
 	}
 
 	handler := h.server.registry.GetToolHandler(req.Name)
 	if handler == nil {
-		http.Error(w, fmt.Sprintf("Tool %q not found", req.Name), http.StatusNotFound)
+		writeToolError(w, http.StatusNotFound, fmt.Sprintf("tool %q not found", req.Name))
 		return
 	}
 
 	// Call the tool handler
-	result, err := handler(context.Background(), mcpReq)
+	ctx := withRouteMetadata(withDryRun(withStartStep(context.Background(), req.StartStep), req.DryRun), req.Metadata)
+	ctx = withMaxSyncWait(ctx, h.maxSyncWait)
+	result, err := handler(ctx, mcpReq)
+	if errors.Is(err, ErrPublishSaturated) || errors.Is(err, ErrQueueUnavailable) {
+		slog.WarnContext(r.Context(), "Rejecting tool call", "tool", req.Name, "error", err)
+		w.Header().Set("Retry-After", "1")
+		writeToolError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if errors.Is(err, envelopestore.ErrTooManyActive) {
+		slog.WarnContext(r.Context(), "Rejecting tool call", "tool", req.Name, "error", err)
+		w.Header().Set("Retry-After", "1")
+		writeToolError(w, http.StatusTooManyRequests, err.Error())
+		return
+	}
+	if errors.Is(err, ErrEmptyRoute) || errors.Is(err, ErrInvalidRouteMetadata) {
+		writeToolError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
-		slog.Error("Tool call failed", "error", err)
-		http.Error(w, fmt.Sprintf("Tool call failed: %v", err), http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Tool call failed", "error", err)
+		writeToolError(w, http.StatusInternalServerError, fmt.Sprintf("tool call failed: %v", err))
 		return
 	}
 
 	// Return the result
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(result); err != nil {
-		slog.Error("Failed to encode result", "error", err)
+		slog.ErrorContext(r.Context(), "Failed to encode result", "error", err)
+	}
+}
+
+// HandleEnvelopeBatch handles POST /envelopes/batch (bulk tool calls)
+// Creates one envelope per item and publishes them together; failures are reported per item
+func (h *Handler) HandleEnvelopeBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.rejectReadOnly(w) {
+		return
+	}
+
+	var items []BatchItem
+	if status := h.decodeBody(w, r, &items); status != 0 {
+		http.Error(w, bodyErrorMessage(status), status)
+		return
+	}
+
+	if len(items) == 0 {
+		http.Error(w, "Batch must contain at least one item", http.StatusBadRequest)
+		return
+	}
+	if len(items) > MaxBatchSize {
+		http.Error(w, fmt.Sprintf("Batch exceeds maximum of %d items", MaxBatchSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if h.server == nil || h.server.registry == nil {
+		http.Error(w, "MCP server not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	// Detached from the request so a client disconnect does not abort a half-published batch
+	results, err := h.server.registry.SubmitBatch(context.WithoutCancel(r.Context()), items)
+
+	w.Header().Set("Content-Type", "application/json")
+	if errors.Is(err, queue.ErrPoolExhausted) {
+		// The failed items are reported in the body; the client should retry them later
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to encode batch results", "error", err)
+	}
+}
+
+// writeToolError writes an MCP error result (isError content) with the given HTTP status,
+// matching the error form MCP clients receive from tools/call
+func writeToolError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(mcp.NewToolResultError(message)); err != nil {
+		slog.Error("Failed to encode tool error", "error", err)
 	}
 }
 
@@ -107,16 +361,22 @@ func (h *Handler) HandleEnvelopeCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.rejectReadOnly(w) {
+		return
+	}
+
 	// Parse create request
 	var createReq struct {
-		ID       string   `json:"id"`
-		ParentID string   `json:"parent_id"`
-		Actors   []string `json:"actors"`
-		Current  int      `json:"current"`
+		ID          string   `json:"id"`
+		ParentID    string   `json:"parent_id"`
+		BranchIndex int      `json:"branch_index"`
+		Branches    int      `json:"fanout_branches"` // Branches of the parent's fanout, its own included
+		Actors      []string `json:"actors"`
+		Current     int      `json:"current"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&createReq); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if status := h.decodeBody(w, r, &createReq); status != 0 {
+		http.Error(w, bodyErrorMessage(status), status)
 		return
 	}
 
@@ -125,12 +385,35 @@ func (h *Handler) HandleEnvelopeCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	slog.Info("Creating fanout envelope", "id", createReq.ID, "parent_id", createReq.ParentID)
+	var err error
+	if createReq.ID, err = types.ParseEnvelopeID(createReq.ID); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid id: %v", err), http.StatusBadRequest)
+		return
+	}
+	r = r.WithContext(logging.ContextWithJobID(r.Context(), createReq.ID))
+	if createReq.ParentID != "" {
+		if createReq.ParentID, err = types.ParseEnvelopeID(createReq.ParentID); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid parent_id: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	maxRouteSteps := DefaultMaxRouteSteps
+	if h.server != nil && h.server.registry != nil {
+		maxRouteSteps = h.server.registry.maxRouteSteps
+	}
+	if err := validateRoute(createReq.Actors, maxRouteSteps); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid route: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Creating fanout envelope", "id", createReq.ID, "parent_id", createReq.ParentID, "branch_index", createReq.BranchIndex)
 
 	// Create minimal envelope for fanout child
 	envelope := &types.Envelope{
 		ID:              createReq.ID,
 		ParentID:        &createReq.ParentID,
+		BranchIndex:     createReq.BranchIndex,
 		Status:          types.EnvelopeStatusPending,
 		Route:           types.Route{Actors: createReq.Actors, Current: createReq.Current},
 		ProgressPercent: 0.0,
@@ -138,44 +421,36 @@ func (h *Handler) HandleEnvelopeCreate(w http.ResponseWriter, r *http.Request) {
 		ActorsCompleted: 0,
 	}
 
+	// Fanout children count toward the tool that created the parent
+	if createReq.ParentID != "" {
+		if parent, err := h.jobStore.Get(createReq.ParentID); err == nil {
+			envelope.Tool = parent.Tool
+		}
+	}
+
 	if err := h.jobStore.Create(envelope); err != nil {
-		slog.Error("Failed to create fanout envelope", "id", createReq.ID, "error", err)
+		slog.ErrorContext(r.Context(), "Failed to create fanout envelope", "id", createReq.ID, "error", err)
 		http.Error(w, "Failed to create envelope", http.StatusInternalServerError)
 		return
 	}
 
-	slog.Info("Fanout envelope created successfully", "id", createReq.ID)
-
-	// Send fanout envelope to queue (async)
-	go func() {
-		// Update status to Running
-		_ = h.jobStore.Update(types.EnvelopeUpdate{
-			ID:        createReq.ID,
-			Status:    types.EnvelopeStatusRunning,
-			Message:   "Sending envelope to first actor",
-			Timestamp: time.Now(),
-		})
-
-		// Skip sending to queue if server is not configured
-		if h.server == nil || h.server.queueClient == nil {
-			slog.Warn("Queue client not configured, skipping envelope send", "id", createReq.ID)
-			return
+	if createReq.ParentID != "" {
+		// Sidecars without fanout_branches announce the fanout one child at a time
+		branches := max(createReq.Branches, createReq.BranchIndex+1)
+		if err := h.jobStore.AddFanoutBranch(createReq.ParentID, branches); err != nil {
+			slog.WarnContext(r.Context(), "Failed to register fanout branch on parent", "id", createReq.ID, "parent_id", createReq.ParentID, "error", err)
 		}
+	}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		if err := h.server.queueClient.SendEnvelope(ctx, envelope); err != nil {
-			slog.Error("Failed to send fanout envelope to queue", "id", createReq.ID, "error", err)
-			_ = h.jobStore.Update(types.EnvelopeUpdate{
-				ID:        createReq.ID,
-				Status:    types.EnvelopeStatusFailed,
-				Error:     fmt.Sprintf("failed to send envelope: %v", err),
-				Timestamp: time.Now(),
-			})
-			return
-		}
-	}()
+	slog.InfoContext(r.Context(), "Fanout envelope created successfully", "id", createReq.ID)
+
+	// Mark as queued and send fanout envelope to queue (async). Fanout children do not take
+	// a publish slot: sidecars do not retry a rejected create, the child would go untracked.
+	var queueClient queue.Client
+	if h.server != nil {
+		queueClient = h.server.queueClient
+	}
+	enqueueEnvelope(h.jobStore, queueClient, envelope, nil)
 
 	w.WriteHeader(http.StatusCreated)
 	_ = json.NewEncoder(w).Encode(map[string]string{"status": "created", "id": createReq.ID})
@@ -188,12 +463,10 @@ func (h *Handler) HandleEnvelopeStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	matches := envelopePathRegex.FindStringSubmatch(r.URL.Path)
-	if matches == nil {
-		http.Error(w, "Invalid envelope path", http.StatusBadRequest)
+	envelopeID, ok := envelopeIDFromPath(w, r)
+	if !ok {
 		return
 	}
-	envelopeID := matches[1]
 
 	envelope, err := h.jobStore.Get(envelopeID)
 	if err != nil {
@@ -203,7 +476,80 @@ func (h *Handler) HandleEnvelopeStatus(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(envelope); err != nil {
-		slog.Error("Failed to encode envelope", "error", err)
+		slog.ErrorContext(r.Context(), "Failed to encode envelope", "error", err)
+	}
+}
+
+// HandleEnvelopeResult handles GET /envelopes/{id}/result
+// Returns the result payload of a succeeded envelope, 202 while it is still in progress,
+// and 410 for envelopes that finished without a result
+func (h *Handler) HandleEnvelopeResult(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	envelopeID, ok := envelopeIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	envelope, err := h.jobStore.Get(envelopeID)
+	if err != nil {
+		http.Error(w, "Envelope not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	switch envelope.Status {
+	case types.EnvelopeStatusSucceeded:
+		if envelope.ResultURL != "" {
+			h.streamOffloadedResult(w, r, envelope)
+			return
+		}
+		// Encode directly to the response so large results are not buffered twice
+		if err := json.NewEncoder(w).Encode(envelope.Result); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to encode envelope result", "id", envelopeID, "error", err)
+		}
+	case types.EnvelopeStatusFailed, types.EnvelopeStatusUnknown:
+		w.WriteHeader(http.StatusGone)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id":     envelope.ID,
+			"status": envelope.Status,
+			"error":  envelope.Error,
+		})
+	default:
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id":     envelope.ID,
+			"status": envelope.Status,
+		})
+	}
+}
+
+// streamOffloadedResult copies a result offloaded to the object store to the response
+func (h *Handler) streamOffloadedResult(w http.ResponseWriter, r *http.Request, envelope *types.Envelope) {
+	if h.results == nil {
+		slog.ErrorContext(r.Context(), "Envelope result is offloaded but no result store is configured", "id", envelope.ID, "url", envelope.ResultURL)
+		http.Error(w, "Result store not configured", http.StatusBadGateway)
+		return
+	}
+
+	body, err := h.results.Open(r.Context(), envelope.ResultURL)
+	if errors.Is(err, payloadstore.ErrNotFound) {
+		http.Error(w, "Result not found in result store", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to read offloaded result", "id", envelope.ID, "url", envelope.ResultURL, "error", err)
+		http.Error(w, "Failed to read result", http.StatusBadGateway)
+		return
+	}
+	defer func() { _ = body.Close() }()
+
+	if _, err := io.Copy(w, body); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to stream offloaded result", "id", envelope.ID, "error", err)
 	}
 }
 
@@ -214,12 +560,10 @@ func (h *Handler) HandleEnvelopeStream(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	matches := envelopeStreamPathRegex.FindStringSubmatch(r.URL.Path)
-	if matches == nil {
-		http.Error(w, "Invalid envelope stream path", http.StatusBadRequest)
+	envelopeID, ok := envelopeIDFromPath(w, r)
+	if !ok {
 		return
 	}
-	envelopeID := matches[1]
 
 	// Verify envelope exists
 	_, err := h.jobStore.Get(envelopeID)
@@ -228,6 +572,14 @@ func (h *Handler) HandleEnvelopeStream(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Bound the number of open streams, each holds a goroutine and a store subscription
+	release, ok := h.acquireStream(w)
+	if !ok {
+		slog.WarnContext(r.Context(), "Rejecting envelope stream, too many open streams", "envelope_id", envelopeID)
+		return
+	}
+	defer release()
+
 	// Set SSE headers
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
@@ -242,12 +594,12 @@ func (h *Handler) HandleEnvelopeStream(w http.ResponseWriter, r *http.Request) {
 	// Send historical updates first (to avoid missing early progress updates)
 	historicalUpdates, err := h.jobStore.GetUpdates(envelopeID, nil)
 	if err != nil {
-		slog.Warn("Failed to get historical updates", "error", err, "envelope_id", envelopeID)
+		slog.WarnContext(r.Context(), "Failed to get historical updates", "error", err, "envelope_id", envelopeID)
 	} else {
 		for _, update := range historicalUpdates {
 			data, err := json.Marshal(update)
 			if err != nil {
-				slog.Error("Failed to marshal historical update", "error", err)
+				slog.ErrorContext(r.Context(), "Failed to marshal historical update", "error", err)
 				continue
 			}
 			// Security: Safe to use Fprintf here - data is pre-encoded JSON for SSE streaming.
@@ -280,7 +632,7 @@ func (h *Handler) HandleEnvelopeStream(w http.ResponseWriter, r *http.Request) {
 			// Send update
 			data, err := json.Marshal(update)
 			if err != nil {
-				slog.Error("Failed to marshal update", "error", err)
+				slog.ErrorContext(r.Context(), "Failed to marshal update", "error", err)
 				continue
 			}
 
@@ -314,12 +666,10 @@ func (h *Handler) HandleEnvelopeActive(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	matches := envelopeActivePathRegex.FindStringSubmatch(r.URL.Path)
-	if matches == nil {
-		http.Error(w, "Invalid envelope active path", http.StatusBadRequest)
+	envelopeID, ok := envelopeIDFromPath(w, r)
+	if !ok {
 		return
 	}
-	envelopeID := matches[1]
 
 	// Check if envelope is active
 	if h.jobStore.IsActive(envelopeID) {
@@ -338,23 +688,125 @@ func (h *Handler) HandleEnvelopeProgress(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	matches := envelopeProgressPathRegex.FindStringSubmatch(r.URL.Path)
-	if matches == nil {
-		http.Error(w, "Invalid envelope progress path", http.StatusBadRequest)
+	if h.rejectReadOnly(w) {
+		return
+	}
+
+	envelopeID, ok := envelopeIDFromPath(w, r)
+	if !ok {
 		return
 	}
-	envelopeID := matches[1]
 
 	// Parse progress update
 	var progress types.ProgressUpdate
-	if err := json.NewDecoder(r.Body).Decode(&progress); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if status := h.decodeBody(w, r, &progress); status != 0 {
+		http.Error(w, bodyErrorMessage(status), status)
 		return
 	}
 
 	progress.ID = envelopeID
 
-	slog.Debug("Received progress update from actor",
+	progressPercent, rejected := h.applyProgress(r.Context(), progress)
+	if rejected != nil {
+		http.Error(w, rejected.message, rejected.status)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":           "ok",
+		"progress_percent": progressPercent,
+	})
+}
+
+// ProgressBatchResult reports the outcome of one update of a progress batch, in request order.
+// Status is "ok" when the update was stored, or "rejected" with the reason in Error.
+type ProgressBatchResult struct {
+	Index           int     `json:"index"`
+	EnvelopeID      string  `json:"envelope_id,omitempty"`
+	Status          string  `json:"status"`
+	ProgressPercent float64 `json:"progress_percent"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// HandleEnvelopeProgressBatch handles POST /envelopes/progress/batch (for sidecars to report
+// progress of many envelopes in one request). Updates are applied in request order, so
+// transitions of the same envelope keep their order; failures are reported per update.
+func (h *Handler) HandleEnvelopeProgressBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.rejectReadOnly(w) {
+		return
+	}
+
+	var updates []types.ProgressUpdate
+	if status := h.decodeBody(w, r, &updates); status != 0 {
+		http.Error(w, bodyErrorMessage(status), status)
+		return
+	}
+
+	if len(updates) == 0 {
+		http.Error(w, "Batch must contain at least one update", http.StatusBadRequest)
+		return
+	}
+	if len(updates) > MaxBatchSize {
+		http.Error(w, fmt.Sprintf("Batch exceeds maximum of %d updates", MaxBatchSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	results := make([]ProgressBatchResult, len(updates))
+	for i, progress := range updates {
+		results[i] = ProgressBatchResult{Index: i, EnvelopeID: progress.ID, Status: "ok"}
+
+		envelopeID, err := types.ParseEnvelopeID(progress.ID)
+		if err != nil {
+			results[i].Status = batchStatusRejected
+			results[i].Error = fmt.Sprintf("Invalid envelope ID: %v", err)
+			continue
+		}
+		progress.ID = envelopeID
+
+		progressPercent, rejected := h.applyProgress(logging.ContextWithJobID(r.Context(), envelopeID), progress)
+		if rejected != nil {
+			results[i].Status = batchStatusRejected
+			results[i].Error = rejected.message
+			continue
+		}
+		results[i].ProgressPercent = progressPercent
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to encode progress batch results", "error", err)
+	}
+}
+
+// progressRejection explains why a progress update was not stored, with the HTTP status to reject it with
+type progressRejection struct {
+	status  int
+	message string
+}
+
+// applyProgress stores a progress update reported by an actor and returns the envelope's
+// progress percentage, or why the update was rejected
+func (h *Handler) applyProgress(ctx context.Context, progress types.ProgressUpdate) (float64, *progressRejection) {
+	envelopeID := progress.ID
+
+	// Partial results are streamed and stored with every update, so keep them small
+	var partialResult any
+	if len(progress.PartialResult) > 0 {
+		if int64(len(progress.PartialResult)) > h.maxPartialResultBytes {
+			return 0, &progressRejection{http.StatusRequestEntityTooLarge, fmt.Sprintf("Partial result too large (limit %d bytes)", h.maxPartialResultBytes)}
+		}
+		if err := json.Unmarshal(progress.PartialResult, &partialResult); err != nil {
+			return 0, &progressRejection{http.StatusBadRequest, "Invalid partial result"}
+		}
+	}
+
+	slog.DebugContext(ctx, "Received progress update from actor",
 		"envelope_id", envelopeID,
 		"status", progress.Status,
 		"current_actor_idx", progress.CurrentActorIdx,
@@ -379,9 +831,14 @@ func (h *Handler) HandleEnvelopeProgress(w http.ResponseWriter, r *http.Request)
 	// This ensures progress calculation is consistent even if sidecar sends partial/empty actors list
 	envelope, err := h.jobStore.Get(envelopeID)
 	if err != nil {
-		slog.Error("Failed to get envelope for progress calculation", "id", envelopeID, "error", err)
-		http.Error(w, "Failed to get envelope", http.StatusInternalServerError)
-		return
+		slog.ErrorContext(ctx, "Failed to get envelope for progress calculation", "id", envelopeID, "error", err)
+		return 0, &progressRejection{http.StatusInternalServerError, "Failed to get envelope"}
+	}
+
+	// A late update (e.g. sent in a batch after the end actor reported) must not reopen a finished envelope
+	if isFinalStatus(envelope.Status) {
+		slog.DebugContext(ctx, "Ignoring progress update of finished envelope", "id", envelopeID, "status", envelope.Status)
+		return envelope.ProgressPercent, nil
 	}
 
 	// Use envelope's actors list as the source of truth
@@ -389,20 +846,20 @@ func (h *Handler) HandleEnvelopeProgress(w http.ResponseWriter, r *http.Request)
 	if len(progress.Actors) > 0 && len(progress.Actors) > len(actors) {
 		// If progress update has more actors (route was extended), use that instead
 		actors = progress.Actors
-		slog.Debug("Progress update has extended route", "id", envelopeID, "envelope_actors", len(envelope.Route.Actors), "progress_actors", len(progress.Actors))
+		slog.DebugContext(ctx, "Progress update has extended route", "id", envelopeID, "envelope_actors", len(envelope.Route.Actors), "progress_actors", len(progress.Actors))
 	}
 	progress.Actors = actors
 
 	totalActors := len(actors)
 	if totalActors == 0 {
-		slog.Warn("No actors in route for progress calculation", "id", envelopeID)
+		slog.WarnContext(ctx, "No actors in route for progress calculation", "id", envelopeID)
 		progress.ProgressPercent = 0
 	} else {
 		newProgress := (float64(progress.CurrentActorIdx)*100 + statusWeight) / float64(totalActors)
 
 		// Enforce monotonic progress: never decrease
 		if newProgress < envelope.ProgressPercent {
-			slog.Debug("Skipping non-monotonic progress update",
+			slog.DebugContext(ctx, "Skipping non-monotonic progress update",
 				"id", envelopeID,
 				"current", envelope.ProgressPercent,
 				"new", newProgress,
@@ -411,7 +868,7 @@ func (h *Handler) HandleEnvelopeProgress(w http.ResponseWriter, r *http.Request)
 			progress.ProgressPercent = envelope.ProgressPercent
 		} else {
 			progress.ProgressPercent = newProgress
-			slog.Debug("Calculated progress", "id", envelopeID, "actor_idx", progress.CurrentActorIdx, "status", progress.Status, "percent", progress.ProgressPercent, "total_actors", totalActors)
+			slog.DebugContext(ctx, "Calculated progress", "id", envelopeID, "actor_idx", progress.CurrentActorIdx, "status", progress.Status, "percent", progress.ProgressPercent, "total_actors", totalActors)
 		}
 	}
 
@@ -425,7 +882,10 @@ func (h *Handler) HandleEnvelopeProgress(w http.ResponseWriter, r *http.Request)
 	// - Sets envelope-level status to Running
 	// - Copies envelope processing state ("received", "processing", "completed")
 	// - Copies route information (Actors and CurrentActorIdx) to persist modifications
+	//   and derives the current actor name from the route position
 	// - Adds calculated progress percentage and timestamp
+	// - Passes the partial result through, it never sets the envelope's final Result
+	// - Passes warnings through, the store appends them to the envelope's warnings
 	envelopeState := string(progress.Status)
 	update := types.EnvelopeUpdate{
 		ID:              envelopeID,
@@ -435,27 +895,34 @@ func (h *Handler) HandleEnvelopeProgress(w http.ResponseWriter, r *http.Request)
 		Actors:          progress.Actors,
 		CurrentActorIdx: &progress.CurrentActorIdx,
 		EnvelopeState:   &envelopeState,
+		PartialResult:   partialResult,
+		Warnings:        progress.Warnings,
 		Timestamp:       time.Now(),
 	}
 
+	// Derive current actor from route position so SSE clients see which step is active
+	if progress.CurrentActorIdx >= 0 && progress.CurrentActorIdx < len(actors) {
+		update.Actor = actors[progress.CurrentActorIdx]
+	}
+
 	// Update envelope store (using UpdateProgress for lighter weight update)
 	if err := h.jobStore.UpdateProgress(update); err != nil {
-		slog.Error("Failed to update envelope progress", "error", err)
-		http.Error(w, "Failed to update progress", http.StatusInternalServerError)
-		return
+		slog.ErrorContext(ctx, "Failed to update envelope progress", "error", err)
+		return 0, &progressRejection{http.StatusInternalServerError, "Failed to update progress"}
+	}
+
+	// The first report of an actor confirms that a published envelope was picked up
+	if envelope.Status == types.EnvelopeStatusQueued {
+		slog.DebugContext(ctx, "Envelope picked up by actor", "id", envelopeID, "actor", update.Actor, "queue_wait", update.Timestamp.Sub(envelopestore.QueuedAt(envelope)))
 	}
 
-	slog.Debug("Progress update stored in postgres",
+	slog.DebugContext(ctx, "Progress update stored in postgres",
 		"envelope_id", envelopeID,
 		"status", progress.Status,
 		"current_actor_idx", progress.CurrentActorIdx,
 		"progress_percent", progress.ProgressPercent)
 
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":           "ok",
-		"progress_percent": progress.ProgressPercent,
-	})
+	return progress.ProgressPercent, nil
 }
 
 // HandleJobFinal handles POST /envelopes/{id}/final (for end actors to report final status)
@@ -466,12 +933,14 @@ func (h *Handler) HandleEnvelopeFinal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	matches := envelopeFinalPathRegex.FindStringSubmatch(r.URL.Path)
-	if matches == nil {
-		http.Error(w, "Invalid envelope final path", http.StatusBadRequest)
+	if h.rejectReadOnly(w) {
+		return
+	}
+
+	envelopeID, ok := envelopeIDFromPath(w, r)
+	if !ok {
 		return
 	}
-	envelopeID := matches[1]
 
 	// Parse final status update
 	var finalUpdate struct {
@@ -488,8 +957,8 @@ func (h *Handler) HandleEnvelopeFinal(w http.ResponseWriter, r *http.Request) {
 		Timestamp        string                 `json:"timestamp"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&finalUpdate); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if status := h.decodeBody(w, r, &finalUpdate); status != 0 {
+		http.Error(w, bodyErrorMessage(status), status)
 		return
 	}
 
@@ -501,12 +970,12 @@ func (h *Handler) HandleEnvelopeFinal(w http.ResponseWriter, r *http.Request) {
 	case "failed":
 		envelopeStatus = types.EnvelopeStatusFailed
 	default:
-		slog.Error("Invalid final status", "id", envelopeID, "status", finalUpdate.Status)
+		slog.ErrorContext(r.Context(), "Invalid final status", "id", envelopeID, "status", finalUpdate.Status)
 		http.Error(w, "Invalid status: must be 'succeeded' or 'failed'", http.StatusBadRequest)
 		return
 	}
 
-	slog.Info("Received final status from end actor",
+	slog.InfoContext(r.Context(), "Received final status from end actor",
 		"id", envelopeID,
 		"status", envelopeStatus,
 		"hasResult", finalUpdate.Result != nil,
@@ -571,19 +1040,26 @@ func (h *Handler) HandleEnvelopeFinal(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	slog.Debug("Updating envelope with final status",
+	slog.DebugContext(r.Context(), "Updating envelope with final status",
 		"id", envelopeID,
 		"status", envelopeStatus,
 		"message", update.Message)
 
-	// Update envelope store
-	if err := h.jobStore.Update(update); err != nil {
-		slog.Error("Failed to update envelope with final status", "id", envelopeID, "error", err)
+	envelope, err := h.jobStore.Get(envelopeID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to get envelope for final status", "id", envelopeID, "error", err)
+		http.Error(w, "Failed to update envelope", http.StatusInternalServerError)
+		return
+	}
+
+	// Update envelope store (aggregating fanout branches when needed)
+	if err := h.applyFinalUpdate(r.Context(), envelope, update); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to update envelope with final status", "id", envelopeID, "error", err)
 		http.Error(w, "Failed to update envelope", http.StatusInternalServerError)
 		return
 	}
 
-	slog.Info("Envelope final status updated successfully",
+	slog.InfoContext(r.Context(), "Envelope final status updated successfully",
 		"id", envelopeID,
 		"status", envelopeStatus)
 
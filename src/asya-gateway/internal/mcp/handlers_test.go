@@ -2,20 +2,33 @@ package mcp
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
-	"regexp"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/deliveryhero/asya/asya-gateway/internal/config"
 	"github.com/deliveryhero/asya/asya-gateway/internal/envelopestore"
+	"github.com/deliveryhero/asya/asya-gateway/internal/logging"
+	"github.com/deliveryhero/asya/asya-gateway/internal/payloadstore"
+	"github.com/deliveryhero/asya/asya-gateway/internal/queue"
 	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
 )
 
+// serveRoutes dispatches req through a ServeMux so {id} path values are set
+func serveRoutes(h *Handler, w http.ResponseWriter, req *http.Request) {
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	mux.ServeHTTP(w, req)
+}
+
 func TestHandleEnvelopeProgress(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -75,13 +88,6 @@ func TestHandleEnvelopeProgress(t *testing.T) {
 			jobExists:  true,
 			wantStatus: http.StatusMethodNotAllowed,
 		},
-		{
-			name:       "missing envelope ID",
-			method:     http.MethodPost,
-			envelopeID: "",
-			jobExists:  false,
-			wantStatus: http.StatusBadRequest,
-		},
 	}
 
 	for _, tt := range tests {
@@ -121,7 +127,7 @@ func TestHandleEnvelopeProgress(t *testing.T) {
 			rr := httptest.NewRecorder()
 
 			// Call handler
-			handler.HandleEnvelopeProgress(rr, req)
+			serveRoutes(handler, rr, req)
 
 			// Check status code
 			if rr.Code != tt.wantStatus {
@@ -235,7 +241,7 @@ func TestHandleEnvelopeProgress_ProgressCalculation(t *testing.T) {
 			req.Header.Set("Content-Type", "application/json")
 			rr := httptest.NewRecorder()
 
-			handler.HandleEnvelopeProgress(rr, req)
+			serveRoutes(handler, rr, req)
 
 			if rr.Code != http.StatusOK {
 				t.Fatalf("Expected status 200, got %v", rr.Code)
@@ -289,7 +295,7 @@ func TestHandleEnvelopeProgress_SSENotification(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
-	handler.HandleEnvelopeProgress(rr, req)
+	serveRoutes(handler, rr, req)
 
 	if rr.Code != http.StatusOK {
 		t.Fatalf("Expected status 200, got %v", rr.Code)
@@ -318,6 +324,159 @@ func TestHandleEnvelopeProgress_SSENotification(t *testing.T) {
 	}
 }
 
+// TestHandleEnvelopeProgress_ArbitraryActorNames tests that the current step is resolved from the
+// route position alone, so no actor or queue naming convention is assumed
+func TestHandleEnvelopeProgress_ArbitraryActorNames(t *testing.T) {
+	actors := []string{"ingest", "scorer-actor", "actor", "asya-ranker.v2"}
+
+	for idx, wantActor := range actors {
+		t.Run(wantActor, func(t *testing.T) {
+			store := envelopestore.NewStore()
+			handler := NewHandler(store)
+
+			envelopeID := fmt.Sprintf("naming-%d", idx)
+			if err := store.Create(&types.Envelope{ID: envelopeID, Route: types.Route{Actors: actors}}); err != nil {
+				t.Fatalf("Failed to create test envelope: %v", err)
+			}
+
+			updateChan := store.Subscribe(envelopeID)
+			defer store.Unsubscribe(envelopeID, updateChan)
+
+			body := fmt.Sprintf(`{"current_actor_idx":%d,"status":"processing"}`, idx)
+			rr := httptest.NewRecorder()
+			serveRoutes(handler, rr, httptest.NewRequest(http.MethodPost, "/envelopes/"+envelopeID+"/progress", strings.NewReader(body)))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("status = %v, want 200, body = %s", rr.Code, rr.Body.String())
+			}
+
+			select {
+			case update := <-updateChan:
+				if update.Actor != wantActor {
+					t.Errorf("update actor = %q, want %q", update.Actor, wantActor)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Timeout waiting for progress update")
+			}
+		})
+	}
+}
+
+func TestHandleEnvelopeProgressBatch(t *testing.T) {
+	store := envelopestore.NewStore()
+	handler := NewHandler(store)
+	handler.SetMaxPartialResultBytes(16)
+
+	for _, id := range []string{"batch-1", "batch-2"} {
+		if err := store.Create(&types.Envelope{ID: id, Route: types.Route{Actors: []string{"parser", "processor"}}}); err != nil {
+			t.Fatalf("Failed to create test envelope: %v", err)
+		}
+	}
+	if err := store.Create(&types.Envelope{ID: "batch-done", Route: types.Route{Actors: []string{"parser"}}}); err != nil {
+		t.Fatalf("Failed to create test envelope: %v", err)
+	}
+	if err := store.Update(types.EnvelopeUpdate{ID: "batch-done", Status: types.EnvelopeStatusSucceeded, Timestamp: time.Now()}); err != nil {
+		t.Fatalf("Failed to finish test envelope: %v", err)
+	}
+
+	body := `[
+		{"id":"batch-1","actors":["parser","processor"],"current_actor_idx":0,"status":"received"},
+		{"id":"batch-2","actors":["parser","processor"],"current_actor_idx":1,"status":"processing","message":"Processing in processor"},
+		{"id":"batch-1","actors":["parser","processor"],"current_actor_idx":0,"status":"completed"},
+		{"id":"missing","actors":["parser"],"current_actor_idx":0,"status":"received"},
+		{"id":"bad/id","current_actor_idx":0,"status":"received"},
+		{"id":"batch-2","current_actor_idx":1,"status":"processing","partial_result":{"text":"far too long for the limit"}},
+		{"id":"batch-done","actors":["parser"],"current_actor_idx":0,"status":"completed"}
+	]`
+	rr := httptest.NewRecorder()
+	serveRoutes(handler, rr, httptest.NewRequest(http.MethodPost, "/envelopes/progress/batch", strings.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %v, want 200, body = %s", rr.Code, rr.Body.String())
+	}
+
+	var results []ProgressBatchResult
+	if err := json.NewDecoder(rr.Body).Decode(&results); err != nil {
+		t.Fatalf("Failed to decode results: %v", err)
+	}
+
+	want := []struct {
+		status  string
+		percent float64
+	}{
+		{status: "ok", percent: 5},
+		{status: "ok", percent: 75},
+		{status: "ok", percent: 50},
+		{status: batchStatusRejected},
+		{status: batchStatusRejected},
+		{status: batchStatusRejected},
+		{status: "ok"}, // Ignored, progress stays as recorded
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d: %+v", len(results), len(want), results)
+	}
+	for i, w := range want {
+		if results[i].Index != i || results[i].Status != w.status || results[i].ProgressPercent != w.percent {
+			t.Errorf("result %d = %+v, want status %s and progress %v", i, results[i], w.status, w.percent)
+		}
+		if (w.status == batchStatusRejected) != (results[i].Error != "") {
+			t.Errorf("result %d error = %q, want an error only for rejected updates", i, results[i].Error)
+		}
+	}
+
+	// Updates of the same envelope are applied in request order
+	envelope, err := store.Get("batch-1")
+	if err != nil {
+		t.Fatalf("Failed to get envelope: %v", err)
+	}
+	if envelope.ProgressPercent != 50 {
+		t.Errorf("batch-1 progress = %v, want 50", envelope.ProgressPercent)
+	}
+	envelope, err = store.Get("batch-2")
+	if err != nil {
+		t.Fatalf("Failed to get envelope: %v", err)
+	}
+	if envelope.Message != "Processing in processor" || envelope.PartialResult != nil {
+		t.Errorf("batch-2 message = %q, partial result = %v; the rejected update must not be stored", envelope.Message, envelope.PartialResult)
+	}
+
+	// A late update does not reopen a finished envelope
+	envelope, err = store.Get("batch-done")
+	if err != nil {
+		t.Fatalf("Failed to get envelope: %v", err)
+	}
+	if envelope.Status != types.EnvelopeStatusSucceeded {
+		t.Errorf("batch-done status = %v, want %v", envelope.Status, types.EnvelopeStatusSucceeded)
+	}
+}
+
+func TestHandleEnvelopeProgressBatch_InvalidRequests(t *testing.T) {
+	handler := NewHandler(envelopestore.NewStore())
+
+	tooMany := make([]types.ProgressUpdate, MaxBatchSize+1)
+	tooManyBody, _ := json.Marshal(tooMany)
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+	}{
+		{name: "invalid method", method: http.MethodGet, wantStatus: http.StatusMethodNotAllowed},
+		{name: "invalid body", method: http.MethodPost, body: `{"id":"not-a-list"}`, wantStatus: http.StatusBadRequest},
+		{name: "empty batch", method: http.MethodPost, body: `[]`, wantStatus: http.StatusBadRequest},
+		{name: "too many updates", method: http.MethodPost, body: string(tooManyBody), wantStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			serveRoutes(handler, rr, httptest.NewRequest(tt.method, "/envelopes/progress/batch", strings.NewReader(tt.body)))
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status = %v, want %v, body = %s", rr.Code, tt.wantStatus, rr.Body.String())
+			}
+		})
+	}
+}
+
 // TestHandleToolCall tests the REST API endpoint for calling MCP tools
 func TestHandleToolCall(t *testing.T) {
 	tests := []struct {
@@ -457,82 +616,229 @@ func TestHandleToolCall(t *testing.T) {
 					t.Errorf("Failed to decode response: %v", err)
 				}
 			}
+
+			// Tool errors use the MCP isError result form, same as MCP tools/call
+			if tt.wantStatus != http.StatusOK && tt.wantStatus != http.StatusMethodNotAllowed {
+				var result struct {
+					IsError bool `json:"isError"`
+					Content []struct {
+						Type string `json:"type"`
+						Text string `json:"text"`
+					} `json:"content"`
+				}
+				if err := json.NewDecoder(rr.Body).Decode(&result); err != nil {
+					t.Fatalf("Failed to decode error result: %v", err)
+				}
+				if !result.IsError {
+					t.Error("Expected isError=true in error result")
+				}
+				if len(result.Content) == 0 || result.Content[0].Text == "" {
+					t.Error("Expected error text content in error result")
+				}
+			}
 		})
 	}
 }
 
-// TestHandleEnvelopeStatus tests the GET /envelopes/{id} endpoint
-func TestHandleEnvelopeStatus(t *testing.T) {
+func TestHandleToolCall_StartStep(t *testing.T) {
 	tests := []struct {
 		name        string
-		method      string
-		envelopeID  string
-		setupEnv    bool
-		envStatus   types.EnvelopeStatus
+		startStep   interface{}
 		wantStatus  int
-		checkFields bool
+		wantIsError bool
+		wantCurrent int
+		wantActor   string
+	}{
+		{name: "omitted starts at first actor", startStep: nil, wantStatus: http.StatusOK, wantCurrent: 0, wantActor: "prep"},
+		{name: "resume at middle actor", startStep: 1, wantStatus: http.StatusOK, wantCurrent: 1, wantActor: "infer"},
+		{name: "resume at last actor", startStep: 2, wantStatus: http.StatusOK, wantCurrent: 2, wantActor: "post"},
+		{name: "past the end of the route", startStep: 3, wantStatus: http.StatusOK, wantIsError: true},
+		{name: "negative", startStep: -1, wantStatus: http.StatusBadRequest, wantIsError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := envelopestore.NewStore()
+			handler := NewHandler(store)
+			handler.SetServer(NewServer(store, nil, &config.Config{
+				Tools: []config.Tool{
+					{Name: "pipeline", Route: config.RouteSpec{Actors: []string{"prep", "infer", "post"}}},
+				},
+			}))
+
+			body := map[string]interface{}{"name": "pipeline", "arguments": map[string]interface{}{"input": "x"}}
+			if tt.startStep != nil {
+				body["start_step"] = tt.startStep
+			}
+			bodyBytes, _ := json.Marshal(body)
+
+			rr := httptest.NewRecorder()
+			handler.HandleToolCall(rr, httptest.NewRequest(http.MethodPost, "/tools/call", bytes.NewReader(bodyBytes)))
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("Status = %d, want %d: %s", rr.Code, tt.wantStatus, rr.Body.String())
+			}
+
+			var result struct {
+				IsError bool `json:"isError"`
+				Content []struct {
+					Text string `json:"text"`
+				} `json:"content"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&result); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if result.IsError != tt.wantIsError {
+				t.Fatalf("isError = %v, want %v", result.IsError, tt.wantIsError)
+			}
+			if tt.wantIsError {
+				return
+			}
+
+			var response map[string]interface{}
+			if err := json.Unmarshal([]byte(result.Content[0].Text), &response); err != nil {
+				t.Fatalf("Failed to parse tool response: %v", err)
+			}
+			envelope, err := store.Get(response["envelope_id"].(string))
+			if err != nil {
+				t.Fatalf("Envelope not found: %v", err)
+			}
+			if envelope.Route.Current != tt.wantCurrent {
+				t.Errorf("Route.Current = %d, want %d", envelope.Route.Current, tt.wantCurrent)
+			}
+			if envelope.CurrentActorName != tt.wantActor {
+				t.Errorf("CurrentActorName = %q, want %q", envelope.CurrentActorName, tt.wantActor)
+			}
+		})
+	}
+}
+
+// TestHandleToolCall_Metadata tests that route metadata given at creation is validated and set on the envelope route
+func TestHandleToolCall_Metadata(t *testing.T) {
+	tests := []struct {
+		name         string
+		metadata     map[string]any
+		wantStatus   int
+		wantMetadata map[string]interface{} // Without job_id
 	}{
+		{name: "omitted", wantStatus: http.StatusOK, wantMetadata: map[string]interface{}{}},
 		{
-			name:        "valid GET - pending envelope",
-			method:      http.MethodGet,
-			envelopeID:  "test-env-1",
-			setupEnv:    true,
-			envStatus:   types.EnvelopeStatusPending,
-			wantStatus:  http.StatusOK,
-			checkFields: true,
+			name:         "client context",
+			metadata:     map[string]any{"user_id": "u-1", "request": map[string]any{"locale": "de"}},
+			wantStatus:   http.StatusOK,
+			wantMetadata: map[string]interface{}{"user_id": "u-1", "request": map[string]interface{}{"locale": "de"}},
 		},
+		{name: "reserved job_id", metadata: map[string]any{"job_id": "spoofed"}, wantStatus: http.StatusBadRequest},
+		{name: "empty key", metadata: map[string]any{"": "x"}, wantStatus: http.StatusBadRequest},
 		{
-			name:        "valid GET - running envelope",
-			method:      http.MethodGet,
-			envelopeID:  "test-env-2",
-			setupEnv:    true,
-			envStatus:   types.EnvelopeStatusRunning,
-			wantStatus:  http.StatusOK,
-			checkFields: true,
+			name:       "too large",
+			metadata:   map[string]any{"blob": strings.Repeat("x", MaxRouteMetadataBytes)},
+			wantStatus: http.StatusBadRequest,
 		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := envelopestore.NewStore()
+			handler := NewHandler(store)
+			handler.SetServer(NewServer(store, nil, &config.Config{
+				Tools: []config.Tool{
+					{Name: "pipeline", Route: config.RouteSpec{Actors: []string{"prep", "infer"}}},
+				},
+			}))
+
+			bodyBytes, _ := json.Marshal(map[string]interface{}{"name": "pipeline", "arguments": map[string]interface{}{}, "metadata": tt.metadata})
+			rr := httptest.NewRecorder()
+			handler.HandleToolCall(rr, httptest.NewRequest(http.MethodPost, "/tools/call", bytes.NewReader(bodyBytes)))
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("Status = %d, want %d: %s", rr.Code, tt.wantStatus, rr.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				if ids, _ := store.ListActive(envelopestore.EnvelopeFilter{}); len(ids) != 0 {
+					t.Errorf("envelopes created = %v, want none", ids)
+				}
+				return
+			}
+
+			var result struct {
+				Content []struct {
+					Text string `json:"text"`
+				} `json:"content"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&result); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			var response map[string]interface{}
+			if err := json.Unmarshal([]byte(result.Content[0].Text), &response); err != nil {
+				t.Fatalf("Failed to parse tool response: %v", err)
+			}
+			envelope, err := store.Get(response["envelope_id"].(string))
+			if err != nil {
+				t.Fatalf("Envelope not found: %v", err)
+			}
+
+			tt.wantMetadata["job_id"] = envelope.ID
+			if !reflect.DeepEqual(envelope.Route.Metadata, tt.wantMetadata) {
+				t.Errorf("Route.Metadata = %v, want %v", envelope.Route.Metadata, tt.wantMetadata)
+			}
+		})
+	}
+}
+
+// TestHandleToolCall_DryRun tests that dry runs return the resolved route and payload without creating an envelope
+func TestHandleToolCall_DryRun(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		readOnly    bool
+		noConfig    bool // Serve the hardcoded tools
+		canary      map[string][]config.WeightedActor
+		wantIsError bool
+		wantTool    string
+		wantActors  []string
+		wantCurrent int
+		wantPayload string
+	}{
 		{
-			name:        "valid GET - succeeded envelope",
-			method:      http.MethodGet,
-			envelopeID:  "test-env-3",
-			setupEnv:    true,
-			envStatus:   types.EnvelopeStatusSucceeded,
-			wantStatus:  http.StatusOK,
-			checkFields: true,
+			name:        "payload template filled",
+			body:        `{"name":"pipeline","arguments":{"text":"hello"},"dry_run":true}`,
+			wantActors:  []string{"prep", "infer", "post"},
+			wantPayload: `{"input":{"text":"hello"},"mode":"fast"}`,
 		},
 		{
-			name:       "invalid method - POST not allowed",
-			method:     http.MethodPost,
-			envelopeID: "test-env-4",
-			setupEnv:   true,
-			wantStatus: http.StatusMethodNotAllowed,
+			name:        "start step",
+			body:        `{"name":"pipeline","arguments":{"text":"hello"},"start_step":1,"dry_run":true}`,
+			wantActors:  []string{"prep", "infer", "post"},
+			wantCurrent: 1,
+			wantPayload: `{"input":{"text":"hello"},"mode":"fast"}`,
 		},
 		{
-			name:       "invalid method - PUT not allowed",
-			method:     http.MethodPut,
-			envelopeID: "test-env-5",
-			setupEnv:   true,
-			wantStatus: http.StatusMethodNotAllowed,
+			name:        "canary step resolved",
+			body:        `{"name":"pipeline","arguments":{"text":"hello"},"dry_run":true}`,
+			canary:      map[string][]config.WeightedActor{"infer": {{Actor: "infer-v1", Weight: 0}, {Actor: "infer-v2", Weight: 1}}},
+			wantActors:  []string{"prep", "infer-v2", "post"},
+			wantPayload: `{"input":{"text":"hello"},"mode":"fast"}`,
 		},
 		{
-			name:       "invalid method - DELETE not allowed",
-			method:     http.MethodDelete,
-			envelopeID: "test-env-6",
-			setupEnv:   true,
-			wantStatus: http.StatusMethodNotAllowed,
+			name:        "served in read-only mode",
+			body:        `{"name":"pipeline","arguments":{"text":"hello"},"dry_run":true}`,
+			readOnly:    true,
+			wantActors:  []string{"prep", "infer", "post"},
+			wantPayload: `{"input":{"text":"hello"},"mode":"fast"}`,
 		},
 		{
-			name:       "missing envelope ID",
-			method:     http.MethodGet,
-			envelopeID: "",
-			setupEnv:   false,
-			wantStatus: http.StatusBadRequest,
+			name:        "hardcoded tool",
+			body:        `{"name":"processImageWorkflow","arguments":{"description":"cats","route":["generate","score"]},"dry_run":true}`,
+			noConfig:    true,
+			wantTool:    "processImageWorkflow",
+			wantActors:  []string{"generate", "score"},
+			wantPayload: `{"count":5,"description":"cats"}`,
 		},
 		{
-			name:       "envelope not found",
-			method:     http.MethodGet,
-			envelopeID: "nonexistent-envelope",
-			setupEnv:   false,
-			wantStatus: http.StatusNotFound,
+			name:        "missing required parameter",
+			body:        `{"name":"pipeline","arguments":{},"dry_run":true}`,
+			wantIsError: true,
 		},
 	}
 
@@ -540,63 +846,647 @@ func TestHandleEnvelopeStatus(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			store := envelopestore.NewStore()
 			handler := NewHandler(store)
-
-			if tt.setupEnv {
-				env := &types.Envelope{
-					ID: tt.envelopeID,
-					Route: types.Route{
-						Actors:  []string{"actor1", "actor2"},
-						Current: 0,
-					},
-					TotalActors: 2,
-				}
-				if err := store.Create(env); err != nil {
-					t.Fatalf("Failed to create test envelope: %v", err)
-				}
-
-				if tt.envStatus != types.EnvelopeStatusPending {
-					update := types.EnvelopeUpdate{
-						ID:        tt.envelopeID,
-						Status:    tt.envStatus,
-						Timestamp: time.Now(),
-					}
-					if err := store.Update(update); err != nil {
-						t.Fatalf("Failed to update envelope status: %v", err)
-					}
-				}
+			handler.SetReadOnly(tt.readOnly)
+			cfg := &config.Config{
+				Tools: []config.Tool{{
+					Name:            "pipeline",
+					Parameters:      map[string]config.Parameter{"text": {Type: "string", Required: true}},
+					Route:           config.RouteSpec{Actors: []string{"prep", "infer", "post"}},
+					PayloadTemplate: map[string]any{"input": map[string]any{"text": "${text}"}, "mode": "fast"},
+					Exchange:        "team-a",
+				}},
+				Canary: tt.canary,
+			}
+			if tt.noConfig {
+				cfg = nil
 			}
+			handler.SetServer(NewServer(store, &MockQueueClientWithError{}, cfg))
 
-			req := httptest.NewRequest(tt.method, "/envelopes/"+tt.envelopeID, nil)
 			rr := httptest.NewRecorder()
+			handler.HandleToolCall(rr, httptest.NewRequest(http.MethodPost, "/tools/call", strings.NewReader(tt.body)))
 
-			handler.HandleEnvelopeStatus(rr, req)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Status = %d, want %d: %s", rr.Code, http.StatusOK, rr.Body.String())
+			}
 
-			if rr.Code != tt.wantStatus {
-				t.Errorf("HandleEnvelopeStatus() status = %v, want %v", rr.Code, tt.wantStatus)
+			var result struct {
+				IsError bool `json:"isError"`
+				Content []struct {
+					Text string `json:"text"`
+				} `json:"content"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&result); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if result.IsError != tt.wantIsError {
+				t.Fatalf("isError = %v, want %v: %s", result.IsError, tt.wantIsError, result.Content[0].Text)
 			}
 
-			if tt.checkFields && tt.wantStatus == http.StatusOK {
-				var envelope types.Envelope
-				if err := json.NewDecoder(rr.Body).Decode(&envelope); err != nil {
-					t.Fatalf("Failed to decode response: %v", err)
-				}
+			if ids, _ := store.ListActive(envelopestore.EnvelopeFilter{}); len(ids) != 0 {
+				t.Errorf("Dry run created envelopes %v", ids)
+			}
+			if tt.wantIsError {
+				return
+			}
 
-				if envelope.ID != tt.envelopeID {
-					t.Errorf("Envelope ID = %v, want %v", envelope.ID, tt.envelopeID)
-				}
-				if envelope.Status != tt.envStatus {
-					t.Errorf("Envelope status = %v, want %v", envelope.Status, tt.envStatus)
-				}
+			var response struct {
+				DryRun   bool            `json:"dry_run"`
+				Tool     string          `json:"tool"`
+				Exchange string          `json:"exchange"`
+				Route    types.Route     `json:"route"`
+				Payload  json.RawMessage `json:"payload"`
+			}
+			if err := json.Unmarshal([]byte(result.Content[0].Text), &response); err != nil {
+				t.Fatalf("Failed to parse tool response: %v", err)
+			}
+			wantTool := tt.wantTool
+			if wantTool == "" {
+				wantTool = "pipeline"
+			}
+			if !response.DryRun || response.Tool != wantTool {
+				t.Errorf("dry_run = %v, tool = %q, want true, %s", response.DryRun, response.Tool, wantTool)
+			}
+			if strings.Join(response.Route.Actors, ",") != strings.Join(tt.wantActors, ",") || response.Route.Current != tt.wantCurrent {
+				t.Errorf("Route = %+v, want actors %v at %d", response.Route, tt.wantActors, tt.wantCurrent)
+			}
+			if string(response.Payload) != tt.wantPayload {
+				t.Errorf("Payload = %s, want %s", response.Payload, tt.wantPayload)
+			}
+			wantExchange := "team-a"
+			if tt.noConfig {
+				wantExchange = ""
+			}
+			if response.Exchange != wantExchange {
+				t.Errorf("Exchange = %q, want %q", response.Exchange, wantExchange)
 			}
 		})
 	}
 }
 
-// TestHandleEnvelopeActive tests the GET /envelopes/{id}/active endpoint
-func TestHandleEnvelopeActive(t *testing.T) {
+// TestHandleToolCall_EmptyRoute tests that tool calls whose route resolves to no actors get 400
+// and create no envelope
+func TestHandleToolCall_EmptyRoute(t *testing.T) {
 	tests := []struct {
 		name       string
-		method     string
+		body       string
+		noConfig   bool // Serve the hardcoded tools
+		wantStatus int  // 400 when zero
+	}{
+		{name: "config tool without actors", body: `{"name":"no_actors","arguments":{}}`},
+		{name: "config tool with empty template", body: `{"name":"empty_template","arguments":{}}`},
+		{name: "dry run", body: `{"name":"no_actors","arguments":{},"dry_run":true}`},
+		// The hardcoded tool takes the route as an argument and reports it like other invalid arguments
+		{name: "hardcoded tool", body: `{"name":"processImageWorkflow","arguments":{"description":"cats","route":[]}}`, noConfig: true, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := envelopestore.NewStore()
+			handler := NewHandler(store)
+			cfg := &config.Config{
+				Routes: map[string][]string{"empty": {}},
+				Tools: []config.Tool{
+					{Name: "no_actors", Route: config.RouteSpec{Actors: []string{}}},
+					{Name: "empty_template", Route: config.RouteSpec{Template: "empty"}},
+				},
+			}
+			if tt.noConfig {
+				cfg = nil
+			}
+			handler.SetServer(NewServer(store, &MockQueueClientWithError{}, cfg))
+
+			rr := httptest.NewRecorder()
+			handler.HandleToolCall(rr, httptest.NewRequest(http.MethodPost, "/tools/call", strings.NewReader(tt.body)))
+
+			wantStatus := tt.wantStatus
+			if wantStatus == 0 {
+				wantStatus = http.StatusBadRequest
+			}
+			if rr.Code != wantStatus {
+				t.Fatalf("Status = %d, want %d: %s", rr.Code, wantStatus, rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), `"isError":true`) {
+				t.Errorf("Body = %s, want an isError result", rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), "route cannot be empty") {
+				t.Errorf("Body = %s, want route cannot be empty", rr.Body.String())
+			}
+			if ids, _ := store.ListActive(envelopestore.EnvelopeFilter{}); len(ids) != 0 {
+				t.Errorf("Empty route created envelopes %v", ids)
+			}
+		})
+	}
+}
+
+// TestHandleToolCall_PublishSaturated tests that async tool calls beyond the pending publish limit get 503
+func TestHandleToolCall_PublishSaturated(t *testing.T) {
+	store := envelopestore.NewStore()
+	queueClient := &blockingQueueClient{release: make(chan struct{})}
+	server := NewServer(store, queueClient, &config.Config{
+		Tools: []config.Tool{
+			{Name: "pipeline", Route: config.RouteSpec{Actors: []string{"prep"}}},
+		},
+	})
+	server.SetMaxPendingPublishes(1)
+	handler := NewHandler(store)
+	handler.SetServer(server)
+
+	call := func() *httptest.ResponseRecorder {
+		body := []byte(`{"name": "pipeline", "arguments": {"input": "x"}}`)
+		rr := httptest.NewRecorder()
+		handler.HandleToolCall(rr, httptest.NewRequest(http.MethodPost, "/tools/call", bytes.NewReader(body)))
+		return rr
+	}
+
+	if rr := call(); rr.Code != http.StatusOK {
+		t.Fatalf("First call status = %d, want %d: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	// The first envelope is still being published
+	rr := call()
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Saturated call status = %d, want %d: %s", rr.Code, http.StatusServiceUnavailable, rr.Body.String())
+	}
+	if got := rr.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want %q", got, "1")
+	}
+
+	// Once the publish completes, the slot is free again
+	close(queueClient.release)
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if rr = call(); rr.Code == http.StatusOK {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Call after publish status = %d, want %d: %s", rr.Code, http.StatusOK, rr.Body.String())
+}
+
+// TestHandleToolCall_TooManyActive tests that tool calls beyond the active envelope limit get 429
+// until an envelope finishes
+func TestHandleToolCall_TooManyActive(t *testing.T) {
+	base := envelopestore.NewStore()
+	store, err := envelopestore.NewConcurrencyLimiter(base, 1)
+	if err != nil {
+		t.Fatalf("NewConcurrencyLimiter() error = %v", err)
+	}
+	base.SetTransitionObserver(store)
+	server := NewServer(store, nil, &config.Config{
+		Tools: []config.Tool{
+			{Name: "pipeline", Route: config.RouteSpec{Actors: []string{"prep"}}},
+		},
+	})
+	handler := NewHandler(store)
+	handler.SetServer(server)
+
+	call := func() *httptest.ResponseRecorder {
+		body := []byte(`{"name": "pipeline", "arguments": {"input": "x"}}`)
+		rr := httptest.NewRecorder()
+		handler.HandleToolCall(rr, httptest.NewRequest(http.MethodPost, "/tools/call", bytes.NewReader(body)))
+		return rr
+	}
+
+	if rr := call(); rr.Code != http.StatusOK {
+		t.Fatalf("First call status = %d, want %d: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	rr := call()
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Call over the limit status = %d, want %d: %s", rr.Code, http.StatusTooManyRequests, rr.Body.String())
+	}
+	if got := rr.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want %q", got, "1")
+	}
+	if ids, _ := base.ListActive(envelopestore.EnvelopeFilter{}); len(ids) != 1 {
+		t.Fatalf("Active envelopes = %v, want only the first", ids)
+	}
+
+	ids, _ := base.ListActive(envelopestore.EnvelopeFilter{})
+	if err := base.Update(types.EnvelopeUpdate{ID: ids[0], Status: types.EnvelopeStatusSucceeded, Timestamp: time.Now()}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if rr := call(); rr.Code != http.StatusOK {
+		t.Errorf("Call after completion status = %d, want %d: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+}
+
+// TestHandleEnvelopeStatus tests the GET /envelopes/{id} endpoint
+func TestHandleEnvelopeStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		envelopeID  string
+		requestID   string // Path ID when it differs from the stored envelopeID
+		setupEnv    bool
+		envStatus   types.EnvelopeStatus
+		wantStatus  int
+		checkFields bool
+	}{
+		{
+			name:        "valid GET - pending envelope",
+			method:      http.MethodGet,
+			envelopeID:  "test-env-1",
+			setupEnv:    true,
+			envStatus:   types.EnvelopeStatusPending,
+			wantStatus:  http.StatusOK,
+			checkFields: true,
+		},
+		{
+			name:        "valid GET - running envelope",
+			method:      http.MethodGet,
+			envelopeID:  "test-env-2",
+			setupEnv:    true,
+			envStatus:   types.EnvelopeStatusRunning,
+			wantStatus:  http.StatusOK,
+			checkFields: true,
+		},
+		{
+			name:        "valid GET - succeeded envelope",
+			method:      http.MethodGet,
+			envelopeID:  "test-env-3",
+			setupEnv:    true,
+			envStatus:   types.EnvelopeStatusSucceeded,
+			wantStatus:  http.StatusOK,
+			checkFields: true,
+		},
+		{
+			name:       "invalid method - POST not allowed",
+			method:     http.MethodPost,
+			envelopeID: "test-env-4",
+			setupEnv:   true,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "invalid method - PUT not allowed",
+			method:     http.MethodPut,
+			envelopeID: "test-env-5",
+			setupEnv:   true,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "invalid method - DELETE not allowed",
+			method:     http.MethodDelete,
+			envelopeID: "test-env-6",
+			setupEnv:   true,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "missing envelope ID",
+			method:     http.MethodGet,
+			envelopeID: "",
+			setupEnv:   false,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "envelope not found",
+			method:     http.MethodGet,
+			envelopeID: "nonexistent-envelope",
+			setupEnv:   false,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "malformed envelope ID",
+			method:     http.MethodGet,
+			envelopeID: "bad;id",
+			setupEnv:   false,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "uppercase UUID is normalized",
+			method:      http.MethodGet,
+			envelopeID:  "5e6fdb2d-1d6b-4e91-baef-73e825434e7b",
+			requestID:   "5E6FDB2D-1D6B-4E91-BAEF-73E825434E7B",
+			setupEnv:    true,
+			envStatus:   types.EnvelopeStatusPending,
+			wantStatus:  http.StatusOK,
+			checkFields: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := envelopestore.NewStore()
+			handler := NewHandler(store)
+
+			if tt.setupEnv {
+				env := &types.Envelope{
+					ID: tt.envelopeID,
+					Route: types.Route{
+						Actors:  []string{"actor1", "actor2"},
+						Current: 0,
+					},
+					TotalActors: 2,
+				}
+				if err := store.Create(env); err != nil {
+					t.Fatalf("Failed to create test envelope: %v", err)
+				}
+
+				if tt.envStatus != types.EnvelopeStatusPending {
+					update := types.EnvelopeUpdate{
+						ID:        tt.envelopeID,
+						Status:    tt.envStatus,
+						Timestamp: time.Now(),
+					}
+					if err := store.Update(update); err != nil {
+						t.Fatalf("Failed to update envelope status: %v", err)
+					}
+				}
+			}
+
+			requestID := tt.envelopeID
+			if tt.requestID != "" {
+				requestID = tt.requestID
+			}
+			req := httptest.NewRequest(tt.method, "/envelopes/"+requestID, nil)
+			rr := httptest.NewRecorder()
+
+			serveRoutes(handler, rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("HandleEnvelopeStatus() status = %v, want %v", rr.Code, tt.wantStatus)
+			}
+
+			if tt.checkFields && tt.wantStatus == http.StatusOK {
+				var envelope types.Envelope
+				if err := json.NewDecoder(rr.Body).Decode(&envelope); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+
+				if envelope.ID != tt.envelopeID {
+					t.Errorf("Envelope ID = %v, want %v", envelope.ID, tt.envelopeID)
+				}
+				if envelope.Status != tt.envStatus {
+					t.Errorf("Envelope status = %v, want %v", envelope.Status, tt.envStatus)
+				}
+			}
+		})
+	}
+}
+
+// TestHandleEnvelopeResult tests the GET /envelopes/{id}/result endpoint
+func TestHandleEnvelopeResult(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		envelopeID string
+		setupEnv   bool
+		envStatus  types.EnvelopeStatus
+		result     interface{}
+		errMsg     string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "succeeded envelope returns result only",
+			method:     http.MethodGet,
+			envelopeID: "result-env-1",
+			setupEnv:   true,
+			envStatus:  types.EnvelopeStatusSucceeded,
+			result:     map[string]interface{}{"answer": float64(42)},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"answer":42}`,
+		},
+		{
+			name:       "pending envelope returns accepted",
+			method:     http.MethodGet,
+			envelopeID: "result-env-2",
+			setupEnv:   true,
+			envStatus:  types.EnvelopeStatusPending,
+			wantStatus: http.StatusAccepted,
+			wantBody:   `{"id":"result-env-2","status":"pending"}`,
+		},
+		{
+			name:       "running envelope returns accepted",
+			method:     http.MethodGet,
+			envelopeID: "result-env-3",
+			setupEnv:   true,
+			envStatus:  types.EnvelopeStatusRunning,
+			wantStatus: http.StatusAccepted,
+			wantBody:   `{"id":"result-env-3","status":"running"}`,
+		},
+		{
+			name:       "failed envelope returns gone",
+			method:     http.MethodGet,
+			envelopeID: "result-env-4",
+			setupEnv:   true,
+			envStatus:  types.EnvelopeStatusFailed,
+			errMsg:     "actor crashed",
+			wantStatus: http.StatusGone,
+			wantBody:   `{"error":"actor crashed","id":"result-env-4","status":"failed"}`,
+		},
+		{
+			name:       "invalid method - POST not allowed",
+			method:     http.MethodPost,
+			envelopeID: "result-env-5",
+			setupEnv:   true,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "envelope not found",
+			method:     http.MethodGet,
+			envelopeID: "nonexistent-envelope",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := envelopestore.NewStore()
+			handler := NewHandler(store)
+
+			if tt.setupEnv {
+				env := &types.Envelope{
+					ID: tt.envelopeID,
+					Route: types.Route{
+						Actors:  []string{"actor1"},
+						Current: 0,
+					},
+					TotalActors: 1,
+				}
+				if err := store.Create(env); err != nil {
+					t.Fatalf("Failed to create test envelope: %v", err)
+				}
+
+				if tt.envStatus != "" && tt.envStatus != types.EnvelopeStatusPending {
+					update := types.EnvelopeUpdate{
+						ID:        tt.envelopeID,
+						Status:    tt.envStatus,
+						Result:    tt.result,
+						Error:     tt.errMsg,
+						Timestamp: time.Now(),
+					}
+					if err := store.Update(update); err != nil {
+						t.Fatalf("Failed to update envelope status: %v", err)
+					}
+				}
+			}
+
+			req := httptest.NewRequest(tt.method, "/envelopes/"+tt.envelopeID+"/result", nil)
+			rr := httptest.NewRecorder()
+
+			serveRoutes(handler, rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("HandleEnvelopeResult() status = %v, want %v", rr.Code, tt.wantStatus)
+			}
+
+			if tt.wantBody != "" {
+				if got := strings.TrimSpace(rr.Body.String()); got != tt.wantBody {
+					t.Errorf("HandleEnvelopeResult() body = %s, want %s", got, tt.wantBody)
+				}
+			}
+		})
+	}
+}
+
+// memoryObjectStore is an in-memory payloadstore.ObjectStore
+type memoryObjectStore map[string][]byte
+
+func (m memoryObjectStore) Put(ctx context.Context, key string, data []byte) (string, error) {
+	m["mem://"+key] = data
+	return "mem://" + key, nil
+}
+
+func (m memoryObjectStore) Open(ctx context.Context, url string) (io.ReadCloser, error) {
+	data, ok := m[url]
+	if !ok {
+		return nil, payloadstore.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m memoryObjectStore) Delete(ctx context.Context, url string) error {
+	delete(m, url)
+	return nil
+}
+
+// TestHandleEnvelopeResult_Offloaded tests that results offloaded to the object store are streamed from it
+func TestHandleEnvelopeResult_Offloaded(t *testing.T) {
+	tests := []struct {
+		name       string
+		resultURL  string
+		objects    memoryObjectStore
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "offloaded result is streamed",
+			resultURL:  "mem://results/offloaded-env.json",
+			objects:    memoryObjectStore{"mem://results/offloaded-env.json": []byte(`{"answer":42}`)},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"answer":42}`,
+		},
+		{
+			name:       "missing object",
+			resultURL:  "mem://results/offloaded-env.json",
+			objects:    memoryObjectStore{},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "no result store configured",
+			resultURL:  "mem://results/offloaded-env.json",
+			wantStatus: http.StatusBadGateway,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := envelopestore.NewStore()
+			handler := NewHandler(store)
+			if tt.objects != nil {
+				handler.SetResultStore(tt.objects)
+			}
+
+			if err := store.Create(&types.Envelope{ID: "offloaded-env", Route: types.Route{Actors: []string{"actor1"}}}); err != nil {
+				t.Fatalf("Failed to create test envelope: %v", err)
+			}
+			if err := store.Update(types.EnvelopeUpdate{
+				ID:        "offloaded-env",
+				Status:    types.EnvelopeStatusSucceeded,
+				ResultURL: tt.resultURL,
+				Timestamp: time.Now(),
+			}); err != nil {
+				t.Fatalf("Failed to update envelope status: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/envelopes/offloaded-env/result", nil)
+			rr := httptest.NewRecorder()
+
+			serveRoutes(handler, rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("HandleEnvelopeResult() status = %v, want %v", rr.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" {
+				if got := strings.TrimSpace(rr.Body.String()); got != tt.wantBody {
+					t.Errorf("HandleEnvelopeResult() body = %s, want %s", got, tt.wantBody)
+				}
+			}
+		})
+	}
+}
+
+// slowObjectStore serves objects that take a while to read
+type slowObjectStore struct {
+	memoryObjectStore
+	delay time.Duration
+}
+
+func (s slowObjectStore) Open(ctx context.Context, url string) (io.ReadCloser, error) {
+	time.Sleep(s.delay)
+	return s.memoryObjectStore.Open(ctx, url)
+}
+
+// TestHandleEnvelopeResult_OffloadedOutlivesWriteTimeout tests that streaming an offloaded
+// result is not cut off by the server's WriteTimeout
+func TestHandleEnvelopeResult_OffloadedOutlivesWriteTimeout(t *testing.T) {
+	store := envelopestore.NewStore()
+	handler := NewHandler(store)
+	handler.SetResultStore(slowObjectStore{
+		memoryObjectStore: memoryObjectStore{"mem://results/slow-env.json": []byte(`{"answer":42}`)},
+		delay:             200 * time.Millisecond,
+	})
+
+	if err := store.Create(&types.Envelope{ID: "slow-env", Route: types.Route{Actors: []string{"actor1"}}}); err != nil {
+		t.Fatalf("Failed to create test envelope: %v", err)
+	}
+	if err := store.Update(types.EnvelopeUpdate{
+		ID:        "slow-env",
+		Status:    types.EnvelopeStatusSucceeded,
+		ResultURL: "mem://results/slow-env.json",
+		Timestamp: time.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to update envelope status: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	server := httptest.NewUnstartedServer(mux)
+	server.Config.WriteTimeout = 50 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/envelopes/slow-env/result")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read body: %v", err)
+	}
+	if got := strings.TrimSpace(string(body)); resp.StatusCode != http.StatusOK || got != `{"answer":42}` {
+		t.Errorf("GET result = %d %s, want 200 with the offloaded result", resp.StatusCode, got)
+	}
+}
+
+// TestHandleEnvelopeActive tests the GET /envelopes/{id}/active endpoint
+func TestHandleEnvelopeActive(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
 		envelopeID string
 		setupEnv   bool
 		envStatus  types.EnvelopeStatus
@@ -647,13 +1537,6 @@ func TestHandleEnvelopeActive(t *testing.T) {
 			envStatus:  types.EnvelopeStatusPending,
 			wantStatus: http.StatusMethodNotAllowed,
 		},
-		{
-			name:       "empty envelope ID path",
-			method:     http.MethodGet,
-			envelopeID: "",
-			setupEnv:   false,
-			wantStatus: http.StatusBadRequest,
-		},
 		{
 			name:       "envelope not found - inactive",
 			method:     http.MethodGet,
@@ -701,7 +1584,7 @@ func TestHandleEnvelopeActive(t *testing.T) {
 			}
 			rr := httptest.NewRecorder()
 
-			handler.HandleEnvelopeActive(rr, req)
+			serveRoutes(handler, rr, req)
 
 			if rr.Code != tt.wantStatus {
 				t.Errorf("HandleEnvelopeActive() status = %v, want %v", rr.Code, tt.wantStatus)
@@ -810,13 +1693,6 @@ func TestHandleEnvelopeFinal(t *testing.T) {
 			},
 			wantStatus: http.StatusMethodNotAllowed,
 		},
-		{
-			name:       "missing envelope ID",
-			method:     http.MethodPost,
-			envelopeID: "",
-			setupEnv:   false,
-			wantStatus: http.StatusBadRequest,
-		},
 		{
 			name:        "invalid JSON body",
 			method:      http.MethodPost,
@@ -891,7 +1767,7 @@ func TestHandleEnvelopeFinal(t *testing.T) {
 			req.Header.Set("Content-Type", "application/json")
 
 			rr := httptest.NewRecorder()
-			handler.HandleEnvelopeFinal(rr, req)
+			serveRoutes(handler, rr, req)
 
 			if rr.Code != tt.wantStatus {
 				t.Errorf("HandleEnvelopeFinal() status = %v, want %v, body = %s", rr.Code, tt.wantStatus, rr.Body.String())
@@ -927,230 +1803,423 @@ func TestHandleEnvelopeFinal(t *testing.T) {
 	}
 }
 
-func TestEnvelopePathRegex(t *testing.T) {
+func TestRegisterRoutes(t *testing.T) {
 	tests := []struct {
 		name        string
 		path        string
-		regex       string
-		wantMatch   bool
+		wantPattern string
 		wantID      string
-		description string
+	}{
+		{name: "envelope status path", path: "/envelopes/abc-123", wantPattern: "/envelopes/{id}", wantID: "abc-123"},
+		{name: "envelope stream path", path: "/envelopes/test-id-456/stream", wantPattern: "/envelopes/{id}/stream", wantID: "test-id-456"},
+		{name: "envelope active path", path: "/envelopes/uuid-789/active", wantPattern: "/envelopes/{id}/active", wantID: "uuid-789"},
+		{name: "envelope progress path", path: "/envelopes/env-001/progress", wantPattern: "/envelopes/{id}/progress", wantID: "env-001"},
+		{name: "envelope final path", path: "/envelopes/final-test/final", wantPattern: "/envelopes/{id}/final", wantID: "final-test"},
+		{name: "envelope result path", path: "/envelopes/result-test/result", wantPattern: "/envelopes/{id}/result", wantID: "result-test"},
+		{name: "UUID format envelope ID", path: "/envelopes/550e8400-e29b-41d4-a716-446655440000", wantPattern: "/envelopes/{id}", wantID: "550e8400-e29b-41d4-a716-446655440000"},
+		{name: "hyphens and underscores", path: "/envelopes/test_id-123_abc/progress", wantPattern: "/envelopes/{id}/progress", wantID: "test_id-123_abc"},
+		{name: "numeric only ID", path: "/envelopes/123456/final", wantPattern: "/envelopes/{id}/final", wantID: "123456"},
+		{name: "envelope creation", path: "/envelopes", wantPattern: "/envelopes"},
+		{name: "batch takes precedence over envelope ID", path: "/envelopes/batch", wantPattern: "/envelopes/batch"},
+		{name: "progress batch takes precedence over envelope ID", path: "/envelopes/progress/batch", wantPattern: "/envelopes/progress/batch"},
+		{name: "tool call", path: "/tools/call", wantPattern: "/tools/call"},
+		{name: "missing envelope ID", path: "/envelopes/"},
+		{name: "wrong suffix", path: "/envelopes/test-id/wrong"},
+		{name: "extra path segments", path: "/envelopes/test-id/stream/extra"},
+		{name: "envelope ID with slashes", path: "/envelopes/id/with/slashes/stream"},
+		{name: "status path with trailing slash", path: "/envelopes/test-id/"},
+	}
+
+	mux := http.NewServeMux()
+	NewHandler(envelopestore.NewStore()).RegisterRoutes(mux)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+
+			_, pattern := mux.Handler(req)
+			if pattern != tt.wantPattern {
+				t.Errorf("Path %q matched pattern %q, want %q", tt.path, pattern, tt.wantPattern)
+			}
+
+			if tt.wantID == "" {
+				return
+			}
+
+			// Resolve {id} the same way ServeMux does when serving the request
+			probe := http.NewServeMux()
+			var gotID string
+			probe.HandleFunc(tt.wantPattern, func(w http.ResponseWriter, r *http.Request) {
+				gotID = r.PathValue("id")
+			})
+			probe.ServeHTTP(httptest.NewRecorder(), req)
+			if gotID != tt.wantID {
+				t.Errorf("Expected envelope ID %q, got %q", tt.wantID, gotID)
+			}
+		})
+	}
+}
+
+func TestRegisterRoutes_EdgeCases(t *testing.T) {
+	store := envelopestore.NewStore()
+	mux := http.NewServeMux()
+	NewHandler(store).RegisterRoutes(mux)
+
+	tests := []struct {
+		name         string
+		path         string
+		method       string
+		wantStatus   int
+		wantLocation string // Redirect target, status may be 301 or 307 depending on Go version
+		description  string
 	}{
 		{
-			name:        "valid envelope status path",
-			path:        "/envelopes/abc-123",
-			regex:       "status",
-			wantMatch:   true,
-			wantID:      "abc-123",
-			description: "Should match /envelopes/{id}",
-		},
-		{
-			name:        "valid envelope stream path",
-			path:        "/envelopes/test-id-456/stream",
-			regex:       "stream",
-			wantMatch:   true,
-			wantID:      "test-id-456",
-			description: "Should match /envelopes/{id}/stream",
-		},
-		{
-			name:        "valid envelope active path",
-			path:        "/envelopes/uuid-789/active",
-			regex:       "active",
-			wantMatch:   true,
-			wantID:      "uuid-789",
-			description: "Should match /envelopes/{id}/active",
-		},
-		{
-			name:        "valid envelope progress path",
-			path:        "/envelopes/env-001/progress",
-			regex:       "progress",
-			wantMatch:   true,
-			wantID:      "env-001",
-			description: "Should match /envelopes/{id}/progress",
-		},
-		{
-			name:        "valid envelope final path",
-			path:        "/envelopes/final-test/final",
-			regex:       "final",
-			wantMatch:   true,
-			wantID:      "final-test",
-			description: "Should match /envelopes/{id}/final",
-		},
-		{
-			name:        "UUID format envelope ID",
-			path:        "/envelopes/550e8400-e29b-41d4-a716-446655440000",
-			regex:       "status",
-			wantMatch:   true,
-			wantID:      "550e8400-e29b-41d4-a716-446655440000",
-			description: "Should match UUID format IDs",
-		},
-		{
-			name:        "empty envelope ID",
-			path:        "/envelopes//stream",
-			regex:       "stream",
-			wantMatch:   false,
-			description: "Should reject empty envelope ID",
-		},
-		{
-			name:        "missing envelope ID",
-			path:        "/envelopes/",
-			regex:       "status",
-			wantMatch:   false,
-			description: "Should reject missing envelope ID",
-		},
-		{
-			name:        "wrong suffix",
-			path:        "/envelopes/test-id/wrong",
-			regex:       "stream",
-			wantMatch:   false,
-			description: "Should reject wrong suffix",
+			name:         "double slashes in path",
+			path:         "/envelopes//active",
+			method:       http.MethodGet,
+			wantLocation: "/envelopes/active",
+			description:  "ServeMux should redirect unclean paths instead of calling the handler",
 		},
 		{
-			name:        "extra path segments",
-			path:        "/envelopes/test-id/stream/extra",
-			regex:       "stream",
-			wantMatch:   false,
-			description: "Should reject extra path segments",
+			name:         "empty envelope ID before progress",
+			path:         "/envelopes//progress",
+			method:       http.MethodPost,
+			wantLocation: "/envelopes/progress",
+			description:  "ServeMux should redirect unclean paths instead of calling the handler",
 		},
 		{
-			name:        "envelope ID with slashes",
-			path:        "/envelopes/id/with/slashes/stream",
-			regex:       "stream",
-			wantMatch:   false,
-			description: "Should reject envelope ID containing slashes",
+			name:         "empty envelope ID before final",
+			path:         "/envelopes//final",
+			method:       http.MethodPost,
+			wantLocation: "/envelopes/final",
+			description:  "ServeMux should redirect unclean paths instead of calling the handler",
 		},
 		{
-			name:        "status path with trailing slash",
-			path:        "/envelopes/test-id/",
-			regex:       "status",
-			wantMatch:   false,
-			description: "Should reject trailing slash",
+			name:        "malformed path missing prefix",
+			path:        "/wrong/test-id/stream",
+			method:      http.MethodGet,
+			wantStatus:  http.StatusNotFound,
+			description: "ServeMux should reject wrong prefix",
 		},
 		{
-			name:        "stream path without trailing slash",
-			path:        "/envelopes/test-id/stream",
-			regex:       "stream",
-			wantMatch:   true,
-			wantID:      "test-id",
-			description: "Should match stream path without trailing slash",
+			name:        "path with query parameters",
+			path:        "/envelopes/test-id?foo=bar",
+			method:      http.MethodGet,
+			wantStatus:  http.StatusNotFound,
+			description: "Query parameters are not part of the ID, envelope not found",
 		},
 		{
-			name:        "alphanumeric with hyphens and underscores",
-			path:        "/envelopes/test_id-123_abc/progress",
-			regex:       "progress",
-			wantMatch:   true,
-			wantID:      "test_id-123_abc",
-			description: "Should match IDs with hyphens and underscores",
+			name:        "extremely long envelope ID",
+			path:        "/envelopes/" + strings.Repeat("a", 1000) + "/progress",
+			method:      http.MethodPost,
+			wantStatus:  http.StatusBadRequest,
+			description: "Should handle extremely long IDs gracefully",
 		},
 		{
-			name:        "numeric only ID",
-			path:        "/envelopes/123456/final",
-			regex:       "final",
-			wantMatch:   true,
-			wantID:      "123456",
-			description: "Should match numeric only IDs",
+			name:        "unsupported method",
+			path:        "/envelopes/test-id/active",
+			method:      http.MethodDelete,
+			wantStatus:  http.StatusMethodNotAllowed,
+			description: "Handlers should reject unsupported methods",
 		},
 	}
 
-	regexMap := map[string]*regexp.Regexp{
-		"status":   envelopePathRegex,
-		"stream":   envelopeStreamPathRegex,
-		"active":   envelopeActivePathRegex,
-		"progress": envelopeProgressPathRegex,
-		"final":    envelopeFinalPathRegex,
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rr := httptest.NewRecorder()
+
+			mux.ServeHTTP(rr, req)
+
+			if tt.wantLocation != "" {
+				if rr.Code < 300 || rr.Code >= 400 || rr.Header().Get("Location") != tt.wantLocation {
+					t.Errorf("%s: got status %d location %q, want redirect to %q", tt.description, rr.Code, rr.Header().Get("Location"), tt.wantLocation)
+				}
+				return
+			}
+			if rr.Code != tt.wantStatus {
+				t.Errorf("%s: got status %d, want %d", tt.description, rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestEnvelopeIDFromPath_Encoded(t *testing.T) {
+	store := envelopestore.NewStore()
+	for _, id := range []string{"abc-123", "active"} {
+		if err := store.Create(&types.Envelope{ID: id, Route: types.Route{Actors: []string{"actor1"}}}); err != nil {
+			t.Fatalf("Failed to create envelope %s: %v", id, err)
+		}
+	}
+	handler := NewHandler(store)
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantBody   string // Substring identifying the endpoint that answered
+	}{
+		{name: "encoded hyphen is decoded", path: "/envelopes/abc%2D123", wantStatus: http.StatusOK, wantBody: `"id":"abc-123"`},
+		{name: "encoded hyphen before suffix", path: "/envelopes/abc%2D123/active", wantStatus: http.StatusOK, wantBody: `"active":true`},
+		{name: "encoded slash is rejected", path: "/envelopes/abc%2F123", wantStatus: http.StatusBadRequest, wantBody: "Invalid envelope ID"},
+		{name: "encoded space is rejected", path: "/envelopes/abc%20123", wantStatus: http.StatusBadRequest, wantBody: "Invalid envelope ID"},
+		{name: "encoded query separator is rejected", path: "/envelopes/abc%3F123", wantStatus: http.StatusBadRequest, wantBody: "Invalid envelope ID"},
+		{name: "encoded NUL is rejected", path: "/envelopes/abc%00", wantStatus: http.StatusBadRequest, wantBody: "Invalid envelope ID"},
+		{name: "ID ending in encoded /active is not the active endpoint", path: "/envelopes/abc-123%2Factive", wantStatus: http.StatusBadRequest, wantBody: "Invalid envelope ID"},
+		{name: "ID ending in encoded /stream is not the stream endpoint", path: "/envelopes/abc-123%2Fstream", wantStatus: http.StatusBadRequest, wantBody: "Invalid envelope ID"},
+		{name: "ID ending in encoded /active on active endpoint", path: "/envelopes/abc-123%2Factive/active", wantStatus: http.StatusBadRequest, wantBody: "Invalid envelope ID"},
+		{name: "ID equal to suffix name is a status request", path: "/envelopes/active", wantStatus: http.StatusOK, wantBody: `"id":"active"`},
+		{name: "active endpoint of ID equal to suffix name", path: "/envelopes/active/active", wantStatus: http.StatusOK, wantBody: `"active":true`},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			pattern := regexMap[tt.regex]
-			if pattern == nil {
-				t.Fatalf("Unknown regex type: %s", tt.regex)
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rr := httptest.NewRecorder()
+
+			serveRoutes(handler, rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status = %v, want %v, body = %s", rr.Code, tt.wantStatus, rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", rr.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestRegisterRoutes_JobIDLogging(t *testing.T) {
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(logging.NewHandler(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+	defer slog.SetDefault(defaultLogger)
+
+	store := envelopestore.NewStore()
+	if err := store.Create(&types.Envelope{ID: "abc-123", Route: types.Route{Actors: []string{"actor1", "actor2"}}}); err != nil {
+		t.Fatalf("Failed to create envelope: %v", err)
+	}
+	handler := NewHandler(store)
+
+	body := `{"actors":["actor1","actor2"],"current_actor_idx":0,"status":"processing"}`
+	req := httptest.NewRequest(http.MethodPost, "/envelopes/abc-123/progress", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	serveRoutes(handler, rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %v, want %v, body = %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) == 0 || lines[0] == "" {
+		t.Fatal("Expected progress update to be logged")
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, "job_id=abc-123") {
+			t.Errorf("log line %q should carry job_id=abc-123", line)
+		}
+	}
+}
+
+// TestEnvelopeLogs_JobID tests that logs written outside an envelope's request (background
+// publish, fanout aggregation, bulk cancel) carry the job ID of the envelope they are about
+func TestEnvelopeLogs_JobID(t *testing.T) {
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(logging.NewHandler(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+	defer slog.SetDefault(defaultLogger)
+
+	logLine := func(t *testing.T, msg string) string {
+		t.Helper()
+		for _, line := range strings.Split(logs.String(), "\n") {
+			if strings.Contains(line, msg) {
+				return line
+			}
+		}
+		t.Fatalf("no log line %q in %q", msg, logs.String())
+		return ""
+	}
+
+	store := envelopestore.NewStore()
+	for _, envelope := range []*types.Envelope{
+		{ID: "publish-1", Route: types.Route{Actors: []string{"a"}}},
+		{ID: "parent-1", Route: types.Route{Actors: []string{"a"}}, FanoutBranches: 1},
+	} {
+		if err := store.Create(envelope); err != nil {
+			t.Fatalf("Failed to create envelope: %v", err)
+		}
+	}
+	handler := NewHandler(store)
+
+	t.Run("failed publish", func(t *testing.T) {
+		envelope, _ := store.Get("publish-1")
+		enqueueEnvelopes(context.Background(), store, &MockQueueClientWithError{sendErr: fmt.Errorf("broker down")}, []*types.Envelope{envelope})
+		if line := logLine(t, "Failed to send envelope to queue"); !strings.Contains(line, "job_id=publish-1") {
+			t.Errorf("log line %q should carry job_id=publish-1", line)
+		}
+	})
+
+	t.Run("fanout aggregation", func(t *testing.T) {
+		// Aggregation is triggered by a branch's final status but logs about the parent
+		ctx := logging.ContextWithJobID(context.Background(), "child-1")
+		if err := handler.finalizeFanout(ctx, "parent-1"); err != nil {
+			t.Fatalf("finalizeFanout() error = %v", err)
+		}
+		if line := logLine(t, "Fanout results aggregated"); !strings.Contains(line, "job_id=parent-1") {
+			t.Errorf("log line %q should carry job_id=parent-1", line)
+		}
+	})
+
+	t.Run("cancel failure", func(t *testing.T) {
+		mockStore := NewMockJobStore()
+		if err := mockStore.Create(&types.Envelope{ID: "cancel-1", Status: types.EnvelopeStatusQueued}); err != nil {
+			t.Fatalf("Failed to create envelope: %v", err)
+		}
+		mockStore.updateErr = fmt.Errorf("store down")
+
+		rr := httptest.NewRecorder()
+		NewHandler(mockStore).HandleEnvelopesCancel(rr, httptest.NewRequest(http.MethodPost, "/admin/envelopes/cancel", strings.NewReader(`{"status":["queued"]}`)))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		if line := logLine(t, "Failed to cancel envelope"); !strings.Contains(line, "job_id=cancel-1") {
+			t.Errorf("log line %q should carry job_id=cancel-1", line)
+		}
+	})
+}
+
+func TestRegisterRoutes_BasePath(t *testing.T) {
+	store := envelopestore.NewStore()
+	if err := store.Create(&types.Envelope{ID: "abc-123", Route: types.Route{Actors: []string{"actor1"}}}); err != nil {
+		t.Fatalf("Failed to create envelope: %v", err)
+	}
+
+	// Mounted the same way as the gateway with ASYA_BASE_PATH=/asya
+	mux := http.NewServeMux()
+	NewHandler(store).RegisterRoutes(mux)
+	root := http.NewServeMux()
+	root.Handle("/asya/", http.StripPrefix("/asya", mux))
+
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{path: "/asya/envelopes/abc-123", wantStatus: http.StatusOK},
+		{path: "/asya/envelopes/abc-123/active", wantStatus: http.StatusOK},
+		{path: "/asya/envelopes/missing", wantStatus: http.StatusNotFound},
+		{path: "/envelopes/abc-123", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			root.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status = %v, want %v, body = %s", rr.Code, tt.wantStatus, rr.Body.String())
 			}
+		})
+	}
+}
+
+func TestRegisterRoutes_Compression(t *testing.T) {
+	store := envelopestore.NewStore()
+	if err := store.Create(&types.Envelope{ID: "abc-123", Route: types.Route{Actors: []string{"actor1"}}}); err != nil {
+		t.Fatalf("Failed to create envelope: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		compress bool
+		path     string
+		wantGzip bool
+	}{
+		{name: "status compressed", compress: true, path: "/envelopes/abc-123", wantGzip: true},
+		{name: "result compressed", compress: true, path: "/envelopes/abc-123/result", wantGzip: true},
+		{name: "active not compressed", compress: true, path: "/envelopes/abc-123/active"},
+		{name: "compression disabled", path: "/envelopes/abc-123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(store)
+			handler.SetCompression(tt.compress)
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rr := httptest.NewRecorder()
 
-			matches := pattern.FindStringSubmatch(tt.path)
+			serveRoutes(handler, rr, req)
 
-			if tt.wantMatch {
-				if matches == nil {
-					t.Errorf("Expected path %q to match regex, but it didn't. %s", tt.path, tt.description)
-					return
-				}
-				if len(matches) < 2 {
-					t.Errorf("Expected regex to capture envelope ID, but got %d matches", len(matches))
-					return
-				}
-				gotID := matches[1]
-				if gotID != tt.wantID {
-					t.Errorf("Expected envelope ID %q, got %q", tt.wantID, gotID)
-				}
-			} else {
-				if matches != nil {
-					t.Errorf("Expected path %q to NOT match regex, but it did. %s. Captured ID: %q", tt.path, tt.description, matches[1])
-				}
+			if gotGzip := rr.Header().Get("Content-Encoding") == "gzip"; gotGzip != tt.wantGzip {
+				t.Errorf("gzip encoded = %v, want %v", gotGzip, tt.wantGzip)
 			}
 		})
 	}
 }
 
-func TestEnvelopePathRegex_EdgeCases(t *testing.T) {
+func TestHandler_MaxBodyBytes(t *testing.T) {
 	store := envelopestore.NewStore()
+	if err := store.Create(&types.Envelope{ID: "abc-123", Route: types.Route{Actors: []string{"actor1"}}}); err != nil {
+		t.Fatalf("Failed to create envelope: %v", err)
+	}
 	handler := NewHandler(store)
+	handler.SetMaxBodyBytes(64)
+
+	oversize := `{"padding":"` + strings.Repeat("x", 128) + `"}`
 
 	tests := []struct {
-		name        string
-		path        string
-		method      string
-		handlerFunc func(http.ResponseWriter, *http.Request)
-		wantStatus  int
-		description string
+		name       string
+		path       string
+		body       string
+		wantStatus int
 	}{
-		{
-			name:        "double slashes in path",
-			path:        "/envelopes//active",
-			method:      http.MethodGet,
-			handlerFunc: handler.HandleEnvelopeActive,
-			wantStatus:  http.StatusBadRequest,
-			description: "Regex should reject double slashes",
-		},
-		{
-			name:        "malformed path missing prefix",
-			path:        "/wrong/test-id/stream",
-			method:      http.MethodGet,
-			handlerFunc: handler.HandleEnvelopeStream,
-			wantStatus:  http.StatusBadRequest,
-			description: "Regex should reject wrong prefix",
-		},
-		{
-			name:        "path with query parameters",
-			path:        "/envelopes/test-id?foo=bar",
-			method:      http.MethodGet,
-			handlerFunc: handler.HandleEnvelopeStatus,
-			wantStatus:  http.StatusNotFound,
-			description: "Query parameters are stripped by URL.Path, envelope not found",
-		},
-		{
-			name:        "extremely long envelope ID",
-			path:        "/envelopes/" + strings.Repeat("a", 1000) + "/progress",
-			method:      http.MethodPost,
-			handlerFunc: handler.HandleEnvelopeProgress,
-			wantStatus:  http.StatusBadRequest,
-			description: "Should handle extremely long IDs gracefully",
-		},
+		{name: "tool call", path: "/tools/call", body: oversize, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "envelope create", path: "/envelopes", body: oversize, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "batch", path: "/envelopes/batch", body: "[" + oversize + "]", wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "progress", path: "/envelopes/abc-123/progress", body: oversize, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "final", path: "/envelopes/abc-123/final", body: oversize, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "progress within limit", path: "/envelopes/abc-123/progress", body: `{"status":"received"}`, wantStatus: http.StatusOK},
+		{name: "malformed within limit", path: "/envelopes/abc-123/final", body: `{"status":`, wantStatus: http.StatusBadRequest},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
 			rr := httptest.NewRecorder()
 
-			tt.handlerFunc(rr, req)
+			serveRoutes(handler, rr, req)
 
 			if rr.Code != tt.wantStatus {
-				t.Errorf("%s: got status %d, want %d", tt.description, rr.Code, tt.wantStatus)
+				t.Errorf("status = %v, want %v, body = %s", rr.Code, tt.wantStatus, rr.Body.String())
 			}
 		})
 	}
 }
 
+func TestHandleEnvelopeCreate_InheritsParentTool(t *testing.T) {
+	store := envelopestore.NewStore()
+	parent := &types.Envelope{ID: "tool-parent", Tool: "summarize", Route: types.Route{Actors: []string{"splitter", "worker"}}}
+	if err := store.Create(parent); err != nil {
+		t.Fatalf("Failed to create parent: %v", err)
+	}
+	handler := NewHandler(store)
+
+	body := `{"id":"tool-parent-1","parent_id":"tool-parent","branch_index":1,"actors":["splitter","worker"],"current":1}`
+	rr := httptest.NewRecorder()
+	handler.HandleEnvelopeCreate(rr, httptest.NewRequest(http.MethodPost, "/envelopes", strings.NewReader(body)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("status = %v, want %v, body = %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+
+	child, err := store.Get("tool-parent-1")
+	if err != nil {
+		t.Fatalf("Failed to get child: %v", err)
+	}
+	if child.Tool != "summarize" {
+		t.Errorf("child tool = %q, want %q", child.Tool, "summarize")
+	}
+}
+
 func TestHandleEnvelopeCreate(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -1163,10 +2232,11 @@ func TestHandleEnvelopeCreate(t *testing.T) {
 			name:   "valid fanout envelope creation",
 			method: http.MethodPost,
 			requestBody: map[string]interface{}{
-				"id":        "abc-123-1",
-				"parent_id": "abc-123",
-				"actors":    []string{"actor1", "actor2"},
-				"current":   1,
+				"id":           "abc-123-1",
+				"parent_id":    "abc-123",
+				"branch_index": 1,
+				"actors":       []string{"actor1", "actor2"},
+				"current":      1,
 			},
 			wantStatus:   http.StatusCreated,
 			wantEnvelope: true,
@@ -1182,6 +2252,30 @@ func TestHandleEnvelopeCreate(t *testing.T) {
 			wantStatus:   http.StatusBadRequest,
 			wantEnvelope: false,
 		},
+		{
+			name:   "malformed id",
+			method: http.MethodPost,
+			requestBody: map[string]interface{}{
+				"id":        "abc/../123",
+				"parent_id": "abc-123",
+				"actors":    []string{"actor1"},
+				"current":   1,
+			},
+			wantStatus:   http.StatusBadRequest,
+			wantEnvelope: false,
+		},
+		{
+			name:   "malformed parent_id",
+			method: http.MethodPost,
+			requestBody: map[string]interface{}{
+				"id":        "abc-123-1",
+				"parent_id": "abc 123",
+				"actors":    []string{"actor1"},
+				"current":   1,
+			},
+			wantStatus:   http.StatusBadRequest,
+			wantEnvelope: false,
+		},
 		{
 			name:         "invalid json",
 			method:       http.MethodPost,
@@ -1248,6 +2342,9 @@ func TestHandleEnvelopeCreate(t *testing.T) {
 					if envelope.ParentID == nil || *envelope.ParentID != parentID {
 						t.Errorf("Envelope ParentID = %v, want %v", envelope.ParentID, parentID)
 					}
+					if branchIndex := tt.requestBody["branch_index"].(int); envelope.BranchIndex != branchIndex {
+						t.Errorf("Envelope BranchIndex = %v, want %v", envelope.BranchIndex, branchIndex)
+					}
 					if envelope.Status != types.EnvelopeStatusPending {
 						t.Errorf("Envelope Status = %v, want Pending", envelope.Status)
 					}
@@ -1291,3 +2388,415 @@ func TestHandleEnvelopeCreate_DuplicateID(t *testing.T) {
 		t.Error("Should not allow duplicate envelope ID")
 	}
 }
+
+// TestHandler_ReadOnly tests that read-only mode rejects mutations but still serves status
+func TestHandler_ReadOnly(t *testing.T) {
+	store := envelopestore.NewStore()
+	envelope := &types.Envelope{
+		ID:    "read-only-1",
+		Route: types.Route{Actors: []string{"actor1"}, Current: 0},
+	}
+	if err := store.Create(envelope); err != nil {
+		t.Fatalf("Failed to create envelope: %v", err)
+	}
+
+	handler := NewHandler(store)
+	handler.SetReadOnly(true)
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{
+			name:       "tool call rejected",
+			method:     http.MethodPost,
+			path:       "/tools/call",
+			body:       `{"name":"test_tool","arguments":{}}`,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "envelope create rejected",
+			method:     http.MethodPost,
+			path:       "/envelopes",
+			body:       `{"id":"read-only-2","actors":["actor1"]}`,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "progress rejected",
+			method:     http.MethodPost,
+			path:       "/envelopes/read-only-1/progress",
+			body:       `{"actors":["actor1"],"current_actor_idx":0,"status":"received"}`,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "progress batch rejected",
+			method:     http.MethodPost,
+			path:       "/envelopes/progress/batch",
+			body:       `[{"id":"read-only-1","actors":["actor1"],"current_actor_idx":0,"status":"received"}]`,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "final rejected",
+			method:     http.MethodPost,
+			path:       "/envelopes/read-only-1/final",
+			body:       `{"status":"succeeded"}`,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "status served",
+			method:     http.MethodGet,
+			path:       "/envelopes/read-only-1",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "active served",
+			method:     http.MethodGet,
+			path:       "/envelopes/read-only-1/active",
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+
+			mux.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status = %v, want %v, body = %s", rr.Code, tt.wantStatus, rr.Body.String())
+			}
+		})
+	}
+
+	// Mutations must not reach the store
+	got, err := store.Get("read-only-1")
+	if err != nil {
+		t.Fatalf("Failed to get envelope: %v", err)
+	}
+	if got.Status != types.EnvelopeStatusPending {
+		t.Errorf("Envelope status = %v, want %v", got.Status, types.EnvelopeStatusPending)
+	}
+	if _, err := store.Get("read-only-2"); err == nil {
+		t.Error("Envelope should not be created in read-only mode")
+	}
+}
+
+// TestHandleEnvelopeFinal_FanoutAggregation tests that fanout branch results are aggregated on the parent
+func TestHandleEnvelopeFinal_FanoutAggregation(t *testing.T) {
+	tests := []struct {
+		name        string
+		childStatus string
+		wantStatus  types.EnvelopeStatus
+	}{
+		{name: "all branches succeed", childStatus: "succeeded", wantStatus: types.EnvelopeStatusSucceeded},
+		{name: "one branch fails", childStatus: "failed", wantStatus: types.EnvelopeStatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := envelopestore.NewStore()
+			handler := NewHandler(store)
+
+			parent := &types.Envelope{
+				ID:    "fanout-parent",
+				Route: types.Route{Actors: []string{"splitter", "worker"}, Current: 0},
+			}
+			if err := store.Create(parent); err != nil {
+				t.Fatalf("Failed to create parent: %v", err)
+			}
+
+			for i, childID := range []string{"fanout-parent-1", "fanout-parent-2"} {
+				body := fmt.Sprintf(`{"id":%q,"parent_id":"fanout-parent","branch_index":%d,"actors":["splitter","worker"],"current":1}`, childID, i+1)
+				rr := httptest.NewRecorder()
+				handler.HandleEnvelopeCreate(rr, httptest.NewRequest(http.MethodPost, "/envelopes", strings.NewReader(body)))
+				if rr.Code != http.StatusCreated {
+					t.Fatalf("Failed to create child %s: %d %s", childID, rr.Code, rr.Body.String())
+				}
+			}
+
+			postFinal := func(id, status string, result any) {
+				final := map[string]any{"id": id, "status": status, "result": result}
+				if status == "failed" {
+					final["error"] = "branch failed"
+				}
+				body, _ := json.Marshal(final)
+				rr := httptest.NewRecorder()
+				serveRoutes(handler, rr, httptest.NewRequest(http.MethodPost, "/envelopes/"+id+"/final", bytes.NewReader(body)))
+				if rr.Code != http.StatusOK {
+					t.Fatalf("Final for %s failed: %d %s", id, rr.Code, rr.Body.String())
+				}
+			}
+
+			postFinal("fanout-parent-1", "succeeded", map[string]any{"branch": 1})
+			postFinal("fanout-parent", "succeeded", map[string]any{"branch": 0})
+
+			got, _ := store.Get("fanout-parent")
+			if got.Status != types.EnvelopeStatusRunning {
+				t.Fatalf("Parent status with pending branch = %v, want %v", got.Status, types.EnvelopeStatusRunning)
+			}
+
+			postFinal("fanout-parent-2", tt.childStatus, map[string]any{"branch": 2})
+
+			got, _ = store.Get("fanout-parent")
+			if got.Status != tt.wantStatus {
+				t.Errorf("Parent status = %v, want %v", got.Status, tt.wantStatus)
+			}
+			results, ok := got.Result.([]any)
+			if !ok || len(results) != 3 {
+				t.Fatalf("Parent result = %#v, want 3 aggregated branch results", got.Result)
+			}
+			if first, ok := results[0].(map[string]interface{}); !ok || first["branch"] != float64(0) {
+				t.Errorf("First result = %#v, want parent branch result", results[0])
+			}
+		})
+	}
+}
+
+// TestHandleEnvelopeFinal_FanoutDuplicateFinals tests that redelivered and out-of-order branch
+// finals neither complete the parent early nor regress it once aggregated
+func TestHandleEnvelopeFinal_FanoutDuplicateFinals(t *testing.T) {
+	store := envelopestore.NewStore()
+	handler := NewHandler(store)
+
+	if err := store.Create(&types.Envelope{ID: "fanout-parent", Route: types.Route{Actors: []string{"splitter", "worker"}}}); err != nil {
+		t.Fatalf("Failed to create parent: %v", err)
+	}
+
+	// Only the first child is registered yet, but it announces all three branches
+	body := `{"id":"fanout-parent-1","parent_id":"fanout-parent","branch_index":1,"fanout_branches":3,"actors":["splitter","worker"],"current":1}`
+	rr := httptest.NewRecorder()
+	handler.HandleEnvelopeCreate(rr, httptest.NewRequest(http.MethodPost, "/envelopes", strings.NewReader(body)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Failed to create child: %d %s", rr.Code, rr.Body.String())
+	}
+
+	postFinal := func(id string) {
+		body := fmt.Sprintf(`{"id":%q,"status":"succeeded","result":{"id":%q}}`, id, id)
+		rr := httptest.NewRecorder()
+		serveRoutes(handler, rr, httptest.NewRequest(http.MethodPost, "/envelopes/"+id+"/final", strings.NewReader(body)))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Final for %s failed: %d %s", id, rr.Code, rr.Body.String())
+		}
+	}
+	parentStatus := func() types.EnvelopeStatus {
+		got, _ := store.Get("fanout-parent")
+		return got.Status
+	}
+
+	postFinal("fanout-parent-1")
+	postFinal("fanout-parent-1")
+	postFinal("fanout-parent")
+	postFinal("fanout-parent")
+	if status := parentStatus(); status != types.EnvelopeStatusRunning {
+		t.Fatalf("Parent status with branch 2 pending = %v, want %v", status, types.EnvelopeStatusRunning)
+	}
+
+	body = `{"id":"fanout-parent-2","parent_id":"fanout-parent","branch_index":2,"fanout_branches":3,"actors":["splitter","worker"],"current":1}`
+	rr = httptest.NewRecorder()
+	handler.HandleEnvelopeCreate(rr, httptest.NewRequest(http.MethodPost, "/envelopes", strings.NewReader(body)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Failed to create child: %d %s", rr.Code, rr.Body.String())
+	}
+	postFinal("fanout-parent-2")
+	if status := parentStatus(); status != types.EnvelopeStatusSucceeded {
+		t.Fatalf("Parent status = %v, want %v", status, types.EnvelopeStatusSucceeded)
+	}
+
+	// A parent final redelivered after aggregation leaves the aggregated result in place
+	postFinal("fanout-parent")
+	got, _ := store.Get("fanout-parent")
+	if got.Status != types.EnvelopeStatusSucceeded {
+		t.Errorf("Parent status after duplicate final = %v, want %v", got.Status, types.EnvelopeStatusSucceeded)
+	}
+	if results, ok := got.Result.([]any); !ok || len(results) != 3 {
+		t.Errorf("Parent result = %#v, want 3 aggregated branch results", got.Result)
+	}
+}
+
+// batchQueueClient records batch publishes and fails envelopes routed to failActor,
+// or every envelope with failAll when set
+type batchQueueClient struct {
+	MockQueueClient
+	failActor  string
+	failAll    error
+	batchCalls int
+	sent       []string
+}
+
+func (m *batchQueueClient) SendEnvelopes(ctx context.Context, envelopes []*types.Envelope) []error {
+	m.batchCalls++
+	errs := make([]error, len(envelopes))
+	for i, envelope := range envelopes {
+		if m.failAll != nil {
+			errs[i] = m.failAll
+			continue
+		}
+		if envelope.Route.Actors[0] == m.failActor {
+			errs[i] = fmt.Errorf("queue unavailable")
+			continue
+		}
+		m.sent = append(m.sent, envelope.ID)
+	}
+	return errs
+}
+
+// TestHandleEnvelopeBatch tests POST /envelopes/batch with per-item results
+func TestHandleEnvelopeBatch(t *testing.T) {
+	cfg := &config.Config{
+		Tools: []config.Tool{
+			{
+				Name:  "echo",
+				Route: config.RouteSpec{Actors: []string{"echo-actor"}},
+				Parameters: map[string]config.Parameter{
+					"text": {Type: "string", Required: true},
+				},
+			},
+			{
+				Name:  "broken",
+				Route: config.RouteSpec{Actors: []string{"broken-actor"}},
+			},
+		},
+	}
+
+	store := envelopestore.NewStore()
+	queueClient := &batchQueueClient{failActor: "broken-actor"}
+	handler := NewHandler(store)
+	handler.SetServer(NewServer(store, queueClient, cfg))
+
+	items := []BatchItem{
+		{Name: "echo", Arguments: map[string]any{"text": "a"}},
+		{Name: "missing-tool"},
+		{Name: "echo", Arguments: map[string]any{}},
+		{Name: "broken"},
+		{Name: "echo", Arguments: map[string]any{"text": "b"}},
+		{Name: "echo", Arguments: map[string]any{"text": "c"}, StartStep: 1},
+		{Name: "echo", Arguments: map[string]any{"text": "d"}, Metadata: map[string]any{"user_id": "u-1"}},
+		{Name: "echo", Arguments: map[string]any{"text": "e"}, Metadata: map[string]any{"job_id": "spoofed"}},
+	}
+	body, _ := json.Marshal(items)
+	req := httptest.NewRequest(http.MethodPost, "/envelopes/batch", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	handler.HandleEnvelopeBatch(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("HandleEnvelopeBatch() status = %v, want %v", rr.Code, http.StatusOK)
+	}
+
+	var results []BatchItemResult
+	if err := json.NewDecoder(rr.Body).Decode(&results); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(results) != len(items) {
+		t.Fatalf("Got %d results, want %d", len(results), len(items))
+	}
+
+	wantStatuses := []string{"queued", "rejected", "rejected", "failed", "queued", "rejected", "queued", "rejected"}
+	for i, result := range results {
+		if result.Index != i {
+			t.Errorf("results[%d].Index = %d, want %d", i, result.Index, i)
+		}
+		if result.Status != wantStatuses[i] {
+			t.Errorf("results[%d].Status = %q, want %q (error: %s)", i, result.Status, wantStatuses[i], result.Error)
+		}
+		if result.Status == "rejected" {
+			if result.EnvelopeID != "" || result.Error == "" {
+				t.Errorf("results[%d] = %+v, want error and no envelope ID", i, result)
+			}
+			continue
+		}
+		if status := mustGetStatus(t, store, result.EnvelopeID); string(status) != result.Status {
+			t.Errorf("stored status for results[%d] = %v, want %v", i, status, result.Status)
+		}
+	}
+
+	if queueClient.batchCalls != 1 {
+		t.Errorf("batch publishes = %d, want 1", queueClient.batchCalls)
+	}
+	if len(queueClient.sent) != 3 {
+		t.Errorf("published envelopes = %d, want 3", len(queueClient.sent))
+	}
+
+	envelope, err := store.Get(results[6].EnvelopeID)
+	if err != nil {
+		t.Fatalf("Envelope not found: %v", err)
+	}
+	if want := map[string]interface{}{"user_id": "u-1", "job_id": envelope.ID}; !reflect.DeepEqual(envelope.Route.Metadata, want) {
+		t.Errorf("Route.Metadata = %v, want %v", envelope.Route.Metadata, want)
+	}
+}
+
+// TestHandleEnvelopeBatch_PoolExhausted tests that an exhausted channel pool surfaces as 503
+func TestHandleEnvelopeBatch_PoolExhausted(t *testing.T) {
+	cfg := &config.Config{
+		Tools: []config.Tool{{Name: "echo", Route: config.RouteSpec{Actors: []string{"echo-actor"}}}},
+	}
+
+	store := envelopestore.NewStore()
+	queueClient := &batchQueueClient{failAll: fmt.Errorf("failed to get channel from pool: %w", queue.ErrPoolExhausted)}
+	handler := NewHandler(store)
+	handler.SetServer(NewServer(store, queueClient, cfg))
+
+	req := httptest.NewRequest(http.MethodPost, "/envelopes/batch", strings.NewReader(`[{"name":"echo"},{"name":"echo"}]`))
+	rr := httptest.NewRecorder()
+
+	handler.HandleEnvelopeBatch(rr, req)
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("HandleEnvelopeBatch() status = %v, want %v", rr.Code, http.StatusServiceUnavailable)
+	}
+	if rr.Header().Get("Retry-After") == "" {
+		t.Error("Retry-After header not set")
+	}
+
+	var results []BatchItemResult
+	if err := json.NewDecoder(rr.Body).Decode(&results); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	for i, result := range results {
+		if result.Status != "failed" || !strings.Contains(result.Error, "channel pool exhausted") {
+			t.Errorf("results[%d] = %+v, want failed with pool exhausted error", i, result)
+		}
+	}
+}
+
+// TestHandleEnvelopeBatch_InvalidRequests tests request-level validation of batch submissions
+func TestHandleEnvelopeBatch_InvalidRequests(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		readOnly   bool
+		wantStatus int
+	}{
+		{name: "wrong method", method: http.MethodGet, wantStatus: http.StatusMethodNotAllowed},
+		{name: "invalid json", method: http.MethodPost, body: "[{", wantStatus: http.StatusBadRequest},
+		{name: "empty batch", method: http.MethodPost, body: "[]", wantStatus: http.StatusBadRequest},
+		{name: "oversize batch", method: http.MethodPost, body: "[" + strings.Repeat(`{"name":"echo"},`, MaxBatchSize) + `{"name":"echo"}]`, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "read-only mode", method: http.MethodPost, body: `[{"name":"echo"}]`, readOnly: true, wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := envelopestore.NewStore()
+			handler := NewHandler(store)
+			handler.SetServer(NewServer(store, &MockQueueClient{}, &config.Config{}))
+			handler.SetReadOnly(tt.readOnly)
+
+			req := httptest.NewRequest(tt.method, "/envelopes/batch", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+
+			handler.HandleEnvelopeBatch(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("HandleEnvelopeBatch() status = %v, want %v", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package mcp
+
+// slots bounds how many operations run at once, e.g. background publishes or open streams.
+// A nil slots is unlimited.
+type slots chan struct{}
+
+// newSlots returns slots for limit concurrent operations; a limit of 0 or less means unlimited
+func newSlots(limit int) slots {
+	if limit <= 0 {
+		return nil
+	}
+	return make(slots, limit)
+}
+
+// tryAcquire takes a slot without waiting, returning a function that releases it.
+// Returns false if all slots are taken.
+func (s slots) tryAcquire() (func(), bool) {
+	if s == nil {
+		return func() {}, true
+	}
+
+	select {
+	case s <- struct{}{}:
+		return func() { <-s }, true
+	default:
+		return nil, false
+	}
+}
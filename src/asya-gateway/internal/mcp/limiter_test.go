@@ -0,0 +1,37 @@
+package mcp
+
+import "testing"
+
+func TestSlots(t *testing.T) {
+	s := newSlots(2)
+
+	release1, ok := s.tryAcquire()
+	if !ok {
+		t.Fatal("First acquire should succeed")
+	}
+	if _, ok := s.tryAcquire(); !ok {
+		t.Fatal("Second acquire should succeed")
+	}
+	if _, ok := s.tryAcquire(); ok {
+		t.Fatal("Acquire beyond the limit should fail")
+	}
+
+	release1()
+	if _, ok := s.tryAcquire(); !ok {
+		t.Error("Acquire after a release should succeed")
+	}
+}
+
+func TestSlots_Unlimited(t *testing.T) {
+	for _, limit := range []int{0, -1} {
+		s := newSlots(limit)
+		if s != nil {
+			t.Errorf("newSlots(%d) should be unlimited", limit)
+		}
+		for i := 0; i < 100; i++ {
+			if _, ok := s.tryAcquire(); !ok {
+				t.Fatalf("newSlots(%d): acquire %d failed", limit, i)
+			}
+		}
+	}
+}
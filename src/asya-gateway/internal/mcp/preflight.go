@@ -0,0 +1,57 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/deliveryhero/asya/asya-gateway/internal/queue"
+)
+
+const (
+	// knownQueueTTL is how long a queue found to exist is trusted before it is inspected again
+	knownQueueTTL = 30 * time.Second
+
+	// queueCheckTimeout bounds the inspection of the first actor's queue
+	queueCheckTimeout = 5 * time.Second
+)
+
+// ErrQueueUnavailable is returned by tool handlers when the first actor's queue could not
+// be checked, e.g. because the broker is unreachable or no channel is available
+var ErrQueueUnavailable = errors.New("queue unavailable")
+
+// checkFirstQueue verifies that the queue of the actor an envelope starts at exists before
+// the envelope is created: messages published to a missing queue are silently dropped,
+// and the caller would be told about an envelope that can never start.
+// Returns an error wrapping ErrQueueUnavailable when the queue could not be inspected.
+// Clients that cannot inspect queues are not checked.
+func (r *Registry) checkFirstQueue(ctx context.Context, actor string) error {
+	inspector, ok := r.queueClient.(queue.Inspector)
+	if !r.checkQueues || !ok {
+		return nil
+	}
+
+	queueName := queue.ActorQueueName(actor)
+	r.knownQueuesMu.Lock()
+	checkedAt, known := r.knownQueues[queueName]
+	r.knownQueuesMu.Unlock()
+	if known && time.Since(checkedAt) < knownQueueTTL {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queueCheckTimeout)
+	defer cancel()
+
+	if _, err := inspector.InspectQueue(ctx, queueName); err != nil {
+		if errors.Is(err, queue.ErrQueueNotFound) {
+			return fmt.Errorf("queue %q of actor %q does not exist", queueName, actor)
+		}
+		return fmt.Errorf("%w: failed to check queue %q: %v", ErrQueueUnavailable, queueName, err)
+	}
+
+	r.knownQueuesMu.Lock()
+	r.knownQueues[queueName] = time.Now()
+	r.knownQueuesMu.Unlock()
+	return nil
+}
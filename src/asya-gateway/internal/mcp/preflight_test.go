@@ -0,0 +1,140 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/deliveryhero/asya/asya-gateway/internal/config"
+	"github.com/deliveryhero/asya/asya-gateway/internal/envelopestore"
+	"github.com/deliveryhero/asya/asya-gateway/internal/queue"
+)
+
+// inspectingQueueClient is a queue client whose queues can be inspected
+type inspectingQueueClient struct {
+	MockQueueClient
+	mu         sync.Mutex
+	inspectErr error
+	inspected  []string
+}
+
+func (m *inspectingQueueClient) InspectQueue(ctx context.Context, queueName string) (queue.QueueStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inspected = append(m.inspected, queueName)
+	return queue.QueueStats{}, m.inspectErr
+}
+
+func newPreflightServer(store envelopestore.EnvelopeStore, queueClient queue.Client) *Server {
+	server := NewServer(store, queueClient, &config.Config{
+		Tools: []config.Tool{
+			{Name: "pipeline", Route: config.RouteSpec{Actors: []string{"prep", "infer"}}},
+		},
+	})
+	server.SetIDGenerator(func() string { return "env-1" })
+	return server
+}
+
+func callPipeline(t *testing.T, handler *Handler, startStep int) *httptest.ResponseRecorder {
+	t.Helper()
+	body := fmt.Sprintf(`{"name": "pipeline", "arguments": {"input": "x"}, "start_step": %d}`, startStep)
+	rr := httptest.NewRecorder()
+	handler.HandleToolCall(rr, httptest.NewRequest(http.MethodPost, "/tools/call", bytes.NewReader([]byte(body))))
+	return rr
+}
+
+func TestCheckFirstQueue(t *testing.T) {
+	tests := []struct {
+		name        string
+		inspectErr  error
+		startStep   int
+		wantStatus  int
+		wantError   string // Substring of the response body; "" for success
+		wantQueue   string
+		wantCreated bool
+	}{
+		{name: "queue exists", wantStatus: http.StatusOK, wantQueue: "asya-prep", wantCreated: true},
+		{name: "checks queue of start step", startStep: 1, wantStatus: http.StatusOK, wantQueue: "asya-infer", wantCreated: true},
+		{name: "queue missing", inspectErr: queue.ErrQueueNotFound, wantStatus: http.StatusOK, wantError: `queue \"asya-prep\" of actor \"prep\" does not exist`, wantQueue: "asya-prep"},
+		{name: "broker unavailable", inspectErr: errors.New("connection refused"), wantStatus: http.StatusServiceUnavailable, wantError: "queue unavailable", wantQueue: "asya-prep"},
+		{name: "pool exhausted", inspectErr: queue.ErrPoolExhausted, wantStatus: http.StatusServiceUnavailable, wantError: "channel pool exhausted", wantQueue: "asya-prep"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := envelopestore.NewStore()
+			queueClient := &inspectingQueueClient{inspectErr: tt.inspectErr}
+			handler := NewHandler(store)
+			handler.SetServer(newPreflightServer(store, queueClient))
+
+			rr := callPipeline(t, handler, tt.startStep)
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("Status = %d, want %d: %s", rr.Code, tt.wantStatus, rr.Body.String())
+			}
+			if tt.wantError != "" && !strings.Contains(rr.Body.String(), tt.wantError) {
+				t.Errorf("Body = %s, want error containing %q", rr.Body.String(), tt.wantError)
+			}
+			if tt.wantError == "" && strings.Contains(rr.Body.String(), `"isError":true`) {
+				t.Errorf("Unexpected error result: %s", rr.Body.String())
+			}
+			if len(queueClient.inspected) != 1 || queueClient.inspected[0] != tt.wantQueue {
+				t.Errorf("Inspected queues = %v, want [%s]", queueClient.inspected, tt.wantQueue)
+			}
+			if rr.Code == http.StatusServiceUnavailable && rr.Header().Get("Retry-After") != "1" {
+				t.Errorf("Retry-After = %q, want %q", rr.Header().Get("Retry-After"), "1")
+			}
+
+			_, err := store.Get("env-1")
+			if created := err == nil; created != tt.wantCreated {
+				t.Errorf("Envelope created = %v, want %v", created, tt.wantCreated)
+			}
+		})
+	}
+}
+
+func TestCheckFirstQueue_CachesExistingQueues(t *testing.T) {
+	queueClient := &inspectingQueueClient{}
+	registry := NewRegistry(&config.Config{}, envelopestore.NewStore(), queueClient)
+
+	for i := 0; i < 3; i++ {
+		if err := registry.checkFirstQueue(context.Background(), "prep"); err != nil {
+			t.Fatalf("checkFirstQueue() error = %v", err)
+		}
+	}
+	if len(queueClient.inspected) != 1 {
+		t.Errorf("Inspected %d times, want 1", len(queueClient.inspected))
+	}
+
+	// Missing queues are not cached, they may be created at any time
+	queueClient.inspectErr = queue.ErrQueueNotFound
+	for i := 0; i < 2; i++ {
+		if err := registry.checkFirstQueue(context.Background(), "infer"); err == nil {
+			t.Fatal("checkFirstQueue() of missing queue succeeded")
+		}
+	}
+	if len(queueClient.inspected) != 3 {
+		t.Errorf("Inspected %d times, want 3", len(queueClient.inspected))
+	}
+}
+
+func TestCheckFirstQueue_Disabled(t *testing.T) {
+	store := envelopestore.NewStore()
+	queueClient := &inspectingQueueClient{inspectErr: queue.ErrQueueNotFound}
+	server := newPreflightServer(store, queueClient)
+	server.SetCheckQueues(false)
+	handler := NewHandler(store)
+	handler.SetServer(server)
+
+	if rr := callPipeline(t, handler, 0); rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), `"isError":true`) {
+		t.Fatalf("Status = %d, want success: %s", rr.Code, rr.Body.String())
+	}
+	if len(queueClient.inspected) != 0 {
+		t.Errorf("Inspected queues = %v, want none", queueClient.inspected)
+	}
+}
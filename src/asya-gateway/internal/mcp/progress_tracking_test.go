@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -100,7 +101,7 @@ func TestProgressTracking_EndToEnd(t *testing.T) {
 		req.Header.Set("Content-Type", "application/json")
 		rr := httptest.NewRecorder()
 
-		handler.HandleEnvelopeProgress(rr, req)
+		serveRoutes(handler, rr, req)
 
 		if rr.Code != http.StatusOK {
 			t.Fatalf("Progress update failed for %s/%s: status=%d", report.actor, report.status, rr.Code)
@@ -175,7 +176,7 @@ func TestProgressTracking_SSEStream(t *testing.T) {
 
 	// Stream in background
 	go func() {
-		handler.HandleEnvelopeStream(rr, req)
+		serveRoutes(handler, rr, req)
 	}()
 
 	// Give stream time to start
@@ -194,7 +195,7 @@ func TestProgressTracking_SSEStream(t *testing.T) {
 		progressReq.Header.Set("Content-Type", "application/json")
 		progressRr := httptest.NewRecorder()
 
-		handler.HandleEnvelopeProgress(progressRr, progressReq)
+		serveRoutes(handler, progressRr, progressReq)
 
 		if progressRr.Code != http.StatusOK {
 			t.Fatalf("Progress update %d failed: %v", i, progressRr.Code)
@@ -243,7 +244,7 @@ func TestProgressTracking_SSEKeepalive(t *testing.T) {
 
 	done := make(chan bool)
 	go func() {
-		handler.HandleEnvelopeStream(rr, req)
+		serveRoutes(handler, rr, req)
 		done <- true
 	}()
 
@@ -304,7 +305,7 @@ func TestProgressTracking_ConcurrentUpdates(t *testing.T) {
 			req.Header.Set("Content-Type", "application/json")
 			rr := httptest.NewRecorder()
 
-			handler.HandleEnvelopeProgress(rr, req)
+			serveRoutes(handler, rr, req)
 
 			if rr.Code != http.StatusOK {
 				t.Errorf("Update %d failed: status=%d", idx, rr.Code)
@@ -346,7 +347,7 @@ func TestProgressTracking_InvalidEnvelopeID(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
-	handler.HandleEnvelopeProgress(rr, req)
+	serveRoutes(handler, rr, req)
 
 	// Should return error for non-existent envelope
 	if rr.Code == http.StatusOK {
@@ -389,7 +390,7 @@ func TestProgressTracking_RouteActorsUpdate(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
-	handler.HandleEnvelopeProgress(rr, req)
+	serveRoutes(handler, rr, req)
 
 	if rr.Code != http.StatusOK {
 		t.Fatalf("Progress update failed: status=%d", rr.Code)
@@ -499,7 +500,7 @@ func TestProgressTracking_EmptyActorsList(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
-	handler.HandleEnvelopeProgress(rr, req)
+	serveRoutes(handler, rr, req)
 
 	if rr.Code != http.StatusOK {
 		t.Fatalf("Progress update failed: status=%d, body=%s", rr.Code, rr.Body.String())
@@ -554,9 +555,240 @@ func sendProgressUpdate(t *testing.T, handler *Handler, envelopeID string, actor
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
-	handler.HandleEnvelopeProgress(rr, req)
+	serveRoutes(handler, rr, req)
 
 	if rr.Code != http.StatusOK {
 		t.Fatalf("Progress update failed: status=%d", rr.Code)
 	}
 }
+
+// TestProgressTracking_PartialResult tests that partial results are stored and streamed
+// without becoming the final result
+func TestProgressTracking_PartialResult(t *testing.T) {
+	tests := []struct {
+		name          string
+		partialResult string
+		limit         int64
+		wantStatus    int
+		wantPartial   any
+	}{
+		{
+			name:          "partial result is stored",
+			partialResult: `{"text":"Once upon a"}`,
+			wantStatus:    http.StatusOK,
+			wantPartial:   map[string]interface{}{"text": "Once upon a"},
+		},
+		{
+			name:       "progress without partial result",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:          "partial result over the limit",
+			partialResult: `{"text":"Once upon a time"}`,
+			limit:         10,
+			wantStatus:    http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := envelopestore.NewStore()
+			handler := NewHandler(store)
+			if tt.limit > 0 {
+				handler.SetMaxPartialResultBytes(tt.limit)
+			}
+
+			envelope := &types.Envelope{
+				ID:     "partial-result-envelope",
+				Route:  types.Route{Actors: []string{"generate"}},
+				Status: types.EnvelopeStatusPending,
+			}
+			if err := store.Create(envelope); err != nil {
+				t.Fatalf("Failed to create envelope: %v", err)
+			}
+
+			updateChan := store.Subscribe(envelope.ID)
+			defer store.Unsubscribe(envelope.ID, updateChan)
+
+			body, _ := json.Marshal(types.ProgressUpdate{
+				Actors:        []string{"generate"},
+				Status:        "processing",
+				PartialResult: json.RawMessage(tt.partialResult),
+			})
+			req := httptest.NewRequest(http.MethodPost, "/envelopes/"+envelope.ID+"/progress", bytes.NewReader(body))
+			rr := httptest.NewRecorder()
+
+			serveRoutes(handler, rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			select {
+			case update := <-updateChan:
+				if !reflect.DeepEqual(update.PartialResult, tt.wantPartial) {
+					t.Errorf("streamed PartialResult = %v, want %v", update.PartialResult, tt.wantPartial)
+				}
+				if update.Result != nil {
+					t.Errorf("streamed Result = %v, want nil", update.Result)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Did not receive update within timeout")
+			}
+
+			stored, err := store.Get(envelope.ID)
+			if err != nil {
+				t.Fatalf("Failed to get envelope: %v", err)
+			}
+			if !reflect.DeepEqual(stored.PartialResult, tt.wantPartial) {
+				t.Errorf("stored PartialResult = %v, want %v", stored.PartialResult, tt.wantPartial)
+			}
+			if stored.Result != nil {
+				t.Errorf("stored Result = %v, want nil", stored.Result)
+			}
+		})
+	}
+}
+
+// TestProgressTracking_Warnings tests that warnings of successive actors accumulate
+// on the envelope and are streamed with the update that reported them
+func TestProgressTracking_Warnings(t *testing.T) {
+	store := envelopestore.NewStore()
+	handler := NewHandler(store)
+
+	envelope := &types.Envelope{
+		ID:     "warnings-envelope",
+		Route:  types.Route{Actors: []string{"prep", "infer", "post"}},
+		Status: types.EnvelopeStatusPending,
+	}
+	if err := store.Create(envelope); err != nil {
+		t.Fatalf("Failed to create envelope: %v", err)
+	}
+
+	updateChan := store.Subscribe(envelope.ID)
+	defer store.Unsubscribe(envelope.ID, updateChan)
+
+	reports := []types.ProgressUpdate{
+		{CurrentActorIdx: 0, Status: "completed", Warnings: []string{"input truncated"}},
+		{CurrentActorIdx: 1, Status: "received"},
+		{CurrentActorIdx: 1, Status: "completed", Warnings: []string{"used fallback model"}},
+	}
+	for _, report := range reports {
+		body, _ := json.Marshal(report)
+		req := httptest.NewRequest(http.MethodPost, "/envelopes/"+envelope.ID+"/progress", bytes.NewReader(body))
+		rr := httptest.NewRecorder()
+
+		serveRoutes(handler, rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", rr.Code)
+		}
+
+		select {
+		case update := <-updateChan:
+			if !reflect.DeepEqual(update.Warnings, report.Warnings) {
+				t.Errorf("streamed Warnings = %v, want %v", update.Warnings, report.Warnings)
+			}
+			if update.Status != types.EnvelopeStatusRunning {
+				t.Errorf("streamed Status = %v, want running", update.Status)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Did not receive update within timeout")
+		}
+	}
+
+	stored, err := store.Get(envelope.ID)
+	if err != nil {
+		t.Fatalf("Failed to get envelope: %v", err)
+	}
+	want := []string{"input truncated", "used fallback model"}
+	if !reflect.DeepEqual(stored.Warnings, want) {
+		t.Errorf("stored Warnings = %v, want %v", stored.Warnings, want)
+	}
+}
+
+// TestProgressTracking_SSEStreamLimit tests that streams beyond the limit are rejected until a slot frees up
+func TestProgressTracking_SSEStreamLimit(t *testing.T) {
+	store := envelopestore.NewStore()
+	handler := NewHandler(store)
+	handler.SetMaxStreams(1)
+
+	job := &types.Envelope{
+		ID:     "sse-limit-job",
+		Route:  types.Route{Actors: []string{"actor1"}, Current: 0},
+		Status: types.EnvelopeStatusRunning,
+	}
+	_ = store.Create(job)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := httptest.NewRequest(http.MethodGet, "/envelopes/"+job.ID+"/stream", nil).WithContext(ctx)
+	done := make(chan struct{})
+	go func() {
+		serveRoutes(handler, httptest.NewRecorder(), first)
+		close(done)
+	}()
+
+	// Wait until the first stream holds the only slot
+	deadline := time.Now().Add(time.Second)
+	for len(handler.streamSlots) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	rr := httptest.NewRecorder()
+	serveRoutes(handler, rr, httptest.NewRequest(http.MethodGet, "/envelopes/"+job.ID+"/stream", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Second stream status = %d, want %d", rr.Code, http.StatusServiceUnavailable)
+	}
+	if rr.Header().Get("Retry-After") == "" {
+		t.Error("Rejected stream should carry Retry-After")
+	}
+
+	// Closing the first stream frees its slot
+	cancel()
+	<-done
+	if len(handler.streamSlots) != 0 {
+		t.Errorf("Open stream slots = %d after the stream closed, want 0", len(handler.streamSlots))
+	}
+}
+
+// TestProgressTracking_SSEUnsubscribesOnDisconnect tests that a client disconnect removes the stream's listener
+func TestProgressTracking_SSEUnsubscribesOnDisconnect(t *testing.T) {
+	store := envelopestore.NewStore()
+	handler := NewHandler(store)
+
+	job := &types.Envelope{
+		ID:     "sse-disconnect-job",
+		Route:  types.Route{Actors: []string{"actor1"}, Current: 0},
+		Status: types.EnvelopeStatusRunning,
+	}
+	_ = store.Create(job)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/envelopes/"+job.ID+"/stream", nil).WithContext(ctx)
+	done := make(chan struct{})
+	go func() {
+		serveRoutes(handler, httptest.NewRecorder(), req)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for store.Subscribers() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := store.Subscribers(); got != 1 {
+		t.Fatalf("Subscribers = %d while streaming, want 1", got)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Stream did not return after client disconnect")
+	}
+	if got := store.Subscribers(); got != 0 {
+		t.Errorf("Subscribers = %d after client disconnect, want 0 (listener leaked)", got)
+	}
+}
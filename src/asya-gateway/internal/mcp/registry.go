@@ -3,42 +3,74 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 
 	"github.com/deliveryhero/asya/asya-gateway/internal/config"
 	"github.com/deliveryhero/asya/asya-gateway/internal/envelopestore"
+	"github.com/deliveryhero/asya/asya-gateway/internal/idgen"
+	"github.com/deliveryhero/asya/asya-gateway/internal/logging"
 	"github.com/deliveryhero/asya/asya-gateway/internal/queue"
 	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
 )
 
+// DefaultMaxRouteSteps is the default upper bound on route length at envelope creation
+const DefaultMaxRouteSteps = 100
+
+// DefaultMaxPendingPublishes is the default upper bound on asynchronous tool calls
+// whose envelope is still being published to the first actor's queue
+const DefaultMaxPendingPublishes = 1000
+
 // ToolHandler is a function that handles MCP tool calls
 type ToolHandler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error)
 
 // Registry manages dynamic MCP tool registration from configuration
 type Registry struct {
-	config      *config.Config
-	jobStore    envelopestore.EnvelopeStore
-	queueClient queue.Client
-	mcpServer   *server.MCPServer
-	handlers    map[string]ToolHandler // Map of tool name -> handler
+	config        *config.Config
+	jobStore      envelopestore.EnvelopeStore
+	queueClient   queue.Client
+	mcpServer     *server.MCPServer
+	handlers      map[string]ToolHandler // Map of tool name -> handler
+	maxRouteSteps int                    // Maximum number of actors in a route
+	basePath      string                 // Prefix for URLs returned to clients ("" for root mounting)
+	newID         idgen.Generator        // Envelope ID generator
+	replyQueue    *queue.ReplyQueue      // Receives outcomes of synchronous tool calls (nil: sync tools respond async)
+	publishSlots  slots                  // Bounds background publishes of tool calls (nil: unlimited)
+
+	checkQueues   bool                 // Verify the first actor's queue exists before creating an envelope
+	knownQueuesMu sync.Mutex           // Guards knownQueues
+	knownQueues   map[string]time.Time // Queue name -> when it was last found to exist
 }
 
 // NewRegistry creates a new tool registry
 func NewRegistry(cfg *config.Config, jobStore envelopestore.EnvelopeStore, queueClient queue.Client) *Registry {
 	return &Registry{
-		config:      cfg,
-		jobStore:    jobStore,
-		queueClient: queueClient,
-		handlers:    make(map[string]ToolHandler),
+		config:        cfg,
+		jobStore:      jobStore,
+		queueClient:   queueClient,
+		handlers:      make(map[string]ToolHandler),
+		maxRouteSteps: DefaultMaxRouteSteps,
+		newID:         idgen.NewUUID,
+		publishSlots:  newSlots(DefaultMaxPendingPublishes),
+		checkQueues:   true,
+		knownQueues:   make(map[string]time.Time),
 	}
 }
 
+// acquirePublish takes a slot for publishing a tool call's envelope in the background,
+// returning a function that releases it. Returns false if all slots are taken.
+func (r *Registry) acquirePublish() (func(), bool) {
+	return r.publishSlots.tryAcquire()
+}
+
 // RegisterAll registers all tools from config to the MCP server
 func (r *Registry) RegisterAll(mcpServer *server.MCPServer) error {
 	r.mcpServer = mcpServer
@@ -47,10 +79,10 @@ func (r *Registry) RegisterAll(mcpServer *server.MCPServer) error {
 		if err := r.registerTool(toolDef); err != nil {
 			return fmt.Errorf("failed to register tool %q: %w", toolDef.Name, err)
 		}
-		log.Printf("Registered tool: %s", toolDef.Name)
+		slog.Info("Registered tool", "tool", toolDef.Name)
 	}
 
-	log.Printf("Successfully registered %d tools", len(r.config.Tools))
+	slog.Info("Successfully registered tools", "count", len(r.config.Tools))
 	return nil
 }
 
@@ -102,13 +134,19 @@ func (r *Registry) buildParameterOptions(name string, param config.Parameter) (m
 		paramOptions = append(paramOptions, mcp.Required())
 	}
 
+	// Advertise the allowed values and constraints
+	paramOptions = append(paramOptions, constraintOptions(param)...)
+
+	// Advertise the default applied when the parameter is omitted
+	if param.Default != nil {
+		if option, ok := defaultOption(param.Default); ok {
+			paramOptions = append(paramOptions, option)
+		}
+	}
+
 	// Build parameter option based on type
 	switch param.Type {
 	case "string":
-		// Add enum if specified
-		if len(param.Options) > 0 {
-			paramOptions = append(paramOptions, mcp.Enum(param.Options...))
-		}
 		return mcp.WithString(name, paramOptions...), nil
 
 	case "number", "integer":
@@ -119,115 +157,188 @@ func (r *Registry) buildParameterOptions(name string, param config.Parameter) (m
 
 	case "array":
 		if param.Items != nil {
-			// Handle typed arrays
-			switch param.Items.Type {
-			case "string":
-				paramOptions = append(paramOptions, mcp.WithStringItems())
-			case "number", "integer":
-				paramOptions = append(paramOptions, mcp.WithNumberItems())
-			}
+			paramOptions = append(paramOptions, mcp.Items(parameterSchema(*param.Items)))
 		}
 		return mcp.WithArray(name, paramOptions...), nil
 
 	case "object":
-		// For objects, use a generic parameter
-		// Object validation will be done in the handler
-		log.Printf("Warning: object parameter %q uses generic validation", name)
-		// We'll treat it as a flexible any parameter - mcp-go will handle it
-		// No specific type method needed - it will accept any JSON object
-		return mcp.WithString(name, paramOptions...), nil
+		withObject := mcp.WithObject(name, paramOptions...)
+		return func(tool *mcp.Tool) {
+			withObject(tool)
+			// Set after mcp.WithObject, which takes "required" for the parameter's own flag
+			if schema, ok := tool.InputSchema.Properties[name].(map[string]any); ok {
+				setObjectProperties(schema, param)
+			}
+		}, nil
 
 	default:
 		return nil, fmt.Errorf("unsupported parameter type: %s", param.Type)
 	}
 }
 
+// parameterSchema returns the JSON Schema of a nested parameter (array items, object properties)
+func parameterSchema(param config.Parameter) map[string]any {
+	schema := map[string]any{"type": param.Type}
+	if param.Description != "" {
+		schema["description"] = param.Description
+	}
+	for _, option := range constraintOptions(param) {
+		option(schema)
+	}
+	if param.Default != nil {
+		if option, ok := defaultOption(param.Default); ok {
+			option(schema)
+		}
+	}
+
+	switch param.Type {
+	case "array":
+		if param.Items != nil {
+			schema["items"] = parameterSchema(*param.Items)
+		}
+	case "object":
+		setObjectProperties(schema, param)
+	}
+	return schema
+}
+
+// setObjectProperties adds the schemas of an object parameter's properties,
+// and the names of the required ones, to the object's schema
+func setObjectProperties(schema map[string]any, param config.Parameter) {
+	if len(param.Properties) == 0 {
+		return
+	}
+
+	properties := make(map[string]any, len(param.Properties))
+	var required []string
+	for name, prop := range param.Properties {
+		properties[name] = parameterSchema(prop)
+		if prop.Required {
+			required = append(required, name)
+		}
+	}
+	schema["properties"] = properties
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+}
+
+// constraintOptions returns the schema options advertising a parameter's allowed values
+// (options or enum) and its range, length and pattern constraints
+func constraintOptions(param config.Parameter) []mcp.PropertyOption {
+	var options []mcp.PropertyOption
+	if allowed := param.AllowedValues(); len(allowed) > 0 {
+		options = append(options, enumOption(allowed))
+	}
+	if param.Minimum != nil {
+		options = append(options, mcp.Min(*param.Minimum))
+	}
+	if param.Maximum != nil {
+		options = append(options, mcp.Max(*param.Maximum))
+	}
+	if param.MinLength != nil {
+		options = append(options, mcp.MinLength(*param.MinLength))
+	}
+	if param.MaxLength != nil {
+		options = append(options, mcp.MaxLength(*param.MaxLength))
+	}
+	if param.Pattern != "" {
+		options = append(options, mcp.Pattern(param.Pattern))
+	}
+	return options
+}
+
+// enumOption restricts a parameter schema to the given values of any type
+// (mcp.Enum only accepts strings)
+func enumOption(values []any) mcp.PropertyOption {
+	return func(schema map[string]any) {
+		schema["enum"] = values
+	}
+}
+
+// defaultOption returns the schema option advertising a parameter default (validated by config.Load)
+func defaultOption(value any) (mcp.PropertyOption, bool) {
+	switch v := value.(type) {
+	case string:
+		return mcp.DefaultString(v), true
+	case bool:
+		return mcp.DefaultBool(v), true
+	case int:
+		return mcp.DefaultNumber(float64(v)), true
+	case float64:
+		return mcp.DefaultNumber(v), true
+	case []any:
+		return mcp.DefaultArray(v), true
+	}
+	return nil, false
+}
+
 // createToolHandler creates a tool handler function for the given tool definition
 func (r *Registry) createToolHandler(toolDef config.Tool) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		// Resolve route actors
-		actors, err := toolDef.Route.GetActors(r.config.Routes)
-		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("route error: %v", err)), nil
+		if dryRunFromContext(ctx) {
+			metadata, err := requestRouteMetadata(ctx, request)
+			if err != nil {
+				return nil, err
+			}
+			return r.dryRun(ctx, toolDef, request.GetArguments(), startStepFromContext(ctx), metadata)
 		}
 
 		// Get tool options (merged with defaults)
 		opts := toolDef.GetOptions(r.config.Defaults)
-
-		// Extract all arguments
-		arguments := request.GetArguments()
-
-		// Validate required parameters
-		for paramName, param := range toolDef.Parameters {
-			if param.Required {
-				if _, ok := arguments[paramName]; !ok {
-					return mcp.NewToolResultError(fmt.Sprintf("missing required parameter: %s", paramName)), nil
-				}
+		isSync := opts.Sync && r.replyQueue != nil && !r.replyQueue.Closed()
+
+		// Asynchronous calls take a publish slot before the envelope is stored,
+		// so a rejected call leaves nothing behind
+		var release func()
+		if !isSync {
+			var ok bool
+			if release, ok = r.acquirePublish(); !ok {
+				return nil, ErrPublishSaturated
 			}
 		}
 
-		// Create envelope
-		envelopeID := uuid.New().String()
-		envelope := &types.Envelope{
-			ID:     envelopeID,
-			Status: types.EnvelopeStatusPending,
-			Route: types.Route{
-				Actors:  actors,
-				Current: 0,
-				Metadata: map[string]interface{}{
-					"job_id": envelopeID, // For end queue tracking
-				},
-			},
-			Payload:    arguments,
-			TimeoutSec: int(opts.Timeout.Seconds()),
-		}
-
-		// Set deadline if timeout is configured
-		if opts.Timeout > 0 {
-			envelope.Deadline = time.Now().Add(opts.Timeout)
+		metadata, err := requestRouteMetadata(ctx, request)
+		if err != nil {
+			if release != nil {
+				release()
+			}
+			return nil, err
 		}
-
-		// Store envelope
-		if err := r.jobStore.Create(envelope); err != nil {
-			log.Printf("Failed to create envelope: %v", err)
-			return mcp.NewToolResultError(fmt.Sprintf("failed to create envelope: %v", err)), nil
+		envelope, err := r.createEnvelope(ctx, toolDef, request.GetArguments(), startStepFromContext(ctx), metadata)
+		if err != nil {
+			if release != nil {
+				release()
+			}
+			if errors.Is(err, ErrQueueUnavailable) || errors.Is(err, ErrEmptyRoute) || errors.Is(err, ErrInvalidRouteMetadata) ||
+				errors.Is(err, envelopestore.ErrTooManyActive) {
+				return nil, err
+			}
+			return mcp.NewToolResultError(err.Error()), nil
 		}
+		envelopeID := envelope.ID
 
-		// Send to queue (async)
-		go func() {
-			// Update status to Running
-			_ = r.jobStore.Update(types.EnvelopeUpdate{
-				ID:        envelopeID,
-				Status:    types.EnvelopeStatusRunning,
-				Message:   "Sending envelope to first actor",
-				Timestamp: time.Now(),
-			})
-
-			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-			defer cancel()
-
-			if err := r.queueClient.SendEnvelope(ctx, envelope); err != nil {
-				log.Printf("Failed to send envelope to queue: %v", err)
-				_ = r.jobStore.Update(types.EnvelopeUpdate{
-					ID:        envelopeID,
-					Status:    types.EnvelopeStatusFailed,
-					Error:     fmt.Sprintf("failed to send envelope: %v", err),
-					Timestamp: time.Now(),
-				})
-				return
+		// Synchronous tools publish with a reply queue and wait for the last actor's outcome
+		if isSync {
+			if result, ok := r.callSync(ctx, envelope, syncWait(ctx, opts.Timeout)); ok {
+				return result, nil
 			}
-		}()
+		} else {
+			// Mark as queued and send to queue (async)
+			enqueueEnvelope(r.jobStore, r.queueClient, envelope, release)
+		}
 
 		// Build MCP-compliant structured response
 		responseData := map[string]interface{}{
 			"envelope_id": envelopeID,
 			"message":     "Envelope created successfully",
-			"status_url":  fmt.Sprintf("/envelopes/%s", envelopeID),
+			"status_url":  r.envelopeURL(envelopeID, ""),
 		}
 
 		// Add stream endpoint if progress is enabled
 		if opts.Progress {
-			responseData["stream_url"] = fmt.Sprintf("/envelopes/%s/stream", envelopeID)
+			responseData["stream_url"] = r.envelopeURL(envelopeID, "/stream")
 		}
 
 		// Add metadata to response if present
@@ -245,13 +356,366 @@ func (r *Registry) createToolHandler(toolDef config.Tool) func(context.Context,
 	}
 }
 
+// dryRun builds the envelope a tool call would create and returns its route and payload,
+// without storing or publishing anything
+func (r *Registry) dryRun(ctx context.Context, toolDef config.Tool, arguments map[string]any, startStep int, metadata map[string]any) (*mcp.CallToolResult, error) {
+	envelope, err := r.buildEnvelope(ctx, toolDef, arguments, startStep, metadata)
+	if err != nil {
+		if errors.Is(err, ErrQueueUnavailable) || errors.Is(err, ErrEmptyRoute) || errors.Is(err, ErrInvalidRouteMetadata) {
+			return nil, err
+		}
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return dryRunResult(envelope), nil
+}
+
+// dryRunResult returns the route and payload of an envelope that was built but not created
+func dryRunResult(envelope *types.Envelope) *mcp.CallToolResult {
+	responseData := map[string]interface{}{
+		"dry_run": true,
+		"tool":    envelope.Tool,
+		"route":   envelope.Route,
+		"payload": envelope.Payload,
+	}
+	if envelope.TimeoutSec > 0 {
+		responseData["timeout_sec"] = envelope.TimeoutSec
+	}
+	if envelope.Exchange != "" {
+		responseData["exchange"] = envelope.Exchange
+	}
+
+	responseJSON, err := json.Marshal(responseData)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal response: %v", err))
+	}
+	return mcp.NewToolResultText(string(responseJSON))
+}
+
+// callSync publishes an envelope with the reply queue as reply_to and waits up to timeout
+// (0: until ctx is done) for its happy-end/error-end message. ok is false when no reply
+// arrived in time; the envelope keeps running and the caller responds as for an async call.
+func (r *Registry) callSync(ctx context.Context, envelope *types.Envelope, timeout time.Duration) (result *mcp.CallToolResult, ok bool) {
+	envelope.ReplyTo = r.replyQueue.Name()
+	reply := r.replyQueue.Wait(envelope.ID)
+	defer r.replyQueue.Cancel(envelope.ID)
+
+	// Publish inline so a failed publish is reported instead of waiting for a reply that never comes
+	sendCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	sendErr := r.queueClient.SendEnvelope(sendCtx, envelope)
+	cancel()
+	recordPublish(logging.ContextWithJobID(ctx, envelope.ID), r.jobStore, envelope.ID, sendErr)
+	if sendErr != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to send envelope: %v", sendErr)), true
+	}
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case body, received := <-reply:
+		if !received {
+			slog.Warn("Reply queue closed while waiting, responding asynchronously", "id", envelope.ID)
+			return nil, false
+		}
+		return r.replyResult(envelope.ID, body), true
+	case <-expired:
+		slog.Info("No reply for synchronous envelope, responding asynchronously", "id", envelope.ID, "timeout", timeout)
+		return nil, false
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+// replyResult converts the terminal message replied for a synchronous tool call into the tool result
+func (r *Registry) replyResult(envelopeID string, body []byte) *mcp.CallToolResult {
+	var terminal types.TerminalMessage
+	if err := json.Unmarshal(body, &terminal); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid reply for envelope %s: %v", envelopeID, err))
+	}
+
+	if terminal.Status == types.EnvelopeStatusFailed {
+		errMsg := terminal.Error
+		if terminal.Details != nil && terminal.Details.Message != "" {
+			errMsg = fmt.Sprintf("%s: %s", errMsg, terminal.Details.Message)
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("envelope %s failed: %s", envelopeID, errMsg))
+	}
+
+	responseData := map[string]interface{}{
+		"envelope_id": envelopeID,
+		"status":      types.EnvelopeStatusSucceeded,
+		"result":      terminal.Payload,
+		"status_url":  r.envelopeURL(envelopeID, ""),
+	}
+	responseJSON, err := json.Marshal(responseData)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal response: %v", err))
+	}
+	return mcp.NewToolResultText(string(responseJSON))
+}
+
+// envelopeURL returns the client-facing path of an envelope endpoint,
+// e.g. envelopeURL(id, "/stream") for the SSE stream
+func (r *Registry) envelopeURL(envelopeID, suffix string) string {
+	return fmt.Sprintf("%s/envelopes/%s%s", r.basePath, envelopeID, suffix)
+}
+
+// createEnvelope validates the arguments for a tool call and stores a new pending envelope
+// routed to the actor at startStep of the tool's route (0 for the first actor), carrying
+// the client's route metadata (see validateRouteMetadata) to every actor.
+// The returned error message is safe to show to clients.
+func (r *Registry) createEnvelope(ctx context.Context, toolDef config.Tool, arguments map[string]any, startStep int, metadata map[string]any) (*types.Envelope, error) {
+	envelope, err := r.buildEnvelope(ctx, toolDef, arguments, startStep, metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope.ID = r.newID()
+	if envelope.Route.Metadata == nil {
+		envelope.Route.Metadata = make(map[string]interface{}, 1)
+	}
+	envelope.Route.Metadata["job_id"] = envelope.ID // For end queue tracking
+
+	// Store envelope
+	if err := r.jobStore.Create(envelope); err != nil {
+		slog.Error("Failed to create envelope", "error", err)
+		return nil, fmt.Errorf("failed to create envelope: %w", err)
+	}
+
+	return envelope, nil
+}
+
+// buildEnvelope validates the arguments for a tool call and assembles the envelope it
+// would create, without an ID and without storing it (see createEnvelope)
+func (r *Registry) buildEnvelope(ctx context.Context, toolDef config.Tool, arguments map[string]any, startStep int, metadata map[string]any) (*types.Envelope, error) {
+	// Resolve route actors
+	actors, err := toolDef.Route.GetActors(r.config.Routes)
+	if err != nil {
+		return nil, fmt.Errorf("route error: %w", err)
+	}
+	if err := validateRoute(actors, r.maxRouteSteps); err != nil {
+		return nil, fmt.Errorf("route error: %w", err)
+	}
+	actors = r.config.PickCanaryActors(actors)
+	if err := validateStartStep(startStep, actors); err != nil {
+		return nil, err
+	}
+	if err := validateRouteMetadata(metadata); err != nil {
+		return nil, err
+	}
+	if err := r.checkFirstQueue(ctx, actors[startStep]); err != nil {
+		return nil, err
+	}
+
+	// Get tool options (merged with defaults)
+	opts := toolDef.GetOptions(r.config.Defaults)
+
+	// Validate required parameters
+	for paramName, param := range toolDef.Parameters {
+		if param.Required {
+			if _, ok := arguments[paramName]; !ok {
+				return nil, fmt.Errorf("missing required parameter: %s", paramName)
+			}
+		}
+	}
+	if err := toolDef.ValidateArguments(arguments); err != nil {
+		return nil, err
+	}
+	arguments = toolDef.ApplyDefaults(arguments)
+
+	envelope := &types.Envelope{
+		Status: types.EnvelopeStatusPending,
+		Route: types.Route{
+			Actors:   actors,
+			Current:  startStep,
+			Metadata: copyRouteMetadata(metadata),
+		},
+		Tool:       toolDef.Name,
+		Exchange:   toolDef.Exchange,
+		Payload:    toolDef.BuildPayload(arguments),
+		TimeoutSec: int(opts.Timeout.Seconds()),
+	}
+
+	// Set deadline if timeout is configured
+	if opts.Timeout > 0 {
+		envelope.Deadline = time.Now().Add(opts.Timeout)
+	}
+
+	return envelope, nil
+}
+
+// ErrEmptyRoute is returned by tool handlers when the route of a call resolves to no actors:
+// the envelope could never be published, so it is not created
+var ErrEmptyRoute = errors.New("route cannot be empty")
+
+// validateRoute rejects empty routes, routes longer than maxSteps and routes containing
+// empty actor names
+func validateRoute(actors []string, maxSteps int) error {
+	if len(actors) == 0 {
+		return ErrEmptyRoute
+	}
+	if maxSteps > 0 && len(actors) > maxSteps {
+		return fmt.Errorf("route has %d actors, exceeds maximum of %d", len(actors), maxSteps)
+	}
+	for i, actor := range actors {
+		if strings.TrimSpace(actor) == "" {
+			return fmt.Errorf("route actor at index %d is empty", i)
+		}
+	}
+	return nil
+}
+
+// validateStartStep checks that a route can be started at the given actor index
+func validateStartStep(startStep int, actors []string) error {
+	if startStep < 0 || startStep >= len(actors) {
+		return fmt.Errorf("start_step %d out of range for route with %d actors", startStep, len(actors))
+	}
+	return nil
+}
+
+// MaxRouteMetadataBytes bounds the JSON size of the route metadata a client attaches to an
+// envelope: it travels with the envelope through every actor of the route
+const MaxRouteMetadataBytes = 16 * 1024
+
+// ErrInvalidRouteMetadata is wrapped by errors about the route metadata of a tool call, which
+// tool handlers return like ErrEmptyRoute so /tools/call can answer 400
+var ErrInvalidRouteMetadata = errors.New("invalid metadata")
+
+// validateRouteMetadata checks the route metadata of a tool call: keys must be non-empty,
+// job_id is set by the gateway and the metadata must fit in MaxRouteMetadataBytes
+func validateRouteMetadata(metadata map[string]any) error {
+	for key := range metadata {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("%w: keys cannot be empty", ErrInvalidRouteMetadata)
+		}
+		if key == "job_id" {
+			return fmt.Errorf("%w: key job_id is reserved", ErrInvalidRouteMetadata)
+		}
+	}
+	if len(metadata) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRouteMetadata, err)
+	}
+	if len(data) > MaxRouteMetadataBytes {
+		return fmt.Errorf("%w: %d bytes, exceeds maximum of %d", ErrInvalidRouteMetadata, len(data), MaxRouteMetadataBytes)
+	}
+	return nil
+}
+
+// copyRouteMetadata returns a copy of the client's route metadata, nil when there is none,
+// so the gateway's job_id is not added to the caller's map
+func copyRouteMetadata(metadata map[string]any) map[string]interface{} {
+	if len(metadata) == 0 {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(metadata)+1)
+	for key, value := range metadata {
+		copied[key] = value
+	}
+	return copied
+}
+
+// startStepKey is the context key of the route index a REST tool call starts at
+type startStepKey struct{}
+
+// withStartStep returns a context that makes tool handlers start the route at startStep,
+// skipping earlier actors. MCP tool calls always start at the first actor.
+func withStartStep(ctx context.Context, startStep int) context.Context {
+	return context.WithValue(ctx, startStepKey{}, startStep)
+}
+
+// startStepFromContext returns the route index set by withStartStep (0 when unset)
+func startStepFromContext(ctx context.Context) int {
+	startStep, _ := ctx.Value(startStepKey{}).(int)
+	return startStep
+}
+
+// routeMetadataKey is the context key of the route metadata of a REST tool call
+type routeMetadataKey struct{}
+
+// MetaRouteMetadata is the _meta field of an MCP tools/call request holding the route metadata
+// of the call, the MCP counterpart of the metadata field of POST /tools/call
+const MetaRouteMetadata = "asya.sh/metadata"
+
+// requestRouteMetadata returns the route metadata of a tool call: the metadata of a REST call
+// (see withRouteMetadata), or the MetaRouteMetadata object in the _meta of an MCP call
+func requestRouteMetadata(ctx context.Context, request mcp.CallToolRequest) (map[string]any, error) {
+	if metadata := routeMetadataFromContext(ctx); metadata != nil {
+		return metadata, nil
+	}
+	if request.Params.Meta == nil {
+		return nil, nil
+	}
+	value, ok := request.Params.Meta.AdditionalFields[MetaRouteMetadata]
+	if !ok || value == nil {
+		return nil, nil
+	}
+	metadata, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: _meta %q must be an object", ErrInvalidRouteMetadata, MetaRouteMetadata)
+	}
+	return metadata, nil
+}
+
+// withRouteMetadata returns a context that makes tool handlers attach metadata to the
+// route of the envelope they create. MCP tool calls carry theirs in _meta (see requestRouteMetadata).
+func withRouteMetadata(ctx context.Context, metadata map[string]any) context.Context {
+	return context.WithValue(ctx, routeMetadataKey{}, metadata)
+}
+
+// routeMetadataFromContext returns the route metadata set by withRouteMetadata (nil when unset)
+func routeMetadataFromContext(ctx context.Context) map[string]any {
+	metadata, _ := ctx.Value(routeMetadataKey{}).(map[string]any)
+	return metadata
+}
+
+// maxSyncWaitKey is the context key of the longest wait of a REST tool call for a sync reply
+type maxSyncWaitKey struct{}
+
+// withMaxSyncWait returns a context that makes synchronous tools wait at most maxWait
+// for their reply (0: no limit besides the tool's timeout)
+func withMaxSyncWait(ctx context.Context, maxWait time.Duration) context.Context {
+	return context.WithValue(ctx, maxSyncWaitKey{}, maxWait)
+}
+
+// syncWait returns how long a synchronous tool with the given timeout (0: unlimited)
+// waits for its reply, capped by withMaxSyncWait
+func syncWait(ctx context.Context, timeout time.Duration) time.Duration {
+	maxWait, _ := ctx.Value(maxSyncWaitKey{}).(time.Duration)
+	if maxWait > 0 && (timeout <= 0 || timeout > maxWait) {
+		return maxWait
+	}
+	return timeout
+}
+
+// dryRunKey is the context key marking a REST tool call as a dry run
+type dryRunKey struct{}
+
+// withDryRun returns a context that makes tool handlers return the route and payload
+// of the envelope they would create, without creating or publishing it
+func withDryRun(ctx context.Context, dryRun bool) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, dryRun)
+}
+
+// dryRunFromContext reports whether withDryRun marked the call as a dry run
+func dryRunFromContext(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
+
 // GetToolOptions returns the options for a specific tool by name
 func (r *Registry) GetToolOptions(toolName string) (*config.ToolOptions, error) {
-	for _, tool := range r.config.Tools {
-		if tool.Name == toolName {
-			opts := tool.GetOptions(r.config.Defaults)
-			return &opts, nil
-		}
+	tool, ok := r.findTool(toolName)
+	if !ok {
+		return nil, fmt.Errorf("tool %q not found", toolName)
 	}
-	return nil, fmt.Errorf("tool %q not found", toolName)
+	opts := tool.GetOptions(r.config.Defaults)
+	return &opts, nil
 }
@@ -2,7 +2,12 @@ package mcp
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -35,12 +40,17 @@ func (m *MockQueueClientWithError) Ack(ctx context.Context, msg queue.QueueMessa
 	return nil
 }
 
+func (m *MockQueueClientWithError) Nack(ctx context.Context, msg queue.QueueMessage) error {
+	return nil
+}
+
 func (m *MockQueueClientWithError) Close() error {
 	return nil
 }
 
 // MockJobStore for testing
 type MockJobStore struct {
+	mu        sync.Mutex
 	createErr error
 	updateErr error
 	envelopes map[string]*types.Envelope
@@ -53,6 +63,8 @@ func NewMockJobStore() *MockJobStore {
 }
 
 func (m *MockJobStore) Create(env *types.Envelope) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.createErr != nil {
 		return m.createErr
 	}
@@ -61,6 +73,8 @@ func (m *MockJobStore) Create(env *types.Envelope) error {
 }
 
 func (m *MockJobStore) Update(update types.EnvelopeUpdate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.updateErr != nil {
 		return m.updateErr
 	}
@@ -71,13 +85,38 @@ func (m *MockJobStore) Update(update types.EnvelopeUpdate) error {
 	return nil
 }
 
+func (m *MockJobStore) UpdateIfStatus(update types.EnvelopeUpdate, expected types.EnvelopeStatus) (bool, error) {
+	m.mu.Lock()
+	env, ok := m.envelopes[update.ID]
+	applies := ok && env.Status == expected
+	m.mu.Unlock()
+	if !applies {
+		return false, nil
+	}
+	return true, m.Update(update)
+}
+
 func (m *MockJobStore) Get(id string) (*types.Envelope, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if env, ok := m.envelopes[id]; ok {
-		return env, nil
+		snapshot := *env
+		return &snapshot, nil
 	}
 	return nil, fmt.Errorf("envelope not found")
 }
 
+// list returns copies of the stored envelopes
+func (m *MockJobStore) list() []types.Envelope {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	envelopes := make([]types.Envelope, 0, len(m.envelopes))
+	for _, env := range m.envelopes {
+		envelopes = append(envelopes, *env)
+	}
+	return envelopes
+}
+
 func (m *MockJobStore) AddProgress(id string, progress types.ProgressUpdate) error {
 	return nil
 }
@@ -100,17 +139,47 @@ func (m *MockJobStore) Unsubscribe(id string, ch chan types.EnvelopeUpdate) {
 }
 
 func (m *MockJobStore) IsActive(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	env, exists := m.envelopes[id]
 	if !exists {
 		return false
 	}
-	return env.Status == types.EnvelopeStatusPending || env.Status == types.EnvelopeStatusRunning
+	return env.Status == types.EnvelopeStatusPending || env.Status == types.EnvelopeStatusQueued || env.Status == types.EnvelopeStatusRunning
 }
 
 func (m *MockJobStore) GetUpdates(id string, since *time.Time) ([]types.EnvelopeUpdate, error) {
 	return []types.EnvelopeUpdate{}, nil
 }
 
+func (m *MockJobStore) ListQueuedBefore(cutoff time.Time) ([]envelopestore.QueuedEnvelope, error) {
+	return nil, nil
+}
+
+func (m *MockJobStore) ListActive(filter envelopestore.EnvelopeFilter) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ids []string
+	for id, envelope := range m.envelopes {
+		if envelope.Status != types.EnvelopeStatusSucceeded && envelope.Status != types.EnvelopeStatusFailed && filter.Matches(envelope) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (m *MockJobStore) GetChildren(parentID string) ([]*types.Envelope, error) {
+	return []*types.Envelope{}, nil
+}
+
+func (m *MockJobStore) AddFanoutBranch(parentID string, branches int) error {
+	return nil
+}
+
+func (m *MockJobStore) CompleteFanoutBranch(parentID string, branchIndex int) (int, int, error) {
+	return 0, 0, nil
+}
+
 // TestNewRegistry tests registry initialization
 func TestNewRegistry(t *testing.T) {
 	cfg := &config.Config{
@@ -256,6 +325,229 @@ func TestBuildParameterOptions(t *testing.T) {
 }
 
 // TestRegisterAll tests tool registration
+func TestBuildParameterOptions_Default(t *testing.T) {
+	registry := NewRegistry(&config.Config{}, envelopestore.NewStore(), &MockQueueClient{})
+
+	tests := []struct {
+		param config.Parameter
+		want  any
+	}{
+		{param: config.Parameter{Type: "string", Default: "photo"}, want: "photo"},
+		{param: config.Parameter{Type: "integer", Default: 5}, want: 5.0},
+		{param: config.Parameter{Type: "number", Default: 0.5}, want: 0.5},
+		{param: config.Parameter{Type: "boolean", Default: true}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.param.Type, func(t *testing.T) {
+			option, err := registry.buildParameterOptions("param", tt.param)
+			if err != nil {
+				t.Fatalf("buildParameterOptions() error = %v", err)
+			}
+			tool := mcp.NewTool("tool", option)
+			property, _ := tool.InputSchema.Properties["param"].(map[string]any)
+			if got := property["default"]; got != tt.want {
+				t.Errorf("default = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildParameterOptions_Enum(t *testing.T) {
+	registry := NewRegistry(&config.Config{}, envelopestore.NewStore(), &MockQueueClient{})
+
+	tests := []struct {
+		name  string
+		param config.Parameter
+		want  []any
+	}{
+		{name: "string options", param: config.Parameter{Type: "string", Options: []string{"pdf", "txt"}}, want: []any{"pdf", "txt"}},
+		{name: "string enum", param: config.Parameter{Type: "string", Enum: []any{"fast", "accurate"}}, want: []any{"fast", "accurate"}},
+		{name: "integer enum", param: config.Parameter{Type: "integer", Enum: []any{256, 512}}, want: []any{256, 512}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			option, err := registry.buildParameterOptions("param", tt.param)
+			if err != nil {
+				t.Fatalf("buildParameterOptions() error = %v", err)
+			}
+			tool := mcp.NewTool("tool", option)
+			property, _ := tool.InputSchema.Properties["param"].(map[string]any)
+			if got := property["enum"]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("enum = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildParameterOptions_Constraints(t *testing.T) {
+	registry := NewRegistry(&config.Config{}, envelopestore.NewStore(), &MockQueueClient{})
+	minimum, maximum, minLength, maxLength := 1.0, 100.0, 2, 64
+
+	tests := []struct {
+		name  string
+		param config.Parameter
+		want  map[string]any
+	}{
+		{
+			name:  "number range",
+			param: config.Parameter{Type: "integer", Minimum: &minimum, Maximum: &maximum},
+			want:  map[string]any{"minimum": 1.0, "maximum": 100.0},
+		},
+		{
+			name:  "string length and pattern",
+			param: config.Parameter{Type: "string", MinLength: &minLength, MaxLength: &maxLength, Pattern: "^[a-z]+$"},
+			want:  map[string]any{"minLength": 2, "maxLength": 64, "pattern": "^[a-z]+$"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			option, err := registry.buildParameterOptions("param", tt.param)
+			if err != nil {
+				t.Fatalf("buildParameterOptions() error = %v", err)
+			}
+			tool := mcp.NewTool("tool", option)
+			property, _ := tool.InputSchema.Properties["param"].(map[string]any)
+			for key, want := range tt.want {
+				if got := property[key]; got != want {
+					t.Errorf("%s = %#v, want %#v", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildParameterOptions_NestedObject(t *testing.T) {
+	registry := NewRegistry(&config.Config{}, envelopestore.NewStore(), &MockQueueClient{})
+
+	option, err := registry.buildParameterOptions("render", config.Parameter{
+		Type:     "object",
+		Required: true,
+		Properties: map[string]config.Parameter{
+			"quality": {Type: "string", Required: true, Enum: []any{"low", "high"}},
+			"size": {Type: "object", Properties: map[string]config.Parameter{
+				"width": {Type: "integer", Required: true},
+			}},
+			"layers": {Type: "array", Items: &config.Parameter{Type: "object", Properties: map[string]config.Parameter{
+				"name": {Type: "string", Required: true},
+			}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("buildParameterOptions() error = %v", err)
+	}
+	tool := mcp.NewTool("tool", option)
+
+	if !reflect.DeepEqual(tool.InputSchema.Required, []string{"render"}) {
+		t.Errorf("Required = %v, want [render]", tool.InputSchema.Required)
+	}
+
+	want := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"quality": map[string]any{"type": "string", "enum": []any{"low", "high"}},
+			"size": map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"width": map[string]any{"type": "integer"}},
+				"required":   []string{"width"},
+			},
+			"layers": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"name": map[string]any{"type": "string"}},
+					"required":   []string{"name"},
+				},
+			},
+		},
+		"required": []string{"quality"},
+	}
+	if got := tool.InputSchema.Properties["render"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Schema = %#v, want %#v", got, want)
+	}
+}
+
+func TestBuildParameterOptions_ItemsEnum(t *testing.T) {
+	registry := NewRegistry(&config.Config{}, envelopestore.NewStore(), &MockQueueClient{})
+
+	option, err := registry.buildParameterOptions("formats", config.Parameter{
+		Type:  "array",
+		Items: &config.Parameter{Type: "string", Enum: []any{"png", "jpg"}},
+	})
+	if err != nil {
+		t.Fatalf("buildParameterOptions() error = %v", err)
+	}
+	tool := mcp.NewTool("tool", option)
+	property, _ := tool.InputSchema.Properties["formats"].(map[string]any)
+	items, _ := property["items"].(map[string]any)
+	if got, want := items["enum"], []any{"png", "jpg"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("items enum = %#v, want %#v", got, want)
+	}
+}
+
+func TestCreateToolHandler_RejectsValueOutsideEnum(t *testing.T) {
+	toolDef := config.Tool{
+		Name: "summarize",
+		Parameters: map[string]config.Parameter{
+			"model": {Type: "string", Enum: []any{"fast", "accurate"}},
+		},
+		Route: config.RouteSpec{Actors: []string{"summarizer"}},
+	}
+
+	jobStore := NewMockJobStore()
+	registry := NewRegistry(&config.Config{Tools: []config.Tool{toolDef}}, jobStore, &MockQueueClient{})
+
+	result, err := registry.createToolHandler(toolDef)(context.Background(), createCallToolRequest(map[string]interface{}{
+		"model": "slow",
+	}))
+	if err != nil {
+		t.Fatalf("Handler returned error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("Expected error result")
+	}
+	want := `invalid parameter model: "slow" is not one of "fast", "accurate"`
+	if got := result.Content[0].(mcp.TextContent).Text; got != want {
+		t.Errorf("Error = %q, want %q", got, want)
+	}
+	if len(jobStore.envelopes) != 0 {
+		t.Errorf("Expected no envelope, got %d", len(jobStore.envelopes))
+	}
+}
+
+func TestCreateToolHandler_RejectsNumberSentAsString(t *testing.T) {
+	maximum := 100.0
+	toolDef := config.Tool{
+		Name: "generate",
+		Parameters: map[string]config.Parameter{
+			"count": {Type: "integer", Maximum: &maximum},
+		},
+		Route: config.RouteSpec{Actors: []string{"generator"}},
+	}
+
+	jobStore := NewMockJobStore()
+	registry := NewRegistry(&config.Config{Tools: []config.Tool{toolDef}}, jobStore, &MockQueueClient{})
+
+	result, err := registry.createToolHandler(toolDef)(context.Background(), createCallToolRequest(map[string]interface{}{
+		"count": "500",
+	}))
+	if err != nil {
+		t.Fatalf("Handler returned error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("A string count should not bypass the maximum")
+	}
+	want := `invalid parameter count: "500" is not of type integer`
+	if got := result.Content[0].(mcp.TextContent).Text; got != want {
+		t.Errorf("Error = %q, want %q", got, want)
+	}
+	if len(jobStore.envelopes) != 0 {
+		t.Errorf("Expected no envelope, got %d", len(jobStore.envelopes))
+	}
+}
+
 func TestRegisterAll(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -735,6 +1027,10 @@ func TestEnvelopeCreation(t *testing.T) {
 			}
 
 			for _, env := range jobStore.envelopes {
+				if env.Tool != tt.toolDef.Name {
+					t.Errorf("Expected envelope tool %q, got %q", tt.toolDef.Name, env.Tool)
+				}
+
 				if tt.expectDeadline {
 					if env.Deadline.IsZero() {
 						t.Error("Expected deadline to be set")
@@ -820,11 +1116,12 @@ func TestQueueSendFailure(t *testing.T) {
 
 	time.Sleep(100 * time.Millisecond)
 
-	if len(jobStore.envelopes) != 1 {
-		t.Fatalf("Expected 1 envelope in store, got %d", len(jobStore.envelopes))
+	envelopes := jobStore.list()
+	if len(envelopes) != 1 {
+		t.Fatalf("Expected 1 envelope in store, got %d", len(envelopes))
 	}
 
-	for _, env := range jobStore.envelopes {
+	for _, env := range envelopes {
 		if env.Status != types.EnvelopeStatusFailed {
 			t.Errorf("Expected envelope status to be Failed, got %v", env.Status)
 		}
@@ -834,6 +1131,269 @@ func TestQueueSendFailure(t *testing.T) {
 	}
 }
 
+// blockingQueueClient blocks SendEnvelope until released, to observe state before publish completes
+type blockingQueueClient struct {
+	MockQueueClient
+	release chan struct{}
+}
+
+func (m *blockingQueueClient) SendEnvelope(ctx context.Context, envelope *types.Envelope) error {
+	select {
+	case <-m.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TestEnvelopeStatusLifecycle tests pending -> queued (after publish) -> running (first progress)
+func TestEnvelopeStatusLifecycle(t *testing.T) {
+	toolDef := config.Tool{
+		Name:  "test_tool",
+		Route: config.RouteSpec{Actors: []string{"actor1"}},
+	}
+
+	cfg := &config.Config{
+		Tools: []config.Tool{toolDef},
+	}
+
+	jobStore := envelopestore.NewStore()
+	queueClient := &blockingQueueClient{release: make(chan struct{})}
+
+	registry := NewRegistry(cfg, jobStore, queueClient)
+	handler := registry.createToolHandler(toolDef)
+
+	result, err := handler(context.Background(), createCallToolRequest(map[string]interface{}{}))
+	if err != nil {
+		t.Fatalf("Handler returned error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Expected success result, got error: %v", result.Content)
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	envelopeID := response["envelope_id"].(string)
+
+	// Before publish completes: pending
+	envelope, err := jobStore.Get(envelopeID)
+	if err != nil {
+		t.Fatalf("Envelope not found: %v", err)
+	}
+	if envelope.Status != types.EnvelopeStatusPending {
+		t.Errorf("Status before publish = %v, want %v", envelope.Status, types.EnvelopeStatusPending)
+	}
+
+	// After publish: queued
+	close(queueClient.release)
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) && !isStatus(jobStore, envelopeID, types.EnvelopeStatusQueued) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !isStatus(jobStore, envelopeID, types.EnvelopeStatusQueued) {
+		t.Fatalf("Status after publish = %v, want %v", mustGetStatus(t, jobStore, envelopeID), types.EnvelopeStatusQueued)
+	}
+
+	// First progress report: running
+	sendProgressUpdate(t, NewHandler(jobStore), envelopeID, []string{"actor1"}, 0, "received")
+	if status := mustGetStatus(t, jobStore, envelopeID); status != types.EnvelopeStatusRunning {
+		t.Errorf("Status after first progress = %v, want %v", status, types.EnvelopeStatusRunning)
+	}
+}
+
+func isStatus(store envelopestore.EnvelopeStore, id string, status types.EnvelopeStatus) bool {
+	envelope, err := store.Get(id)
+	return err == nil && envelope.Status == status
+}
+
+func mustGetStatus(t *testing.T, store envelopestore.EnvelopeStore, id string) types.EnvelopeStatus {
+	t.Helper()
+	envelope, err := store.Get(id)
+	if err != nil {
+		t.Fatalf("Envelope not found: %v", err)
+	}
+	return envelope.Status
+}
+
+// TestValidateRoute tests route length and actor name validation
+func TestValidateRoute(t *testing.T) {
+	tests := []struct {
+		name     string
+		actors   []string
+		maxSteps int
+		wantErr  bool
+	}{
+		{name: "empty", actors: []string{}, maxSteps: 10, wantErr: true},
+		{name: "within limit", actors: []string{"a", "b"}, maxSteps: 2},
+		{name: "exceeds limit", actors: []string{"a", "b", "c"}, maxSteps: 2, wantErr: true},
+		{name: "limit disabled", actors: []string{"a", "b", "c"}, maxSteps: 0},
+		{name: "empty actor name", actors: []string{"a", ""}, maxSteps: 10, wantErr: true},
+		{name: "blank actor name", actors: []string{"  ", "b"}, maxSteps: 10, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRoute(tt.actors, tt.maxSteps)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateRoute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestCreateToolHandler_MaxRouteSteps tests that oversize routes are rejected before envelope creation
+func TestCreateToolHandler_MaxRouteSteps(t *testing.T) {
+	toolDef := config.Tool{
+		Name:  "long_route_tool",
+		Route: config.RouteSpec{Actors: []string{"actor1", "actor2", "actor3"}},
+	}
+
+	jobStore := NewMockJobStore()
+	registry := NewRegistry(&config.Config{Tools: []config.Tool{toolDef}}, jobStore, &MockQueueClient{})
+	registry.maxRouteSteps = 2
+
+	result, err := registry.createToolHandler(toolDef)(context.Background(), createCallToolRequest(map[string]interface{}{}))
+	if err != nil {
+		t.Fatalf("Handler returned error: %v", err)
+	}
+	if !result.IsError {
+		t.Error("Expected error result for oversize route")
+	}
+	if len(jobStore.envelopes) != 0 {
+		t.Errorf("Expected no envelope to be created, got %d", len(jobStore.envelopes))
+	}
+}
+
+// TestCreateToolHandler_BasePath tests that returned URLs include the configured base path
+func TestCreateToolHandler_BasePath(t *testing.T) {
+	toolDef := config.Tool{
+		Name:     "progress_tool",
+		Route:    config.RouteSpec{Actors: []string{"actor1"}},
+		Progress: boolPtr(true),
+	}
+
+	registry := NewRegistry(&config.Config{Tools: []config.Tool{toolDef}}, NewMockJobStore(), &MockQueueClient{})
+	registry.basePath = "/asya"
+
+	result, err := registry.createToolHandler(toolDef)(context.Background(), createCallToolRequest(map[string]interface{}{}))
+	if err != nil {
+		t.Fatalf("Handler returned error: %v", err)
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	envelopeID := response["envelope_id"]
+	if got, want := response["status_url"], fmt.Sprintf("/asya/envelopes/%s", envelopeID); got != want {
+		t.Errorf("status_url = %v, want %v", got, want)
+	}
+	if got, want := response["stream_url"], fmt.Sprintf("/asya/envelopes/%s/stream", envelopeID); got != want {
+		t.Errorf("stream_url = %v, want %v", got, want)
+	}
+}
+
+func TestCreateToolHandler_IDGenerator(t *testing.T) {
+	toolDef := config.Tool{
+		Name:  "test_tool",
+		Route: config.RouteSpec{Actors: []string{"actor1"}},
+	}
+
+	jobStore := NewMockJobStore()
+	registry := NewRegistry(&config.Config{Tools: []config.Tool{toolDef}}, jobStore, &MockQueueClient{})
+	registry.newID = func() string { return "job_custom-1" }
+
+	result, err := registry.createToolHandler(toolDef)(context.Background(), createCallToolRequest(map[string]interface{}{}))
+	if err != nil {
+		t.Fatalf("Handler returned error: %v", err)
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if got := response["envelope_id"]; got != "job_custom-1" {
+		t.Errorf("envelope_id = %v, want job_custom-1", got)
+	}
+	if _, err := jobStore.Get("job_custom-1"); err != nil {
+		t.Errorf("Envelope not stored under generated ID: %v", err)
+	}
+}
+
+func TestCreateToolHandler_PayloadTemplate(t *testing.T) {
+	toolDef := config.Tool{
+		Name: "generate",
+		Parameters: map[string]config.Parameter{
+			"description": {Type: "string", Required: true},
+			"count":       {Type: "number"},
+		},
+		Route: config.RouteSpec{Actors: []string{"image-generator"}},
+		PayloadTemplate: map[string]any{
+			"image":  map[string]any{"prompt": "${description}", "n": "${count}"},
+			"source": "gateway",
+		},
+	}
+
+	jobStore := NewMockJobStore()
+	registry := NewRegistry(&config.Config{Tools: []config.Tool{toolDef}}, jobStore, &MockQueueClient{})
+	registry.newID = func() string { return "env-1" }
+
+	_, err := registry.createToolHandler(toolDef)(context.Background(), createCallToolRequest(map[string]interface{}{
+		"description": "a cat",
+		"count":       3.0,
+	}))
+	if err != nil {
+		t.Fatalf("Handler returned error: %v", err)
+	}
+
+	envelope, err := jobStore.Get("env-1")
+	if err != nil {
+		t.Fatalf("Envelope not stored: %v", err)
+	}
+	want := map[string]any{
+		"image":  map[string]any{"prompt": "a cat", "n": 3.0},
+		"source": "gateway",
+	}
+	if !reflect.DeepEqual(envelope.Payload, want) {
+		t.Errorf("Payload = %#v, want %#v", envelope.Payload, want)
+	}
+}
+
+func TestCreateToolHandler_ParameterDefaults(t *testing.T) {
+	toolDef := config.Tool{
+		Name: "generate",
+		Parameters: map[string]config.Parameter{
+			"description": {Type: "string", Required: true},
+			"count":       {Type: "integer", Default: 5},
+			"style":       {Type: "string", Default: "photo"},
+		},
+		Route: config.RouteSpec{Actors: []string{"image-generator"}},
+	}
+
+	jobStore := NewMockJobStore()
+	registry := NewRegistry(&config.Config{Tools: []config.Tool{toolDef}}, jobStore, &MockQueueClient{})
+	registry.newID = func() string { return "env-1" }
+
+	_, err := registry.createToolHandler(toolDef)(context.Background(), createCallToolRequest(map[string]interface{}{
+		"description": "a cat",
+		"style":       "anime",
+	}))
+	if err != nil {
+		t.Fatalf("Handler returned error: %v", err)
+	}
+
+	envelope, err := jobStore.Get("env-1")
+	if err != nil {
+		t.Fatalf("Envelope not stored: %v", err)
+	}
+	want := map[string]any{"description": "a cat", "count": 5, "style": "anime"}
+	if !reflect.DeepEqual(envelope.Payload, want) {
+		t.Errorf("Payload = %#v, want %#v", envelope.Payload, want)
+	}
+}
+
 // Helper functions
 
 func createCallToolRequest(args map[string]interface{}) mcp.CallToolRequest {
@@ -849,3 +1409,222 @@ func intPtr(i int) *int {
 func boolPtr(b bool) *bool {
 	return &b
 }
+
+// replyingQueueClient answers every published envelope on its reply queue
+type replyingQueueClient struct {
+	MockQueueClient
+	replyQueue *queue.ReplyQueue
+	reply      *types.TerminalMessage // nil: never reply
+	replyTo    string
+}
+
+func (m *replyingQueueClient) SendEnvelope(ctx context.Context, envelope *types.Envelope) error {
+	m.replyTo = envelope.ReplyTo
+	if m.reply != nil {
+		reply := *m.reply
+		reply.ID = envelope.ID
+		body, _ := json.Marshal(reply)
+		go m.replyQueue.Deliver(envelope.ID, body)
+	}
+	return nil
+}
+
+func TestCreateToolHandler_Sync(t *testing.T) {
+	timeout := 1
+	toolDef := config.Tool{
+		Name:    "test_tool",
+		Route:   config.RouteSpec{Actors: []string{"actor1"}},
+		Sync:    true,
+		Timeout: &timeout,
+	}
+
+	tests := []struct {
+		name        string
+		reply       *types.TerminalMessage
+		wantError   bool
+		wantText    string
+		wantResult  bool
+		wantPending bool
+	}{
+		{
+			name:       "succeeded",
+			reply:      &types.TerminalMessage{Status: types.EnvelopeStatusSucceeded, Payload: map[string]any{"answer": 42.0}},
+			wantResult: true,
+		},
+		{
+			name:      "failed",
+			reply:     &types.TerminalMessage{Status: types.EnvelopeStatusFailed, Error: "processing_error", Details: &types.TerminalErrorDetails{Message: "bad input"}},
+			wantError: true,
+			wantText:  "processing_error: bad input",
+		},
+		{
+			name:        "no reply in time falls back to async response",
+			wantPending: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replyQueue := queue.NewReplyQueue("amq.gen-test")
+			queueClient := &replyingQueueClient{replyQueue: replyQueue, reply: tt.reply}
+			registry := NewRegistry(&config.Config{Tools: []config.Tool{toolDef}}, NewMockJobStore(), queueClient)
+			registry.replyQueue = replyQueue
+
+			result, err := registry.createToolHandler(toolDef)(context.Background(), createCallToolRequest(map[string]interface{}{}))
+			if err != nil {
+				t.Fatalf("Handler returned error: %v", err)
+			}
+			if queueClient.replyTo != "amq.gen-test" {
+				t.Errorf("Envelope published with reply_to %q, want amq.gen-test", queueClient.replyTo)
+			}
+			if result.IsError != tt.wantError {
+				t.Fatalf("IsError = %v, want %v: %v", result.IsError, tt.wantError, result.Content)
+			}
+
+			text := result.Content[0].(mcp.TextContent).Text
+			if tt.wantText != "" && !strings.Contains(text, tt.wantText) {
+				t.Errorf("Result %q should contain %q", text, tt.wantText)
+			}
+			if tt.wantError {
+				return
+			}
+
+			var response map[string]interface{}
+			if err := json.Unmarshal([]byte(text), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if tt.wantResult {
+				if response["status"] != string(types.EnvelopeStatusSucceeded) {
+					t.Errorf("status = %v, want succeeded", response["status"])
+				}
+				if result, ok := response["result"].(map[string]interface{}); !ok || result["answer"] != 42.0 {
+					t.Errorf("result = %v, want the replied payload", response["result"])
+				}
+			}
+			if tt.wantPending {
+				if _, ok := response["result"]; ok {
+					t.Errorf("Async fallback should not carry a result: %v", response)
+				}
+				if response["status_url"] == nil {
+					t.Errorf("Async fallback should carry status_url: %v", response)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateToolHandler_SyncWaitCappedByContext(t *testing.T) {
+	timeout := 300
+	toolDef := config.Tool{
+		Name:    "test_tool",
+		Route:   config.RouteSpec{Actors: []string{"actor1"}},
+		Sync:    true,
+		Timeout: &timeout,
+	}
+
+	replyQueue := queue.NewReplyQueue("amq.gen-test")
+	registry := NewRegistry(&config.Config{Tools: []config.Tool{toolDef}}, NewMockJobStore(), &replyingQueueClient{replyQueue: replyQueue})
+	registry.replyQueue = replyQueue
+
+	ctx := withMaxSyncWait(context.Background(), 10*time.Millisecond)
+	done := make(chan *mcp.CallToolResult, 1)
+	go func() {
+		result, _ := registry.createToolHandler(toolDef)(ctx, createCallToolRequest(map[string]interface{}{}))
+		done <- result
+	}()
+
+	select {
+	case result := <-done:
+		if result == nil || result.IsError {
+			t.Fatalf("Expected async response, got %v", result)
+		}
+		if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "status_url") {
+			t.Errorf("Async fallback should carry status_url: %s", text)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Sync wait should be capped by the context's max wait, not the tool's timeout")
+	}
+}
+
+func TestSyncWait(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxWait time.Duration
+		timeout time.Duration
+		want    time.Duration
+	}{
+		{name: "no limit", timeout: time.Minute, want: time.Minute},
+		{name: "timeout within limit", maxWait: time.Minute, timeout: 30 * time.Second, want: 30 * time.Second},
+		{name: "timeout above limit", maxWait: 55 * time.Second, timeout: 5 * time.Minute, want: 55 * time.Second},
+		{name: "unlimited timeout", maxWait: 55 * time.Second, want: 55 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := withMaxSyncWait(context.Background(), tt.maxWait)
+			if got := syncWait(ctx, tt.timeout); got != tt.want {
+				t.Errorf("syncWait() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestToolHandler_MetaRouteMetadata tests that MCP tool calls carry route metadata in _meta
+func TestToolHandler_MetaRouteMetadata(t *testing.T) {
+	tests := []struct {
+		name         string
+		meta         *mcp.Meta
+		wantErr      bool
+		wantMetadata map[string]interface{} // Without job_id
+	}{
+		{name: "no _meta", wantMetadata: map[string]interface{}{}},
+		{name: "other _meta fields", meta: &mcp.Meta{AdditionalFields: map[string]any{"trace": "t-1"}}, wantMetadata: map[string]interface{}{}},
+		{
+			name:         "route metadata",
+			meta:         &mcp.Meta{AdditionalFields: map[string]any{MetaRouteMetadata: map[string]any{"user_id": "u-1"}}},
+			wantMetadata: map[string]interface{}{"user_id": "u-1"},
+		},
+		{name: "not an object", meta: &mcp.Meta{AdditionalFields: map[string]any{MetaRouteMetadata: "u-1"}}, wantErr: true},
+		{name: "reserved job_id", meta: &mcp.Meta{AdditionalFields: map[string]any{MetaRouteMetadata: map[string]any{"job_id": "spoofed"}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := envelopestore.NewStore()
+			server := NewServer(store, nil, &config.Config{
+				Tools: []config.Tool{
+					{Name: "pipeline", Route: config.RouteSpec{Actors: []string{"prep", "infer"}}},
+				},
+			})
+
+			request := mcp.CallToolRequest{Params: mcp.CallToolParams{Name: "pipeline", Arguments: map[string]any{}, Meta: tt.meta}}
+			result, err := server.registry.GetToolHandler("pipeline")(context.Background(), request)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidRouteMetadata) {
+					t.Fatalf("error = %v, want ErrInvalidRouteMetadata", err)
+				}
+				if ids, _ := store.ListActive(envelopestore.EnvelopeFilter{}); len(ids) != 0 {
+					t.Errorf("envelopes created = %v, want none", ids)
+				}
+				return
+			}
+			if err != nil || result.IsError {
+				t.Fatalf("tool call failed: %v %+v", err, result)
+			}
+
+			var response map[string]interface{}
+			if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &response); err != nil {
+				t.Fatalf("Failed to parse tool response: %v", err)
+			}
+			envelope, err := store.Get(response["envelope_id"].(string))
+			if err != nil {
+				t.Fatalf("Envelope not found: %v", err)
+			}
+
+			tt.wantMetadata["job_id"] = envelope.ID
+			if !reflect.DeepEqual(envelope.Route.Metadata, tt.wantMetadata) {
+				t.Errorf("Route.Metadata = %v, want %v", envelope.Route.Metadata, tt.wantMetadata)
+			}
+		})
+	}
+}
@@ -3,16 +3,18 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
-	"time"
+	"log/slog"
+	"os"
+	"strings"
 
-	"github.com/google/uuid"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 
 	"github.com/deliveryhero/asya/asya-gateway/internal/config"
 	"github.com/deliveryhero/asya/asya-gateway/internal/envelopestore"
+	"github.com/deliveryhero/asya/asya-gateway/internal/idgen"
 	"github.com/deliveryhero/asya/asya-gateway/internal/queue"
 	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
 )
@@ -38,6 +40,7 @@ func NewServer(jobStore envelopestore.EnvelopeStore, queueClient queue.Client, c
 		"asya-gateway",
 		"0.1.0",
 		server.WithToolCapabilities(false), // Tools don't change at runtime
+		server.WithToolHandlerMiddleware(toolErrorMiddleware),
 	)
 
 	// Always create registry for /tools/call REST endpoint support
@@ -45,11 +48,12 @@ func NewServer(jobStore envelopestore.EnvelopeStore, queueClient queue.Client, c
 		// Use registry for dynamic tool registration
 		s.registry = NewRegistry(cfg, jobStore, queueClient)
 		if err := s.registry.RegisterAll(s.mcpServer); err != nil {
-			log.Fatalf("Failed to register tools from config: %v", err)
+			slog.Error("Failed to register tools from config", "error", err)
+			os.Exit(1)
 		}
 	} else {
 		// Fallback to hardcoded tools for backward compatibility
-		log.Println("No config provided, using default empty list of tools")
+		slog.Info("No config provided, using default empty list of tools")
 		// Create empty registry to support REST API
 		s.registry = NewRegistry(&config.Config{Tools: []config.Tool{}}, jobStore, queueClient)
 		s.registry.mcpServer = s.mcpServer
@@ -100,21 +104,38 @@ func (s *Server) handleProcessImageWorkflow(ctx context.Context, request mcp.Cal
 		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	if len(route) == 0 {
-		return mcp.NewToolResultError("route cannot be empty"), nil
+	// The route is an argument of this tool, so an empty one is reported like other invalid arguments
+	if err := validateRoute(route, s.registry.maxRouteSteps); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	startStep := startStepFromContext(ctx)
+	if err := validateStartStep(startStep, route); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	metadata, err := requestRouteMetadata(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := validateRouteMetadata(metadata); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := s.registry.checkFirstQueue(ctx, route[startStep]); err != nil {
+		if errors.Is(err, ErrQueueUnavailable) {
+			return nil, err
+		}
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
 	// Extract optional parameters with defaults
 	count := request.GetFloat("count", 5.0)
 	timeout := request.GetFloat("timeout", 0.0)
 
-	// Create envelope
-	envelopeID := uuid.New().String()
 	envelope := &types.Envelope{
-		ID: envelopeID,
+		Tool: "processImageWorkflow",
 		Route: types.Route{
-			Actors:  route,
-			Current: 0,
+			Actors:   route,
+			Current:  startStep,
+			Metadata: copyRouteMetadata(metadata),
 		},
 		Payload: map[string]any{
 			"description": description,
@@ -122,44 +143,38 @@ func (s *Server) handleProcessImageWorkflow(ctx context.Context, request mcp.Cal
 		},
 		TimeoutSec: int(timeout),
 	}
+	if dryRunFromContext(ctx) {
+		return dryRunResult(envelope), nil
+	}
+
+	release, ok := s.registry.acquirePublish()
+	if !ok {
+		return nil, ErrPublishSaturated
+	}
+
+	// Create envelope
+	envelopeID := s.registry.newID()
+	envelope.ID = envelopeID
 
 	// Store envelope
 	if err := s.jobStore.Create(envelope); err != nil {
-		log.Printf("Failed to create envelope: %v", err)
+		release()
+		if errors.Is(err, envelopestore.ErrTooManyActive) {
+			return nil, err
+		}
+		slog.Error("Failed to create envelope", "error", err)
 		return mcp.NewToolResultError(fmt.Sprintf("failed to create envelope: %v", err)), nil
 	}
 
-	// Send to queue (async)
-	go func() {
-		// Update status to Running
-		_ = s.jobStore.Update(types.EnvelopeUpdate{
-			ID:        envelopeID,
-			Status:    types.EnvelopeStatusRunning,
-			Message:   "Sending envelope to first actor",
-			Timestamp: time.Now(),
-		})
-
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		if err := s.queueClient.SendEnvelope(ctx, envelope); err != nil {
-			log.Printf("Failed to send envelope to queue: %v", err)
-			_ = s.jobStore.Update(types.EnvelopeUpdate{
-				ID:        envelopeID,
-				Status:    types.EnvelopeStatusFailed,
-				Error:     fmt.Sprintf("failed to send envelope: %v", err),
-				Timestamp: time.Now(),
-			})
-			return
-		}
-	}()
+	// Mark as queued and send to queue (async)
+	enqueueEnvelope(s.jobStore, s.queueClient, envelope, release)
 
 	// Build MCP-compliant structured response
 	responseData := map[string]interface{}{
 		"envelope_id": envelopeID,
 		"message":     "Envelope created successfully",
-		"status_url":  fmt.Sprintf("/envelopes/%s", envelopeID),
-		"stream_url":  fmt.Sprintf("/envelopes/%s/stream", envelopeID),
+		"status_url":  s.registry.envelopeURL(envelopeID, ""),
+		"stream_url":  s.registry.envelopeURL(envelopeID, "/stream"),
 	}
 
 	// Convert to JSON string for text content
@@ -171,6 +186,72 @@ func (s *Server) handleProcessImageWorkflow(ctx context.Context, request mcp.Cal
 	return mcp.NewToolResultText(string(responseJSON)), nil
 }
 
+// toolErrorMiddleware converts tool handler errors into MCP error results (isError content)
+// so MCP clients get the same error semantics as the REST /tools/call endpoint
+func toolErrorMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := next(ctx, request)
+		if err != nil {
+			slog.ErrorContext(ctx, "Tool call failed", "tool", request.Params.Name, "error", err)
+			return mcp.NewToolResultError(fmt.Sprintf("tool call failed: %v", err)), nil
+		}
+		return result, nil
+	}
+}
+
+// SetMaxRouteSteps sets the maximum number of actors allowed in a route at envelope creation
+// (0 disables the length check)
+func (s *Server) SetMaxRouteSteps(maxSteps int) {
+	s.registry.maxRouteSteps = maxSteps
+}
+
+// SetMaxPendingPublishes sets how many asynchronous tool calls may be publishing their
+// envelope at once; further calls fail with ErrPublishSaturated (503 on /tools/call).
+// A limit of 0 or less means unlimited. Must not be called while tool calls are served.
+func (s *Server) SetMaxPendingPublishes(maxPending int) {
+	s.registry.publishSlots = newSlots(maxPending)
+}
+
+// SetCheckQueues sets whether tool calls verify that the queue of the actor an envelope
+// starts at exists before creating the envelope (default true). Calls to a missing queue
+// return an error result; calls whose queue cannot be checked fail with ErrQueueUnavailable
+// (503 on /tools/call). Only queue clients implementing queue.Inspector are checked.
+func (s *Server) SetCheckQueues(enabled bool) {
+	s.registry.checkQueues = enabled
+}
+
+// SetReplyQueue sets the queue receiving the outcomes of synchronous tool calls (tools with sync: true).
+// Without one, synchronous tools respond like asynchronous ones.
+func (s *Server) SetReplyQueue(replyQueue *queue.ReplyQueue) {
+	s.registry.replyQueue = replyQueue
+}
+
+// SetIDGenerator sets how envelope IDs are generated (random UUIDs by default)
+func (s *Server) SetIDGenerator(newID idgen.Generator) {
+	s.registry.newID = newID
+}
+
+// SetBasePath sets the path prefix the gateway is mounted under (e.g. "/asya")
+// so that status and stream URLs in tool responses resolve behind an ingress.
+// The prefix is normalized to a leading slash without a trailing one; "" or "/" means root.
+func (s *Server) SetBasePath(basePath string) {
+	s.registry.basePath = NormalizeBasePath(basePath)
+}
+
+// BasePath returns the normalized path prefix set by SetBasePath
+func (s *Server) BasePath() string {
+	return s.registry.basePath
+}
+
+// NormalizeBasePath converts a configured base path to "/prefix" form ("" for root)
+func NormalizeBasePath(basePath string) string {
+	basePath = strings.Trim(strings.TrimSpace(basePath), "/")
+	if basePath == "" {
+		return ""
+	}
+	return "/" + basePath
+}
+
 // GetMCPServer returns the underlying MCP server for HTTP integration
 func (s *Server) GetMCPServer() *server.MCPServer {
 	return s.mcpServer
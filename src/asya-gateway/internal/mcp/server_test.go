@@ -2,8 +2,12 @@ package mcp
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 
+	"github.com/mark3labs/mcp-go/mcp"
+
 	"github.com/deliveryhero/asya/asya-gateway/internal/config"
 	"github.com/deliveryhero/asya/asya-gateway/internal/envelopestore"
 	"github.com/deliveryhero/asya/asya-gateway/internal/queue"
@@ -25,6 +29,10 @@ func (m *MockQueueClient) Ack(ctx context.Context, msg queue.QueueMessage) error
 	return nil
 }
 
+func (m *MockQueueClient) Nack(ctx context.Context, msg queue.QueueMessage) error {
+	return nil
+}
+
 func (m *MockQueueClient) Close() error {
 	return nil
 }
@@ -333,3 +341,45 @@ func TestNewServer_InvalidConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestToolErrorMiddleware(t *testing.T) {
+	failing := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return nil, errors.New("boom")
+	}
+
+	req := mcp.CallToolRequest{Params: mcp.CallToolParams{Name: "failing_tool"}}
+	result, err := toolErrorMiddleware(failing)(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected error to be converted into result, got: %v", err)
+	}
+	if result == nil || !result.IsError {
+		t.Fatalf("Expected isError result, got %+v", result)
+	}
+
+	text, ok := result.Content[0].(mcp.TextContent)
+	if !ok || !strings.Contains(text.Text, "boom") {
+		t.Errorf("Expected error text to contain original error, got %+v", result.Content[0])
+	}
+}
+
+func TestNormalizeBasePath(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "", want: ""},
+		{input: "/", want: ""},
+		{input: "asya", want: "/asya"},
+		{input: "/asya", want: "/asya"},
+		{input: "/asya/", want: "/asya"},
+		{input: " /team/asya/ ", want: "/team/asya"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := NormalizeBasePath(tt.input); got != tt.want {
+				t.Errorf("NormalizeBasePath(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+// Package metrics exposes Prometheus metrics for envelopes handled by the gateway.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/deliveryhero/asya/asya-gateway/internal/envelopestore"
+	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+)
+
+const (
+	// DefaultNamespace prefixes all gateway metrics unless configured otherwise
+	DefaultNamespace = "asya_gateway"
+
+	// ToolOther aggregates envelopes from tools missing in the configuration
+	// (including envelopes created without a tool), bounding label cardinality
+	ToolOther = "other"
+
+	// TenantNone labels envelopes without a tenant. Tenants come from the
+	// authenticated caller, so every envelope is TenantNone until the gateway has auth.
+	TenantNone = "none"
+)
+
+// Metrics holds the gateway envelope metrics
+type Metrics struct {
+	envelopesCreated   *prometheus.CounterVec
+	envelopesCompleted *prometheus.CounterVec
+	envelopeDuration   *prometheus.HistogramVec
+	statusDuration     *prometheus.HistogramVec
+	pickupOverdue      *prometheus.GaugeVec
+
+	channelPoolWait      prometheus.Histogram
+	channelPoolBlocked   prometheus.Counter
+	channelPoolExhausted prometheus.Counter
+
+	activeConsumers *prometheus.GaugeVec
+
+	namespace string
+	subsystem string
+	tools     map[string]bool // Configured tool names, the only accepted "tool" label values
+	replica   string          // Gateway replica reporting store-wide gauges (see SetReplica)
+	registry  *prometheus.Registry
+}
+
+// NewMetrics creates the gateway metrics, named <namespace>_<subsystem>_<name>
+// (an empty subsystem is left out). tools lists the configured tool names;
+// any other tool name is recorded as ToolOther.
+func NewMetrics(namespace, subsystem string, tools []string) *Metrics {
+	m := &Metrics{
+		tools:     make(map[string]bool, len(tools)),
+		registry:  prometheus.NewRegistry(),
+		namespace: namespace,
+		subsystem: subsystem,
+	}
+	for _, tool := range tools {
+		m.tools[tool] = true
+	}
+
+	m.envelopesCreated = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "envelopes_created_total",
+			Help:      "Total number of envelopes created",
+		},
+		[]string{"tool", "tenant"},
+	)
+
+	m.envelopesCompleted = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "envelopes_completed_total",
+			Help:      "Total number of envelopes that reached a final status",
+		},
+		[]string{"tool", "tenant", "status"}, // status: succeeded, failed
+	)
+
+	m.envelopeDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "envelope_duration_seconds",
+			Help:      "Time from envelope creation to final status",
+			Buckets:   []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600, 1800, 3600},
+		},
+		[]string{"tool", "tenant", "status"},
+	)
+
+	// Time in pending/queued is scheduling latency (backlog, scaling), time in running is processing
+	m.statusDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "envelope_status_duration_seconds",
+			Help:      "Time envelopes spent in a non-final status before moving to another status",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600, 1800, 3600},
+		},
+		[]string{"tool", "tenant", "status"}, // status: pending, queued, running, unknown
+	)
+
+	m.pickupOverdue = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "envelopes_pickup_overdue",
+			Help:      "Envelopes queued longer than the pickup window without an actor picking them up, as seen by a gateway replica",
+		},
+		[]string{"tool", "replica"},
+	)
+
+	m.channelPoolWait = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "channel_pool_get_wait_seconds",
+			Help:      "Time spent waiting for a RabbitMQ channel from the pool",
+			Buckets:   []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5},
+		},
+	)
+
+	m.channelPoolBlocked = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "channel_pool_get_blocked_total",
+			Help:      "Total number of channel pool gets that found the pool empty and had to wait",
+		},
+	)
+
+	m.channelPoolExhausted = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "channel_pool_exhausted_total",
+			Help:      "Total number of channel pool gets that failed because no channel became available within the acquire timeout",
+		},
+	)
+
+	m.activeConsumers = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "active_consumers",
+			Help:      "Queue consumers currently running",
+		},
+		[]string{"queue"},
+	)
+
+	// Go runtime metrics (go_goroutines, go_memstats_*) reveal goroutine and memory leaks
+	m.registry.MustRegister(m.envelopesCreated, m.envelopesCompleted, m.envelopeDuration, m.statusDuration, m.pickupOverdue, m.activeConsumers, collectors.NewGoCollector())
+	return m
+}
+
+// RegisterSubscribers exposes the number of open SSE update listeners of the envelope store.
+// A count that keeps growing while streams are closed means listeners leak. Call at most once.
+func (m *Metrics) RegisterSubscribers(subscribers func() int) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: m.namespace,
+			Subsystem: m.subsystem,
+			Name:      "sse_subscribers",
+			Help:      "Update listeners currently subscribed to the envelope store",
+		},
+		func() float64 { return float64(subscribers()) },
+	))
+}
+
+// ConsumerStarted counts a running queue consumer
+func (m *Metrics) ConsumerStarted(queue string) {
+	m.activeConsumers.WithLabelValues(queue).Inc()
+}
+
+// ConsumerStopped uncounts a queue consumer that returned
+func (m *Metrics) ConsumerStopped(queue string) {
+	m.activeConsumers.WithLabelValues(queue).Dec()
+}
+
+// RegisterChannelPool exposes the RabbitMQ channel pool: idle and capacity report the
+// channels currently in the pool and its maximum size. Call at most once.
+func (m *Metrics) RegisterChannelPool(idle, capacity func() int) {
+	m.registry.MustRegister(
+		prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Namespace: m.namespace,
+				Subsystem: m.subsystem,
+				Name:      "channel_pool_idle_channels",
+				Help:      "RabbitMQ channels currently available in the pool",
+			},
+			func() float64 { return float64(idle()) },
+		),
+		prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Namespace: m.namespace,
+				Subsystem: m.subsystem,
+				Name:      "channel_pool_capacity",
+				Help:      "Maximum number of RabbitMQ channels in the pool",
+			},
+			func() float64 { return float64(capacity()) },
+		),
+		m.channelPoolWait,
+		m.channelPoolBlocked,
+		m.channelPoolExhausted,
+	)
+}
+
+// ObserveChannelPoolWait records a channel pool get; blocked means the pool was empty
+func (m *Metrics) ObserveChannelPoolWait(wait time.Duration, blocked bool) {
+	m.channelPoolWait.Observe(wait.Seconds())
+	if blocked {
+		m.channelPoolBlocked.Inc()
+	}
+}
+
+// ObserveChannelPoolExhausted counts a channel pool get that timed out
+func (m *Metrics) ObserveChannelPoolExhausted() {
+	m.channelPoolExhausted.Inc()
+}
+
+// RecordCreated counts a new envelope
+func (m *Metrics) RecordCreated(tool, tenant string) {
+	m.envelopesCreated.WithLabelValues(m.toolLabel(tool), tenantLabel(tenant)).Inc()
+}
+
+// RecordCompleted counts an envelope reaching a final status and observes its duration
+func (m *Metrics) RecordCompleted(tool, tenant, status string, duration time.Duration) {
+	toolLabel, tenant := m.toolLabel(tool), tenantLabel(tenant)
+	m.envelopesCompleted.WithLabelValues(toolLabel, tenant, status).Inc()
+	if duration > 0 {
+		m.envelopeDuration.WithLabelValues(toolLabel, tenant, status).Observe(duration.Seconds())
+	}
+}
+
+// StatusChanged observes the time an envelope spent in a status it left, and counts the
+// envelope as completed when it first reaches a final status. Time in a final status is
+// not observed: leaving one means a late report overwrote a timeout or cancellation.
+func (m *Metrics) StatusChanged(transition envelopestore.Transition) {
+	envelope := transition.Envelope
+	if transition.Completed() {
+		var duration time.Duration
+		if !envelope.CreatedAt.IsZero() {
+			duration = time.Since(envelope.CreatedAt)
+		}
+		m.RecordCompleted(envelope.Tool, TenantNone, string(transition.To), duration)
+	}
+
+	from := transition.From
+	if from == types.EnvelopeStatusSucceeded || from == types.EnvelopeStatusFailed || transition.Elapsed < 0 {
+		return
+	}
+	m.statusDuration.WithLabelValues(m.toolLabel(envelope.Tool), TenantNone, string(from)).Observe(transition.Elapsed.Seconds())
+}
+
+// SetReplica sets the "replica" label of gauges counting envelopes of the whole store, which
+// every gateway replica sharing the store reports alike (e.g. the pod name)
+func (m *Metrics) SetReplica(replica string) {
+	m.replica = replica
+}
+
+// PickupOverdue sets the number of envelopes per tool waiting for pickup past the window
+func (m *Metrics) PickupOverdue(overdue map[string]int) {
+	m.pickupOverdue.Reset()
+	for tool, count := range overdue {
+		m.pickupOverdue.WithLabelValues(m.toolLabel(tool), m.replica).Add(float64(count))
+	}
+}
+
+// Handler serves the metrics in Prometheus exposition format, or in OpenMetrics format
+// (which carries exemplars) to scrapers that negotiate it
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{
+		EnableOpenMetrics: true,
+	})
+}
+
+func (m *Metrics) toolLabel(tool string) string {
+	if m.tools[tool] {
+		return tool
+	}
+	return ToolOther
+}
+
+func tenantLabel(tenant string) string {
+	if tenant == "" {
+		return TenantNone
+	}
+	return tenant
+}
@@ -0,0 +1,276 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/deliveryhero/asya/asya-gateway/internal/envelopestore"
+	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+)
+
+func TestMetrics_ToolLabelCardinality(t *testing.T) {
+	m := NewMetrics("test", "", []string{"summarize"})
+
+	m.RecordCreated("summarize", "")
+	m.RecordCreated("unknown-tool", "")
+	m.RecordCreated("another-unknown-tool", "acme")
+	m.RecordCreated("", "")
+
+	tests := []struct {
+		tool   string
+		tenant string
+		want   float64
+	}{
+		{tool: "summarize", tenant: TenantNone, want: 1},
+		{tool: ToolOther, tenant: TenantNone, want: 2},
+		{tool: ToolOther, tenant: "acme", want: 1},
+		{tool: "unknown-tool", tenant: TenantNone, want: 0},
+	}
+	for _, tt := range tests {
+		if got := testutil.ToFloat64(m.envelopesCreated.WithLabelValues(tt.tool, tt.tenant)); got != tt.want {
+			t.Errorf("envelopes_created_total{tool=%q,tenant=%q} = %v, want %v", tt.tool, tt.tenant, got, tt.want)
+		}
+	}
+}
+
+// newMetricsStore returns an in-memory store recording its lifecycle in m
+func newMetricsStore(m *Metrics) *Store {
+	base := envelopestore.NewStore()
+	base.SetTransitionObserver(m)
+	return NewStore(base, m)
+}
+
+func TestStore_RecordsLifecycle(t *testing.T) {
+	m := NewMetrics("test", "", []string{"summarize"})
+	store := newMetricsStore(m)
+
+	envelope := &types.Envelope{
+		ID:     "env-1",
+		Tool:   "summarize",
+		Status: types.EnvelopeStatusPending,
+		Route:  types.Route{Actors: []string{"prep"}},
+	}
+	if err := store.Create(envelope); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := store.Update(types.EnvelopeUpdate{ID: "env-1", Status: types.EnvelopeStatusRunning, Timestamp: time.Now()}); err != nil {
+		t.Fatalf("Update running failed: %v", err)
+	}
+	if err := store.Update(types.EnvelopeUpdate{ID: "env-1", Status: types.EnvelopeStatusSucceeded, Timestamp: time.Now()}); err != nil {
+		t.Fatalf("Update succeeded failed: %v", err)
+	}
+	// Redelivered final status is not counted again
+	if err := store.Update(types.EnvelopeUpdate{ID: "env-1", Status: types.EnvelopeStatusSucceeded, Timestamp: time.Now()}); err != nil {
+		t.Fatalf("Duplicate update failed: %v", err)
+	}
+
+	if got := testutil.ToFloat64(m.envelopesCreated.WithLabelValues("summarize", TenantNone)); got != 1 {
+		t.Errorf("envelopes_created_total = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.envelopesCompleted.WithLabelValues("summarize", TenantNone, "succeeded")); got != 1 {
+		t.Errorf("envelopes_completed_total{status=succeeded} = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(m.envelopeDuration); got != 1 {
+		t.Errorf("envelope_duration_seconds series = %d, want 1", got)
+	}
+}
+
+func TestStore_RecordsTimeout(t *testing.T) {
+	m := NewMetrics("test", "", []string{"summarize"})
+	store := newMetricsStore(m)
+
+	envelope := &types.Envelope{
+		ID:         "env-timeout",
+		Tool:       "summarize",
+		Route:      types.Route{Actors: []string{"prep"}},
+		TimeoutSec: 1,
+	}
+	if err := store.Create(envelope); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for testutil.ToFloat64(m.envelopesCompleted.WithLabelValues("summarize", TenantNone, "failed")) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the timeout to be counted")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// A late final report after the timeout is not counted as another completion
+	if err := store.Update(types.EnvelopeUpdate{ID: "env-timeout", Status: types.EnvelopeStatusSucceeded, Timestamp: time.Now()}); err != nil {
+		t.Fatalf("Update succeeded failed: %v", err)
+	}
+	if got := testutil.CollectAndCount(m.envelopesCompleted); got != 1 {
+		t.Errorf("envelopes_completed_total series = %d, want only the timeout", got)
+	}
+}
+
+func TestMetrics_ChannelPool(t *testing.T) {
+	m := NewMetrics("test", "", nil)
+
+	idle := 3
+	m.RegisterChannelPool(func() int { return idle }, func() int { return 5 })
+
+	m.ObserveChannelPoolWait(0, false)
+	m.ObserveChannelPoolWait(20*time.Millisecond, true)
+	m.ObserveChannelPoolExhausted()
+	idle = 0
+
+	if got := testutil.ToFloat64(m.channelPoolExhausted); got != 1 {
+		t.Errorf("channel_pool_exhausted_total = %v, want 1", got)
+	}
+
+	if got := testutil.ToFloat64(m.channelPoolBlocked); got != 1 {
+		t.Errorf("channel_pool_get_blocked_total = %v, want 1", got)
+	}
+
+	families, err := m.registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+	values := make(map[string]float64)
+	for _, family := range families {
+		metric := family.GetMetric()[0]
+		switch {
+		case metric.GetGauge() != nil:
+			values[family.GetName()] = metric.GetGauge().GetValue()
+		case metric.GetHistogram() != nil:
+			values[family.GetName()] = float64(metric.GetHistogram().GetSampleCount())
+		}
+	}
+
+	want := map[string]float64{
+		"test_channel_pool_idle_channels":    0,
+		"test_channel_pool_capacity":         5,
+		"test_channel_pool_get_wait_seconds": 2,
+	}
+	for name, value := range want {
+		if got, ok := values[name]; !ok || got != value {
+			t.Errorf("%s = %v (present %v), want %v", name, got, ok, value)
+		}
+	}
+}
+
+func TestMetrics_NamespaceAndSubsystem(t *testing.T) {
+	m := NewMetrics("platform", "gateway", []string{"summarize"})
+	m.RegisterChannelPool(func() int { return 1 }, func() int { return 1 })
+	m.RecordCreated("summarize", "")
+
+	families, err := m.registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+	names := make(map[string]bool)
+	for _, family := range families {
+		names[family.GetName()] = true
+	}
+
+	for _, name := range []string{"platform_gateway_envelopes_created_total", "platform_gateway_channel_pool_capacity"} {
+		if !names[name] {
+			t.Errorf("metric %s not registered, got %v", name, names)
+		}
+	}
+}
+
+func TestMetrics_HandlerNegotiatesOpenMetrics(t *testing.T) {
+	m := NewMetrics("test", "", []string{"summarize"})
+	m.RecordCreated("summarize", "")
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, req)
+
+	if contentType := rec.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/openmetrics-text") {
+		t.Errorf("Content-Type = %q, want OpenMetrics", contentType)
+	}
+	if !strings.HasSuffix(rec.Body.String(), "# EOF\n") {
+		t.Errorf("OpenMetrics exposition should end with # EOF")
+	}
+}
+
+func TestMetrics_LeakGauges(t *testing.T) {
+	m := NewMetrics("test", "", nil)
+
+	subscribers := 2
+	m.RegisterSubscribers(func() int { return subscribers })
+	m.ConsumerStarted("happy-end")
+	m.ConsumerStarted("error-end")
+	m.ConsumerStopped("error-end")
+
+	if got := testutil.ToFloat64(m.activeConsumers.WithLabelValues("happy-end")); got != 1 {
+		t.Errorf("active_consumers{queue=happy-end} = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.activeConsumers.WithLabelValues("error-end")); got != 0 {
+		t.Errorf("active_consumers{queue=error-end} = %v, want 0", got)
+	}
+
+	families, err := m.registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+	values := make(map[string]float64)
+	for _, family := range families {
+		if gauge := family.GetMetric()[0].GetGauge(); gauge != nil {
+			values[family.GetName()] = gauge.GetValue()
+		}
+	}
+	if values["test_sse_subscribers"] != 2 {
+		t.Errorf("test_sse_subscribers = %v, want 2", values["test_sse_subscribers"])
+	}
+	if values["go_goroutines"] < 1 {
+		t.Errorf("go_goroutines = %v, want the Go collector registered", values["go_goroutines"])
+	}
+}
+
+// transition returns a status change of an envelope of tool
+func transition(tool string, from, to types.EnvelopeStatus, elapsed time.Duration) envelopestore.Transition {
+	return envelopestore.Transition{
+		Envelope: &types.Envelope{ID: "env-1", Tool: tool, Status: to},
+		From:     from,
+		To:       to,
+		Elapsed:  elapsed,
+	}
+}
+
+func TestMetrics_StatusChanged(t *testing.T) {
+	m := NewMetrics("test", "", []string{"summarize"})
+
+	m.StatusChanged(transition("summarize", types.EnvelopeStatusPending, types.EnvelopeStatusQueued, 50*time.Millisecond))
+	m.StatusChanged(transition("summarize", types.EnvelopeStatusQueued, types.EnvelopeStatusRunning, 2*time.Second))
+	m.StatusChanged(transition("summarize", types.EnvelopeStatusRunning, types.EnvelopeStatusSucceeded, 30*time.Second))
+	m.StatusChanged(transition("summarize", types.EnvelopeStatusFailed, types.EnvelopeStatusSucceeded, time.Minute))
+	m.StatusChanged(transition("unknown-tool", types.EnvelopeStatusPending, types.EnvelopeStatusRunning, time.Second))
+
+	if got := testutil.CollectAndCount(m.statusDuration); got != 4 {
+		t.Errorf("envelope_status_duration_seconds series = %d, want 4 (time in failed is not observed)", got)
+	}
+	if got := testutil.ToFloat64(m.envelopesCompleted.WithLabelValues("summarize", TenantNone, "succeeded")); got != 1 {
+		t.Errorf("envelopes_completed_total{status=succeeded} = %v, want 1 (leaving failed is not a completion)", got)
+	}
+}
+
+func TestMetrics_PickupOverdue(t *testing.T) {
+	m := NewMetrics("test", "", []string{"summarize"})
+	m.SetReplica("gateway-0")
+
+	m.PickupOverdue(map[string]int{"summarize": 2, "unknown-a": 1, "unknown-b": 3})
+	if got := testutil.ToFloat64(m.pickupOverdue.WithLabelValues("summarize", "gateway-0")); got != 2 {
+		t.Errorf("envelopes_pickup_overdue{tool=summarize} = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.pickupOverdue.WithLabelValues(ToolOther, "gateway-0")); got != 4 {
+		t.Errorf("envelopes_pickup_overdue{tool=other} = %v, want 4", got)
+	}
+
+	// Envelopes picked up since the last check are no longer overdue
+	m.PickupOverdue(map[string]int{})
+	if got := testutil.CollectAndCount(m.pickupOverdue); got != 0 {
+		t.Errorf("envelopes_pickup_overdue series = %d, want 0", got)
+	}
+}
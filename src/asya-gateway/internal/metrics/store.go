@@ -0,0 +1,28 @@
+package metrics
+
+import (
+	"github.com/deliveryhero/asya/asya-gateway/internal/envelopestore"
+	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+)
+
+// Store wraps an envelope store and counts created envelopes. Completions are recorded
+// by Metrics as the TransitionObserver of the base store, so timeouts are counted and
+// redelivered final statuses are not.
+type Store struct {
+	envelopestore.EnvelopeStore
+	metrics *Metrics
+}
+
+// NewStore wraps store so created envelopes are counted in m
+func NewStore(store envelopestore.EnvelopeStore, m *Metrics) *Store {
+	return &Store{EnvelopeStore: store, metrics: m}
+}
+
+// Create creates the envelope and counts it
+func (s *Store) Create(envelope *types.Envelope) error {
+	if err := s.EnvelopeStore.Create(envelope); err != nil {
+		return err
+	}
+	s.metrics.RecordCreated(envelope.Tool, TenantNone)
+	return nil
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name           string
+		allowedOrigins []string
+		method         string
+		origin         string
+		preflight      bool
+		wantStatus     int
+		wantOrigin     string
+		wantMethods    string
+	}{
+		{
+			name:       "disabled by default",
+			method:     http.MethodGet,
+			origin:     "https://dashboard.example.com",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:           "allowed origin",
+			allowedOrigins: []string{"https://dashboard.example.com"},
+			method:         http.MethodGet,
+			origin:         "https://dashboard.example.com",
+			wantStatus:     http.StatusOK,
+			wantOrigin:     "https://dashboard.example.com",
+		},
+		{
+			name:           "allowed origin with trailing slash in config",
+			allowedOrigins: []string{"https://dashboard.example.com/"},
+			method:         http.MethodGet,
+			origin:         "https://dashboard.example.com",
+			wantStatus:     http.StatusOK,
+			wantOrigin:     "https://dashboard.example.com",
+		},
+		{
+			name:           "disallowed origin",
+			allowedOrigins: []string{"https://dashboard.example.com"},
+			method:         http.MethodGet,
+			origin:         "https://evil.example.com",
+			wantStatus:     http.StatusOK,
+		},
+		{
+			name:           "wildcard",
+			allowedOrigins: []string{"*"},
+			method:         http.MethodPost,
+			origin:         "https://any.example.com",
+			wantStatus:     http.StatusOK,
+			wantOrigin:     "https://any.example.com",
+		},
+		{
+			name:           "no origin header",
+			allowedOrigins: []string{"*"},
+			method:         http.MethodGet,
+			wantStatus:     http.StatusOK,
+		},
+		{
+			name:           "preflight from allowed origin",
+			allowedOrigins: []string{"https://dashboard.example.com"},
+			method:         http.MethodOptions,
+			origin:         "https://dashboard.example.com",
+			preflight:      true,
+			wantStatus:     http.StatusNoContent,
+			wantOrigin:     "https://dashboard.example.com",
+			wantMethods:    corsAllowMethods,
+		},
+		{
+			name:           "preflight from disallowed origin reaches handler",
+			allowedOrigins: []string{"https://dashboard.example.com"},
+			method:         http.MethodOptions,
+			origin:         "https://evil.example.com",
+			preflight:      true,
+			wantStatus:     http.StatusOK,
+		},
+		{
+			name:           "plain OPTIONS is not a preflight",
+			allowedOrigins: []string{"*"},
+			method:         http.MethodOptions,
+			origin:         "https://dashboard.example.com",
+			wantStatus:     http.StatusOK,
+			wantOrigin:     "https://dashboard.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/envelopes/abc-123", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+			}
+			rr := httptest.NewRecorder()
+
+			CORS(tt.allowedOrigins, next).ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status = %v, want %v", rr.Code, tt.wantStatus)
+			}
+			if got := rr.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := rr.Header().Get("Access-Control-Allow-Methods"); got != tt.wantMethods {
+				t.Errorf("Access-Control-Allow-Methods = %q, want %q", got, tt.wantMethods)
+			}
+		})
+	}
+}
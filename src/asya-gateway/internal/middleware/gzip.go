@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+)
+
+// Gzip compresses responses of next for clients that send "Accept-Encoding: gzip".
+// Meant for plain JSON endpoints; do not wrap SSE streams, which need unbuffered writes.
+func Gzip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsGzip reports whether the request allows a gzip-encoded response
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(encoding), ";")
+		if strings.EqualFold(strings.TrimSpace(name), "gzip") {
+			return strings.ReplaceAll(strings.TrimSpace(params), " ", "") != "q=0"
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter defers the response header until the first body write, so
+// bodiless responses (204, 304, empty errors) are sent without Content-Encoding
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz      *gzip.Writer
+	status  int
+	started bool
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if !w.started && w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.started {
+		if len(b) == 0 {
+			return 0, nil
+		}
+		w.start(b)
+	}
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// start sends the header and enables compression for the response body
+func (w *gzipResponseWriter) start(firstChunk []byte) {
+	w.started = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	header := w.Header()
+	if header.Get("Content-Encoding") == "" && w.status != http.StatusNoContent && w.status != http.StatusNotModified {
+		// Sniff before compressing, net/http would otherwise sniff the gzip bytes
+		if header.Get("Content-Type") == "" {
+			header.Set("Content-Type", http.DetectContentType(firstChunk))
+		}
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+// close flushes the compressed body, or sends the deferred header when nothing was written
+func (w *gzipResponseWriter) close() {
+	if !w.started {
+		w.started = true
+		if w.status != 0 {
+			w.ResponseWriter.WriteHeader(w.status)
+		}
+		return
+	}
+	if w.gz != nil {
+		_ = w.gz.Close()
+	}
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGzip(t *testing.T) {
+	body := `{"id":"abc-123","result":"` + strings.Repeat("x", 1024) + `"}`
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		status         int
+		body           string
+		wantGzip       bool
+	}{
+		{name: "gzip accepted", acceptEncoding: "gzip, deflate, br", status: http.StatusOK, body: body, wantGzip: true},
+		{name: "gzip with quality", acceptEncoding: "br;q=1.0, gzip;q=0.8", status: http.StatusOK, body: body, wantGzip: true},
+		{name: "gzip refused", acceptEncoding: "gzip;q=0", status: http.StatusOK, body: body},
+		{name: "no accept-encoding", status: http.StatusOK, body: body},
+		{name: "other encodings only", acceptEncoding: "br", status: http.StatusOK, body: body},
+		{name: "error response", acceptEncoding: "gzip", status: http.StatusNotFound, body: "Envelope not found\n", wantGzip: true},
+		{name: "empty body", acceptEncoding: "gzip", status: http.StatusAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Gzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.body != "" {
+					w.Header().Set("Content-Type", "application/json")
+				}
+				w.WriteHeader(tt.status)
+				_, _ = io.WriteString(w, tt.body)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/envelopes/abc-123", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.status {
+				t.Errorf("status = %v, want %v", rr.Code, tt.status)
+			}
+			if got := rr.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", got)
+			}
+
+			gotGzip := rr.Header().Get("Content-Encoding") == "gzip"
+			if gotGzip != tt.wantGzip {
+				t.Fatalf("gzip encoded = %v, want %v", gotGzip, tt.wantGzip)
+			}
+
+			got := rr.Body.String()
+			if gotGzip {
+				reader, err := gzip.NewReader(rr.Body)
+				if err != nil {
+					t.Fatalf("Failed to open gzip body: %v", err)
+				}
+				decoded, err := io.ReadAll(reader)
+				if err != nil {
+					t.Fatalf("Failed to decompress body: %v", err)
+				}
+				got = string(decoded)
+			}
+			if got != tt.body {
+				t.Errorf("body = %q, want %q", got, tt.body)
+			}
+		})
+	}
+}
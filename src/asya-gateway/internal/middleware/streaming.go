@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// Streaming clears the server read and write deadlines for long-lived responses
+// (SSE streams, results streamed from object storage). Otherwise the stream is cut off once WriteTimeout passes, and the
+// request context is canceled when ReadTimeout expires on the idle connection.
+func Streaming(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Errors only mean the writer does not support deadlines (e.g. test recorders)
+		rc := http.NewResponseController(w)
+		_ = rc.SetReadDeadline(time.Time{})
+		_ = rc.SetWriteDeadline(time.Time{})
+		next.ServeHTTP(w, r)
+	})
+}
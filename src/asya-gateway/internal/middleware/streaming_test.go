@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStreaming_OutlivesServerTimeouts(t *testing.T) {
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		if err := r.Context().Err(); err != nil {
+			t.Errorf("request context canceled: %v", err)
+		}
+		_, _ = io.WriteString(w, "data: done\n\n")
+	})
+
+	server := httptest.NewUnstartedServer(Streaming(slow))
+	server.Config.ReadTimeout = 50 * time.Millisecond
+	server.Config.WriteTimeout = 50 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read body: %v", err)
+	}
+	if string(body) != "data: done\n\n" {
+		t.Errorf("body = %q, want stream event", body)
+	}
+}
@@ -0,0 +1,49 @@
+// Package payloadstore offloads large final results from the envelope store to
+// object storage, keeping only a reference URL on the envelope (claim check).
+package payloadstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Result store backend names (ASYA_RESULT_STORE)
+const (
+	BackendNone = "none"
+	BackendS3   = "s3"
+)
+
+// DefaultThresholdBytes is the default JSON size above which final results are offloaded
+const DefaultThresholdBytes = 256 << 10
+
+// ErrNotFound is returned when a referenced object does not exist
+var ErrNotFound = errors.New("object not found")
+
+// ObjectStore reads and writes result objects
+type ObjectStore interface {
+	// Put writes data under key and returns the reference URL of the object
+	Put(ctx context.Context, key string, data []byte) (string, error)
+
+	// Open streams the object referenced by url, the caller closes the reader
+	Open(ctx context.Context, url string) (io.ReadCloser, error)
+
+	// Delete removes the object referenced by url; objects that do not exist are not an error
+	Delete(ctx context.Context, url string) error
+}
+
+// ValidateBackendName returns an error if name is not a supported result store backend
+func ValidateBackendName(name string) error {
+	switch name {
+	case BackendNone, BackendS3:
+		return nil
+	default:
+		return fmt.Errorf("unknown result store %q (must be %s or %s)", name, BackendNone, BackendS3)
+	}
+}
+
+// resultKey returns the object key of an envelope's final result
+func resultKey(envelopeID string) string {
+	return "results/" + envelopeID + ".json"
+}
@@ -0,0 +1,171 @@
+package payloadstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// S3Config configures an S3-compatible object store
+type S3Config struct {
+	Bucket   string
+	Prefix   string // Key prefix, e.g. "asya/"
+	Region   string
+	Endpoint string // Custom endpoint for MinIO, LocalStack or GCS (https://storage.googleapis.com); uses path-style URLs
+}
+
+// S3Store stores objects in an S3-compatible bucket using the S3 REST API with
+// SigV4-signed requests. Credentials come from the default AWS chain (env, IRSA, ...);
+// GCS works through its S3 interoperability endpoint with HMAC keys.
+type S3Store struct {
+	bucket      string
+	prefix      string
+	region      string
+	endpoint    string
+	credentials aws.CredentialsProvider
+	signer      *v4.Signer
+	httpClient  *http.Client
+}
+
+// NewS3Store creates an S3 object store
+func NewS3Store(ctx context.Context, cfg S3Config) (*S3Store, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("bucket is required")
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(cfg.Region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	return &S3Store{
+		bucket:      cfg.Bucket,
+		prefix:      cfg.Prefix,
+		region:      cfg.Region,
+		endpoint:    strings.TrimSuffix(cfg.Endpoint, "/"),
+		credentials: awsCfg.Credentials,
+		signer:      v4.NewSigner(),
+		httpClient:  &http.Client{Timeout: 5 * time.Minute},
+	}, nil
+}
+
+// Put uploads data and returns its s3://bucket/key reference
+func (s *S3Store) Put(ctx context.Context, key string, data []byte) (string, error) {
+	key = s.prefix + key
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key), bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	sum := sha256.Sum256(data)
+	resp, err := s.do(ctx, req, hex.EncodeToString(sum[:]))
+	if err != nil {
+		return "", err
+	}
+	_ = resp.Body.Close()
+
+	return fmt.Sprintf("s3://%s/%s", s.bucket, key), nil
+}
+
+// Open downloads the object referenced by an s3://bucket/key URL of this store
+func (s *S3Store) Open(ctx context.Context, ref string) (io.ReadCloser, error) {
+	key, err := s.key(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := s.do(ctx, req, "UNSIGNED-PAYLOAD")
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete removes the object referenced by an s3://bucket/key URL of this store
+func (s *S3Store) Delete(ctx context.Context, ref string) error {
+	key, err := s.key(ref)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := s.do(ctx, req, "UNSIGNED-PAYLOAD")
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	return nil
+}
+
+// key returns the object key of an s3://bucket/key URL of this store
+func (s *S3Store) key(ref string) (string, error) {
+	key, ok := strings.CutPrefix(ref, fmt.Sprintf("s3://%s/", s.bucket))
+	if !ok || key == "" {
+		return "", fmt.Errorf("reference %q is not in bucket %s", ref, s.bucket)
+	}
+	return key, nil
+}
+
+// do signs and sends req, returning an error for non-2xx responses
+func (s *S3Store) do(ctx context.Context, req *http.Request, payloadHash string) (*http.Response, error) {
+	creds, err := s.credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve credentials: %w", err)
+	}
+
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if err := s.signer.SignHTTP(ctx, creds, req, payloadHash, "s3", s.region, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL.Path, err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		_ = resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("%s %s returned status %d: %s", req.Method, req.URL.Path, resp.StatusCode, body)
+	}
+	return resp, nil
+}
+
+// objectURL returns the HTTP URL of key: path-style for custom endpoints,
+// virtual-hosted style for AWS
+func (s *S3Store) objectURL(key string) string {
+	path := (&url.URL{Path: "/" + key}).EscapedPath()
+	if s.endpoint != "" {
+		return s.endpoint + "/" + s.bucket + path
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com%s", s.bucket, s.region, path)
+}
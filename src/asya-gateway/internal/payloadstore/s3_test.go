@@ -0,0 +1,140 @@
+package payloadstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 serves path-style PUT, GET and DELETE object requests from memory
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=test-key/") {
+		http.Error(w, "unsigned request", http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = data
+	case http.MethodGet:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func newTestS3Store(t *testing.T, endpoint string) *S3Store {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test-key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test-secret")
+
+	store, err := NewS3Store(context.Background(), S3Config{
+		Bucket:   "results",
+		Prefix:   "asya/",
+		Region:   "us-east-1",
+		Endpoint: endpoint,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Store failed: %v", err)
+	}
+	return store
+}
+
+func TestS3Store_PutOpen(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store := newTestS3Store(t, server.URL)
+	ctx := context.Background()
+
+	ref, err := store.Put(ctx, resultKey("env-1"), []byte(`{"answer":42}`))
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if ref != "s3://results/asya/results/env-1.json" {
+		t.Errorf("Put() ref = %q, want s3://results/asya/results/env-1.json", ref)
+	}
+	if _, ok := fake.objects["/results/asya/results/env-1.json"]; !ok {
+		t.Errorf("object not stored at path-style key, have %v", fake.objects)
+	}
+
+	body, err := store.Open(ctx, ref)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer func() { _ = body.Close() }()
+	data, _ := io.ReadAll(body)
+	if string(data) != `{"answer":42}` {
+		t.Errorf("Open() = %s, want {\"answer\":42}", data)
+	}
+}
+
+func TestS3Store_OpenErrors(t *testing.T) {
+	server := httptest.NewServer(&fakeS3{objects: map[string][]byte{}})
+	defer server.Close()
+
+	store := newTestS3Store(t, server.URL)
+	ctx := context.Background()
+
+	if _, err := store.Open(ctx, "s3://results/asya/results/missing.json"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open(missing) error = %v, want ErrNotFound", err)
+	}
+	if _, err := store.Open(ctx, "s3://other-bucket/asya/results/env-1.json"); err == nil {
+		t.Error("Open(other bucket) error = nil, want error")
+	}
+}
+
+func TestS3Store_Delete(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store := newTestS3Store(t, server.URL)
+	ctx := context.Background()
+
+	ref, err := store.Put(ctx, resultKey("env-1"), []byte(`{"answer":42}`))
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := store.Delete(ctx, ref); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Open(ctx, ref); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open(deleted) error = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, ref); err != nil {
+		t.Errorf("Delete(missing) error = %v, want nil", err)
+	}
+	if err := store.Delete(ctx, "s3://other-bucket/asya/results/env-1.json"); err == nil {
+		t.Error("Delete(other bucket) error = nil, want error")
+	}
+}
+
+func TestS3Store_ObjectURL(t *testing.T) {
+	store := &S3Store{bucket: "results", region: "eu-west-1"}
+	if got := store.objectURL("asya/results/env 1.json"); got != "https://results.s3.eu-west-1.amazonaws.com/asya/results/env%201.json" {
+		t.Errorf("objectURL() = %q", got)
+	}
+}
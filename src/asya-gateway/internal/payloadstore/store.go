@@ -0,0 +1,62 @@
+package payloadstore
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/deliveryhero/asya/asya-gateway/internal/envelopestore"
+	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+)
+
+// DefaultPutTimeout bounds the upload of an offloaded result, so a hanging object store
+// does not block the final status update
+const DefaultPutTimeout = 30 * time.Second
+
+// Store wraps an envelope store and moves final results larger than the threshold
+// to object storage, so the envelope, its update history and SSE events only carry
+// the reference URL. Offload failures are logged and the result is stored inline.
+type Store struct {
+	envelopestore.EnvelopeStore
+	objects    ObjectStore
+	threshold  int
+	putTimeout time.Duration
+}
+
+// NewStore wraps store so final results over threshold bytes (JSON) are written to objects
+func NewStore(store envelopestore.EnvelopeStore, objects ObjectStore, threshold int) *Store {
+	return &Store{EnvelopeStore: store, objects: objects, threshold: threshold, putTimeout: DefaultPutTimeout}
+}
+
+// SetPutTimeout sets how long a result upload may take before the result is stored inline
+func (s *Store) SetPutTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		s.putTimeout = timeout
+	}
+}
+
+// Update offloads a large final result before applying the update
+func (s *Store) Update(update types.EnvelopeUpdate) error {
+	if update.Result == nil || (update.Status != types.EnvelopeStatusSucceeded && update.Status != types.EnvelopeStatusFailed) {
+		return s.EnvelopeStore.Update(update)
+	}
+
+	data, err := json.Marshal(update.Result)
+	if err != nil || len(data) <= s.threshold {
+		return s.EnvelopeStore.Update(update)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.putTimeout)
+	defer cancel()
+	ref, err := s.objects.Put(ctx, resultKey(update.ID), data)
+	if err != nil {
+		slog.Error("Failed to offload result, storing it inline", "id", update.ID, "size", len(data), "error", err)
+		return s.EnvelopeStore.Update(update)
+	}
+
+	slog.Debug("Offloaded result to object store", "id", update.ID, "size", len(data), "url", ref)
+	update.Result = nil
+	update.ResultURL = ref
+	return s.EnvelopeStore.Update(update)
+}
@@ -0,0 +1,153 @@
+package payloadstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deliveryhero/asya/asya-gateway/internal/envelopestore"
+	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+)
+
+// memoryObjectStore keeps objects in memory
+type memoryObjectStore struct {
+	objects map[string][]byte
+	err     error
+}
+
+func (m *memoryObjectStore) Put(ctx context.Context, key string, data []byte) (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+	m.objects["mem://"+key] = data
+	return "mem://" + key, nil
+}
+
+// hangingObjectStore blocks uploads until their context is done
+type hangingObjectStore struct {
+	memoryObjectStore
+}
+
+func (h *hangingObjectStore) Put(ctx context.Context, key string, data []byte) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func (m *memoryObjectStore) Open(ctx context.Context, url string) (io.ReadCloser, error) {
+	data, ok := m.objects[url]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return io.NopCloser(strings.NewReader(string(data))), nil
+}
+
+func (m *memoryObjectStore) Delete(ctx context.Context, url string) error {
+	delete(m.objects, url)
+	return nil
+}
+
+func TestStore_Update(t *testing.T) {
+	largeResult := map[string]any{"text": strings.Repeat("a", 100)}
+
+	tests := []struct {
+		name          string
+		status        types.EnvelopeStatus
+		result        any
+		putErr        error
+		wantOffloaded bool
+	}{
+		{name: "large succeeded result is offloaded", status: types.EnvelopeStatusSucceeded, result: largeResult, wantOffloaded: true},
+		{name: "large failed result is offloaded", status: types.EnvelopeStatusFailed, result: largeResult, wantOffloaded: true},
+		{name: "small result stays inline", status: types.EnvelopeStatusSucceeded, result: map[string]any{"answer": 42}},
+		{name: "running update is not offloaded", status: types.EnvelopeStatusRunning, result: largeResult},
+		{name: "offload failure keeps result inline", status: types.EnvelopeStatusSucceeded, result: largeResult, putErr: errors.New("bucket unavailable")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := &memoryObjectStore{objects: map[string][]byte{}, err: tt.putErr}
+			inner := envelopestore.NewStore()
+			store := NewStore(inner, objects, 50)
+
+			if err := store.Create(&types.Envelope{ID: "env-1", Status: types.EnvelopeStatusPending}); err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+			updates := store.Subscribe("env-1")
+			defer store.Unsubscribe("env-1", updates)
+
+			if err := store.Update(types.EnvelopeUpdate{ID: "env-1", Status: tt.status, Result: tt.result}); err != nil {
+				t.Fatalf("Update failed: %v", err)
+			}
+
+			envelope, err := store.Get("env-1")
+			if err != nil {
+				t.Fatalf("Get failed: %v", err)
+			}
+			update := <-updates
+
+			if !tt.wantOffloaded {
+				if envelope.ResultURL != "" || update.ResultURL != "" {
+					t.Errorf("ResultURL = %q, want result stored inline", envelope.ResultURL)
+				}
+				if envelope.Result == nil || update.Result == nil {
+					t.Error("Result should be stored inline")
+				}
+				return
+			}
+
+			if envelope.ResultURL != "mem://results/env-1.json" || update.ResultURL != envelope.ResultURL {
+				t.Errorf("ResultURL = %q (update %q), want mem://results/env-1.json", envelope.ResultURL, update.ResultURL)
+			}
+			if envelope.Result != nil || update.Result != nil {
+				t.Errorf("Result = %v, want nil after offload", envelope.Result)
+			}
+			if got := string(objects.objects["mem://results/env-1.json"]); got != `{"text":"`+strings.Repeat("a", 100)+`"}` {
+				t.Errorf("stored object = %s", got)
+			}
+		})
+	}
+}
+
+func TestStore_UpdatePutTimeout(t *testing.T) {
+	store := NewStore(envelopestore.NewStore(), &hangingObjectStore{}, 10)
+	store.SetPutTimeout(50 * time.Millisecond)
+
+	if err := store.Create(&types.Envelope{ID: "env-1", Status: types.EnvelopeStatusPending}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- store.Update(types.EnvelopeUpdate{ID: "env-1", Status: types.EnvelopeStatusSucceeded, Result: strings.Repeat("a", 100)})
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Update blocked on a hanging object store")
+	}
+
+	envelope, err := store.Get("env-1")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if envelope.ResultURL != "" || envelope.Result == nil {
+		t.Errorf("Result = %v, ResultURL = %q, want the result stored inline after the upload timed out", envelope.Result, envelope.ResultURL)
+	}
+}
+
+func TestValidateBackendName(t *testing.T) {
+	for _, name := range []string{BackendNone, BackendS3} {
+		if err := ValidateBackendName(name); err != nil {
+			t.Errorf("ValidateBackendName(%q) = %v, want nil", name, err)
+		}
+	}
+	if err := ValidateBackendName("gcs"); err == nil {
+		t.Error("ValidateBackendName(gcs) = nil, want error")
+	}
+}
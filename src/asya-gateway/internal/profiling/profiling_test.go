@@ -0,0 +1,82 @@
+package profiling
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestStart(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Start(ctx, addr) }()
+
+	var resp *http.Response
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		resp, err = http.Get("http://" + addr + "/debug/pprof/cmdline")
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Profiling server not reachable: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /debug/pprof/cmdline = %d, want 200", resp.StatusCode)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Start() after shutdown = %v, want nil", err)
+		}
+	case <-time.After(7 * time.Second):
+		t.Fatal("Start() did not return after ctx was cancelled")
+	}
+}
+
+func TestStart_ListenError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	if err := Start(context.Background(), listener.Addr().String()); err == nil {
+		t.Error("Start() on a used address should fail")
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{addr: DefaultAddr, want: true},
+		{addr: "localhost:6060", want: true},
+		{addr: "[::1]:6060", want: true},
+		{addr: ":6060", want: false},
+		{addr: "0.0.0.0:6060", want: false},
+		{addr: "10.0.0.5:6060", want: false},
+		{addr: "invalid", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := IsLoopback(tt.addr); got != tt.want {
+			t.Errorf("IsLoopback(%q) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
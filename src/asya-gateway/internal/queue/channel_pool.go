@@ -15,58 +15,58 @@ import (
 // This pool provides efficient channel reuse without mutex contention.
 type ChannelPool struct {
 	conn     *amqp.Connection
+	urls     []string           // Broker URLs in failover order
+	connMu   sync.Mutex         // Protects conn during reconnects
 	pool     chan *amqp.Channel // Buffered channel acts as semaphore
 	maxSize  int
 	exchange string
 	mu       sync.Mutex // Protects pool creation/destruction only
 	closed   bool
+	observer PoolObserver
+
+	acquireTimeout time.Duration // Max wait in Get for an empty pool, 0 waits for the context only
+
+	// Channel liveness check and replacement of closed channels, replaced in tests
+	channelClosed func(*amqp.Channel) bool
+	openChannel   func() (*amqp.Channel, error)
 }
 
-// NewChannelPool creates a new channel pool
-func NewChannelPool(url, exchange string, poolSize int) (*ChannelPool, error) {
-	if poolSize <= 0 {
-		poolSize = 10 // Default pool size
-	}
+// PoolObserver is notified of every channel pool Get, e.g. to export wait time metrics
+type PoolObserver interface {
+	// ObserveChannelPoolWait records how long Get waited; blocked means the pool was empty
+	ObserveChannelPoolWait(wait time.Duration, blocked bool)
 
-	// Create single connection with retry logic for resilience during startup
-	// This allows the gateway to wait for RabbitMQ to become available during:
-	// - Initial cluster deployment
-	// - RabbitMQ pod restarts
-	// - Network temporary failures
-	var conn *amqp.Connection
-	var err error
-	maxRetries := 5
-	initialBackoff := 1 * time.Second
-
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		conn, err = amqp.Dial(url)
-		if err == nil {
-			break
-		}
+	// ObserveChannelPoolExhausted records a Get that gave up after the acquire timeout
+	ObserveChannelPoolExhausted()
+}
 
-		if attempt < maxRetries-1 {
-			backoff := initialBackoff * (1 << uint(attempt))
-			slog.Warn("Failed to connect to RabbitMQ, retrying",
-				"attempt", attempt+1,
-				"maxRetries", maxRetries,
-				"backoff", backoff,
-				"error", err)
-			time.Sleep(backoff)
-		}
+// NewChannelPool creates a new channel pool.
+// url may list failover brokers separated by commas: they are tried in order on
+// startup and whenever the connection is lost. Startup retries until ctx is done.
+func NewChannelPool(ctx context.Context, url, exchange string, poolSize int) (*ChannelPool, error) {
+	if poolSize <= 0 {
+		poolSize = 10 // Default pool size
 	}
 
+	// Wait for a broker to become available, e.g. during initial cluster deployment
+	// or a RabbitMQ restart, until ctx is done
+	urls := SplitBrokerURLs(url)
+	conn, err := dialWithRetry(ctx, urls, amqp.Dial)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to RabbitMQ after %d attempts: %w", maxRetries, err)
+		return nil, err
 	}
 
 	slog.Info("Connected to RabbitMQ successfully")
 
 	p := &ChannelPool{
 		conn:     conn,
+		urls:     urls,
 		pool:     make(chan *amqp.Channel, poolSize),
 		maxSize:  poolSize,
 		exchange: exchange,
 	}
+	p.channelClosed = (*amqp.Channel).IsClosed
+	p.openChannel = p.createChannel
 
 	// Pre-populate pool with channels
 	for i := 0; i < poolSize; i++ {
@@ -81,9 +81,34 @@ func NewChannelPool(url, exchange string, poolSize int) (*ChannelPool, error) {
 	return p, nil
 }
 
+// connection returns the pool's connection, redialing the brokers in order when it was lost
+func (p *ChannelPool) connection() (*amqp.Connection, error) {
+	p.connMu.Lock()
+	defer p.connMu.Unlock()
+
+	if p.conn != nil && !p.conn.IsClosed() {
+		return p.conn, nil
+	}
+
+	slog.Warn("RabbitMQ connection lost, reconnecting", "brokers", len(p.urls))
+	conn, err := dialFirst(p.urls, amqp.Dial)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconnect to RabbitMQ: %w", err)
+	}
+	slog.Info("Reconnected to RabbitMQ")
+
+	p.conn = conn
+	return conn, nil
+}
+
 // createChannel creates and configures a new channel
 func (p *ChannelPool) createChannel() (*amqp.Channel, error) {
-	ch, err := p.conn.Channel()
+	conn, err := p.connection()
+	if err != nil {
+		return nil, err
+	}
+
+	ch, err := conn.Channel()
 	if err != nil {
 		return nil, fmt.Errorf("failed to open channel: %w", err)
 	}
@@ -103,6 +128,12 @@ func (p *ChannelPool) createChannel() (*amqp.Channel, error) {
 		return nil, fmt.Errorf("failed to declare exchange: %w", err)
 	}
 
+	// Enable publisher confirms so publishes can wait for broker acknowledgement
+	if err := ch.Confirm(false); err != nil {
+		_ = ch.Close()
+		return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+
 	return ch, nil
 }
 
@@ -120,20 +151,65 @@ func (p *ChannelPool) Get(ctx context.Context) (*amqp.Channel, error) {
 		return nil, ctx.Err()
 	}
 
+	var ch *amqp.Channel
 	select {
-	case ch := <-p.pool:
-		// Got a channel from pool - verify it's still open
-		if ch.IsClosed() {
-			// Channel closed, create a new one
-			newCh, err := p.createChannel()
-			if err != nil {
-				return nil, fmt.Errorf("failed to recreate closed channel: %w", err)
+	case ch = <-p.pool:
+		p.observeWait(0, false)
+	default:
+		// Pool is empty, wait for a channel to be returned
+		start := time.Now()
+		var timeout <-chan time.Time
+		if p.acquireTimeout > 0 {
+			timer := time.NewTimer(p.acquireTimeout)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+
+		select {
+		case ch = <-p.pool:
+			p.observeWait(time.Since(start), true)
+		case <-timeout:
+			p.observeWait(time.Since(start), true)
+			if p.observer != nil {
+				p.observer.ObserveChannelPoolExhausted()
 			}
-			return newCh, nil
+			return nil, fmt.Errorf("%w: no channel available within %v", ErrPoolExhausted, p.acquireTimeout)
+		case <-ctx.Done():
+			p.observeWait(time.Since(start), true)
+			return nil, ctx.Err()
 		}
-		return ch, nil
-	case <-ctx.Done():
-		return nil, ctx.Err()
+	}
+
+	// Got a channel from pool - verify it's still open
+	if p.channelClosed(ch) {
+		// Channel closed, create a new one
+		newCh, err := p.openChannel()
+		if err != nil {
+			// Keep the slot while the brokers are down: the closed channel goes back to the
+			// pool, so a later Get recreates it
+			p.Return(ch)
+			return nil, fmt.Errorf("failed to recreate closed channel: %w", err)
+		}
+		return newCh, nil
+	}
+	return ch, nil
+}
+
+// SetAcquireTimeout limits how long Get waits when the pool is empty; it then fails with
+// ErrPoolExhausted instead of queueing callers behind slow publishes. 0 disables the limit.
+// Must be called before the pool is used.
+func (p *ChannelPool) SetAcquireTimeout(timeout time.Duration) {
+	p.acquireTimeout = timeout
+}
+
+// SetObserver sets the observer of Get wait times. Must be called before the pool is used.
+func (p *ChannelPool) SetObserver(observer PoolObserver) {
+	p.observer = observer
+}
+
+func (p *ChannelPool) observeWait(wait time.Duration, blocked bool) {
+	if p.observer != nil {
+		p.observer.ObserveChannelPoolWait(wait, blocked)
 	}
 }
 
@@ -181,6 +257,8 @@ func (p *ChannelPool) Close() error {
 	}
 
 	// Close connection
+	p.connMu.Lock()
+	defer p.connMu.Unlock()
 	if p.conn != nil && !p.conn.IsClosed() {
 		return p.conn.Close()
 	}
@@ -0,0 +1,108 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyBroker stands in for the brokers behind a ChannelPool: channels it opened before
+// the last outage are closed, and no channel can be opened while it is down
+type flakyBroker struct {
+	down   bool
+	closed map[*amqp.Channel]bool
+}
+
+func (b *flakyBroker) outage() {
+	b.down = true
+	for ch := range b.closed {
+		b.closed[ch] = true
+	}
+}
+
+func (b *flakyBroker) openChannel() (*amqp.Channel, error) {
+	if b.down {
+		return nil, errors.New("connection refused")
+	}
+	ch := &amqp.Channel{}
+	b.closed[ch] = false
+	return ch, nil
+}
+
+func newFlakyPool(t *testing.T, size int) (*ChannelPool, *flakyBroker) {
+	t.Helper()
+	broker := &flakyBroker{closed: make(map[*amqp.Channel]bool)}
+	p := &ChannelPool{
+		pool:          make(chan *amqp.Channel, size),
+		maxSize:       size,
+		channelClosed: func(ch *amqp.Channel) bool { return broker.closed[ch] },
+		openChannel:   broker.openChannel,
+	}
+	for range size {
+		ch, err := broker.openChannel()
+		require.NoError(t, err)
+		p.pool <- ch
+	}
+	return p, broker
+}
+
+// TestChannelPool_RecoversAfterOutages verifies that channels closed by a lost connection
+// keep their pool slot while the brokers are down, so the pool is full again once they are back
+func TestChannelPool_RecoversAfterOutages(t *testing.T) {
+	p, broker := newFlakyPool(t, 3)
+	ctx := context.Background()
+
+	for range 5 {
+		broker.outage()
+		for range 2 * p.Capacity() {
+			_, err := p.Get(ctx)
+			assert.ErrorContains(t, err, "failed to recreate closed channel")
+		}
+		assert.Equal(t, p.Capacity(), p.Size(), "failed recreates must not lose pool slots")
+
+		broker.down = false
+		var channels []*amqp.Channel
+		for range p.Capacity() {
+			ch, err := p.Get(ctx)
+			require.NoError(t, err)
+			assert.False(t, broker.closed[ch])
+			channels = append(channels, ch)
+		}
+		for _, ch := range channels {
+			p.Return(ch)
+		}
+		assert.Equal(t, p.Capacity(), p.Size())
+	}
+}
+
+// TestRabbitMQClientPooled_Receive_ReturnsClosedConsumerChannel verifies that a persistent
+// consumer whose deliveries closed gives its channel back to the pool
+func TestRabbitMQClientPooled_Receive_ReturnsClosedConsumerChannel(t *testing.T) {
+	p, broker := newFlakyPool(t, 3)
+	ctx := context.Background()
+	client := &RabbitMQClientPooled{pool: p, consumers: make(map[string]*consumerInfo)}
+
+	for range 5 {
+		for _, queueName := range []string{"asya-happy-end", "asya-error-end"} {
+			ch, err := p.Get(ctx)
+			require.NoError(t, err)
+			deliveries := make(chan amqp.Delivery)
+			close(deliveries)
+			client.consumers[queueName] = &consumerInfo{channel: ch, deliveries: deliveries}
+		}
+		assert.Equal(t, 1, p.Size())
+
+		broker.outage()
+		for _, queueName := range []string{"asya-happy-end", "asya-error-end"} {
+			_, err := client.Receive(ctx, queueName)
+			assert.ErrorContains(t, err, "delivery channel closed")
+			assert.NotContains(t, client.consumers, queueName)
+		}
+		assert.Equal(t, p.Capacity(), p.Size(), "closed consumers must give their channels back")
+		broker.down = false
+	}
+}
@@ -0,0 +1,120 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+)
+
+// MessageFormat is the wire format of envelopes published to actor queues (ASYA_MESSAGE_FORMAT)
+type MessageFormat string
+
+const (
+	// FormatEnvelope publishes asya envelope JSON (default)
+	FormatEnvelope MessageFormat = "envelope"
+	// FormatCloudEvents publishes CloudEvents 1.0 JSON structured-mode events. Actors consuming
+	// them need ASYA_INBOUND_ADAPTER=cloudevents.
+	FormatCloudEvents MessageFormat = "cloudevents"
+)
+
+// ParseMessageFormat parses a message format name, empty meaning FormatEnvelope
+func ParseMessageFormat(name string) (MessageFormat, error) {
+	switch MessageFormat(strings.ToLower(name)) {
+	case "", FormatEnvelope:
+		return FormatEnvelope, nil
+	case FormatCloudEvents:
+		return FormatCloudEvents, nil
+	default:
+		return "", fmt.Errorf("unknown message format %q (supported: %s, %s)", name, FormatEnvelope, FormatCloudEvents)
+	}
+}
+
+// MessageFormatter is implemented by queue clients that can publish envelopes in another format
+type MessageFormatter interface {
+	SetMessageFormat(format MessageFormat)
+}
+
+// CloudEvents attributes of published envelopes
+const (
+	cloudEventsSpecVersion = "1.0"
+	cloudEventsSource      = "/asya/gateway"
+	cloudEventsDefaultType = "sh.asya.envelope" // Type of envelopes not created by a tool
+	cloudEventsContentType = "application/cloudevents+json"
+)
+
+// CloudEvent is a CloudEvents 1.0 structured-mode event carrying an envelope.
+// The route and the other envelope fields without a CloudEvents counterpart travel as
+// "asya*" extension attributes, which must be strings, integers or booleans.
+type CloudEvent struct {
+	SpecVersion     string `json:"specversion"`
+	ID              string `json:"id"`
+	Source          string `json:"source"`
+	Type            string `json:"type"`
+	Time            string `json:"time,omitempty"`
+	DataContentType string `json:"datacontenttype"`
+	Data            any    `json:"data"`
+
+	Route         string `json:"asyaroute"`                   // Comma-separated route actors
+	RouteCurrent  int    `json:"asyaroutecurrent"`            // Index of the actor receiving the event
+	RouteMetadata string `json:"asyaroutemetadata,omitempty"` // Route metadata as a JSON string
+	ParentID      string `json:"asyaparentid,omitempty"`
+	BranchIndex   int    `json:"asyabranchindex,omitempty"`
+	ReplyTo       string `json:"asyareplyto,omitempty"`
+	Deadline      string `json:"asyadeadline,omitempty"`
+}
+
+// newCloudEvent maps an envelope to a CloudEvent: ID to id, tool to type, payload to data
+// and the route to extension attributes
+func newCloudEvent(envelope *types.Envelope) (CloudEvent, error) {
+	msg := newActorEnvelope(envelope)
+
+	event := CloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              msg.ID,
+		Source:          cloudEventsSource,
+		Type:            msg.Tool,
+		DataContentType: "application/json",
+		Data:            msg.Payload,
+		Route:           strings.Join(msg.Route.Actors, ","),
+		RouteCurrent:    msg.Route.Current,
+		BranchIndex:     msg.BranchIndex,
+		ReplyTo:         msg.ReplyTo,
+		Deadline:        msg.Deadline,
+	}
+	if event.Type == "" {
+		event.Type = cloudEventsDefaultType
+	}
+	if !envelope.CreatedAt.IsZero() {
+		event.Time = envelope.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+	if msg.ParentID != nil {
+		event.ParentID = *msg.ParentID
+	}
+	if len(msg.Route.Metadata) > 0 {
+		metadata, err := json.Marshal(msg.Route.Metadata)
+		if err != nil {
+			return CloudEvent{}, fmt.Errorf("failed to marshal route metadata: %w", err)
+		}
+		event.RouteMetadata = string(metadata)
+	}
+	return event, nil
+}
+
+// encodeEnvelope marshals the message published for an envelope in the given format
+// and returns it with its content type
+func encodeEnvelope(envelope *types.Envelope, format MessageFormat) ([]byte, string, error) {
+	if format != FormatCloudEvents {
+		body, err := json.Marshal(newActorEnvelope(envelope))
+		return body, "application/json", err
+	}
+
+	event, err := newCloudEvent(envelope)
+	if err != nil {
+		return nil, "", err
+	}
+	body, err := json.Marshal(event)
+	return body, cloudEventsContentType, err
+}
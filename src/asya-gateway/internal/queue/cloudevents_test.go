@@ -0,0 +1,112 @@
+package queue
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+)
+
+func TestParseMessageFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		want        MessageFormat
+		expectError bool
+	}{
+		{name: "", want: FormatEnvelope},
+		{name: "envelope", want: FormatEnvelope},
+		{name: "CloudEvents", want: FormatCloudEvents},
+		{name: "avro", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMessageFormat(tt.name)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEncodeEnvelope_CloudEvents(t *testing.T) {
+	parentID := "env-0"
+	envelope := &types.Envelope{
+		ID:          "env-1",
+		ParentID:    &parentID,
+		BranchIndex: 2,
+		Tool:        "summarize",
+		ReplyTo:     "asya-reply-1",
+		Route: types.Route{
+			Actors:   []string{"prep", "infer"},
+			Current:  1,
+			Metadata: map[string]interface{}{"lang": "en"},
+		},
+		Payload:   map[string]interface{}{"text": "hi"},
+		Deadline:  time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		CreatedAt: time.Date(2026, 1, 2, 3, 0, 0, 0, time.UTC),
+	}
+
+	body, contentType, err := encodeEnvelope(envelope, FormatCloudEvents)
+	require.NoError(t, err)
+	assert.Equal(t, "application/cloudevents+json", contentType)
+
+	var event map[string]any
+	require.NoError(t, json.Unmarshal(body, &event))
+	assert.Equal(t, map[string]any{
+		"specversion":       "1.0",
+		"id":                "env-1",
+		"source":            "/asya/gateway",
+		"type":              "summarize",
+		"time":              "2026-01-02T03:00:00Z",
+		"datacontenttype":   "application/json",
+		"data":              map[string]any{"text": "hi"},
+		"asyaroute":         "prep,infer",
+		"asyaroutecurrent":  float64(1),
+		"asyaroutemetadata": `{"lang":"en"}`,
+		"asyaparentid":      "env-0",
+		"asyabranchindex":   float64(2),
+		"asyareplyto":       "asya-reply-1",
+		"asyadeadline":      "2026-01-02T03:04:05Z",
+	}, event)
+}
+
+func TestEncodeEnvelope_CloudEventsDefaults(t *testing.T) {
+	envelope := &types.Envelope{
+		ID:    "env-1",
+		Route: types.Route{Actors: []string{"echo"}},
+	}
+
+	body, _, err := encodeEnvelope(envelope, FormatCloudEvents)
+	require.NoError(t, err)
+
+	var event map[string]any
+	require.NoError(t, json.Unmarshal(body, &event))
+	assert.Equal(t, "sh.asya.envelope", event["type"], "envelopes without tool get the default type")
+	assert.Equal(t, float64(0), event["asyaroutecurrent"], "current is always set")
+	assert.Contains(t, event, "data")
+	for _, attr := range []string{"time", "asyaroutemetadata", "asyaparentid", "asyareplyto", "asyadeadline"} {
+		assert.NotContains(t, event, attr)
+	}
+}
+
+func TestEncodeEnvelope_Envelope(t *testing.T) {
+	envelope := &types.Envelope{
+		ID:      "env-1",
+		Tool:    "echo",
+		Route:   types.Route{Actors: []string{"echo"}},
+		Payload: "hi",
+	}
+
+	body, contentType, err := encodeEnvelope(envelope, FormatEnvelope)
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", contentType)
+	assert.JSONEq(t, `{"id":"env-1","tool":"echo","route":{"actors":["echo"],"current":0},"payload":"hi"}`, string(body))
+}
@@ -0,0 +1,77 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// SplitBrokerURLs splits a comma-separated list of RabbitMQ URLs (primary first, then failover brokers)
+func SplitBrokerURLs(urls string) []string {
+	var result []string
+	for _, url := range strings.Split(urls, ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			result = append(result, url)
+		}
+	}
+	return result
+}
+
+// Backoff between startup connection attempts, doubled after each failure
+var (
+	connectMinBackoff = time.Second
+	connectMaxBackoff = 30 * time.Second
+)
+
+// dialWithRetry connects to the first reachable broker, retrying with exponential backoff
+// until a broker accepts the connection or ctx is done, so the gateway waits for a broker
+// that is still starting instead of exiting
+func dialWithRetry(ctx context.Context, urls []string, dial func(url string) (*amqp.Connection, error)) (*amqp.Connection, error) {
+	backoff := connectMinBackoff
+	for attempt := 1; ; attempt++ {
+		conn, err := dialFirst(urls, dial)
+		if err == nil {
+			return conn, nil
+		}
+		if len(urls) == 0 {
+			return nil, err
+		}
+
+		slog.Warn("Failed to connect to RabbitMQ, retrying", "attempt", attempt, "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to connect to RabbitMQ after %d attempts: %w", attempt, err)
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, connectMaxBackoff)
+	}
+}
+
+// dialFirst connects to the first reachable broker, trying urls in order.
+// Brokers are identified by position in logs, since URLs carry credentials.
+func dialFirst(urls []string, dial func(url string) (*amqp.Connection, error)) (*amqp.Connection, error) {
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("no RabbitMQ URL configured")
+	}
+
+	var errs []error
+	for i, url := range urls {
+		conn, err := dial(url)
+		if err == nil {
+			if i > 0 {
+				slog.Warn("Connected to failover RabbitMQ broker", "broker", i, "brokers", len(urls))
+			}
+			return conn, nil
+		}
+		if len(urls) > 1 {
+			slog.Warn("Failed to connect to RabbitMQ broker", "broker", i, "error", err)
+		}
+		errs = append(errs, fmt.Errorf("broker %d: %w", i, err))
+	}
+	return nil, errors.Join(errs...)
+}
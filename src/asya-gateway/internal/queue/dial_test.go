@@ -0,0 +1,108 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitBrokerURLs(t *testing.T) {
+	assert.Equal(t, []string{"amqp://a:5672/"}, SplitBrokerURLs("amqp://a:5672/"))
+	assert.Equal(t, []string{"amqp://a/", "amqp://b/"}, SplitBrokerURLs(" amqp://a/ , amqp://b/,"))
+	assert.Empty(t, SplitBrokerURLs(""))
+}
+
+func TestDialFirst(t *testing.T) {
+	primary := &amqp.Connection{}
+	secondary := &amqp.Connection{}
+	conns := map[string]*amqp.Connection{"amqp://primary/": primary, "amqp://secondary/": secondary}
+
+	dial := func(down ...string) (func(string) (*amqp.Connection, error), *[]string) {
+		var tried []string
+		return func(url string) (*amqp.Connection, error) {
+			tried = append(tried, url)
+			for _, d := range down {
+				if url == d {
+					return nil, errors.New("connection refused")
+				}
+			}
+			return conns[url], nil
+		}, &tried
+	}
+	urls := []string{"amqp://primary/", "amqp://secondary/"}
+
+	t.Run("primary first", func(t *testing.T) {
+		d, tried := dial()
+		conn, err := dialFirst(urls, d)
+		assert.NoError(t, err)
+		assert.Same(t, primary, conn)
+		assert.Equal(t, []string{"amqp://primary/"}, *tried)
+	})
+
+	t.Run("fails over in order", func(t *testing.T) {
+		d, tried := dial("amqp://primary/")
+		conn, err := dialFirst(urls, d)
+		assert.NoError(t, err)
+		assert.Same(t, secondary, conn)
+		assert.Equal(t, urls, *tried)
+	})
+
+	t.Run("all brokers down", func(t *testing.T) {
+		d, _ := dial(urls...)
+		_, err := dialFirst(urls, d)
+		assert.ErrorContains(t, err, "broker 0")
+		assert.ErrorContains(t, err, "broker 1")
+	})
+
+	t.Run("no URLs", func(t *testing.T) {
+		d, _ := dial()
+		_, err := dialFirst(nil, d)
+		assert.Error(t, err)
+	})
+}
+
+func TestDialWithRetry(t *testing.T) {
+	minBackoff, maxBackoff := connectMinBackoff, connectMaxBackoff
+	connectMinBackoff, connectMaxBackoff = time.Millisecond, 4*time.Millisecond
+	t.Cleanup(func() { connectMinBackoff, connectMaxBackoff = minBackoff, maxBackoff })
+
+	broker := &amqp.Connection{}
+	urls := []string{"amqp://broker/"}
+
+	t.Run("waits for the broker to come up", func(t *testing.T) {
+		attempts := 0
+		dial := func(url string) (*amqp.Connection, error) {
+			if attempts++; attempts < 4 {
+				return nil, errors.New("connection refused")
+			}
+			return broker, nil
+		}
+
+		conn, err := dialWithRetry(context.Background(), urls, dial)
+		assert.NoError(t, err)
+		assert.Same(t, broker, conn)
+		assert.Equal(t, 4, attempts)
+	})
+
+	t.Run("gives up when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		dial := func(url string) (*amqp.Connection, error) { return nil, errors.New("connection refused") }
+		_, err := dialWithRetry(ctx, urls, dial)
+		assert.ErrorContains(t, err, "failed to connect to RabbitMQ after")
+		assert.ErrorContains(t, err, "connection refused")
+	})
+
+	t.Run("no URLs are not retried", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := dialWithRetry(ctx, nil, func(string) (*amqp.Connection, error) { return broker, nil })
+		assert.ErrorContains(t, err, "no RabbitMQ URL configured")
+	})
+}
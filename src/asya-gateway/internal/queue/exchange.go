@@ -0,0 +1,62 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+)
+
+// ExchangeDeclarer is implemented by clients that route envelopes through exchanges (RabbitMQ)
+type ExchangeDeclarer interface {
+	// DeclareExchanges declares the pipeline exchanges envelopes are published to besides the
+	// client's own (types.Envelope.Exchange). Terminal queues consumed afterwards are bound to
+	// them as well, so results of every pipeline reach the gateway.
+	DeclareExchanges(ctx context.Context, exchanges []string) error
+}
+
+// envelopeExchange returns the exchange an envelope is published to: its tool's, or the client's
+func envelopeExchange(envelope *types.Envelope, exchange string) string {
+	if envelope.Exchange != "" {
+		return envelope.Exchange
+	}
+	return exchange
+}
+
+// declareExchanges declares durable topic exchanges, like the operator does for actor queues
+func declareExchanges(ch *amqp.Channel, exchanges []string) error {
+	for _, exchange := range exchanges {
+		err := ch.ExchangeDeclare(
+			exchange, // name
+			"topic",  // type
+			true,     // durable
+			false,    // auto-deleted
+			false,    // internal
+			false,    // no-wait
+			nil,      // arguments
+		)
+		if err != nil {
+			return fmt.Errorf("failed to declare exchange %s: %w", exchange, err)
+		}
+	}
+	return nil
+}
+
+// bindQueue binds a queue to each exchange with its name as routing key
+func bindQueue(ch *amqp.Channel, queueName string, exchanges []string) error {
+	for _, exchange := range exchanges {
+		err := ch.QueueBind(
+			queueName, // queue name
+			queueName, // routing key (same as queue name)
+			exchange,  // exchange
+			false,     // no-wait
+			nil,       // args
+		)
+		if err != nil {
+			return fmt.Errorf("failed to bind queue to exchange %s: %w", exchange, err)
+		}
+	}
+	return nil
+}
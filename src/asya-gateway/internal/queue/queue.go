@@ -2,18 +2,83 @@ package queue
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
 )
 
 // ActorEnvelope represents the envelope format sent to actors
 type ActorEnvelope struct {
-	ID       string      `json:"id"`
-	Route    types.Route `json:"route"`
-	Payload  any         `json:"payload"`
-	Deadline string      `json:"deadline,omitempty"` // ISO8601 timestamp
+	ID          string      `json:"id"`
+	ParentID    *string     `json:"parent_id,omitempty"`
+	BranchIndex int         `json:"branch_index,omitempty"`
+	Tool        string      `json:"tool,omitempty"`     // Originating tool, carried by sidecars to every hop
+	ReplyTo     string      `json:"reply_to,omitempty"` // Reply queue of a synchronous tool call
+	Route       types.Route `json:"route"`
+	Payload     any         `json:"payload"`
+	Deadline    string      `json:"deadline,omitempty"` // ISO8601 timestamp
 }
 
+// newActorEnvelope builds the message published for an envelope
+func newActorEnvelope(envelope *types.Envelope) ActorEnvelope {
+	msg := ActorEnvelope{
+		ID:          envelope.ID,
+		ParentID:    envelope.ParentID,
+		BranchIndex: envelope.BranchIndex,
+		Tool:        envelope.Tool,
+		ReplyTo:     envelope.ReplyTo,
+		Route:       envelope.Route,
+		Payload:     envelope.Payload,
+	}
+
+	// Add deadline if envelope has timeout
+	if !envelope.Deadline.IsZero() {
+		msg.Deadline = envelope.Deadline.Format("2006-01-02T15:04:05Z07:00")
+	}
+
+	return msg
+}
+
+// currentActor returns the actor an envelope is published to: the one at route.current.
+// Envelopes resumed or replayed mid-route have a non-zero current and must not go to the first actor.
+func currentActor(envelope *types.Envelope) (string, error) {
+	if len(envelope.Route.Actors) == 0 {
+		return "", fmt.Errorf("route has no actors")
+	}
+	if envelope.Route.Current < 0 || envelope.Route.Current >= len(envelope.Route.Actors) {
+		return "", fmt.Errorf("invalid route.current=%d for actors length %d", envelope.Route.Current, len(envelope.Route.Actors))
+	}
+	return envelope.Route.Actors[envelope.Route.Current], nil
+}
+
+// ErrNoEnvelope is returned by non-blocking Receive implementations when the queue is empty
+var ErrNoEnvelope = errors.New("no envelope available")
+
+// ErrQueueNotFound is returned by InspectQueue when the queue does not exist
+var ErrQueueNotFound = errors.New("queue not found")
+
+// QueueStats is a point-in-time view of a queue
+type QueueStats struct {
+	Messages  int // Messages waiting (approximate for SQS)
+	InFlight  int // Messages received but not yet acknowledged, -1 when the transport does not report it
+	Consumers int // Active consumers, -1 when the transport does not report it
+}
+
+// Inspector is implemented by queue clients that can report queue statistics
+type Inspector interface {
+	// InspectQueue returns statistics of the named queue, or ErrQueueNotFound
+	InspectQueue(ctx context.Context, queueName string) (QueueStats, error)
+}
+
+// ActorQueueName returns the queue an actor consumes from ("asya-" prefix, see the naming convention)
+func ActorQueueName(actor string) string {
+	return "asya-" + actor
+}
+
+// ErrPoolExhausted is returned when no channel became available within the pool's acquire timeout
+var ErrPoolExhausted = errors.New("channel pool exhausted")
+
 // QueueMessage represents a envelope received from a queue
 type QueueMessage interface {
 	Body() []byte
@@ -25,5 +90,28 @@ type Client interface {
 	SendEnvelope(ctx context.Context, envelope *types.Envelope) error
 	Receive(ctx context.Context, queueName string) (QueueMessage, error)
 	Ack(ctx context.Context, msg QueueMessage) error
+	// Nack returns a received message to the queue for redelivery
+	Nack(ctx context.Context, msg QueueMessage) error
 	Close() error
 }
+
+// BatchSender is implemented by clients that can publish many envelopes at once
+// more cheaply than with one SendEnvelope call each
+type BatchSender interface {
+	// SendEnvelopes publishes envelopes in order and returns one error (or nil) per envelope
+	SendEnvelopes(ctx context.Context, envelopes []*types.Envelope) []error
+}
+
+// SendEnvelopes publishes envelopes through the client's batch path when available,
+// falling back to sending them one by one. The result has one error (or nil) per envelope.
+func SendEnvelopes(ctx context.Context, client Client, envelopes []*types.Envelope) []error {
+	if batch, ok := client.(BatchSender); ok {
+		return batch.SendEnvelopes(ctx, envelopes)
+	}
+
+	errs := make([]error, len(envelopes))
+	for i, envelope := range envelopes {
+		errs[i] = client.SendEnvelope(ctx, envelope)
+	}
+	return errs
+}
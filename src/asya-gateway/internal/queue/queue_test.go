@@ -0,0 +1,115 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// sequentialClient implements Client without BatchSender
+type sequentialClient struct {
+	failID string
+	sent   []string
+}
+
+func (c *sequentialClient) SendEnvelope(ctx context.Context, envelope *types.Envelope) error {
+	if envelope.ID == c.failID {
+		return errors.New("publish failed")
+	}
+	c.sent = append(c.sent, envelope.ID)
+	return nil
+}
+
+func (c *sequentialClient) Receive(ctx context.Context, queueName string) (QueueMessage, error) {
+	return nil, ErrNoEnvelope
+}
+
+func (c *sequentialClient) Ack(ctx context.Context, msg QueueMessage) error {
+	return nil
+}
+
+func (c *sequentialClient) Nack(ctx context.Context, msg QueueMessage) error {
+	return nil
+}
+
+func (c *sequentialClient) Close() error {
+	return nil
+}
+
+// batchClient implements BatchSender on top of sequentialClient
+type batchClient struct {
+	sequentialClient
+	batches int
+}
+
+func (c *batchClient) SendEnvelopes(ctx context.Context, envelopes []*types.Envelope) []error {
+	c.batches++
+	errs := make([]error, len(envelopes))
+	for i, envelope := range envelopes {
+		errs[i] = c.SendEnvelope(ctx, envelope)
+	}
+	return errs
+}
+
+func TestSendEnvelopes(t *testing.T) {
+	envelopes := []*types.Envelope{{ID: "env-1"}, {ID: "env-2"}, {ID: "env-3"}}
+
+	t.Run("falls back to per-envelope sends", func(t *testing.T) {
+		client := &sequentialClient{failID: "env-2"}
+
+		errs := SendEnvelopes(context.Background(), client, envelopes)
+
+		assert.Len(t, errs, 3)
+		assert.NoError(t, errs[0])
+		assert.Error(t, errs[1])
+		assert.NoError(t, errs[2])
+		assert.Equal(t, []string{"env-1", "env-3"}, client.sent)
+	})
+
+	t.Run("uses batch sender when available", func(t *testing.T) {
+		client := &batchClient{sequentialClient: sequentialClient{failID: "env-3"}}
+
+		errs := SendEnvelopes(context.Background(), client, envelopes)
+
+		assert.Equal(t, 1, client.batches)
+		assert.Len(t, errs, 3)
+		assert.Error(t, errs[2])
+		assert.Equal(t, []string{"env-1", "env-2"}, client.sent)
+	})
+}
+
+func TestCurrentActor(t *testing.T) {
+	tests := []struct {
+		name    string
+		route   types.Route
+		want    string
+		wantErr bool
+	}{
+		{name: "first actor", route: types.Route{Actors: []string{"prep", "infer", "post"}, Current: 0}, want: "prep"},
+		{name: "resumed at current 1", route: types.Route{Actors: []string{"prep", "infer", "post"}, Current: 1}, want: "infer"},
+		{name: "last actor", route: types.Route{Actors: []string{"prep", "infer", "post"}, Current: 2}, want: "post"},
+		{name: "current past the end", route: types.Route{Actors: []string{"prep", "infer"}, Current: 2}, wantErr: true},
+		{name: "negative current", route: types.Route{Actors: []string{"prep"}, Current: -1}, wantErr: true},
+		{name: "empty route", route: types.Route{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := currentActor(&types.Envelope{ID: "env-1", Route: tt.route})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEnvelopeExchange(t *testing.T) {
+	assert.Equal(t, "asya", envelopeExchange(&types.Envelope{ID: "env-1"}, "asya"))
+	assert.Equal(t, "team-a", envelopeExchange(&types.Envelope{ID: "env-1", Exchange: "team-a"}, "asya"))
+}
@@ -2,8 +2,8 @@ package queue
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"slices"
 	"sync"
 
 	amqp "github.com/rabbitmq/amqp091-go"
@@ -29,15 +29,19 @@ import (
 
 // RabbitMQClient sends envelopes to RabbitMQ
 type RabbitMQClient struct {
-	conn     *amqp.Connection
-	ch       *amqp.Channel
-	exchange string
-	mu       sync.Mutex // Protects channel access for thread-safety
+	conn      *amqp.Connection
+	ch        *amqp.Channel
+	exchange  string
+	exchanges []string                        // Pipeline exchanges besides exchange
+	consumers map[string]<-chan amqp.Delivery // Persistent consumer deliveries per queue
+	mu        sync.Mutex                      // Protects channel access for thread-safety
+	format    MessageFormat                   // Wire format of published envelopes
 }
 
-// NewRabbitMQClient creates a new RabbitMQ client
+// NewRabbitMQClient creates a new RabbitMQ client.
+// url may list failover brokers separated by commas; the first reachable one is used.
 func NewRabbitMQClient(url, exchange string) (*RabbitMQClient, error) {
-	conn, err := amqp.Dial(url)
+	conn, err := dialFirst(SplitBrokerURLs(url), amqp.Dial)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
 	}
@@ -65,54 +69,40 @@ func NewRabbitMQClient(url, exchange string) (*RabbitMQClient, error) {
 	}
 
 	return &RabbitMQClient{
-		conn:     conn,
-		ch:       ch,
-		exchange: exchange,
+		conn:      conn,
+		ch:        ch,
+		exchange:  exchange,
+		consumers: make(map[string]<-chan amqp.Delivery),
 	}, nil
 }
 
 // SendEnvelope sends an envelope to the current actor's queue in the route
 func (c *RabbitMQClient) SendEnvelope(ctx context.Context, envelope *types.Envelope) error {
-	if len(envelope.Route.Actors) == 0 {
-		return fmt.Errorf("route has no actors")
-	}
-	if envelope.Route.Current < 0 || envelope.Route.Current >= len(envelope.Route.Actors) {
-		return fmt.Errorf("invalid route.current=%d for actors length %d", envelope.Route.Current, len(envelope.Route.Actors))
-	}
-
-	// Create actor envelope
-	msg := ActorEnvelope{
-		ID:      envelope.ID,
-		Route:   envelope.Route,
-		Payload: envelope.Payload,
-	}
-
-	// Add deadline if envelope has timeout
-	if !envelope.Deadline.IsZero() {
-		msg.Deadline = envelope.Deadline.Format("2006-01-02T15:04:05Z07:00")
+	actorName, err := currentActor(envelope)
+	if err != nil {
+		return err
 	}
 
-	// Marshal to JSON
-	body, err := json.Marshal(msg)
+	body, contentType, err := encodeEnvelope(envelope, c.format)
 	if err != nil {
 		return fmt.Errorf("failed to marshal envelope: %w", err)
 	}
 
 	// Send envelope to current actor's queue
 	// Use actor name as routing key (sidecar binds queue with actor name, not "asya-" prefixed name)
-	actorName := envelope.Route.Actors[envelope.Route.Current]
 	routingKey := actorName
+	exchange := envelopeExchange(envelope, c.exchange) // Exchange of the tool's pipeline
 
 	// Protect channel access with mutex for thread-safety
 	c.mu.Lock()
 	err = c.ch.PublishWithContext(ctx,
-		c.exchange, // exchange
+		exchange,   // exchange
 		routingKey, // routing key (queue name)
 		false,      // mandatory
 		false,      // immediate
 		amqp.Publishing{
 			DeliveryMode: amqp.Persistent,
-			ContentType:  "application/json",
+			ContentType:  contentType,
 			Body:         body,
 		})
 	c.mu.Unlock()
@@ -124,6 +114,48 @@ func (c *RabbitMQClient) SendEnvelope(ctx context.Context, envelope *types.Envel
 	return nil
 }
 
+// SetMessageFormat sets the wire format of published envelopes (default FormatEnvelope)
+func (c *RabbitMQClient) SetMessageFormat(format MessageFormat) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.format = format
+}
+
+// DeclareExchanges declares pipeline exchanges and binds terminal queues consumed afterwards to them
+func (c *RabbitMQClient) DeclareExchanges(ctx context.Context, exchanges []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := declareExchanges(c.ch, exchanges); err != nil {
+		return err
+	}
+	for _, exchange := range exchanges {
+		if exchange != c.exchange && !slices.Contains(c.exchanges, exchange) {
+			c.exchanges = append(c.exchanges, exchange)
+		}
+	}
+	return nil
+}
+
+// ReplyQueue declares a reply queue on a channel of its own, opening a new channel
+// whenever the queue closes. A lost connection is not redialed, so the queue stays
+// closed until the gateway restarts; the pooled client reconnects.
+func (c *RabbitMQClient) ReplyQueue(ctx context.Context) (*ReplyQueue, error) {
+	return startReplyQueue(ctx, func(ctx context.Context) (string, <-chan amqp.Delivery, error) {
+		ch, err := c.conn.Channel()
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to open channel: %w", err)
+		}
+
+		name, deliveries, err := declareReplyQueue(ch)
+		if err != nil {
+			_ = ch.Close()
+			return "", nil, err
+		}
+		return name, deliveries, nil
+	})
+}
+
 // rabbitMQMessage wraps amqp.Delivery to implement QueueMessage
 type rabbitMQMessage struct {
 	delivery amqp.Delivery
@@ -137,11 +169,39 @@ func (m *rabbitMQMessage) DeliveryTag() uint64 {
 	return m.delivery.DeliveryTag
 }
 
-// Receive receives a envelope from the specified queue
+// Receive receives a envelope from the specified queue using a persistent consumer.
+// Envelopes are pushed by the broker, so Receive blocks until one arrives or ctx is done.
 func (c *RabbitMQClient) Receive(ctx context.Context, queueName string) (QueueMessage, error) {
+	deliveries, err := c.consumerDeliveries(queueName)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case delivery, ok := <-deliveries:
+		if !ok {
+			// Consumer cancelled or channel closed, start a new consumer on next Receive
+			c.mu.Lock()
+			delete(c.consumers, queueName)
+			c.mu.Unlock()
+			return nil, fmt.Errorf("delivery channel closed")
+		}
+		return &rabbitMQMessage{delivery: delivery}, nil
+
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// consumerDeliveries returns the deliveries of the queue's persistent consumer, starting it on first use
+func (c *RabbitMQClient) consumerDeliveries(queueName string) (<-chan amqp.Delivery, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if deliveries, exists := c.consumers[queueName]; exists {
+		return deliveries, nil
+	}
+
 	// Declare queue (idempotent)
 	_, err := c.ch.QueueDeclare(
 		queueName, // name
@@ -155,30 +215,32 @@ func (c *RabbitMQClient) Receive(ctx context.Context, queueName string) (QueueMe
 		return nil, fmt.Errorf("failed to declare queue: %w", err)
 	}
 
-	// Bind queue to exchange
-	err = c.ch.QueueBind(
-		queueName,  // queue name
-		queueName,  // routing key (same as queue name)
-		c.exchange, // exchange
-		false,      // no-wait
-		nil,        // args
-	)
-	if err != nil {
+	// Bind queue to the exchanges of all pipelines
+	if err := bindQueue(c.ch, queueName, append([]string{c.exchange}, c.exchanges...)); err != nil {
 		return nil, fmt.Errorf("failed to bind queue: %w", err)
 	}
 
-	// Get a single envelope
-	delivery, ok, err := c.ch.Get(queueName, false) // autoAck=false
-	if err != nil {
-		return nil, fmt.Errorf("failed to get envelope: %w", err)
+	// Fetch one envelope at a time per consumer
+	if err := c.ch.Qos(1, 0, false); err != nil {
+		return nil, fmt.Errorf("failed to set QoS: %w", err)
 	}
 
-	if !ok {
-		// No envelope available
-		return nil, fmt.Errorf("no envelope available")
+	// Start persistent consumer (NOT cancelled after each envelope)
+	deliveries, err := c.ch.Consume(
+		queueName, // queue
+		"",        // consumer tag (auto-generated)
+		false,     // auto-ack
+		false,     // exclusive
+		false,     // no-local
+		false,     // no-wait
+		nil,       // args
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start consume: %w", err)
 	}
 
-	return &rabbitMQMessage{delivery: delivery}, nil
+	c.consumers[queueName] = deliveries
+	return deliveries, nil
 }
 
 // Ack acknowledges a envelope
@@ -194,6 +256,19 @@ func (c *RabbitMQClient) Ack(ctx context.Context, msg QueueMessage) error {
 	return c.ch.Ack(rmqMsg.delivery.DeliveryTag, false)
 }
 
+// Nack requeues a envelope for redelivery
+func (c *RabbitMQClient) Nack(ctx context.Context, msg QueueMessage) error {
+	rmqMsg, ok := msg.(*rabbitMQMessage)
+	if !ok {
+		return fmt.Errorf("invalid envelope type")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ch.Nack(rmqMsg.delivery.DeliveryTag, false, true)
+}
+
 // Close closes the RabbitMQ connection
 func (c *RabbitMQClient) Close() error {
 	c.mu.Lock()
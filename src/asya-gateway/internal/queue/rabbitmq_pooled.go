@@ -2,8 +2,9 @@ package queue
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"sync"
 
 	amqp "github.com/rabbitmq/amqp091-go"
@@ -23,11 +24,16 @@ type RabbitMQClientPooled struct {
 	pool        *ChannelPool
 	consumers   map[string]*consumerInfo
 	consumersMu sync.Mutex
+	prefetch    int      // Unacked deliveries per persistent consumer
+	exchanges   []string // Pipeline exchanges besides the pool's (guarded by consumersMu)
+
+	format MessageFormat // Wire format of published envelopes, set before publishing
 }
 
-// NewRabbitMQClientPooled creates a new RabbitMQ client with channel pooling
-func NewRabbitMQClientPooled(url, exchange string, poolSize int) (*RabbitMQClientPooled, error) {
-	pool, err := NewChannelPool(url, exchange, poolSize)
+// NewRabbitMQClientPooled creates a new RabbitMQ client with channel pooling,
+// waiting for a broker to accept the connection until ctx is done
+func NewRabbitMQClientPooled(ctx context.Context, url, exchange string, poolSize int) (*RabbitMQClientPooled, error) {
+	pool, err := NewChannelPool(ctx, url, exchange, poolSize)
 	if err != nil {
 		return nil, err
 	}
@@ -35,36 +41,57 @@ func NewRabbitMQClientPooled(url, exchange string, poolSize int) (*RabbitMQClien
 	return &RabbitMQClientPooled{
 		pool:      pool,
 		consumers: make(map[string]*consumerInfo),
+		prefetch:  1,
 	}, nil
 }
 
-// SendEnvelope sends an envelope to the current actor's queue in the route
-func (c *RabbitMQClientPooled) SendEnvelope(ctx context.Context, envelope *types.Envelope) error {
-	if len(envelope.Route.Actors) == 0 {
-		return fmt.Errorf("route has no actors")
-	}
-	if envelope.Route.Current < 0 || envelope.Route.Current >= len(envelope.Route.Actors) {
-		return fmt.Errorf("invalid route.current=%d for actors length %d", envelope.Route.Current, len(envelope.Route.Actors))
+// SetConsumerPrefetch sets how many unacked deliveries each persistent consumer may hold
+// (default 1). Applies to consumers created after the call.
+func (c *RabbitMQClientPooled) SetConsumerPrefetch(n int) {
+	if n < 1 {
+		n = 1
 	}
+	c.consumersMu.Lock()
+	defer c.consumersMu.Unlock()
+	c.prefetch = n
+}
 
-	// Create actor envelope
-	msg := ActorEnvelope{
-		ID:      envelope.ID,
-		Route:   envelope.Route,
-		Payload: envelope.Payload,
+// SetMessageFormat sets the wire format of published envelopes (default FormatEnvelope).
+// Call it before publishing.
+func (c *RabbitMQClientPooled) SetMessageFormat(format MessageFormat) {
+	c.format = format
+}
+
+// DeclareExchanges declares pipeline exchanges and binds terminal queues consumed afterwards to them
+func (c *RabbitMQClientPooled) DeclareExchanges(ctx context.Context, exchanges []string) error {
+	ch, err := c.pool.Get(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get channel from pool: %w", err)
 	}
+	defer c.pool.Return(ch)
 
-	// Add deadline if envelope has timeout
-	if !envelope.Deadline.IsZero() {
-		msg.Deadline = envelope.Deadline.Format("2006-01-02T15:04:05Z07:00")
+	if err := declareExchanges(ch, exchanges); err != nil {
+		return err
 	}
 
-	// Marshal to JSON
-	body, err := json.Marshal(msg)
-	if err != nil {
-		return fmt.Errorf("failed to marshal envelope: %w", err)
+	c.consumersMu.Lock()
+	defer c.consumersMu.Unlock()
+	for _, exchange := range exchanges {
+		if exchange != c.pool.exchange && !slices.Contains(c.exchanges, exchange) {
+			c.exchanges = append(c.exchanges, exchange)
+		}
 	}
+	return nil
+}
+
+// ChannelPool returns the pool of channels used for publishing and consuming
+func (c *RabbitMQClientPooled) ChannelPool() *ChannelPool {
+	return c.pool
+}
 
+// SendEnvelope sends an envelope to the current actor's queue in the route,
+// waiting for the publisher confirm when the channel is in confirm mode
+func (c *RabbitMQClientPooled) SendEnvelope(ctx context.Context, envelope *types.Envelope) error {
 	// Get channel from pool
 	ch, err := c.pool.Get(ctx)
 	if err != nil {
@@ -72,25 +99,90 @@ func (c *RabbitMQClientPooled) SendEnvelope(ctx context.Context, envelope *types
 	}
 	defer c.pool.Return(ch)
 
+	confirm, err := c.publish(ctx, ch, envelope)
+	if err != nil {
+		return err
+	}
+	return awaitConfirm(ctx, confirm)
+}
+
+// SendEnvelopes publishes a batch of envelopes on a single pooled channel,
+// so large submissions do not contend for the pool once per envelope.
+// Publisher confirms are awaited once the whole batch is published rather than
+// per envelope; an envelope the broker nacks is reported as failed.
+func (c *RabbitMQClientPooled) SendEnvelopes(ctx context.Context, envelopes []*types.Envelope) []error {
+	errs := make([]error, len(envelopes))
+
+	ch, err := c.pool.Get(ctx)
+	if err != nil {
+		for i := range errs {
+			errs[i] = fmt.Errorf("failed to get channel from pool: %w", err)
+		}
+		return errs
+	}
+	defer c.pool.Return(ch)
+
+	confirms := make([]*amqp.DeferredConfirmation, len(envelopes))
+	for i, envelope := range envelopes {
+		confirms[i], errs[i] = c.publish(ctx, ch, envelope)
+	}
+
+	for i, confirm := range confirms {
+		if errs[i] == nil {
+			errs[i] = awaitConfirm(ctx, confirm)
+		}
+	}
+	return errs
+}
+
+// awaitConfirm waits for a publisher confirm; a nil confirmation means the channel
+// is not in confirm mode and the publish is not awaited
+func awaitConfirm(ctx context.Context, confirm *amqp.DeferredConfirmation) error {
+	if confirm == nil {
+		return nil
+	}
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed waiting for publisher confirm: %w", err)
+	}
+	if !acked {
+		return fmt.Errorf("publish was nacked by RabbitMQ")
+	}
+	return nil
+}
+
+// publish sends an envelope to the current actor's queue on the given channel.
+// The returned confirmation is nil unless the channel is in confirm mode.
+func (c *RabbitMQClientPooled) publish(ctx context.Context, ch *amqp.Channel, envelope *types.Envelope) (*amqp.DeferredConfirmation, error) {
+	actorName, err := currentActor(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	body, contentType, err := encodeEnvelope(envelope, c.format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal envelope: %w", err)
+	}
+
 	// Send envelope to current actor's queue
 	// Use actor name as routing key (sidecar binds queue with actor name, not "asya-" prefixed name)
-	actorName := envelope.Route.Actors[envelope.Route.Current]
 	routingKey := actorName
-	err = ch.PublishWithContext(ctx,
-		c.pool.exchange, // exchange
-		routingKey,      // routing key (queue name)
-		false,           // mandatory
-		false,           // immediate
+	exchange := envelopeExchange(envelope, c.pool.exchange) // Exchange of the tool's pipeline
+	confirm, err := ch.PublishWithDeferredConfirmWithContext(ctx,
+		exchange,   // exchange
+		routingKey, // routing key (queue name)
+		false,      // mandatory
+		false,      // immediate
 		amqp.Publishing{
 			DeliveryMode: amqp.Persistent,
-			ContentType:  "application/json",
+			ContentType:  contentType,
 			Body:         body,
 		})
 	if err != nil {
-		return fmt.Errorf("failed to publish to RabbitMQ: %w", err)
+		return nil, fmt.Errorf("failed to publish to RabbitMQ: %w", err)
 	}
 
-	return nil
+	return confirm, nil
 }
 
 // pooledRabbitMQMessage wraps amqp.Delivery and channel for pooled operations
@@ -109,6 +201,30 @@ func (m *pooledRabbitMQMessage) DeliveryTag() uint64 {
 	return m.delivery.DeliveryTag
 }
 
+// ReplyQueue declares a reply queue on a dedicated channel of the pool's connection,
+// outside the pool. The queue is declared again on a new channel whenever it closes,
+// reconnecting to the brokers when the connection was lost.
+func (c *RabbitMQClientPooled) ReplyQueue(ctx context.Context) (*ReplyQueue, error) {
+	return startReplyQueue(ctx, func(ctx context.Context) (string, <-chan amqp.Delivery, error) {
+		conn, err := c.pool.connection()
+		if err != nil {
+			return "", nil, err
+		}
+
+		ch, err := conn.Channel()
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to open channel: %w", err)
+		}
+
+		name, deliveries, err := declareReplyQueue(ch)
+		if err != nil {
+			_ = ch.Close()
+			return "", nil, err
+		}
+		return name, deliveries, nil
+	})
+}
+
 // Receive receives a envelope from the specified queue using a persistent consumer
 // This creates ONE consumer per queue (not per Receive call) to avoid consumer leaks
 func (c *RabbitMQClientPooled) Receive(ctx context.Context, queueName string) (QueueMessage, error) {
@@ -139,22 +255,16 @@ func (c *RabbitMQClientPooled) Receive(ctx context.Context, queueName string) (Q
 			return nil, fmt.Errorf("failed to declare queue: %w", err)
 		}
 
-		// Bind queue to exchange
-		err = ch.QueueBind(
-			queueName,       // queue name
-			queueName,       // routing key (same as queue name)
-			c.pool.exchange, // exchange
-			false,           // no-wait
-			nil,             // args
-		)
+		// Bind queue to the exchanges of all pipelines
+		err = bindQueue(ch, queueName, append([]string{c.pool.exchange}, c.exchanges...))
 		if err != nil {
 			c.pool.Return(ch)
 			c.consumersMu.Unlock()
 			return nil, fmt.Errorf("failed to bind queue: %w", err)
 		}
 
-		// Set QoS to fetch one envelope at a time
-		err = ch.Qos(1, 0, false)
+		// Limit unacked deliveries held by this consumer
+		err = ch.Qos(c.prefetch, 0, false)
 		if err != nil {
 			c.pool.Return(ch)
 			c.consumersMu.Unlock()
@@ -190,9 +300,14 @@ func (c *RabbitMQClientPooled) Receive(ctx context.Context, queueName string) (Q
 	select {
 	case delivery, ok := <-consumer.deliveries:
 		if !ok {
-			// Consumer channel closed, remove from map and retry
+			// Consumer channel closed (e.g. connection lost): remove it from the map so the
+			// next Receive starts a new consumer, and give its slot back to the pool, which
+			// recreates the closed channel on Get
 			c.consumersMu.Lock()
-			delete(c.consumers, queueName)
+			if c.consumers[queueName] == consumer {
+				delete(c.consumers, queueName)
+				c.pool.Return(consumer.channel)
+			}
 			c.consumersMu.Unlock()
 			return nil, fmt.Errorf("delivery channel closed")
 		}
@@ -226,6 +341,43 @@ func (c *RabbitMQClientPooled) Ack(ctx context.Context, msg QueueMessage) error
 	return nil
 }
 
+// Nack requeues a envelope for redelivery on the channel that received it
+func (c *RabbitMQClientPooled) Nack(ctx context.Context, msg QueueMessage) error {
+	pooledMsg, ok := msg.(*pooledRabbitMQMessage)
+	if !ok {
+		return fmt.Errorf("invalid envelope type: expected *pooledRabbitMQMessage")
+	}
+
+	err := pooledMsg.channel.Nack(pooledMsg.delivery.DeliveryTag, false, true)
+	if err != nil {
+		return fmt.Errorf("failed to nack envelope: %w", err)
+	}
+
+	return nil
+}
+
+// InspectQueue reports the depth and consumer count of a queue without creating it
+func (c *RabbitMQClientPooled) InspectQueue(ctx context.Context, queueName string) (QueueStats, error) {
+	ch, err := c.pool.Get(ctx)
+	if err != nil {
+		return QueueStats{}, fmt.Errorf("failed to get channel from pool: %w", err)
+	}
+	// A failed passive declare closes the channel; the pool replaces closed channels on Get
+	defer c.pool.Return(ch)
+
+	q, err := ch.QueueDeclarePassive(queueName, true, false, false, false, nil)
+	if err != nil {
+		var amqpErr *amqp.Error
+		if errors.As(err, &amqpErr) && amqpErr.Code == amqp.NotFound {
+			return QueueStats{}, ErrQueueNotFound
+		}
+		return QueueStats{}, fmt.Errorf("failed to inspect queue: %w", err)
+	}
+
+	// RabbitMQ reports only ready messages; unacked ones are not exposed over AMQP
+	return QueueStats{Messages: q.Messages, InFlight: -1, Consumers: q.Consumers}, nil
+}
+
 // Close closes all persistent consumers and the channel pool
 func (c *RabbitMQClientPooled) Close() error {
 	c.consumersMu.Lock()
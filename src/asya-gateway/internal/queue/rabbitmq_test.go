@@ -93,7 +93,8 @@ func TestRabbitMQQueueNaming(t *testing.T) {
 
 			// Since we can't easily inject the mock channel without refactoring,
 			// let's at least verify the routing key construction logic
-			actorName := envelope.Route.Actors[0]
+			actorName, err := currentActor(envelope)
+			assert.NoError(t, err)
 			actualRoutingKey := actorName
 
 			assert.Equal(t, tt.expectedRoutingKey, actualRoutingKey,
@@ -101,3 +102,36 @@ func TestRabbitMQQueueNaming(t *testing.T) {
 		})
 	}
 }
+
+// TestRabbitMQClient_Receive_PushDelivery verifies Receive returns envelopes pushed by the persistent consumer
+func TestRabbitMQClient_Receive_PushDelivery(t *testing.T) {
+	deliveries := make(chan amqp.Delivery, 1)
+	client := &RabbitMQClient{
+		consumers: map[string]<-chan amqp.Delivery{"asya-happy-end": deliveries},
+	}
+
+	// Envelope pushed while Receive is waiting
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		deliveries <- amqp.Delivery{DeliveryTag: 7, Body: []byte(`{"id":"envelope-1"}`)}
+	}()
+
+	msg, err := client.Receive(context.Background(), "asya-happy-end")
+	assert.NoError(t, err)
+	if assert.NotNil(t, msg) {
+		assert.Equal(t, `{"id":"envelope-1"}`, string(msg.Body()))
+		assert.Equal(t, uint64(7), msg.DeliveryTag())
+	}
+
+	// No envelope: Receive blocks until the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = client.Receive(ctx, "asya-happy-end")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Closed consumer is dropped so the next Receive starts a new one
+	close(deliveries)
+	_, err = client.Receive(context.Background(), "asya-happy-end")
+	assert.Error(t, err)
+	assert.NotContains(t, client.consumers, "asya-happy-end")
+}
@@ -0,0 +1,190 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ReplyQueueProvider is implemented by queue clients that can receive direct replies
+// to published envelopes (request-reply), bypassing the terminal queues
+type ReplyQueueProvider interface {
+	// ReplyQueue declares a server-named exclusive queue and starts consuming replies from it.
+	// The queue is declared again whenever it closes (e.g. after a connection loss) until ctx is done.
+	ReplyQueue(ctx context.Context) (*ReplyQueue, error)
+}
+
+// ReplyQueue delivers replies to envelopes published with ReplyTo set to its name.
+// Sidecars publish the happy-end/error-end message of such an envelope to the queue
+// as well, tagged with the envelope ID as correlation ID. Replies nobody waits for are dropped.
+type ReplyQueue struct {
+	mu      sync.Mutex
+	name    string
+	waiters map[string]chan []byte // Envelope ID -> waiter
+	closed  atomic.Bool
+
+	declare replyDeclarer // Recreates the queue after it closed (nil: the queue stays closed)
+}
+
+// replyDeclarer declares a reply queue and returns its name and deliveries
+type replyDeclarer func(ctx context.Context) (string, <-chan amqp.Delivery, error)
+
+// NewReplyQueue creates a reply queue named name. Replies are handed to it with Deliver,
+// which the RabbitMQ clients do for the queues they declare.
+func NewReplyQueue(name string) *ReplyQueue {
+	return &ReplyQueue{
+		name:    name,
+		waiters: make(map[string]chan []byte),
+	}
+}
+
+// Name returns the queue actors publish replies to. It changes when the queue is recreated.
+func (q *ReplyQueue) Name() string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.name
+}
+
+// Closed reports whether the queue stopped receiving replies (e.g. after a connection loss)
+// and was not recreated yet
+func (q *ReplyQueue) Closed() bool {
+	return q.closed.Load()
+}
+
+// Wait registers interest in the reply to an envelope. Call it before publishing the envelope,
+// and Cancel once done waiting. The channel receives the terminal message body once, or is
+// closed without a reply when the queue closes.
+func (q *ReplyQueue) Wait(envelopeID string) <-chan []byte {
+	ch := make(chan []byte, 1)
+	q.mu.Lock()
+	q.waiters[envelopeID] = ch
+	q.mu.Unlock()
+	return ch
+}
+
+// Cancel stops waiting for the reply to an envelope
+func (q *ReplyQueue) Cancel(envelopeID string) {
+	q.mu.Lock()
+	delete(q.waiters, envelopeID)
+	q.mu.Unlock()
+}
+
+// Deliver hands a reply to its waiter, reporting whether there was one
+func (q *ReplyQueue) Deliver(correlationID string, body []byte) bool {
+	q.mu.Lock()
+	ch, ok := q.waiters[correlationID]
+	delete(q.waiters, correlationID)
+	q.mu.Unlock()
+
+	if ok {
+		ch <- body
+	}
+	return ok
+}
+
+// close marks the queue closed and releases the waiters: replies published to
+// the lost queue never arrive, so they stop waiting right away
+func (q *ReplyQueue) close() {
+	q.closed.Store(true)
+
+	q.mu.Lock()
+	for id, ch := range q.waiters {
+		close(ch)
+		delete(q.waiters, id)
+	}
+	q.mu.Unlock()
+}
+
+// consume delivers replies until the deliveries channel is closed
+func (q *ReplyQueue) consume(deliveries <-chan amqp.Delivery) {
+	name := q.Name()
+	for d := range deliveries {
+		if !q.Deliver(d.CorrelationId, d.Body) {
+			slog.Debug("Dropping reply nobody waits for", "queue", name, "correlationId", d.CorrelationId)
+		}
+	}
+	q.close()
+	slog.Warn("Reply queue closed, synchronous tool calls fall back to async responses", "queue", name)
+}
+
+// run consumes deliveries and, whenever the queue closes, declares a new one with
+// exponential backoff until ctx is done
+func (q *ReplyQueue) run(ctx context.Context, deliveries <-chan amqp.Delivery) {
+	for {
+		q.consume(deliveries)
+		if q.declare == nil {
+			return
+		}
+
+		backoff := connectMinBackoff
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+
+			name, redeclared, err := q.declare(ctx)
+			if err == nil {
+				q.mu.Lock()
+				q.name = name
+				q.mu.Unlock()
+				q.closed.Store(false)
+				deliveries = redeclared
+				slog.Info("Reply queue recreated, synchronous tool calls wait for replies again", "queue", name)
+				break
+			}
+			slog.Warn("Failed to recreate reply queue, retrying", "backoff", backoff, "error", err)
+			backoff = min(backoff*2, connectMaxBackoff)
+		}
+	}
+}
+
+// startReplyQueue declares a reply queue with declare and consumes it until ctx is done,
+// declaring it again with declare whenever it closes (e.g. after a connection loss)
+func startReplyQueue(ctx context.Context, declare replyDeclarer) (*ReplyQueue, error) {
+	name, deliveries, err := declare(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	q := NewReplyQueue(name)
+	q.declare = declare
+	go q.run(ctx, deliveries)
+	return q, nil
+}
+
+// declareReplyQueue declares a server-named, exclusive, auto-deleted queue on ch and
+// consumes it with auto-ack. The queue lives as long as the channel.
+func declareReplyQueue(ch *amqp.Channel) (string, <-chan amqp.Delivery, error) {
+	declared, err := ch.QueueDeclare(
+		"",    // name (server-named, amq.gen-...)
+		false, // durable
+		true,  // delete when unused
+		true,  // exclusive
+		false, // no-wait
+		nil,   // arguments
+	)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to declare reply queue: %w", err)
+	}
+
+	deliveries, err := ch.Consume(
+		declared.Name, // queue
+		"",            // consumer tag (auto-generated)
+		true,          // auto-ack
+		true,          // exclusive
+		false,         // no-local
+		false,         // no-wait
+		nil,           // args
+	)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to consume reply queue: %w", err)
+	}
+	return declared.Name, deliveries, nil
+}
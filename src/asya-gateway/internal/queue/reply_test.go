@@ -0,0 +1,148 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestReplyQueue_Deliver(t *testing.T) {
+	q := NewReplyQueue("amq.gen-test")
+
+	reply := q.Wait("env-1")
+	if q.Deliver("env-2", []byte("other")) {
+		t.Error("Deliver of a reply nobody waits for should report false")
+	}
+	if !q.Deliver("env-1", []byte("done")) {
+		t.Fatal("Deliver to a waiter should report true")
+	}
+
+	select {
+	case body := <-reply:
+		if string(body) != "done" {
+			t.Errorf("reply = %q, want done", body)
+		}
+	default:
+		t.Fatal("Expected reply to be delivered")
+	}
+
+	// Each reply is delivered once
+	if q.Deliver("env-1", []byte("again")) {
+		t.Error("Second reply to the same envelope should be dropped")
+	}
+}
+
+func TestReplyQueue_Cancel(t *testing.T) {
+	q := NewReplyQueue("amq.gen-test")
+
+	q.Wait("env-1")
+	q.Cancel("env-1")
+
+	if q.Deliver("env-1", []byte("late")) {
+		t.Error("Reply after Cancel should be dropped")
+	}
+}
+
+func TestReplyQueue_Consume(t *testing.T) {
+	q := NewReplyQueue("amq.gen-test")
+	reply := q.Wait("env-1")
+
+	deliveries := make(chan amqp.Delivery, 2)
+	deliveries <- amqp.Delivery{CorrelationId: "unknown", Body: []byte("dropped")}
+	deliveries <- amqp.Delivery{CorrelationId: "env-1", Body: []byte("done")}
+	close(deliveries)
+
+	done := make(chan struct{})
+	go func() {
+		q.consume(deliveries)
+		close(done)
+	}()
+
+	select {
+	case body := <-reply:
+		if string(body) != "done" {
+			t.Errorf("reply = %q, want done", body)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected reply to be delivered")
+	}
+
+	<-done
+	if !q.Closed() {
+		t.Error("Reply queue should be closed once deliveries end")
+	}
+}
+
+func TestReplyQueue_CloseReleasesWaiters(t *testing.T) {
+	q := NewReplyQueue("amq.gen-test")
+	reply := q.Wait("env-1")
+
+	deliveries := make(chan amqp.Delivery)
+	close(deliveries)
+	q.consume(deliveries)
+
+	select {
+	case body, ok := <-reply:
+		if ok {
+			t.Errorf("Expected closed waiter, got reply %q", body)
+		}
+	default:
+		t.Fatal("Waiter should be released when the queue closes")
+	}
+}
+
+func TestReplyQueue_RecreatedAfterClose(t *testing.T) {
+	minBackoff, maxBackoff := connectMinBackoff, connectMaxBackoff
+	connectMinBackoff, connectMaxBackoff = time.Millisecond, 4*time.Millisecond
+	t.Cleanup(func() { connectMinBackoff, connectMaxBackoff = minBackoff, maxBackoff })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The first redeclare fails, e.g. while the broker is still down
+	first := make(chan amqp.Delivery)
+	second := make(chan amqp.Delivery, 1)
+	declares := 0
+	declare := func(ctx context.Context) (string, <-chan amqp.Delivery, error) {
+		declares++
+		switch declares {
+		case 1:
+			return "amq.gen-1", first, nil
+		case 2:
+			return "", nil, errors.New("connection refused")
+		default:
+			return "amq.gen-2", second, nil
+		}
+	}
+
+	q, err := startReplyQueue(ctx, declare)
+	if err != nil {
+		t.Fatalf("startReplyQueue failed: %v", err)
+	}
+	if q.Name() != "amq.gen-1" {
+		t.Fatalf("Name() = %q, want amq.gen-1", q.Name())
+	}
+
+	close(first)
+	deadline := time.Now().Add(time.Second)
+	for q.Name() != "amq.gen-2" || q.Closed() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Reply queue not recreated: name = %q, closed = %v", q.Name(), q.Closed())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	reply := q.Wait("env-1")
+	second <- amqp.Delivery{CorrelationId: "env-1", Body: []byte("done")}
+	select {
+	case body := <-reply:
+		if string(body) != "done" {
+			t.Errorf("reply = %q, want done", body)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected reply on the recreated queue")
+	}
+}
@@ -2,14 +2,16 @@ package queue
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strconv"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
 
 	"github.com/deliveryhero/asya/asya-gateway/pkg/types"
 )
@@ -35,7 +37,9 @@ type sqsClient interface {
 	ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
 	SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
 	DeleteMessage(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error)
+	ChangeMessageVisibility(ctx context.Context, params *sqs.ChangeMessageVisibilityInput, optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error)
 	GetQueueUrl(ctx context.Context, params *sqs.GetQueueUrlInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueUrlOutput, error)
+	GetQueueAttributes(ctx context.Context, params *sqs.GetQueueAttributesInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error)
 }
 
 // SQSClient implements the Client interface for AWS SQS
@@ -46,6 +50,7 @@ type SQSClient struct {
 	visibilityTimeout int32
 	waitTimeSeconds   int32
 	queueURLCache     map[string]string
+	format            MessageFormat // Wire format of published envelopes, set before publishing
 }
 
 // SQSConfig holds SQS-specific configuration
@@ -162,36 +167,26 @@ func (m *sqsMessage) DeliveryTag() uint64 {
 	return m.deliveryTag
 }
 
+// SetMessageFormat sets the wire format of published envelopes (default FormatEnvelope).
+// Call it before publishing.
+func (c *SQSClient) SetMessageFormat(format MessageFormat) {
+	c.format = format
+}
+
 // SendEnvelope sends an envelope to the current actor's queue in the route
 func (c *SQSClient) SendEnvelope(ctx context.Context, envelope *types.Envelope) error {
-	if len(envelope.Route.Actors) == 0 {
-		return fmt.Errorf("route has no actors")
-	}
-	if envelope.Route.Current < 0 || envelope.Route.Current >= len(envelope.Route.Actors) {
-		return fmt.Errorf("invalid route.current=%d for actors length %d", envelope.Route.Current, len(envelope.Route.Actors))
-	}
-
-	// Create actor envelope
-	msg := ActorEnvelope{
-		ID:      envelope.ID,
-		Route:   envelope.Route,
-		Payload: envelope.Payload,
-	}
-
-	// Add deadline if envelope has timeout
-	if !envelope.Deadline.IsZero() {
-		msg.Deadline = envelope.Deadline.Format("2006-01-02T15:04:05Z07:00")
+	actorName, err := currentActor(envelope)
+	if err != nil {
+		return err
 	}
 
-	// Marshal to JSON
-	body, err := json.Marshal(msg)
+	body, _, err := encodeEnvelope(envelope, c.format)
 	if err != nil {
 		return fmt.Errorf("failed to marshal envelope: %w", err)
 	}
 
 	// Get queue URL for current actor
 	// Add "asya-" prefix to convert actor name to queue name
-	actorName := envelope.Route.Actors[envelope.Route.Current]
 	queueName := fmt.Sprintf("asya-%s", actorName)
 	queueURL, err := c.resolveQueueURL(ctx, queueName)
 	if err != nil {
@@ -255,6 +250,34 @@ func (c *SQSClient) Receive(ctx context.Context, queueName string) (QueueMessage
 	}
 }
 
+// InspectQueue reports the approximate depth of a queue; SQS does not expose consumers
+func (c *SQSClient) InspectQueue(ctx context.Context, queueName string) (QueueStats, error) {
+	queueURL, err := c.resolveQueueURL(ctx, queueName)
+	if err != nil {
+		var notFound *sqstypes.QueueDoesNotExist
+		if errors.As(err, &notFound) {
+			return QueueStats{}, ErrQueueNotFound
+		}
+		return QueueStats{}, fmt.Errorf("failed to resolve queue URL: %w", err)
+	}
+
+	resp, err := c.client.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl: aws.String(queueURL),
+		AttributeNames: []sqstypes.QueueAttributeName{
+			sqstypes.QueueAttributeNameApproximateNumberOfMessages,
+			sqstypes.QueueAttributeNameApproximateNumberOfMessagesNotVisible,
+		},
+	})
+	if err != nil {
+		return QueueStats{}, fmt.Errorf("failed to get queue attributes: %w", err)
+	}
+
+	stats := QueueStats{Consumers: -1}
+	stats.Messages, _ = strconv.Atoi(resp.Attributes[string(sqstypes.QueueAttributeNameApproximateNumberOfMessages)])
+	stats.InFlight, _ = strconv.Atoi(resp.Attributes[string(sqstypes.QueueAttributeNameApproximateNumberOfMessagesNotVisible)])
+	return stats, nil
+}
+
 // Ack acknowledges a message by deleting it from the queue
 func (c *SQSClient) Ack(ctx context.Context, msg QueueMessage) error {
 	sqsMsg, ok := msg.(*sqsMessage)
@@ -273,6 +296,25 @@ func (c *SQSClient) Ack(ctx context.Context, msg QueueMessage) error {
 	return nil
 }
 
+// Nack makes a message visible again right away instead of after the visibility timeout
+func (c *SQSClient) Nack(ctx context.Context, msg QueueMessage) error {
+	sqsMsg, ok := msg.(*sqsMessage)
+	if !ok {
+		return fmt.Errorf("invalid message type: expected *sqsMessage")
+	}
+
+	_, err := c.client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(sqsMsg.queueURL),
+		ReceiptHandle:     aws.String(sqsMsg.receiptHandle),
+		VisibilityTimeout: 0,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to nack message: %w", err)
+	}
+
+	return nil
+}
+
 // Close closes the SQS client (no-op for SQS)
 func (c *SQSClient) Close() error {
 	return nil
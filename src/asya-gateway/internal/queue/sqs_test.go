@@ -2,6 +2,7 @@ package queue
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -40,6 +41,14 @@ func (m *mockSQSClient) DeleteMessage(ctx context.Context, params *sqs.DeleteMes
 	return args.Get(0).(*sqs.DeleteMessageOutput), args.Error(1)
 }
 
+func (m *mockSQSClient) ChangeMessageVisibility(ctx context.Context, params *sqs.ChangeMessageVisibilityInput, optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*sqs.ChangeMessageVisibilityOutput), args.Error(1)
+}
+
 func (m *mockSQSClient) GetQueueUrl(ctx context.Context, params *sqs.GetQueueUrlInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueUrlOutput, error) {
 	args := m.Called(ctx, params)
 	if args.Get(0) == nil {
@@ -48,6 +57,14 @@ func (m *mockSQSClient) GetQueueUrl(ctx context.Context, params *sqs.GetQueueUrl
 	return args.Get(0).(*sqs.GetQueueUrlOutput), args.Error(1)
 }
 
+func (m *mockSQSClient) GetQueueAttributes(ctx context.Context, params *sqs.GetQueueAttributesInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*sqs.GetQueueAttributesOutput), args.Error(1)
+}
+
 // TestSQSQueueNaming tests that actor names are prefixed with "asya-" for SQS queue names
 // This is SQS-specific behavior: actor "data-processor" -> queue "asya-data-processor"
 func TestSQSQueueNaming(t *testing.T) {
@@ -113,6 +130,72 @@ func TestSQSQueueNaming(t *testing.T) {
 	}
 }
 
+// TestSQSSendEnvelope_CurrentActor verifies envelopes are published to the queue of route.current,
+// not the first actor, and that an out-of-range current is rejected without publishing
+func TestSQSSendEnvelope_CurrentActor(t *testing.T) {
+	mockClient := new(mockSQSClient)
+	mockClient.On("GetQueueUrl", mock.Anything, mock.MatchedBy(func(params *sqs.GetQueueUrlInput) bool {
+		return *params.QueueName == "asya-infer"
+	})).Return(&sqs.GetQueueUrlOutput{
+		QueueUrl: stringPtr("http://sqs:4566/000000000000/asya-infer"),
+	}, nil)
+	mockClient.On("SendMessage", mock.Anything, mock.MatchedBy(func(params *sqs.SendMessageInput) bool {
+		return *params.QueueUrl == "http://sqs:4566/000000000000/asya-infer"
+	})).Return(&sqs.SendMessageOutput{}, nil)
+
+	sqsClient := &SQSClient{
+		client:        mockClient,
+		region:        "us-east-1",
+		baseURL:       "http://sqs:4566",
+		queueURLCache: make(map[string]string),
+	}
+
+	envelope := &types.Envelope{
+		ID:      "test-envelope-1",
+		Route:   types.Route{Actors: []string{"prep", "infer", "post"}, Current: 1},
+		Payload: map[string]interface{}{"test": "data"},
+	}
+	assert.NoError(t, sqsClient.SendEnvelope(context.Background(), envelope))
+	mockClient.AssertExpectations(t)
+
+	envelope.Route.Current = 3
+	assert.Error(t, sqsClient.SendEnvelope(context.Background(), envelope))
+	mockClient.AssertNumberOfCalls(t, "SendMessage", 1)
+}
+
+func TestSQSSendEnvelope_Body(t *testing.T) {
+	var sent ActorEnvelope
+	mockClient := new(mockSQSClient)
+	mockClient.On("GetQueueUrl", mock.Anything, mock.Anything).Return(&sqs.GetQueueUrlOutput{
+		QueueUrl: stringPtr("http://sqs:4566/000000000000/asya-prep"),
+	}, nil)
+	mockClient.On("SendMessage", mock.Anything, mock.MatchedBy(func(params *sqs.SendMessageInput) bool {
+		return json.Unmarshal([]byte(*params.MessageBody), &sent) == nil
+	})).Return(&sqs.SendMessageOutput{}, nil)
+
+	sqsClient := &SQSClient{
+		client:        mockClient,
+		region:        "us-east-1",
+		baseURL:       "http://sqs:4566",
+		queueURLCache: make(map[string]string),
+	}
+
+	parentID := "parent-1"
+	envelope := &types.Envelope{
+		ID:          "parent-1-2",
+		ParentID:    &parentID,
+		BranchIndex: 2,
+		Tool:        "summarize",
+		Route:       types.Route{Actors: []string{"prep"}},
+		Payload:     map[string]interface{}{"test": "data"},
+	}
+	assert.NoError(t, sqsClient.SendEnvelope(context.Background(), envelope))
+
+	assert.Equal(t, "summarize", sent.Tool)
+	assert.Equal(t, &parentID, sent.ParentID)
+	assert.Equal(t, 2, sent.BranchIndex)
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
@@ -0,0 +1,222 @@
+// Package scaler serves the KEDA external scaler gRPC API, so AsyncActors can scale on
+// what the gateway knows about their queues instead of the raw queue length.
+package scaler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/deliveryhero/asya/asya-gateway/internal/queue"
+	pb "github.com/deliveryhero/asya/asya-gateway/internal/scaler/externalscaler"
+)
+
+const (
+	// DefaultAddr is the listen address of the scaler, used when ASYA_SCALER_ADDR is empty
+	DefaultAddr = ":9090"
+
+	// DefaultTargetSize is the number of messages per replica when the trigger sets no targetSize
+	DefaultTargetSize = 5
+
+	// DefaultStreamInterval is how often StreamIsActive re-inspects the queue
+	DefaultStreamInterval = 5 * time.Second
+
+	// inspectTimeout bounds the inspection of a single queue
+	inspectTimeout = 5 * time.Second
+)
+
+// Trigger metadata keys (spec.triggers[].metadata of the ScaledObject)
+const (
+	metadataActor               = "actor"               // Actor whose queue is measured (required)
+	metadataTargetSize          = "targetSize"          // Messages per replica
+	metadataActivationThreshold = "activationThreshold" // Waiting messages needed to scale from zero
+)
+
+// Server implements the KEDA external scaler service on top of queue statistics.
+// An actor is active while more messages than its activation threshold wait, or while
+// any message is in flight, so replicas are not scaled to zero in the middle of processing.
+type Server struct {
+	pb.UnimplementedExternalScalerServer
+
+	inspector      queue.Inspector
+	streamInterval time.Duration
+}
+
+// NewServer creates a scaler reporting the queues of inspector
+func NewServer(inspector queue.Inspector) *Server {
+	return &Server{inspector: inspector, streamInterval: DefaultStreamInterval}
+}
+
+// SetStreamInterval sets how often StreamIsActive re-inspects the queue (default 5s)
+func (s *Server) SetStreamInterval(interval time.Duration) {
+	if interval > 0 {
+		s.streamInterval = interval
+	}
+}
+
+// trigger is the parsed metadata of a ScaledObject trigger
+type trigger struct {
+	actor               string
+	targetSize          int64
+	activationThreshold int64
+}
+
+// parseTrigger reads the trigger metadata of a ScaledObject
+func parseTrigger(ref *pb.ScaledObjectRef) (trigger, error) {
+	metadata := ref.GetScalerMetadata()
+	t := trigger{actor: metadata[metadataActor], targetSize: DefaultTargetSize}
+	if t.actor == "" {
+		return t, fmt.Errorf("metadata %q is required", metadataActor)
+	}
+
+	if v, ok := metadata[metadataTargetSize]; ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			return t, fmt.Errorf("metadata %q must be a positive integer, got %q", metadataTargetSize, v)
+		}
+		t.targetSize = n
+	}
+	if v, ok := metadata[metadataActivationThreshold]; ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return t, fmt.Errorf("metadata %q must be a non-negative integer, got %q", metadataActivationThreshold, v)
+		}
+		t.activationThreshold = n
+	}
+	return t, nil
+}
+
+// metricName is the name of the queue metric of an actor
+func (t trigger) metricName() string {
+	return queue.ActorQueueName(t.actor)
+}
+
+// inspect returns the statistics of the trigger's actor queue
+func (s *Server) inspect(ctx context.Context, t trigger) (queue.QueueStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, inspectTimeout)
+	defer cancel()
+
+	stats, err := s.inspector.InspectQueue(ctx, t.metricName())
+	switch {
+	case errors.Is(err, queue.ErrQueueNotFound):
+		return stats, status.Errorf(codes.NotFound, "queue %s does not exist", t.metricName())
+	case err != nil:
+		slog.Warn("Failed to inspect queue for scaler", "queue", t.metricName(), "error", err)
+		return stats, status.Errorf(codes.Unavailable, "failed to inspect queue %s: %v", t.metricName(), err)
+	}
+	return stats, nil
+}
+
+// active reports whether the actor needs at least one replica
+func (t trigger) active(stats queue.QueueStats) bool {
+	return int64(stats.Messages) > t.activationThreshold || stats.InFlight > 0
+}
+
+// backlog is the metric value of an actor: messages waiting or being processed
+func backlog(stats queue.QueueStats) int64 {
+	return int64(stats.Messages) + int64(max(stats.InFlight, 0))
+}
+
+// IsActive reports whether the actor should be scaled up from zero
+func (s *Server) IsActive(ctx context.Context, ref *pb.ScaledObjectRef) (*pb.IsActiveResponse, error) {
+	t, err := parseTrigger(ref)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	stats, err := s.inspect(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.IsActiveResponse{Result: t.active(stats)}, nil
+}
+
+// StreamIsActive pushes the activity of the actor whenever it changes, until KEDA disconnects
+func (s *Server) StreamIsActive(ref *pb.ScaledObjectRef, stream pb.ExternalScaler_StreamIsActiveServer) error {
+	t, err := parseTrigger(ref)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	ticker := time.NewTicker(s.streamInterval)
+	defer ticker.Stop()
+
+	var sent, wasActive bool
+	for {
+		stats, err := s.inspect(stream.Context(), t)
+		if err == nil {
+			if active := t.active(stats); !sent || active != wasActive {
+				if err := stream.Send(&pb.IsActiveResponse{Result: active}); err != nil {
+					return err
+				}
+				sent, wasActive = true, active
+			}
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetMetricSpec returns the target backlog per replica
+func (s *Server) GetMetricSpec(ctx context.Context, ref *pb.ScaledObjectRef) (*pb.GetMetricSpecResponse, error) {
+	t, err := parseTrigger(ref)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &pb.GetMetricSpecResponse{
+		MetricSpecs: []*pb.MetricSpec{{MetricName: t.metricName(), TargetSize: t.targetSize}},
+	}, nil
+}
+
+// GetMetrics returns the backlog of the actor queue
+func (s *Server) GetMetrics(ctx context.Context, req *pb.GetMetricsRequest) (*pb.GetMetricsResponse, error) {
+	t, err := parseTrigger(req.GetScaledObjectRef())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	stats, err := s.inspect(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.GetMetricsResponse{
+		MetricValues: []*pb.MetricValue{{MetricName: t.metricName(), MetricValue: backlog(stats)}},
+	}, nil
+}
+
+// Start serves the scaler on addr until ctx is cancelled
+func Start(ctx context.Context, addr string, server *Server) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	grpcServer := grpc.NewServer()
+	pb.RegisterExternalScalerServer(grpcServer, server)
+
+	go func() {
+		<-ctx.Done()
+		// Streams only end when KEDA disconnects, so don't wait for them
+		grpcServer.Stop()
+	}()
+
+	slog.Info("KEDA external scaler listening", "addr", addr)
+	if err := grpcServer.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
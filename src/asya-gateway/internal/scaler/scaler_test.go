@@ -0,0 +1,190 @@
+package scaler
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/deliveryhero/asya/asya-gateway/internal/queue"
+	pb "github.com/deliveryhero/asya/asya-gateway/internal/scaler/externalscaler"
+)
+
+// fakeInspector returns fixed stats or errors per queue
+type fakeInspector struct {
+	mu    sync.Mutex
+	stats map[string]queue.QueueStats
+	errs  map[string]error
+}
+
+func (f *fakeInspector) InspectQueue(ctx context.Context, queueName string) (queue.QueueStats, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err, ok := f.errs[queueName]; ok {
+		return queue.QueueStats{}, err
+	}
+	return f.stats[queueName], nil
+}
+
+func (f *fakeInspector) set(queueName string, stats queue.QueueStats) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stats[queueName] = stats
+}
+
+// dial serves s over an in-memory connection and returns a client for it
+func dial(t *testing.T, s *Server) pb.ExternalScalerClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	pb.RegisterExternalScalerServer(grpcServer, s)
+	go func() { _ = grpcServer.Serve(listener) }()
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial scaler: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return pb.NewExternalScalerClient(conn)
+}
+
+func ref(metadata map[string]string) *pb.ScaledObjectRef {
+	return &pb.ScaledObjectRef{Name: "infer", Namespace: "default", ScalerMetadata: metadata}
+}
+
+func TestServer_IsActive(t *testing.T) {
+	inspector := &fakeInspector{
+		stats: map[string]queue.QueueStats{
+			"asya-idle":    {Messages: 0, InFlight: 0},
+			"asya-waiting": {Messages: 3, InFlight: -1},
+			"asya-busy":    {Messages: 0, InFlight: 2},
+		},
+		errs: map[string]error{
+			"asya-missing": queue.ErrQueueNotFound,
+			"asya-broken":  errors.New("channel closed"),
+		},
+	}
+	client := dial(t, NewServer(inspector))
+
+	tests := []struct {
+		name     string
+		metadata map[string]string
+		want     bool
+		wantCode codes.Code
+	}{
+		{name: "empty queue", metadata: map[string]string{"actor": "idle"}, want: false},
+		{name: "waiting messages", metadata: map[string]string{"actor": "waiting"}, want: true},
+		{name: "below activation threshold", metadata: map[string]string{"actor": "waiting", "activationThreshold": "3"}, want: false},
+		{name: "messages in flight", metadata: map[string]string{"actor": "busy", "activationThreshold": "10"}, want: true},
+		{name: "missing actor", metadata: map[string]string{}, wantCode: codes.InvalidArgument},
+		{name: "invalid threshold", metadata: map[string]string{"actor": "idle", "activationThreshold": "-1"}, wantCode: codes.InvalidArgument},
+		{name: "missing queue", metadata: map[string]string{"actor": "missing"}, wantCode: codes.NotFound},
+		{name: "inspection failure", metadata: map[string]string{"actor": "broken"}, wantCode: codes.Unavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.IsActive(context.Background(), ref(tt.metadata))
+			if tt.wantCode != codes.OK {
+				if status.Code(err) != tt.wantCode {
+					t.Fatalf("IsActive() error = %v, want code %v", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("IsActive() error = %v", err)
+			}
+			if resp.GetResult() != tt.want {
+				t.Errorf("IsActive() = %v, want %v", resp.GetResult(), tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_Metrics(t *testing.T) {
+	inspector := &fakeInspector{stats: map[string]queue.QueueStats{
+		"asya-infer": {Messages: 12, InFlight: 4},
+		"asya-prep":  {Messages: 7, InFlight: -1},
+	}}
+	client := dial(t, NewServer(inspector))
+
+	spec, err := client.GetMetricSpec(context.Background(), ref(map[string]string{"actor": "infer", "targetSize": "8"}))
+	if err != nil {
+		t.Fatalf("GetMetricSpec() error = %v", err)
+	}
+	if len(spec.GetMetricSpecs()) != 1 || spec.GetMetricSpecs()[0].GetMetricName() != "asya-infer" || spec.GetMetricSpecs()[0].GetTargetSize() != 8 {
+		t.Errorf("GetMetricSpec() = %v, want asya-infer with target 8", spec.GetMetricSpecs())
+	}
+
+	spec, err = client.GetMetricSpec(context.Background(), ref(map[string]string{"actor": "infer"}))
+	if err != nil {
+		t.Fatalf("GetMetricSpec() error = %v", err)
+	}
+	if got := spec.GetMetricSpecs()[0].GetTargetSize(); got != DefaultTargetSize {
+		t.Errorf("default target size = %d, want %d", got, DefaultTargetSize)
+	}
+
+	tests := []struct {
+		actor string
+		want  int64
+	}{
+		{actor: "infer", want: 16}, // Waiting and in flight
+		{actor: "prep", want: 7},   // In-flight count not reported by the transport
+	}
+	for _, tt := range tests {
+		resp, err := client.GetMetrics(context.Background(), &pb.GetMetricsRequest{
+			ScaledObjectRef: ref(map[string]string{"actor": tt.actor}),
+			MetricName:      "asya-" + tt.actor,
+		})
+		if err != nil {
+			t.Fatalf("GetMetrics(%s) error = %v", tt.actor, err)
+		}
+		if len(resp.GetMetricValues()) != 1 || resp.GetMetricValues()[0].GetMetricValue() != tt.want {
+			t.Errorf("GetMetrics(%s) = %v, want %d", tt.actor, resp.GetMetricValues(), tt.want)
+		}
+	}
+}
+
+func TestServer_StreamIsActive(t *testing.T) {
+	inspector := &fakeInspector{stats: map[string]queue.QueueStats{"asya-infer": {}}}
+	s := NewServer(inspector)
+	s.SetStreamInterval(10 * time.Millisecond)
+	client := dial(t, s)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.StreamIsActive(ctx, ref(map[string]string{"actor": "infer"}))
+	if err != nil {
+		t.Fatalf("StreamIsActive() error = %v", err)
+	}
+
+	// The current activity is sent right away, then only changes
+	for i, want := range []bool{false, true, false} {
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv() %d error = %v", i, err)
+		}
+		if resp.GetResult() != want {
+			t.Fatalf("Recv() %d = %v, want %v", i, resp.GetResult(), want)
+		}
+
+		if want {
+			inspector.set("asya-infer", queue.QueueStats{})
+		} else {
+			inspector.set("asya-infer", queue.QueueStats{Messages: 1})
+		}
+	}
+}
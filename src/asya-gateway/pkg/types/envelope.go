@@ -1,12 +1,16 @@
 package types
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // EnvelopeStatus represents the current state of an envelope (MCP-style lowercase)
 type EnvelopeStatus string
 
 const (
 	EnvelopeStatusPending   EnvelopeStatus = "pending"
+	EnvelopeStatusQueued    EnvelopeStatus = "queued" // Published to the first actor's queue, not yet picked up by an actor
 	EnvelopeStatusRunning   EnvelopeStatus = "running"
 	EnvelopeStatusSucceeded EnvelopeStatus = "succeeded"
 	EnvelopeStatusFailed    EnvelopeStatus = "failed"
@@ -22,34 +26,58 @@ const (
 //
 // Example fanout from envelope "abc-123" returning 3 items:
 //   - Index 0: ID = "abc-123"      (original ID, SSE clients can track this)
-//   - Index 1: ID = "abc-123-1"    (fanout child)
-//   - Index 2: ID = "abc-123-2"    (fanout child)
+//   - Index 1: ID = "abc-123-1"    BranchIndex = 1 (fanout child)
+//   - Index 2: ID = "abc-123-2"    BranchIndex = 2 (fanout child)
+//
+// All fanout children have ParentID set to the original envelope ID for traceability,
+// and BranchIndex set to their position in the fanout.
+//
+// Fanout Result Aggregation:
+// The gateway counts fanout branches on the parent (FanoutBranches = the parent's own branch
+// plus one per child). The parent stays running until every branch reaches a final state,
+// then its Result becomes an array of branch results (parent branch first, then children
+// in branch order) and it is marked succeeded, or failed if any branch failed.
 //
-// All fanout children have ParentID set to the original envelope ID for traceability.
 // This design ensures:
 //   - SSE streaming works for at least the first fanout envelope
 //   - Fanout children don't overwrite each other in the database
 //   - Parent-child relationships are explicit via ParentID field
 //   - Log queries can find all related envelopes via ID prefix matching
 type Envelope struct {
-	ID               string                 `json:"id"`
-	ParentID         *string                `json:"parent_id,omitempty"` // Set for fanout children (index > 0)
-	Status           EnvelopeStatus         `json:"status"`
-	Route            Route                  `json:"route"`
-	Headers          map[string]interface{} `json:"headers,omitempty"`
-	Payload          any                    `json:"payload"`
-	Result           any                    `json:"result,omitempty"`
-	Error            string                 `json:"error,omitempty"`
-	TimeoutSec       int                    `json:"timeout_seconds,omitempty"` // Total timeout in seconds
-	Deadline         time.Time              `json:"deadline,omitempty"`        // Absolute deadline
-	ProgressPercent  float64                `json:"progress_percent"`
-	CurrentActorIdx  int                    `json:"current_actor_idx"`
-	CurrentActorName string                 `json:"current_actor_name,omitempty"`
-	Message          string                 `json:"message,omitempty"` // Current progress message
-	ActorsCompleted  int                    `json:"actors_completed"`
-	TotalActors      int                    `json:"total_actors"`
-	CreatedAt        time.Time              `json:"created_at"`
-	UpdatedAt        time.Time              `json:"updated_at"`
+	ID                string                 `json:"id"`
+	ParentID          *string                `json:"parent_id,omitempty"`    // Set for fanout children (index > 0)
+	BranchIndex       int                    `json:"branch_index,omitempty"` // Position within the parent's fanout (index > 0)
+	Tool              string                 `json:"tool,omitempty"`         // Tool that created the envelope (inherited by fanout children)
+	ReplyTo           string                 `json:"-"`                      // Reply queue of a synchronous tool call, only sent to actors
+	Exchange          string                 `json:"-"`                      // RabbitMQ exchange of the tool's pipeline, empty for the gateway's exchange
+	Status            EnvelopeStatus         `json:"status"`
+	Route             Route                  `json:"route"`
+	Headers           map[string]interface{} `json:"headers,omitempty"`
+	Payload           any                    `json:"payload"`
+	Result            any                    `json:"result,omitempty"`
+	ResultURL         string                 `json:"result_url,omitempty"`     // Object store reference of an offloaded result (Result is then empty)
+	PartialResult     any                    `json:"partial_result,omitempty"` // Latest intermediate output reported while running (never the final result)
+	Warnings          []string               `json:"warnings,omitempty"`       // Warnings reported by actors that succeeded, in report order
+	Error             string                 `json:"error,omitempty"`
+	TimeoutSec        int                    `json:"timeout_seconds,omitempty"` // Total timeout in seconds
+	Deadline          time.Time              `json:"deadline,omitempty"`        // Absolute deadline
+	ProgressPercent   float64                `json:"progress_percent"`
+	CurrentActorIdx   int                    `json:"current_actor_idx"`
+	CurrentActorName  string                 `json:"current_actor_name,omitempty"`
+	Message           string                 `json:"message,omitempty"` // Current progress message
+	ActorsCompleted   int                    `json:"actors_completed"`
+	TotalActors       int                    `json:"total_actors"`
+	FanoutBranches    int                    `json:"fanout_branches,omitempty"`    // Branches to aggregate (own + fanout children), 0 without fanout
+	BranchesCompleted int                    `json:"branches_completed,omitempty"` // Branches that reached a final state
+	StatusHistory     []StatusTransition     `json:"status_history,omitempty"`     // When the envelope entered each status, oldest first
+	CreatedAt         time.Time              `json:"created_at"`
+	UpdatedAt         time.Time              `json:"updated_at"`
+}
+
+// StatusTransition records when an envelope entered a status
+type StatusTransition struct {
+	Status EnvelopeStatus `json:"status"`
+	At     time.Time      `json:"at"`
 }
 
 // Route represents the envelope routing information
@@ -74,9 +102,12 @@ type Route struct {
 // final status updates (from end actors like happy-end/error-end).
 type EnvelopeUpdate struct {
 	ID              string         `json:"id"`
-	Status          EnvelopeStatus `json:"status"`                      // Envelope status (pending/running/succeeded/failed)
+	Status          EnvelopeStatus `json:"status"`                      // Envelope status (pending/queued/running/succeeded/failed)
 	Message         string         `json:"message,omitempty"`           // Human-readable status message
 	Result          any            `json:"result,omitempty"`            // Final result (only for final states)
+	ResultURL       string         `json:"result_url,omitempty"`        // Object store reference replacing a large final result
+	PartialResult   any            `json:"partial_result,omitempty"`    // Intermediate output (only for progress updates)
+	Warnings        []string       `json:"warnings,omitempty"`          // Warnings added by this update (appended to the envelope's)
 	Error           string         `json:"error,omitempty"`             // Error message (only for failed status)
 	ProgressPercent *float64       `json:"progress_percent,omitempty"`  // Progress 0-100 (nil if not a progress update)
 	Actor           string         `json:"actor,omitempty"`             // Current actor name (for progress updates)
@@ -112,11 +143,20 @@ type EnvelopeUpdate struct {
 // 1. "received" - Message pulled from queue, before forwarding to runtime
 // 2. "processing" - Message sent to runtime via Unix socket
 // 3. "completed" - Runtime returned successful response
+//
+// Long-running actors (e.g. streaming generation) may attach a PartialResult to
+// show incremental output to SSE clients. It is size-capped by the gateway and
+// never replaces the final result reported by happy-end.
+//
+// Actors that succeed with warnings (e.g. "used fallback model") report them with the
+// "completed" update; the gateway accumulates them on the envelope without failing it.
 type ProgressUpdate struct {
-	ID              string   `json:"id"`
-	Actors          []string `json:"actors"`            // Full route (may differ from original if actor modified it)
-	CurrentActorIdx int      `json:"current_actor_idx"` // Index of current actor being processed (0-based)
-	Status          string   `json:"status"`            // Actor status: "received" | "processing" | "completed"
-	Message         string   `json:"message,omitempty"` // Optional progress message
-	ProgressPercent float64  `json:"progress_percent"`  // Calculated by gateway based on actor progress
+	ID              string          `json:"id"`
+	Actors          []string        `json:"actors"`                   // Full route (may differ from original if actor modified it)
+	CurrentActorIdx int             `json:"current_actor_idx"`        // Index of current actor being processed (0-based)
+	Status          string          `json:"status"`                   // Actor status: "received" | "processing" | "completed"
+	Message         string          `json:"message,omitempty"`        // Optional progress message
+	ProgressPercent float64         `json:"progress_percent"`         // Calculated by gateway based on actor progress
+	PartialResult   json.RawMessage `json:"partial_result,omitempty"` // Optional intermediate output (size-capped)
+	Warnings        []string        `json:"warnings,omitempty"`       // Warnings of an actor that succeeded (with "completed")
 }
@@ -0,0 +1,56 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxEnvelopeIDLength is the maximum accepted length of an envelope ID
+const MaxEnvelopeIDLength = 128
+
+var (
+	// Letters, digits, '.', '_' and '-', starting with a letter or digit
+	envelopeIDRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+	// Canonical UUID at the start of an ID (gateway IDs, optionally with fanout suffixes)
+	uuidPrefixRegex = regexp.MustCompile(`^[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12}`)
+)
+
+// ValidateEnvelopeID checks that id is a well-formed envelope ID.
+//
+// Gateway-created IDs are UUIDs or (optionally prefixed) ULIDs and fanout children
+// append "-{index}", but envelopes may also originate from other producers, so any ID of up to MaxEnvelopeIDLength
+// letters, digits, '.', '_' and '-' (starting with a letter or digit) is accepted.
+// This keeps IDs safe to use as database keys, URL path segments and log values.
+func ValidateEnvelopeID(id string) error {
+	if id == "" {
+		return fmt.Errorf("envelope ID is empty")
+	}
+	if len(id) > MaxEnvelopeIDLength {
+		return fmt.Errorf("envelope ID exceeds %d characters", MaxEnvelopeIDLength)
+	}
+	if !envelopeIDRegex.MatchString(id) {
+		return fmt.Errorf("envelope ID %q contains invalid characters", id)
+	}
+	return nil
+}
+
+// NormalizeEnvelopeID trims surrounding whitespace and lowercases a leading UUID,
+// so IDs match regardless of how clients format the UUID part
+func NormalizeEnvelopeID(id string) string {
+	id = strings.TrimSpace(id)
+	if loc := uuidPrefixRegex.FindStringIndex(id); loc != nil {
+		id = strings.ToLower(id[:loc[1]]) + id[loc[1]:]
+	}
+	return id
+}
+
+// ParseEnvelopeID normalizes and validates an envelope ID from an untrusted source
+func ParseEnvelopeID(id string) (string, error) {
+	id = NormalizeEnvelopeID(id)
+	if err := ValidateEnvelopeID(id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
@@ -0,0 +1,39 @@
+package types
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseEnvelopeID(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		want    string
+		wantErr bool
+	}{
+		{name: "uuid", id: "5e6fdb2d-1d6b-4e91-baef-73e825434e7b", want: "5e6fdb2d-1d6b-4e91-baef-73e825434e7b"},
+		{name: "uppercase uuid is lowercased", id: "5E6FDB2D-1D6B-4E91-BAEF-73E825434E7B", want: "5e6fdb2d-1d6b-4e91-baef-73e825434e7b"},
+		{name: "fanout child suffix", id: "5E6FDB2D-1D6B-4E91-BAEF-73E825434E7B-2", want: "5e6fdb2d-1d6b-4e91-baef-73e825434e7b-2"},
+		{name: "foreign id keeps case", id: "Order_42.v1", want: "Order_42.v1"},
+		{name: "surrounding whitespace trimmed", id: "  env-1 ", want: "env-1"},
+		{name: "empty", id: "", wantErr: true},
+		{name: "slash", id: "env/1", wantErr: true},
+		{name: "leading dash", id: "-env", wantErr: true},
+		{name: "control characters", id: "env\n1", wantErr: true},
+		{name: "sql-ish", id: "1';DROP TABLE envelopes;--", wantErr: true},
+		{name: "too long", id: strings.Repeat("a", MaxEnvelopeIDLength+1), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseEnvelopeID(tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseEnvelopeID(%q) error = %v, wantErr %v", tt.id, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseEnvelopeID(%q) = %q, want %q", tt.id, got, tt.want)
+			}
+		})
+	}
+}
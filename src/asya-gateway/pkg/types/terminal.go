@@ -0,0 +1,29 @@
+package types
+
+import "time"
+
+// TerminalMessage is the message sidecars send to the happy-end and error-end queues.
+//
+// Version 1 messages carry the outcome at the top level (Status, CompletedAt, Error, Details)
+// and the final result in Payload. Messages without a version come from older sidecars:
+// their errors are wrapped in the payload as {"error", "details", "original_payload"}.
+type TerminalMessage struct {
+	ID          string                `json:"id"`
+	ParentID    *string               `json:"parent_id,omitempty"`
+	BranchIndex int                   `json:"branch_index,omitempty"`
+	Tool        string                `json:"tool,omitempty"` // Originating tool, absent in messages from older sidecars
+	Route       Route                 `json:"route"`
+	Payload     any                   `json:"payload"`
+	Version     int                   `json:"version,omitempty"`
+	Status      EnvelopeStatus        `json:"status,omitempty"`
+	CompletedAt time.Time             `json:"completed_at,omitempty"`
+	Error       string                `json:"error,omitempty"`
+	Details     *TerminalErrorDetails `json:"details,omitempty"`
+}
+
+// TerminalErrorDetails describes the runtime error behind a failed terminal message
+type TerminalErrorDetails struct {
+	Message   string `json:"message,omitempty"`
+	Type      string `json:"type,omitempty"`
+	Traceback string `json:"traceback,omitempty"`
+}
@@ -12,6 +12,13 @@ type AsyncActorSpec struct {
 	// +kubebuilder:validation:MinLength=1
 	Transport string `json:"transport"`
 
+	// RabbitMQ exchange of the actor's pipeline, overriding the transport's exchange.
+	// The actor queue is bound only to this exchange and the sidecar publishes to it,
+	// so pipelines on different exchanges cannot route into each other's queues.
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9_.:-]{1,255}$`
+	// +optional
+	Exchange string `json:"exchange,omitempty"`
+
 	// Sidecar container configuration
 	// +optional
 	Sidecar SidecarConfig `json:"sidecar,omitempty"`
@@ -20,6 +27,10 @@ type AsyncActorSpec struct {
 	// +optional
 	Timeout TimeoutConfig `json:"timeout,omitempty"`
 
+	// Progress reporting to the gateway
+	// +optional
+	Progress ProgressConfig `json:"progress,omitempty"`
+
 	// KEDA autoscaling configuration
 	// +optional
 	Scaling ScalingConfig `json:"scaling,omitempty"`
@@ -27,6 +38,11 @@ type AsyncActorSpec struct {
 	// Workload template for the actor runtime
 	// +kubebuilder:validation:Required
 	Workload WorkloadConfig `json:"workload"`
+
+	// ServiceAccount of the actor pods. With SQS IRSA (actorRoleArn), the operator creates it
+	// with the role annotation if absent (default asya-<actor name>) and uses an existing one as-is.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
 }
 
 // SidecarConfig defines sidecar container configuration
@@ -48,6 +64,16 @@ type SidecarConfig struct {
 	// Additional environment variables
 	// +optional
 	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// ConfigMaps and Secrets whose keys become environment variables of the sidecar
+	// and runtime containers. Variables set explicitly (env, operator-managed) take precedence.
+	// +optional
+	EnvFrom []corev1.EnvFromSource `json:"envFrom,omitempty"`
+
+	// Additional volume mounts for every injected sidecar, referencing volumes
+	// of the pod template or workload.extraVolumes
+	// +optional
+	ExtraVolumeMounts []corev1.VolumeMount `json:"extraVolumeMounts,omitempty"`
 }
 
 // TimeoutConfig defines timeout configuration
@@ -63,6 +89,15 @@ type TimeoutConfig struct {
 	GracefulShutdown int `json:"gracefulShutdown,omitempty"`
 }
 
+// ProgressConfig defines progress reporting configuration
+type ProgressConfig struct {
+	// Report the intermediate processing progress of every message to the gateway (default true).
+	// Disable for high-throughput actors to save one of three gateway requests per message.
+	// The received and completed updates (status transitions), fanout and final status are always reported.
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
 // ScalingConfig defines KEDA autoscaling configuration
 type ScalingConfig struct {
 	// Enable KEDA autoscaling
@@ -98,9 +133,31 @@ type ScalingConfig struct {
 	// +optional
 	QueueLength int `json:"queueLength,omitempty"`
 
+	// Address (host:port) of a KEDA external scaler, e.g. the gateway's (ASYA_ENABLE_SCALER).
+	// When set, the actor scales on the scaler's queue activity instead of the transport's queue trigger.
+	// +optional
+	ExternalScalerAddress string `json:"externalScalerAddress,omitempty"`
+
+	// Waiting messages needed to scale from zero with the external scaler
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ActivationQueueLength int `json:"activationQueueLength,omitempty"`
+
 	// Advanced scaling modifiers for KEDA
 	// +optional
 	Advanced *AdvancedScalingConfig `json:"advanced,omitempty"`
+
+	// Target average CPU utilization (percent of requests) of the HorizontalPodAutoscaler
+	// created instead of a ScaledObject when KEDA is not installed (operator --hpa-fallback).
+	// Defaults to 80 when neither CPU nor memory target is set.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TargetCPUUtilization *int32 `json:"targetCPUUtilization,omitempty"`
+
+	// Target average memory utilization (percent of requests) of the fallback HorizontalPodAutoscaler
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TargetMemoryUtilization *int32 `json:"targetMemoryUtilization,omitempty"`
 }
 
 // AdvancedScalingConfig defines advanced KEDA scaling options
@@ -146,11 +203,46 @@ type WorkloadConfig struct {
 	// +optional
 	PythonExecutable string `json:"pythonExecutable,omitempty"`
 
+	// Runtime containers, each served by its own injected sidecar over its own socket
+	// (defaults to a single container named asya-runtime)
+	// +optional
+	RuntimeContainers []RuntimeContainerConfig `json:"runtimeContainers,omitempty"`
+
+	// Additional pod volumes, e.g. a PVC for a model cache. Names must not collide
+	// with the pod template's volumes or the volumes injected by the operator.
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	ExtraVolumes []corev1.Volume `json:"extraVolumes,omitempty"`
+
+	// Additional volume mounts for every runtime container, referencing volumes
+	// of the pod template or extraVolumes
+	// +optional
+	ExtraVolumeMounts []corev1.VolumeMount `json:"extraVolumeMounts,omitempty"`
+
+	// Secrets for pulling the sidecar and runtime images from private registries,
+	// added to the pod template's own pull secrets and the operator defaults
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
 	// Pod template
 	// +kubebuilder:validation:Required
 	Template PodTemplateSpec `json:"template"`
 }
 
+// RuntimeContainerConfig defines a runtime container and its socket location
+type RuntimeContainerConfig struct {
+	// Name of the runtime container in the pod template
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Directory for the runtime socket inside the runtime and its sidecar (defaults to /var/run/asya)
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	SocketDir string `json:"socketDir,omitempty"`
+}
+
 // PodTemplateSpec is a simplified pod template
 type PodTemplateSpec struct {
 	// Metadata
@@ -222,6 +314,11 @@ type AsyncActorStatus struct {
 	// +optional
 	TransportStatus string `json:"transportStatus,omitempty"`
 
+	// BoundExchanges are the RabbitMQ exchanges the actor queue was last bound to.
+	// Used to unbind the queue from exchanges it left when spec.exchange changes.
+	// +optional
+	BoundExchanges []string `json:"boundExchanges,omitempty"`
+
 	// WorkloadRef is a reference to the created workload (Deployment or StatefulSet)
 	// +optional
 	WorkloadRef *WorkloadReference `json:"workloadRef,omitempty"`
@@ -260,7 +357,7 @@ type AsyncActorStatus struct {
 	LastScaleDirection string `json:"lastScaleDirection,omitempty"`
 
 	// ScalingMode indicates the scaling mode for kubectl output.
-	// Values: "KEDA" (autoscaling enabled), "Manual" (fixed replicas)
+	// Values: "KEDA" (autoscaling enabled), "HPA" (autoscaling enabled, KEDA not installed), "Manual" (fixed replicas)
 	// Displayed in kubectl -o wide output as SCALING column.
 	// +optional
 	ScalingMode string `json:"scalingMode,omitempty"`
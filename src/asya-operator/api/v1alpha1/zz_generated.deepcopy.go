@@ -105,6 +105,7 @@ func (in *AsyncActorSpec) DeepCopyInto(out *AsyncActorSpec) {
 	*out = *in
 	in.Sidecar.DeepCopyInto(&out.Sidecar)
 	out.Timeout = in.Timeout
+	in.Progress.DeepCopyInto(&out.Progress)
 	in.Scaling.DeepCopyInto(&out.Scaling)
 	in.Workload.DeepCopyInto(&out.Workload)
 }
@@ -149,6 +150,11 @@ func (in *AsyncActorStatus) DeepCopyInto(out *AsyncActorStatus) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.BoundExchanges != nil {
+		in, out := &in.BoundExchanges, &out.BoundExchanges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.WorkloadRef != nil {
 		in, out := &in.WorkloadRef, &out.WorkloadRef
 		*out = new(WorkloadReference)
@@ -227,6 +233,41 @@ func (in *PodTemplateSpec) DeepCopy() *PodTemplateSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProgressConfig) DeepCopyInto(out *ProgressConfig) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProgressConfig.
+func (in *ProgressConfig) DeepCopy() *ProgressConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ProgressConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RuntimeContainerConfig) DeepCopyInto(out *RuntimeContainerConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RuntimeContainerConfig.
+func (in *RuntimeContainerConfig) DeepCopy() *RuntimeContainerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(RuntimeContainerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ScalingConfig) DeepCopyInto(out *ScalingConfig) {
 	*out = *in
@@ -245,6 +286,16 @@ func (in *ScalingConfig) DeepCopyInto(out *ScalingConfig) {
 		*out = new(AdvancedScalingConfig)
 		**out = **in
 	}
+	if in.TargetCPUUtilization != nil {
+		in, out := &in.TargetCPUUtilization, &out.TargetCPUUtilization
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TargetMemoryUtilization != nil {
+		in, out := &in.TargetMemoryUtilization, &out.TargetMemoryUtilization
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScalingConfig.
@@ -268,6 +319,20 @@ func (in *SidecarConfig) DeepCopyInto(out *SidecarConfig) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.EnvFrom != nil {
+		in, out := &in.EnvFrom, &out.EnvFrom
+		*out = make([]v1.EnvFromSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExtraVolumeMounts != nil {
+		in, out := &in.ExtraVolumeMounts, &out.ExtraVolumeMounts
+		*out = make([]v1.VolumeMount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SidecarConfig.
@@ -303,6 +368,30 @@ func (in *WorkloadConfig) DeepCopyInto(out *WorkloadConfig) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.RuntimeContainers != nil {
+		in, out := &in.RuntimeContainers, &out.RuntimeContainers
+		*out = make([]RuntimeContainerConfig, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExtraVolumes != nil {
+		in, out := &in.ExtraVolumes, &out.ExtraVolumes
+		*out = make([]v1.Volume, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExtraVolumeMounts != nil {
+		in, out := &in.ExtraVolumeMounts, &out.ExtraVolumeMounts
+		*out = make([]v1.VolumeMount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]v1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
 	in.Template.DeepCopyInto(&out.Template)
 }
 
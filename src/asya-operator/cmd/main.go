@@ -43,6 +43,8 @@ func main() {
 	var probeAddr string
 	var runtimeNamespace string
 	var maxConcurrentReconciles int
+	var hpaFallback bool
+	var scrapeAnnotations bool
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -57,6 +59,10 @@ func main() {
 	// Controller configuration
 	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", getEnvIntOrDefault("ASYA_MAX_CONCURRENT_RECONCILES", 10),
 		"Maximum number of concurrent AsyncActor reconciliations")
+	flag.BoolVar(&hpaFallback, "hpa-fallback", getEnvBoolOrDefault("ASYA_HPA_FALLBACK", false),
+		"Scale actors with CPU/memory HorizontalPodAutoscalers when KEDA is not installed")
+	flag.BoolVar(&scrapeAnnotations, "prometheus-scrape-annotations", getEnvBoolOrDefault("ASYA_PROMETHEUS_SCRAPE_ANNOTATIONS", true),
+		"Add prometheus.io scrape annotations for the sidecar metrics endpoint to actor pods")
 
 	opts := zap.Options{
 		Development: true,
@@ -95,13 +101,30 @@ func main() {
 	// Read gateway URL from environment
 	gatewayURL := os.Getenv("ASYA_GATEWAY_URL")
 
+	// Detect KEDA once at startup; without it, autoscaled actors get native HPAs if enabled
+	kedaInstalled, err := controller.KEDAInstalled(mgr.GetRESTMapper())
+	if err != nil {
+		setupLog.Error(err, "unable to detect KEDA, assuming it is installed")
+		kedaInstalled = true
+	}
+	switch {
+	case !kedaInstalled && hpaFallback:
+		setupLog.Info("KEDA not installed, scaling actors with CPU/memory HorizontalPodAutoscalers")
+	case !kedaInstalled:
+		setupLog.Info("WARNING: KEDA CRDs not installed, actors with spec.scaling.enabled are not autoscaled. " +
+			"Install KEDA and restart the operator, or run it with --hpa-fallback")
+	}
+
 	asyncActorReconciler := &controller.AsyncActorReconciler{
-		Client:                  mgr.GetClient(),
-		Scheme:                  mgr.GetScheme(),
-		TransportRegistry:       transportRegistry,
-		TransportFactory:        transportFactory,
-		MaxConcurrentReconciles: maxConcurrentReconciles,
-		GatewayURL:              gatewayURL,
+		Client:                      mgr.GetClient(),
+		Scheme:                      mgr.GetScheme(),
+		TransportRegistry:           transportRegistry,
+		TransportFactory:            transportFactory,
+		MaxConcurrentReconciles:     maxConcurrentReconciles,
+		GatewayURL:                  gatewayURL,
+		HPAFallback:                 !kedaInstalled && hpaFallback,
+		KEDAMissing:                 !kedaInstalled && !hpaFallback,
+		PrometheusScrapeAnnotations: scrapeAnnotations,
 	}
 	if err = asyncActorReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "AsyncActor")
@@ -163,6 +186,16 @@ func getEnvOrDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvBoolOrDefault gets a boolean environment variable or returns a default value
+func getEnvBoolOrDefault(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
 // getEnvIntOrDefault gets an integer environment variable or returns a default value
 func getEnvIntOrDefault(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
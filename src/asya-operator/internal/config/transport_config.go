@@ -106,6 +106,10 @@ type SQSConfig struct {
 	Credentials       *SQSCredentialsConfig `json:"credentials,omitempty"`
 	Queues            QueueManagementConfig `json:"queues"`
 	Tags              map[string]string     `json:"tags,omitempty"`
+
+	// Fraction of visibilityTimeout after which sidecars extend the visibility of a message
+	// in processing (sidecar default 0.5, 0 disables)
+	VisibilityExtension *float64 `json:"visibilityExtension,omitempty"`
 }
 
 // SQSCredentialsConfig defines AWS credentials for SQS
@@ -283,6 +287,9 @@ func (t *TransportConfig) BuildEnvVars() ([]corev1.EnvVar, error) {
 		if config.WaitTimeSeconds > 0 {
 			env = append(env, corev1.EnvVar{Name: "ASYA_SQS_WAIT_TIME_SECONDS", Value: fmt.Sprintf("%d", config.WaitTimeSeconds)})
 		}
+		if config.VisibilityExtension != nil {
+			env = append(env, corev1.EnvVar{Name: "ASYA_SQS_VISIBILITY_EXTENSION", Value: strconv.FormatFloat(*config.VisibilityExtension, 'g', -1, 64)})
+		}
 
 		if config.Credentials != nil {
 			if config.Credentials.AccessKeyIdSecretRef != nil {
@@ -642,11 +642,13 @@ func TestBuildEnvVars_RabbitMQWithSecret(t *testing.T) {
 }
 
 func TestBuildEnvVars_SQS(t *testing.T) {
+	visibilityExtension := 0.25
 	config := &TransportConfig{
 		Type:    "sqs",
 		Enabled: true,
 		Config: &SQSConfig{
-			Region: "us-west-2",
+			Region:              "us-west-2",
+			VisibilityExtension: &visibilityExtension,
 		},
 	}
 
@@ -656,8 +658,9 @@ func TestBuildEnvVars_SQS(t *testing.T) {
 	}
 
 	expectedEnv := map[string]string{
-		"ASYA_TRANSPORT":  "sqs",
-		"ASYA_AWS_REGION": "us-west-2",
+		"ASYA_TRANSPORT":                "sqs",
+		"ASYA_AWS_REGION":               "us-west-2",
+		"ASYA_SQS_VISIBILITY_EXTENSION": "0.25",
 	}
 
 	for key, expectedValue := range expectedEnv {
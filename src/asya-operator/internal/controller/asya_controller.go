@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -43,6 +45,7 @@ const (
 	runtimeVolume         = "asya-runtime"
 	runtimeConfigMap      = "asya-runtime"
 	runtimeMountPath      = "/opt/asya/asya_runtime.py"
+	defaultSocketDir      = "/var/run/asya"
 	transportTypeRabbitMQ = "rabbitmq"
 	transportTypeSQS      = "sqs"
 
@@ -50,6 +53,10 @@ const (
 	actorNameErrorEnd = "error-end"
 
 	defaultQueueHealthCheckInterval = 5 * time.Minute
+	defaultSidecarMetricsPort       = 8080
+
+	defaultTerminationGracePeriod = 30 // Seconds, the Kubernetes default
+	shutdownDrainMargin           = 5  // Seconds between the end of the sidecar's drain and SIGKILL
 
 	podReasonCrashLoopBackOff           = "CrashLoopBackOff"
 	podReasonImagePullBackOff           = "ImagePullBackOff"
@@ -84,6 +91,33 @@ func getSidecarImage() string {
 	return "asya-sidecar:latest"
 }
 
+// getDefaultImagePullSecrets returns the pull secrets added to every actor pod,
+// read from the comma-separated ASYA_IMAGE_PULL_SECRETS
+func getDefaultImagePullSecrets() []corev1.LocalObjectReference {
+	var secrets []corev1.LocalObjectReference
+	for _, name := range strings.Split(os.Getenv("ASYA_IMAGE_PULL_SECRETS"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			secrets = append(secrets, corev1.LocalObjectReference{Name: name})
+		}
+	}
+	return secrets
+}
+
+// mergeImagePullSecrets concatenates pull secret lists, keeping the first reference to each secret
+func mergeImagePullSecrets(lists ...[]corev1.LocalObjectReference) []corev1.LocalObjectReference {
+	var merged []corev1.LocalObjectReference
+	seen := make(map[string]bool)
+	for _, secrets := range lists {
+		for _, secret := range secrets {
+			if !seen[secret.Name] {
+				seen[secret.Name] = true
+				merged = append(merged, secret)
+			}
+		}
+	}
+	return merged
+}
+
 func isFailingContainerReason(reason string) bool {
 	return reason == podReasonCrashLoopBackOff ||
 		reason == podReasonImagePullBackOff ||
@@ -158,6 +192,18 @@ type AsyncActorReconciler struct {
 	TransportFactory        *transports.Factory
 	MaxConcurrentReconciles int
 	GatewayURL              string
+
+	// HPAFallback scales actors with a native CPU/memory HorizontalPodAutoscaler instead of
+	// a KEDA ScaledObject, set when the operator runs with --hpa-fallback and KEDA is not installed
+	HPAFallback bool
+
+	// KEDAMissing is set when the KEDA CRDs were not found at startup and HPAFallback is off:
+	// scaling-enabled actors run unscaled with a ScalingReady=False condition explaining why
+	KEDAMissing bool
+
+	// PrometheusScrapeAnnotations adds prometheus.io scrape annotations for the sidecar
+	// metrics endpoint to actor pods, unless the pod template sets them
+	PrometheusScrapeAnnotations bool
 }
 
 // +kubebuilder:rbac:groups=asya.sh,resources=asyncactors,verbs=get;list;watch;create;update;patch;delete
@@ -171,7 +217,7 @@ type AsyncActorReconciler struct {
 // +kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
-// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
 
 // isQueueManagementEnabled checks if queue management is enabled via environment variable
 func isQueueManagementEnabled() bool {
@@ -287,8 +333,30 @@ func (r *AsyncActorReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	}
 	logger.Info("Workload condition set", "healthy", podHealthy)
 
-	// Reconcile KEDA ScaledObject based on latest spec
-	if asya.Spec.Scaling.Enabled {
+	// Without KEDA, autoscaled actors get a CPU/memory HPA instead of a ScaledObject
+	if asya.Spec.Scaling.Enabled && r.HPAFallback {
+		logger.Info("Reconciling HorizontalPodAutoscaler (KEDA not installed)")
+		if err := r.reconcileHPA(ctx, asya); err != nil {
+			logger.Error(err, "Failed to reconcile HorizontalPodAutoscaler")
+			r.setCondition(asya, "ScalingReady", metav1.ConditionFalse, "ReconcileError", err.Error())
+			if updateErr := r.Status().Update(ctx, asya); updateErr != nil {
+				logger.Error(updateErr, "Failed to update status")
+			}
+			return ctrl.Result{}, err
+		}
+		r.setCondition(asya, "ScalingReady", metav1.ConditionTrue, "HPACreated", "HorizontalPodAutoscaler successfully created")
+
+		hpaDesired, err := r.getHPADesiredReplicas(ctx, asya)
+		if err != nil {
+			logger.Error(err, "Failed to get HPA desired replicas")
+		} else if hpaDesired != nil {
+			asya.Status.DesiredReplicas = hpaDesired
+		}
+	} else if asya.Spec.Scaling.Enabled && r.KEDAMissing {
+		logger.Info("Not autoscaling actor, KEDA CRDs are not installed")
+		r.setCondition(asya, "ScalingReady", metav1.ConditionFalse, "KEDANotInstalled", kedaMissingMessage)
+	} else if asya.Spec.Scaling.Enabled {
+		// Reconcile KEDA ScaledObject based on latest spec
 		logger.Info("Reconciling KEDA ScaledObject", "enabled", asya.Spec.Scaling.Enabled)
 		if err := r.reconcileScaledObject(ctx, asya); err != nil {
 			logger.Error(err, "Failed to reconcile ScaledObject")
@@ -322,6 +390,12 @@ func (r *AsyncActorReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			// Requeue after 5 seconds to give KEDA time to create the HPA
 			return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
 		}
+	} else if r.HPAFallback {
+		logger.Info("Scaling disabled, ensuring HorizontalPodAutoscaler is deleted")
+		if err := r.deleteHPA(ctx, asya); err != nil {
+			logger.Error(err, "Failed to delete HorizontalPodAutoscaler")
+			return ctrl.Result{}, err
+		}
 	} else {
 		logger.Info("KEDA scaling disabled, ensuring ScaledObject is deleted")
 		if err := r.deleteScaledObject(ctx, asya); err != nil {
@@ -389,10 +463,14 @@ func (r *AsyncActorReconciler) updateStatusWithRetry(ctx context.Context, asya *
 
 // validateAsyncActorSpec validates the AsyncActor spec for forbidden configurations
 func (r *AsyncActorReconciler) validateAsyncActorSpec(asya *asyav1alpha1.AsyncActor) error {
-	// Validate: user containers must not be named "asya-sidecar"
+	runtimes := getRuntimeContainers(asya)
+
+	// Validate: user containers must not use injected sidecar names
 	for _, container := range asya.Spec.Workload.Template.Spec.Containers {
-		if container.Name == sidecarName {
-			return fmt.Errorf("container name '%s' is reserved for the injected sidecar and cannot be defined in the AsyncActor spec", sidecarName)
+		for idx, rt := range runtimes {
+			if name := getSidecarContainerName(idx, rt.Name); container.Name == name {
+				return fmt.Errorf("container name '%s' is reserved for the injected sidecar and cannot be defined in the AsyncActor spec", name)
+			}
 		}
 	}
 
@@ -403,24 +481,133 @@ func (r *AsyncActorReconciler) validateAsyncActorSpec(asya *asyav1alpha1.AsyncAc
 		}
 	}
 
-	// Validate: runtime container must be named "asya-runtime"
-	runtimeContainerCount := 0
-	for _, container := range asya.Spec.Workload.Template.Spec.Containers {
-		if container.Name == runtimeContainerName {
-			runtimeContainerCount++
-			if len(container.Command) > 0 {
-				return fmt.Errorf("container '%s' cannot override command (command is managed by operator)", runtimeContainerName)
+	// Validate: with several sidecars, a metrics address override must name a port to offset
+	if len(runtimes) > 1 {
+		for _, env := range asya.Spec.Sidecar.Env {
+			if env.Name != "ASYA_METRICS_ADDR" {
+				continue
+			}
+			if _, _, err := parseMetricsAddr(env.Value); err != nil {
+				return fmt.Errorf("invalid ASYA_METRICS_ADDR for an actor with several runtime containers: %w", err)
+			}
+		}
+	}
+
+	// Validate: each runtime container (default "asya-runtime") must be defined exactly once
+	seen := make(map[string]bool, len(runtimes))
+	for _, rt := range runtimes {
+		if seen[rt.Name] {
+			return fmt.Errorf("runtime container '%s' is listed more than once", rt.Name)
+		}
+		seen[rt.Name] = true
+
+		runtimeContainerCount := 0
+		for _, container := range asya.Spec.Workload.Template.Spec.Containers {
+			if container.Name == rt.Name {
+				runtimeContainerCount++
+				if len(container.Command) > 0 {
+					return fmt.Errorf("container '%s' cannot override command (command is managed by operator)", rt.Name)
+				}
 			}
 		}
+
+		if runtimeContainerCount != 1 {
+			return fmt.Errorf("workload must contain exactly one container named '%s', but found %d", rt.Name, runtimeContainerCount)
+		}
+	}
+
+	// Validate: the actor's ServiceAccount is not overridden by the pod template
+	if sa := asya.Spec.Workload.Template.Spec.ServiceAccountName; asya.Spec.ServiceAccountName != "" && sa != "" && sa != asya.Spec.ServiceAccountName {
+		return fmt.Errorf("pod template serviceAccountName '%s' conflicts with spec.serviceAccountName '%s'", sa, asya.Spec.ServiceAccountName)
+	}
+
+	// Validate: exchanges only exist in RabbitMQ
+	if asya.Spec.Exchange != "" && asya.Spec.Transport != transportTypeRabbitMQ {
+		return fmt.Errorf("spec.exchange is only supported by the '%s' transport, got '%s'", transportTypeRabbitMQ, asya.Spec.Transport)
+	}
+
+	// Validate: each sidecar envFrom entry references exactly one named ConfigMap or Secret
+	for i, source := range asya.Spec.Sidecar.EnvFrom {
+		switch {
+		case source.ConfigMapRef != nil && source.SecretRef != nil:
+			return fmt.Errorf("sidecar envFrom[%d] cannot reference both a ConfigMap and a Secret", i)
+		case source.ConfigMapRef != nil && source.ConfigMapRef.Name == "",
+			source.SecretRef != nil && source.SecretRef.Name == "":
+			return fmt.Errorf("sidecar envFrom[%d] must reference a ConfigMap or Secret by name", i)
+		case source.ConfigMapRef == nil && source.SecretRef == nil:
+			return fmt.Errorf("sidecar envFrom[%d] must reference a ConfigMap or a Secret", i)
+		}
+	}
+
+	return validateVolumes(asya, runtimes)
+}
+
+// validateVolumes checks that extra volumes don't clash with the pod template's or the
+// operator's volumes, that extra mounts reference a known volume, and that no two mounts
+// of an injected or runtime container share a path
+func validateVolumes(asya *asyav1alpha1.AsyncActor, runtimes []asyav1alpha1.RuntimeContainerConfig) error {
+	reserved := map[string]bool{socketVolume: true, tmpVolume: true, runtimeVolume: true}
+	volumes := make(map[string]bool)
+	all := append([]corev1.Volume{}, asya.Spec.Workload.Template.Spec.Volumes...)
+	for _, volume := range append(all, asya.Spec.Workload.ExtraVolumes...) {
+		if reserved[volume.Name] {
+			return fmt.Errorf("volume name '%s' is reserved for operator use", volume.Name)
+		}
+		if volumes[volume.Name] {
+			return fmt.Errorf("volume '%s' is defined more than once", volume.Name)
+		}
+		volumes[volume.Name] = true
 	}
 
-	if runtimeContainerCount != 1 {
-		return fmt.Errorf("workload must contain exactly one container named '%s', but found %d", runtimeContainerName, runtimeContainerCount)
+	for _, mount := range asya.Spec.Workload.ExtraVolumeMounts {
+		if !volumes[mount.Name] {
+			return fmt.Errorf("workload extra volume mount at %s references undefined volume '%s'", mount.MountPath, mount.Name)
+		}
+	}
+	for _, mount := range asya.Spec.Sidecar.ExtraVolumeMounts {
+		if !volumes[mount.Name] {
+			return fmt.Errorf("sidecar extra volume mount at %s references undefined volume '%s'", mount.MountPath, mount.Name)
+		}
+	}
+
+	for idx, rt := range runtimes {
+		injected := []corev1.VolumeMount{
+			{Name: socketVolume, MountPath: rt.SocketDir},
+			{Name: tmpVolume, MountPath: "/tmp"},
+		}
+
+		runtimeMounts := append([]corev1.VolumeMount{}, injected...)
+		runtimeMounts = append(runtimeMounts, corev1.VolumeMount{Name: runtimeVolume, MountPath: runtimeMountPath})
+		for _, container := range asya.Spec.Workload.Template.Spec.Containers {
+			if container.Name == rt.Name {
+				runtimeMounts = append(runtimeMounts, container.VolumeMounts...)
+			}
+		}
+		if err := checkMountPaths(rt.Name, append(runtimeMounts, asya.Spec.Workload.ExtraVolumeMounts...)); err != nil {
+			return err
+		}
+
+		if err := checkMountPaths(getSidecarContainerName(idx, rt.Name), append(injected, asya.Spec.Sidecar.ExtraVolumeMounts...)); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// checkMountPaths returns an error if two volume mounts of a container use the same path
+func checkMountPaths(containerName string, mounts []corev1.VolumeMount) error {
+	paths := make(map[string]string, len(mounts))
+	for _, mount := range mounts {
+		mountPath := path.Clean(mount.MountPath)
+		if other, ok := paths[mountPath]; ok {
+			return fmt.Errorf("container '%s': volume mount '%s' at %s collides with volume mount '%s'", containerName, mount.Name, mountPath, other)
+		}
+		paths[mountPath] = mount.Name
+	}
+	return nil
+}
+
 // reconcileDelete handles AsyncActor deletion
 func (r *AsyncActorReconciler) reconcileDelete(ctx context.Context, asya *asyav1alpha1.AsyncActor) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
@@ -430,6 +617,11 @@ func (r *AsyncActorReconciler) reconcileDelete(ctx context.Context, asya *asyav1
 	if err := r.deleteScaledObject(ctx, asya); err != nil {
 		logger.Error(err, "Failed to delete ScaledObject, continuing with deletion")
 	}
+	if r.HPAFallback {
+		if err := r.deleteHPA(ctx, asya); err != nil {
+			logger.Error(err, "Failed to delete HorizontalPodAutoscaler, continuing with deletion")
+		}
+	}
 
 	// Delete transport queue using transport layer
 	if isQueueManagementEnabled() {
@@ -529,7 +721,7 @@ func (r *AsyncActorReconciler) CreateSQSClient(ctx context.Context, sqsConfig *a
 	return sqs.NewFromConfig(cfg), nil
 }
 
-// reconcileServiceAccountIfNeeded creates or updates the ServiceAccount with IRSA annotation
+// reconcileServiceAccountIfNeeded creates the actor's ServiceAccount with IRSA annotation if absent
 // Only creates ServiceAccount if actorRoleArn is configured (for EKS IRSA)
 // Skips for LocalStack or environments using static credentials
 func (r *AsyncActorReconciler) reconcileServiceAccountIfNeeded(ctx context.Context, asya *asyav1alpha1.AsyncActor) error {
@@ -550,33 +742,9 @@ func (r *AsyncActorReconciler) reconcileServiceAccountIfNeeded(ctx context.Conte
 		return nil
 	}
 
-	saName := fmt.Sprintf("asya-%s", asya.Name)
-
-	sa := &corev1.ServiceAccount{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      saName,
-			Namespace: asya.Namespace,
-		},
-	}
-
-	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, sa, func() error {
-		if err := controllerutil.SetControllerReference(asya, sa, r.Scheme); err != nil {
-			return err
-		}
-
-		if sa.Annotations == nil {
-			sa.Annotations = make(map[string]string)
-		}
-		sa.Annotations["eks.amazonaws.com/role-arn"] = sqsConfig.ActorRoleArn
-
-		return nil
-	})
-
-	if err != nil {
+	if err := transports.ReconcileIRSAServiceAccount(ctx, r.Client, r.Scheme, asya, sqsConfig.ActorRoleArn); err != nil {
 		return fmt.Errorf("failed to reconcile ServiceAccount: %w", err)
 	}
-
-	logger.Info("ServiceAccount reconciled", "result", result, "name", saName)
 	return nil
 }
 
@@ -659,10 +827,6 @@ func (r *AsyncActorReconciler) injectSidecar(asya *asyav1alpha1.AsyncActor) core
 	// Note: Validation is performed in validateAsyncActorSpec() before reaching this point
 	// This function assumes the spec has already been validated
 
-	// Hardcoded socket path (not user-configurable to prevent misconfiguration)
-	const socketsDir = "/var/run/asya"
-	socketPath := socketsDir + "/asya-runtime.sock"
-
 	sidecarImage := getSidecarImage()
 	if asya.Spec.Sidecar.Image != "" {
 		sidecarImage = asya.Spec.Sidecar.Image
@@ -673,131 +837,71 @@ func (r *AsyncActorReconciler) injectSidecar(asya *asyav1alpha1.AsyncActor) core
 		imagePullPolicy = asya.Spec.Sidecar.ImagePullPolicy
 	}
 
-	// Build sidecar environment variables
-	env := r.buildSidecarEnv(asya)
-	env = append(env, corev1.EnvVar{
-		Name:  "ASYA_SOCKET_DIR",
-		Value: socketsDir,
-	})
-	env = append(env, asya.Spec.Sidecar.Env...)
-
-	// Create sidecar container
-	sidecarContainer := corev1.Container{
-		Name:            sidecarName,
-		Image:           sidecarImage,
-		ImagePullPolicy: imagePullPolicy,
-		Env:             env,
-		Resources:       asya.Spec.Sidecar.Resources,
-		VolumeMounts: []corev1.VolumeMount{
-			{
-				Name:      socketVolume,
-				MountPath: socketsDir,
-			},
-			{
-				Name:      tmpVolume,
-				MountPath: "/tmp",
-			},
-		},
-	}
-
-	// Add sidecar to containers (append at end to preserve container ordering)
-	template.Spec.Containers = append(template.Spec.Containers, sidecarContainer)
-
-	// Queue initialization is handled by operator's ReconcileQueue()
-
-	// Add socket path to runtime container and inject asya_runtime.py
-	for i := range template.Spec.Containers {
-		if template.Spec.Containers[i].Name == runtimeContainerName {
-			// Set runtime command (validation ensures it's not already set)
-			pythonExec := "python3"
-			if asya.Spec.Workload.PythonExecutable != "" {
-				pythonExec = asya.Spec.Workload.PythonExecutable
-			}
-			template.Spec.Containers[i].Command = []string{pythonExec, runtimeMountPath}
+	// Each runtime container gets its own sidecar and socket. Additional runtimes use their
+	// own subdirectory of the socket volume so sockets and ready files never collide.
+	runtimes := getRuntimeContainers(asya)
+	for idx, rt := range runtimes {
+		socketMount := corev1.VolumeMount{
+			Name:      socketVolume,
+			MountPath: rt.SocketDir,
+		}
+		if idx > 0 {
+			socketMount.SubPath = rt.Name
+		}
 
-			// Add ASYA_SOCKET_DIR environment variable
-			template.Spec.Containers[i].Env = append(template.Spec.Containers[i].Env,
-				corev1.EnvVar{
-					Name:  "ASYA_SOCKET_DIR",
-					Value: socketsDir,
-				},
-			)
-
-			// Disable validation for end actors
-			if asya.Name == actorNameHappyEnd || asya.Name == actorNameErrorEnd {
-				template.Spec.Containers[i].Env = append(template.Spec.Containers[i].Env,
-					corev1.EnvVar{
-						Name:  "ASYA_ENABLE_VALIDATION",
-						Value: "false",
-					},
-				)
+		// Build sidecar environment variables
+		env := r.buildSidecarEnv(asya)
+		env = append(env, corev1.EnvVar{
+			Name:  "ASYA_SOCKET_DIR",
+			Value: rt.SocketDir,
+		})
+		if idx > 0 {
+			// Sidecars share the pod network namespace, so each needs its own metrics port,
+			// also when spec.sidecar.env overrides ASYA_METRICS_ADDR
+			env = append(env, corev1.EnvVar{
+				Name:  "ASYA_METRICS_ADDR",
+				Value: sidecarMetricsAddr(asya, idx),
+			})
+			for _, userEnv := range asya.Spec.Sidecar.Env {
+				if userEnv.Name != "ASYA_METRICS_ADDR" {
+					env = append(env, userEnv)
+				}
 			}
+		} else {
+			env = append(env, asya.Spec.Sidecar.Env...)
+		}
 
-			// Add volume mounts
-			template.Spec.Containers[i].VolumeMounts = append(template.Spec.Containers[i].VolumeMounts,
-				corev1.VolumeMount{
-					Name:      socketVolume,
-					MountPath: socketsDir,
-				},
-				corev1.VolumeMount{
+		// Create sidecar container
+		sidecarContainer := corev1.Container{
+			Name:            getSidecarContainerName(idx, rt.Name),
+			Image:           sidecarImage,
+			ImagePullPolicy: imagePullPolicy,
+			Env:             env,
+			EnvFrom:         append([]corev1.EnvFromSource(nil), asya.Spec.Sidecar.EnvFrom...),
+			Resources:       asya.Spec.Sidecar.Resources,
+			VolumeMounts: []corev1.VolumeMount{
+				socketMount,
+				{
 					Name:      tmpVolume,
 					MountPath: "/tmp",
 				},
-				corev1.VolumeMount{
-					Name:      runtimeVolume,
-					MountPath: runtimeMountPath,
-					SubPath:   "asya_runtime.py",
-					ReadOnly:  true,
-				},
-			)
-
-			// Add startup probe to detect initialization failures
-			if template.Spec.Containers[i].StartupProbe == nil {
-				template.Spec.Containers[i].StartupProbe = &corev1.Probe{
-					ProbeHandler: corev1.ProbeHandler{
-						Exec: &corev1.ExecAction{
-							Command: []string{"sh", "-c", fmt.Sprintf("test -S %s && test -f %s/runtime-ready", socketPath, socketsDir)},
-						},
-					},
-					InitialDelaySeconds: 3,
-					PeriodSeconds:       2,
-					TimeoutSeconds:      3,
-					FailureThreshold:    150,
-				}
-			}
+			},
+		}
+		sidecarContainer.VolumeMounts = append(sidecarContainer.VolumeMounts, asya.Spec.Sidecar.ExtraVolumeMounts...)
 
-			// Add liveness probe to detect hung runtime processes
-			if template.Spec.Containers[i].LivenessProbe == nil {
-				template.Spec.Containers[i].LivenessProbe = &corev1.Probe{
-					ProbeHandler: corev1.ProbeHandler{
-						Exec: &corev1.ExecAction{
-							Command: []string{"sh", "-c", fmt.Sprintf("test -S %s && test -f %s/runtime-ready", socketPath, socketsDir)},
-						},
-					},
-					InitialDelaySeconds: 0,
-					PeriodSeconds:       30,
-					TimeoutSeconds:      5,
-					FailureThreshold:    3,
-				}
-			}
+		// Add sidecar to containers (append at end to preserve container ordering)
+		template.Spec.Containers = append(template.Spec.Containers, sidecarContainer)
 
-			// Add readiness probe for graceful startup
-			if template.Spec.Containers[i].ReadinessProbe == nil {
-				template.Spec.Containers[i].ReadinessProbe = &corev1.Probe{
-					ProbeHandler: corev1.ProbeHandler{
-						Exec: &corev1.ExecAction{
-							Command: []string{"sh", "-c", fmt.Sprintf("test -S %s && test -f %s/runtime-ready", socketPath, socketsDir)},
-						},
-					},
-					InitialDelaySeconds: 0,
-					PeriodSeconds:       10,
-					TimeoutSeconds:      3,
-					FailureThreshold:    3,
-				}
+		// Add socket path to runtime container and inject asya_runtime.py
+		for i := range template.Spec.Containers {
+			if template.Spec.Containers[i].Name == rt.Name {
+				injectRuntime(asya, &template.Spec.Containers[i], rt.SocketDir, socketMount)
 			}
 		}
 	}
 
+	// Queue initialization is handled by operator's ReconcileQueue()
+
 	// Add volumes
 	template.Spec.Volumes = append(template.Spec.Volumes,
 		corev1.Volume{
@@ -824,25 +928,164 @@ func (r *AsyncActorReconciler) injectSidecar(asya *asyav1alpha1.AsyncActor) core
 			},
 		},
 	)
+	template.Spec.Volumes = append(template.Spec.Volumes, asya.Spec.Workload.ExtraVolumes...)
 
-	// Set termination grace period
-	gracePeriod := int64(30)
-	if asya.Spec.Timeout.GracefulShutdown > 0 {
-		gracePeriod = int64(asya.Spec.Timeout.GracefulShutdown)
+	// Pull secrets of the pod template, the actor and the operator, so private sidecar images work
+	template.Spec.ImagePullSecrets = mergeImagePullSecrets(template.Spec.ImagePullSecrets, asya.Spec.Workload.ImagePullSecrets, getDefaultImagePullSecrets())
+
+	if asya.Spec.ServiceAccountName != "" {
+		template.Spec.ServiceAccountName = asya.Spec.ServiceAccountName
 	}
+
+	// Set termination grace period
+	gracePeriod := int64(terminationGracePeriod(asya))
 	template.Spec.TerminationGracePeriodSeconds = &gracePeriod
 
+	if r.PrometheusScrapeAnnotations {
+		addScrapeAnnotations(&template, asya)
+	}
+
 	return template
 }
 
+// injectRuntime sets the runtime command, socket location, mounts and probes on a runtime container
+func injectRuntime(asya *asyav1alpha1.AsyncActor, container *corev1.Container, socketDir string, socketMount corev1.VolumeMount) {
+	socketPath := socketDir + "/asya-runtime.sock"
+
+	// Set runtime command (validation ensures it's not already set)
+	pythonExec := "python3"
+	if asya.Spec.Workload.PythonExecutable != "" {
+		pythonExec = asya.Spec.Workload.PythonExecutable
+	}
+	container.Command = []string{pythonExec, runtimeMountPath}
+
+	// Actor configuration from referenced ConfigMaps/Secrets, shared with the sidecar
+	container.EnvFrom = append(container.EnvFrom, asya.Spec.Sidecar.EnvFrom...)
+
+	// Add ASYA_SOCKET_DIR environment variable
+	container.Env = append(container.Env,
+		corev1.EnvVar{
+			Name:  "ASYA_SOCKET_DIR",
+			Value: socketDir,
+		},
+	)
+
+	// Disable validation for end actors
+	if asya.Name == actorNameHappyEnd || asya.Name == actorNameErrorEnd {
+		container.Env = append(container.Env,
+			corev1.EnvVar{
+				Name:  "ASYA_ENABLE_VALIDATION",
+				Value: "false",
+			},
+		)
+	}
+
+	// Add volume mounts
+	container.VolumeMounts = append(container.VolumeMounts,
+		socketMount,
+		corev1.VolumeMount{
+			Name:      tmpVolume,
+			MountPath: "/tmp",
+		},
+		corev1.VolumeMount{
+			Name:      runtimeVolume,
+			MountPath: runtimeMountPath,
+			SubPath:   "asya_runtime.py",
+			ReadOnly:  true,
+		},
+	)
+	container.VolumeMounts = append(container.VolumeMounts, asya.Spec.Workload.ExtraVolumeMounts...)
+
+	// Add startup probe to detect initialization failures
+	if container.StartupProbe == nil {
+		container.StartupProbe = &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{
+				Exec: &corev1.ExecAction{
+					Command: []string{"sh", "-c", fmt.Sprintf("test -S %s && test -f %s/runtime-ready", socketPath, socketDir)},
+				},
+			},
+			InitialDelaySeconds: 3,
+			PeriodSeconds:       2,
+			TimeoutSeconds:      3,
+			FailureThreshold:    150,
+		}
+	}
+
+	// Add liveness probe to detect hung runtime processes
+	if container.LivenessProbe == nil {
+		container.LivenessProbe = &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{
+				Exec: &corev1.ExecAction{
+					Command: []string{"sh", "-c", fmt.Sprintf("test -S %s && test -f %s/runtime-ready", socketPath, socketDir)},
+				},
+			},
+			InitialDelaySeconds: 0,
+			PeriodSeconds:       30,
+			TimeoutSeconds:      5,
+			FailureThreshold:    3,
+		}
+	}
+
+	// Add readiness probe for graceful startup
+	if container.ReadinessProbe == nil {
+		container.ReadinessProbe = &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{
+				Exec: &corev1.ExecAction{
+					Command: []string{"sh", "-c", fmt.Sprintf("test -S %s && test -f %s/runtime-ready", socketPath, socketDir)},
+				},
+			},
+			InitialDelaySeconds: 0,
+			PeriodSeconds:       10,
+			TimeoutSeconds:      3,
+			FailureThreshold:    3,
+		}
+	}
+}
+
+// getRuntimeContainers returns the runtime containers with defaults applied
+func getRuntimeContainers(asya *asyav1alpha1.AsyncActor) []asyav1alpha1.RuntimeContainerConfig {
+	if len(asya.Spec.Workload.RuntimeContainers) == 0 {
+		return []asyav1alpha1.RuntimeContainerConfig{{Name: runtimeContainerName, SocketDir: defaultSocketDir}}
+	}
+
+	runtimes := make([]asyav1alpha1.RuntimeContainerConfig, 0, len(asya.Spec.Workload.RuntimeContainers))
+	for _, rt := range asya.Spec.Workload.RuntimeContainers {
+		if rt.SocketDir == "" {
+			rt.SocketDir = defaultSocketDir
+		}
+		runtimes = append(runtimes, rt)
+	}
+	return runtimes
+}
+
+// getSidecarContainerName returns the sidecar name for the runtime at the given position.
+// The first runtime keeps the plain sidecar name for compatibility with single-runtime pods.
+func getSidecarContainerName(idx int, runtimeName string) string {
+	if idx == 0 {
+		return sidecarName
+	}
+	return sidecarName + "-" + runtimeName
+}
+
+// isAsyaContainer reports whether a container is a runtime or an injected sidecar
+func isAsyaContainer(asya *asyav1alpha1.AsyncActor, name string) bool {
+	for idx, rt := range getRuntimeContainers(asya) {
+		if name == rt.Name || name == getSidecarContainerName(idx, rt.Name) {
+			return true
+		}
+	}
+	return false
+}
+
 // extractGatewayURLFromRuntime extracts ASYA_GATEWAY_URL from runtime container env vars
 func (r *AsyncActorReconciler) extractGatewayURLFromRuntime(asya *asyav1alpha1.AsyncActor) string {
 	if asya.Spec.Workload.Template.Spec.Containers == nil {
 		return ""
 	}
 
+	primaryRuntime := getRuntimeContainers(asya)[0].Name
 	for _, container := range asya.Spec.Workload.Template.Spec.Containers {
-		if container.Name != runtimeContainerName {
+		if container.Name != primaryRuntime {
 			continue
 		}
 		for _, env := range container.Env {
@@ -886,6 +1129,20 @@ func (r *AsyncActorReconciler) buildSidecarEnv(asya *asyav1alpha1.AsyncActor) []
 		})
 	}
 
+	// End the sidecar's drain before the pod's termination grace period, so it exits
+	// (and logs the unacknowledged message) before the kubelet kills it
+	env = append(env, corev1.EnvVar{
+		Name:  "ASYA_GRACEFUL_SHUTDOWN",
+		Value: strconv.Itoa(sidecarDrainSeconds(terminationGracePeriod(asya))),
+	})
+
+	if enabled := asya.Spec.Progress.Enabled; enabled != nil && !*enabled {
+		env = append(env, corev1.EnvVar{
+			Name:  "ASYA_DISABLE_PROGRESS",
+			Value: "true",
+		})
+	}
+
 	// Get transport config from registry and build env vars
 	transport, err := r.TransportRegistry.GetTransport(asya.Spec.Transport)
 	if err != nil {
@@ -897,6 +1154,12 @@ func (r *AsyncActorReconciler) buildSidecarEnv(asya *asyav1alpha1.AsyncActor) []
 		return env
 	}
 
+	// The actor's exchange replaces the transport's
+	if asya.Spec.Exchange != "" {
+		transportEnv = slices.DeleteFunc(transportEnv, func(e corev1.EnvVar) bool { return e.Name == "ASYA_RABBITMQ_EXCHANGE" })
+		transportEnv = append(transportEnv, corev1.EnvVar{Name: "ASYA_RABBITMQ_EXCHANGE", Value: asya.Spec.Exchange})
+	}
+
 	env = append(env, transportEnv...)
 
 	return env
@@ -940,7 +1203,7 @@ func (r *AsyncActorReconciler) checkPodHealth(ctx context.Context, asya *asyav1a
 		}
 
 		for _, containerStatus := range pod.Status.ContainerStatuses {
-			if containerStatus.Name == runtimeContainerName || containerStatus.Name == sidecarName {
+			if isAsyaContainer(asya, containerStatus.Name) {
 				if containerStatus.RestartCount > 5 {
 					return false, fmt.Sprintf("Container %s has %d restarts (pod: %s)", containerStatus.Name, containerStatus.RestartCount, pod.Name)
 				}
@@ -1073,11 +1336,11 @@ func (r *AsyncActorReconciler) reconcileDeployment(ctx context.Context, asya *as
 					userProvidedSA := asya.Spec.Workload.Template.Spec.ServiceAccountName
 
 					if sqsConfig.ActorRoleArn != "" {
-						// IRSA enabled: require operator-managed ServiceAccount
-						if userProvidedSA != "" {
-							return fmt.Errorf("cannot use custom serviceAccountName %q when IRSA (actorRoleArn) is configured: remove serviceAccountName from spec or disable IRSA", userProvidedSA)
+						// IRSA enabled: require the ServiceAccount reconciled for the actor
+						if userProvidedSA != "" && userProvidedSA != asya.Spec.ServiceAccountName {
+							return fmt.Errorf("cannot use custom serviceAccountName %q when IRSA (actorRoleArn) is configured: set spec.serviceAccountName instead or disable IRSA", userProvidedSA)
 						}
-						podTemplate.Spec.ServiceAccountName = fmt.Sprintf("asya-%s", asya.Name)
+						podTemplate.Spec.ServiceAccountName = transports.ServiceAccountName(asya)
 					}
 					// IRSA disabled: preserve user's ServiceAccount choice (or empty if not provided)
 				}
@@ -1154,13 +1417,12 @@ func (r *AsyncActorReconciler) reconcileStatefulSet(ctx context.Context, asya *a
 	return fmt.Errorf("StatefulSet support not yet implemented")
 }
 
-// getHPADesiredReplicas fetches the desired replica count from KEDA's HPA
+// getHPADesiredReplicas fetches the desired replica count from the actor's HPA (KEDA's or the fallback HPA)
 // Returns the desired replicas if found, or nil if HPA doesn't exist or has no desired replicas
 func (r *AsyncActorReconciler) getHPADesiredReplicas(ctx context.Context, asya *asyav1alpha1.AsyncActor) (*int32, error) {
 	logger := log.FromContext(ctx)
 
-	// KEDA creates HPAs with the naming convention: keda-hpa-{scaledobject-name}
-	hpaName := fmt.Sprintf("keda-hpa-%s", asya.Name)
+	hpaName := r.hpaName(asya)
 
 	hpa := &autoscalingv2.HorizontalPodAutoscaler{}
 	err := r.Get(ctx, client.ObjectKey{
@@ -1452,3 +1714,20 @@ func (r *AsyncActorReconciler) startPeriodicQueueHealthCheck(mgr ctrl.Manager) {
 		}
 	}
 }
+
+// terminationGracePeriod returns the pod's termination grace period in seconds
+func terminationGracePeriod(asya *asyav1alpha1.AsyncActor) int {
+	if asya.Spec.Timeout.GracefulShutdown > 0 {
+		return asya.Spec.Timeout.GracefulShutdown
+	}
+	return defaultTerminationGracePeriod
+}
+
+// sidecarDrainSeconds returns how long the sidecar lets the in-flight message finish after
+// SIGTERM: shutdownDrainMargin less than the grace period, or half of short grace periods
+func sidecarDrainSeconds(gracePeriod int) int {
+	if gracePeriod > 2*shutdownDrainMargin {
+		return gracePeriod - shutdownDrainMargin
+	}
+	return max(gracePeriod/2, 1)
+}
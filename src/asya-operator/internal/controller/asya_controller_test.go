@@ -3,8 +3,10 @@ package controller
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -598,6 +600,108 @@ func TestBuildSidecarEnv(t *testing.T) {
 		}
 	})
 
+	t.Run("with graceful shutdown", func(t *testing.T) {
+		asya := &asyav1alpha1.AsyncActor{
+			Spec: asyav1alpha1.AsyncActorSpec{
+				Transport: testTransportRabbitMQ,
+				Timeout: asyav1alpha1.TimeoutConfig{
+					GracefulShutdown: 90,
+				},
+			},
+		}
+
+		env := r.buildSidecarEnv(asya)
+
+		envMap := make(map[string]string)
+		for _, e := range env {
+			envMap[e.Name] = e.Value
+		}
+
+		// The drain ends before the pod's grace period of 90s
+		if envMap["ASYA_GRACEFUL_SHUTDOWN"] != "85" {
+			t.Errorf("Expected ASYA_GRACEFUL_SHUTDOWN=85, got %q", envMap["ASYA_GRACEFUL_SHUTDOWN"])
+		}
+	})
+
+	t.Run("default graceful shutdown", func(t *testing.T) {
+		asya := &asyav1alpha1.AsyncActor{
+			Spec: asyav1alpha1.AsyncActorSpec{Transport: testTransportRabbitMQ},
+		}
+
+		env := r.buildSidecarEnv(asya)
+
+		envMap := make(map[string]string)
+		for _, e := range env {
+			envMap[e.Name] = e.Value
+		}
+
+		if envMap["ASYA_GRACEFUL_SHUTDOWN"] != "25" {
+			t.Errorf("Expected ASYA_GRACEFUL_SHUTDOWN=25, got %q", envMap["ASYA_GRACEFUL_SHUTDOWN"])
+		}
+	})
+
+	t.Run("progress reporting disabled", func(t *testing.T) {
+		enabled, disabled := true, false
+		for _, tt := range []struct {
+			name    string
+			enabled *bool
+			want    string
+		}{{"unset", nil, ""}, {"enabled", &enabled, ""}, {"disabled", &disabled, "true"}} {
+			asya := &asyav1alpha1.AsyncActor{
+				Spec: asyav1alpha1.AsyncActorSpec{
+					Transport: testTransportRabbitMQ,
+					Progress:  asyav1alpha1.ProgressConfig{Enabled: tt.enabled},
+				},
+			}
+
+			envMap := make(map[string]string)
+			for _, e := range r.buildSidecarEnv(asya) {
+				envMap[e.Name] = e.Value
+			}
+			if envMap["ASYA_DISABLE_PROGRESS"] != tt.want {
+				t.Errorf("progress %s: ASYA_DISABLE_PROGRESS = %q, want %q", tt.name, envMap["ASYA_DISABLE_PROGRESS"], tt.want)
+			}
+		}
+	})
+
+	t.Run("actor exchange replaces the transport exchange", func(t *testing.T) {
+		r := &AsyncActorReconciler{
+			TransportRegistry: &asyaconfig.TransportRegistry{
+				Transports: map[string]*asyaconfig.TransportConfig{
+					testTransportRabbitMQ: {
+						Type:    testTransportRabbitMQ,
+						Enabled: true,
+						Config: &asyaconfig.RabbitMQConfig{
+							Host:     "localhost",
+							Port:     5672,
+							Username: "guest",
+							Exchange: "asya",
+						},
+					},
+				},
+			},
+		}
+
+		for _, tt := range []struct{ exchange, want string }{{"", "asya"}, {"team-a", "team-a"}} {
+			asya := &asyav1alpha1.AsyncActor{
+				Spec: asyav1alpha1.AsyncActorSpec{
+					Transport: testTransportRabbitMQ,
+					Exchange:  tt.exchange,
+				},
+			}
+
+			var exchanges []string
+			for _, e := range r.buildSidecarEnv(asya) {
+				if e.Name == "ASYA_RABBITMQ_EXCHANGE" {
+					exchanges = append(exchanges, e.Value)
+				}
+			}
+			if len(exchanges) != 1 || exchanges[0] != tt.want {
+				t.Errorf("spec.exchange %q: ASYA_RABBITMQ_EXCHANGE = %v, want [%s]", tt.exchange, exchanges, tt.want)
+			}
+		}
+	})
+
 	t.Run("invalid transport returns basic env", func(t *testing.T) {
 		asya := &asyav1alpha1.AsyncActor{
 			Spec: asyav1alpha1.AsyncActorSpec{
@@ -1102,6 +1206,148 @@ func TestReconcileDeployment_SQSServiceAccount(t *testing.T) {
 	}
 }
 
+func TestReconcileDeployment_SQSCustomServiceAccount(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = asyav1alpha1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+
+	r := &AsyncActorReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).Build(),
+		Scheme: scheme,
+		TransportRegistry: &asyaconfig.TransportRegistry{
+			Transports: map[string]*asyaconfig.TransportConfig{
+				testTransportSQS: {
+					Type:    testTransportSQS,
+					Enabled: true,
+					Config: &asyaconfig.SQSConfig{
+						Region:            "us-east-1",
+						ActorRoleArn:      "arn:aws:iam::123456789012:role/asya-actor",
+						VisibilityTimeout: 300,
+						WaitTimeSeconds:   20,
+					},
+				},
+			},
+		},
+	}
+
+	asya := &asyav1alpha1.AsyncActor{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-actor",
+			Namespace: "default",
+		},
+		Spec: asyav1alpha1.AsyncActorSpec{
+			Transport:          testTransportSQS,
+			ServiceAccountName: "summarizer",
+			Workload: asyav1alpha1.WorkloadConfig{
+				Template: asyav1alpha1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "asya-runtime",
+								Image: "python:3.13-slim",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if err := r.reconcileServiceAccountIfNeeded(context.Background(), asya); err != nil {
+		t.Fatalf("reconcileServiceAccountIfNeeded failed: %v", err)
+	}
+
+	sa := &corev1.ServiceAccount{}
+	if err := r.Get(context.Background(), client.ObjectKey{Name: "summarizer", Namespace: asya.Namespace}, sa); err != nil {
+		t.Fatalf("Failed to get ServiceAccount: %v", err)
+	}
+	if got := sa.Annotations["eks.amazonaws.com/role-arn"]; got != "arn:aws:iam::123456789012:role/asya-actor" {
+		t.Errorf("Expected IRSA role annotation, got %q", got)
+	}
+
+	podTemplate := r.injectSidecar(asya)
+	if err := r.reconcileDeployment(context.Background(), asya, podTemplate); err != nil {
+		t.Fatalf("reconcileDeployment failed: %v", err)
+	}
+
+	deployment := &appsv1.Deployment{}
+	if err := r.Get(context.Background(), client.ObjectKey{Name: asya.Name, Namespace: asya.Namespace}, deployment); err != nil {
+		t.Fatalf("Failed to get deployment: %v", err)
+	}
+
+	if deployment.Spec.Template.Spec.ServiceAccountName != "summarizer" {
+		t.Errorf("Expected ServiceAccountName to be 'summarizer', got %q", deployment.Spec.Template.Spec.ServiceAccountName)
+	}
+}
+
+func TestValidateAsyncActorSpec_ServiceAccountConflict(t *testing.T) {
+	r := &AsyncActorReconciler{}
+
+	asya := &asyav1alpha1.AsyncActor{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-actor",
+			Namespace: "default",
+		},
+		Spec: asyav1alpha1.AsyncActorSpec{
+			Transport:          testTransportSQS,
+			ServiceAccountName: "summarizer",
+			Workload: asyav1alpha1.WorkloadConfig{
+				Template: asyav1alpha1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						ServiceAccountName: "other",
+						Containers:         []corev1.Container{{Name: runtimeContainerName, Image: "python:3.13-slim"}},
+					},
+				},
+			},
+		},
+	}
+
+	err := r.validateAsyncActorSpec(asya)
+	expectedError := "pod template serviceAccountName 'other' conflicts with spec.serviceAccountName 'summarizer'"
+	if err == nil || err.Error() != expectedError {
+		t.Errorf("Expected error %q, got %v", expectedError, err)
+	}
+
+	asya.Spec.Workload.Template.Spec.ServiceAccountName = "summarizer"
+	if err := r.validateAsyncActorSpec(asya); err != nil {
+		t.Errorf("Expected no error for matching ServiceAccount names, got %v", err)
+	}
+}
+
+func TestValidateAsyncActorSpec_Exchange(t *testing.T) {
+	r := &AsyncActorReconciler{}
+
+	asya := &asyav1alpha1.AsyncActor{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-actor",
+			Namespace: "default",
+		},
+		Spec: asyav1alpha1.AsyncActorSpec{
+			Transport: testTransportSQS,
+			Exchange:  "team-a",
+			Workload: asyav1alpha1.WorkloadConfig{
+				Template: asyav1alpha1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: runtimeContainerName, Image: "python:3.13-slim"}},
+					},
+				},
+			},
+		},
+	}
+
+	err := r.validateAsyncActorSpec(asya)
+	expectedError := "spec.exchange is only supported by the 'rabbitmq' transport, got 'sqs'"
+	if err == nil || err.Error() != expectedError {
+		t.Errorf("Expected error %q, got %v", expectedError, err)
+	}
+
+	asya.Spec.Transport = testTransportRabbitMQ
+	if err := r.validateAsyncActorSpec(asya); err != nil {
+		t.Errorf("Expected no error for an exchange with RabbitMQ, got %v", err)
+	}
+}
+
 func TestReconcileDeployment_RabbitMQNoServiceAccount(t *testing.T) {
 	scheme := runtime.NewScheme()
 	_ = asyav1alpha1.AddToScheme(scheme)
@@ -3102,3 +3348,565 @@ func TestValidateAsyncActorSpec_CommandOverride(t *testing.T) {
 		})
 	}
 }
+
+func TestInjectSidecar_MultipleRuntimeContainers(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = asyav1alpha1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	r := &AsyncActorReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).Build(),
+		Scheme: scheme,
+		TransportRegistry: &asyaconfig.TransportRegistry{
+			Transports: make(map[string]*asyaconfig.TransportConfig),
+		},
+	}
+
+	asya := &asyav1alpha1.AsyncActor{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-actor",
+			Namespace: "default",
+		},
+		Spec: asyav1alpha1.AsyncActorSpec{
+			Transport: testTransportRabbitMQ,
+			Workload: asyav1alpha1.WorkloadConfig{
+				RuntimeContainers: []asyav1alpha1.RuntimeContainerConfig{
+					{Name: "runtime-a"},
+					{Name: "runtime-b", SocketDir: "/var/run/custom"},
+				},
+				Template: asyav1alpha1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{Name: "runtime-a", Image: "python:3.13-slim"},
+							{Name: "runtime-b", Image: "python:3.13-slim"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	result := r.injectSidecar(asya)
+
+	containers := make(map[string]*corev1.Container)
+	for i := range result.Spec.Containers {
+		containers[result.Spec.Containers[i].Name] = &result.Spec.Containers[i]
+	}
+
+	tests := []struct {
+		runtime           string
+		sidecar           string
+		socketDir         string
+		subPath           string
+		sidecarMetricsEnv string
+	}{
+		{runtime: "runtime-a", sidecar: sidecarName, socketDir: "/var/run/asya", subPath: ""},
+		{runtime: "runtime-b", sidecar: sidecarName + "-runtime-b", socketDir: "/var/run/custom", subPath: "runtime-b", sidecarMetricsEnv: ":8081"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.runtime, func(t *testing.T) {
+			runtimeContainer := containers[tt.runtime]
+			sidecarContainer := containers[tt.sidecar]
+			if runtimeContainer == nil || sidecarContainer == nil {
+				t.Fatalf("Expected runtime %q and sidecar %q containers", tt.runtime, tt.sidecar)
+			}
+
+			if len(runtimeContainer.Command) != 2 || runtimeContainer.Command[1] != runtimeMountPath {
+				t.Errorf("Runtime command = %v, want runtime script", runtimeContainer.Command)
+			}
+
+			for _, c := range []*corev1.Container{runtimeContainer, sidecarContainer} {
+				if got := getEnvValue(c.Env, "ASYA_SOCKET_DIR"); got != tt.socketDir {
+					t.Errorf("%s ASYA_SOCKET_DIR = %q, want %q", c.Name, got, tt.socketDir)
+				}
+
+				var socketMount *corev1.VolumeMount
+				for i := range c.VolumeMounts {
+					if c.VolumeMounts[i].Name == socketVolume {
+						socketMount = &c.VolumeMounts[i]
+					}
+				}
+				if socketMount == nil {
+					t.Fatalf("%s has no socket volume mount", c.Name)
+				}
+				if socketMount.MountPath != tt.socketDir || socketMount.SubPath != tt.subPath {
+					t.Errorf("%s socket mount = %s (subPath %q), want %s (subPath %q)",
+						c.Name, socketMount.MountPath, socketMount.SubPath, tt.socketDir, tt.subPath)
+				}
+			}
+
+			if got := getEnvValue(sidecarContainer.Env, "ASYA_METRICS_ADDR"); got != tt.sidecarMetricsEnv {
+				t.Errorf("Sidecar ASYA_METRICS_ADDR = %q, want %q", got, tt.sidecarMetricsEnv)
+			}
+
+			expectedProbe := fmt.Sprintf("test -S %s/asya-runtime.sock && test -f %s/runtime-ready", tt.socketDir, tt.socketDir)
+			if runtimeContainer.ReadinessProbe == nil || runtimeContainer.ReadinessProbe.Exec.Command[2] != expectedProbe {
+				t.Errorf("Readiness probe = %v, want %q", runtimeContainer.ReadinessProbe, expectedProbe)
+			}
+		})
+	}
+}
+
+func TestInjectSidecar_MultipleRuntimeContainersMetricsAddrOverride(t *testing.T) {
+	r := &AsyncActorReconciler{
+		TransportRegistry: &asyaconfig.TransportRegistry{
+			Transports: make(map[string]*asyaconfig.TransportConfig),
+		},
+	}
+
+	asya := &asyav1alpha1.AsyncActor{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-actor",
+			Namespace: "default",
+		},
+		Spec: asyav1alpha1.AsyncActorSpec{
+			Transport: testTransportRabbitMQ,
+			Sidecar: asyav1alpha1.SidecarConfig{
+				Env: []corev1.EnvVar{{Name: "ASYA_METRICS_ADDR", Value: "0.0.0.0:9100"}},
+			},
+			Workload: asyav1alpha1.WorkloadConfig{
+				RuntimeContainers: []asyav1alpha1.RuntimeContainerConfig{{Name: "runtime-a"}, {Name: "runtime-b"}},
+				Template: asyav1alpha1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{Name: "runtime-a", Image: "python:3.13-slim"},
+							{Name: "runtime-b", Image: "python:3.13-slim"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	result := r.injectSidecar(asya)
+
+	// The override moves every sidecar, each keeping its own port
+	want := map[string]string{
+		sidecarName:                "0.0.0.0:9100",
+		sidecarName + "-runtime-b": "0.0.0.0:9101",
+	}
+	for _, c := range result.Spec.Containers {
+		addr, ok := want[c.Name]
+		if !ok {
+			continue
+		}
+		var values []string
+		for _, env := range c.Env {
+			if env.Name == "ASYA_METRICS_ADDR" {
+				values = append(values, env.Value)
+			}
+		}
+		if len(values) != 1 || values[0] != addr {
+			t.Errorf("%s ASYA_METRICS_ADDR = %v, want [%s]", c.Name, values, addr)
+		}
+	}
+}
+
+func TestValidateAsyncActorSpec_RuntimeContainers(t *testing.T) {
+	tests := []struct {
+		name        string
+		runtimes    []asyav1alpha1.RuntimeContainerConfig
+		containers  []corev1.Container
+		sidecarEnv  []corev1.EnvVar
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:     "multiple runtime containers",
+			runtimes: []asyav1alpha1.RuntimeContainerConfig{{Name: "runtime-a"}, {Name: "runtime-b"}},
+			containers: []corev1.Container{
+				{Name: "runtime-a", Image: "python:3.13-slim"},
+				{Name: "runtime-b", Image: "python:3.13-slim"},
+			},
+			expectError: false,
+		},
+		{
+			name:     "listed runtime container missing",
+			runtimes: []asyav1alpha1.RuntimeContainerConfig{{Name: "runtime-a"}, {Name: "runtime-b"}},
+			containers: []corev1.Container{
+				{Name: "runtime-a", Image: "python:3.13-slim"},
+			},
+			expectError: true,
+			errorMsg:    "workload must contain exactly one container named 'runtime-b', but found 0",
+		},
+		{
+			name:     "runtime container listed twice",
+			runtimes: []asyav1alpha1.RuntimeContainerConfig{{Name: "runtime-a"}, {Name: "runtime-a"}},
+			containers: []corev1.Container{
+				{Name: "runtime-a", Image: "python:3.13-slim"},
+			},
+			expectError: true,
+			errorMsg:    "runtime container 'runtime-a' is listed more than once",
+		},
+		{
+			name:     "container uses generated sidecar name",
+			runtimes: []asyav1alpha1.RuntimeContainerConfig{{Name: "runtime-a"}, {Name: "runtime-b"}},
+			containers: []corev1.Container{
+				{Name: "runtime-a", Image: "python:3.13-slim"},
+				{Name: "runtime-b", Image: "python:3.13-slim"},
+				{Name: "asya-sidecar-runtime-b", Image: "helper:latest"},
+			},
+			expectError: true,
+			errorMsg:    "container name 'asya-sidecar-runtime-b' is reserved for the injected sidecar",
+		},
+		{
+			name:     "metrics address override without port",
+			runtimes: []asyav1alpha1.RuntimeContainerConfig{{Name: "runtime-a"}, {Name: "runtime-b"}},
+			containers: []corev1.Container{
+				{Name: "runtime-a", Image: "python:3.13-slim"},
+				{Name: "runtime-b", Image: "python:3.13-slim"},
+			},
+			sidecarEnv:  []corev1.EnvVar{{Name: "ASYA_METRICS_ADDR", Value: "0.0.0.0"}},
+			expectError: true,
+			errorMsg:    "invalid ASYA_METRICS_ADDR",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &AsyncActorReconciler{}
+
+			asya := &asyav1alpha1.AsyncActor{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-actor",
+					Namespace: "default",
+				},
+				Spec: asyav1alpha1.AsyncActorSpec{
+					Transport: testTransportRabbitMQ,
+					Sidecar:   asyav1alpha1.SidecarConfig{Env: tt.sidecarEnv},
+					Workload: asyav1alpha1.WorkloadConfig{
+						RuntimeContainers: tt.runtimes,
+						Template: asyav1alpha1.PodTemplateSpec{
+							Spec: corev1.PodSpec{
+								Containers: tt.containers,
+							},
+						},
+					},
+				},
+			}
+
+			err := r.validateAsyncActorSpec(asya)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error containing %q, got nil", tt.errorMsg)
+				} else if !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %q", tt.errorMsg, err.Error())
+				}
+			} else if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestInjectSidecar_EnvFrom(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = asyav1alpha1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	r := &AsyncActorReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).Build(),
+		Scheme: scheme,
+		TransportRegistry: &asyaconfig.TransportRegistry{
+			Transports: make(map[string]*asyaconfig.TransportConfig),
+		},
+	}
+
+	envFrom := []corev1.EnvFromSource{
+		{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "actor-config"}}},
+		{Prefix: "DB_", SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "actor-secrets"}}},
+	}
+	userEnvFrom := corev1.EnvFromSource{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "runtime-only"}}}
+
+	asya := &asyav1alpha1.AsyncActor{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-actor",
+			Namespace: "default",
+		},
+		Spec: asyav1alpha1.AsyncActorSpec{
+			Transport: testTransportRabbitMQ,
+			Sidecar:   asyav1alpha1.SidecarConfig{EnvFrom: envFrom},
+			Workload: asyav1alpha1.WorkloadConfig{
+				Template: asyav1alpha1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{Name: runtimeContainerName, Image: "python:3.13-slim", EnvFrom: []corev1.EnvFromSource{userEnvFrom}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	result := r.injectSidecar(asya)
+
+	want := map[string][]corev1.EnvFromSource{
+		sidecarName:          envFrom,
+		runtimeContainerName: append([]corev1.EnvFromSource{userEnvFrom}, envFrom...),
+	}
+	for _, c := range result.Spec.Containers {
+		if !reflect.DeepEqual(c.EnvFrom, want[c.Name]) {
+			t.Errorf("%s envFrom = %+v, want %+v", c.Name, c.EnvFrom, want[c.Name])
+		}
+	}
+}
+
+func TestValidateAsyncActorSpec_SidecarEnvFrom(t *testing.T) {
+	configMapRef := &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "actor-config"}}
+	secretRef := &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "actor-secrets"}}
+
+	tests := []struct {
+		name     string
+		envFrom  []corev1.EnvFromSource
+		errorMsg string
+	}{
+		{name: "configmap and secret", envFrom: []corev1.EnvFromSource{{ConfigMapRef: configMapRef}, {SecretRef: secretRef}}},
+		{name: "both in one entry", envFrom: []corev1.EnvFromSource{{ConfigMapRef: configMapRef, SecretRef: secretRef}}, errorMsg: "sidecar envFrom[0] cannot reference both"},
+		{name: "no reference", envFrom: []corev1.EnvFromSource{{ConfigMapRef: configMapRef}, {Prefix: "X_"}}, errorMsg: "sidecar envFrom[1] must reference a ConfigMap or a Secret"},
+		{name: "unnamed reference", envFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{}}}, errorMsg: "sidecar envFrom[0] must reference a ConfigMap or Secret by name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &AsyncActorReconciler{}
+
+			asya := &asyav1alpha1.AsyncActor{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-actor",
+					Namespace: "default",
+				},
+				Spec: asyav1alpha1.AsyncActorSpec{
+					Transport: testTransportRabbitMQ,
+					Sidecar:   asyav1alpha1.SidecarConfig{EnvFrom: tt.envFrom},
+					Workload: asyav1alpha1.WorkloadConfig{
+						Template: asyav1alpha1.PodTemplateSpec{
+							Spec: corev1.PodSpec{
+								Containers: []corev1.Container{{Name: runtimeContainerName, Image: "python:3.13-slim"}},
+							},
+						},
+					},
+				},
+			}
+
+			err := r.validateAsyncActorSpec(asya)
+
+			if tt.errorMsg != "" {
+				if err == nil {
+					t.Errorf("Expected error containing %q, got nil", tt.errorMsg)
+				} else if !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %q", tt.errorMsg, err.Error())
+				}
+			} else if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestInjectSidecar_ExtraVolumes(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = asyav1alpha1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+
+	r := &AsyncActorReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).Build(),
+		Scheme: scheme,
+		TransportRegistry: &asyaconfig.TransportRegistry{
+			Transports: make(map[string]*asyaconfig.TransportConfig),
+		},
+	}
+
+	modelCache := corev1.Volume{
+		Name: "model-cache",
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "models"},
+		},
+	}
+	runtimeMount := corev1.VolumeMount{Name: "model-cache", MountPath: "/models", ReadOnly: true}
+	sidecarMount := corev1.VolumeMount{Name: "model-cache", MountPath: "/var/cache/models"}
+
+	asya := &asyav1alpha1.AsyncActor{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-actor",
+			Namespace: "default",
+		},
+		Spec: asyav1alpha1.AsyncActorSpec{
+			Transport: testTransportRabbitMQ,
+			Sidecar:   asyav1alpha1.SidecarConfig{ExtraVolumeMounts: []corev1.VolumeMount{sidecarMount}},
+			Workload: asyav1alpha1.WorkloadConfig{
+				ExtraVolumes:      []corev1.Volume{modelCache},
+				ExtraVolumeMounts: []corev1.VolumeMount{runtimeMount},
+				Template: asyav1alpha1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: runtimeContainerName, Image: "python:3.13-slim"}},
+					},
+				},
+			},
+		},
+	}
+
+	if err := r.validateAsyncActorSpec(asya); err != nil {
+		t.Fatalf("Expected valid spec, got %v", err)
+	}
+
+	result := r.injectSidecar(asya)
+
+	if got := result.Spec.Volumes[len(result.Spec.Volumes)-1]; !reflect.DeepEqual(got, modelCache) {
+		t.Errorf("last volume = %+v, want %+v", got, modelCache)
+	}
+	if len(result.Spec.Volumes) != 4 {
+		t.Errorf("Expected 4 volumes (3 injected + 1 extra), got %d", len(result.Spec.Volumes))
+	}
+
+	want := map[string]corev1.VolumeMount{
+		sidecarName:          sidecarMount,
+		runtimeContainerName: runtimeMount,
+	}
+	for _, c := range result.Spec.Containers {
+		if got := c.VolumeMounts[len(c.VolumeMounts)-1]; !reflect.DeepEqual(got, want[c.Name]) {
+			t.Errorf("%s last volume mount = %+v, want %+v", c.Name, got, want[c.Name])
+		}
+	}
+}
+
+func TestValidateAsyncActorSpec_Volumes(t *testing.T) {
+	emptyDir := corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}
+
+	tests := []struct {
+		name           string
+		volumes        []corev1.Volume
+		extraVolumes   []corev1.Volume
+		runtimeMounts  []corev1.VolumeMount
+		workloadMounts []corev1.VolumeMount
+		sidecarMounts  []corev1.VolumeMount
+		errorMsg       string
+	}{
+		{
+			name:           "extra volume mounted in runtime and sidecar",
+			extraVolumes:   []corev1.Volume{{Name: "cache", VolumeSource: emptyDir}},
+			workloadMounts: []corev1.VolumeMount{{Name: "cache", MountPath: "/cache"}},
+			sidecarMounts:  []corev1.VolumeMount{{Name: "cache", MountPath: "/cache"}},
+		},
+		{
+			name:           "extra mount of template volume",
+			volumes:        []corev1.Volume{{Name: "data", VolumeSource: emptyDir}},
+			workloadMounts: []corev1.VolumeMount{{Name: "data", MountPath: "/data"}},
+		},
+		{
+			name:         "reserved volume name",
+			extraVolumes: []corev1.Volume{{Name: tmpVolume, VolumeSource: emptyDir}},
+			errorMsg:     "volume name 'tmp' is reserved for operator use",
+		},
+		{
+			name:     "reserved volume name in template",
+			volumes:  []corev1.Volume{{Name: socketVolume, VolumeSource: emptyDir}},
+			errorMsg: "volume name 'socket-dir' is reserved for operator use",
+		},
+		{
+			name:         "extra volume collides with template volume",
+			volumes:      []corev1.Volume{{Name: "cache", VolumeSource: emptyDir}},
+			extraVolumes: []corev1.Volume{{Name: "cache", VolumeSource: emptyDir}},
+			errorMsg:     "volume 'cache' is defined more than once",
+		},
+		{
+			name:           "mount of undefined volume",
+			workloadMounts: []corev1.VolumeMount{{Name: "missing", MountPath: "/missing"}},
+			errorMsg:       "workload extra volume mount at /missing references undefined volume 'missing'",
+		},
+		{
+			name:          "sidecar mount of undefined volume",
+			sidecarMounts: []corev1.VolumeMount{{Name: "missing", MountPath: "/missing"}},
+			errorMsg:      "sidecar extra volume mount at /missing references undefined volume 'missing'",
+		},
+		{
+			name:           "extra mount at injected path",
+			extraVolumes:   []corev1.Volume{{Name: "scratch", VolumeSource: emptyDir}},
+			workloadMounts: []corev1.VolumeMount{{Name: "scratch", MountPath: "/tmp/"}},
+			errorMsg:       "container 'asya-runtime': volume mount 'scratch' at /tmp collides with volume mount 'tmp'",
+		},
+		{
+			name:          "sidecar extra mount at socket dir",
+			extraVolumes:  []corev1.Volume{{Name: "scratch", VolumeSource: emptyDir}},
+			sidecarMounts: []corev1.VolumeMount{{Name: "scratch", MountPath: defaultSocketDir}},
+			errorMsg:      "container 'asya-sidecar': volume mount 'scratch' at " + defaultSocketDir + " collides with volume mount 'socket-dir'",
+		},
+		{
+			name:           "extra mount collides with container mount",
+			volumes:        []corev1.Volume{{Name: "data", VolumeSource: emptyDir}},
+			extraVolumes:   []corev1.Volume{{Name: "cache", VolumeSource: emptyDir}},
+			runtimeMounts:  []corev1.VolumeMount{{Name: "data", MountPath: "/data"}},
+			workloadMounts: []corev1.VolumeMount{{Name: "cache", MountPath: "/data"}},
+			errorMsg:       "container 'asya-runtime': volume mount 'cache' at /data collides with volume mount 'data'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &AsyncActorReconciler{}
+
+			asya := &asyav1alpha1.AsyncActor{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-actor",
+					Namespace: "default",
+				},
+				Spec: asyav1alpha1.AsyncActorSpec{
+					Transport: testTransportRabbitMQ,
+					Sidecar:   asyav1alpha1.SidecarConfig{ExtraVolumeMounts: tt.sidecarMounts},
+					Workload: asyav1alpha1.WorkloadConfig{
+						ExtraVolumes:      tt.extraVolumes,
+						ExtraVolumeMounts: tt.workloadMounts,
+						Template: asyav1alpha1.PodTemplateSpec{
+							Spec: corev1.PodSpec{
+								Containers: []corev1.Container{{Name: runtimeContainerName, Image: "python:3.13-slim", VolumeMounts: tt.runtimeMounts}},
+								Volumes:    tt.volumes,
+							},
+						},
+					},
+				},
+			}
+
+			err := r.validateAsyncActorSpec(asya)
+
+			if tt.errorMsg != "" {
+				if err == nil {
+					t.Errorf("Expected error containing %q, got nil", tt.errorMsg)
+				} else if !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing %q, got %q", tt.errorMsg, err.Error())
+				}
+			} else if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+// getEnvValue returns the value of the named environment variable, or empty if unset
+func getEnvValue(env []corev1.EnvVar, name string) string {
+	for _, e := range env {
+		if e.Name == name {
+			return e.Value
+		}
+	}
+	return ""
+}
+
+func TestSidecarDrainSeconds(t *testing.T) {
+	tests := []struct {
+		gracePeriod int
+		want        int
+	}{
+		{gracePeriod: 30, want: 25},
+		{gracePeriod: 11, want: 6},
+		{gracePeriod: 10, want: 5},
+		{gracePeriod: 4, want: 2},
+		{gracePeriod: 1, want: 1},
+	}
+
+	for _, tt := range tests {
+		if got := sidecarDrainSeconds(tt.gracePeriod); got != tt.want {
+			t.Errorf("sidecarDrainSeconds(%d) = %d, want %d", tt.gracePeriod, got, tt.want)
+		}
+	}
+}
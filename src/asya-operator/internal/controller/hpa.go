@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	asyav1alpha1 "github.com/asya/operator/api/v1alpha1"
+)
+
+// defaultTargetCPUUtilization is the CPU target of fallback HPAs without CPU or memory target
+const defaultTargetCPUUtilization = int32(80)
+
+// kedaMissingMessage explains the ScalingReady condition of scaling-enabled actors when KEDA is not installed
+const kedaMissingMessage = "KEDA CRDs are not installed, actor is not autoscaled: install KEDA and restart the operator, " +
+	"or run the operator with --hpa-fallback for CPU/memory autoscaling"
+
+// KEDAInstalled reports whether the cluster serves KEDA ScaledObjects
+func KEDAInstalled(mapper meta.RESTMapper) (bool, error) {
+	_, err := mapper.RESTMapping(schema.GroupKind{Group: "keda.sh", Kind: "ScaledObject"}, "v1alpha1")
+	if meta.IsNoMatchError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// hpaName returns the name of the HPA scaling an actor: created by KEDA for its
+// ScaledObject, or by the operator itself in HPA fallback mode
+func (r *AsyncActorReconciler) hpaName(asya *asyav1alpha1.AsyncActor) string {
+	if r.HPAFallback {
+		return asya.Name
+	}
+	return fmt.Sprintf("keda-hpa-%s", asya.Name)
+}
+
+// reconcileHPA creates or updates the CPU/memory based HorizontalPodAutoscaler that scales
+// an actor when KEDA is not installed. It is not queue-aware and cannot scale to zero.
+func (r *AsyncActorReconciler) reconcileHPA(ctx context.Context, asya *asyav1alpha1.AsyncActor) error {
+	logger := log.FromContext(ctx)
+
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      r.hpaName(asya),
+			Namespace: asya.Namespace,
+		},
+	}
+
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, hpa, func() error {
+		if err := controllerutil.SetControllerReference(asya, hpa, r.Scheme); err != nil {
+			return err
+		}
+
+		kind := "Deployment"
+		if asya.Spec.Workload.Kind == "StatefulSet" {
+			kind = "StatefulSet"
+		}
+		hpa.Spec.ScaleTargetRef = autoscalingv2.CrossVersionObjectReference{
+			APIVersion: "apps/v1",
+			Kind:       kind,
+			Name:       asya.Name,
+		}
+
+		// HPAs cannot scale to zero
+		minReplicas := int32(1)
+		if asya.Spec.Scaling.MinReplicas != nil && *asya.Spec.Scaling.MinReplicas > 1 {
+			minReplicas = *asya.Spec.Scaling.MinReplicas
+		}
+		hpa.Spec.MinReplicas = &minReplicas
+
+		maxReplicas := int32(50)
+		if asya.Spec.Scaling.MaxReplicas != nil {
+			maxReplicas = *asya.Spec.Scaling.MaxReplicas
+		}
+		hpa.Spec.MaxReplicas = max(maxReplicas, minReplicas)
+
+		hpa.Spec.Metrics = hpaMetrics(asya.Spec.Scaling)
+		hpa.Spec.Behavior = scalingBehavior()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("HorizontalPodAutoscaler reconciled", "result", result)
+	return nil
+}
+
+// hpaMetrics returns the resource utilization targets of a fallback HPA
+func hpaMetrics(scaling asyav1alpha1.ScalingConfig) []autoscalingv2.MetricSpec {
+	var metrics []autoscalingv2.MetricSpec
+	if scaling.TargetCPUUtilization != nil {
+		metrics = append(metrics, resourceMetric(corev1.ResourceCPU, *scaling.TargetCPUUtilization))
+	}
+	if scaling.TargetMemoryUtilization != nil {
+		metrics = append(metrics, resourceMetric(corev1.ResourceMemory, *scaling.TargetMemoryUtilization))
+	}
+	if len(metrics) == 0 {
+		metrics = append(metrics, resourceMetric(corev1.ResourceCPU, defaultTargetCPUUtilization))
+	}
+	return metrics
+}
+
+// resourceMetric returns an average utilization target for a container resource
+func resourceMetric(resource corev1.ResourceName, utilization int32) autoscalingv2.MetricSpec {
+	return autoscalingv2.MetricSpec{
+		Type: autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricSource{
+			Name: resource,
+			Target: autoscalingv2.MetricTarget{
+				Type:               autoscalingv2.UtilizationMetricType,
+				AverageUtilization: &utilization,
+			},
+		},
+	}
+}
+
+// deleteHPA deletes the fallback HorizontalPodAutoscaler if it exists
+func (r *AsyncActorReconciler) deleteHPA(ctx context.Context, asya *asyav1alpha1.AsyncActor) error {
+	logger := log.FromContext(ctx)
+
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      r.hpaName(asya),
+			Namespace: asya.Namespace,
+		},
+	}
+	if err := r.Delete(ctx, hpa); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			logger.V(1).Info("HorizontalPodAutoscaler not found or already deleted")
+			return nil
+		}
+		return fmt.Errorf("failed to delete HorizontalPodAutoscaler: %w", err)
+	}
+	logger.Info("HorizontalPodAutoscaler deleted successfully")
+	return nil
+}
@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	asyav1alpha1 "github.com/asya/operator/api/v1alpha1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestKEDAInstalled(t *testing.T) {
+	scaledObjectKind := schema.GroupVersionKind{Group: "keda.sh", Version: "v1alpha1", Kind: "ScaledObject"}
+
+	t.Run("KEDA CRDs installed", func(t *testing.T) {
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(scaledObjectKind, meta.RESTScopeNamespace)
+
+		installed, err := KEDAInstalled(mapper)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !installed {
+			t.Error("Expected KEDA to be detected")
+		}
+	})
+
+	t.Run("KEDA CRDs missing", func(t *testing.T) {
+		installed, err := KEDAInstalled(meta.NewDefaultRESTMapper(nil))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if installed {
+			t.Error("Expected KEDA not to be detected")
+		}
+	})
+}
+
+func TestReconcileHPA(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = scheme.AddToScheme(testScheme)
+	_ = asyav1alpha1.AddToScheme(testScheme)
+
+	int32Ptr := func(v int32) *int32 { return &v }
+
+	tests := []struct {
+		name         string
+		scaling      asyav1alpha1.ScalingConfig
+		workloadKind string
+		wantKind     string
+		wantMin      int32
+		wantMax      int32
+		wantTargets  map[corev1.ResourceName]int32
+	}{
+		{
+			name:        "defaults scale on CPU and keep one replica",
+			scaling:     asyav1alpha1.ScalingConfig{Enabled: true, MinReplicas: int32Ptr(0)},
+			wantKind:    "Deployment",
+			wantMin:     1,
+			wantMax:     50,
+			wantTargets: map[corev1.ResourceName]int32{corev1.ResourceCPU: 80},
+		},
+		{
+			name: "custom targets and replicas",
+			scaling: asyav1alpha1.ScalingConfig{
+				Enabled:                 true,
+				MinReplicas:             int32Ptr(2),
+				MaxReplicas:             int32Ptr(10),
+				TargetCPUUtilization:    int32Ptr(60),
+				TargetMemoryUtilization: int32Ptr(75),
+			},
+			wantKind:    "Deployment",
+			wantMin:     2,
+			wantMax:     10,
+			wantTargets: map[corev1.ResourceName]int32{corev1.ResourceCPU: 60, corev1.ResourceMemory: 75},
+		},
+		{
+			name:         "memory only on a StatefulSet",
+			scaling:      asyav1alpha1.ScalingConfig{Enabled: true, TargetMemoryUtilization: int32Ptr(70)},
+			workloadKind: "StatefulSet",
+			wantKind:     "StatefulSet",
+			wantMin:      1,
+			wantMax:      50,
+			wantTargets:  map[corev1.ResourceName]int32{corev1.ResourceMemory: 70},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asya := &asyav1alpha1.AsyncActor{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      testActorName,
+					Namespace: "default",
+				},
+				Spec: asyav1alpha1.AsyncActorSpec{
+					Transport: "sqs",
+					Scaling:   tt.scaling,
+					Workload:  asyav1alpha1.WorkloadConfig{Kind: tt.workloadKind},
+				},
+			}
+
+			fakeClient := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(asya).Build()
+			r := &AsyncActorReconciler{Client: fakeClient, Scheme: testScheme, HPAFallback: true}
+
+			if err := r.reconcileHPA(context.Background(), asya); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+			if err := fakeClient.Get(context.Background(), client.ObjectKey{Name: testActorName, Namespace: "default"}, hpa); err != nil {
+				t.Fatalf("Failed to get HPA: %v", err)
+			}
+
+			if hpa.Spec.ScaleTargetRef.Kind != tt.wantKind || hpa.Spec.ScaleTargetRef.Name != testActorName {
+				t.Errorf("ScaleTargetRef = %+v, want %s %s", hpa.Spec.ScaleTargetRef, tt.wantKind, testActorName)
+			}
+			if hpa.Spec.MinReplicas == nil || *hpa.Spec.MinReplicas != tt.wantMin {
+				t.Errorf("MinReplicas = %v, want %d", hpa.Spec.MinReplicas, tt.wantMin)
+			}
+			if hpa.Spec.MaxReplicas != tt.wantMax {
+				t.Errorf("MaxReplicas = %d, want %d", hpa.Spec.MaxReplicas, tt.wantMax)
+			}
+			if hpa.Spec.Behavior == nil || hpa.Spec.Behavior.ScaleDown == nil {
+				t.Error("Expected scale-down behavior to be set")
+			}
+			if len(hpa.OwnerReferences) != 1 || hpa.OwnerReferences[0].Name != testActorName {
+				t.Errorf("OwnerReferences = %+v, want the AsyncActor", hpa.OwnerReferences)
+			}
+
+			targets := make(map[corev1.ResourceName]int32)
+			for _, metric := range hpa.Spec.Metrics {
+				if metric.Resource == nil || metric.Resource.Target.AverageUtilization == nil {
+					t.Fatalf("Expected resource utilization metric, got %+v", metric)
+				}
+				targets[metric.Resource.Name] = *metric.Resource.Target.AverageUtilization
+			}
+			if len(targets) != len(tt.wantTargets) {
+				t.Errorf("Targets = %v, want %v", targets, tt.wantTargets)
+			}
+			for resource, want := range tt.wantTargets {
+				if targets[resource] != want {
+					t.Errorf("Target %s = %d, want %d", resource, targets[resource], want)
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteHPA(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = scheme.AddToScheme(testScheme)
+	_ = asyav1alpha1.AddToScheme(testScheme)
+
+	asya := &asyav1alpha1.AsyncActor{
+		ObjectMeta: metav1.ObjectMeta{Name: testActorName, Namespace: "default"},
+	}
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: testActorName, Namespace: "default"},
+	}
+
+	fakeClient := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(asya, hpa).Build()
+	r := &AsyncActorReconciler{Client: fakeClient, Scheme: testScheme, HPAFallback: true}
+
+	if err := r.deleteHPA(context.Background(), asya); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err := fakeClient.Get(context.Background(), client.ObjectKey{Name: testActorName, Namespace: "default"}, &autoscalingv2.HorizontalPodAutoscaler{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("Expected HPA to be deleted, got %v", err)
+	}
+
+	// Deleting again is a no-op
+	if err := r.deleteHPA(context.Background(), asya); err != nil {
+		t.Errorf("Unexpected error deleting missing HPA: %v", err)
+	}
+}
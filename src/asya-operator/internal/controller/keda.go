@@ -117,39 +117,9 @@ func (r *AsyncActorReconciler) reconcileScaledObject(ctx context.Context, asya *
 	}
 
 	// Build advanced scaling config with HPA behavior to prevent thrashing
-	stabilizationWindowSeconds := int32(300)
-	selectPolicy := autoscalingv2.MaxChangePolicySelect
 	advanced := &kedav1alpha1.AdvancedConfig{
 		HorizontalPodAutoscalerConfig: &kedav1alpha1.HorizontalPodAutoscalerConfig{
-			Behavior: &autoscalingv2.HorizontalPodAutoscalerBehavior{
-				ScaleDown: &autoscalingv2.HPAScalingRules{
-					StabilizationWindowSeconds: &stabilizationWindowSeconds,
-					SelectPolicy:               &selectPolicy,
-					Policies: []autoscalingv2.HPAScalingPolicy{
-						{
-							Type:          autoscalingv2.PodsScalingPolicy,
-							Value:         1,
-							PeriodSeconds: 60,
-						},
-					},
-				},
-				ScaleUp: &autoscalingv2.HPAScalingRules{
-					StabilizationWindowSeconds: func() *int32 { v := int32(0); return &v }(),
-					SelectPolicy:               &selectPolicy,
-					Policies: []autoscalingv2.HPAScalingPolicy{
-						{
-							Type:          autoscalingv2.PodsScalingPolicy,
-							Value:         10,
-							PeriodSeconds: 60,
-						},
-						{
-							Type:          autoscalingv2.PercentScalingPolicy,
-							Value:         100,
-							PeriodSeconds: 60,
-						},
-					},
-				},
-			},
+			Behavior: scalingBehavior(),
 		},
 	}
 
@@ -269,6 +239,10 @@ func (r *AsyncActorReconciler) buildKEDATriggers(ctx context.Context, asya *asya
 		queueLength = fmt.Sprintf("%d", asya.Spec.Scaling.QueueLength)
 	}
 
+	if asya.Spec.Scaling.ExternalScalerAddress != "" {
+		return buildExternalTrigger(asya, queueLength), nil
+	}
+
 	// Get transport config from registry
 	transport, err := r.TransportRegistry.GetTransport(asya.Spec.Transport)
 	if err != nil {
@@ -285,6 +259,19 @@ func (r *AsyncActorReconciler) buildKEDATriggers(ctx context.Context, asya *asya
 	}
 }
 
+// buildExternalTrigger builds a KEDA external scaler trigger, e.g. for the gateway's scaler
+func buildExternalTrigger(asya *asyav1alpha1.AsyncActor, queueLength string) []kedav1alpha1.ScaleTriggers {
+	return []kedav1alpha1.ScaleTriggers{{
+		Type: "external",
+		Metadata: map[string]string{
+			"scalerAddress":       asya.Spec.Scaling.ExternalScalerAddress,
+			"actor":               asya.Name,
+			"targetSize":          queueLength,
+			"activationThreshold": fmt.Sprintf("%d", asya.Spec.Scaling.ActivationQueueLength),
+		},
+	}}
+}
+
 // buildSQSTrigger builds an SQS KEDA trigger
 func (r *AsyncActorReconciler) buildSQSTrigger(asya *asyav1alpha1.AsyncActor, transport *asyaconfig.TransportConfig, queueLength string) ([]kedav1alpha1.ScaleTriggers, error) {
 	config, ok := transport.Config.(*asyaconfig.SQSConfig)
@@ -500,6 +487,42 @@ func (r *AsyncActorReconciler) reconcileTriggerAuthentication(ctx context.Contex
 	return nil
 }
 
+// scalingBehavior returns the HPA behavior of autoscaled actors, both through KEDA and the
+// HPA fallback: immediate scale-up, slow scale-down to prevent thrashing
+func scalingBehavior() *autoscalingv2.HorizontalPodAutoscalerBehavior {
+	stabilizationWindowSeconds := int32(300)
+	selectPolicy := autoscalingv2.MaxChangePolicySelect
+	return &autoscalingv2.HorizontalPodAutoscalerBehavior{
+		ScaleDown: &autoscalingv2.HPAScalingRules{
+			StabilizationWindowSeconds: &stabilizationWindowSeconds,
+			SelectPolicy:               &selectPolicy,
+			Policies: []autoscalingv2.HPAScalingPolicy{
+				{
+					Type:          autoscalingv2.PodsScalingPolicy,
+					Value:         1,
+					PeriodSeconds: 60,
+				},
+			},
+		},
+		ScaleUp: &autoscalingv2.HPAScalingRules{
+			StabilizationWindowSeconds: func() *int32 { v := int32(0); return &v }(),
+			SelectPolicy:               &selectPolicy,
+			Policies: []autoscalingv2.HPAScalingPolicy{
+				{
+					Type:          autoscalingv2.PodsScalingPolicy,
+					Value:         10,
+					PeriodSeconds: 60,
+				},
+				{
+					Type:          autoscalingv2.PercentScalingPolicy,
+					Value:         100,
+					PeriodSeconds: 60,
+				},
+			},
+		},
+	}
+}
+
 // parseMetricType converts a string to autoscaling MetricTargetType
 func (r *AsyncActorReconciler) parseMetricType(metricType string) autoscalingv2.MetricTargetType {
 	switch metricType {
@@ -216,6 +216,49 @@ func TestBuildKEDATriggers(t *testing.T) {
 		}
 	})
 
+	t.Run("external scaler trigger", func(t *testing.T) {
+		asya := &asyav1alpha1.AsyncActor{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: testActorName,
+			},
+			Spec: asyav1alpha1.AsyncActorSpec{
+				Transport: "rabbitmq",
+				Scaling: asyav1alpha1.ScalingConfig{
+					QueueLength:           10,
+					ExternalScalerAddress: "asya-gateway.default.svc:9090",
+					ActivationQueueLength: 2,
+				},
+			},
+		}
+
+		triggers, err := r.buildKEDATriggers(context.Background(), asya)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(triggers) != 1 {
+			t.Fatalf("Expected 1 trigger, got %d", len(triggers))
+		}
+
+		trigger := triggers[0]
+		if trigger.Type != "external" {
+			t.Errorf("Expected trigger type 'external', got %q", trigger.Type)
+		}
+		want := map[string]string{
+			"scalerAddress":       "asya-gateway.default.svc:9090",
+			"actor":               testActorName,
+			"targetSize":          "10",
+			"activationThreshold": "2",
+		}
+		for key, value := range want {
+			if trigger.Metadata[key] != value {
+				t.Errorf("Expected %s %q, got %q", key, value, trigger.Metadata[key])
+			}
+		}
+		if trigger.AuthenticationRef != nil {
+			t.Errorf("Expected no authentication for external trigger, got %v", trigger.AuthenticationRef)
+		}
+	})
+
 	t.Run("invalid transport returns error", func(t *testing.T) {
 		asya := &asyav1alpha1.AsyncActor{
 			ObjectMeta: metav1.ObjectMeta{
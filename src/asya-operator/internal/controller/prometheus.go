@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+
+	asyav1alpha1 "github.com/asya/operator/api/v1alpha1"
+)
+
+// Standard Prometheus pod discovery annotations
+const (
+	prometheusScrapeAnnotation = "prometheus.io/scrape"
+	prometheusPortAnnotation   = "prometheus.io/port"
+	prometheusPathAnnotation   = "prometheus.io/path"
+
+	sidecarMetricsPath = "/metrics"
+)
+
+// sidecarMetricsPort returns the metrics port of the first sidecar, honoring ASYA_METRICS_ENABLED
+// and ASYA_METRICS_ADDR overrides in spec.sidecar.env. It returns false when metrics are disabled.
+func sidecarMetricsPort(asya *asyav1alpha1.AsyncActor) (int, bool) {
+	port := defaultSidecarMetricsPort
+	for _, env := range asya.Spec.Sidecar.Env {
+		switch env.Name {
+		case "ASYA_METRICS_ENABLED":
+			if enabled, err := strconv.ParseBool(env.Value); err == nil && !enabled {
+				return 0, false
+			}
+		case "ASYA_METRICS_ADDR":
+			_, p, err := parseMetricsAddr(env.Value)
+			if err != nil {
+				return 0, false
+			}
+			port = p
+		}
+	}
+	return port, true
+}
+
+// parseMetricsAddr splits a sidecar metrics address (host:port, host optional) into host and port
+func parseMetricsAddr(addr string) (string, int, error) {
+	host, p, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", 0, err
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		return "", 0, fmt.Errorf("address %s: invalid port %q", addr, p)
+	}
+	return host, port, nil
+}
+
+// sidecarMetricsAddr returns the metrics address of the sidecar at idx (> 0): the first sidecar's
+// address (ASYA_METRICS_ADDR in spec.sidecar.env, or the default port) with its port offset by idx
+func sidecarMetricsAddr(asya *asyav1alpha1.AsyncActor, idx int) string {
+	host, port := "", defaultSidecarMetricsPort
+	for _, env := range asya.Spec.Sidecar.Env {
+		if env.Name != "ASYA_METRICS_ADDR" {
+			continue
+		}
+		// Validated in validateAsyncActorSpec when there are several sidecars
+		if h, p, err := parseMetricsAddr(env.Value); err == nil {
+			host, port = h, p
+		}
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port+idx))
+}
+
+// addScrapeAnnotations points Prometheus pod discovery at the sidecar metrics endpoint.
+// Annotations set in the pod template win, so prometheus.io/scrape: "false" opts an actor out.
+// Prometheus annotations name a single port: with several runtimes, only the first sidecar is scraped.
+func addScrapeAnnotations(template *corev1.PodTemplateSpec, asya *asyav1alpha1.AsyncActor) {
+	port, ok := sidecarMetricsPort(asya)
+	if !ok {
+		return
+	}
+
+	// Copy, the template metadata is shared with the AsyncActor spec
+	annotations := make(map[string]string, len(template.Annotations)+3)
+	for key, value := range template.Annotations {
+		annotations[key] = value
+	}
+	for key, value := range map[string]string{
+		prometheusScrapeAnnotation: "true",
+		prometheusPortAnnotation:   strconv.Itoa(port),
+		prometheusPathAnnotation:   sidecarMetricsPath,
+	} {
+		if _, exists := annotations[key]; !exists {
+			annotations[key] = value
+		}
+	}
+	template.Annotations = annotations
+}
@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	asyav1alpha1 "github.com/asya/operator/api/v1alpha1"
+	asyaconfig "github.com/asya/operator/internal/config"
+)
+
+func TestInjectSidecar_PrometheusScrapeAnnotations(t *testing.T) {
+	defaults := map[string]string{
+		prometheusScrapeAnnotation: "true",
+		prometheusPortAnnotation:   "8080",
+		prometheusPathAnnotation:   "/metrics",
+	}
+
+	tests := []struct {
+		name        string
+		disabled    bool
+		annotations map[string]string
+		sidecarEnv  []corev1.EnvVar
+		want        map[string]string
+	}{
+		{name: "defaults", want: defaults},
+		{name: "disabled in operator", disabled: true, want: nil},
+		{
+			name:        "user annotations kept",
+			annotations: map[string]string{"team": "ml", prometheusScrapeAnnotation: "false"},
+			want: map[string]string{
+				"team":                     "ml",
+				prometheusScrapeAnnotation: "false",
+				prometheusPortAnnotation:   "8080",
+				prometheusPathAnnotation:   "/metrics",
+			},
+		},
+		{
+			name:       "custom metrics address",
+			sidecarEnv: []corev1.EnvVar{{Name: "ASYA_METRICS_ADDR", Value: "0.0.0.0:9100"}},
+			want: map[string]string{
+				prometheusScrapeAnnotation: "true",
+				prometheusPortAnnotation:   "9100",
+				prometheusPathAnnotation:   "/metrics",
+			},
+		},
+		{
+			name:       "sidecar metrics disabled",
+			sidecarEnv: []corev1.EnvVar{{Name: "ASYA_METRICS_ENABLED", Value: "false"}},
+			want:       nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asya := &asyav1alpha1.AsyncActor{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-actor", Namespace: "default"},
+				Spec: asyav1alpha1.AsyncActorSpec{
+					Transport: testTransportRabbitMQ,
+					Sidecar:   asyav1alpha1.SidecarConfig{Env: tt.sidecarEnv},
+					Workload: asyav1alpha1.WorkloadConfig{
+						Template: asyav1alpha1.PodTemplateSpec{
+							Metadata: metav1.ObjectMeta{Annotations: tt.annotations},
+							Spec: corev1.PodSpec{
+								Containers: []corev1.Container{{Name: runtimeContainerName, Image: "python:3.13-slim"}},
+							},
+						},
+					},
+				},
+			}
+			userAnnotations := len(tt.annotations)
+
+			r := &AsyncActorReconciler{
+				TransportRegistry: &asyaconfig.TransportRegistry{
+					Transports: make(map[string]*asyaconfig.TransportConfig),
+				},
+				PrometheusScrapeAnnotations: !tt.disabled,
+			}
+			template := r.injectSidecar(asya)
+
+			if len(template.Annotations) != len(tt.want) || (len(tt.want) > 0 && !reflect.DeepEqual(template.Annotations, tt.want)) {
+				t.Errorf("Annotations = %v, want %v", template.Annotations, tt.want)
+			}
+			if len(asya.Spec.Workload.Template.Metadata.Annotations) != userAnnotations {
+				t.Errorf("AsyncActor spec annotations modified: %v", asya.Spec.Workload.Template.Metadata.Annotations)
+			}
+		})
+	}
+}
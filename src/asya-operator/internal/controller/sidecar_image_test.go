@@ -2,6 +2,7 @@ package controller
 
 import (
 	"os"
+	"reflect"
 	"testing"
 
 	corev1 "k8s.io/api/core/v1"
@@ -162,3 +163,86 @@ func TestInjectSidecar_SidecarImage(t *testing.T) {
 		})
 	}
 }
+
+func TestInjectSidecar_ImagePullSecrets(t *testing.T) {
+	secret := func(name string) corev1.LocalObjectReference {
+		return corev1.LocalObjectReference{Name: name}
+	}
+
+	tests := []struct {
+		name        string
+		envSecrets  string
+		specSecrets []corev1.LocalObjectReference
+		podSecrets  []corev1.LocalObjectReference
+		wantSecrets []corev1.LocalObjectReference
+	}{
+		{
+			name: "no pull secrets",
+		},
+		{
+			name:        "operator defaults",
+			envSecrets:  "registry-a, registry-b,",
+			wantSecrets: []corev1.LocalObjectReference{secret("registry-a"), secret("registry-b")},
+		},
+		{
+			name:        "actor secrets",
+			specSecrets: []corev1.LocalObjectReference{secret("team-registry")},
+			wantSecrets: []corev1.LocalObjectReference{secret("team-registry")},
+		},
+		{
+			name:        "merged with pod template and deduplicated",
+			envSecrets:  "registry-a,team-registry",
+			specSecrets: []corev1.LocalObjectReference{secret("team-registry"), secret("pod-registry")},
+			podSecrets:  []corev1.LocalObjectReference{secret("pod-registry")},
+			wantSecrets: []corev1.LocalObjectReference{secret("pod-registry"), secret("team-registry"), secret("registry-a")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ASYA_IMAGE_PULL_SECRETS", tt.envSecrets)
+
+			scheme := runtime.NewScheme()
+			_ = asyav1alpha1.AddToScheme(scheme)
+			_ = corev1.AddToScheme(scheme)
+
+			r := &AsyncActorReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme).Build(),
+				Scheme: scheme,
+				TransportRegistry: &asyaconfig.TransportRegistry{
+					Transports: make(map[string]*asyaconfig.TransportConfig),
+				},
+			}
+
+			asya := &asyav1alpha1.AsyncActor{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-actor",
+					Namespace: "default",
+				},
+				Spec: asyav1alpha1.AsyncActorSpec{
+					Transport: testTransportRabbitMQ,
+					Workload: asyav1alpha1.WorkloadConfig{
+						ImagePullSecrets: tt.specSecrets,
+						Template: asyav1alpha1.PodTemplateSpec{
+							Spec: corev1.PodSpec{
+								ImagePullSecrets: tt.podSecrets,
+								Containers: []corev1.Container{
+									{
+										Name:  "asya-runtime",
+										Image: "python:3.13-slim",
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+
+			result := r.injectSidecar(asya)
+
+			if !reflect.DeepEqual(result.Spec.ImagePullSecrets, tt.wantSecrets) {
+				t.Errorf("Expected image pull secrets %v, got %v", tt.wantSecrets, result.Spec.ImagePullSecrets)
+			}
+		})
+	}
+}
@@ -58,7 +58,9 @@ func (r *AsyncActorReconciler) updateDisplayFields(asya *asyav1alpha1.AsyncActor
 	asya.Status.ReplicasSummary = fmt.Sprintf("%d/%d", current, desired)
 
 	// Update scaling mode
-	if asya.Spec.Scaling.Enabled {
+	if asya.Spec.Scaling.Enabled && r.HPAFallback {
+		asya.Status.ScalingMode = "HPA"
+	} else if asya.Spec.Scaling.Enabled {
 		asya.Status.ScalingMode = "KEDA"
 	} else {
 		asya.Status.ScalingMode = "Manual"
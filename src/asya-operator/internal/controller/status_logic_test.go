@@ -586,11 +586,10 @@ func TestReadyReplicasSummary(t *testing.T) {
 
 // TestScalingModeDisplay tests the SCALING column values
 func TestScalingModeDisplay(t *testing.T) {
-	r := &AsyncActorReconciler{}
-
 	tests := []struct {
 		name           string
 		scalingEnabled bool
+		hpaFallback    bool
 		expected       string
 	}{
 		{
@@ -598,6 +597,17 @@ func TestScalingModeDisplay(t *testing.T) {
 			scalingEnabled: true,
 			expected:       "KEDA",
 		},
+		{
+			name:           "HPA fallback without KEDA",
+			scalingEnabled: true,
+			hpaFallback:    true,
+			expected:       "HPA",
+		},
+		{
+			name:        "Manual scaling with HPA fallback",
+			hpaFallback: true,
+			expected:    "Manual",
+		},
 		{
 			name:           "Manual scaling",
 			scalingEnabled: false,
@@ -607,6 +617,7 @@ func TestScalingModeDisplay(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			r := &AsyncActorReconciler{HPAFallback: tt.hpaFallback}
 			asya := &asyav1alpha1.AsyncActor{
 				Spec: asyav1alpha1.AsyncActorSpec{
 					Scaling: asyav1alpha1.ScalingConfig{
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
 	amqp "github.com/rabbitmq/amqp091-go"
@@ -106,9 +107,19 @@ func (t *RabbitMQTransport) ReconcileQueue(ctx context.Context, actor *asyav1alp
 		return nil
 	}
 
-	// Auto mode: declare exchange and queue
-	exchange := rabbitmqConfig.Exchange
-	if exchange != "" {
+	// Auto mode: declare exchanges and queue
+	exchanges := []string{actorExchange(actor, rabbitmqConfig)}
+	if isEndQueue(queueName) {
+		// End actors receive the results of every pipeline, whatever its exchange
+		exchanges, err = t.pipelineExchanges(ctx, actor.Namespace, rabbitmqConfig)
+		if err != nil {
+			return err
+		}
+	}
+	for _, exchange := range exchanges {
+		if exchange == "" {
+			continue
+		}
 		err = ch.ExchangeDeclare(
 			exchange,
 			"topic",
@@ -119,7 +130,7 @@ func (t *RabbitMQTransport) ReconcileQueue(ctx context.Context, actor *asyav1alp
 			nil,
 		)
 		if err != nil {
-			return fmt.Errorf("failed to declare exchange: %w", err)
+			return fmt.Errorf("failed to declare exchange %s: %w", exchange, err)
 		}
 	}
 
@@ -183,8 +194,11 @@ func (t *RabbitMQTransport) ReconcileQueue(ctx context.Context, actor *asyav1alp
 		}
 	}
 
-	// Bind queue to exchange if configured
-	if exchange != "" {
+	// Bind queue to exchanges if configured
+	for _, exchange := range exchanges {
+		if exchange == "" {
+			continue
+		}
 		err = ch.QueueBind(
 			queueName,
 			queueName,
@@ -193,11 +207,117 @@ func (t *RabbitMQTransport) ReconcileQueue(ctx context.Context, actor *asyav1alp
 			nil,
 		)
 		if err != nil {
-			return fmt.Errorf("failed to bind queue to exchange: %w", err)
+			return fmt.Errorf("failed to bind queue to exchange %s: %w", exchange, err)
+		}
+	}
+
+	// Drop bindings to exchanges the actor left (spec.exchange changed or was removed),
+	// otherwise the queue keeps receiving messages routed on the old pipeline's exchange
+	for _, exchange := range staleExchanges(actor.Status.BoundExchanges, exchanges) {
+		if err := unbindQueue(conn, queueName, exchange); err != nil {
+			return err
+		}
+		logger.Info("Unbound queue from stale exchange", "queue", queueName, "exchange", exchange)
+	}
+	actor.Status.BoundExchanges = slices.DeleteFunc(slices.Clone(exchanges), func(e string) bool { return e == "" })
+
+	// Results of a pipeline on its own exchange are published to the end queues on that exchange
+	if actor.Spec.Exchange != "" && !isEndQueue(queueName) {
+		if err := bindEndQueues(ctx, conn, actor.Spec.Exchange); err != nil {
+			return err
+		}
+	}
+
+	logger.Info("RabbitMQ queue reconciled", "queue", queueName, "exchanges", exchanges, "dlq_enabled", rabbitmqConfig.Queues.DLQ.Enabled)
+	return nil
+}
+
+// endQueues are the queues of the happy-end and error-end actors, shared by all pipelines
+var endQueues = []string{"asya-happy-end", "asya-error-end"}
+
+func isEndQueue(queueName string) bool {
+	return slices.Contains(endQueues, queueName)
+}
+
+// actorExchange returns the exchange an actor consumes from: its own (spec.exchange) or the transport's
+func actorExchange(actor *asyav1alpha1.AsyncActor, cfg *asyaconfig.RabbitMQConfig) string {
+	if actor.Spec.Exchange != "" {
+		return actor.Spec.Exchange
+	}
+	return cfg.Exchange
+}
+
+// pipelineExchanges returns the transport's exchange and the exchanges of the actors in a namespace
+func (t *RabbitMQTransport) pipelineExchanges(ctx context.Context, namespace string, cfg *asyaconfig.RabbitMQConfig) ([]string, error) {
+	var actors asyav1alpha1.AsyncActorList
+	if err := t.k8sClient.List(ctx, &actors, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list actors: %w", err)
+	}
+
+	exchanges := []string{cfg.Exchange}
+	for i := range actors.Items {
+		if exchange := actors.Items[i].Spec.Exchange; exchange != "" && !slices.Contains(exchanges, exchange) {
+			exchanges = append(exchanges, exchange)
 		}
 	}
+	return exchanges, nil
+}
 
-	logger.Info("RabbitMQ queue reconciled", "queue", queueName, "exchange", exchange, "dlq_enabled", rabbitmqConfig.Queues.DLQ.Enabled)
+// staleExchanges returns the exchanges a queue was bound to that it is no longer bound to
+func staleExchanges(bound, current []string) []string {
+	var stale []string
+	for _, exchange := range bound {
+		if exchange != "" && !slices.Contains(current, exchange) {
+			stale = append(stale, exchange)
+		}
+	}
+	return stale
+}
+
+// unbindQueue removes the binding of a queue to an exchange. An exchange that no longer exists
+// has no bindings left; that error closes the channel, so the unbind gets a channel of its own.
+func unbindQueue(conn *amqp.Connection, queueName, exchange string) error {
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open channel: %w", err)
+	}
+	defer func() {
+		_ = ch.Close()
+	}()
+
+	err = ch.QueueUnbind(queueName, queueName, exchange, nil)
+	var amqpErr *amqp.Error
+	if errors.As(err, &amqpErr) && amqpErr.Code == amqp.NotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to unbind queue from exchange %s: %w", exchange, err)
+	}
+	return nil
+}
+
+// bindEndQueues binds the existing end queues to a pipeline exchange. End queues created
+// later are bound to it when their own actor is reconciled (see pipelineExchanges).
+func bindEndQueues(ctx context.Context, conn *amqp.Connection, exchange string) error {
+	logger := log.FromContext(ctx)
+
+	for _, queueName := range endQueues {
+		// A missing queue closes the channel, so each queue is checked on its own
+		ch, err := conn.Channel()
+		if err != nil {
+			return fmt.Errorf("failed to open channel: %w", err)
+		}
+
+		if _, err := ch.QueueDeclarePassive(queueName, true, false, false, false, nil); err != nil {
+			logger.Info("End queue not found, skipping exchange binding", "queue", queueName, "exchange", exchange)
+			continue
+		}
+		err = ch.QueueBind(queueName, queueName, exchange, false, nil)
+		_ = ch.Close()
+		if err != nil {
+			return fmt.Errorf("failed to bind %s to exchange %s: %w", queueName, exchange, err)
+		}
+	}
 	return nil
 }
 
@@ -394,3 +394,77 @@ func TestRabbitMQTransport_QueueExists_InvalidConfigType(t *testing.T) {
 		t.Errorf("Expected %q error, got %q", expectedError, err.Error())
 	}
 }
+
+func TestRabbitMQTransport_PipelineExchanges(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = asyav1alpha1.AddToScheme(scheme)
+
+	newActor := func(name, namespace, exchange string) *asyav1alpha1.AsyncActor {
+		return &asyav1alpha1.AsyncActor{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       asyav1alpha1.AsyncActorSpec{Transport: transportTypeRabbitMQ, Exchange: exchange},
+		}
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newActor("happy-end", testActorNamespace, ""),
+		newActor("team-a-prep", testActorNamespace, "team-a"),
+		newActor("team-a-infer", testActorNamespace, "team-a"),
+		newActor("team-b-infer", testActorNamespace, "team-b"),
+		newActor("other-infer", "other", "team-c"),
+	).Build()
+
+	transport := NewRabbitMQTransport(fakeClient, &asyaconfig.TransportRegistry{})
+	exchanges, err := transport.pipelineExchanges(context.Background(), testActorNamespace, &asyaconfig.RabbitMQConfig{Exchange: "asya"})
+	if err != nil {
+		t.Fatalf("pipelineExchanges() error = %v", err)
+	}
+
+	got := strings.Join(exchanges, ",")
+	for _, want := range []string{"asya", "team-a", "team-b"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("pipelineExchanges() = %v, missing %s", exchanges, want)
+		}
+	}
+	if len(exchanges) != 3 {
+		t.Errorf("pipelineExchanges() = %v, want each exchange of the namespace once", exchanges)
+	}
+}
+
+func TestActorExchange(t *testing.T) {
+	cfg := &asyaconfig.RabbitMQConfig{Exchange: "asya"}
+
+	actor := &asyav1alpha1.AsyncActor{}
+	if got := actorExchange(actor, cfg); got != "asya" {
+		t.Errorf("actorExchange() = %q, want transport exchange %q", got, "asya")
+	}
+
+	actor.Spec.Exchange = "team-a"
+	if got := actorExchange(actor, cfg); got != "team-a" {
+		t.Errorf("actorExchange() = %q, want actor exchange %q", got, "team-a")
+	}
+}
+
+func TestStaleExchanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		bound   []string
+		current []string
+		want    []string
+	}{
+		{name: "first reconcile", bound: nil, current: []string{"asya"}, want: nil},
+		{name: "unchanged", bound: []string{"team-a"}, current: []string{"team-a"}, want: nil},
+		{name: "exchange changed", bound: []string{"team-a"}, current: []string{"team-b"}, want: []string{"team-a"}},
+		{name: "back to transport exchange", bound: []string{"team-a"}, current: []string{"asya"}, want: []string{"team-a"}},
+		{name: "end queue loses a pipeline", bound: []string{"asya", "team-a", "team-b"}, current: []string{"asya", "team-b"}, want: []string{"team-a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := staleExchanges(tt.bound, tt.current)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("staleExchanges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
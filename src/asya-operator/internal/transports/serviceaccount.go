@@ -0,0 +1,82 @@
+package transports
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	asyav1alpha1 "github.com/asya/operator/api/v1alpha1"
+)
+
+// irsaRoleAnnotation is the ServiceAccount annotation EKS uses to let pods assume an IAM role (IRSA)
+const irsaRoleAnnotation = "eks.amazonaws.com/role-arn"
+
+// ServiceAccountName returns the ServiceAccount used by an actor's pods when the transport
+// manages one: spec.serviceAccountName, or asya-<actor name> when unset
+func ServiceAccountName(actor *asyav1alpha1.AsyncActor) string {
+	if actor.Spec.ServiceAccountName != "" {
+		return actor.Spec.ServiceAccountName
+	}
+	return fmt.Sprintf("asya-%s", actor.Name)
+}
+
+// ReconcileIRSAServiceAccount ensures the actor's ServiceAccount exists and can assume roleArn.
+// A missing ServiceAccount is created with the IRSA annotation and owned by the actor, so it is
+// removed with it. An existing ServiceAccount created by another actor or by the user is used as-is.
+func ReconcileIRSAServiceAccount(ctx context.Context, c client.Client, scheme *runtime.Scheme, actor *asyav1alpha1.AsyncActor, roleArn string) error {
+	logger := log.FromContext(ctx)
+
+	name := ServiceAccountName(actor)
+	sa := &corev1.ServiceAccount{}
+	err := c.Get(ctx, client.ObjectKey{Namespace: actor.Namespace, Name: name}, sa)
+	if apierrors.IsNotFound(err) {
+		sa = &corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   actor.Namespace,
+				Annotations: map[string]string{irsaRoleAnnotation: roleArn},
+			},
+		}
+		if err := controllerutil.SetControllerReference(actor, sa, scheme); err != nil {
+			return err
+		}
+		if err := c.Create(ctx, sa); err != nil {
+			return fmt.Errorf("failed to create ServiceAccount %s: %w", name, err)
+		}
+		logger.Info("ServiceAccount created", "name", name, "roleArn", roleArn)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get ServiceAccount %s: %w", name, err)
+	}
+
+	if !metav1.IsControlledBy(sa, actor) {
+		if sa.Annotations[irsaRoleAnnotation] == "" {
+			logger.Info("Using existing ServiceAccount without IRSA role annotation", "name", name)
+		} else {
+			logger.V(1).Info("Using existing ServiceAccount", "name", name, "roleArn", sa.Annotations[irsaRoleAnnotation])
+		}
+		return nil
+	}
+
+	if sa.Annotations[irsaRoleAnnotation] == roleArn {
+		return nil
+	}
+	if sa.Annotations == nil {
+		sa.Annotations = make(map[string]string)
+	}
+	sa.Annotations[irsaRoleAnnotation] = roleArn
+	if err := c.Update(ctx, sa); err != nil {
+		return fmt.Errorf("failed to update ServiceAccount %s: %w", name, err)
+	}
+
+	logger.Info("ServiceAccount updated", "name", name, "roleArn", roleArn)
+	return nil
+}
@@ -0,0 +1,130 @@
+package transports
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	asyav1alpha1 "github.com/asya/operator/api/v1alpha1"
+	asyaconfig "github.com/asya/operator/internal/config"
+)
+
+const testRoleArn = "arn:aws:iam::123456789012:role/asya-actor"
+
+func TestServiceAccountName(t *testing.T) {
+	actor := &asyav1alpha1.AsyncActor{ObjectMeta: metav1.ObjectMeta{Name: testActorName}}
+	if got := ServiceAccountName(actor); got != "asya-"+testActorName {
+		t.Errorf("Expected default ServiceAccount name %q, got %q", "asya-"+testActorName, got)
+	}
+
+	actor.Spec.ServiceAccountName = "summarizer"
+	if got := ServiceAccountName(actor); got != "summarizer" {
+		t.Errorf("Expected ServiceAccount name %q, got %q", "summarizer", got)
+	}
+}
+
+func TestReconcileIRSAServiceAccount(t *testing.T) {
+	actor := &asyav1alpha1.AsyncActor{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testActorName,
+			Namespace: testActorNamespace,
+			UID:       types.UID("actor-uid"),
+		},
+		Spec: asyav1alpha1.AsyncActorSpec{
+			Transport:          transportTypeSQS,
+			ServiceAccountName: "summarizer",
+		},
+	}
+	controllerRef := metav1.OwnerReference{
+		APIVersion: asyav1alpha1.GroupVersion.String(),
+		Kind:       "AsyncActor",
+		Name:       testActorName,
+		UID:        actor.UID,
+		Controller: func() *bool { b := true; return &b }(),
+	}
+
+	tests := []struct {
+		name           string
+		existing       *corev1.ServiceAccount
+		wantAnnotation string
+		wantOwned      bool
+	}{
+		{
+			name:           "creates missing ServiceAccount",
+			wantAnnotation: testRoleArn,
+			wantOwned:      true,
+		},
+		{
+			name: "uses existing ServiceAccount as-is",
+			existing: &corev1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "summarizer",
+					Namespace:   testActorNamespace,
+					Annotations: map[string]string{irsaRoleAnnotation: "arn:aws:iam::123456789012:role/team"},
+				},
+			},
+			wantAnnotation: "arn:aws:iam::123456789012:role/team",
+		},
+		{
+			name: "updates role of owned ServiceAccount",
+			existing: &corev1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "summarizer",
+					Namespace:       testActorNamespace,
+					Annotations:     map[string]string{irsaRoleAnnotation: "arn:aws:iam::123456789012:role/old"},
+					OwnerReferences: []metav1.OwnerReference{controllerRef},
+				},
+			},
+			wantAnnotation: testRoleArn,
+			wantOwned:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			_ = corev1.AddToScheme(scheme)
+			_ = asyav1alpha1.AddToScheme(scheme)
+
+			builder := fake.NewClientBuilder().WithScheme(scheme)
+			if tt.existing != nil {
+				builder = builder.WithObjects(tt.existing)
+			}
+			fakeClient := builder.Build()
+
+			registry := &asyaconfig.TransportRegistry{
+				Transports: map[string]*asyaconfig.TransportConfig{
+					transportTypeSQS: {
+						Type:    transportTypeSQS,
+						Enabled: true,
+						Config: &asyaconfig.SQSConfig{
+							Region:       "us-east-1",
+							ActorRoleArn: testRoleArn,
+						},
+					},
+				},
+			}
+
+			if err := NewSQSTransport(fakeClient, registry).ReconcileServiceAccount(context.Background(), actor); err != nil {
+				t.Fatalf("ReconcileServiceAccount failed: %v", err)
+			}
+
+			sa := &corev1.ServiceAccount{}
+			if err := fakeClient.Get(context.Background(), client.ObjectKey{Name: "summarizer", Namespace: testActorNamespace}, sa); err != nil {
+				t.Fatalf("Failed to get ServiceAccount: %v", err)
+			}
+			if got := sa.Annotations[irsaRoleAnnotation]; got != tt.wantAnnotation {
+				t.Errorf("Expected role annotation %q, got %q", tt.wantAnnotation, got)
+			}
+			if owned := metav1.IsControlledBy(sa, actor); owned != tt.wantOwned {
+				t.Errorf("Expected ServiceAccount owned by actor = %v, got %v", tt.wantOwned, owned)
+			}
+		})
+	}
+}
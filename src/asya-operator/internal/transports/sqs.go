@@ -206,7 +206,7 @@ func (t *SQSTransport) DeleteQueue(ctx context.Context, actor *asyav1alpha1.Asyn
 	return nil
 }
 
-// ReconcileServiceAccount creates or updates ServiceAccount with IRSA annotation
+// ReconcileServiceAccount creates the actor's ServiceAccount with IRSA annotation if absent
 func (t *SQSTransport) ReconcileServiceAccount(ctx context.Context, actor *asyav1alpha1.AsyncActor) error {
 	logger := log.FromContext(ctx)
 
@@ -227,10 +227,7 @@ func (t *SQSTransport) ReconcileServiceAccount(ctx context.Context, actor *asyav
 		return nil
 	}
 
-	// TODO: Implement ServiceAccount creation with IRSA annotation
-	// This would create a ServiceAccount with eks.amazonaws.com/role-arn annotation
-	logger.Info("ServiceAccount reconciliation not yet implemented", "actor", actor.Name)
-	return nil
+	return ReconcileIRSAServiceAccount(ctx, t.k8sClient, t.k8sClient.Scheme(), actor, sqsConfig.ActorRoleArn)
 }
 
 // createSQSClient creates an SQS client with proper credentials
@@ -20,20 +20,65 @@ Handler Types:
 
         Note: All __init__ parameters must have default values for zero-arg instantiation.
 
+Route metadata:
+    Payload mode handlers may declare a `metadata` parameter to read the envelope's route metadata,
+    the context attached when the envelope was created (e.g. user ID), without it being part of the payload:
+        def process(payload: dict, metadata: dict) -> dict:
+            return {"user": metadata.get("user_id"), ...}
+
+Skipping steps:
+    Payload mode handlers return Skip(payload, to="step") to continue at a later actor of the route,
+    or Skip(payload, next=N) to skip the next N actors (skipping all remaining ones goes to happy-end).
+    An empty response still ends the route early; Skip continues it after the skipped steps:
+        from asya_runtime import Skip
+
+        def process(payload: dict):
+            if payload.get("cached"):
+                return Skip(payload, next=1)  # Skip the next step only
+            return {"result": ...}
+
+    Envelope mode handlers set "skip_to" or "skip_next" on their output envelopes instead.
+    The sidecar checks the target is a later step of the route and fails the envelope otherwise.
+
+Warnings:
+    Handlers that succeed with caveats (e.g. a fallback model was used) call warnings.warn().
+    The messages are attached to the handler's responses as "warnings"; the envelope
+    continues along its route and the gateway shows them on the envelope.
+
+Partial results:
+    Long-running handlers call emit_partial(result) to stream intermediate output (e.g. generated
+    tokens) to clients before returning. Each call sends a {"partial_result": ...} frame to the
+    sidecar ahead of the final response array; the sidecar reports it to the gateway with the
+    envelope's progress. Calls outside a handler are ignored with a warning:
+        from asya_runtime import emit_partial
+
+        def process(payload: dict) -> dict:
+            for chunk in generate(payload):
+                emit_partial({"text": chunk})
+            return {"result": ...}
+
 Environment Variables:
     ASYA_HANDLER: Full path to function or method (e.g., "foo.bar.process" or "foo.bar.Processor.process")
     ASYA_HANDLER_MODE: Handler argument type ("payload" or "envelope", default: "payload")
     ASYA_SOCKET_CHMOD: Socket permissions in octal (default: "0o666", empty = skip chmod)
     ASYA_CHUNK_SIZE: Socket read chunk size in bytes (default: 65536)
+    ASYA_SOCKET_STREAM_THRESHOLD: Stream responses of at least this many bytes in chunks (default: 0, disabled)
     ASYA_ENABLE_VALIDATION: Enable envelope validation ("true" or "false", default: "true")
-    ASYA_LOG_LEVEL: Logging level (DEBUG, INFO, WARNING, ERROR, default: INFO)
+    ASYA_LOG_LEVEL: Logging level (TRACE, DEBUG, INFO, WARNING, ERROR, default: INFO)
+    ASYA_LOG_REDACT_PAYLOADS: How payloads are logged ("omit": sizes only, "hash": sizes and
+        a SHA-256 prefix, "none": full payloads at TRACE level, sizes otherwise; default: "omit")
 
 Socket Configuration:
     The socket path defaults to /var/run/asya/asya-runtime.sock and is managed by the operator.
+    Messages are a 4-byte big-endian length prefix followed by the data. Large messages may
+    instead be streamed: the 0xFFFFFFFF prefix, then length-prefixed chunks ended by an empty
+    chunk. Both framings are always accepted; streaming is used for sending above
+    ASYA_SOCKET_STREAM_THRESHOLD.
     ASYA_SOCKET_DIR and ASYA_SOCKET_NAME are for internal testing only - DO NOT set in production.
 """
 
 import contextlib
+import hashlib
 import importlib
 import inspect
 import json
@@ -45,12 +90,17 @@ import socket
 import struct
 import sys
 import traceback
+import warnings
 from typing import Any
 
 
+# TRACE is below DEBUG, the only level that may log full payloads
+TRACE = logging.DEBUG - 5
+logging.addLevelName(TRACE, "TRACE")
+
 # Configure logging with unbuffered output
 log_level = os.getenv("ASYA_LOG_LEVEL", "INFO").upper()
-log_level_value = getattr(logging, log_level, logging.INFO)
+log_level_value = TRACE if log_level == "TRACE" else getattr(logging, log_level, logging.INFO)
 
 # Ensure stderr is unbuffered
 sys.stderr.reconfigure(line_buffering=True)
@@ -69,6 +119,22 @@ ASYA_HANDLER_MODE = (os.getenv("ASYA_HANDLER_MODE") or "payload").lower()
 ASYA_SOCKET_CHMOD = os.getenv("ASYA_SOCKET_CHMOD", "0o666")
 ASYA_CHUNK_SIZE = int(os.getenv("ASYA_CHUNK_SIZE", 65536))
 ASYA_ENABLE_VALIDATION = os.getenv("ASYA_ENABLE_VALIDATION", "true").lower() == "true"
+ASYA_SOCKET_STREAM_THRESHOLD = int(os.getenv("ASYA_SOCKET_STREAM_THRESHOLD", 0))
+ASYA_LOG_REDACT_PAYLOADS = (os.getenv("ASYA_LOG_REDACT_PAYLOADS") or "omit").strip().lower()
+
+VALID_REDACT_MODES = ("omit", "hash", "none")
+if ASYA_LOG_REDACT_PAYLOADS not in VALID_REDACT_MODES:
+    logger.warning(
+        f"Invalid ASYA_LOG_REDACT_PAYLOADS={ASYA_LOG_REDACT_PAYLOADS}: not in {VALID_REDACT_MODES}, omitting payloads"
+    )
+    ASYA_LOG_REDACT_PAYLOADS = "omit"
+
+# Number of SHA-256 bytes logged in "hash" mode
+REDACT_HASH_BYTES = 8
+
+# Length prefix of a streamed message, and the chunk size of streamed responses
+STREAM_MARKER = 0xFFFFFFFF
+STREAM_CHUNK_SIZE = 1 << 20
 
 # Socket configuration - hard-coded, managed by operator
 # ASYA_SOCKET_DIR and ASYA_SOCKET_NAME are for internal testing only - DO NOT set in production
@@ -78,6 +144,44 @@ SOCKET_PATH = os.path.join(SOCKET_DIR, SOCKET_NAME)
 
 VALID_ASYA_HANDLER_MODES = ("payload", "envelope")
 
+# Connection of the request being handled, where emit_partial sends partial results
+_current_conn: socket.socket | None = None
+
+# Handlers import Skip from asya_runtime while it runs as __main__: share this module instead of loading a copy
+sys.modules.setdefault("asya_runtime", sys.modules[__name__])
+
+
+class Skip:
+    """Payload mode handler result that skips steps of the route after the current actor.
+
+    Either `to` names the later actor to continue at, or `next` is the number of next actors to skip.
+    """
+
+    def __init__(self, payload: Any, to: str | None = None, next: int | None = None):
+        if (to is None) == (next is None):
+            raise ValueError("Skip needs exactly one of 'to' or 'next'")
+        if to is not None and (not isinstance(to, str) or not to):
+            raise ValueError(f"Skip 'to' must be a non-empty actor name, got {to!r}")
+        if next is not None and (not isinstance(next, int) or isinstance(next, bool) or next < 1):
+            raise ValueError(f"Skip 'next' must be a positive integer, got {next!r}")
+        self.payload = payload
+        self.to = to
+        self.next = next
+
+    def fields(self) -> dict[str, Any]:
+        """Returns the skip control field of the runtime response."""
+        if self.to is not None:
+            return {"skip_to": self.to}
+        return {"skip_next": self.next}
+
+
+def emit_partial(result: Any) -> None:
+    """Sends an intermediate result of the running handler to the sidecar, ahead of its final response."""
+    if _current_conn is None:
+        logger.warning("emit_partial called outside a handler, ignoring partial result")
+        return
+    _send_envelope(_current_conn, json.dumps({"partial_result": result}).encode("utf-8"))
+
 
 def _instantiate_class_handler(handler_class):
     """Instantiate class handler.
@@ -222,8 +326,32 @@ def _recv_exact(sock, n: int) -> bytes:
     return b"".join(chunks)
 
 
+def _recv_message(sock) -> bytes:
+    """Read a message, buffered (length-prefixed) or streamed (chunks ended by an empty chunk)."""
+    length = struct.unpack(">I", _recv_exact(sock, 4))[0]
+    if length != STREAM_MARKER:
+        return _recv_exact(sock, length)
+
+    data = bytearray()
+    while True:
+        chunk_length = struct.unpack(">I", _recv_exact(sock, 4))[0]
+        if chunk_length == 0:
+            return bytes(data)
+        data += _recv_exact(sock, chunk_length)
+
+
 def _send_envelope(sock, data: bytes):
-    """Send envelope with length-prefix (4-byte big-endian uint32)."""
+    """Send envelope with length-prefix (4-byte big-endian uint32), streaming large ones in chunks."""
+    if ASYA_SOCKET_STREAM_THRESHOLD > 0 and len(data) >= ASYA_SOCKET_STREAM_THRESHOLD:
+        view = memoryview(data)
+        sock.sendall(struct.pack(">I", STREAM_MARKER))
+        for offset in range(0, len(data), STREAM_CHUNK_SIZE):
+            chunk = view[offset : offset + STREAM_CHUNK_SIZE]
+            sock.sendall(struct.pack(">I", len(chunk)))
+            sock.sendall(chunk)
+        sock.sendall(struct.pack(">I", 0))
+        return
+
     length = struct.pack(">I", len(data))
     sock.sendall(length + data)
 
@@ -239,7 +367,8 @@ def _setup_socket(socket_path):
 
     sock = socket.socket(socket.AF_UNIX, socket.SOCK_STREAM)
     sock.bind(socket_path)
-    sock.listen(5)
+    # Requests of a sidecar with ASYA_CONCURRENCY above 1 wait here while a handler runs
+    sock.listen(socket.SOMAXCONN)
 
     # Apply chmod if configured (skip if ASYA_SOCKET_CHMOD is empty)
     if ASYA_SOCKET_CHMOD:
@@ -336,6 +465,12 @@ def _validate_envelope(
     if "id" in e and not isinstance(e["id"], str):
         raise ValueError("Field 'id' must be a string")
 
+    # Validate skip control fields if present (the sidecar checks them against the route)
+    if "skip_to" in e and not isinstance(e["skip_to"], str):
+        raise ValueError("Field 'skip_to' must be a string")
+    if "skip_next" in e and (not isinstance(e["skip_next"], int) or isinstance(e["skip_next"], bool)):
+        raise ValueError("Field 'skip_next' must be an integer")
+
     result = {
         "payload": e["payload"],
         "route": e["route"],
@@ -344,8 +479,14 @@ def _validate_envelope(
         result["id"] = e["id"]
     if "parent_id" in e:
         result["parent_id"] = e["parent_id"]
+    if "branch_index" in e:
+        result["branch_index"] = e["branch_index"]
     if "headers" in e:
         result["headers"] = e["headers"]
+    if "skip_to" in e:
+        result["skip_to"] = e["skip_to"]
+    if "skip_next" in e:
+        result["skip_next"] = e["skip_next"]
 
     return result
 
@@ -356,6 +497,41 @@ def _get_current_actor(e: dict) -> str:
     return actors[current]
 
 
+def _call_handler(user_func: Any, arg: Any, **kwargs: Any) -> tuple[Any, list[str]]:
+    """Calls the user function, collecting the messages of warnings it emits."""
+    with warnings.catch_warnings(record=True) as caught:
+        warnings.simplefilter("always")
+        result = user_func(arg, **kwargs)
+    return result, [str(w.message) for w in caught]
+
+
+def _accepts_metadata(user_func: Any) -> bool:
+    """Tells whether a payload mode handler takes the route metadata as a `metadata` argument."""
+    try:
+        return "metadata" in inspect.signature(user_func).parameters
+    except (TypeError, ValueError):
+        return False
+
+
+class _LoggedPayload:
+    """Payload in a log message, redacted per ASYA_LOG_REDACT_PAYLOADS.
+
+    Logs the payload's JSON size (and in "hash" mode a SHA-256 prefix), or the payload itself
+    with ASYA_LOG_REDACT_PAYLOADS=none at TRACE level. Formatted only when the record is emitted.
+    """
+
+    def __init__(self, payload: Any):
+        self.payload = payload
+
+    def __str__(self) -> str:
+        data = json.dumps(self.payload, default=str).encode("utf-8")
+        if ASYA_LOG_REDACT_PAYLOADS == "none" and logger.isEnabledFor(TRACE):
+            return data.decode("utf-8")
+        if ASYA_LOG_REDACT_PAYLOADS == "hash":
+            return f"<{len(data)} bytes, sha256={hashlib.sha256(data).hexdigest()[: REDACT_HASH_BYTES * 2]}>"
+        return f"<{len(data)} bytes>"
+
+
 def _error_response(code: str, exc: Exception | None = None) -> list[dict[str, Any]]:
     """Returns standardized error response dict."""
     error: dict[str, Any] = {"error": code}
@@ -369,12 +545,20 @@ def _error_response(code: str, exc: Exception | None = None) -> list[dict[str, A
 
 
 def _handle_request(conn: socket.socket, user_func: Any) -> list[dict[str, Any]]:
-    """Handle a single request with length-prefix framing."""
+    """Handle a single request with length-prefix framing, letting the handler emit partial results on conn."""
+    global _current_conn
+    _current_conn = conn
+    try:
+        return _handle_envelope(conn, user_func)
+    finally:
+        _current_conn = None
+
+
+def _handle_envelope(conn: socket.socket, user_func: Any) -> list[dict[str, Any]]:
+    """Reads an envelope from conn and calls the handler on it, returning its responses."""
     # Read envelope from socket
     try:
-        length_bytes = _recv_exact(conn, 4)
-        length = struct.unpack(">I", length_bytes)[0]
-        data = _recv_exact(conn, length)
+        data = _recv_message(conn)
     except ConnectionError as exc:
         return _error_response("connection_error", exc)
     except Exception as exc:
@@ -401,9 +585,14 @@ def _handle_request(conn: socket.socket, user_func: Any) -> list[dict[str, Any]]
             # Simple processor: user function expects and returns payload only
             # Runtime auto-increments route.current for normal actors
             # NOTE: End actors should NOT use payload mode - they run in envelope mode
-            logger.info(f"[DIAG] Calling user_func with payload: {e['payload']}")
-            payload = user_func(e["payload"])  # user function
-            logger.info(f"[DIAG] user_func returned: {payload}")
+            logger.info("[DIAG] Calling user_func with payload: %s", _LoggedPayload(e["payload"]))
+            # Handlers declaring a `metadata` parameter also get the route metadata (a copy,
+            # the metadata is passed on unchanged)
+            kwargs: dict[str, Any] = {}
+            if _accepts_metadata(user_func):
+                kwargs["metadata"] = dict(e["route"].get("metadata") or {})
+            payload, handler_warnings = _call_handler(user_func, e["payload"], **kwargs)  # user function
+            logger.info("[DIAG] user_func returned: %s", _LoggedPayload(payload))
             payload_list: list[Any]
             if payload is None:
                 payload_list = []
@@ -419,7 +608,10 @@ def _handle_request(conn: socket.socket, user_func: Any) -> list[dict[str, Any]]
             # Build output envelopes with updated route
             out_list = []
             for p in payload_list:
-                out: dict[str, Any] = {"payload": p, "route": output_route}
+                skip: dict[str, Any] = {}
+                if isinstance(p, Skip):
+                    p, skip = p.payload, p.fields()
+                out: dict[str, Any] = {"payload": p, "route": output_route, **skip}
                 if "headers" in e:
                     out["headers"] = e["headers"]
                 out_list.append(out)
@@ -428,7 +620,7 @@ def _handle_request(conn: socket.socket, user_func: Any) -> list[dict[str, Any]]
             # Full envelope mode: user function gets complete envelope structure
             # Handler is responsible for route management (including incrementing current)
             # End actors use this mode and return empty dict {} (no routing)
-            out = user_func(e)  # user function
+            out, handler_warnings = _call_handler(user_func, e)  # user function
             if out is None:
                 out_list = []
             elif isinstance(out, (list, tuple)):
@@ -451,6 +643,13 @@ def _handle_request(conn: socket.socket, user_func: Any) -> list[dict[str, Any]]
         else:
             raise ValueError(f"Invalid ASYA_HANDLER_MODE={ASYA_HANDLER_MODE}: not in {VALID_ASYA_HANDLER_MODES}")
 
+        # Warnings do not fail the envelope, every response carries them
+        if handler_warnings:
+            logger.warning(f"Handler succeeded with warnings: {handler_warnings}")
+            for out in out_list:
+                if isinstance(out, dict):
+                    out["warnings"] = handler_warnings
+
         logger.info(f"[DIAG] Handler completed successfully: returning {len(out_list)} response(s)")
         return out_list
 
@@ -11,6 +11,7 @@ import sys
 import tempfile
 import textwrap
 import threading
+import warnings
 from contextlib import contextmanager
 from pathlib import Path
 
@@ -164,6 +165,236 @@ class TestHandlerReturnTypeValidation:
         assert len(responses) == 0
 
 
+class TestHandlerWarnings:
+    """Test warnings emitted by handlers that succeed."""
+
+    def test_warnings_attached_to_every_response(self, socket_pair):
+        """Test warnings.warn() messages are attached to all fanout responses."""
+        server_sock, client_sock = socket_pair
+
+        def fallback_handler(payload):
+            warnings.warn("used fallback model")
+            return [{"chunk": 1}, {"chunk": 2}]
+
+        envelope = {
+            "payload": {"test": "data"},
+            "route": {"actors": ["a", "b"], "current": 0},
+        }
+        asya_runtime._send_envelope(client_sock, json.dumps(envelope).encode("utf-8"))
+
+        responses = asya_runtime._handle_request(server_sock, fallback_handler)
+
+        assert len(responses) == 2
+        for response in responses:
+            assert response["warnings"] == ["used fallback model"]
+            assert "error" not in response
+            assert response["route"]["current"] == 1
+
+    def test_no_warnings_field_without_warnings(self, socket_pair):
+        """Test responses of handlers without warnings have no warnings field."""
+        server_sock, client_sock = socket_pair
+
+        envelope = {
+            "payload": {"test": "data"},
+            "route": {"actors": ["a"], "current": 0},
+        }
+        asya_runtime._send_envelope(client_sock, json.dumps(envelope).encode("utf-8"))
+
+        responses = asya_runtime._handle_request(server_sock, lambda payload: payload)
+
+        assert len(responses) == 1
+        assert "warnings" not in responses[0]
+
+    def test_warnings_in_envelope_mode(self, socket_pair, mock_env):
+        """Test warnings are collected from envelope mode handlers."""
+        server_sock, client_sock = socket_pair
+
+        def envelope_handler(envelope):
+            warnings.warn("input truncated", UserWarning)
+            warnings.warn("input truncated", UserWarning)
+            envelope["route"]["current"] += 1
+            return envelope
+
+        with mock_env(ASYA_HANDLER_MODE="envelope"):
+            envelope = {
+                "id": "abc-123",
+                "payload": {"test": "data"},
+                "route": {"actors": ["a", "b"], "current": 0},
+            }
+            asya_runtime._send_envelope(client_sock, json.dumps(envelope).encode("utf-8"))
+
+            responses = asya_runtime._handle_request(server_sock, envelope_handler)
+
+        assert len(responses) == 1
+        assert responses[0]["warnings"] == ["input truncated", "input truncated"]
+
+
+class TestRouteMetadata:
+    """Test route metadata passed to payload mode handlers."""
+
+    def test_metadata_passed_to_handler_declaring_it(self, socket_pair):
+        """Test handlers with a metadata parameter get the route metadata, which is passed on unchanged."""
+        server_sock, client_sock = socket_pair
+
+        def context_handler(payload, metadata):
+            metadata["user_id"] = "overwritten"
+            return {"user": metadata.get("tenant")}
+
+        envelope = {
+            "payload": {"test": "data"},
+            "route": {"actors": ["a", "b"], "current": 0, "metadata": {"job_id": "abc-123", "tenant": "acme"}},
+        }
+        asya_runtime._send_envelope(client_sock, json.dumps(envelope).encode("utf-8"))
+
+        responses = asya_runtime._handle_request(server_sock, context_handler)
+
+        assert len(responses) == 1
+        assert responses[0]["payload"] == {"user": "acme"}
+        assert responses[0]["route"]["metadata"] == {"job_id": "abc-123", "tenant": "acme"}
+
+    def test_metadata_empty_without_route_metadata(self, socket_pair):
+        """Test handlers with a metadata parameter get an empty dict when the route has none."""
+        server_sock, client_sock = socket_pair
+
+        envelope = {
+            "payload": {"test": "data"},
+            "route": {"actors": ["a"], "current": 0},
+        }
+        asya_runtime._send_envelope(client_sock, json.dumps(envelope).encode("utf-8"))
+
+        responses = asya_runtime._handle_request(server_sock, lambda payload, metadata: {"metadata": metadata})
+
+        assert responses[0]["payload"] == {"metadata": {}}
+
+    def test_metadata_not_passed_to_payload_only_handler(self, socket_pair):
+        """Test handlers without a metadata parameter are called with the payload only."""
+        server_sock, client_sock = socket_pair
+
+        envelope = {
+            "payload": {"test": "data"},
+            "route": {"actors": ["a"], "current": 0, "metadata": {"tenant": "acme"}},
+        }
+        asya_runtime._send_envelope(client_sock, json.dumps(envelope).encode("utf-8"))
+
+        responses = asya_runtime._handle_request(server_sock, lambda payload: payload)
+
+        assert responses[0]["payload"] == {"test": "data"}
+        assert responses[0]["route"]["metadata"] == {"tenant": "acme"}
+
+
+class TestSkipSteps:
+    """Test handlers skipping steps of the route."""
+
+    def test_skip_next_in_payload_mode(self, socket_pair):
+        """Test Skip(next=N) sets skip_next and unwraps the payload."""
+        server_sock, client_sock = socket_pair
+
+        envelope = {
+            "payload": {"test": "data"},
+            "route": {"actors": ["a", "b", "c"], "current": 0},
+        }
+        asya_runtime._send_envelope(client_sock, json.dumps(envelope).encode("utf-8"))
+
+        responses = asya_runtime._handle_request(server_sock, lambda payload: asya_runtime.Skip(payload, next=1))
+
+        assert len(responses) == 1
+        assert responses[0]["payload"] == {"test": "data"}
+        assert responses[0]["route"]["current"] == 1
+        assert responses[0]["skip_next"] == 1
+        assert "skip_to" not in responses[0]
+
+    def test_skip_to_in_fanout(self, socket_pair):
+        """Test fanout responses skip independently."""
+        server_sock, client_sock = socket_pair
+
+        def branching_handler(payload):
+            return [{"branch": 1}, asya_runtime.Skip({"branch": 2}, to="c")]
+
+        envelope = {
+            "payload": {"test": "data"},
+            "route": {"actors": ["a", "b", "c"], "current": 0},
+        }
+        asya_runtime._send_envelope(client_sock, json.dumps(envelope).encode("utf-8"))
+
+        responses = asya_runtime._handle_request(server_sock, branching_handler)
+
+        assert len(responses) == 2
+        assert "skip_to" not in responses[0]
+        assert responses[1]["payload"] == {"branch": 2}
+        assert responses[1]["skip_to"] == "c"
+
+    @pytest.mark.parametrize(
+        "kwargs",
+        [{}, {"to": "c", "next": 1}, {"to": ""}, {"next": 0}, {"next": True}],
+    )
+    def test_invalid_skip_is_processing_error(self, socket_pair, kwargs):
+        """Test malformed Skip results fail the handler call."""
+        server_sock, client_sock = socket_pair
+
+        envelope = {
+            "payload": {"test": "data"},
+            "route": {"actors": ["a", "b", "c"], "current": 0},
+        }
+        asya_runtime._send_envelope(client_sock, json.dumps(envelope).encode("utf-8"))
+
+        responses = asya_runtime._handle_request(server_sock, lambda payload: asya_runtime.Skip(payload, **kwargs))
+
+        assert len(responses) == 1
+        assert responses[0]["error"] == "processing_error"
+
+    def test_skip_fields_in_envelope_mode(self, socket_pair, mock_env):
+        """Test skip fields set by envelope mode handlers are passed on."""
+        server_sock, client_sock = socket_pair
+
+        def envelope_handler(envelope):
+            envelope["route"]["current"] += 1
+            envelope["skip_to"] = "c"
+            return envelope
+
+        with mock_env(ASYA_HANDLER_MODE="envelope"):
+            envelope = {
+                "id": "abc-123",
+                "payload": {"test": "data"},
+                "route": {"actors": ["a", "b", "c"], "current": 0},
+            }
+            asya_runtime._send_envelope(client_sock, json.dumps(envelope).encode("utf-8"))
+
+            responses = asya_runtime._handle_request(server_sock, envelope_handler)
+
+        assert len(responses) == 1
+        assert responses[0]["skip_to"] == "c"
+
+
+class TestPartialResults:
+    """Test partial results emitted by handlers ahead of their response."""
+
+    def test_partial_results_sent_before_response(self, socket_pair):
+        """Test emit_partial() frames reach the sidecar before the final response."""
+        server_sock, client_sock = socket_pair
+
+        def streaming_handler(payload):
+            asya_runtime.emit_partial({"text": "Hel"})
+            asya_runtime.emit_partial({"text": "Hello"})
+            return {"text": "Hello!"}
+
+        envelope = {
+            "payload": {"test": "data"},
+            "route": {"actors": ["a"], "current": 0},
+        }
+        asya_runtime._send_envelope(client_sock, json.dumps(envelope).encode("utf-8"))
+
+        responses = asya_runtime._handle_request(server_sock, streaming_handler)
+
+        assert json.loads(asya_runtime._recv_message(client_sock)) == {"partial_result": {"text": "Hel"}}
+        assert json.loads(asya_runtime._recv_message(client_sock)) == {"partial_result": {"text": "Hello"}}
+        assert responses[0]["payload"] == {"text": "Hello!"}
+        assert asya_runtime._current_conn is None
+
+    def test_emit_partial_outside_handler_ignored(self):
+        """Test emit_partial() without a running handler does nothing."""
+        asya_runtime.emit_partial({"text": "ignored"})
+
+
 class TestRouteValidation:
     """Test route validation edge cases."""
 
@@ -266,6 +497,20 @@ class TestEnvelopeFieldPreservation:
         assert validated["parent_id"] == "parent-envelope-123"
         assert validated["payload"] == {"test": "data"}
 
+    def test_validate_envelope_preserves_branch_index_field(self):
+        """Test that branch_index field is preserved through validation."""
+        envelope = {
+            "id": "envelope-456-2",
+            "parent_id": "envelope-456",
+            "branch_index": 2,
+            "payload": {"test": "data"},
+            "route": {"actors": ["a"], "current": 0},
+        }
+        validated = asya_runtime._validate_envelope(envelope)
+
+        assert validated["parent_id"] == "envelope-456"
+        assert validated["branch_index"] == 2
+
     def test_validate_envelope_preserves_all_fields(self):
         """Test that all envelope fields are preserved together."""
         envelope = {
@@ -865,6 +1110,57 @@ class TestSocketProtocol:
 
         sender_thread.join()
 
+    def test_recv_message_buffered(self, socket_pair):
+        """Test recv_message with a length-prefixed message."""
+        server_sock, client_sock = socket_pair
+
+        client_sock.sendall(struct.pack(">I", 5) + b"hello")
+        assert asya_runtime._recv_message(server_sock) == b"hello"
+
+    def test_recv_message_streamed(self, socket_pair):
+        """Test recv_message with a message streamed in chunks."""
+        server_sock, client_sock = socket_pair
+
+        frames = [struct.pack(">I", asya_runtime.STREAM_MARKER)]
+        for chunk in (b"hello ", b"streamed ", b"world"):
+            frames.append(struct.pack(">I", len(chunk)) + chunk)
+        frames.append(struct.pack(">I", 0))
+        client_sock.sendall(b"".join(frames))
+
+        assert asya_runtime._recv_message(server_sock) == b"hello streamed world"
+
+    def test_recv_message_truncated_stream(self, socket_pair):
+        """Test recv_message when the connection closes inside a streamed message."""
+        server_sock, client_sock = socket_pair
+
+        client_sock.sendall(struct.pack(">I", asya_runtime.STREAM_MARKER) + struct.pack(">I", 10) + b"abc")
+        client_sock.close()
+
+        with pytest.raises(ConnectionError):
+            asya_runtime._recv_message(server_sock)
+
+    def test_send_envelope_streamed(self, socket_pair, monkeypatch):
+        """Test send_envelope streams messages above the threshold in chunks."""
+        server_sock, client_sock = socket_pair
+        monkeypatch.setattr(asya_runtime, "ASYA_SOCKET_STREAM_THRESHOLD", 10)
+        monkeypatch.setattr(asya_runtime, "STREAM_CHUNK_SIZE", 4)
+
+        asya_runtime._send_envelope(client_sock, b"0123456789")
+
+        marker = struct.unpack(">I", asya_runtime._recv_exact(server_sock, 4))[0]
+        assert marker == asya_runtime.STREAM_MARKER
+        chunks = []
+        while True:
+            length = struct.unpack(">I", asya_runtime._recv_exact(server_sock, 4))[0]
+            if length == 0:
+                break
+            chunks.append(asya_runtime._recv_exact(server_sock, length))
+        assert chunks == [b"0123", b"4567", b"89"]
+
+        # Below the threshold the message is length-prefixed
+        asya_runtime._send_envelope(client_sock, b"short")
+        assert asya_runtime._recv_exact(server_sock, 9) == struct.pack(">I", 5) + b"short"
+
 
 class TestSocketSetup:
     """Test socket setup and cleanup."""
@@ -1005,6 +1301,42 @@ class TestErrorDict:
             assert "ValueError" in err["details"]["traceback"]
 
 
+class TestLoggedPayload:
+    """Test payload redaction in logs (ASYA_LOG_REDACT_PAYLOADS)."""
+
+    payload = {"ssn": "123-45-6789"}
+
+    def test_omit_by_default(self):
+        logged = str(asya_runtime._LoggedPayload(self.payload))
+        assert logged == "<22 bytes>"
+
+    def test_hash(self, monkeypatch):
+        monkeypatch.setattr(asya_runtime, "ASYA_LOG_REDACT_PAYLOADS", "hash")
+        logged = str(asya_runtime._LoggedPayload(self.payload))
+        assert logged.startswith("<22 bytes, sha256=")
+        assert "123-45-6789" not in logged
+
+    def test_none_below_trace_logs_size(self, monkeypatch):
+        monkeypatch.setattr(asya_runtime, "ASYA_LOG_REDACT_PAYLOADS", "none")
+        monkeypatch.setattr(asya_runtime.logger, "isEnabledFor", lambda level: level >= asya_runtime.logging.DEBUG)
+        assert str(asya_runtime._LoggedPayload(self.payload)) == "<22 bytes>"
+
+    def test_none_at_trace_logs_payload(self, monkeypatch):
+        monkeypatch.setattr(asya_runtime, "ASYA_LOG_REDACT_PAYLOADS", "none")
+        monkeypatch.setattr(asya_runtime.logger, "isEnabledFor", lambda level: level >= asya_runtime.TRACE)
+        assert "123-45-6789" in str(asya_runtime._LoggedPayload(self.payload))
+
+    def test_handler_logs_redact_payload(self, socket_pair, caplog):
+        server_sock, client_sock = socket_pair
+        envelope = {"id": "test-redact", "route": {"actors": ["a"], "current": 0}, "payload": self.payload}
+        asya_runtime._send_envelope(client_sock, json.dumps(envelope).encode("utf-8"))
+
+        with caplog.at_level("INFO", logger="asya.runtime"):
+            asya_runtime._handle_request(server_sock, lambda payload: payload)
+        assert "[DIAG] Calling user_func" in caplog.text
+        assert "123-45-6789" not in caplog.text
+
+
 class TestHandleRequestPayloadMode:
     """Test _handle_request in payload mode (ASYA_HANDLER_MODE=payload)."""
 
@@ -12,8 +12,11 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/deliveryhero/asya/asya-sidecar/internal/adapter"
 	"github.com/deliveryhero/asya/asya-sidecar/internal/config"
+	"github.com/deliveryhero/asya/asya-sidecar/internal/logging"
 	"github.com/deliveryhero/asya/asya-sidecar/internal/metrics"
+	"github.com/deliveryhero/asya/asya-sidecar/internal/profiling"
 	"github.com/deliveryhero/asya/asya-sidecar/internal/router"
 	"github.com/deliveryhero/asya/asya-sidecar/internal/runtime"
 	"github.com/deliveryhero/asya/asya-sidecar/internal/transport"
@@ -90,6 +93,30 @@ func waitForRuntime(ctx context.Context, readyFile string, socketPath string, ma
 	}
 }
 
+// waitForRouter waits for the router to return. Once ctx is cancelled (shutdown), the in-flight
+// message gets up to drain to finish (0 waits indefinitely); drained is false if it did not.
+func waitForRouter(ctx context.Context, done <-chan error, drain time.Duration) (drained bool, err error) {
+	select {
+	case err := <-done:
+		return true, err
+	case <-ctx.Done():
+	}
+
+	var timeout <-chan time.Time
+	if drain > 0 {
+		timer := time.NewTimer(drain)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case err := <-done:
+		return true, err
+	case <-timeout:
+		return false, nil
+	}
+}
+
 func main() {
 	// Set up structured logging with level control
 	logLevel := os.Getenv("ASYA_LOG_LEVEL")
@@ -110,9 +137,10 @@ func main() {
 		level = slog.LevelInfo
 	}
 
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+	// Log records of an envelope being processed carry its job_id
+	logger := slog.New(logging.NewHandler(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
 		Level: level,
-	}))
+	})))
 	slog.SetDefault(logger)
 
 	slog.Info("Starting Asya Actor Sidecar", "logLevel", logLevel)
@@ -130,36 +158,50 @@ func main() {
 	var tp transport.Transport
 	switch cfg.TransportType {
 	case "rabbitmq":
-		tp, err = transport.NewRabbitMQTransport(transport.RabbitMQConfig{
-			URL:           cfg.RabbitMQURL,
-			Exchange:      cfg.RabbitMQExchange,
-			PrefetchCount: cfg.RabbitMQPrefetch,
+		connectCtx, cancel := context.WithTimeout(context.Background(), cfg.RabbitMQConnectTimeout)
+		tp, err = transport.NewRabbitMQTransport(connectCtx, transport.RabbitMQConfig{
+			URL:             cfg.RabbitMQURL,
+			Exchange:        cfg.RabbitMQExchange,
+			PrefetchCount:   cfg.RabbitMQPrefetch,
+			PublishChannels: cfg.RabbitMQPublish,
+			AutoAck:         cfg.AutoAck,
+			RepublishOnNack: cfg.RabbitMQRepublishOnNack,
 		})
+		cancel()
 		if err != nil {
 			slog.Error("Failed to create RabbitMQ transport", "error", err)
 			os.Exit(1)
 		}
 		slog.Info("RabbitMQ transport initialized", "exchange", cfg.RabbitMQExchange)
+		if cfg.AutoAck {
+			slog.Warn("Auto-ack enabled: messages are removed from the queue on delivery and lost if processing fails (at-most-once)")
+		}
 	case "sqs":
 		initCtx := context.Background()
 		visibilityTimeout := cfg.SQSVisibilityTimeout
 		if visibilityTimeout == 0 {
-			visibilityTimeout = int32(cfg.Timeout.Seconds() * 2)
+			// Cover the longest runtime call, including per-message timeout overrides
+			visibilityTimeout = int32(cfg.MaxProcessingTimeout.Seconds() * 2)
 		}
 		tp, err = transport.NewSQSTransport(initCtx, transport.SQSConfig{
-			Region:            cfg.SQSRegion,
-			BaseURL:           cfg.SQSBaseURL,
-			VisibilityTimeout: visibilityTimeout,
-			WaitTimeSeconds:   cfg.SQSWaitTimeSeconds,
+			Region:              cfg.SQSRegion,
+			BaseURL:             cfg.SQSBaseURL,
+			VisibilityTimeout:   visibilityTimeout,
+			WaitTimeSeconds:     cfg.SQSWaitTimeSeconds,
+			VisibilityExtension: cfg.SQSVisibilityExtension,
 		})
 		if err != nil {
 			slog.Error("Failed to create SQS transport", "error", err)
 			os.Exit(1)
 		}
+		if cfg.AutoAck {
+			slog.Warn("ASYA_AUTO_ACK is not supported by the SQS transport, messages are deleted after processing")
+		}
 		slog.Info("SQS transport initialized",
 			"region", cfg.SQSRegion,
 			"baseURL", cfg.SQSBaseURL,
 			"visibilityTimeout", visibilityTimeout,
+			"visibilityExtension", cfg.SQSVisibilityExtension,
 			"waitTimeSeconds", cfg.SQSWaitTimeSeconds)
 	default:
 		slog.Error("Unsupported transport type", "transport", cfg.TransportType)
@@ -167,9 +209,18 @@ func main() {
 	}
 	defer func() { _ = tp.Close() }()
 
+	if len(cfg.RetrySchedule) > 0 {
+		if _, ok := tp.(transport.Retrier); ok {
+			slog.Info("Retry ladder enabled", "schedule", cfg.RetrySchedule)
+		} else {
+			slog.Warn("ASYA_RETRY_SCHEDULE is not supported by this transport, failed messages are requeued immediately", "transport", cfg.TransportType)
+		}
+	}
+
 	// Create runtime client
 	runtimeClient := runtime.NewClient(cfg.SocketPath, cfg.Timeout)
-	slog.Info("Runtime client configured", "socket", cfg.SocketPath, "timeout", cfg.Timeout)
+	runtimeClient.SetStreamThreshold(cfg.SocketStreamThreshold)
+	slog.Info("Runtime client configured", "socket", cfg.SocketPath, "timeout", cfg.Timeout, "streamThreshold", cfg.SocketStreamThreshold)
 
 	// Initialize metrics
 	var m *metrics.Metrics
@@ -184,6 +235,16 @@ func main() {
 	// Create router
 	r := router.NewRouter(cfg, tp, runtimeClient, m)
 
+	inboundAdapter, err := adapter.New(cfg.InboundAdapter, cfg.ActorName, cfg.InboundAdapterConfig)
+	if err != nil {
+		slog.Error("Failed to create inbound adapter", "error", err)
+		os.Exit(1)
+	}
+	if inboundAdapter != nil {
+		r.SetInboundAdapter(inboundAdapter)
+		slog.Info("Inbound adapter enabled", "adapter", cfg.InboundAdapter)
+	}
+
 	// Setup graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -193,7 +254,7 @@ func main() {
 
 	go func() {
 		sig := <-sigChan
-		slog.Info("Received signal, initiating shutdown", "signal", sig)
+		slog.Info("Received signal, initiating shutdown", "signal", sig, "gracefulShutdown", cfg.GracefulShutdown)
 		cancel()
 	}()
 
@@ -207,6 +268,14 @@ func main() {
 		slog.Info("Metrics server started", "addr", cfg.MetricsAddr)
 	}
 
+	if cfg.PprofEnabled {
+		go func() {
+			if err := profiling.Start(ctx, cfg.PprofAddr); err != nil {
+				slog.Error("Profiling server error", "error", err)
+			}
+		}()
+	}
+
 	// Wait for runtime to become ready before starting message consumption
 	readyFile := filepath.Join(filepath.Dir(cfg.SocketPath), "runtime-ready")
 	maxWaitStr := os.Getenv("ASYA_RUNTIME_READY_TIMEOUT")
@@ -239,7 +308,16 @@ func main() {
 
 	// Run router
 	slog.Info("Starting message processing")
-	if err := r.Run(ctx); err != nil && err != context.Canceled {
+	routerDone := make(chan error, 1)
+	go func() { routerDone <- r.Run(ctx) }()
+
+	drained, err := waitForRouter(ctx, routerDone, cfg.GracefulShutdown)
+	if !drained {
+		slog.Error("Graceful shutdown timeout exceeded, exiting with the in-flight message unacknowledged (the queue redelivers it)",
+			"gracefulShutdown", cfg.GracefulShutdown)
+		os.Exit(1)
+	}
+	if err != nil && err != context.Canceled {
 		slog.Error("Router error", "error", err)
 		os.Exit(1)
 	}
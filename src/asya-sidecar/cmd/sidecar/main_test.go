@@ -163,3 +163,45 @@ func TestWaitForRuntime_ReadyFileButSocketNotListening(t *testing.T) {
 		t.Error("waitForRuntime() expected error when socket exists but not listening, got nil")
 	}
 }
+
+func TestWaitForRouter(t *testing.T) {
+	t.Run("router returns before shutdown", func(t *testing.T) {
+		done := make(chan error, 1)
+		done <- context.DeadlineExceeded
+
+		drained, err := waitForRouter(context.Background(), done, time.Second)
+		if !drained || err != context.DeadlineExceeded {
+			t.Errorf("waitForRouter() = %v, %v, want drained and router error", drained, err)
+		}
+	})
+
+	t.Run("in-flight message finishes within drain timeout", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		done := make(chan error, 1)
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			done <- context.Canceled
+		}()
+
+		drained, err := waitForRouter(ctx, done, time.Second)
+		if !drained || err != context.Canceled {
+			t.Errorf("waitForRouter() = %v, %v, want drained and context.Canceled", drained, err)
+		}
+	})
+
+	t.Run("drain timeout exceeded", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		start := time.Now()
+		drained, _ := waitForRouter(ctx, make(chan error), 50*time.Millisecond)
+		if drained {
+			t.Error("waitForRouter() drained, want timeout")
+		}
+		if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+			t.Errorf("waitForRouter() returned after %v, before the drain timeout", elapsed)
+		}
+	})
+}
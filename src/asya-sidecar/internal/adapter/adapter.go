@@ -0,0 +1,306 @@
+package adapter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/deliveryhero/asya/asya-sidecar/pkg/envelopes"
+)
+
+// Built-in inbound adapter names (ASYA_INBOUND_ADAPTER)
+const (
+	NameNone        = "none"
+	NameGzip        = "gzip"
+	NameRaw         = "raw"
+	NameCloudEvents = "cloudevents"
+	NameTransform   = "transform"
+)
+
+// Adapter converts a message body produced outside asya into asya envelope JSON.
+// Adapters run before envelope parsing, so everything downstream (runtime call,
+// routing, error queue) sees the converted envelope.
+type Adapter interface {
+	Adapt(body []byte) ([]byte, error)
+}
+
+// Options configures the built-in adapters (ASYA_INBOUND_ADAPTER_CONFIG, JSON)
+type Options struct {
+	// Route lists the actors after this one for adapted messages.
+	// Empty means the message goes to happy-end after this actor.
+	Route []string `json:"route,omitempty"`
+
+	// Transform adapter only: dotted paths into the foreign message
+	IDField      string `json:"id_field,omitempty"`      // Envelope ID (generated when missing)
+	PayloadField string `json:"payload_field,omitempty"` // Payload (whole message when empty)
+	ActorsField  string `json:"actors_field,omitempty"`  // Full route actors (overrides Route)
+}
+
+// New creates the named inbound adapter for the given actor.
+// Returns nil for an empty name or "none" (messages are already asya envelopes).
+func New(name, actorName, optionsJSON string) (Adapter, error) {
+	var opts Options
+	if optionsJSON != "" {
+		if err := json.Unmarshal([]byte(optionsJSON), &opts); err != nil {
+			return nil, fmt.Errorf("invalid inbound adapter config: %w", err)
+		}
+	}
+
+	base := baseAdapter{actorName: actorName, opts: opts}
+
+	switch strings.ToLower(name) {
+	case "", NameNone:
+		return nil, nil
+	case NameGzip:
+		return &gzipAdapter{}, nil
+	case NameRaw:
+		return &rawAdapter{base}, nil
+	case NameCloudEvents:
+		return &cloudEventsAdapter{base}, nil
+	case NameTransform:
+		if opts.IDField == "" && opts.PayloadField == "" && opts.ActorsField == "" {
+			return nil, fmt.Errorf("transform adapter requires at least one of id_field, payload_field, actors_field")
+		}
+		return &transformAdapter{base}, nil
+	default:
+		return nil, fmt.Errorf("unknown inbound adapter %q (supported: %s, %s, %s, %s, %s)",
+			name, NameNone, NameGzip, NameRaw, NameCloudEvents, NameTransform)
+	}
+}
+
+// gzipAdapter decompresses gzip bodies that already contain asya envelopes
+type gzipAdapter struct{}
+
+func (a *gzipAdapter) Adapt(body []byte) ([]byte, error) {
+	return decompress(body)
+}
+
+// rawAdapter wraps the whole message as the payload of a new envelope
+type rawAdapter struct {
+	baseAdapter
+}
+
+func (a *rawAdapter) Adapt(body []byte) ([]byte, error) {
+	body, err := decompress(body)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := json.RawMessage(body)
+	if !json.Valid(body) {
+		// Non-JSON bodies are passed to the runtime as a JSON string
+		encoded, err := json.Marshal(string(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode raw payload: %w", err)
+		}
+		payload = encoded
+	}
+
+	return a.envelope("", nil, payload)
+}
+
+// cloudEventsDefaultType is the CloudEvent type of gateway envelopes not created by a tool
+const cloudEventsDefaultType = "sh.asya.envelope"
+
+// cloudEventsAdapter maps structured-mode CloudEvents (id, data) to envelopes.
+// Events published by the gateway (ASYA_MESSAGE_FORMAT=cloudevents) also carry the route and
+// the other envelope fields as "asya*" extension attributes, and their type is the tool name.
+// Asya envelopes routed from a previous actor are passed through unchanged.
+type cloudEventsAdapter struct {
+	baseAdapter
+}
+
+func (a *cloudEventsAdapter) Adapt(body []byte) ([]byte, error) {
+	body, err := decompress(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var event struct {
+		SpecVersion string          `json:"specversion"`
+		ID          string          `json:"id"`
+		Type        string          `json:"type"`
+		Data        json.RawMessage `json:"data"`
+		AsyaRoute   json.RawMessage `json:"route"` // Set for asya envelopes, not CloudEvents
+
+		Route         string `json:"asyaroute"`
+		RouteCurrent  *int   `json:"asyaroutecurrent"`
+		RouteMetadata string `json:"asyaroutemetadata"`
+		ParentID      string `json:"asyaparentid"`
+		BranchIndex   int    `json:"asyabranchindex"`
+		ReplyTo       string `json:"asyareplyto"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("failed to parse CloudEvent: %w", err)
+	}
+
+	if event.SpecVersion == "" && len(event.AsyaRoute) > 0 {
+		return body, nil
+	}
+	if event.Route == "" {
+		return a.envelope(event.ID, nil, event.Data)
+	}
+
+	envelope := envelopes.Envelope{
+		ID:          event.ID,
+		BranchIndex: event.BranchIndex,
+		ReplyTo:     event.ReplyTo,
+		Route:       envelopes.Route{Actors: strings.Split(event.Route, ",")},
+		Payload:     event.Data,
+	}
+	if envelope.ID == "" {
+		envelope.ID = newID()
+	}
+	if event.Type != cloudEventsDefaultType {
+		envelope.Tool = event.Type
+	}
+	if event.ParentID != "" {
+		envelope.ParentID = &event.ParentID
+	}
+	if event.RouteCurrent != nil {
+		envelope.Route.Current = *event.RouteCurrent
+	} else if i := slices.Index(envelope.Route.Actors, a.actorName); i >= 0 {
+		envelope.Route.Current = i
+	}
+	if event.RouteMetadata != "" {
+		if err := json.Unmarshal([]byte(event.RouteMetadata), &envelope.Route.Metadata); err != nil {
+			return nil, fmt.Errorf("invalid asyaroutemetadata: %w", err)
+		}
+	}
+	if len(envelope.Payload) == 0 {
+		envelope.Payload = json.RawMessage("null")
+	}
+
+	return json.Marshal(envelope)
+}
+
+// transformAdapter maps fields of an arbitrary JSON message using dotted paths
+type transformAdapter struct {
+	baseAdapter
+}
+
+func (a *transformAdapter) Adapt(body []byte) ([]byte, error) {
+	body, err := decompress(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse message: %w", err)
+	}
+
+	var id string
+	if a.opts.IDField != "" {
+		if value, ok := lookup(doc, a.opts.IDField); ok {
+			id = fmt.Sprint(value)
+		}
+	}
+
+	var actors []string
+	if a.opts.ActorsField != "" {
+		value, _ := lookup(doc, a.opts.ActorsField)
+		if list, ok := value.([]any); ok {
+			for _, actor := range list {
+				actors = append(actors, fmt.Sprint(actor))
+			}
+		}
+	}
+
+	payload := json.RawMessage(body)
+	if a.opts.PayloadField != "" {
+		value, ok := lookup(doc, a.opts.PayloadField)
+		if !ok {
+			return nil, fmt.Errorf("payload field %q not found", a.opts.PayloadField)
+		}
+		if payload, err = json.Marshal(value); err != nil {
+			return nil, fmt.Errorf("failed to encode payload: %w", err)
+		}
+	}
+
+	return a.envelope(id, actors, payload)
+}
+
+// baseAdapter builds envelopes addressed to this actor
+type baseAdapter struct {
+	actorName string
+	opts      Options
+}
+
+// envelope marshals an asya envelope for an adapted message.
+// A missing ID is generated; a missing route starts at this actor followed by opts.Route.
+func (a baseAdapter) envelope(id string, actors []string, payload json.RawMessage) ([]byte, error) {
+	if id == "" {
+		id = newID()
+	}
+	if len(actors) == 0 {
+		actors = append([]string{a.actorName}, a.opts.Route...)
+	}
+	if len(payload) == 0 {
+		payload = json.RawMessage("null")
+	}
+
+	current := 0
+	for i, actor := range actors {
+		if actor == a.actorName {
+			current = i
+			break
+		}
+	}
+
+	return json.Marshal(envelopes.Envelope{
+		ID:      id,
+		Route:   envelopes.Route{Actors: actors, Current: current},
+		Payload: payload,
+	})
+}
+
+// lookup resolves a dotted path ("meta.id") in a decoded JSON document
+func lookup(doc any, path string) (any, bool) {
+	value := doc
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// gzipMagic is the header of gzip-compressed data
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decompress inflates gzip bodies and returns other bodies unchanged
+func decompress(body []byte) ([]byte, error) {
+	if !bytes.HasPrefix(body, gzipMagic) {
+		return body, nil
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open gzip body: %w", err)
+	}
+	defer func() { _ = reader.Close() }()
+
+	decompressed, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress gzip body: %w", err)
+	}
+	return decompressed, nil
+}
+
+// newID generates a random envelope ID for messages that do not carry one
+func newID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
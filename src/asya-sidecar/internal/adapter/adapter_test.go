@@ -0,0 +1,250 @@
+package adapter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"testing"
+
+	"github.com/deliveryhero/asya/asya-sidecar/pkg/envelopes"
+)
+
+func gzipBytes(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(data)); err != nil {
+		t.Fatalf("Failed to gzip data: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close gzip writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		adapter     string
+		options     string
+		wantNil     bool
+		expectError bool
+	}{
+		{name: "empty name disables adapter", adapter: "", wantNil: true},
+		{name: "none disables adapter", adapter: "none", wantNil: true},
+		{name: "gzip", adapter: "gzip"},
+		{name: "raw", adapter: "raw"},
+		{name: "cloudevents case insensitive", adapter: "CloudEvents"},
+		{name: "transform with fields", adapter: "transform", options: `{"payload_field":"data"}`},
+		{name: "transform without fields", adapter: "transform", expectError: true},
+		{name: "unknown adapter", adapter: "avro", expectError: true},
+		{name: "invalid options", adapter: "raw", options: `{`, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := New(tt.adapter, "test-actor", tt.options)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("New() error = %v, expectError %v", err, tt.expectError)
+			}
+			if !tt.expectError && (a == nil) != tt.wantNil {
+				t.Errorf("New() adapter = %v, wantNil %v", a, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestAdapt(t *testing.T) {
+	tests := []struct {
+		name        string
+		adapter     string
+		options     string
+		body        []byte
+		wantID      string
+		wantActors  []string
+		wantCurrent int
+		wantPayload string
+		expectError bool
+	}{
+		{
+			name:        "gzip envelope",
+			adapter:     "gzip",
+			body:        gzipBytes(t, `{"id":"env-1","route":{"actors":["test-actor"],"current":0},"payload":{"a":1}}`),
+			wantID:      "env-1",
+			wantActors:  []string{"test-actor"},
+			wantPayload: `{"a":1}`,
+		},
+		{
+			name:        "raw json body with route",
+			adapter:     "raw",
+			options:     `{"route":["next-actor"]}`,
+			body:        []byte(`{"order":42}`),
+			wantActors:  []string{"test-actor", "next-actor"},
+			wantPayload: `{"order":42}`,
+		},
+		{
+			name:        "raw text body",
+			adapter:     "raw",
+			body:        []byte("plain text"),
+			wantActors:  []string{"test-actor"},
+			wantPayload: `"plain text"`,
+		},
+		{
+			name:        "cloudevent",
+			adapter:     "cloudevents",
+			body:        []byte(`{"specversion":"1.0","id":"evt-7","type":"order.created","data":{"order":7}}`),
+			wantID:      "evt-7",
+			wantActors:  []string{"test-actor"},
+			wantPayload: `{"order":7}`,
+		},
+		{
+			name:        "compressed cloudevent",
+			adapter:     "cloudevents",
+			body:        gzipBytes(t, `{"id":"evt-8","data":[1,2]}`),
+			wantID:      "evt-8",
+			wantActors:  []string{"test-actor"},
+			wantPayload: `[1,2]`,
+		},
+		{
+			name:        "gateway cloudevent",
+			adapter:     "cloudevents",
+			body:        []byte(`{"specversion":"1.0","id":"env-3","type":"summarize","data":{"text":"hi"},"asyaroute":"prep,test-actor,post","asyaroutecurrent":1}`),
+			wantID:      "env-3",
+			wantActors:  []string{"prep", "test-actor", "post"},
+			wantCurrent: 1,
+			wantPayload: `{"text":"hi"}`,
+		},
+		{
+			name:        "envelope from previous actor passes cloudevents adapter",
+			adapter:     "cloudevents",
+			body:        []byte(`{"id":"env-4","route":{"actors":["prep","test-actor"],"current":1},"payload":{"a":1}}`),
+			wantID:      "env-4",
+			wantActors:  []string{"prep", "test-actor"},
+			wantCurrent: 1,
+			wantPayload: `{"a":1}`,
+		},
+		{
+			name:        "cloudevent invalid route metadata",
+			adapter:     "cloudevents",
+			body:        []byte(`{"specversion":"1.0","id":"env-5","asyaroute":"test-actor","asyaroutemetadata":"{"}`),
+			expectError: true,
+		},
+		{
+			name:        "transform nested fields",
+			adapter:     "transform",
+			options:     `{"id_field":"meta.message_id","payload_field":"body","actors_field":"meta.steps"}`,
+			body:        []byte(`{"meta":{"message_id":"msg-9","steps":["prep","test-actor","post"]},"body":{"text":"hi"}}`),
+			wantID:      "msg-9",
+			wantActors:  []string{"prep", "test-actor", "post"},
+			wantCurrent: 1,
+			wantPayload: `{"text":"hi"}`,
+		},
+		{
+			name:        "transform missing payload field",
+			adapter:     "transform",
+			options:     `{"payload_field":"body"}`,
+			body:        []byte(`{"other":1}`),
+			expectError: true,
+		},
+		{
+			name:        "cloudevent invalid json",
+			adapter:     "cloudevents",
+			body:        []byte(`not json`),
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := New(tt.adapter, "test-actor", tt.options)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			out, err := a.Adapt(tt.body)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("Adapt() error = %v, expectError %v", err, tt.expectError)
+			}
+			if tt.expectError {
+				return
+			}
+
+			var envelope envelopes.Envelope
+			if err := json.Unmarshal(out, &envelope); err != nil {
+				t.Fatalf("Adapted body is not an envelope: %v", err)
+			}
+
+			if tt.wantID != "" && envelope.ID != tt.wantID {
+				t.Errorf("ID = %q, want %q", envelope.ID, tt.wantID)
+			}
+			if envelope.ID == "" {
+				t.Error("ID should be generated when missing")
+			}
+			if len(envelope.Route.Actors) != len(tt.wantActors) {
+				t.Fatalf("Actors = %v, want %v", envelope.Route.Actors, tt.wantActors)
+			}
+			for i := range tt.wantActors {
+				if envelope.Route.Actors[i] != tt.wantActors[i] {
+					t.Errorf("Actors = %v, want %v", envelope.Route.Actors, tt.wantActors)
+					break
+				}
+			}
+			if envelope.Route.Current != tt.wantCurrent {
+				t.Errorf("Current = %d, want %d", envelope.Route.Current, tt.wantCurrent)
+			}
+			if string(envelope.Payload) != tt.wantPayload {
+				t.Errorf("Payload = %s, want %s", envelope.Payload, tt.wantPayload)
+			}
+		})
+	}
+}
+
+func TestCloudEventsAdapter_GatewayEvent(t *testing.T) {
+	a, err := New("cloudevents", "test-actor", "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	out, err := a.Adapt([]byte(`{
+		"specversion": "1.0", "id": "env-1", "source": "/asya/gateway", "type": "summarize",
+		"datacontenttype": "application/json", "data": {"text": "hi"},
+		"asyaroute": "test-actor,post", "asyaroutecurrent": 0, "asyaroutemetadata": "{\"lang\":\"en\"}",
+		"asyaparentid": "env-0", "asyabranchindex": 2, "asyareplyto": "asya-reply-1"
+	}`))
+	if err != nil {
+		t.Fatalf("Adapt() error = %v", err)
+	}
+
+	var envelope envelopes.Envelope
+	if err := json.Unmarshal(out, &envelope); err != nil {
+		t.Fatalf("Adapted body is not an envelope: %v", err)
+	}
+	if envelope.Tool != "summarize" {
+		t.Errorf("Tool = %q, want summarize", envelope.Tool)
+	}
+	if envelope.ReplyTo != "asya-reply-1" {
+		t.Errorf("ReplyTo = %q, want asya-reply-1", envelope.ReplyTo)
+	}
+	if envelope.ParentID == nil || *envelope.ParentID != "env-0" || envelope.BranchIndex != 2 {
+		t.Errorf("ParentID = %v, BranchIndex = %d, want env-0 and 2", envelope.ParentID, envelope.BranchIndex)
+	}
+	if envelope.Route.Metadata["lang"] != "en" {
+		t.Errorf("Route metadata = %v, want lang=en", envelope.Route.Metadata)
+	}
+
+	// The default type of envelopes without tool does not become a tool name
+	out, err = a.Adapt([]byte(`{"specversion":"1.0","id":"env-2","type":"sh.asya.envelope","asyaroute":"test-actor"}`))
+	if err != nil {
+		t.Fatalf("Adapt() error = %v", err)
+	}
+	envelope = envelopes.Envelope{}
+	if err := json.Unmarshal(out, &envelope); err != nil {
+		t.Fatalf("Adapted body is not an envelope: %v", err)
+	}
+	if envelope.Tool != "" {
+		t.Errorf("Tool = %q, want empty", envelope.Tool)
+	}
+	if string(envelope.Payload) != "null" {
+		t.Errorf("Payload = %s, want null", envelope.Payload)
+	}
+}
@@ -7,6 +7,9 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/deliveryhero/asya/asya-sidecar/internal/profiling"
+	"github.com/deliveryhero/asya/asya-sidecar/internal/progress"
 )
 
 type Config struct {
@@ -16,7 +19,17 @@ type Config struct {
 	// RabbitMQ configuration
 	RabbitMQURL      string
 	RabbitMQExchange string
-	RabbitMQPrefetch int
+	RabbitMQPrefetch int  // Unacked deliveries per consumer, defaults to Concurrency
+	RabbitMQPublish  int  // Channels reserved for publishing, separate from the consume channel
+	AutoAck          bool // Consume with auto-ack: at-most-once delivery, no per-message ack/nack
+
+	// NACK by republishing with an updated x-asya-delivery-count header instead of requeueing,
+	// so redeliveries are counted exactly without dead-lettering (default false: requeued in
+	// place, and only quorum queues then report an exact count)
+	RabbitMQRepublishOnNack bool
+
+	// How long startup retries connecting to RabbitMQ before the sidecar exits
+	RabbitMQConnectTimeout time.Duration
 
 	// SQS configuration
 	SQSBaseURL           string
@@ -24,10 +37,23 @@ type Config struct {
 	SQSVisibilityTimeout int32 // seconds
 	SQSWaitTimeSeconds   int32
 
+	// Fraction of the visibility timeout after which the visibility of a message in processing
+	// is extended again, so long runs are not redelivered (0 disables)
+	SQSVisibilityExtension float64
+
+	// Envelopes processed at once across all input queues
+	Concurrency int
+
 	// Runtime communication
 	SocketPath string
 	Timeout    time.Duration
 
+	// Upper bound for per-message timeout overrides (defaults to Timeout)
+	MaxProcessingTimeout time.Duration
+
+	// Runtime requests of at least this many bytes are streamed in chunks (0 disables)
+	SocketStreamThreshold int
+
 	// End queues
 	HappyEndQueue string
 	ErrorEndQueue string
@@ -38,15 +64,60 @@ type Config struct {
 	// envelopes but don't produce new ones to route.
 	IsEndActor bool
 
+	// Route validation
+	// Envelopes whose current route step is not this actor are sent to the error queue.
+	// When true (ASYA_STRICT_ROUTE_VALIDATION=false), the mismatch is only logged and the envelope is processed.
+	AllowRouteMismatch bool
+
 	// Gateway integration for progress reporting
 	GatewayURL string
 	ActorName  string
 
+	// Skip the intermediate processing update of each message; the received and completed
+	// updates (status transitions), partial results, fanout envelopes and final status are still sent
+	ProgressDisabled bool
+
+	// Progress batching: updates are sent in batches of up to ProgressBatchSize
+	// every ProgressBatchInterval (0 sends every update right away)
+	ProgressBatchInterval time.Duration
+	ProgressBatchSize     int
+
+	// Input queues to consume from (one consumer each)
+	// Empty means the actor's own queue
+	QueueNames []string
+
+	// Inbound adapter for messages from foreign producers (empty means asya envelopes)
+	InboundAdapter       string
+	InboundAdapterConfig string // JSON options for the adapter
+
+	// Delays before each redelivery of a message whose processing failed (retry ladder).
+	// Once exhausted the message is dead-lettered. Empty means immediate requeue.
+	RetrySchedule []time.Duration
+
+	// Deliveries after which a failing message is dead-lettered instead of NACKed (0 is unlimited)
+	MaxDeliveries int
+
+	// Wait before a NACK without a retry ladder, doubling with each delivery up to NackMaxDelay
+	// (default 0 NACKs right away). The processing slot is released while waiting.
+	NackDelay    time.Duration
+	NackMaxDelay time.Duration
+
+	// Idle shutdown for scale-to-zero
+	// When > 0, consumers pause after this long without messages (0 disables)
+	IdleTimeout time.Duration
+
+	// Time to finish the in-flight message after SIGTERM before exiting anyway (0 waits indefinitely)
+	GracefulShutdown time.Duration
+
 	// Metrics configuration
 	MetricsEnabled   bool
 	MetricsAddr      string
 	MetricsNamespace string
 	CustomMetrics    []CustomMetricConfig
+
+	// Profiling (net/http/pprof) on a separate listener, loopback only by default
+	PprofEnabled bool
+	PprofAddr    string
 }
 
 // CustomMetricConfig defines configuration for a custom metric
@@ -66,7 +137,12 @@ func LoadFromEnv() (*Config, error) {
 		// RabbitMQ configuration
 		RabbitMQURL:      buildRabbitMQURL(),
 		RabbitMQExchange: getEnv("ASYA_RABBITMQ_EXCHANGE", "asya"),
-		RabbitMQPrefetch: getEnvInt("ASYA_RABBITMQ_PREFETCH", 1),
+		RabbitMQPrefetch: getEnvInt("ASYA_RABBITMQ_PREFETCH", 0), // Set below
+		RabbitMQPublish:  getEnvInt("ASYA_RABBITMQ_PUBLISH_CHANNELS", 2),
+		AutoAck:          getEnvBool("ASYA_AUTO_ACK", false),
+
+		RabbitMQRepublishOnNack: getEnvBool("ASYA_RABBITMQ_REPUBLISH_ON_NACK", false),
+		RabbitMQConnectTimeout:  getEnvDuration("ASYA_RABBITMQ_CONNECT_TIMEOUT", 2*time.Minute),
 
 		// SQS configuration
 		SQSBaseURL:           getEnv("ASYA_SQS_ENDPOINT", ""),
@@ -74,30 +150,79 @@ func LoadFromEnv() (*Config, error) {
 		SQSVisibilityTimeout: getEnvInt32("ASYA_SQS_VISIBILITY_TIMEOUT", 0),
 		SQSWaitTimeSeconds:   getEnvInt32("ASYA_SQS_WAIT_TIME_SECONDS", 20),
 
+		SQSVisibilityExtension: getEnvFloat("ASYA_SQS_VISIBILITY_EXTENSION", 0.5),
+
+		Concurrency: getEnvInt("ASYA_CONCURRENCY", 1),
+
 		// Runtime communication - hard-coded, managed by operator
 		// ASYA_SOCKET_DIR is for internal testing only - DO NOT set in production
 		SocketPath: "", // Will be set below
 		Timeout:    getEnvDuration("ASYA_RUNTIME_TIMEOUT", 5*time.Minute),
 
+		MaxProcessingTimeout: getEnvDuration("ASYA_MAX_PROCESSING_TIMEOUT", 0),
+
+		SocketStreamThreshold: getEnvInt("ASYA_SOCKET_STREAM_THRESHOLD", 0),
+
 		// End queues
 		HappyEndQueue: getEnv("ASYA_ACTOR_HAPPY_END", "happy-end"),
 		ErrorEndQueue: getEnv("ASYA_ACTOR_ERROR_END", "error-end"),
 		IsEndActor:    getEnvBool("ASYA_IS_END_ACTOR", false),
 
+		AllowRouteMismatch: !getEnvBool("ASYA_STRICT_ROUTE_VALIDATION", true),
+
 		// Progress reporting
 		GatewayURL: getEnv("ASYA_GATEWAY_URL", ""),
 		ActorName:  getEnv("ASYA_ACTOR_NAME", ""),
+		QueueNames: getEnvList("ASYA_QUEUE_NAME"),
+
+		ProgressDisabled:      getEnvBool("ASYA_DISABLE_PROGRESS", false),
+		ProgressBatchInterval: getEnvDuration("ASYA_PROGRESS_BATCH_INTERVAL", 0),
+		ProgressBatchSize:     getEnvInt("ASYA_PROGRESS_BATCH_SIZE", 100),
+
+		// Inbound adapter
+		InboundAdapter:       getEnv("ASYA_INBOUND_ADAPTER", ""),
+		InboundAdapterConfig: getEnv("ASYA_INBOUND_ADAPTER_CONFIG", ""),
+
+		// Redelivery of failed messages
+		MaxDeliveries: getEnvInt("ASYA_MAX_DELIVERIES", 0),
+		NackDelay:     getEnvDuration("ASYA_NACK_DELAY", 0),
+		NackMaxDelay:  getEnvDuration("ASYA_NACK_MAX_DELAY", 30*time.Second),
+
+		// Idle shutdown
+		IdleTimeout: getEnvDuration("ASYA_IDLE_TIMEOUT", 0),
+
+		// Graceful shutdown, ends before the pod's default termination grace period of 30s
+		GracefulShutdown: time.Duration(getEnvInt("ASYA_GRACEFUL_SHUTDOWN", 25)) * time.Second,
 
 		// Metrics defaults
 		MetricsEnabled:   getEnvBool("ASYA_METRICS_ENABLED", true),
 		MetricsAddr:      getEnv("ASYA_METRICS_ADDR", ":8080"),
 		MetricsNamespace: getEnv("ASYA_METRICS_NAMESPACE", "asya_actor"),
+
+		// Profiling defaults
+		PprofEnabled: getEnvBool("ASYA_ENABLE_PPROF", false),
+		PprofAddr:    getEnv("ASYA_PPROF_ADDR", profiling.DefaultAddr),
 	}
 
 	// Set socket path (allow ASYA_SOCKET_DIR override for testing only)
 	socketDir := getEnv("ASYA_SOCKET_DIR", "/var/run/asya")
 	cfg.SocketPath = socketDir + "/asya-runtime.sock"
 
+	// Each consumer needs a delivery per worker, more only hoard messages in this pod
+	if cfg.RabbitMQPrefetch == 0 {
+		cfg.RabbitMQPrefetch = cfg.Concurrency
+	}
+
+	if cfg.MaxProcessingTimeout < cfg.Timeout {
+		cfg.MaxProcessingTimeout = cfg.Timeout
+	}
+
+	retrySchedule, err := parseRetrySchedule(getEnv("ASYA_RETRY_SCHEDULE", ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ASYA_RETRY_SCHEDULE: %w", err)
+	}
+	cfg.RetrySchedule = retrySchedule
+
 	// Load custom metrics configuration
 	if customMetricsJSON := getEnv("ASYA_CUSTOM_METRICS", ""); customMetricsJSON != "" {
 		var customMetrics []CustomMetricConfig
@@ -111,10 +236,61 @@ func LoadFromEnv() (*Config, error) {
 	if cfg.ActorName == "" {
 		return nil, fmt.Errorf("ASYA_ACTOR_NAME is required")
 	}
+	if cfg.Concurrency < 1 {
+		return nil, fmt.Errorf("ASYA_CONCURRENCY must be at least 1, got %d", cfg.Concurrency)
+	}
+	if cfg.TransportType == "rabbitmq" && cfg.RabbitMQPrefetch < cfg.Concurrency {
+		return nil, fmt.Errorf("ASYA_RABBITMQ_PREFETCH (%d) must be at least ASYA_CONCURRENCY (%d), otherwise workers starve", cfg.RabbitMQPrefetch, cfg.Concurrency)
+	}
+	if cfg.RabbitMQPublish < 1 {
+		return nil, fmt.Errorf("ASYA_RABBITMQ_PUBLISH_CHANNELS must be at least 1, got %d", cfg.RabbitMQPublish)
+	}
+	if cfg.SQSVisibilityExtension < 0 || cfg.SQSVisibilityExtension >= 1 {
+		return nil, fmt.Errorf("ASYA_SQS_VISIBILITY_EXTENSION must be between 0 (disabled) and 1 (exclusive), got %g", cfg.SQSVisibilityExtension)
+	}
+	if cfg.SocketStreamThreshold < 0 {
+		return nil, fmt.Errorf("ASYA_SOCKET_STREAM_THRESHOLD must not be negative, got %d", cfg.SocketStreamThreshold)
+	}
+	if cfg.MaxDeliveries < 0 {
+		return nil, fmt.Errorf("ASYA_MAX_DELIVERIES must not be negative, got %d", cfg.MaxDeliveries)
+	}
+	if cfg.ProgressBatchSize < 1 || cfg.ProgressBatchSize > progress.MaxBatchSize {
+		return nil, fmt.Errorf("ASYA_PROGRESS_BATCH_SIZE must be between 1 and %d, got %d", progress.MaxBatchSize, cfg.ProgressBatchSize)
+	}
 
 	return cfg, nil
 }
 
+// parseRetrySchedule parses a comma-separated list of positive durations (e.g. "10s,1m,5m")
+func parseRetrySchedule(value string) ([]time.Duration, error) {
+	var schedule []time.Duration
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		delay, err := time.ParseDuration(item)
+		if err != nil {
+			return nil, err
+		}
+		if delay < time.Millisecond {
+			return nil, fmt.Errorf("retry delay %q must be at least 1ms", item)
+		}
+		schedule = append(schedule, delay)
+	}
+	return schedule, nil
+}
+
+// getEnvList parses a comma-separated environment variable, skipping empty items
+func getEnvList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -140,6 +316,15 @@ func getEnvInt32(key string, defaultValue int32) int32 {
 	return defaultValue
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
 func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if d, err := time.ParseDuration(value); err == nil {
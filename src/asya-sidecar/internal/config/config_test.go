@@ -48,6 +48,218 @@ func TestLoadFromEnv(t *testing.T) {
 			env:         map[string]string{},
 			expectError: true,
 		},
+		{
+			name: "max processing timeout",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME":             "test-actor",
+				"ASYA_RUNTIME_TIMEOUT":        "1m",
+				"ASYA_MAX_PROCESSING_TIMEOUT": "30m",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.MaxProcessingTimeout != 30*time.Minute {
+					t.Errorf("MaxProcessingTimeout = %v, want 30m", cfg.MaxProcessingTimeout)
+				}
+			},
+		},
+		{
+			name: "max processing timeout defaults to runtime timeout",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME":      "test-actor",
+				"ASYA_RUNTIME_TIMEOUT": "2m",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.MaxProcessingTimeout != 2*time.Minute {
+					t.Errorf("MaxProcessingTimeout = %v, want 2m", cfg.MaxProcessingTimeout)
+				}
+			},
+		},
+		{
+			name: "inbound adapter",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME":             "test-actor",
+				"ASYA_INBOUND_ADAPTER":        "cloudevents",
+				"ASYA_INBOUND_ADAPTER_CONFIG": `{"route":["next"]}`,
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.InboundAdapter != "cloudevents" {
+					t.Errorf("InboundAdapter = %q, want cloudevents", cfg.InboundAdapter)
+				}
+				if cfg.InboundAdapterConfig != `{"route":["next"]}` {
+					t.Errorf("InboundAdapterConfig = %q", cfg.InboundAdapterConfig)
+				}
+			},
+		},
+		{
+			name: "idle timeout",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME":   "test-actor",
+				"ASYA_IDLE_TIMEOUT": "2m",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.IdleTimeout != 2*time.Minute {
+					t.Errorf("IdleTimeout = %v, want 2m", cfg.IdleTimeout)
+				}
+			},
+		},
+		{
+			name: "graceful shutdown",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME":        "test-actor",
+				"ASYA_GRACEFUL_SHUTDOWN": "120",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.GracefulShutdown != 2*time.Minute {
+					t.Errorf("GracefulShutdown = %v, want 2m", cfg.GracefulShutdown)
+				}
+			},
+		},
+		{
+			name: "graceful shutdown defaults to 25s",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME": "test-actor",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				// Below the default termination grace period of 30s
+				if cfg.GracefulShutdown != 25*time.Second {
+					t.Errorf("GracefulShutdown = %v, want 25s", cfg.GracefulShutdown)
+				}
+			},
+		},
+		{
+			name: "multiple input queues",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME": "test-actor",
+				"ASYA_QUEUE_NAME": "asya-source-a, asya-source-b,,",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if len(cfg.QueueNames) != 2 || cfg.QueueNames[0] != "asya-source-a" || cfg.QueueNames[1] != "asya-source-b" {
+					t.Errorf("QueueNames = %v, want [asya-source-a asya-source-b]", cfg.QueueNames)
+				}
+			},
+		},
+		{
+			name: "auto-ack",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME": "test-actor",
+				"ASYA_AUTO_ACK":   "true",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if !cfg.AutoAck {
+					t.Error("AutoAck should be true")
+				}
+			},
+		},
+		{
+			name: "republish on nack",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME":                 "test-actor",
+				"ASYA_RABBITMQ_REPUBLISH_ON_NACK": "true",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if !cfg.RabbitMQRepublishOnNack {
+					t.Error("RabbitMQRepublishOnNack should be true")
+				}
+			},
+		},
+		{
+			name: "republish on nack disabled",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME":                 "test-actor",
+				"ASYA_RABBITMQ_REPUBLISH_ON_NACK": "false",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.RabbitMQRepublishOnNack {
+					t.Error("RabbitMQRepublishOnNack should be false")
+				}
+			},
+		},
+		{
+			name: "max deliveries and nack backoff",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME":     "test-actor",
+				"ASYA_MAX_DELIVERIES": "5",
+				"ASYA_NACK_DELAY":     "2s",
+				"ASYA_NACK_MAX_DELAY": "1m",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.MaxDeliveries != 5 {
+					t.Errorf("MaxDeliveries = %d, want 5", cfg.MaxDeliveries)
+				}
+				if cfg.NackDelay != 2*time.Second {
+					t.Errorf("NackDelay = %v, want 2s", cfg.NackDelay)
+				}
+				if cfg.NackMaxDelay != time.Minute {
+					t.Errorf("NackMaxDelay = %v, want 1m", cfg.NackMaxDelay)
+				}
+			},
+		},
+		{
+			name: "negative max deliveries",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME":     "test-actor",
+				"ASYA_MAX_DELIVERIES": "-1",
+			},
+			expectError: true,
+		},
+		{
+			name: "rabbitmq connect timeout",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME":               "test-actor",
+				"ASYA_RABBITMQ_CONNECT_TIMEOUT": "5m",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.RabbitMQConnectTimeout != 5*time.Minute {
+					t.Errorf("RabbitMQConnectTimeout = %v, want 5m", cfg.RabbitMQConnectTimeout)
+				}
+			},
+		},
+		{
+			name: "retry schedule",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME":     "test-actor",
+				"ASYA_RETRY_SCHEDULE": "10s, 1m,5m,",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				want := []time.Duration{10 * time.Second, time.Minute, 5 * time.Minute}
+				if len(cfg.RetrySchedule) != len(want) {
+					t.Fatalf("RetrySchedule = %v, want %v", cfg.RetrySchedule, want)
+				}
+				for i := range want {
+					if cfg.RetrySchedule[i] != want[i] {
+						t.Errorf("RetrySchedule[%d] = %v, want %v", i, cfg.RetrySchedule[i], want[i])
+					}
+				}
+			},
+		},
+		{
+			name: "invalid retry schedule",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME":     "test-actor",
+				"ASYA_RETRY_SCHEDULE": "10s,soon",
+			},
+			expectError: true,
+		},
+		{
+			name: "zero retry delay",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME":     "test-actor",
+				"ASYA_RETRY_SCHEDULE": "0s",
+			},
+			expectError: true,
+		},
 		{
 			name: "default values",
 			env: map[string]string{
@@ -67,6 +279,31 @@ func TestLoadFromEnv(t *testing.T) {
 				if cfg.ErrorEndQueue != "error-end" {
 					t.Errorf("Default ErrorEndQueue = %v, want error-end", cfg.ErrorEndQueue)
 				}
+				if cfg.AllowRouteMismatch {
+					t.Error("AllowRouteMismatch should default to false")
+				}
+				if cfg.RabbitMQRepublishOnNack {
+					t.Error("RabbitMQRepublishOnNack should default to false")
+				}
+				if cfg.MaxDeliveries != 0 {
+					t.Errorf("Default MaxDeliveries = %d, want 0", cfg.MaxDeliveries)
+				}
+				if cfg.NackDelay != 0 || cfg.NackMaxDelay != 30*time.Second {
+					t.Errorf("Default NackDelay/NackMaxDelay = %v/%v, want 0/30s", cfg.NackDelay, cfg.NackMaxDelay)
+				}
+			},
+		},
+		{
+			name: "log-only route validation",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME":              "test-actor",
+				"ASYA_STRICT_ROUTE_VALIDATION": "false",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if !cfg.AllowRouteMismatch {
+					t.Error("AllowRouteMismatch should be true")
+				}
 			},
 		},
 		{
@@ -184,7 +421,145 @@ func TestLoadFromEnv(t *testing.T) {
 				if cfg.RabbitMQPrefetch != 10 {
 					t.Errorf("RabbitMQPrefetch = %v, want 10", cfg.RabbitMQPrefetch)
 				}
+				if cfg.RabbitMQPublish != 2 {
+					t.Errorf("RabbitMQPublish = %v, want 2", cfg.RabbitMQPublish)
+				}
+			},
+		},
+		{
+			name: "prefetch defaults to concurrency",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME":  "test-actor",
+				"ASYA_CONCURRENCY": "4",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.Concurrency != 4 {
+					t.Errorf("Concurrency = %v, want 4", cfg.Concurrency)
+				}
+				if cfg.RabbitMQPrefetch != 4 {
+					t.Errorf("RabbitMQPrefetch = %v, want 4", cfg.RabbitMQPrefetch)
+				}
+			},
+		},
+		{
+			name: "prefetch below concurrency",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME":        "test-actor",
+				"ASYA_CONCURRENCY":       "4",
+				"ASYA_RABBITMQ_PREFETCH": "2",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid concurrency",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME":  "test-actor",
+				"ASYA_CONCURRENCY": "0",
+			},
+			expectError: true,
+		},
+		{
+			name: "RabbitMQ publish channels",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME":                "test-actor",
+				"ASYA_RABBITMQ_PUBLISH_CHANNELS": "4",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.RabbitMQPublish != 4 {
+					t.Errorf("RabbitMQPublish = %v, want 4", cfg.RabbitMQPublish)
+				}
+			},
+		},
+		{
+			name: "invalid RabbitMQ publish channels",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME":                "test-actor",
+				"ASYA_RABBITMQ_PUBLISH_CHANNELS": "0",
 			},
+			expectError: true,
+		},
+		{
+			name: "progress disabled",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME":       "test-actor",
+				"ASYA_GATEWAY_URL":      "http://gateway:8080",
+				"ASYA_DISABLE_PROGRESS": "true",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if !cfg.ProgressDisabled {
+					t.Error("ProgressDisabled should be true")
+				}
+			},
+		},
+		{
+			name: "progress batching",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME":              "test-actor",
+				"ASYA_PROGRESS_BATCH_INTERVAL": "200ms",
+				"ASYA_PROGRESS_BATCH_SIZE":     "50",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.ProgressBatchInterval != 200*time.Millisecond {
+					t.Errorf("ProgressBatchInterval = %v, want 200ms", cfg.ProgressBatchInterval)
+				}
+				if cfg.ProgressBatchSize != 50 {
+					t.Errorf("ProgressBatchSize = %v, want 50", cfg.ProgressBatchSize)
+				}
+			},
+		},
+		{
+			name: "invalid progress batch size",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME":          "test-actor",
+				"ASYA_PROGRESS_BATCH_SIZE": "5000",
+			},
+			expectError: true,
+		},
+		{
+			name: "SQS visibility extension",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME":               "test-actor",
+				"ASYA_SQS_VISIBILITY_EXTENSION": "0.25",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.SQSVisibilityExtension != 0.25 {
+					t.Errorf("SQSVisibilityExtension = %v, want 0.25", cfg.SQSVisibilityExtension)
+				}
+			},
+		},
+		{
+			name: "invalid SQS visibility extension",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME":               "test-actor",
+				"ASYA_SQS_VISIBILITY_EXTENSION": "1",
+			},
+			expectError: true,
+		},
+		{
+			name: "socket stream threshold",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME":              "test-actor",
+				"ASYA_SOCKET_STREAM_THRESHOLD": "8388608",
+			},
+			expectError: false,
+			validate: func(t *testing.T, cfg *Config) {
+				if cfg.SocketStreamThreshold != 8388608 {
+					t.Errorf("SocketStreamThreshold = %d, want 8388608", cfg.SocketStreamThreshold)
+				}
+			},
+		},
+		{
+			name: "negative socket stream threshold",
+			env: map[string]string{
+				"ASYA_ACTOR_NAME":              "test-actor",
+				"ASYA_SOCKET_STREAM_THRESHOLD": "-1",
+			},
+			expectError: true,
 		},
 		{
 			name: "RabbitMQ URL from individual env vars",
@@ -0,0 +1,45 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestHandler_JobID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(slog.NewTextHandler(&buf, nil))).With("actor", "echo")
+
+	ctx := ContextWithJobID(context.Background(), "job-1")
+	logger.InfoContext(ctx, "with job")
+	logger.InfoContext(context.Background(), "without job")
+	logger.Info("without context")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d log lines, want 3: %q", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[0], "actor=echo job_id=job-1") {
+		t.Errorf("line %q should carry actor and job_id", lines[0])
+	}
+	for _, line := range lines[1:] {
+		if strings.Contains(line, "job_id") {
+			t.Errorf("line %q should not carry job_id", line)
+		}
+	}
+}
+
+func TestContextWithJobID(t *testing.T) {
+	ctx := context.Background()
+	if got := ContextWithJobID(ctx, ""); got != ctx {
+		t.Error("empty job ID should return the context unchanged")
+	}
+	if got := JobIDFromContext(ContextWithJobID(ctx, "job-1")); got != "job-1" {
+		t.Errorf("JobIDFromContext() = %q, want job-1", got)
+	}
+	if got := JobIDFromContext(ctx); got != "" {
+		t.Errorf("JobIDFromContext() = %q, want empty", got)
+	}
+}
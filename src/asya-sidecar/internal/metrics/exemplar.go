@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"context"
+	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TraceIDHeader is the envelope header holding the trace ID that duration exemplars link to
+const TraceIDHeader = "trace_id"
+
+// maxTraceIDLength bounds trace IDs recorded as exemplars; OpenMetrics limits
+// exemplar labels to 128 characters and invalid exemplars make the client panic
+const maxTraceIDLength = 64
+
+type traceIDKey struct{}
+
+// ContextWithTraceID returns a context whose duration observations carry traceID as exemplar.
+// An empty traceID returns ctx unchanged.
+func ContextWithTraceID(ctx context.Context, traceID string) context.Context {
+	if traceID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+// TraceIDFromContext returns the trace ID set by ContextWithTraceID, or ""
+func TraceIDFromContext(ctx context.Context) string {
+	traceID, _ := ctx.Value(traceIDKey{}).(string)
+	return traceID
+}
+
+// TraceIDFromHeaders returns the trace ID of envelope headers, or "" when it is
+// missing or unusable as exemplar (not a string, too long or invalid UTF-8)
+func TraceIDFromHeaders(headers map[string]interface{}) string {
+	traceID, _ := headers[TraceIDHeader].(string)
+	if len(traceID) > maxTraceIDLength || !utf8.ValidString(traceID) {
+		return ""
+	}
+	return traceID
+}
+
+// observe records v, with the context's trace ID as exemplar when there is one
+func observe(ctx context.Context, o prometheus.Observer, v float64) {
+	if traceID := TraceIDFromContext(ctx); traceID != "" {
+		if eo, ok := o.(prometheus.ExemplarObserver); ok {
+			eo.ObserveWithExemplar(v, prometheus.Labels{"trace_id": traceID})
+			return
+		}
+	}
+	o.Observe(v)
+}
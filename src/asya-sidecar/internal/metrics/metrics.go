@@ -10,6 +10,7 @@ import (
 
 	"github.com/deliveryhero/asya/asya-sidecar/internal/config"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
@@ -26,7 +27,9 @@ type Metrics struct {
 	queueSendDuration    *prometheus.HistogramVec
 	messageSize          *prometheus.HistogramVec
 	activeMessages       prometheus.Gauge
+	idle                 prometheus.Gauge
 	runtimeErrors        *prometheus.CounterVec
+	activeConsumers      *prometheus.GaugeVec
 
 	// Custom metrics (dynamically registered)
 	customCounters   map[string]*prometheus.CounterVec
@@ -81,7 +84,7 @@ func NewMetrics(namespace string, customMetricsConfig []config.CustomMetricConfi
 			Name:      "messages_failed_total",
 			Help:      "Total number of failed messages",
 		},
-		[]string{"queue", "reason"}, // reason: parse_error, runtime_error, transport_error
+		[]string{"queue", "reason"}, // reason: parse_error, runtime_error, transport_error, retries_exhausted
 	)
 
 	m.processingDuration = prometheus.NewHistogramVec(
@@ -142,6 +145,14 @@ func NewMetrics(namespace string, customMetricsConfig []config.CustomMetricConfi
 		},
 	)
 
+	m.idle = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "idle",
+			Help:      "Whether consumers are paused after the idle timeout (1) or consuming (0)",
+		},
+	)
+
 	m.runtimeErrors = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Namespace: namespace,
@@ -151,6 +162,15 @@ func NewMetrics(namespace string, customMetricsConfig []config.CustomMetricConfi
 		[]string{"queue", "error_type"},
 	)
 
+	m.activeConsumers = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "active_consumers",
+			Help:      "Queue consumers currently running",
+		},
+		[]string{"queue"},
+	)
+
 	// Register standard metrics
 	registry.MustRegister(
 		m.messagesReceived,
@@ -163,7 +183,11 @@ func NewMetrics(namespace string, customMetricsConfig []config.CustomMetricConfi
 		m.queueSendDuration,
 		m.messageSize,
 		m.activeMessages,
+		m.idle,
 		m.runtimeErrors,
+		m.activeConsumers,
+		// Go runtime metrics (go_goroutines, go_memstats_*) reveal goroutine and memory leaks
+		collectors.NewGoCollector(),
 	)
 
 	// Register custom metrics
@@ -261,12 +285,14 @@ func (m *Metrics) RecordMessageFailed(queue, reason string) {
 	m.messagesFailed.WithLabelValues(queue, reason).Inc()
 }
 
-func (m *Metrics) RecordProcessingDuration(queue string, duration time.Duration) {
-	m.processingDuration.WithLabelValues(queue).Observe(duration.Seconds())
+// RecordProcessingDuration observes the processing time of a message, linked to the trace in ctx (see ContextWithTraceID)
+func (m *Metrics) RecordProcessingDuration(ctx context.Context, queue string, duration time.Duration) {
+	observe(ctx, m.processingDuration.WithLabelValues(queue), duration.Seconds())
 }
 
-func (m *Metrics) RecordRuntimeDuration(queue string, duration time.Duration) {
-	m.runtimeDuration.WithLabelValues(queue).Observe(duration.Seconds())
+// RecordRuntimeDuration observes the runtime call time of a message, linked to the trace in ctx (see ContextWithTraceID)
+func (m *Metrics) RecordRuntimeDuration(ctx context.Context, queue string, duration time.Duration) {
+	observe(ctx, m.runtimeDuration.WithLabelValues(queue), duration.Seconds())
 }
 
 func (m *Metrics) RecordQueueReceiveDuration(queue, transport string, duration time.Duration) {
@@ -289,6 +315,24 @@ func (m *Metrics) DecrementActiveEnvelopes() {
 	m.activeMessages.Dec()
 }
 
+func (m *Metrics) SetIdle(idle bool) {
+	if idle {
+		m.idle.Set(1)
+	} else {
+		m.idle.Set(0)
+	}
+}
+
+// ConsumerStarted counts a running queue consumer
+func (m *Metrics) ConsumerStarted(queue string) {
+	m.activeConsumers.WithLabelValues(queue).Inc()
+}
+
+// ConsumerStopped uncounts a queue consumer that returned
+func (m *Metrics) ConsumerStopped(queue string) {
+	m.activeConsumers.WithLabelValues(queue).Dec()
+}
+
 func (m *Metrics) RecordRuntimeError(queue, errorType string) {
 	m.runtimeErrors.WithLabelValues(queue, errorType).Inc()
 }
@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -159,8 +161,8 @@ func TestMetrics_RecordMessageFailed(t *testing.T) {
 func TestMetrics_RecordDurations(t *testing.T) {
 	m := NewMetrics("test", []config.CustomMetricConfig{})
 
-	m.RecordProcessingDuration("test-queue", 100*time.Millisecond)
-	m.RecordRuntimeDuration("test-queue", 50*time.Millisecond)
+	m.RecordProcessingDuration(context.Background(), "test-queue", 100*time.Millisecond)
+	m.RecordRuntimeDuration(context.Background(), "test-queue", 50*time.Millisecond)
 	m.RecordQueueReceiveDuration("test-queue", "rabbitmq", 10*time.Millisecond)
 	m.RecordQueueSendDuration("next-queue", "rabbitmq", 5*time.Millisecond)
 
@@ -215,6 +217,35 @@ func TestMetrics_ActiveEnvelopes(t *testing.T) {
 	}
 }
 
+func TestMetrics_ActiveConsumers(t *testing.T) {
+	m := NewMetrics("test", []config.CustomMetricConfig{})
+
+	m.ConsumerStarted("asya-a")
+	m.ConsumerStarted("asya-b")
+	m.ConsumerStopped("asya-b")
+
+	if value := testutil.ToFloat64(m.activeConsumers.WithLabelValues("asya-a")); value != 1.0 {
+		t.Errorf("Expected 1 active consumer for asya-a, got %f", value)
+	}
+	if value := testutil.ToFloat64(m.activeConsumers.WithLabelValues("asya-b")); value != 0.0 {
+		t.Errorf("Expected 0 active consumers for asya-b, got %f", value)
+	}
+
+	families, err := m.registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+	found := false
+	for _, family := range families {
+		if family.GetName() == "go_goroutines" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("go_goroutines not exported")
+	}
+}
+
 func TestMetrics_RecordRuntimeError(t *testing.T) {
 	m := NewMetrics("test", []config.CustomMetricConfig{})
 
@@ -346,3 +377,59 @@ func TestSanitizeMetricName(t *testing.T) {
 		})
 	}
 }
+
+func TestMetrics_DurationExemplars(t *testing.T) {
+	m := NewMetrics("test", []config.CustomMetricConfig{})
+
+	ctx := ContextWithTraceID(context.Background(), TraceIDFromHeaders(map[string]interface{}{"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736"}))
+	m.RecordProcessingDuration(ctx, "test-queue", 100*time.Millisecond)
+	m.RecordRuntimeDuration(context.Background(), "test-queue", 50*time.Millisecond)
+
+	families, err := m.registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+
+	exemplars := make(map[string]string)
+	for _, family := range families {
+		if !strings.HasSuffix(family.GetName(), "_duration_seconds") {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, bucket := range metric.GetHistogram().GetBucket() {
+				for _, label := range bucket.GetExemplar().GetLabel() {
+					exemplars[family.GetName()] = label.GetValue()
+				}
+			}
+		}
+	}
+
+	if got := exemplars["test_processing_duration_seconds"]; got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("processing duration exemplar = %q, want trace ID", got)
+	}
+	if got, ok := exemplars["test_runtime_execution_duration_seconds"]; ok {
+		t.Errorf("runtime duration without trace has exemplar %q", got)
+	}
+}
+
+func TestTraceIDFromHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]interface{}
+		want    string
+	}{
+		{name: "trace ID", headers: map[string]interface{}{"trace_id": "abc-123"}, want: "abc-123"},
+		{name: "no headers", headers: nil, want: ""},
+		{name: "not a string", headers: map[string]interface{}{"trace_id": 42}, want: ""},
+		{name: "too long", headers: map[string]interface{}{"trace_id": strings.Repeat("a", 65)}, want: ""},
+		{name: "invalid UTF-8", headers: map[string]interface{}{"trace_id": "\xff"}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TraceIDFromHeaders(tt.headers); got != tt.want {
+				t.Errorf("TraceIDFromHeaders() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
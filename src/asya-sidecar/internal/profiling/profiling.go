@@ -0,0 +1,67 @@
+// Package profiling serves the net/http/pprof endpoints on a separate, private listener.
+package profiling
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"time"
+)
+
+// DefaultAddr only accepts local connections, e.g. through kubectl port-forward
+const DefaultAddr = "127.0.0.1:6060"
+
+// Handler returns a mux serving the profiles under /debug/pprof/. It is separate from
+// http.DefaultServeMux, so the endpoints never leak onto another server.
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// IsLoopback reports whether addr only listens on a loopback interface
+func IsLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Start serves the profiling endpoints on addr until ctx is cancelled
+func Start(ctx context.Context, addr string) error {
+	if !IsLoopback(addr) {
+		slog.Warn("Profiling endpoint listens beyond loopback, make sure it is not reachable from outside the pod", "addr", addr)
+	}
+
+	// No write timeout: CPU profiles and traces stream for their requested duration
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("Starting profiling server", "addr", addr)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("profiling server error: %w", err)
+	}
+	return nil
+}
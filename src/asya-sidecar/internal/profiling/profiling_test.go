@@ -0,0 +1,50 @@
+package profiling
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		path string
+		want int
+	}{
+		{path: "/debug/pprof/", want: http.StatusOK},
+		{path: "/debug/pprof/goroutine?debug=1", want: http.StatusOK},
+		{path: "/debug/pprof/heap", want: http.StatusOK},
+		{path: "/metrics", want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.want {
+				t.Errorf("GET %s = %d, want %d", tt.path, rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{addr: DefaultAddr, want: true},
+		{addr: "localhost:6060", want: true},
+		{addr: "[::1]:6060", want: true},
+		{addr: ":6060", want: false},
+		{addr: "0.0.0.0:6060", want: false},
+		{addr: "10.0.0.5:6060", want: false},
+		{addr: "invalid", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := IsLoopback(tt.addr); got != tt.want {
+			t.Errorf("IsLoopback(%q) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
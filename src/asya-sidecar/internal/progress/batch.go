@@ -0,0 +1,141 @@
+package progress
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// MaxBatchSize matches the gateway's limit of updates per batch request
+const MaxBatchSize = 1000
+
+// maxPendingBatches bounds the updates queued while the gateway is slow or unreachable;
+// further updates are dropped like updates whose retries are exhausted
+const maxPendingBatches = 100
+
+// flushTimeout bounds sending the remaining updates when batching stops
+const flushTimeout = 5 * time.Second
+
+// BatchedUpdate is a progress update of one envelope in a batch request
+type BatchedUpdate struct {
+	ID string `json:"id"`
+	ProgressUpdate
+}
+
+// batchResult is the gateway's outcome of one update of a batch request
+type batchResult struct {
+	EnvelopeID string `json:"envelope_id"`
+	Error      string `json:"error"`
+}
+
+// EnableBatching queues progress updates and sends them to the gateway in batches of up
+// to size updates, every interval or as soon as a batch is full, instead of one request
+// per update. Must be called before StartBatching and before any update is reported.
+func (r *Reporter) EnableBatching(interval time.Duration, size int) {
+	r.batchInterval = interval
+	r.batchSize = min(max(size, 1), MaxBatchSize)
+	r.batchFull = make(chan struct{}, 1)
+}
+
+// StartBatching sends queued updates in the background until the returned function is
+// called, which stops it and sends the remaining updates. Does nothing without batching.
+func (r *Reporter) StartBatching() (stop func()) {
+	if r.batchInterval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(r.batchInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			case <-r.batchFull:
+			}
+			r.flush(context.Background())
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+
+		ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+		defer cancel()
+		r.flush(ctx)
+	}
+}
+
+// enqueue adds an update to the next batch
+func (r *Reporter) enqueue(update BatchedUpdate) {
+	r.batchMu.Lock()
+	defer r.batchMu.Unlock()
+
+	if len(r.batch) >= r.batchSize*maxPendingBatches {
+		slog.Warn("Dropping progress update, too many updates waiting for the gateway",
+			"envelope_id", update.ID, "status", update.Status, "pending", len(r.batch))
+		return
+	}
+
+	r.batch = append(r.batch, update)
+	if len(r.batch) >= r.batchSize {
+		select {
+		case r.batchFull <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// flush sends all queued updates, in batches of up to batchSize, in the order they were reported
+func (r *Reporter) flush(ctx context.Context) {
+	for {
+		r.batchMu.Lock()
+		n := min(len(r.batch), r.batchSize)
+		updates := r.batch[:n:n]
+		r.batch = r.batch[n:]
+		r.batchMu.Unlock()
+
+		if n == 0 {
+			return
+		}
+		if err := r.sendBatch(ctx, updates); err != nil {
+			slog.Warn("Failed to send progress batch", "updates", n, "error", err)
+		}
+	}
+}
+
+// sendBatch sends updates to the gateway in one request and logs the updates it rejected
+func (r *Reporter) sendBatch(ctx context.Context, updates []BatchedUpdate) error {
+	payload, err := json.Marshal(updates)
+	if err != nil {
+		return fmt.Errorf("failed to marshal progress batch: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/envelopes/progress/batch", r.gatewayURL)
+	slog.Debug("Sending progress batch to gateway", "updates", len(updates), "url", url)
+
+	body, err := r.postWithRetry(ctx, url, payload)
+	if err != nil || body == nil {
+		return err
+	}
+
+	var results []batchResult
+	if err := json.Unmarshal(body, &results); err != nil {
+		return fmt.Errorf("failed to decode progress batch results: %w", err)
+	}
+	for _, result := range results {
+		if result.Error != "" {
+			slog.Warn("Gateway rejected progress update", "envelope_id", result.EnvelopeID, "error", result.Error)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package progress
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// batchGateway records the progress batches posted to it
+type batchGateway struct {
+	mu      sync.Mutex
+	batches [][]BatchedUpdate
+}
+
+func (g *batchGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/envelopes/progress/batch" {
+		http.Error(w, "unexpected path "+r.URL.Path, http.StatusNotFound)
+		return
+	}
+
+	var updates []BatchedUpdate
+	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	g.mu.Lock()
+	g.batches = append(g.batches, updates)
+	g.mu.Unlock()
+
+	results := make([]map[string]any, len(updates))
+	for i, update := range updates {
+		results[i] = map[string]any{"index": i, "envelope_id": update.ID, "status": "ok"}
+	}
+	_ = json.NewEncoder(w).Encode(results)
+}
+
+func (g *batchGateway) received() [][]BatchedUpdate {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([][]BatchedUpdate(nil), g.batches...)
+}
+
+func TestReporter_Batching(t *testing.T) {
+	gateway := &batchGateway{}
+	server := httptest.NewServer(gateway)
+	defer server.Close()
+
+	reporter := NewReporter(server.URL, "test-actor")
+	reporter.EnableBatching(time.Hour, 3)
+	stop := reporter.StartBatching()
+
+	ctx := context.Background()
+	statuses := []ProgressStatus{StatusReceived, StatusProcessing, StatusCompleted}
+	for _, id := range []string{"envelope-1", "envelope-2"} {
+		for _, status := range statuses {
+			if err := reporter.ReportProgress(ctx, id, ProgressUpdate{Actors: []string{"test-actor"}, Status: status}); err != nil {
+				t.Fatalf("ReportProgress() error = %v", err)
+			}
+		}
+	}
+
+	// Full batches are sent without waiting for the interval
+	deadline := time.Now().Add(5 * time.Second)
+	for len(gateway.received()) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d batches, want 2 full batches", len(gateway.received()))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Stopping sends the remaining updates
+	if err := reporter.ReportProgress(ctx, "envelope-3", ProgressUpdate{Status: StatusReceived}); err != nil {
+		t.Fatalf("ReportProgress() error = %v", err)
+	}
+	stop()
+
+	batches := gateway.received()
+	if len(batches) != 3 {
+		t.Fatalf("got %d batches, want 3", len(batches))
+	}
+
+	// Updates arrive in the order they were reported
+	var got []string
+	for _, batch := range batches {
+		for _, update := range batch {
+			got = append(got, update.ID+":"+string(update.Status))
+		}
+	}
+	want := []string{
+		"envelope-1:received", "envelope-1:processing", "envelope-1:completed",
+		"envelope-2:received", "envelope-2:processing", "envelope-2:completed",
+		"envelope-3:received",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("updates = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("update %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestReporter_BatchingInterval(t *testing.T) {
+	gateway := &batchGateway{}
+	server := httptest.NewServer(gateway)
+	defer server.Close()
+
+	reporter := NewReporter(server.URL, "test-actor")
+	reporter.EnableBatching(20*time.Millisecond, 100)
+	stop := reporter.StartBatching()
+	defer stop()
+
+	if err := reporter.ReportProgress(context.Background(), "envelope-1", ProgressUpdate{Status: StatusReceived}); err != nil {
+		t.Fatalf("ReportProgress() error = %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(gateway.received()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("partial batch not sent after the batch interval")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -19,11 +21,22 @@ const (
 	StatusCompleted  ProgressStatus = "completed"
 )
 
+// MaxPartialResultBytes matches the gateway's default partial result limit;
+// larger partial results are dropped instead of being rejected by the gateway
+const MaxPartialResultBytes = 64 << 10
+
 // Reporter sends progress updates to the gateway
 type Reporter struct {
 	gatewayURL string
 	httpClient *http.Client
 	actorName  string
+
+	// Batching (see EnableBatching); a zero interval sends every update right away
+	batchInterval time.Duration
+	batchSize     int
+	batchMu       sync.Mutex
+	batch         []BatchedUpdate
+	batchFull     chan struct{}
 }
 
 // NewReporter creates a new progress reporter
@@ -39,21 +52,35 @@ func NewReporter(gatewayURL, actorName string) *Reporter {
 
 // ProgressUpdate represents a progress update payload
 type ProgressUpdate struct {
-	Actors          []string       `json:"actors"`            // Full list of actors in the route
-	CurrentActorIdx int            `json:"current_actor_idx"` // Index of current actor
-	Status          ProgressStatus `json:"status"`            // "received" | "processing" | "completed"
-	Message         string         `json:"message,omitempty"`
-	DurationMs      *int64         `json:"duration_ms,omitempty"`     // Processing duration in milliseconds
-	MessageSizeKB   *float64       `json:"message_size_kb,omitempty"` // Message size in KB
+	Actors          []string        `json:"actors"`            // Full list of actors in the route
+	CurrentActorIdx int             `json:"current_actor_idx"` // Index of current actor
+	Status          ProgressStatus  `json:"status"`            // "received" | "processing" | "completed"
+	Message         string          `json:"message,omitempty"`
+	DurationMs      *int64          `json:"duration_ms,omitempty"`     // Processing duration in milliseconds
+	MessageSizeKB   *float64        `json:"message_size_kb,omitempty"` // Message size in KB
+	PartialResult   json.RawMessage `json:"partial_result,omitempty"`  // Intermediate output streamed to clients (not the final result)
+	Warnings        []string        `json:"warnings,omitempty"`        // Warnings of a successful runtime call, accumulated by the gateway
 }
 
-// ReportProgress sends a progress update to the gateway
+// ReportProgress sends a progress update to the gateway, or queues it for the next batch
+// when batching is enabled
 func (r *Reporter) ReportProgress(ctx context.Context, id string, update ProgressUpdate) error {
 	if id == "" {
 		// No id in message, skip progress reporting
 		return nil
 	}
 
+	if len(update.PartialResult) > MaxPartialResultBytes {
+		slog.WarnContext(ctx, "Dropping partial result over size limit",
+			"envelope_id", id, "size", len(update.PartialResult), "limit", MaxPartialResultBytes)
+		update.PartialResult = nil
+	}
+
+	if r.batchInterval > 0 {
+		r.enqueue(BatchedUpdate{ID: id, ProgressUpdate: update})
+		return nil
+	}
+
 	payload, err := json.Marshal(update)
 	if err != nil {
 		return fmt.Errorf("failed to marshal progress update: %w", err)
@@ -61,13 +88,30 @@ func (r *Reporter) ReportProgress(ctx context.Context, id string, update Progres
 
 	url := fmt.Sprintf("%s/envelopes/%s/progress", r.gatewayURL, id)
 
-	slog.Info("Sending progress update to gateway",
+	slog.InfoContext(ctx, "Sending progress update to gateway",
 		"envelope_id", id,
 		"status", update.Status,
 		"current_actor_idx", update.CurrentActorIdx,
 		"total_actors", len(update.Actors),
 		"url", url)
 
+	body, err := r.postWithRetry(ctx, url, payload)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		slog.DebugContext(ctx, "Progress update sent successfully",
+			"envelope_id", id,
+			"status", update.Status,
+			"current_actor_idx", update.CurrentActorIdx)
+	}
+	return nil
+}
+
+// postWithRetry posts a JSON payload to the gateway, retrying failed attempts, and returns
+// the body of the successful response. Progress reporting is best-effort: the body is nil
+// once every attempt failed or ctx is done, and the error only reports a malformed request.
+func (r *Reporter) postWithRetry(ctx context.Context, url string, payload []byte) ([]byte, error) {
 	maxRetries := 5
 	retryDelay := 200 * time.Millisecond
 
@@ -75,45 +119,39 @@ func (r *Reporter) ReportProgress(ctx context.Context, id string, update Progres
 		if attempt > 0 {
 			select {
 			case <-ctx.Done():
-				return nil
+				return nil, nil
 			case <-time.After(retryDelay):
 			}
 		}
 
 		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
 		if err != nil {
-			return fmt.Errorf("failed to create request: %w", err)
+			return nil, fmt.Errorf("failed to create request: %w", err)
 		}
 
 		req.Header.Set("Content-Type", "application/json")
 
 		resp, err := r.httpClient.Do(req)
 		if err != nil {
-			slog.Warn("Failed to send progress update", "error", err, "attempt", attempt+1, "max_retries", maxRetries)
-			if attempt == maxRetries-1 {
-				return nil
-			}
+			slog.WarnContext(ctx, "Failed to send progress update", "error", err, "attempt", attempt+1, "max_retries", maxRetries)
 			continue
 		}
-		defer func() { _ = resp.Body.Close() }()
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
-			slog.Warn("Progress update returned non-200 status", "status", resp.StatusCode, "attempt", attempt+1)
-			if attempt == maxRetries-1 {
-				return nil
-			}
+			slog.WarnContext(ctx, "Progress update returned non-200 status", "status", resp.StatusCode, "attempt", attempt+1)
+			continue
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to read progress update response", "error", err, "attempt", attempt+1)
 			continue
 		}
 
-		slog.Debug("Progress update sent successfully",
-			"envelope_id", id,
-			"status", update.Status,
-			"current_actor_idx", update.CurrentActorIdx)
-
-		return nil
+		return body, nil
 	}
 
-	return nil
+	return nil, nil
 }
 
 // GetGatewayURL returns the configured gateway URL
@@ -145,22 +183,70 @@ func (r *Reporter) CheckHealth(ctx context.Context) error {
 	return nil
 }
 
+// IsActive reports whether the gateway still waits for the envelope: false once it succeeded,
+// failed, timed out or was cancelled. Envelopes the gateway does not track (published by other
+// producers) are active. Returns error if the gateway cannot tell.
+func (r *Reporter) IsActive(ctx context.Context, envelopeID string) (bool, error) {
+	status, err := r.get(ctx, fmt.Sprintf("%s/envelopes/%s/active", r.gatewayURL, envelopeID))
+	if err != nil {
+		return false, err
+	}
+	switch status {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusGone:
+		// The gateway answers 410 for unknown envelopes too; only those it knows are dropped
+		status, err = r.get(ctx, fmt.Sprintf("%s/envelopes/%s", r.gatewayURL, envelopeID))
+		if err != nil {
+			return false, err
+		}
+		switch status {
+		case http.StatusOK:
+			return false, nil
+		case http.StatusNotFound:
+			return true, nil
+		}
+	}
+	return false, fmt.Errorf("envelope active check returned status %d", status)
+}
+
+// get sends a GET request to the gateway and returns the response status
+func (r *Reporter) get(ctx context.Context, url string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reach gateway: %w", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
 // CreateEnvelopePayload represents the payload for creating a fanout envelope
 type CreateEnvelopePayload struct {
-	ID       string   `json:"id"`
-	ParentID string   `json:"parent_id"`
-	Actors   []string `json:"actors"`
-	Current  int      `json:"current"`
+	ID             string   `json:"id"`
+	ParentID       string   `json:"parent_id"`
+	BranchIndex    int      `json:"branch_index"`
+	FanoutBranches int      `json:"fanout_branches,omitempty"` // Branches of the parent's fanout, its own included
+	Actors         []string `json:"actors"`
+	Current        int      `json:"current"`
 }
 
 // CreateEnvelope creates a fanout child envelope in the gateway
-// This is called when the sidecar detects multiple responses from runtime (fanout scenario)
-func (r *Reporter) CreateEnvelope(ctx context.Context, id, parentID string, actors []string, current int) error {
+// This is called when the sidecar detects multiple responses from runtime (fanout scenario);
+// branches is the number of responses, so the gateway knows how many branches the parent waits for
+func (r *Reporter) CreateEnvelope(ctx context.Context, id, parentID string, branchIndex, branches int, actors []string, current int) error {
 	payload := CreateEnvelopePayload{
-		ID:       id,
-		ParentID: parentID,
-		Actors:   actors,
-		Current:  current,
+		ID:             id,
+		ParentID:       parentID,
+		BranchIndex:    branchIndex,
+		FanoutBranches: branches,
+		Actors:         actors,
+		Current:        current,
 	}
 
 	payloadBytes, err := json.Marshal(payload)
@@ -187,7 +273,7 @@ func (r *Reporter) CreateEnvelope(ctx context.Context, id, parentID string, acto
 		return fmt.Errorf("create envelope returned status %d", resp.StatusCode)
 	}
 
-	slog.Debug("Created fanout envelope in gateway", "id", id, "parent_id", parentID)
+	slog.DebugContext(ctx, "Created fanout envelope in gateway", "id", id, "parent_id", parentID, "branch_index", branchIndex)
 	return nil
 }
 
@@ -228,6 +314,6 @@ func (r *Reporter) ReportFinalError(ctx context.Context, envelopeID, errorMsg st
 		return fmt.Errorf("gateway returned non-success status: %d", resp.StatusCode)
 	}
 
-	slog.Info("Reported final error to gateway", "id", envelopeID, "error", errorMsg)
+	slog.InfoContext(ctx, "Reported final error to gateway", "id", envelopeID, "error", errorMsg)
 	return nil
 }
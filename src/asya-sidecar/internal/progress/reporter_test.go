@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -513,6 +514,55 @@ func TestReportProgress_SucceedsOnFirstAttempt(t *testing.T) {
 	}
 }
 
+func TestReportProgress_PartialResult(t *testing.T) {
+	tests := []struct {
+		name          string
+		partialResult json.RawMessage
+		wantPartial   string
+	}{
+		{
+			name:          "partial result is sent",
+			partialResult: json.RawMessage(`{"text":"Once upon a"}`),
+			wantPartial:   `{"text":"Once upon a"}`,
+		},
+		{
+			name:          "oversized partial result is dropped",
+			partialResult: json.RawMessage(`"` + strings.Repeat("a", MaxPartialResultBytes) + `"`),
+			wantPartial:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received ProgressUpdate
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+					t.Errorf("Failed to decode request body: %v", err)
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			reporter := NewReporter(server.URL, "test-actor")
+			err := reporter.ReportProgress(context.Background(), "test-message-123", ProgressUpdate{
+				Actors:        []string{"generate"},
+				Status:        StatusProcessing,
+				PartialResult: tt.partialResult,
+			})
+			if err != nil {
+				t.Fatalf("ReportProgress returned error: %v", err)
+			}
+
+			if string(received.PartialResult) != tt.wantPartial {
+				t.Errorf("PartialResult = %.50s, want %q", received.PartialResult, tt.wantPartial)
+			}
+			if received.Status != StatusProcessing {
+				t.Errorf("Status = %v, want processing", received.Status)
+			}
+		})
+	}
+}
+
 func TestCreateEnvelope_Success(t *testing.T) {
 	var receivedPayload CreateEnvelopePayload
 
@@ -545,7 +595,7 @@ func TestCreateEnvelope_Success(t *testing.T) {
 	reporter := NewReporter(server.URL, "test-actor")
 
 	ctx := context.Background()
-	err := reporter.CreateEnvelope(ctx, "abc-123-1", "abc-123", []string{"actor1", "actor2"}, 1)
+	err := reporter.CreateEnvelope(ctx, "abc-123-1", "abc-123", 1, 2, []string{"actor1", "actor2"}, 1)
 
 	if err != nil {
 		t.Errorf("CreateEnvelope returned error: %v", err)
@@ -560,6 +610,14 @@ func TestCreateEnvelope_Success(t *testing.T) {
 		t.Errorf("ParentID = %v, want abc-123", receivedPayload.ParentID)
 	}
 
+	if receivedPayload.BranchIndex != 1 {
+		t.Errorf("BranchIndex = %v, want 1", receivedPayload.BranchIndex)
+	}
+
+	if receivedPayload.FanoutBranches != 2 {
+		t.Errorf("FanoutBranches = %v, want 2", receivedPayload.FanoutBranches)
+	}
+
 	if len(receivedPayload.Actors) != 2 {
 		t.Errorf("Actors length = %v, want 2", len(receivedPayload.Actors))
 	}
@@ -579,7 +637,7 @@ func TestCreateEnvelope_ServerError(t *testing.T) {
 	reporter := NewReporter(server.URL, "test-actor")
 
 	ctx := context.Background()
-	err := reporter.CreateEnvelope(ctx, "abc-123-1", "abc-123", []string{"actor1"}, 1)
+	err := reporter.CreateEnvelope(ctx, "abc-123-1", "abc-123", 1, 2, []string{"actor1"}, 1)
 
 	// Should return error
 	if err == nil {
@@ -596,7 +654,7 @@ func TestCreateEnvelope_NetworkError(t *testing.T) {
 	reporter := NewReporter("http://invalid-host-that-does-not-exist:99999", "test-actor")
 
 	ctx := context.Background()
-	err := reporter.CreateEnvelope(ctx, "abc-123-1", "abc-123", []string{"actor1"}, 1)
+	err := reporter.CreateEnvelope(ctx, "abc-123-1", "abc-123", 1, 2, []string{"actor1"}, 1)
 
 	// Should return error
 	if err == nil {
@@ -618,7 +676,7 @@ func TestCreateEnvelope_ContextCancellation(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 	defer cancel()
 
-	err := reporter.CreateEnvelope(ctx, "abc-123-1", "abc-123", []string{"actor1"}, 1)
+	err := reporter.CreateEnvelope(ctx, "abc-123-1", "abc-123", 1, 2, []string{"actor1"}, 1)
 
 	// Should return error due to timeout
 	if err == nil {
@@ -765,3 +823,46 @@ func TestCheckHealth_NonOKStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestIsActive(t *testing.T) {
+	tests := []struct {
+		name           string
+		activeStatus   int
+		envelopeStatus int
+		want           bool
+		wantErr        bool
+	}{
+		{name: "active", activeStatus: http.StatusOK, want: true},
+		{name: "completed or cancelled", activeStatus: http.StatusGone, envelopeStatus: http.StatusOK, want: false},
+		{name: "unknown to the gateway", activeStatus: http.StatusGone, envelopeStatus: http.StatusNotFound, want: true},
+		{name: "status lookup fails", activeStatus: http.StatusGone, envelopeStatus: http.StatusServiceUnavailable, wantErr: true},
+		{name: "gateway error", activeStatus: http.StatusInternalServerError, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet {
+					t.Errorf("Method = %v, want GET", r.Method)
+				}
+				switch r.URL.Path {
+				case "/envelopes/env-1/active":
+					w.WriteHeader(tt.activeStatus)
+				case "/envelopes/env-1":
+					w.WriteHeader(tt.envelopeStatus)
+				default:
+					t.Errorf("Unexpected path %s", r.URL.Path)
+				}
+			}))
+			defer server.Close()
+
+			active, err := NewReporter(server.URL, "test-actor").IsActive(context.Background(), "env-1")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("IsActive error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if active != tt.want {
+				t.Errorf("IsActive = %v, want %v", active, tt.want)
+			}
+		})
+	}
+}
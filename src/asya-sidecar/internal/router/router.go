@@ -9,9 +9,15 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/deliveryhero/asya/asya-sidecar/internal/adapter"
 	"github.com/deliveryhero/asya/asya-sidecar/internal/config"
+	"github.com/deliveryhero/asya/asya-sidecar/internal/logging"
 	"github.com/deliveryhero/asya/asya-sidecar/internal/metrics"
 	"github.com/deliveryhero/asya/asya-sidecar/internal/progress"
 	"github.com/deliveryhero/asya/asya-sidecar/internal/runtime"
@@ -24,6 +30,33 @@ const (
 	statusFailed    = "failed"
 )
 
+// ProcessResult tells the consumer what to do with a message once ProcessEnvelope returns
+type ProcessResult int
+
+const (
+	// ProcessAcked: the message was handled (routed onward or to an end queue) and is acknowledged
+	ProcessAcked ProcessResult = iota
+	// ProcessRequeue: processing failed and may succeed later, the message is retried
+	// (retry ladder or NACK for immediate redelivery)
+	ProcessRequeue
+	// ProcessDeadLetter: the message can never be processed and could not be sent to the error queue,
+	// it is rejected without requeueing so the queue's dead-letter configuration applies
+	ProcessDeadLetter
+)
+
+func (p ProcessResult) String() string {
+	switch p {
+	case ProcessAcked:
+		return "acked"
+	case ProcessRequeue:
+		return "requeue"
+	case ProcessDeadLetter:
+		return "dead-letter"
+	default:
+		return fmt.Sprintf("ProcessResult(%d)", int(p))
+	}
+}
+
 // Router handles message routing between queues and runtime client
 type Router struct {
 	cfg              *config.Config
@@ -34,7 +67,13 @@ type Router struct {
 	errorEndQueue    string
 	metrics          *metrics.Metrics
 	progressReporter *progress.Reporter
+	inboundAdapter   adapter.Adapter // Converts foreign message formats into envelopes (optional)
 	gatewayURL       string
+	retrySchedule    []time.Duration // Delays of the retry ladder (empty: NACK for immediate requeue)
+	processSlots     chan struct{}   // Envelopes processed at once across input queue consumers (cfg.Concurrency)
+	processSlotsOnce sync.Once       // Creates processSlots on first use
+	lastActivity     atomic.Int64    // Unix nanoseconds of the last receive or completed message
+	inFlight         atomic.Int32    // Messages received but not yet acknowledged
 }
 
 // NewRouter creates a new router instance
@@ -42,6 +81,9 @@ func NewRouter(cfg *config.Config, transport transport.Transport, runtimeClient
 	var progressReporter *progress.Reporter
 	if cfg.GatewayURL != "" {
 		progressReporter = progress.NewReporter(cfg.GatewayURL, cfg.ActorName)
+		if cfg.ProgressBatchInterval > 0 {
+			progressReporter.EnableBatching(cfg.ProgressBatchInterval, cfg.ProgressBatchSize)
+		}
 	}
 
 	return &Router{
@@ -54,9 +96,15 @@ func NewRouter(cfg *config.Config, transport transport.Transport, runtimeClient
 		metrics:          m,
 		progressReporter: progressReporter,
 		gatewayURL:       cfg.GatewayURL,
+		retrySchedule:    cfg.RetrySchedule,
 	}
 }
 
+// SetInboundAdapter sets the adapter applied to message bodies before envelope parsing
+func (r *Router) SetInboundAdapter(a adapter.Adapter) {
+	r.inboundAdapter = a
+}
+
 // processEndActorEnvelope handles envelope processing for end actors (happy-end, error-end)
 // End actors are terminal nodes that:
 // - Accept envelopes with ANY route state (no validation)
@@ -64,7 +112,7 @@ func NewRouter(cfg *config.Config, transport transport.Transport, runtimeClient
 // - Do NOT route responses anywhere (terminal processing)
 // - Report final status to gateway
 func (r *Router) processEndActorEnvelope(ctx context.Context, envelope envelopes.Envelope, msgBody []byte, startTime time.Time) error {
-	slog.Debug("End actor processing envelope", "id", envelope.ID, "actor", r.actorName)
+	slog.DebugContext(ctx, "End actor processing envelope", "id", envelope.ID, "actor", r.actorName)
 
 	// IMPORTANT: End actors are terminal - they do NOT route to any queue
 	// and do NOT increment route.current. They only:
@@ -75,32 +123,32 @@ func (r *Router) processEndActorEnvelope(ctx context.Context, envelope envelopes
 
 	// Send to runtime without route validation
 	runtimeStart := time.Now()
-	responses, err := r.runtimeClient.CallRuntime(ctx, msgBody)
+	responses, err := r.callRuntime(ctx, &envelope, msgBody)
 	runtimeDuration := time.Since(runtimeStart)
 
 	if r.metrics != nil {
-		r.metrics.RecordRuntimeDuration(r.actorName, runtimeDuration)
+		r.metrics.RecordRuntimeDuration(ctx, r.actorName, runtimeDuration)
 	}
 
 	if err != nil {
-		slog.Error("End actor runtime error", "id", envelope.ID, "error", err)
+		slog.ErrorContext(ctx, "End actor runtime error", "id", envelope.ID, "error", err)
 		if r.metrics != nil {
 			r.metrics.RecordMessageFailed(r.actorName, "runtime_error")
 			r.metrics.RecordRuntimeError(r.actorName, "execution_error")
-			r.metrics.RecordProcessingDuration(r.actorName, time.Since(startTime))
+			r.metrics.RecordProcessingDuration(ctx, r.actorName, time.Since(startTime))
 		}
 
 		if errors.Is(err, context.DeadlineExceeded) {
-			slog.Error("End actor runtime timeout exceeded - crashing pod to recover",
-				"timeout", r.cfg.Timeout, "envelope", envelope.ID)
+			slog.ErrorContext(ctx, "End actor runtime timeout exceeded - crashing pod to recover",
+				"timeout", r.runtimeTimeout(ctx, &envelope), "envelope", envelope.ID)
 
 			if r.progressReporter != nil {
-				errorCtx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+				errorCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 1*time.Second)
 				defer cancel()
 				_ = r.progressReporter.ReportFinalError(errorCtx, envelope.ID, "Runtime timeout exceeded")
 			}
 
-			slog.Error("Exiting to prevent zombie processing (runtime may still be working)")
+			slog.ErrorContext(ctx, "Exiting to prevent zombie processing (runtime may still be working)")
 			os.Exit(1)
 		}
 
@@ -110,7 +158,7 @@ func (r *Router) processEndActorEnvelope(ctx context.Context, envelope envelopes
 	// Record success metrics
 	if r.metrics != nil {
 		r.metrics.RecordMessageProcessed(r.actorName, "end_consumed")
-		r.metrics.RecordProcessingDuration(r.actorName, time.Since(startTime))
+		r.metrics.RecordProcessingDuration(ctx, r.actorName, time.Since(startTime))
 	}
 
 	// Extract result payload from runtime response
@@ -127,60 +175,108 @@ func (r *Router) processEndActorEnvelope(ctx context.Context, envelope envelopes
 	// Report final status to gateway if configured
 	if r.progressReporter != nil {
 		if err := r.reportFinalStatusWithEnvelope(ctx, &envelope, resultPayload, runtimeDuration); err != nil {
-			slog.Warn("Failed to report final status to gateway", "id", envelope.ID, "error", err)
+			slog.WarnContext(ctx, "Failed to report final status to gateway", "id", envelope.ID, "error", err)
 		}
 	}
 
-	slog.Debug("End actor completed processing", "id", envelope.ID, "actor", r.actorName)
+	slog.DebugContext(ctx, "End actor completed processing", "id", envelope.ID, "actor", r.actorName)
 	return nil
 }
 
-// parseAndValidateEnvelope parses and validates the envelope from message body
-func (r *Router) parseAndValidateEnvelope(ctx context.Context, msgBody []byte, startTime time.Time) (*envelopes.Envelope, error) {
+// parseAndValidateEnvelope parses and validates the envelope from message body.
+// Invalid messages are sent to the error queue and a nil envelope is returned
+// with the result of rejecting the message.
+func (r *Router) parseAndValidateEnvelope(ctx context.Context, msgBody []byte, startTime time.Time) (*envelopes.Envelope, ProcessResult, error) {
 	var envelope envelopes.Envelope
 	if err := json.Unmarshal(msgBody, &envelope); err != nil {
-		slog.Error("Failed to parse envelope", "error", err)
+		slog.ErrorContext(ctx, "Failed to parse envelope", "error", err)
 
 		if r.metrics != nil {
 			r.metrics.RecordMessageFailed(r.actorName, "parse_error")
-			r.metrics.RecordProcessingDuration(r.actorName, time.Since(startTime))
+			r.metrics.RecordProcessingDuration(ctx, r.actorName, time.Since(startTime))
 		}
 
-		_ = r.sendToErrorQueue(ctx, msgBody, fmt.Sprintf("Failed to parse message: %v", err))
-		return nil, err
+		result, err := r.rejectMessage(ctx, msgBody, fmt.Sprintf("Failed to parse message: %v", err))
+		return nil, result, err
 	}
 
 	if envelope.ID == "" {
-		slog.Error("Envelope missing required ID field")
+		slog.ErrorContext(ctx, "Envelope missing required ID field")
 
 		if r.metrics != nil {
 			r.metrics.RecordMessageFailed(r.actorName, "validation_error")
-			r.metrics.RecordProcessingDuration(r.actorName, time.Since(startTime))
+			r.metrics.RecordProcessingDuration(ctx, r.actorName, time.Since(startTime))
 		}
 
-		_ = r.sendToErrorQueue(ctx, msgBody, "Envelope missing required 'id' field")
-		return nil, fmt.Errorf("envelope missing required 'id' field")
+		result, err := r.rejectMessage(ctx, msgBody, "Envelope missing required 'id' field")
+		return nil, result, err
 	}
 
-	slog.Info("Envelope parsed and validated", "id", envelope.ID, "route", envelope.Route)
-	return &envelope, nil
+	if err := envelopes.ValidateID(envelope.ID); err != nil {
+		slog.ErrorContext(ctx, "Envelope has malformed ID", "error", err)
+
+		if r.metrics != nil {
+			r.metrics.RecordMessageFailed(r.actorName, "validation_error")
+			r.metrics.RecordProcessingDuration(ctx, r.actorName, time.Since(startTime))
+		}
+
+		result, err := r.rejectMessage(ctx, msgBody, fmt.Sprintf("Invalid envelope: %v", err))
+		return nil, result, err
+	}
+
+	slog.InfoContext(ctx, "Envelope parsed and validated", "id", envelope.ID, "route", envelope.Route)
+	return &envelope, ProcessAcked, nil
+}
+
+// rejectMessage sends a message that cannot be processed to the error queue.
+// If that fails, the message is dead-lettered since redelivering it cannot succeed.
+func (r *Router) rejectMessage(ctx context.Context, msgBody []byte, errorMsg string) (ProcessResult, error) {
+	if err := r.sendToErrorQueue(ctx, msgBody, errorMsg); err != nil {
+		return ProcessDeadLetter, fmt.Errorf("failed to send rejected message to error queue: %w", err)
+	}
+	return ProcessAcked, nil
 }
 
 // handleRuntimeResponses processes runtime responses and routes them to appropriate destinations
 func (r *Router) handleRuntimeResponses(ctx context.Context, envelope *envelopes.Envelope, responses []runtime.RuntimeResponse, msgBody []byte, runtimeDuration time.Duration, startTime time.Time) error {
 	if len(responses) == 0 {
-		slog.Info("Empty response from runtime, routing to happy-end", "id", envelope.ID)
+		slog.InfoContext(ctx, "Empty response from runtime, routing to happy-end", "id", envelope.ID)
 
 		if r.metrics != nil {
 			r.metrics.RecordMessageProcessed(r.actorName, "empty_response")
-			r.metrics.RecordProcessingDuration(r.actorName, time.Since(startTime))
+			r.metrics.RecordProcessingDuration(ctx, r.actorName, time.Since(startTime))
 		}
 
 		return r.sendToHappyQueue(ctx, *envelope)
 	}
 
+	// A handler skipping to a step its route does not have fails the envelope rather than retrying it
+	for i := range responses {
+		if responses[i].IsError() {
+			continue
+		}
+		if err := responses[i].ApplySkip(); err != nil {
+			slog.WarnContext(ctx, "Invalid step skip in runtime response", "id", envelope.ID, "index", i, "error", err)
+			return r.handleErrorResponse(ctx, msgBody, runtime.RuntimeResponse{
+				Error:   "invalid_skip",
+				Details: runtime.ErrorDetails{Message: err.Error(), Type: "InvalidSkip"},
+			}, startTime)
+		}
+	}
+
+	// Fanout children are registered before any branch is routed, so the gateway knows
+	// how many branches the parent waits for before the parent's own branch can complete
+	r.createFanoutEnvelopes(ctx, envelope, responses)
+
+	// A synchronous caller waits for a single outcome, so fanout branches do not reply;
+	// the gateway answers the call asynchronously once its wait times out
+	if len(responses) > 1 && envelope.ReplyTo != "" {
+		slog.InfoContext(ctx, "Fan-out of a synchronous envelope, branches do not reply", "id", envelope.ID, "branches", len(responses))
+		envelope.ReplyTo = ""
+	}
+
 	for i, response := range responses {
-		slog.Debug("Processing response", "index", i+1, "total", len(responses))
+		slog.DebugContext(ctx, "Processing response", "index", i+1, "total", len(responses))
 
 		if response.IsError() {
 			return r.handleErrorResponse(ctx, msgBody, response, startTime)
@@ -189,7 +285,7 @@ func (r *Router) handleRuntimeResponses(ctx context.Context, envelope *envelopes
 		if err := r.handleSuccessResponse(ctx, envelope, response, i, len(responses), runtimeDuration); err != nil {
 			if r.metrics != nil {
 				r.metrics.RecordMessageFailed(r.actorName, "routing_error")
-				r.metrics.RecordProcessingDuration(r.actorName, time.Since(startTime))
+				r.metrics.RecordProcessingDuration(ctx, r.actorName, time.Since(startTime))
 			}
 			return fmt.Errorf("failed to route response %d: %w", i, err)
 		}
@@ -197,7 +293,7 @@ func (r *Router) handleRuntimeResponses(ctx context.Context, envelope *envelopes
 
 	if r.metrics != nil {
 		r.metrics.RecordMessageProcessed(r.actorName, "success")
-		r.metrics.RecordProcessingDuration(r.actorName, time.Since(startTime))
+		r.metrics.RecordProcessingDuration(ctx, r.actorName, time.Since(startTime))
 	}
 
 	return nil
@@ -207,11 +303,11 @@ func (r *Router) handleRuntimeResponses(ctx context.Context, envelope *envelopes
 func (r *Router) handleErrorResponse(ctx context.Context, msgBody []byte, response runtime.RuntimeResponse, startTime time.Time) error {
 	if r.metrics != nil {
 		r.metrics.RecordMessageFailed(r.actorName, "runtime_error")
-		r.metrics.RecordProcessingDuration(r.actorName, time.Since(startTime))
+		r.metrics.RecordProcessingDuration(ctx, r.actorName, time.Since(startTime))
 	}
 
 	if err := r.sendToErrorQueue(ctx, msgBody, response.Error, response.Details); err != nil {
-		slog.Error("Failed to send error to error queue - will NACK for DLQ handling", "error", err)
+		slog.ErrorContext(ctx, "Failed to send error to error queue - will NACK for DLQ handling", "error", err)
 		if r.metrics != nil {
 			r.metrics.RecordMessageFailed(r.actorName, "error_queue_send_failed")
 		}
@@ -222,10 +318,7 @@ func (r *Router) handleErrorResponse(ctx context.Context, msgBody []byte, respon
 
 // handleSuccessResponse handles successful responses from runtime
 func (r *Router) handleSuccessResponse(ctx context.Context, envelope *envelopes.Envelope, response runtime.RuntimeResponse, index, totalResponses int, runtimeDuration time.Duration) error {
-	// Runtime is responsible for incrementing route.current:
-	// - In payload mode: runtime auto-increments
-	// - In envelope mode: user handler manually increments
-	outputRoute := response.Route
+	outputRoute := responseRoute(envelope, response)
 
 	if index == 0 && r.progressReporter != nil {
 		durationMs := runtimeDuration.Milliseconds()
@@ -235,28 +328,43 @@ func (r *Router) handleSuccessResponse(ctx context.Context, envelope *envelopes.
 			Status:          progress.StatusCompleted,
 			Message:         fmt.Sprintf("Completed processing in %dms", durationMs),
 			DurationMs:      &durationMs,
+			Warnings:        response.Warnings,
 		})
 	}
 
+	// Envelopes that keep their ID (no fanout, or fanout index 0) keep their own fanout position
 	envelopeID := envelope.ID
-	var parentID *string
+	parentID := envelope.ParentID
+	branchIndex := envelope.BranchIndex
 	if totalResponses > 1 && index > 0 {
-		envelopeID = fmt.Sprintf("%s-%d", envelope.ID, index)
+		envelopeID = fanoutID(envelope.ID, index)
 		parentID = &envelope.ID
-		slog.Debug("Fan-out: generated unique envelope ID", "original", envelope.ID, "fanout", envelopeID, "index", index)
+		branchIndex = index
+		slog.DebugContext(ctx, "Fan-out: generated unique envelope ID", "original", envelope.ID, "fanout", envelopeID, "index", index)
+	}
 
-		if r.progressReporter != nil {
-			if err := r.createFanoutEnvelope(ctx, envelopeID, *parentID, outputRoute); err != nil {
-				slog.Warn("Failed to create fanout envelope in gateway", "id", envelopeID, "error", err)
-			}
-		}
+	if len(response.Warnings) > 0 {
+		slog.WarnContext(ctx, "Runtime succeeded with warnings", "id", envelopeID, "warnings", response.Warnings)
 	}
 
-	return r.routeResponse(ctx, envelopeID, parentID, outputRoute, response.Payload)
+	return r.routeResponse(ctx, envelopes.Envelope{
+		ID:          envelopeID,
+		ParentID:    parentID,
+		BranchIndex: branchIndex,
+		Tool:        envelope.Tool,
+		ReplyTo:     envelope.ReplyTo,
+		Headers:     envelope.Headers,
+		Route:       outputRoute,
+		Payload:     response.Payload,
+		Warnings:    appendWarnings(envelope.Warnings, response.Warnings),
+
+		TimeoutOverrideSeconds: envelope.TimeoutOverrideSeconds,
+	})
 }
 
-// ProcessEnvelope handles a single envelope from the queue
-func (r *Router) ProcessEnvelope(ctx context.Context, msg transport.QueueMessage) error {
+// ProcessEnvelope handles a single envelope from the queue. The result tells whether
+// the message is acknowledged, retried or dead-lettered; the error explains the latter two.
+func (r *Router) ProcessEnvelope(ctx context.Context, msg transport.QueueMessage) (ProcessResult, error) {
 	startTime := time.Now()
 
 	if r.metrics != nil {
@@ -268,16 +376,42 @@ func (r *Router) ProcessEnvelope(ctx context.Context, msg transport.QueueMessage
 		r.metrics.RecordMessageSize("received", len(msg.Body))
 	}
 
-	envelope, err := r.parseAndValidateEnvelope(ctx, msg.Body, startTime)
-	if err != nil {
-		slog.Error("Failed to parse/validate envelope, sent to error queue", "error", err)
-		return nil
+	if r.inboundAdapter != nil {
+		adapted, err := r.inboundAdapter.Adapt(msg.Body)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to adapt inbound message", "msgID", msg.ID, "error", err)
+
+			if r.metrics != nil {
+				r.metrics.RecordMessageFailed(r.actorName, "adapter_error")
+				r.metrics.RecordProcessingDuration(ctx, r.actorName, time.Since(startTime))
+			}
+
+			return r.rejectMessage(ctx, msg.Body, fmt.Sprintf("Failed to adapt inbound message: %v", err))
+		}
+		msg.Body = adapted
+	}
+
+	envelope, result, err := r.parseAndValidateEnvelope(ctx, msg.Body, startTime)
+	if envelope == nil {
+		if result == ProcessAcked {
+			slog.ErrorContext(ctx, "Failed to parse/validate envelope, sent to error queue", "error", err)
+		}
+		return result, err
 	}
 
+	// Link duration metrics of this envelope to its trace
+	ctx = metrics.ContextWithTraceID(ctx, metrics.TraceIDFromHeaders(envelope.Headers))
+	// Tag the logs of this envelope with its job
+	ctx = logging.ContextWithJobID(ctx, jobID(envelope))
+
 	if r.cfg.IsEndActor {
-		return r.processEndActorEnvelope(ctx, *envelope, msg.Body, startTime)
+		if err := r.processEndActorEnvelope(ctx, *envelope, msg.Body, startTime); err != nil {
+			return ProcessRequeue, err
+		}
+		return ProcessAcked, nil
 	}
 
+	// The received report moves the envelope to running in the gateway, so it is always sent
 	if r.progressReporter != nil {
 		envelopeSizeKB := float64(len(msg.Body)) / 1024.0
 		_ = r.progressReporter.ReportProgress(ctx, envelope.ID, progress.ProgressUpdate{
@@ -290,22 +424,33 @@ func (r *Router) ProcessEnvelope(ctx context.Context, msg transport.QueueMessage
 	}
 
 	currentActor := envelope.Route.GetCurrentActor()
-	if currentActor != r.cfg.ActorName {
-		slog.Warn("Route mismatch: message routed to wrong actor",
+	if currentActor != r.cfg.ActorName && r.cfg.AllowRouteMismatch {
+		slog.WarnContext(ctx, "Route mismatch: message routed to wrong actor, processing anyway (strict route validation disabled)",
+			"expected", r.cfg.ActorName, "actual", currentActor, "id", envelope.ID)
+	} else if currentActor != r.cfg.ActorName {
+		slog.WarnContext(ctx, "Route mismatch: message routed to wrong actor, sending to error queue",
 			"expected", r.cfg.ActorName, "actual", currentActor, "id", envelope.ID)
 
 		if r.metrics != nil {
 			r.metrics.RecordMessageFailed(r.actorName, "route_mismatch")
-			r.metrics.RecordProcessingDuration(r.actorName, time.Since(startTime))
+			r.metrics.RecordProcessingDuration(ctx, r.actorName, time.Since(startTime))
 		}
 
 		errorMsg := fmt.Sprintf("Route mismatch: message routed to wrong actor (expected: %s, actual: %s)",
 			r.cfg.ActorName, currentActor)
-		_ = r.sendToErrorQueue(ctx, msg.Body, errorMsg)
-		return nil
+		return r.rejectMessage(ctx, msg.Body, errorMsg)
 	}
 
-	if r.progressReporter != nil {
+	if r.isCancelled(ctx, envelope) {
+		slog.InfoContext(ctx, "Dropping envelope no longer active in the gateway", "id", envelope.ID, "actor", r.cfg.ActorName)
+		if r.metrics != nil {
+			r.metrics.RecordMessageProcessed(r.actorName, "cancelled")
+			r.metrics.RecordProcessingDuration(ctx, r.actorName, time.Since(startTime))
+		}
+		return ProcessAcked, nil
+	}
+
+	if r.reportsIntermediateProgress() {
 		_ = r.progressReporter.ReportProgress(ctx, envelope.ID, progress.ProgressUpdate{
 			Actors:          envelope.Route.Actors,
 			CurrentActorIdx: envelope.Route.Current,
@@ -314,65 +459,79 @@ func (r *Router) ProcessEnvelope(ctx context.Context, msg transport.QueueMessage
 		})
 	}
 
-	slog.Info("Calling runtime", "id", envelope.ID, "actor", r.cfg.ActorName)
+	slog.InfoContext(ctx, "Calling runtime", "id", envelope.ID, "actor", r.cfg.ActorName)
 	runtimeStart := time.Now()
-	responses, err := r.runtimeClient.CallRuntime(ctx, msg.Body)
+	responses, err := r.callRuntime(ctx, envelope, msg.Body)
 	runtimeDuration := time.Since(runtimeStart)
 
 	if err != nil {
-		slog.Info("Runtime call failed", "id", envelope.ID, "duration", runtimeDuration, "error", err)
+		slog.InfoContext(ctx, "Runtime call failed", "id", envelope.ID, "duration", runtimeDuration, "error", err)
 	} else {
-		slog.Info("Runtime call completed", "id", envelope.ID, "duration", runtimeDuration, "responses", len(responses))
+		slog.InfoContext(ctx, "Runtime call completed", "id", envelope.ID, "duration", runtimeDuration, "responses", len(responses))
 	}
 
 	if r.metrics != nil {
-		r.metrics.RecordRuntimeDuration(r.actorName, runtimeDuration)
+		r.metrics.RecordRuntimeDuration(ctx, r.actorName, runtimeDuration)
 	}
 
 	if err != nil {
-		slog.Error("Runtime calling error", "error", err)
+		slog.ErrorContext(ctx, "Runtime calling error", "error", err)
 
 		if r.metrics != nil {
 			r.metrics.RecordMessageFailed(r.actorName, "runtime_error")
 			r.metrics.RecordRuntimeError(r.actorName, "execution_error")
-			r.metrics.RecordProcessingDuration(r.actorName, time.Since(startTime))
+			r.metrics.RecordProcessingDuration(ctx, r.actorName, time.Since(startTime))
 		}
 
 		// Check for timeout to provide better error message
 		isTimeout := errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded)
 		errorMsg := err.Error()
 		if isTimeout {
-			slog.Error("Runtime timeout exceeded - crashing pod to recover",
-				"timeout", r.cfg.Timeout, "envelope", envelope.ID)
-			errorMsg = fmt.Sprintf("Runtime timeout exceeded after %s", r.cfg.Timeout)
+			slog.ErrorContext(ctx, "Runtime timeout exceeded - crashing pod to recover",
+				"timeout", r.runtimeTimeout(ctx, envelope), "envelope", envelope.ID)
+			errorMsg = fmt.Sprintf("Runtime timeout exceeded after %s", r.runtimeTimeout(ctx, envelope))
 
 			if err := r.sendToErrorQueue(ctx, msg.Body, errorMsg); err != nil {
-				slog.Error("Failed to send timeout error to error queue - exiting anyway", "error", err)
+				slog.ErrorContext(ctx, "Failed to send timeout error to error queue - exiting anyway", "error", err)
 			}
 
-			slog.Error("Exiting to prevent zombie processing (runtime may still be working)")
+			slog.ErrorContext(ctx, "Exiting to prevent zombie processing (runtime may still be working)")
 			os.Exit(1)
 		}
 
 		if err := r.sendToErrorQueue(ctx, msg.Body, errorMsg); err != nil {
-			slog.Error("Failed to send runtime error to error queue - will NACK for DLQ handling", "error", err)
-			return fmt.Errorf("failed to send runtime error to error queue: %w", err)
+			slog.ErrorContext(ctx, "Failed to send runtime error to error queue - will NACK for DLQ handling", "error", err)
+			return ProcessRequeue, fmt.Errorf("failed to send runtime error to error queue: %w", err)
 		}
-		return nil
+		return ProcessAcked, nil
 	}
 
-	return r.handleRuntimeResponses(ctx, envelope, responses, msg.Body, runtimeDuration, startTime)
+	if err := r.handleRuntimeResponses(ctx, envelope, responses, msg.Body, runtimeDuration, startTime); err != nil {
+		return ProcessRequeue, err
+	}
+	return ProcessAcked, nil
 }
 
-// routeResponse routes a single response to the appropriate queue
-// The route parameter should already have its Current index incremented by the caller
-// parentID should be set for fanout children (when index > 0 in fanout scenario)
-func (r *Router) routeResponse(ctx context.Context, id string, parentID *string, route envelopes.Route, payload json.RawMessage) error {
+// appendWarnings returns the warnings of previous actors followed by new ones,
+// without modifying the previous slice (fanout children share it)
+func appendWarnings(previous, warnings []string) []string {
+	if len(warnings) == 0 {
+		return previous
+	}
+	return append(slices.Clip(previous), warnings...)
+}
+
+// routeResponse routes a single response envelope to the appropriate queue
+// The envelope's route should already have its Current index incremented by the caller
+// ParentID and BranchIndex should be set for fanout children (when index > 0 in fanout scenario)
+// Tool, ReplyTo, Warnings and the timeout override are carried over from the incoming envelope, so every hop keeps them
+func (r *Router) routeResponse(ctx context.Context, newEnvelope envelopes.Envelope) error {
 	// Determine destination queue
 	var destinationQueue string
 	var envelopeType string
 
-	actorToSend := route.GetCurrentActor()
+	id := newEnvelope.ID
+	actorToSend := newEnvelope.Route.GetCurrentActor()
 
 	if actorToSend != "" {
 		// There's a next actor in the route
@@ -384,18 +543,16 @@ func (r *Router) routeResponse(ctx context.Context, id string, parentID *string,
 		envelopeType = "happy_end"
 	}
 
-	// Create new message with the route as-is
-	newEnvelope := envelopes.Envelope{
-		ID:       id,
-		ParentID: parentID,
-		Route:    route,
-		Payload:  payload,
+	// Marshal message (end of route becomes a terminal message)
+	var envelopeBody []byte
+	var err error
+	if envelopeType == "happy_end" {
+		envelopeBody, err = json.Marshal(envelopes.NewSucceededMessage(newEnvelope))
+	} else {
+		envelopeBody, err = json.Marshal(newEnvelope)
 	}
-
-	// Marshal message
-	envelopeBody, err := json.Marshal(newEnvelope)
 	if err != nil {
-		slog.Error("Failed to marshal envelope for routing", "id", id, "error", err)
+		slog.ErrorContext(ctx, "Failed to marshal envelope for routing", "id", id, "error", err)
 		return fmt.Errorf("failed to marshal envelope: %w", err)
 	}
 
@@ -406,14 +563,14 @@ func (r *Router) routeResponse(ctx context.Context, id string, parentID *string,
 
 	// Send to destination queue
 	sendStart := time.Now()
-	slog.Info("Sending envelope to queue", "id", id, "queue", destinationQueue, "type", envelopeType)
+	slog.InfoContext(ctx, "Sending envelope to queue", "id", id, "queue", destinationQueue, "type", envelopeType)
 	err = r.transport.Send(ctx, destinationQueue, envelopeBody)
 	sendDuration := time.Since(sendStart)
 
 	if err != nil {
-		slog.Error("Failed to send envelope to queue", "id", id, "queue", destinationQueue, "error", err)
+		slog.ErrorContext(ctx, "Failed to send envelope to queue", "id", id, "queue", destinationQueue, "error", err)
 	} else {
-		slog.Info("Successfully sent envelope to queue", "id", id, "queue", destinationQueue, "duration", sendDuration)
+		slog.InfoContext(ctx, "Successfully sent envelope to queue", "id", id, "queue", destinationQueue, "duration", sendDuration)
 	}
 
 	// Record metrics
@@ -424,12 +581,16 @@ func (r *Router) routeResponse(ctx context.Context, id string, parentID *string,
 		}
 	}
 
+	if err == nil && envelopeType == "happy_end" {
+		r.sendReply(ctx, newEnvelope.ReplyTo, id, envelopeBody)
+	}
+
 	return err
 }
 
 // sendToHappyQueue sends the original message to the happy-end queue
 func (r *Router) sendToHappyQueue(ctx context.Context, message envelopes.Envelope) error {
-	envelopeBody, err := json.Marshal(message)
+	envelopeBody, err := json.Marshal(envelopes.NewSucceededMessage(message))
 	if err != nil {
 		return fmt.Errorf("failed to marshal envelope for happy-end: %w", err)
 	}
@@ -453,26 +614,34 @@ func (r *Router) sendToHappyQueue(ctx context.Context, message envelopes.Envelop
 		}
 	}
 
+	if err == nil {
+		r.sendReply(ctx, message.ReplyTo, message.ID, envelopeBody)
+	}
+
 	return err
 }
 
 // sendToErrorQueue sends an error message to the error-end queue
 func (r *Router) sendToErrorQueue(ctx context.Context, originalBody []byte, errorMsg string, errorDetails ...runtime.ErrorDetails) error {
-	// Parse original message to extract id, parent_id, and route
+	// Parse original message to extract id, parent_id, branch_index, tool, and route
 	var originalMsg envelopes.Envelope
-	id := ""
-	var parentID *string
-	route := map[string]any{
-		"actors":  []string{"error-end"},
-		"current": 0,
+	errorEnvelope := envelopes.Envelope{
+		Route: envelopes.Route{Actors: []string{"error-end"}, Current: 0},
 	}
 	if err := json.Unmarshal(originalBody, &originalMsg); err == nil {
-		id = originalMsg.ID
-		parentID = originalMsg.ParentID
+		// A malformed ID is dropped so error-end does not reject the error message too
+		if envelopes.ValidateID(originalMsg.ID) == nil {
+			errorEnvelope.ID = originalMsg.ID
+		}
+		errorEnvelope.ParentID = originalMsg.ParentID
+		errorEnvelope.BranchIndex = originalMsg.BranchIndex
+		errorEnvelope.Tool = originalMsg.Tool
+		errorEnvelope.ReplyTo = originalMsg.ReplyTo
+		errorEnvelope.Headers = originalMsg.Headers
+		errorEnvelope.Warnings = originalMsg.Warnings
 		// Preserve original route for traceability
 		if originalMsg.Route.Actors != nil {
-			route["actors"] = originalMsg.Route.Actors
-			route["current"] = originalMsg.Route.Current
+			errorEnvelope.Route = envelopes.Route{Actors: originalMsg.Route.Actors, Current: originalMsg.Route.Current, Metadata: originalMsg.Route.Metadata}
 		}
 	}
 
@@ -482,8 +651,10 @@ func (r *Router) sendToErrorQueue(ctx context.Context, originalBody []byte, erro
 	}
 
 	// Add error details to payload
+	var details any
 	if len(errorDetails) > 0 {
-		errorPayload["details"] = errorDetails[0]
+		details = errorDetails[0]
+		errorPayload["details"] = details
 	}
 
 	// Preserve original payload if available
@@ -495,16 +666,13 @@ func (r *Router) sendToErrorQueue(ctx context.Context, originalBody []byte, erro
 		}
 	}
 
-	errorMessage := map[string]any{
-		"id":      id,
-		"route":   route,
-		"payload": errorPayload,
-	}
-	if parentID != nil {
-		errorMessage["parent_id"] = *parentID
+	payloadBytes, err := json.Marshal(errorPayload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal error payload: %w", err)
 	}
+	errorEnvelope.Payload = payloadBytes
 
-	envelopeBody, err := json.Marshal(errorMessage)
+	envelopeBody, err := json.Marshal(envelopes.NewFailedMessage(errorEnvelope, errorMsg, details))
 	if err != nil {
 		return fmt.Errorf("failed to marshal error message: %w", err)
 	}
@@ -528,9 +696,34 @@ func (r *Router) sendToErrorQueue(ctx context.Context, originalBody []byte, erro
 		}
 	}
 
+	if err == nil {
+		r.sendReply(ctx, errorEnvelope.ReplyTo, errorEnvelope.ID, envelopeBody)
+	}
+
 	return err
 }
 
+// sendReply publishes a terminal message to the envelope's reply queue, if it has one.
+// The end queues stay the source of truth, so a failed reply is only logged:
+// the waiting gateway falls back to the envelope's status.
+func (r *Router) sendReply(ctx context.Context, replyTo, envelopeID string, terminalBody []byte) {
+	if replyTo == "" || envelopeID == "" {
+		return
+	}
+
+	replier, ok := r.transport.(transport.Replier)
+	if !ok {
+		slog.WarnContext(ctx, "Transport does not support reply queues, not replying", "id", envelopeID, "replyTo", replyTo)
+		return
+	}
+
+	if err := replier.Reply(ctx, replyTo, envelopeID, terminalBody); err != nil {
+		slog.WarnContext(ctx, "Failed to send reply", "id", envelopeID, "replyTo", replyTo, "error", err)
+		return
+	}
+	slog.DebugContext(ctx, "Sent reply", "id", envelopeID, "replyTo", replyTo)
+}
+
 // reportFinalStatusWithEnvelope reports final envelope status to gateway with full envelope context
 // This is called by end actors (happy-end, error-end) after processing
 // It has access to both the envelope (with route) and the result payload
@@ -543,7 +736,7 @@ func (r *Router) reportFinalStatusWithEnvelope(ctx context.Context, envelope *en
 	var result interface{}
 	if len(resultPayload) > 0 {
 		if err := json.Unmarshal(resultPayload, &result); err != nil {
-			slog.Warn("Failed to parse result payload", "error", err)
+			slog.WarnContext(ctx, "Failed to parse result payload", "error", err)
 			result = nil
 		}
 	}
@@ -583,7 +776,7 @@ func (r *Router) reportFinalStatusWithEnvelope(ctx context.Context, envelope *en
 			}
 		}
 	default:
-		slog.Warn("reportFinalStatusWithEnvelope called on non-end actor", "queue", r.actorName)
+		slog.WarnContext(ctx, "reportFinalStatusWithEnvelope called on non-end actor", "queue", r.actorName)
 		return nil
 	}
 
@@ -639,7 +832,7 @@ func (r *Router) reportFinalStatusWithEnvelope(ctx context.Context, envelope *en
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
-			slog.Error("Failed to close response body", "error", err)
+			slog.ErrorContext(ctx, "Failed to close response body", "error", err)
 		}
 	}()
 
@@ -647,7 +840,7 @@ func (r *Router) reportFinalStatusWithEnvelope(ctx context.Context, envelope *en
 		return fmt.Errorf("gateway returned non-success status: %d", resp.StatusCode)
 	}
 
-	slog.Info("Reported final status to gateway", "id", envelope.ID, "status", status,
+	slog.InfoContext(ctx, "Reported final status to gateway", "id", envelope.ID, "status", status,
 		"actor", currentActorName, "actor_idx", currentActorIdx)
 	return nil
 }
@@ -663,7 +856,7 @@ func (r *Router) reportFinalStatus(ctx context.Context, envelopeID string, resul
 	var result interface{}
 	if len(resultPayload) > 0 {
 		if err := json.Unmarshal(resultPayload, &result); err != nil {
-			slog.Warn("Failed to parse result payload", "error", err)
+			slog.WarnContext(ctx, "Failed to parse result payload", "error", err)
 			result = nil
 		}
 	}
@@ -707,13 +900,13 @@ func (r *Router) reportFinalStatus(ctx context.Context, envelopeID string, resul
 					}
 				}
 			} else {
-				slog.Warn("Failed to unmarshal error payload", "error", err)
+				slog.WarnContext(ctx, "Failed to unmarshal error payload", "error", err)
 			}
 		} else {
-			slog.Warn("Failed to marshal result for parsing", "error", err)
+			slog.WarnContext(ctx, "Failed to marshal result for parsing", "error", err)
 		}
 	default:
-		slog.Warn("reportFinalStatus called on non-end actor", "queue", r.actorName)
+		slog.WarnContext(ctx, "reportFinalStatus called on non-end actor", "queue", r.actorName)
 		return nil
 	}
 
@@ -769,7 +962,7 @@ func (r *Router) reportFinalStatus(ctx context.Context, envelopeID string, resul
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
-			slog.Error("Failed to close response body", "error", err)
+			slog.ErrorContext(ctx, "Failed to close response body", "error", err)
 		}
 	}()
 
@@ -777,7 +970,7 @@ func (r *Router) reportFinalStatus(ctx context.Context, envelopeID string, resul
 		return fmt.Errorf("gateway returned non-success status: %d", resp.StatusCode)
 	}
 
-	slog.Info("Reported final status to gateway", "id", envelopeID, "status", status,
+	slog.InfoContext(ctx, "Reported final status to gateway", "id", envelopeID, "status", status,
 		"actor", currentActorName, "actor_idx", currentActorIdx)
 	return nil
 }
@@ -793,10 +986,170 @@ func (r *Router) resolveQueueName(actorName string) string {
 	}
 }
 
-// createFanoutEnvelope creates a fanout child envelope in the gateway
+// callRuntime calls the runtime with the envelope's timeout override, if any, reporting the
+// partial results the handler emits to the gateway as progress of the envelope
+func (r *Router) callRuntime(ctx context.Context, envelope *envelopes.Envelope, body []byte) ([]runtime.RuntimeResponse, error) {
+	body = runtimeRequest(envelope, body)
+	timeout := r.runtimeClient.Timeout()
+	if envelope.TimeoutOverrideSeconds > 0 {
+		timeout = r.runtimeTimeout(ctx, envelope)
+	}
+
+	var onPartial runtime.PartialResultFunc
+	if r.progressReporter != nil {
+		onPartial = func(result json.RawMessage) {
+			_ = r.progressReporter.ReportProgress(ctx, envelope.ID, progress.ProgressUpdate{
+				Actors:          envelope.Route.Actors,
+				CurrentActorIdx: envelope.Route.Current,
+				Status:          progress.StatusProcessing,
+				Message:         fmt.Sprintf("Partial result from %s", r.cfg.ActorName),
+				PartialResult:   result,
+			})
+		}
+	}
+	return r.runtimeClient.CallRuntimeWithPartials(ctx, body, timeout, onPartial)
+}
+
+// jobID returns the job an envelope belongs to: route.metadata.job_id, or the envelope ID
+// when the envelope carries none
+func jobID(envelope *envelopes.Envelope) string {
+	if id, ok := envelope.Route.Metadata["job_id"].(string); ok && id != "" {
+		return id
+	}
+	return envelope.ID
+}
+
+// runtimeRequest returns the socket request for an envelope: its message body, with
+// route.metadata.job_id set to the envelope ID when the envelope carries none, so handlers
+// always find the job in the route metadata. Only that key is added; every other field keeps
+// its original JSON, and the body is passed on as is when job_id is already set.
+func runtimeRequest(envelope *envelopes.Envelope, body []byte) []byte {
+	if _, ok := envelope.Route.Metadata["job_id"]; ok {
+		return body
+	}
+
+	jobIDJSON, err := json.Marshal(envelope.ID)
+	if err != nil {
+		return body
+	}
+	request, err := setRawField(body, jobIDJSON, "route", "metadata", "job_id")
+	if err != nil {
+		return body
+	}
+	return request
+}
+
+// setRawField sets the field at path in a JSON object to value, creating missing (or null)
+// objects on the way. The values of all other fields keep their JSON, only compacted, so
+// unknown fields and number precision survive.
+func setRawField(object json.RawMessage, value json.RawMessage, path ...string) (json.RawMessage, error) {
+	fields := make(map[string]json.RawMessage)
+	if len(object) > 0 && string(object) != "null" {
+		if err := json.Unmarshal(object, &fields); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(path) > 1 {
+		var err error
+		if value, err = setRawField(fields[path[0]], value, path[1:]...); err != nil {
+			return nil, err
+		}
+	}
+	fields[path[0]] = value
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(fields); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// runtimeTimeout returns the runtime timeout for an envelope: its override capped by
+// the actor's maximum processing timeout, or the actor's default timeout
+func (r *Router) runtimeTimeout(ctx context.Context, envelope *envelopes.Envelope) time.Duration {
+	if envelope.TimeoutOverrideSeconds <= 0 {
+		return r.cfg.Timeout
+	}
+
+	timeout := time.Duration(envelope.TimeoutOverrideSeconds) * time.Second
+	maxTimeout := r.cfg.MaxProcessingTimeout
+	if maxTimeout <= 0 {
+		maxTimeout = r.cfg.Timeout
+	}
+	if timeout > maxTimeout {
+		slog.WarnContext(ctx, "Timeout override exceeds actor maximum, capping",
+			"id", envelope.ID, "override", timeout, "max", maxTimeout)
+		return maxTimeout
+	}
+	return timeout
+}
+
+// createFanoutEnvelopes creates the gateway envelopes of fanout children: the responses after
+// the first one, up to the first error response (which stops routing)
 // Fanout children use the same route state as the parent after runtime processing
-func (r *Router) createFanoutEnvelope(ctx context.Context, id, parentID string, route envelopes.Route) error {
-	return r.progressReporter.CreateEnvelope(ctx, id, parentID, route.Actors, route.Current)
+func (r *Router) createFanoutEnvelopes(ctx context.Context, envelope *envelopes.Envelope, responses []runtime.RuntimeResponse) {
+	if len(responses) < 2 || r.progressReporter == nil {
+		return
+	}
+
+	branches := len(responses)
+	for i, response := range responses {
+		if response.IsError() {
+			branches = i
+			break
+		}
+	}
+
+	for i := 1; i < branches; i++ {
+		id := fanoutID(envelope.ID, i)
+		route := responseRoute(envelope, responses[i])
+		if err := r.progressReporter.CreateEnvelope(ctx, id, envelope.ID, i, branches, route.Actors, route.Current); err != nil {
+			slog.WarnContext(ctx, "Failed to create fanout envelope in gateway", "id", id, "error", err)
+		}
+	}
+}
+
+// fanoutID returns the envelope ID of the fanout child at index (> 0)
+func fanoutID(parentID string, index int) string {
+	return fmt.Sprintf("%s-%d", parentID, index)
+}
+
+// responseRoute returns the route a runtime response is sent on.
+// Runtime is responsible for incrementing route.current:
+// - In payload mode: runtime auto-increments
+// - In envelope mode: user handler manually increments
+// Route metadata is the envelope's context for every step, kept even if an envelope mode handler drops it.
+func responseRoute(envelope *envelopes.Envelope, response runtime.RuntimeResponse) envelopes.Route {
+	route := response.Route
+	if route.Metadata == nil {
+		route.Metadata = envelope.Route.Metadata
+	}
+	return route
+}
+
+// reportsIntermediateProgress tells whether the processing update, which only advances the
+// progress percentage, is sent to the gateway. The received and completed updates carry status
+// transitions (running, actor done) and are sent whenever a gateway is configured.
+func (r *Router) reportsIntermediateProgress() bool {
+	return r.progressReporter != nil && !r.cfg.ProgressDisabled
+}
+
+// isCancelled tells whether the gateway no longer waits for the envelope (completed, timed out
+// or cancelled through /admin/envelopes/cancel), so the runtime call can be skipped.
+// Without a gateway, or when the gateway cannot tell, the envelope is processed.
+func (r *Router) isCancelled(ctx context.Context, envelope *envelopes.Envelope) bool {
+	if r.progressReporter == nil || envelope.ID == "" {
+		return false
+	}
+	active, err := r.progressReporter.IsActive(ctx, envelope.ID)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to check whether envelope is active, processing it", "id", envelope.ID, "error", err)
+		return false
+	}
+	return !active
 }
 
 // CheckGatewayHealth verifies the gateway is reachable if gateway URL is configured
@@ -809,10 +1162,156 @@ func (r *Router) CheckGatewayHealth(ctx context.Context) error {
 	return r.progressReporter.CheckHealth(ctx)
 }
 
-// Run starts the message processing loop
+// Run starts the message processing loop, with one consumer per input queue.
+//
+// With an idle timeout configured, consumers are paused once no message has been received
+// for that long and none is in flight, and broker consumers are cancelled, so a scale-to-zero
+// termination cannot catch the sidecar holding a message. If the pod is still running after another idle timeout
+// (e.g. KEDA kept it because new messages arrived), consumption resumes.
 func (r *Router) Run(ctx context.Context) error {
-	queueName := r.resolveQueueName(r.actorName)
-	slog.Info("Starting router", "queue", queueName)
+	queueNames := r.inputQueues()
+	slog.InfoContext(ctx, "Starting router", "queues", queueNames, "idleTimeout", r.cfg.IdleTimeout)
+
+	// Batched progress updates of the last messages are sent once consumers stop
+	if r.progressReporter != nil {
+		stopBatching := r.progressReporter.StartBatching()
+		defer stopBatching()
+	}
+
+	if r.cfg.IdleTimeout <= 0 {
+		return r.runConsumers(ctx, queueNames)
+	}
+
+	for {
+		r.lastActivity.Store(time.Now().UnixNano())
+
+		consumeCtx, stopConsumers := context.WithCancel(ctx)
+		go r.watchIdle(consumeCtx, stopConsumers)
+		_ = r.runConsumers(consumeCtx, queueNames)
+		stopConsumers()
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// Stop the broker from delivering to the paused actor, so no message waits in its prefetch
+		if canceller, ok := r.transport.(transport.ConsumerCanceller); ok {
+			if err := canceller.CancelConsumers(ctx); err != nil {
+				slog.WarnContext(ctx, "Failed to cancel consumers of idle actor", "error", err)
+			}
+		}
+
+		slog.InfoContext(ctx, "Actor idle, consumers paused until termination or resume", "idleTimeout", r.cfg.IdleTimeout)
+		if r.metrics != nil {
+			r.metrics.SetIdle(true)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(r.cfg.IdleTimeout):
+		}
+
+		slog.InfoContext(ctx, "Actor still running after idle pause, resuming consumers")
+		if r.metrics != nil {
+			r.metrics.SetIdle(false)
+		}
+	}
+}
+
+// watchIdle stops the consumers once the actor has been idle for the configured timeout
+func (r *Router) watchIdle(ctx context.Context, stopConsumers context.CancelFunc) {
+	ticker := time.NewTicker(max(r.cfg.IdleTimeout/4, 10*time.Millisecond))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			idleFor := time.Since(time.Unix(0, r.lastActivity.Load()))
+			if r.inFlight.Load() == 0 && idleFor >= r.cfg.IdleTimeout {
+				slog.InfoContext(ctx, "No messages received within idle timeout, stopping consumers", "idleFor", idleFor)
+				stopConsumers()
+				return
+			}
+		}
+	}
+}
+
+// runConsumers consumes from all input queues until the context is cancelled
+func (r *Router) runConsumers(ctx context.Context, queueNames []string) error {
+	if len(queueNames) == 1 {
+		return r.consume(ctx, queueNames[0])
+	}
+
+	// Consumers run independently: a consumer that stops unexpectedly is restarted
+	// without affecting the others, and Run returns once all consumers have stopped
+	var wg sync.WaitGroup
+	for _, queueName := range queueNames {
+		wg.Add(1)
+		go func(queueName string) {
+			defer wg.Done()
+			r.superviseConsumer(ctx, queueName)
+		}(queueName)
+	}
+	wg.Wait()
+
+	slog.InfoContext(ctx, "Router shutting down", "reason", ctx.Err())
+	return ctx.Err()
+}
+
+// inputQueues returns the queues to consume from (the actor's own queue by default)
+func (r *Router) inputQueues() []string {
+	if len(r.cfg.QueueNames) == 0 {
+		return []string{r.resolveQueueName(r.actorName)}
+	}
+	return r.cfg.QueueNames
+}
+
+// superviseConsumer runs a queue consumer until the context is cancelled, restarting it if it stops
+func (r *Router) superviseConsumer(ctx context.Context, queueName string) {
+	const restartDelay = 5 * time.Second
+
+	for {
+		err := r.consumeRecovered(ctx, queueName)
+		if ctx.Err() != nil {
+			return
+		}
+
+		slog.ErrorContext(ctx, "Queue consumer stopped, restarting", "queue", queueName, "error", err, "restartDelaySeconds", restartDelay.Seconds())
+		if r.metrics != nil {
+			r.metrics.RecordMessageFailed(queueName, "consumer_stopped")
+		}
+
+		select {
+		case <-time.After(restartDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// consumeRecovered runs a queue consumer, converting a panic into an error
+func (r *Router) consumeRecovered(ctx context.Context, queueName string) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("consumer panic: %v", rec)
+		}
+	}()
+	return r.consume(ctx, queueName)
+}
+
+// consume receives and processes messages from a single queue until the context is cancelled
+func (r *Router) consume(ctx context.Context, queueName string) error {
+	if r.metrics != nil {
+		r.metrics.ConsumerStarted(queueName)
+		defer r.metrics.ConsumerStopped(queueName)
+	}
+
+	// Messages in processing finish before the consumer returns
+	var workers sync.WaitGroup
+	defer workers.Wait()
 
 	var consecutiveFailures int
 	const maxBackoff = 30 * time.Second
@@ -820,12 +1319,11 @@ func (r *Router) Run(ctx context.Context) error {
 	for {
 		select {
 		case <-ctx.Done():
-			slog.Info("Router shutting down", "reason", ctx.Err())
+			slog.InfoContext(ctx, "Queue consumer shutting down", "queue", queueName, "reason", ctx.Err())
 			return ctx.Err()
 		default:
 			// Receive message from queue
 			receiveStart := time.Now()
-			queueName := r.resolveQueueName(r.actorName)
 			msg, err := r.transport.Receive(ctx, queueName)
 			receiveDuration := time.Since(receiveStart)
 
@@ -844,7 +1342,8 @@ func (r *Router) Run(ctx context.Context) error {
 					backoff = maxBackoff
 				}
 
-				slog.Error("Failed to receive message",
+				slog.ErrorContext(ctx, "Failed to receive message",
+					"queue", queueName,
 					"error", err,
 					"consecutiveFailures", consecutiveFailures,
 					"backoffSeconds", backoff.Seconds())
@@ -858,30 +1357,186 @@ func (r *Router) Run(ctx context.Context) error {
 			}
 
 			consecutiveFailures = 0
+			r.inFlight.Add(1)
+			r.lastActivity.Store(time.Now().UnixNano())
 
-			slog.Info("Message received from queue", "msgID", msg.ID, "receiveDuration", receiveDuration)
+			slog.InfoContext(ctx, "Message received from queue", "queue", queueName, "msgID", msg.ID, "receiveDuration", receiveDuration)
 
-			// Record receive metrics
+			// Record receive metrics by source queue
 			if r.metrics != nil {
-				r.metrics.RecordMessageReceived(r.actorName, r.cfg.TransportType)
-				r.metrics.RecordQueueReceiveDuration(r.actorName, r.cfg.TransportType, receiveDuration)
+				r.metrics.RecordMessageReceived(queueName, r.cfg.TransportType)
+				r.metrics.RecordQueueReceiveDuration(queueName, r.cfg.TransportType, receiveDuration)
 			}
 
-			// Process envelope
-			slog.Info("Processing envelope", "msgID", msg.ID)
-			if err := r.ProcessEnvelope(ctx, msg); err != nil {
-				slog.Error("Envelope processing failed", "msgID", msg.ID, "error", err)
-				// NACK the envelope for retry
-				if nackErr := r.transport.Nack(ctx, msg); nackErr != nil {
-					slog.Error("Failed to NACK envelope", "msgID", msg.ID, "error", nackErr)
-				}
-				continue
-			}
+			r.dispatch(ctx, msg, &workers)
+		}
+	}
+}
 
-			// ACK the envelope on success
-			if err := r.transport.Ack(ctx, msg); err != nil {
-				slog.Error("Failed to ACK envelope", "msgID", msg.ID, "error", err)
+// slots returns the processing slots shared by the input queue consumers
+func (r *Router) slots() chan struct{} {
+	r.processSlotsOnce.Do(func() {
+		if r.processSlots == nil {
+			r.processSlots = make(chan struct{}, max(r.cfg.Concurrency, 1))
+		}
+	})
+	return r.processSlots
+}
+
+// dispatch processes a received message once a processing slot is free. With a single slot
+// it is processed in the consumer; otherwise in its own worker, so the consumer receives the
+// next message while it runs.
+func (r *Router) dispatch(ctx context.Context, msg transport.QueueMessage, workers *sync.WaitGroup) {
+	slots := r.slots()
+	slots <- struct{}{}
+	if cap(slots) == 1 {
+		defer func() { <-slots }()
+		r.handleMessage(ctx, msg)
+		return
+	}
+
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		defer func() { <-slots }()
+		defer func() {
+			if rec := recover(); rec != nil {
+				slog.ErrorContext(ctx, "Panic while processing message", "msgID", msg.ID, "error", rec)
 			}
+		}()
+		r.handleMessage(ctx, msg)
+	}()
+}
+
+// handleMessage processes a received message and acknowledges, retries or dead-letters it.
+// The caller holds a processing slot (see dispatch), so at most cfg.Concurrency messages are
+// processed at once across input queues.
+// A received message is always processed to completion, even if consumers are being stopped.
+func (r *Router) handleMessage(ctx context.Context, msg transport.QueueMessage) {
+	defer func() {
+		r.lastActivity.Store(time.Now().UnixNano())
+		r.inFlight.Add(-1)
+	}()
+
+	ctx = context.WithoutCancel(ctx)
+
+	// Keep the message from being redelivered while it is processed
+	stopHeartbeat := func() {}
+	if heartbeater, ok := r.transport.(transport.Heartbeater); ok {
+		stopHeartbeat = heartbeater.Heartbeat(ctx, msg)
+	}
+
+	// Process envelope
+	slog.InfoContext(ctx, "Processing envelope", "msgID", msg.ID, "deliveryCount", transport.DeliveryCount(msg))
+	result, err := r.ProcessEnvelope(ctx, msg)
+	stopHeartbeat()
+	switch result {
+	case ProcessAcked:
+		if err := r.transport.Ack(ctx, msg); err != nil {
+			slog.ErrorContext(ctx, "Failed to ACK envelope", "msgID", msg.ID, "error", err)
 		}
+	case ProcessDeadLetter:
+		slog.ErrorContext(ctx, "Envelope cannot be processed, dead-lettering", "msgID", msg.ID, "error", err)
+		r.deadLetter(ctx, msg)
+	default:
+		slog.ErrorContext(ctx, "Envelope processing failed", "msgID", msg.ID, "result", result, "error", err)
+		r.retryFailed(ctx, msg)
+	}
+}
+
+// deadLetter rejects a message without requeueing. Transports that cannot dead-letter
+// NACK it instead, leaving it to the queue's redrive policy.
+func (r *Router) deadLetter(ctx context.Context, msg transport.QueueMessage) {
+	retrier, ok := r.transport.(transport.Retrier)
+	if !ok {
+		if err := r.transport.Nack(ctx, msg); err != nil {
+			slog.ErrorContext(ctx, "Failed to NACK envelope", "msgID", msg.ID, "error", err)
+		}
+		return
+	}
+	if err := retrier.DeadLetter(ctx, msg); err != nil {
+		slog.ErrorContext(ctx, "Failed to dead-letter envelope", "msgID", msg.ID, "error", err)
+	}
+}
+
+// retryFailed redelivers a message whose processing failed. With a retry ladder and a transport
+// supporting delayed redelivery, the message is retried after the next delay of the schedule
+// and dead-lettered once the schedule is exhausted; otherwise it is NACKed for requeue after the
+// NACK backoff. Either way it is dead-lettered once delivered MaxDeliveries times.
+func (r *Router) retryFailed(ctx context.Context, msg transport.QueueMessage) {
+	deliveries := transport.DeliveryCount(msg)
+	if r.cfg.MaxDeliveries > 0 && deliveries >= r.cfg.MaxDeliveries {
+		slog.WarnContext(ctx, "Delivery limit reached, dead-lettering envelope", "msgID", msg.ID, "deliveries", deliveries)
+		if r.metrics != nil {
+			r.metrics.RecordMessageFailed(r.actorName, "deliveries_exhausted")
+		}
+		r.deadLetter(ctx, msg)
+		return
+	}
+
+	retrier, ok := r.transport.(transport.Retrier)
+	if len(r.retrySchedule) == 0 || !ok {
+		if delay := r.nackDelay(deliveries); delay > 0 {
+			slog.DebugContext(ctx, "Delaying NACK", "msgID", msg.ID, "deliveries", deliveries, "delay", delay)
+			r.nackAfter(ctx, msg, delay)
+			return
+		}
+		if err := r.transport.Nack(ctx, msg); err != nil {
+			slog.ErrorContext(ctx, "Failed to NACK envelope", "msgID", msg.ID, "error", err)
+		}
+		return
+	}
+
+	attempt, _ := strconv.Atoi(msg.Headers[transport.RetryAttemptHeader])
+	if attempt >= len(r.retrySchedule) {
+		slog.WarnContext(ctx, "Retry ladder exhausted, dead-lettering envelope", "msgID", msg.ID, "attempts", attempt)
+		if r.metrics != nil {
+			r.metrics.RecordMessageFailed(r.actorName, "retries_exhausted")
+		}
+		r.deadLetter(ctx, msg)
+		return
+	}
+
+	delay := r.retrySchedule[attempt]
+	slog.InfoContext(ctx, "Scheduling envelope retry", "msgID", msg.ID, "attempt", attempt+1, "delay", delay)
+	if err := retrier.Retry(ctx, msg, delay, attempt+1); err != nil {
+		slog.ErrorContext(ctx, "Failed to schedule retry, NACKing envelope", "msgID", msg.ID, "error", err)
+		if nackErr := r.transport.Nack(ctx, msg); nackErr != nil {
+			slog.ErrorContext(ctx, "Failed to NACK envelope", "msgID", msg.ID, "error", nackErr)
+		}
+	}
+}
+
+// nackAfter NACKs msg once delay has passed, without holding the processing slot meanwhile.
+// Transports that can delay a NACK themselves (SQS) are NACKed right away; otherwise msg stays
+// unacked until the delay has passed, and so keeps its place in the consumer's prefetch window.
+func (r *Router) nackAfter(ctx context.Context, msg transport.QueueMessage, delay time.Duration) {
+	if nacker, ok := r.transport.(transport.DelayedNacker); ok {
+		if err := nacker.NackAfter(ctx, msg, delay); err != nil {
+			slog.ErrorContext(ctx, "Failed to NACK envelope", "msgID", msg.ID, "error", err)
+		}
+		return
+	}
+
+	time.AfterFunc(delay, func() {
+		if err := r.transport.Nack(ctx, msg); err != nil {
+			slog.ErrorContext(ctx, "Failed to NACK envelope", "msgID", msg.ID, "error", err)
+		}
+	})
+}
+
+// nackDelay returns how long to wait before NACKing a message on its given delivery:
+// NackDelay doubled for each earlier delivery, capped at NackMaxDelay
+func (r *Router) nackDelay(deliveries int) time.Duration {
+	delay := r.cfg.NackDelay
+	for i := 1; i < deliveries && delay > 0; i++ {
+		delay *= 2
+		if r.cfg.NackMaxDelay > 0 && delay >= r.cfg.NackMaxDelay {
+			return r.cfg.NackMaxDelay
+		}
+	}
+	if r.cfg.NackMaxDelay > 0 && delay > r.cfg.NackMaxDelay {
+		return r.cfg.NackMaxDelay
 	}
+	return delay
 }
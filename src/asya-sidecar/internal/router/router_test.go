@@ -11,12 +11,16 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/deliveryhero/asya/asya-sidecar/internal/adapter"
 	"github.com/deliveryhero/asya/asya-sidecar/internal/config"
+	"github.com/deliveryhero/asya/asya-sidecar/internal/logging"
 	"github.com/deliveryhero/asya/asya-sidecar/internal/metrics"
 	"github.com/deliveryhero/asya/asya-sidecar/internal/progress"
 	"github.com/deliveryhero/asya/asya-sidecar/internal/runtime"
@@ -125,6 +129,7 @@ func TestRouter_RouteValidation(t *testing.T) {
 	tests := []struct {
 		name                 string
 		actorName            string
+		allowRouteMismatch   bool
 		inputRoute           envelopes.Route
 		expectedWarnContains string
 		shouldRejectAndError bool
@@ -166,6 +171,19 @@ func TestRouter_RouteValidation(t *testing.T) {
 			shouldCallRuntime:    false,
 			expectedDestQueue:    "asya-error-end",
 		},
+		{
+			name:               "route mismatch with strict validation disabled - processes anyway",
+			actorName:          "test-actor",
+			allowRouteMismatch: true,
+			inputRoute: envelopes.Route{
+				Actors:  []string{"wrong-actor", "next-actor"},
+				Current: 0,
+			},
+			expectedWarnContains: "processing anyway",
+			shouldRejectAndError: false,
+			shouldCallRuntime:    true,
+			expectedDestQueue:    "asya-next-actor",
+		},
 	}
 
 	for _, tt := range tests {
@@ -218,6 +236,8 @@ func TestRouter_RouteValidation(t *testing.T) {
 				HappyEndQueue: "happy-end",
 				ErrorEndQueue: "error-end",
 				TransportType: "rabbitmq",
+
+				AllowRouteMismatch: tt.allowRouteMismatch,
 			}
 
 			mockTransport := &mockTransport{}
@@ -249,10 +269,13 @@ func TestRouter_RouteValidation(t *testing.T) {
 			}
 
 			ctx := context.Background()
-			err = router.ProcessEnvelope(ctx, queueMsg)
+			result, err := router.ProcessEnvelope(ctx, queueMsg)
 			if err != nil {
 				t.Fatalf("ProcessMessage failed: %v", err)
 			}
+			if result != ProcessAcked {
+				t.Errorf("ProcessEnvelope result = %v, want %v", result, ProcessAcked)
+			}
 
 			time.Sleep(50 * time.Millisecond)
 
@@ -264,12 +287,12 @@ func TestRouter_RouteValidation(t *testing.T) {
 			}
 
 			logOutput := logBuf.String()
-			if tt.shouldRejectAndError {
-				if !strings.Contains(logOutput, tt.expectedWarnContains) {
-					t.Errorf("Expected warning containing %q, got log output:\n%s",
-						tt.expectedWarnContains, logOutput)
-				}
+			if tt.expectedWarnContains != "" && !strings.Contains(logOutput, tt.expectedWarnContains) {
+				t.Errorf("Expected warning containing %q, got log output:\n%s",
+					tt.expectedWarnContains, logOutput)
+			}
 
+			if tt.shouldRejectAndError {
 				if len(mockTransport.sentMessages) != 1 {
 					t.Fatalf("Expected 1 message to error queue, got %d", len(mockTransport.sentMessages))
 				}
@@ -293,7 +316,7 @@ func TestRouter_RouteValidation(t *testing.T) {
 					t.Errorf("Error message should contain 'Route mismatch', got: %v", payload["error"])
 				}
 			} else {
-				if strings.Contains(logOutput, "Route mismatch") {
+				if tt.expectedWarnContains == "" && strings.Contains(logOutput, "Route mismatch") {
 					t.Errorf("Unexpected warning in log output:\n%s", logOutput)
 				}
 
@@ -499,10 +522,13 @@ func TestRouter_DynamicRouteModification(t *testing.T) {
 
 			// Process message
 			ctx := context.Background()
-			err = router.ProcessEnvelope(ctx, queueMsg)
+			result, err := router.ProcessEnvelope(ctx, queueMsg)
 			if err != nil {
 				t.Fatalf("ProcessMessage failed: %v", err)
 			}
+			if result != ProcessAcked {
+				t.Errorf("ProcessEnvelope result = %v, want %v", result, ProcessAcked)
+			}
 
 			// Verify message was routed successfully
 			if len(mockTransport.sentMessages) != 1 {
@@ -656,10 +682,13 @@ func TestRouter_ResolveQueueName_Integration(t *testing.T) {
 
 			// Process message
 			ctx := context.Background()
-			err = router.ProcessEnvelope(ctx, queueMsg)
+			result, err := router.ProcessEnvelope(ctx, queueMsg)
 			if err != nil {
 				t.Fatalf("ProcessMessage failed: %v", err)
 			}
+			if result != ProcessAcked {
+				t.Errorf("ProcessEnvelope result = %v, want %v", result, ProcessAcked)
+			}
 
 			// Verify the correct queue was used
 			if len(mockTransport.sentMessages) != len(tt.expectedQueues) {
@@ -780,7 +809,7 @@ func TestRouter_SendToHappyQueue(t *testing.T) {
 		t.Errorf("Envelope sent to queue %q, expected %q", mockTransport.sentMessages[0].queue, "asya-"+testQueueHappyEnd)
 	}
 
-	var sentEnvelope envelopes.Envelope
+	var sentEnvelope envelopes.TerminalMessage
 	err = json.Unmarshal(mockTransport.sentMessages[0].body, &sentEnvelope)
 	if err != nil {
 		t.Fatalf("Failed to unmarshal sent message: %v", err)
@@ -789,6 +818,78 @@ func TestRouter_SendToHappyQueue(t *testing.T) {
 	if sentEnvelope.ID != "test-envelope-123" {
 		t.Errorf("Expected envelope ID 'test-envelope-123', got %q", sentEnvelope.ID)
 	}
+	if sentEnvelope.Version != envelopes.TerminalMessageVersion {
+		t.Errorf("Expected version %d, got %d", envelopes.TerminalMessageVersion, sentEnvelope.Version)
+	}
+	if sentEnvelope.Status != envelopes.TerminalStatusSucceeded {
+		t.Errorf("Expected status %q, got %q", envelopes.TerminalStatusSucceeded, sentEnvelope.Status)
+	}
+	if sentEnvelope.CompletedAt.IsZero() {
+		t.Error("Expected completed_at to be set")
+	}
+	if string(sentEnvelope.Payload) != `{"result":"success"}` {
+		t.Errorf("Expected result payload to be kept, got %s", sentEnvelope.Payload)
+	}
+}
+
+func TestRouter_HandleSuccessResponse_Warnings(t *testing.T) {
+	cfg := &config.Config{
+		ActorName:     "test-actor",
+		HappyEndQueue: "happy-end",
+		ErrorEndQueue: "error-end",
+		TransportType: "rabbitmq",
+	}
+
+	var reported progress.ProgressUpdate
+	gatewayServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&reported); err != nil {
+			t.Errorf("Failed to decode progress update: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer gatewayServer.Close()
+
+	mockTransport := &mockTransport{}
+	router := &Router{
+		cfg:              cfg,
+		transport:        mockTransport,
+		actorName:        cfg.ActorName,
+		happyEndQueue:    cfg.HappyEndQueue,
+		errorEndQueue:    cfg.ErrorEndQueue,
+		progressReporter: progress.NewReporter(gatewayServer.URL, cfg.ActorName),
+	}
+
+	inputEnvelope := &envelopes.Envelope{
+		ID:       "test-warnings-1",
+		Route:    envelopes.Route{Actors: []string{"prep", "test-actor", "post"}, Current: 1},
+		Payload:  json.RawMessage(`{}`),
+		Warnings: []string{"input truncated"},
+	}
+	response := runtime.RuntimeResponse{
+		Route:    envelopes.Route{Actors: []string{"prep", "test-actor", "post"}, Current: 2},
+		Payload:  json.RawMessage(`{"ok": true}`),
+		Warnings: []string{"used fallback model"},
+	}
+
+	if err := router.handleSuccessResponse(context.Background(), inputEnvelope, response, 0, 1, time.Millisecond); err != nil {
+		t.Fatalf("handleSuccessResponse failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(reported.Warnings, []string{"used fallback model"}) {
+		t.Errorf("reported Warnings = %v, want only this actor's warnings", reported.Warnings)
+	}
+
+	if len(mockTransport.sentMessages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(mockTransport.sentMessages))
+	}
+	var envelope envelopes.Envelope
+	if err := json.Unmarshal(mockTransport.sentMessages[0].body, &envelope); err != nil {
+		t.Fatalf("Failed to unmarshal message: %v", err)
+	}
+	want := []string{"input truncated", "used fallback model"}
+	if !reflect.DeepEqual(envelope.Warnings, want) {
+		t.Errorf("routed Warnings = %v, want %v", envelope.Warnings, want)
+	}
 }
 
 func TestRouter_SendToErrorQueue(t *testing.T) {
@@ -812,7 +913,8 @@ func TestRouter_SendToErrorQueue(t *testing.T) {
 	}
 
 	originalEnvelope := envelopes.Envelope{
-		ID: "test-envelope-456",
+		ID:   "test-envelope-456",
+		Tool: "summarize",
 		Route: envelopes.Route{
 			Actors:  []string{"actor1"},
 			Current: 0,
@@ -845,6 +947,9 @@ func TestRouter_SendToErrorQueue(t *testing.T) {
 	if errorMsg["id"] != "test-envelope-456" {
 		t.Errorf("Expected error message ID 'test-envelope-456', got %v", errorMsg["id"])
 	}
+	if errorMsg["tool"] != "summarize" {
+		t.Errorf("Expected originating tool 'summarize', got %v", errorMsg["tool"])
+	}
 
 	// Error should be inside payload (nested format)
 	payload, ok := errorMsg["payload"].(map[string]any)
@@ -870,6 +975,20 @@ func TestRouter_SendToErrorQueue(t *testing.T) {
 	if errorMsg["route"] == nil {
 		t.Error("Expected route field in error envelope")
 	}
+
+	// Terminal message fields
+	if errorMsg["status"] != envelopes.TerminalStatusFailed {
+		t.Errorf("Expected status %q, got %v", envelopes.TerminalStatusFailed, errorMsg["status"])
+	}
+	if errorMsg["error"] != "Runtime processing failed" {
+		t.Errorf("Expected top-level error 'Runtime processing failed', got %v", errorMsg["error"])
+	}
+	if errorMsg["version"] != float64(envelopes.TerminalMessageVersion) {
+		t.Errorf("Expected version %d, got %v", envelopes.TerminalMessageVersion, errorMsg["version"])
+	}
+	if _, ok := errorMsg["completed_at"].(string); !ok {
+		t.Errorf("Expected completed_at timestamp, got %v", errorMsg["completed_at"])
+	}
 }
 
 func TestRouter_SendToErrorQueue_WithInvalidOriginalMessage(t *testing.T) {
@@ -971,6 +1090,13 @@ func TestRouter_Run(t *testing.T) {
 	if err != context.Canceled {
 		t.Errorf("Expected context.Canceled error, got: %v", err)
 	}
+
+	// The stopped consumer must no longer be counted
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), `test_active_consumers{queue="asya-test-actor"} 0`) {
+		t.Errorf("Expected 0 active consumers after Run returned, metrics:\n%s", rec.Body.String())
+	}
 }
 
 func TestRouter_ProcessMessage_ParseError(t *testing.T) {
@@ -1000,10 +1126,13 @@ func TestRouter_ProcessMessage_ParseError(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	err := router.ProcessEnvelope(ctx, queueMsg)
+	result, err := router.ProcessEnvelope(ctx, queueMsg)
 	if err != nil {
 		t.Fatalf("ProcessMessage should not return error (sends to error queue): %v", err)
 	}
+	if result != ProcessAcked {
+		t.Errorf("ProcessEnvelope result = %v, want %v", result, ProcessAcked)
+	}
 
 	if len(mockTransport.sentMessages) != 1 {
 		t.Fatalf("Expected 1 message sent to error queue, got %d", len(mockTransport.sentMessages))
@@ -1041,10 +1170,13 @@ func TestRouter_ProcessMessage_MissingEnvelopeID(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	err := router.ProcessEnvelope(ctx, queueMsg)
+	result, err := router.ProcessEnvelope(ctx, queueMsg)
 	if err != nil {
 		t.Fatalf("ProcessMessage should not return error (sends to error queue): %v", err)
 	}
+	if result != ProcessAcked {
+		t.Errorf("ProcessEnvelope result = %v, want %v", result, ProcessAcked)
+	}
 
 	if len(mockTransport.sentMessages) != 1 {
 		t.Fatalf("Expected 1 message sent to error queue, got %d", len(mockTransport.sentMessages))
@@ -1070,6 +1202,99 @@ func TestRouter_ProcessMessage_MissingEnvelopeID(t *testing.T) {
 	}
 }
 
+func TestRouter_ProcessMessage_MalformedEnvelopeID(t *testing.T) {
+	cfg := &config.Config{
+		ActorName:     "test-actor",
+		HappyEndQueue: "happy-end",
+		ErrorEndQueue: "error-end",
+		TransportType: "rabbitmq",
+	}
+
+	mockTransport := &mockTransport{}
+	router := &Router{
+		cfg:           cfg,
+		transport:     mockTransport,
+		actorName:     cfg.ActorName,
+		happyEndQueue: cfg.HappyEndQueue,
+		errorEndQueue: cfg.ErrorEndQueue,
+	}
+
+	queueMsg := transport.QueueMessage{
+		ID:   "msg-1",
+		Body: []byte(`{"id": "../../etc/passwd", "route": {"actors": ["test-actor"], "current": 0}, "payload": {}}`),
+	}
+
+	result, err := router.ProcessEnvelope(context.Background(), queueMsg)
+	if err != nil {
+		t.Fatalf("ProcessMessage should not return error (sends to error queue): %v", err)
+	}
+	if result != ProcessAcked {
+		t.Errorf("ProcessEnvelope result = %v, want %v", result, ProcessAcked)
+	}
+
+	if len(mockTransport.sentMessages) != 1 {
+		t.Fatalf("Expected 1 message sent to error queue, got %d", len(mockTransport.sentMessages))
+	}
+	if mockTransport.sentMessages[0].queue != "asya-"+testQueueErrorEnd {
+		t.Errorf("Envelope sent to %q, expected %q", mockTransport.sentMessages[0].queue, "asya-"+testQueueErrorEnd)
+	}
+
+	var errorEnvelope map[string]interface{}
+	if err := json.Unmarshal(mockTransport.sentMessages[0].body, &errorEnvelope); err != nil {
+		t.Fatalf("Failed to parse error envelope: %v", err)
+	}
+	if errorEnvelope["id"] != "" {
+		t.Errorf("Malformed ID should not be forwarded to error-end, got %v", errorEnvelope["id"])
+	}
+}
+
+func TestRouter_ProcessMessage_InboundAdapterError(t *testing.T) {
+	cfg := &config.Config{
+		ActorName:     "test-actor",
+		HappyEndQueue: "happy-end",
+		ErrorEndQueue: "error-end",
+		TransportType: "rabbitmq",
+	}
+
+	inboundAdapter, err := adapter.New("cloudevents", cfg.ActorName, "")
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+
+	mockTransport := &mockTransport{}
+	router := &Router{
+		cfg:            cfg,
+		transport:      mockTransport,
+		actorName:      cfg.ActorName,
+		happyEndQueue:  cfg.HappyEndQueue,
+		errorEndQueue:  cfg.ErrorEndQueue,
+		inboundAdapter: inboundAdapter,
+	}
+
+	queueMsg := transport.QueueMessage{
+		ID:   "msg-1",
+		Body: []byte("not a cloudevent"),
+	}
+
+	result, err := router.ProcessEnvelope(context.Background(), queueMsg)
+	if err != nil {
+		t.Fatalf("ProcessMessage should not return error (sends to error queue): %v", err)
+	}
+	if result != ProcessAcked {
+		t.Errorf("ProcessEnvelope result = %v, want %v", result, ProcessAcked)
+	}
+
+	if len(mockTransport.sentMessages) != 1 {
+		t.Fatalf("Expected 1 message sent to error queue, got %d", len(mockTransport.sentMessages))
+	}
+	if mockTransport.sentMessages[0].queue != "asya-"+testQueueErrorEnd {
+		t.Errorf("Envelope sent to %q, expected %q", mockTransport.sentMessages[0].queue, "asya-"+testQueueErrorEnd)
+	}
+	if !strings.Contains(string(mockTransport.sentMessages[0].body), "Failed to adapt inbound message") {
+		t.Errorf("Error envelope should mention adapter failure, got: %s", mockTransport.sentMessages[0].body)
+	}
+}
+
 func TestRouter_ProcessMessage_EmptyResponse(t *testing.T) {
 	socketPath := fmt.Sprintf("/tmp/test-empty-response-%d.sock", time.Now().UnixNano())
 	defer func() { _ = os.Remove(socketPath) }()
@@ -1134,10 +1359,13 @@ func TestRouter_ProcessMessage_EmptyResponse(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	err = router.ProcessEnvelope(ctx, queueMsg)
+	result, err := router.ProcessEnvelope(ctx, queueMsg)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
+	if result != ProcessAcked {
+		t.Errorf("ProcessEnvelope result = %v, want %v", result, ProcessAcked)
+	}
 
 	if len(mockTransport.sentMessages) != 1 {
 		t.Fatalf("Expected 1 message sent to happy-end, got %d", len(mockTransport.sentMessages))
@@ -1221,59 +1449,85 @@ func TestRouter_ProcessMessage_EndActor(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	err = router.ProcessEnvelope(ctx, queueMsg)
+	result, err := router.ProcessEnvelope(ctx, queueMsg)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
+	if result != ProcessAcked {
+		t.Errorf("ProcessEnvelope result = %v, want %v", result, ProcessAcked)
+	}
 
 	if len(mockTransport.sentMessages) != 0 {
 		t.Errorf("End actor should not send any messages, got %d", len(mockTransport.sentMessages))
 	}
 }
 
-func TestRouter_EndActor_WithInvalidRoute(t *testing.T) {
+func TestRuntimeRequest(t *testing.T) {
 	tests := []struct {
-		name  string
-		route envelopes.Route
-		desc  string
+		name string
+		body string
+		want string
 	}{
 		{
-			name: "current points to wrong actor",
-			route: envelopes.Route{
-				Actors:  []string{"test-echo"},
-				Current: 0,
-			},
-			desc: "Route points to test-echo but end actor is happy-end",
+			name: "job_id set is passed on as is",
+			body: `{"id": "env-1", "route": {"actors": ["a"], "metadata": {"job_id": "job-1"}}}`,
+			want: `{"id": "env-1", "route": {"actors": ["a"], "metadata": {"job_id": "job-1"}}}`,
 		},
 		{
-			name: "current out of bounds",
-			route: envelopes.Route{
-				Actors:  []string{"test-echo"},
-				Current: 5,
-			},
-			desc: "Route current index is out of bounds",
+			name: "job_id added next to unknown fields",
+			body: `{"id":"env-1","route":{"actors":["a"],"current":0,"hops":7,"metadata":{"user":"<u>","big":12345678901234567890}},"payload":{"n":1.50},"extra":true}`,
+			want: `{"extra":true,"id":"env-1","payload":{"n":1.50},"route":{"actors":["a"],"current":0,"hops":7,"metadata":{"big":12345678901234567890,"job_id":"env-1","user":"<u>"}}}`,
 		},
 		{
-			name: "empty route",
-			route: envelopes.Route{
-				Actors:  []string{},
-				Current: 0,
-			},
-			desc: "Route has no actors",
+			name: "null metadata",
+			body: `{"id":"env-1","route":{"actors":["a"],"metadata":null}}`,
+			want: `{"id":"env-1","route":{"actors":["a"],"metadata":{"job_id":"env-1"}}}`,
 		},
 		{
-			name: "multi-actor route pointing elsewhere",
-			route: envelopes.Route{
-				Actors:  []string{"actor1", "actor2", "actor3"},
-				Current: 1,
-			},
-			desc: "Route points to actor2 but end actor is happy-end",
+			name: "no route",
+			body: `{"id":"env-1"}`,
+			want: `{"id":"env-1","route":{"metadata":{"job_id":"env-1"}}}`,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			socketPath := fmt.Sprintf("/tmp/test-end-invalid-%d.sock", time.Now().UnixNano())
+			var envelope envelopes.Envelope
+			if err := json.Unmarshal([]byte(tt.body), &envelope); err != nil {
+				t.Fatalf("Failed to parse envelope: %v", err)
+			}
+			if got := string(runtimeRequest(&envelope, []byte(tt.body))); got != tt.want {
+				t.Errorf("runtimeRequest() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouter_ProcessMessage_RouteMetadataReachesRuntime(t *testing.T) {
+	tests := []struct {
+		name      string
+		metadata  map[string]interface{}
+		wantJobID string
+		wantUser  string
+	}{
+		{
+			name:      "metadata set at creation",
+			metadata:  map[string]interface{}{"job_id": "job-1", "user_id": "user-42"},
+			wantJobID: "job-1",
+			wantUser:  "user-42",
+		},
+		{name: "no metadata defaults job_id to envelope ID", wantJobID: "test-123"},
+		{
+			name:      "metadata without job_id",
+			metadata:  map[string]interface{}{"user_id": "user-42"},
+			wantJobID: "test-123",
+			wantUser:  "user-42",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			socketPath := fmt.Sprintf("/tmp/test-metadata-%d.sock", time.Now().UnixNano())
 			defer func() { _ = os.Remove(socketPath) }()
 
 			listener, err := net.Listen("unix", socketPath)
@@ -1282,6 +1536,7 @@ func TestRouter_EndActor_WithInvalidRoute(t *testing.T) {
 			}
 			defer func() { _ = listener.Close() }()
 
+			// Runtime handler echoing metadata fields back in its payload
 			go func() {
 				conn, err := listener.Accept()
 				if err != nil {
@@ -1289,96 +1544,333 @@ func TestRouter_EndActor_WithInvalidRoute(t *testing.T) {
 				}
 				defer func() { _ = conn.Close() }()
 
-				_, err = runtime.RecvSocketData(conn)
+				data, err := runtime.RecvSocketData(conn)
 				if err != nil {
 					return
 				}
-
-				responses := []runtime.RuntimeResponse{
-					{
-						Payload: json.RawMessage(`{"status": "processed"}`),
-					},
+				var request envelopes.Envelope
+				if err := json.Unmarshal(data, &request); err != nil {
+					return
 				}
-				data, _ := json.Marshal(responses)
+
+				payload, _ := json.Marshal(map[string]interface{}{
+					"job_id":  request.Route.Metadata["job_id"],
+					"user_id": request.Route.Metadata["user_id"],
+				})
+				responses := []runtime.RuntimeResponse{{Payload: payload, Route: request.Route.IncrementCurrent()}}
+				data, _ = json.Marshal(responses)
 				_ = runtime.SendSocketData(conn, data)
 			}()
 
 			cfg := &config.Config{
-				ActorName:     "happy-end",
+				ActorName:     "test-actor",
 				HappyEndQueue: "happy-end",
 				ErrorEndQueue: "error-end",
 				TransportType: "rabbitmq",
-				IsEndActor:    true,
 			}
 
 			mockTransport := &mockTransport{}
-			runtimeClient := runtime.NewClient(socketPath, 2*time.Second)
-			m := metrics.NewMetrics("test", []config.CustomMetricConfig{})
-
 			router := &Router{
 				cfg:           cfg,
 				transport:     mockTransport,
-				runtimeClient: runtimeClient,
+				runtimeClient: runtime.NewClient(socketPath, 2*time.Second),
 				actorName:     cfg.ActorName,
 				happyEndQueue: cfg.HappyEndQueue,
 				errorEndQueue: cfg.ErrorEndQueue,
-				metrics:       m,
 			}
 
-			inputEnvelope := envelopes.Envelope{
-				ID:      "test-invalid-route",
-				Route:   tt.route,
-				Payload: json.RawMessage(`{"data": "test"}`),
+			msgBody, _ := json.Marshal(envelopes.Envelope{
+				ID:      "test-123",
+				Route:   envelopes.Route{Actors: []string{"test-actor", "next-actor"}, Metadata: tt.metadata},
+				Payload: json.RawMessage(`{"text": "hello"}`),
+			})
+
+			// Capture logs to check that they carry the job ID
+			var logs bytes.Buffer
+			defaultLogger := slog.Default()
+			slog.SetDefault(slog.New(logging.NewHandler(slog.NewTextHandler(&logs, nil))))
+			defer slog.SetDefault(defaultLogger)
+
+			result, err := router.ProcessEnvelope(context.Background(), transport.QueueMessage{ID: "msg-1", Body: msgBody})
+			if err != nil || result != ProcessAcked {
+				t.Fatalf("ProcessEnvelope() = %v, %v, want %v", result, err, ProcessAcked)
 			}
-			msgBody, _ := json.Marshal(inputEnvelope)
 
-			queueMsg := transport.QueueMessage{
-				ID:   "msg-1",
-				Body: msgBody,
+			for _, line := range strings.Split(logs.String(), "\n") {
+				if strings.Contains(line, "Calling runtime") || strings.Contains(line, "Sending envelope to queue") {
+					if !strings.Contains(line, "job_id="+tt.wantJobID) {
+						t.Errorf("log line %q should carry job_id=%s", line, tt.wantJobID)
+					}
+				}
 			}
 
-			ctx := context.Background()
-			err = router.ProcessEnvelope(ctx, queueMsg)
-			if err != nil {
-				t.Fatalf("ProcessMessage failed for %s: %v", tt.desc, err)
+			if len(mockTransport.sentMessages) != 1 {
+				t.Fatalf("Expected 1 message, got %d", len(mockTransport.sentMessages))
 			}
-
-			if len(mockTransport.sentMessages) != 0 {
-				t.Errorf("End actor should not send any messages even with invalid route, got %d", len(mockTransport.sentMessages))
+			var sent envelopes.Envelope
+			if err := json.Unmarshal(mockTransport.sentMessages[0].body, &sent); err != nil {
+				t.Fatalf("Failed to unmarshal message: %v", err)
+			}
+			var echoed struct {
+				JobID  string `json:"job_id"`
+				UserID string `json:"user_id"`
+			}
+			if err := json.Unmarshal(sent.Payload, &echoed); err != nil {
+				t.Fatalf("Failed to unmarshal payload: %v", err)
+			}
+			if echoed.JobID != tt.wantJobID {
+				t.Errorf("echoed job_id = %q, want %q", echoed.JobID, tt.wantJobID)
+			}
+			if echoed.UserID != tt.wantUser {
+				t.Errorf("echoed user_id = %q, want %q", echoed.UserID, tt.wantUser)
 			}
 		})
 	}
 }
 
-func TestRouter_EndActor_WithGatewayReporting(t *testing.T) {
-	mockServer := &mockHTTPServer{responses: make(map[string]mockHTTPResponse)}
-	mockServer.Start(t)
-	defer mockServer.Close()
-
-	socketPath := fmt.Sprintf("/tmp/test-end-gateway-%d.sock", time.Now().UnixNano())
-	defer func() { _ = os.Remove(socketPath) }()
-
-	listener, err := net.Listen("unix", socketPath)
-	if err != nil {
-		t.Fatalf("Failed to create socket: %v", err)
+func TestRouter_ProcessMessage_SkipSteps(t *testing.T) {
+	tests := []struct {
+		name      string
+		response  string // Runtime response fields besides payload and route
+		wantQueue string
+		wantError string // Error of the error-end message, empty for a routed envelope
+	}{
+		{name: "skip next step", response: `"skip_next": 1`, wantQueue: "asya-post"},
+		{name: "skip to later step", response: `"skip_to": "post"`, wantQueue: "asya-post"},
+		{name: "skip remaining steps", response: `"skip_next": 2`, wantQueue: "asya-" + testQueueHappyEnd},
+		{name: "skip to earlier step", response: `"skip_to": "test-actor"`, wantQueue: "asya-" + testQueueErrorEnd, wantError: "invalid_skip"},
+		{name: "skip past route end", response: `"skip_next": 3`, wantQueue: "asya-" + testQueueErrorEnd, wantError: "invalid_skip"},
 	}
-	defer func() { _ = listener.Close() }()
 
-	go func() {
-		conn, err := listener.Accept()
-		if err != nil {
-			return
-		}
-		defer func() { _ = conn.Close() }()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			socketPath := fmt.Sprintf("/tmp/test-skip-%d.sock", time.Now().UnixNano())
+			defer func() { _ = os.Remove(socketPath) }()
 
-		_, err = runtime.RecvSocketData(conn)
-		if err != nil {
-			return
-		}
+			listener, err := net.Listen("unix", socketPath)
+			if err != nil {
+				t.Fatalf("Failed to create socket: %v", err)
+			}
+			defer func() { _ = listener.Close() }()
 
-		responses := []runtime.RuntimeResponse{
-			{
-				Payload: json.RawMessage(`{"result": {"value": 42}, "s3_info": {"s3_uri": "s3://test/result"}}`),
+			go func() {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				defer func() { _ = conn.Close() }()
+
+				if _, err := runtime.RecvSocketData(conn); err != nil {
+					return
+				}
+				response := `[{"payload": {"ok": true}, "route": {"actors": ["test-actor", "enrich", "post"], "current": 1}, ` + tt.response + `}]`
+				_ = runtime.SendSocketData(conn, []byte(response))
+			}()
+
+			cfg := &config.Config{
+				ActorName:     "test-actor",
+				HappyEndQueue: "happy-end",
+				ErrorEndQueue: "error-end",
+				TransportType: "rabbitmq",
+			}
+
+			mockTransport := &mockTransport{}
+			router := &Router{
+				cfg:           cfg,
+				transport:     mockTransport,
+				runtimeClient: runtime.NewClient(socketPath, 2*time.Second),
+				actorName:     cfg.ActorName,
+				happyEndQueue: cfg.HappyEndQueue,
+				errorEndQueue: cfg.ErrorEndQueue,
+			}
+
+			msgBody, _ := json.Marshal(envelopes.Envelope{
+				ID:      "test-123",
+				Route:   envelopes.Route{Actors: []string{"test-actor", "enrich", "post"}},
+				Payload: json.RawMessage(`{"input": "test"}`),
+			})
+
+			result, err := router.ProcessEnvelope(context.Background(), transport.QueueMessage{ID: "msg-1", Body: msgBody})
+			if err != nil || result != ProcessAcked {
+				t.Fatalf("ProcessEnvelope() = %v, %v, want %v", result, err, ProcessAcked)
+			}
+
+			if len(mockTransport.sentMessages) != 1 {
+				t.Fatalf("Expected 1 message, got %d", len(mockTransport.sentMessages))
+			}
+			if queue := mockTransport.sentMessages[0].queue; queue != tt.wantQueue {
+				t.Errorf("Message sent to %q, want %q", queue, tt.wantQueue)
+			}
+			if tt.wantError == "" {
+				return
+			}
+
+			var errorMsg struct {
+				Payload struct {
+					Error string `json:"error"`
+				} `json:"payload"`
+			}
+			if err := json.Unmarshal(mockTransport.sentMessages[0].body, &errorMsg); err != nil {
+				t.Fatalf("Failed to unmarshal error message: %v", err)
+			}
+			if errorMsg.Payload.Error != tt.wantError {
+				t.Errorf("error = %q, want %q", errorMsg.Payload.Error, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestRouter_EndActor_WithInvalidRoute(t *testing.T) {
+	tests := []struct {
+		name  string
+		route envelopes.Route
+		desc  string
+	}{
+		{
+			name: "current points to wrong actor",
+			route: envelopes.Route{
+				Actors:  []string{"test-echo"},
+				Current: 0,
+			},
+			desc: "Route points to test-echo but end actor is happy-end",
+		},
+		{
+			name: "current out of bounds",
+			route: envelopes.Route{
+				Actors:  []string{"test-echo"},
+				Current: 5,
+			},
+			desc: "Route current index is out of bounds",
+		},
+		{
+			name: "empty route",
+			route: envelopes.Route{
+				Actors:  []string{},
+				Current: 0,
+			},
+			desc: "Route has no actors",
+		},
+		{
+			name: "multi-actor route pointing elsewhere",
+			route: envelopes.Route{
+				Actors:  []string{"actor1", "actor2", "actor3"},
+				Current: 1,
+			},
+			desc: "Route points to actor2 but end actor is happy-end",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			socketPath := fmt.Sprintf("/tmp/test-end-invalid-%d.sock", time.Now().UnixNano())
+			defer func() { _ = os.Remove(socketPath) }()
+
+			listener, err := net.Listen("unix", socketPath)
+			if err != nil {
+				t.Fatalf("Failed to create socket: %v", err)
+			}
+			defer func() { _ = listener.Close() }()
+
+			go func() {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				defer func() { _ = conn.Close() }()
+
+				_, err = runtime.RecvSocketData(conn)
+				if err != nil {
+					return
+				}
+
+				responses := []runtime.RuntimeResponse{
+					{
+						Payload: json.RawMessage(`{"status": "processed"}`),
+					},
+				}
+				data, _ := json.Marshal(responses)
+				_ = runtime.SendSocketData(conn, data)
+			}()
+
+			cfg := &config.Config{
+				ActorName:     "happy-end",
+				HappyEndQueue: "happy-end",
+				ErrorEndQueue: "error-end",
+				TransportType: "rabbitmq",
+				IsEndActor:    true,
+			}
+
+			mockTransport := &mockTransport{}
+			runtimeClient := runtime.NewClient(socketPath, 2*time.Second)
+			m := metrics.NewMetrics("test", []config.CustomMetricConfig{})
+
+			router := &Router{
+				cfg:           cfg,
+				transport:     mockTransport,
+				runtimeClient: runtimeClient,
+				actorName:     cfg.ActorName,
+				happyEndQueue: cfg.HappyEndQueue,
+				errorEndQueue: cfg.ErrorEndQueue,
+				metrics:       m,
+			}
+
+			inputEnvelope := envelopes.Envelope{
+				ID:      "test-invalid-route",
+				Route:   tt.route,
+				Payload: json.RawMessage(`{"data": "test"}`),
+			}
+			msgBody, _ := json.Marshal(inputEnvelope)
+
+			queueMsg := transport.QueueMessage{
+				ID:   "msg-1",
+				Body: msgBody,
+			}
+
+			ctx := context.Background()
+			result, err := router.ProcessEnvelope(ctx, queueMsg)
+			if err != nil {
+				t.Fatalf("ProcessMessage failed for %s: %v", tt.desc, err)
+			}
+			if result != ProcessAcked {
+				t.Errorf("ProcessEnvelope result = %v, want %v", result, ProcessAcked)
+			}
+
+			if len(mockTransport.sentMessages) != 0 {
+				t.Errorf("End actor should not send any messages even with invalid route, got %d", len(mockTransport.sentMessages))
+			}
+		})
+	}
+}
+
+func TestRouter_EndActor_WithGatewayReporting(t *testing.T) {
+	mockServer := &mockHTTPServer{responses: make(map[string]mockHTTPResponse)}
+	mockServer.Start(t)
+	defer mockServer.Close()
+
+	socketPath := fmt.Sprintf("/tmp/test-end-gateway-%d.sock", time.Now().UnixNano())
+	defer func() { _ = os.Remove(socketPath) }()
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to create socket: %v", err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+
+		_, err = runtime.RecvSocketData(conn)
+		if err != nil {
+			return
+		}
+
+		responses := []runtime.RuntimeResponse{
+			{
+				Payload: json.RawMessage(`{"result": {"value": 42}, "s3_info": {"s3_uri": "s3://test/result"}}`),
 			},
 		}
 		data, _ := json.Marshal(responses)
@@ -1416,10 +1908,13 @@ func TestRouter_EndActor_WithGatewayReporting(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	err = router.ProcessEnvelope(ctx, queueMsg)
+	result, err := router.ProcessEnvelope(ctx, queueMsg)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
+	if result != ProcessAcked {
+		t.Errorf("ProcessEnvelope result = %v, want %v", result, ProcessAcked)
+	}
 
 	if len(mockTransport.sentMessages) != 0 {
 		t.Errorf("End actor should not send any messages, got %d", len(mockTransport.sentMessages))
@@ -1501,10 +1996,13 @@ func TestRouter_EndActor_RuntimeError(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	err = router.ProcessEnvelope(ctx, queueMsg)
+	result, err := router.ProcessEnvelope(ctx, queueMsg)
 	if err == nil {
 		t.Fatal("Expected error from end actor runtime failure")
 	}
+	if result != ProcessRequeue {
+		t.Errorf("ProcessEnvelope result = %v, want %v", result, ProcessRequeue)
+	}
 
 	if !strings.Contains(err.Error(), "runtime error in end actor") {
 		t.Errorf("Expected 'runtime error in end actor', got: %v", err)
@@ -1589,10 +2087,13 @@ func TestRouter_EndActor_DoesNotIncrementCurrent(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	err = router.ProcessEnvelope(ctx, queueMsg)
+	result, err := router.ProcessEnvelope(ctx, queueMsg)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
+	if result != ProcessAcked {
+		t.Errorf("ProcessEnvelope result = %v, want %v", result, ProcessAcked)
+	}
 
 	// End actors must NOT send any messages (no routing, no increment)
 	if len(mockTransport.sentMessages) != 0 {
@@ -1655,10 +2156,13 @@ func TestRouter_ProcessMessage_RuntimeError(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	err = router.ProcessEnvelope(ctx, queueMsg)
+	result, err := router.ProcessEnvelope(ctx, queueMsg)
 	if err != nil {
 		t.Fatalf("ProcessMessage should not return error (sends to error queue): %v", err)
 	}
+	if result != ProcessAcked {
+		t.Errorf("ProcessEnvelope result = %v, want %v", result, ProcessAcked)
+	}
 
 	if len(mockTransport.sentMessages) != 1 {
 		t.Fatalf("Expected 1 message sent to error queue, got %d", len(mockTransport.sentMessages))
@@ -1741,10 +2245,13 @@ func TestRouter_ProcessMessage_ErrorResponse(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	err = router.ProcessEnvelope(ctx, queueMsg)
+	result, err := router.ProcessEnvelope(ctx, queueMsg)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
+	if result != ProcessAcked {
+		t.Errorf("ProcessEnvelope result = %v, want %v", result, ProcessAcked)
+	}
 
 	if len(mockTransport.sentMessages) != 1 {
 		t.Fatalf("Expected 1 message sent to error queue, got %d", len(mockTransport.sentMessages))
@@ -1962,10 +2469,13 @@ func TestRouter_ReportFinalStatusWithEnvelope_ErrorEnd_ExtractsErrorDetails(t *t
 	}
 
 	ctx := context.Background()
-	err = router.ProcessEnvelope(ctx, queueMsg)
+	result, err := router.ProcessEnvelope(ctx, queueMsg)
 	if err != nil {
 		t.Fatalf("ProcessEnvelope failed: %v", err)
 	}
+	if result != ProcessAcked {
+		t.Errorf("ProcessEnvelope result = %v, want %v", result, ProcessAcked)
+	}
 
 	expectedPath := "/envelopes/test-error-details-789/final"
 	req := mockServer.GetRequest(expectedPath)
@@ -2078,10 +2588,13 @@ func TestRouter_ReportFinalStatusWithEnvelope_ErrorEnd_NoErrorDetails(t *testing
 	}
 
 	ctx := context.Background()
-	err = router.ProcessEnvelope(ctx, queueMsg)
+	result, err := router.ProcessEnvelope(ctx, queueMsg)
 	if err != nil {
 		t.Fatalf("ProcessEnvelope failed: %v", err)
 	}
+	if result != ProcessAcked {
+		t.Errorf("ProcessEnvelope result = %v, want %v", result, ProcessAcked)
+	}
 
 	expectedPath := "/envelopes/test-no-error-details/final"
 	req := mockServer.GetRequest(expectedPath)
@@ -2208,10 +2721,13 @@ func TestRouter_ProcessMessage_FanOut(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	err = router.ProcessEnvelope(ctx, queueMsg)
+	result, err := router.ProcessEnvelope(ctx, queueMsg)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
+	if result != ProcessAcked {
+		t.Errorf("ProcessEnvelope result = %v, want %v", result, ProcessAcked)
+	}
 
 	if len(mockTransport.sentMessages) != 3 {
 		t.Fatalf("Expected 3 fan-out messages, got %d", len(mockTransport.sentMessages))
@@ -2253,6 +2769,10 @@ func TestRouter_ProcessMessage_FanOut(t *testing.T) {
 			}
 		}
 
+		if envelope.BranchIndex != i {
+			t.Errorf("Message %d has branch_index %d, expected %d", i, envelope.BranchIndex, i)
+		}
+
 		if envelope.Route.Current != 1 {
 			t.Errorf("Message %d route.current = %d, expected 1", i, envelope.Route.Current)
 		}
@@ -2310,32 +2830,43 @@ func TestRouter_ProcessMessage_FanOut_CreatesGatewayEnvelopes(t *testing.T) {
 
 	// Track envelope creation calls
 	var createdEnvelopes []struct {
-		id       string
-		parentID string
-		actors   []string
-		current  int
+		id          string
+		parentID    string
+		branchIndex int
+		branches    int
+		actors      []string
+		current     int
 	}
 	createEnvelopeCalled := 0
+	mockTransport := &mockTransport{}
 
 	// Mock HTTP server for gateway
 	gatewayServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/envelopes" && r.Method == http.MethodPost {
 			createEnvelopeCalled++
 			var req struct {
-				ID       string   `json:"id"`
-				ParentID string   `json:"parent_id"`
-				Actors   []string `json:"actors"`
-				Current  int      `json:"current"`
+				ID          string   `json:"id"`
+				ParentID    string   `json:"parent_id"`
+				BranchIndex int      `json:"branch_index"`
+				Branches    int      `json:"fanout_branches"`
+				Actors      []string `json:"actors"`
+				Current     int      `json:"current"`
 			}
 			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 				t.Errorf("Failed to decode envelope create request: %v", err)
 			}
+			// Children must be known to the gateway before the parent's branch can complete
+			if len(mockTransport.sentMessages) > 0 {
+				t.Errorf("Envelope %s created after %d branches were routed", req.ID, len(mockTransport.sentMessages))
+			}
 			createdEnvelopes = append(createdEnvelopes, struct {
-				id       string
-				parentID string
-				actors   []string
-				current  int
-			}{req.ID, req.ParentID, req.Actors, req.Current})
+				id          string
+				parentID    string
+				branchIndex int
+				branches    int
+				actors      []string
+				current     int
+			}{req.ID, req.ParentID, req.BranchIndex, req.Branches, req.Actors, req.Current})
 			w.WriteHeader(http.StatusCreated)
 			_ = json.NewEncoder(w).Encode(map[string]string{"status": "created"})
 		} else {
@@ -2352,7 +2883,6 @@ func TestRouter_ProcessMessage_FanOut_CreatesGatewayEnvelopes(t *testing.T) {
 		GatewayURL:    gatewayServer.URL,
 	}
 
-	mockTransport := &mockTransport{}
 	runtimeClient := runtime.NewClient(socketPath, 2*time.Second)
 	progressReporter := progress.NewReporter(gatewayServer.URL, cfg.ActorName)
 	m := metrics.NewMetrics("test", []config.CustomMetricConfig{})
@@ -2385,10 +2915,13 @@ func TestRouter_ProcessMessage_FanOut_CreatesGatewayEnvelopes(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	err = router.ProcessEnvelope(ctx, queueMsg)
+	result, err := router.ProcessEnvelope(ctx, queueMsg)
 	if err != nil {
 		t.Fatalf("ProcessEnvelope failed: %v", err)
 	}
+	if result != ProcessAcked {
+		t.Errorf("ProcessEnvelope result = %v, want %v", result, ProcessAcked)
+	}
 
 	// Verify envelope creation was called for fanout children (indices 1 and 2)
 	expectedCalls := 2
@@ -2408,6 +2941,12 @@ func TestRouter_ProcessMessage_FanOut_CreatesGatewayEnvelopes(t *testing.T) {
 	if createdEnvelopes[0].parentID != "test-fanout-456" {
 		t.Errorf("First envelope ParentID = %q, want test-fanout-456", createdEnvelopes[0].parentID)
 	}
+	if createdEnvelopes[0].branchIndex != 1 {
+		t.Errorf("First envelope BranchIndex = %d, want 1", createdEnvelopes[0].branchIndex)
+	}
+	if createdEnvelopes[0].branches != 3 {
+		t.Errorf("First envelope FanoutBranches = %d, want 3", createdEnvelopes[0].branches)
+	}
 
 	// Second fanout child (index 2)
 	if createdEnvelopes[1].id != "test-fanout-456-2" {
@@ -2416,20 +2955,54 @@ func TestRouter_ProcessMessage_FanOut_CreatesGatewayEnvelopes(t *testing.T) {
 	if createdEnvelopes[1].parentID != "test-fanout-456" {
 		t.Errorf("Second envelope ParentID = %q, want test-fanout-456", createdEnvelopes[1].parentID)
 	}
+	if createdEnvelopes[1].branchIndex != 2 {
+		t.Errorf("Second envelope BranchIndex = %d, want 2", createdEnvelopes[1].branchIndex)
+	}
 }
 
-func TestRouter_CheckGatewayHealth_Success(t *testing.T) {
-	healthCheckCalled := false
+func TestRouter_ProcessMessage_ReportsPartialResults(t *testing.T) {
+	socketPath := fmt.Sprintf("/tmp/test-partial-results-%d.sock", time.Now().UnixNano())
+	defer func() { _ = os.Remove(socketPath) }()
 
-	// Mock HTTP server for gateway
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to create socket: %v", err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	// Runtime whose handler emits two partial results before returning
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+
+		if _, err := runtime.RecvSocketData(conn); err != nil {
+			return
+		}
+		_ = runtime.SendSocketData(conn, []byte(`{"partial_result":{"text":"Hel"}}`))
+		_ = runtime.SendSocketData(conn, []byte(`{"partial_result":{"text":"Hello"}}`))
+		responses, _ := json.Marshal([]runtime.RuntimeResponse{{
+			Route:   envelopes.Route{Actors: []string{"test-actor", "next-actor"}, Current: 1},
+			Payload: json.RawMessage(`{"text":"Hello!"}`),
+		}})
+		_ = runtime.SendSocketData(conn, responses)
+	}()
+
+	var mu sync.Mutex
+	var partials []string
 	gatewayServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/health" && r.Method == http.MethodGet {
-			healthCheckCalled = true
-			w.WriteHeader(http.StatusOK)
-			_, _ = w.Write([]byte("OK"))
-		} else {
-			w.WriteHeader(http.StatusNotFound)
+		var update progress.ProgressUpdate
+		if strings.HasSuffix(r.URL.Path, "/progress") && json.NewDecoder(r.Body).Decode(&update) == nil && update.PartialResult != nil {
+			if update.Status != progress.StatusProcessing {
+				t.Errorf("Partial result reported with status %q, want %q", update.Status, progress.StatusProcessing)
+			}
+			mu.Lock()
+			partials = append(partials, string(update.PartialResult))
+			mu.Unlock()
 		}
+		w.WriteHeader(http.StatusOK)
 	}))
 	defer gatewayServer.Close()
 
@@ -2440,21 +3013,264 @@ func TestRouter_CheckGatewayHealth_Success(t *testing.T) {
 		TransportType: "rabbitmq",
 		GatewayURL:    gatewayServer.URL,
 	}
-
 	mockTransport := &mockTransport{}
-	runtimeClient := runtime.NewClient("/tmp/test.sock", 2*time.Second)
-	m := metrics.NewMetrics("test", []config.CustomMetricConfig{})
-
-	router := NewRouter(cfg, mockTransport, runtimeClient, m)
-
-	ctx := context.Background()
-	err := router.CheckGatewayHealth(ctx)
+	router := &Router{
+		cfg:              cfg,
+		transport:        mockTransport,
+		runtimeClient:    runtime.NewClient(socketPath, 2*time.Second),
+		actorName:        cfg.ActorName,
+		happyEndQueue:    cfg.HappyEndQueue,
+		errorEndQueue:    cfg.ErrorEndQueue,
+		gatewayURL:       cfg.GatewayURL,
+		progressReporter: progress.NewReporter(gatewayServer.URL, cfg.ActorName),
+	}
 
+	msgBody, _ := json.Marshal(envelopes.Envelope{
+		ID:      "test-partial-1",
+		Route:   envelopes.Route{Actors: []string{"test-actor", "next-actor"}, Current: 0},
+		Payload: json.RawMessage(`{"prompt":"greet"}`),
+	})
+	result, err := router.ProcessEnvelope(context.Background(), transport.QueueMessage{ID: "msg-1", Body: msgBody})
 	if err != nil {
-		t.Errorf("CheckGatewayHealth returned error: %v", err)
+		t.Fatalf("ProcessEnvelope failed: %v", err)
+	}
+	if result != ProcessAcked {
+		t.Errorf("ProcessEnvelope result = %v, want %v", result, ProcessAcked)
 	}
 
-	if !healthCheckCalled {
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{`{"text":"Hel"}`, `{"text":"Hello"}`}; !reflect.DeepEqual(partials, want) {
+		t.Errorf("Partial results reported to the gateway = %v, want %v", partials, want)
+	}
+	if len(mockTransport.sentMessages) != 1 || mockTransport.sentMessages[0].queue != "asya-next-actor" {
+		t.Errorf("Expected the final response routed to asya-next-actor, got %+v", mockTransport.sentMessages)
+	}
+}
+
+func TestRouter_ProcessMessage_ProgressDisabled(t *testing.T) {
+	socketPath := fmt.Sprintf("/tmp/test-progress-disabled-%d.sock", time.Now().UnixNano())
+	defer func() { _ = os.Remove(socketPath) }()
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to create socket: %v", err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+
+		if _, err := runtime.RecvSocketData(conn); err != nil {
+			return
+		}
+
+		route := envelopes.Route{Actors: []string{"test-actor", "next-actor"}, Current: 1}
+		data, _ := json.Marshal([]runtime.RuntimeResponse{
+			{Route: route, Payload: json.RawMessage(`{"index": 0}`)},
+			{Route: route, Payload: json.RawMessage(`{"index": 1}`)},
+		})
+		_ = runtime.SendSocketData(conn, data)
+	}()
+
+	var createCalls atomic.Int32
+	var statusesMu sync.Mutex
+	var statuses []progress.ProgressStatus
+	gatewayServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/envelopes":
+			createCalls.Add(1)
+			w.WriteHeader(http.StatusCreated)
+		case strings.HasSuffix(r.URL.Path, "/progress"):
+			var update progress.ProgressUpdate
+			_ = json.NewDecoder(r.Body).Decode(&update)
+			statusesMu.Lock()
+			statuses = append(statuses, update.Status)
+			statusesMu.Unlock()
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer gatewayServer.Close()
+
+	cfg := &config.Config{
+		ActorName:        "test-actor",
+		HappyEndQueue:    "happy-end",
+		ErrorEndQueue:    "error-end",
+		TransportType:    "rabbitmq",
+		GatewayURL:       gatewayServer.URL,
+		ProgressDisabled: true,
+	}
+	router := NewRouter(cfg, &mockTransport{}, runtime.NewClient(socketPath, 2*time.Second), nil)
+
+	msgBody, _ := json.Marshal(envelopes.Envelope{
+		ID:      "test-no-progress",
+		Route:   envelopes.Route{Actors: []string{"test-actor", "next-actor"}},
+		Payload: json.RawMessage(`{}`),
+	})
+	result, err := router.ProcessEnvelope(context.Background(), transport.QueueMessage{ID: "msg-1", Body: msgBody})
+	if err != nil || result != ProcessAcked {
+		t.Fatalf("ProcessEnvelope() = %v, %v, want %v", result, err, ProcessAcked)
+	}
+
+	// Only the intermediate processing update is skipped, status transitions are still reported
+	statusesMu.Lock()
+	defer statusesMu.Unlock()
+	want := []progress.ProgressStatus{progress.StatusReceived, progress.StatusCompleted}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("progress updates sent = %v, want %v", statuses, want)
+	}
+	// Fanout envelopes are still created so the gateway can aggregate the branches
+	if got := createCalls.Load(); got != 1 {
+		t.Errorf("fanout envelopes created = %d, want 1", got)
+	}
+}
+
+func TestRouter_HandleSuccessResponse_PreservesFanoutPosition(t *testing.T) {
+	cfg := &config.Config{
+		ActorName:     "test-actor",
+		HappyEndQueue: "happy-end",
+		ErrorEndQueue: "error-end",
+		TransportType: "rabbitmq",
+	}
+
+	mockTransport := &mockTransport{}
+	router := &Router{
+		cfg:           cfg,
+		transport:     mockTransport,
+		actorName:     cfg.ActorName,
+		happyEndQueue: cfg.HappyEndQueue,
+		errorEndQueue: cfg.ErrorEndQueue,
+	}
+
+	parentID := "test-fanout-789"
+	inputEnvelope := &envelopes.Envelope{
+		ID:          "test-fanout-789-2",
+		ParentID:    &parentID,
+		BranchIndex: 2,
+		Tool:        "summarize",
+		Route:       envelopes.Route{Actors: []string{"test-actor", "next-actor"}, Current: 0},
+		Payload:     json.RawMessage(`{}`),
+	}
+	response := runtime.RuntimeResponse{
+		Route:   envelopes.Route{Actors: []string{"test-actor", "next-actor"}, Current: 1},
+		Payload: json.RawMessage(`{"ok": true}`),
+	}
+
+	if err := router.handleSuccessResponse(context.Background(), inputEnvelope, response, 0, 1, time.Millisecond); err != nil {
+		t.Fatalf("handleSuccessResponse failed: %v", err)
+	}
+
+	if len(mockTransport.sentMessages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(mockTransport.sentMessages))
+	}
+
+	var envelope envelopes.Envelope
+	if err := json.Unmarshal(mockTransport.sentMessages[0].body, &envelope); err != nil {
+		t.Fatalf("Failed to unmarshal message: %v", err)
+	}
+	if envelope.ID != "test-fanout-789-2" {
+		t.Errorf("ID = %q, want test-fanout-789-2", envelope.ID)
+	}
+	if envelope.ParentID == nil || *envelope.ParentID != parentID {
+		t.Errorf("ParentID = %v, want %q", envelope.ParentID, parentID)
+	}
+	if envelope.BranchIndex != 2 {
+		t.Errorf("BranchIndex = %d, want 2", envelope.BranchIndex)
+	}
+	if envelope.Tool != "summarize" {
+		t.Errorf("Tool = %q, want summarize", envelope.Tool)
+	}
+}
+
+func TestRouter_HandleSuccessResponse_PreservesRouteMetadata(t *testing.T) {
+	cfg := &config.Config{
+		ActorName:     "test-actor",
+		HappyEndQueue: "happy-end",
+		ErrorEndQueue: "error-end",
+		TransportType: "rabbitmq",
+	}
+
+	mockTransport := &mockTransport{}
+	router := &Router{
+		cfg:           cfg,
+		transport:     mockTransport,
+		actorName:     cfg.ActorName,
+		happyEndQueue: cfg.HappyEndQueue,
+		errorEndQueue: cfg.ErrorEndQueue,
+	}
+
+	metadata := map[string]interface{}{"job_id": "test-metadata-1", "user_id": "user-42"}
+	inputEnvelope := &envelopes.Envelope{
+		ID:      "test-metadata-1",
+		Route:   envelopes.Route{Actors: []string{"test-actor", "next-actor"}, Current: 0, Metadata: metadata},
+		Payload: json.RawMessage(`{}`),
+	}
+	// Envelope mode handler building a new route without the metadata
+	response := runtime.RuntimeResponse{
+		Route:   envelopes.Route{Actors: []string{"test-actor", "next-actor"}, Current: 1},
+		Payload: json.RawMessage(`{"ok": true}`),
+	}
+
+	if err := router.handleSuccessResponse(context.Background(), inputEnvelope, response, 0, 1, time.Millisecond); err != nil {
+		t.Fatalf("handleSuccessResponse failed: %v", err)
+	}
+
+	if len(mockTransport.sentMessages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(mockTransport.sentMessages))
+	}
+
+	var envelope envelopes.Envelope
+	if err := json.Unmarshal(mockTransport.sentMessages[0].body, &envelope); err != nil {
+		t.Fatalf("Failed to unmarshal message: %v", err)
+	}
+	if !reflect.DeepEqual(envelope.Route.Metadata, metadata) {
+		t.Errorf("Route.Metadata = %v, want %v", envelope.Route.Metadata, metadata)
+	}
+}
+
+func TestRouter_CheckGatewayHealth_Success(t *testing.T) {
+	healthCheckCalled := false
+
+	// Mock HTTP server for gateway
+	gatewayServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" && r.Method == http.MethodGet {
+			healthCheckCalled = true
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("OK"))
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer gatewayServer.Close()
+
+	cfg := &config.Config{
+		ActorName:     "test-actor",
+		HappyEndQueue: "happy-end",
+		ErrorEndQueue: "error-end",
+		TransportType: "rabbitmq",
+		GatewayURL:    gatewayServer.URL,
+	}
+
+	mockTransport := &mockTransport{}
+	runtimeClient := runtime.NewClient("/tmp/test.sock", 2*time.Second)
+	m := metrics.NewMetrics("test", []config.CustomMetricConfig{})
+
+	router := NewRouter(cfg, mockTransport, runtimeClient, m)
+
+	ctx := context.Background()
+	err := router.CheckGatewayHealth(ctx)
+
+	if err != nil {
+		t.Errorf("CheckGatewayHealth returned error: %v", err)
+	}
+
+	if !healthCheckCalled {
 		t.Error("Health check was not called")
 	}
 }
@@ -2537,3 +3353,873 @@ func TestRouter_CheckGatewayHealth_NetworkError(t *testing.T) {
 		t.Error("CheckGatewayHealth should return error for network failure")
 	}
 }
+
+// multiQueueTransport records receives per queue and panics on receive from failQueue
+type multiQueueTransport struct {
+	mockTransport
+	mu        sync.Mutex
+	receives  map[string]int
+	cancels   []int // Receives per queue when consumers were cancelled
+	failQueue string
+}
+
+func (m *multiQueueTransport) CancelConsumers(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cancels = append(m.cancels, m.receives["asya-test-actor"])
+	return nil
+}
+
+func (m *multiQueueTransport) Receive(ctx context.Context, queueName string) (transport.QueueMessage, error) {
+	m.mu.Lock()
+	m.receives[queueName]++
+	m.mu.Unlock()
+
+	if queueName == m.failQueue {
+		panic("consumer failure")
+	}
+
+	// Healthy queues stay idle until shutdown
+	<-ctx.Done()
+	return transport.QueueMessage{}, ctx.Err()
+}
+
+func (m *multiQueueTransport) receiveCount(queueName string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.receives[queueName]
+}
+
+func TestRouter_Run_MultipleQueues(t *testing.T) {
+	cfg := &config.Config{
+		ActorName:     "test-actor",
+		HappyEndQueue: "happy-end",
+		ErrorEndQueue: "error-end",
+		TransportType: "rabbitmq",
+		QueueNames:    []string{"asya-source-a", "asya-source-b"},
+	}
+
+	mt := &multiQueueTransport{receives: make(map[string]int), failQueue: "asya-source-b"}
+	router := &Router{
+		cfg:           cfg,
+		transport:     mt,
+		actorName:     cfg.ActorName,
+		happyEndQueue: cfg.HappyEndQueue,
+		errorEndQueue: cfg.ErrorEndQueue,
+		metrics:       metrics.NewMetrics("test", []config.CustomMetricConfig{}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- router.Run(ctx) }()
+
+	// Wait until both queues have been polled
+	deadline := time.Now().Add(2 * time.Second)
+	for mt.receiveCount("asya-source-a") == 0 || mt.receiveCount("asya-source-b") == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for both queues to be consumed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// The failing consumer must not stop the router or the healthy consumer
+	select {
+	case err := <-done:
+		t.Fatalf("Run returned after a single consumer failed: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("Expected context.Canceled error, got: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after context cancellation")
+	}
+}
+
+func TestRouter_Run_IdleTimeout(t *testing.T) {
+	cfg := &config.Config{
+		ActorName:     "test-actor",
+		HappyEndQueue: "happy-end",
+		ErrorEndQueue: "error-end",
+		TransportType: "rabbitmq",
+		IdleTimeout:   50 * time.Millisecond,
+	}
+
+	mt := &multiQueueTransport{receives: make(map[string]int)}
+	router := &Router{
+		cfg:           cfg,
+		transport:     mt,
+		actorName:     cfg.ActorName,
+		happyEndQueue: cfg.HappyEndQueue,
+		errorEndQueue: cfg.ErrorEndQueue,
+		metrics:       metrics.NewMetrics("test", []config.CustomMetricConfig{}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- router.Run(ctx) }()
+
+	// Idle consumer is stopped, then resumed after the idle pause with a new Receive
+	deadline := time.Now().Add(2 * time.Second)
+	for mt.receiveCount("asya-test-actor") < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected consumer to pause and resume, got %d receives", mt.receiveCount("asya-test-actor"))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Broker consumers are cancelled when pausing, before consumption resumes
+	mt.mu.Lock()
+	cancels := append([]int(nil), mt.cancels...)
+	mt.mu.Unlock()
+	if len(cancels) == 0 || cancels[0] != 1 {
+		t.Errorf("Expected consumers to be cancelled after the first receive, got cancels at %v", cancels)
+	}
+
+	select {
+	case err := <-done:
+		t.Fatalf("Run returned while idle: %v", err)
+	default:
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("Expected context.Canceled error, got: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after context cancellation")
+	}
+}
+
+// queuedTransport delivers the given messages, then waits for shutdown, and counts acks
+type queuedTransport struct {
+	mockTransport
+	messages chan transport.QueueMessage
+	mu       sync.Mutex
+	acked    int
+}
+
+func (q *queuedTransport) Receive(ctx context.Context, queueName string) (transport.QueueMessage, error) {
+	select {
+	case msg := <-q.messages:
+		return msg, nil
+	case <-ctx.Done():
+		return transport.QueueMessage{}, ctx.Err()
+	}
+}
+
+func (q *queuedTransport) Send(ctx context.Context, queueName string, body []byte) error {
+	return nil
+}
+
+func (q *queuedTransport) Ack(ctx context.Context, msg transport.QueueMessage) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.acked++
+	return nil
+}
+
+func (q *queuedTransport) ackCount() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.acked
+}
+
+func TestRouter_Run_Concurrency(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		wantMax     int32
+	}{
+		{name: "one envelope at a time by default", concurrency: 0, wantMax: 1},
+		{name: "envelopes processed concurrently", concurrency: 3, wantMax: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			socketPath := fmt.Sprintf("/tmp/test-concurrency-%d.sock", time.Now().UnixNano())
+			defer func() { _ = os.Remove(socketPath) }()
+
+			listener, err := net.Listen("unix", socketPath)
+			if err != nil {
+				t.Fatalf("Failed to create socket: %v", err)
+			}
+			defer func() { _ = listener.Close() }()
+
+			// Runtime serving requests concurrently, recording how many overlap
+			var running, maxRunning atomic.Int32
+			go func() {
+				for {
+					conn, err := listener.Accept()
+					if err != nil {
+						return
+					}
+					go func() {
+						defer func() { _ = conn.Close() }()
+						if _, err := runtime.RecvSocketData(conn); err != nil {
+							return
+						}
+						n := running.Add(1)
+						for {
+							current := maxRunning.Load()
+							if n <= current || maxRunning.CompareAndSwap(current, n) {
+								break
+							}
+						}
+						time.Sleep(100 * time.Millisecond)
+						running.Add(-1)
+						_ = runtime.SendSocketData(conn, []byte(`[{"route":{"actors":["test-actor"],"current":1},"payload":{}}]`))
+					}()
+				}
+			}()
+
+			const messages = 6
+			qt := &queuedTransport{messages: make(chan transport.QueueMessage, messages)}
+			for i := range messages {
+				body, _ := json.Marshal(envelopes.Envelope{
+					ID:      fmt.Sprintf("env-%d", i),
+					Route:   envelopes.Route{Actors: []string{"test-actor"}},
+					Payload: json.RawMessage(`{}`),
+				})
+				qt.messages <- transport.QueueMessage{ID: fmt.Sprintf("msg-%d", i), Body: body}
+			}
+
+			cfg := &config.Config{
+				ActorName:     "test-actor",
+				HappyEndQueue: "happy-end",
+				ErrorEndQueue: "error-end",
+				TransportType: "rabbitmq",
+				Concurrency:   tt.concurrency,
+			}
+			router := &Router{
+				cfg:           cfg,
+				transport:     qt,
+				runtimeClient: runtime.NewClient(socketPath, 2*time.Second),
+				actorName:     cfg.ActorName,
+				happyEndQueue: cfg.HappyEndQueue,
+				errorEndQueue: cfg.ErrorEndQueue,
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() { done <- router.Run(ctx) }()
+
+			deadline := time.Now().Add(5 * time.Second)
+			for qt.ackCount() < messages {
+				if time.Now().After(deadline) {
+					t.Fatalf("Timed out with %d of %d messages acked", qt.ackCount(), messages)
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+			cancel()
+			<-done
+
+			if got := maxRunning.Load(); got != tt.wantMax {
+				t.Errorf("Envelopes processed at once = %d, want %d", got, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestRouter_RuntimeTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		timeout    time.Duration
+		maxTimeout time.Duration
+		override   int
+		want       time.Duration
+	}{
+		{name: "no override uses default", timeout: time.Minute, maxTimeout: 10 * time.Minute, override: 0, want: time.Minute},
+		{name: "override within max", timeout: time.Minute, maxTimeout: 10 * time.Minute, override: 300, want: 5 * time.Minute},
+		{name: "override shorter than default", timeout: time.Minute, maxTimeout: 10 * time.Minute, override: 10, want: 10 * time.Second},
+		{name: "override capped at max", timeout: time.Minute, maxTimeout: 10 * time.Minute, override: 3600, want: 10 * time.Minute},
+		{name: "unset max caps at default", timeout: time.Minute, maxTimeout: 0, override: 3600, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := &Router{cfg: &config.Config{Timeout: tt.timeout, MaxProcessingTimeout: tt.maxTimeout}}
+			envelope := &envelopes.Envelope{ID: "test-timeout", TimeoutOverrideSeconds: tt.override}
+
+			if got := router.runtimeTimeout(context.Background(), envelope); got != tt.want {
+				t.Errorf("runtimeTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouter_RuntimeTimeout_CapLogCarriesJobID(t *testing.T) {
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(logging.NewHandler(slog.NewTextHandler(&logs, nil))))
+	defer slog.SetDefault(defaultLogger)
+
+	router := &Router{cfg: &config.Config{Timeout: time.Minute, MaxProcessingTimeout: 10 * time.Minute}}
+	envelope := &envelopes.Envelope{ID: "test-timeout", TimeoutOverrideSeconds: 3600}
+	router.runtimeTimeout(logging.ContextWithJobID(context.Background(), "job-1"), envelope)
+
+	if line := logs.String(); !strings.Contains(line, "capping") || !strings.Contains(line, "job_id=job-1") {
+		t.Errorf("cap warning %q should carry job_id=job-1", line)
+	}
+}
+
+func TestRouter_TimeoutOverrideKeptOnNextHop(t *testing.T) {
+	cfg := &config.Config{
+		ActorName:     "test-actor",
+		HappyEndQueue: "happy-end",
+		ErrorEndQueue: "error-end",
+		TransportType: "rabbitmq",
+	}
+
+	mockTransport := &mockTransport{}
+	router := &Router{
+		cfg:           cfg,
+		transport:     mockTransport,
+		actorName:     cfg.ActorName,
+		happyEndQueue: cfg.HappyEndQueue,
+		errorEndQueue: cfg.ErrorEndQueue,
+	}
+
+	inputEnvelope := &envelopes.Envelope{
+		ID:                     "test-timeout-hop",
+		Route:                  envelopes.Route{Actors: []string{"test-actor", "post"}, Current: 0},
+		Payload:                json.RawMessage(`{}`),
+		TimeoutOverrideSeconds: 600,
+	}
+	response := runtime.RuntimeResponse{
+		Route:   envelopes.Route{Actors: []string{"test-actor", "post"}, Current: 1},
+		Payload: json.RawMessage(`{"ok": true}`),
+	}
+
+	if err := router.handleSuccessResponse(context.Background(), inputEnvelope, response, 0, 1, time.Millisecond); err != nil {
+		t.Fatalf("handleSuccessResponse failed: %v", err)
+	}
+
+	if len(mockTransport.sentMessages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(mockTransport.sentMessages))
+	}
+	var envelope envelopes.Envelope
+	if err := json.Unmarshal(mockTransport.sentMessages[0].body, &envelope); err != nil {
+		t.Fatalf("Failed to unmarshal message: %v", err)
+	}
+	if envelope.TimeoutOverrideSeconds != 600 {
+		t.Errorf("routed TimeoutOverrideSeconds = %d, want 600", envelope.TimeoutOverrideSeconds)
+	}
+}
+
+// TestRouter_TraceIDKeptAcrossHops verifies that the trace_id header linking duration metrics
+// to a trace survives two routing hops, fanout children and the error-end message
+func TestRouter_TraceIDKeptAcrossHops(t *testing.T) {
+	newRouter := func(actor string) (*Router, *mockTransport) {
+		tp := &mockTransport{}
+		return &Router{
+			cfg:           &config.Config{ActorName: actor, HappyEndQueue: "happy-end", ErrorEndQueue: "error-end", TransportType: "rabbitmq"},
+			transport:     tp,
+			actorName:     actor,
+			happyEndQueue: "happy-end",
+			errorEndQueue: "error-end",
+		}, tp
+	}
+	sent := func(t *testing.T, tp *mockTransport, i int) envelopes.Envelope {
+		t.Helper()
+		var envelope envelopes.Envelope
+		if err := json.Unmarshal(tp.sentMessages[i].body, &envelope); err != nil {
+			t.Fatalf("Failed to unmarshal message: %v", err)
+		}
+		return envelope
+	}
+	actors := []string{"prep", "infer", "post"}
+
+	// First hop: prep fans out to infer
+	prep, prepTransport := newRouter("prep")
+	first := &envelopes.Envelope{
+		ID:      "trace-hops",
+		Route:   envelopes.Route{Actors: actors, Current: 0},
+		Headers: map[string]interface{}{"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736"},
+		Payload: json.RawMessage(`{}`),
+	}
+	response := runtime.RuntimeResponse{Route: envelopes.Route{Actors: actors, Current: 1}, Payload: json.RawMessage(`{}`)}
+	for i := range 2 {
+		if err := prep.handleSuccessResponse(context.Background(), first, response, i, 2, time.Millisecond); err != nil {
+			t.Fatalf("handleSuccessResponse(%d) failed: %v", i, err)
+		}
+	}
+	for i := range 2 {
+		if got := metrics.TraceIDFromHeaders(sent(t, prepTransport, i).Headers); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("hop 1 message %d trace ID = %q, want the received one", i, got)
+		}
+	}
+
+	// Second hop: infer routes the first branch on to post
+	infer, inferTransport := newRouter("infer")
+	second := sent(t, prepTransport, 0)
+	response.Route.Current = 2
+	if err := infer.handleSuccessResponse(context.Background(), &second, response, 0, 1, time.Millisecond); err != nil {
+		t.Fatalf("handleSuccessResponse failed: %v", err)
+	}
+	if got := metrics.TraceIDFromHeaders(sent(t, inferTransport, 0).Headers); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("hop 2 trace ID = %q, want the one of the first hop", got)
+	}
+
+	// A failing second hop reports to error-end with the trace ID too
+	body := prepTransport.sentMessages[1].body
+	if err := infer.sendToErrorQueue(context.Background(), body, "boom"); err != nil {
+		t.Fatalf("sendToErrorQueue failed: %v", err)
+	}
+	if got := metrics.TraceIDFromHeaders(sent(t, inferTransport, 1).Headers); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("error-end trace ID = %q, want the one of the first hop", got)
+	}
+}
+
+// nackTransport counts NACKs
+type nackTransport struct {
+	mockTransport
+	nacks int
+}
+
+func (m *nackTransport) Nack(ctx context.Context, msg transport.QueueMessage) error {
+	m.nacks++
+	return nil
+}
+
+// retryTransport implements transport.Retrier and records delayed retries and dead-lettering
+type retryTransport struct {
+	nackTransport
+	retryErr    error
+	delays      []time.Duration
+	attempts    []int
+	deadLetters int
+}
+
+func (m *retryTransport) Retry(ctx context.Context, msg transport.QueueMessage, delay time.Duration, attempt int) error {
+	if m.retryErr != nil {
+		return m.retryErr
+	}
+	m.delays = append(m.delays, delay)
+	m.attempts = append(m.attempts, attempt)
+	return nil
+}
+
+func (m *retryTransport) DeadLetter(ctx context.Context, msg transport.QueueMessage) error {
+	m.deadLetters++
+	return nil
+}
+
+func TestRouter_RetryFailed(t *testing.T) {
+	schedule := []time.Duration{10 * time.Second, time.Minute, 5 * time.Minute}
+
+	tests := []struct {
+		name            string
+		schedule        []time.Duration
+		attemptHeader   string
+		deliveries      string
+		maxDeliveries   int
+		retryErr        error
+		wantDelay       time.Duration
+		wantAttempt     int
+		wantNacks       int
+		wantDeadLetters int
+	}{
+		{name: "no schedule requeues immediately", schedule: nil, wantNacks: 1},
+		{name: "first failure", schedule: schedule, wantDelay: 10 * time.Second, wantAttempt: 1},
+		{name: "second failure", schedule: schedule, attemptHeader: "1", wantDelay: time.Minute, wantAttempt: 2},
+		{name: "last rung", schedule: schedule, attemptHeader: "2", wantDelay: 5 * time.Minute, wantAttempt: 3},
+		{name: "ladder exhausted", schedule: schedule, attemptHeader: "3", wantDeadLetters: 1},
+		{name: "malformed attempt header starts over", schedule: schedule, attemptHeader: "x", wantDelay: 10 * time.Second, wantAttempt: 1},
+		{name: "retry publish failure falls back to nack", schedule: schedule, retryErr: fmt.Errorf("publish failed"), wantNacks: 1},
+		{name: "below delivery limit requeues", deliveries: "2", maxDeliveries: 3, wantNacks: 1},
+		{name: "delivery limit reached", deliveries: "3", maxDeliveries: 3, wantDeadLetters: 1},
+		{name: "delivery limit before ladder", schedule: schedule, deliveries: "5", maxDeliveries: 5, wantDeadLetters: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tp := &retryTransport{retryErr: tt.retryErr}
+			r := &Router{
+				cfg:           &config.Config{MaxDeliveries: tt.maxDeliveries},
+				transport:     tp,
+				actorName:     "test-actor",
+				retrySchedule: tt.schedule,
+			}
+
+			msg := transport.QueueMessage{ID: "msg-1", Headers: map[string]string{"QueueName": "asya-test-actor"}}
+			if tt.attemptHeader != "" {
+				msg.Headers[transport.RetryAttemptHeader] = tt.attemptHeader
+			}
+			if tt.deliveries != "" {
+				msg.Headers[transport.DeliveryCountHeader] = tt.deliveries
+			}
+
+			r.retryFailed(context.Background(), msg)
+
+			if tp.nacks != tt.wantNacks {
+				t.Errorf("nacks = %d, want %d", tp.nacks, tt.wantNacks)
+			}
+			if tp.deadLetters != tt.wantDeadLetters {
+				t.Errorf("dead letters = %d, want %d", tp.deadLetters, tt.wantDeadLetters)
+			}
+			if tt.wantAttempt == 0 {
+				if len(tp.attempts) != 0 {
+					t.Errorf("unexpected retries: %v", tp.attempts)
+				}
+				return
+			}
+			if len(tp.attempts) != 1 || tp.attempts[0] != tt.wantAttempt || tp.delays[0] != tt.wantDelay {
+				t.Errorf("retries = %v after %v, want attempt %d after %v", tp.attempts, tp.delays, tt.wantAttempt, tt.wantDelay)
+			}
+		})
+	}
+}
+
+func TestRouter_RetryFailed_TransportWithoutRetry(t *testing.T) {
+	tp := &nackTransport{}
+	r := &Router{cfg: &config.Config{}, transport: tp, actorName: "test-actor", retrySchedule: []time.Duration{time.Second}}
+
+	r.retryFailed(context.Background(), transport.QueueMessage{ID: "msg-1"})
+
+	if tp.nacks != 1 {
+		t.Errorf("nacks = %d, want 1", tp.nacks)
+	}
+}
+
+// notifyNackTransport reports each NACK on a channel
+type notifyNackTransport struct {
+	mockTransport
+	nacked chan string
+}
+
+func (m *notifyNackTransport) Nack(ctx context.Context, msg transport.QueueMessage) error {
+	m.nacked <- msg.ID
+	return nil
+}
+
+// delayedNackTransport implements transport.DelayedNacker and records delayed NACKs
+type delayedNackTransport struct {
+	mockTransport
+	delays []time.Duration
+}
+
+func (m *delayedNackTransport) NackAfter(ctx context.Context, msg transport.QueueMessage, delay time.Duration) error {
+	m.delays = append(m.delays, delay)
+	return nil
+}
+
+// TestRouter_RetryFailed_NackDelayReleasesSlot verifies that a delayed NACK does not keep
+// retryFailed (and so the processing slot) waiting
+func TestRouter_RetryFailed_NackDelayReleasesSlot(t *testing.T) {
+	cfg := &config.Config{NackDelay: 50 * time.Millisecond, NackMaxDelay: time.Second}
+	msg := transport.QueueMessage{ID: "msg-1", Headers: map[string]string{transport.DeliveryCountHeader: "2"}}
+
+	t.Run("transport waits", func(t *testing.T) {
+		tp := &notifyNackTransport{nacked: make(chan string, 1)}
+		r := &Router{cfg: cfg, transport: tp, actorName: "test-actor"}
+
+		start := time.Now()
+		r.retryFailed(context.Background(), msg)
+		if elapsed := time.Since(start); elapsed >= cfg.NackDelay {
+			t.Errorf("retryFailed took %v, want it to return before the %v delay", elapsed, cfg.NackDelay)
+		}
+
+		select {
+		case id := <-tp.nacked:
+			if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+				t.Errorf("%s NACKed after %v, want the doubled delay of 100ms", id, elapsed)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("message was never NACKed")
+		}
+	})
+
+	t.Run("transport delays itself", func(t *testing.T) {
+		tp := &delayedNackTransport{}
+		r := &Router{cfg: cfg, transport: tp, actorName: "test-actor"}
+
+		r.retryFailed(context.Background(), msg)
+		if want := []time.Duration{100 * time.Millisecond}; !reflect.DeepEqual(tp.delays, want) {
+			t.Errorf("delayed NACKs = %v, want %v", tp.delays, want)
+		}
+	})
+}
+
+func TestRouter_NackDelay(t *testing.T) {
+	tests := []struct {
+		name       string
+		delay      time.Duration
+		maxDelay   time.Duration
+		deliveries int
+		want       time.Duration
+	}{
+		{name: "disabled", delay: 0, maxDelay: 30 * time.Second, deliveries: 4, want: 0},
+		{name: "first delivery", delay: time.Second, maxDelay: 30 * time.Second, deliveries: 1, want: time.Second},
+		{name: "doubles per delivery", delay: time.Second, maxDelay: 30 * time.Second, deliveries: 4, want: 8 * time.Second},
+		{name: "capped", delay: time.Second, maxDelay: 30 * time.Second, deliveries: 10, want: 30 * time.Second},
+		{name: "delay above cap", delay: time.Minute, maxDelay: 30 * time.Second, deliveries: 1, want: 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Router{cfg: &config.Config{NackDelay: tt.delay, NackMaxDelay: tt.maxDelay}}
+			if got := r.nackDelay(tt.deliveries); got != tt.want {
+				t.Errorf("nackDelay(%d) = %v, want %v", tt.deliveries, got, tt.want)
+			}
+		})
+	}
+}
+
+// sendFailingTransport is a retryTransport whose sends fail
+type sendFailingTransport struct {
+	retryTransport
+	acks int
+}
+
+func (m *sendFailingTransport) Send(ctx context.Context, queueName string, body []byte) error {
+	return fmt.Errorf("broker unavailable")
+}
+
+func (m *sendFailingTransport) Ack(ctx context.Context, msg transport.QueueMessage) error {
+	m.acks++
+	return nil
+}
+
+func TestRouter_HandleMessage_DeadLettersUnroutableReject(t *testing.T) {
+	tp := &sendFailingTransport{}
+	r := &Router{
+		cfg:           &config.Config{ActorName: "test-actor", TransportType: "rabbitmq"},
+		transport:     tp,
+		actorName:     "test-actor",
+		errorEndQueue: testQueueErrorEnd,
+		retrySchedule: []time.Duration{time.Second},
+	}
+	msg := transport.QueueMessage{ID: "msg-1", Body: []byte("not json"), Headers: map[string]string{"QueueName": "asya-test-actor"}}
+
+	result, err := r.ProcessEnvelope(context.Background(), msg)
+	if result != ProcessDeadLetter || err == nil {
+		t.Fatalf("ProcessEnvelope = (%v, %v), want dead-letter with error", result, err)
+	}
+
+	r.inFlight.Add(1)
+	r.handleMessage(context.Background(), msg)
+
+	if tp.deadLetters != 1 {
+		t.Errorf("dead letters = %d, want 1", tp.deadLetters)
+	}
+	if tp.acks != 0 || tp.nacks != 0 || len(tp.attempts) != 0 {
+		t.Errorf("acks = %d, nacks = %d, retries = %v, want none", tp.acks, tp.nacks, tp.attempts)
+	}
+}
+
+// replyTransport implements transport.Replier and records replies
+type replyTransport struct {
+	mockTransport
+	replies []struct {
+		replyTo       string
+		correlationID string
+		body          []byte
+	}
+}
+
+func (m *replyTransport) Reply(ctx context.Context, replyTo, correlationID string, body []byte) error {
+	m.replies = append(m.replies, struct {
+		replyTo       string
+		correlationID string
+		body          []byte
+	}{replyTo, correlationID, body})
+	return nil
+}
+
+func TestRouter_RepliesToReplyQueue(t *testing.T) {
+	cfg := &config.Config{
+		ActorName:     "test-actor",
+		HappyEndQueue: "happy-end",
+		ErrorEndQueue: "error-end",
+		TransportType: "rabbitmq",
+	}
+
+	tests := []struct {
+		name       string
+		send       func(r *Router, envelope envelopes.Envelope) error
+		wantStatus string
+	}{
+		{
+			name: "end of route",
+			send: func(r *Router, envelope envelopes.Envelope) error {
+				envelope.Route.Current = 1
+				return r.routeResponse(context.Background(), envelope)
+			},
+			wantStatus: statusSucceeded,
+		},
+		{
+			name: "empty response",
+			send: func(r *Router, envelope envelopes.Envelope) error {
+				return r.sendToHappyQueue(context.Background(), envelope)
+			},
+			wantStatus: statusSucceeded,
+		},
+		{
+			name: "error",
+			send: func(r *Router, envelope envelopes.Envelope) error {
+				body, _ := json.Marshal(envelope)
+				return r.sendToErrorQueue(context.Background(), body, "boom")
+			},
+			wantStatus: statusFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tp := &replyTransport{}
+			r := &Router{cfg: cfg, transport: tp, actorName: cfg.ActorName, happyEndQueue: cfg.HappyEndQueue, errorEndQueue: cfg.ErrorEndQueue}
+
+			envelope := envelopes.Envelope{
+				ID:      "test-reply-1",
+				ReplyTo: "amq.gen-reply",
+				Route:   envelopes.Route{Actors: []string{"test-actor"}, Current: 0},
+				Payload: json.RawMessage(`{"answer": 42}`),
+			}
+			if err := tt.send(r, envelope); err != nil {
+				t.Fatalf("send failed: %v", err)
+			}
+
+			if len(tp.replies) != 1 {
+				t.Fatalf("Expected 1 reply, got %d", len(tp.replies))
+			}
+			reply := tp.replies[0]
+			if reply.replyTo != "amq.gen-reply" || reply.correlationID != "test-reply-1" {
+				t.Errorf("Reply sent to %q with correlation ID %q", reply.replyTo, reply.correlationID)
+			}
+			if len(tp.sentMessages) != 1 || !bytes.Equal(reply.body, tp.sentMessages[0].body) {
+				t.Errorf("Reply body should be the terminal message sent to the end queue")
+			}
+
+			var terminal envelopes.TerminalMessage
+			if err := json.Unmarshal(reply.body, &terminal); err != nil {
+				t.Fatalf("Failed to parse reply: %v", err)
+			}
+			if terminal.Status != tt.wantStatus || terminal.ReplyTo != "amq.gen-reply" {
+				t.Errorf("Reply status = %q, reply_to = %q", terminal.Status, terminal.ReplyTo)
+			}
+		})
+	}
+}
+
+func TestRouter_FanoutDoesNotReply(t *testing.T) {
+	tp := &replyTransport{}
+	r := &Router{cfg: &config.Config{TransportType: "rabbitmq"}, transport: tp, actorName: "test-actor", happyEndQueue: "happy-end"}
+
+	envelope := &envelopes.Envelope{
+		ID:      "test-reply-3",
+		ReplyTo: "amq.gen-reply",
+		Route:   envelopes.Route{Actors: []string{"test-actor"}, Current: 0},
+	}
+	route := envelopes.Route{Actors: []string{"test-actor"}, Current: 1}
+	responses := []runtime.RuntimeResponse{
+		{Route: route, Payload: json.RawMessage(`{"branch": 0}`)},
+		{Route: route, Payload: json.RawMessage(`{"branch": 1}`)},
+	}
+
+	if err := r.handleRuntimeResponses(context.Background(), envelope, responses, nil, 0, time.Now()); err != nil {
+		t.Fatalf("handleRuntimeResponses failed: %v", err)
+	}
+
+	if len(tp.sentMessages) != 2 {
+		t.Fatalf("Expected 2 happy-end messages, got %d", len(tp.sentMessages))
+	}
+	if len(tp.replies) != 0 {
+		t.Errorf("Fanout branches should not reply, got %d replies", len(tp.replies))
+	}
+	for _, sent := range tp.sentMessages {
+		var terminal envelopes.TerminalMessage
+		if err := json.Unmarshal(sent.body, &terminal); err != nil {
+			t.Fatalf("Failed to parse terminal message: %v", err)
+		}
+		if terminal.ReplyTo != "" {
+			t.Errorf("Branch %s carries reply_to %q", terminal.ID, terminal.ReplyTo)
+		}
+	}
+}
+
+func TestRouter_NoReplyWithoutReplyTo(t *testing.T) {
+	tp := &replyTransport{}
+	r := &Router{cfg: &config.Config{TransportType: "rabbitmq"}, transport: tp, happyEndQueue: "happy-end"}
+
+	envelope := envelopes.Envelope{ID: "test-reply-2", Route: envelopes.Route{Actors: []string{"a"}, Current: 1}}
+	if err := r.routeResponse(context.Background(), envelope); err != nil {
+		t.Fatalf("routeResponse failed: %v", err)
+	}
+
+	if len(tp.replies) != 0 {
+		t.Errorf("Expected no reply, got %d", len(tp.replies))
+	}
+}
+
+func TestRouter_SkipsInactiveEnvelopes(t *testing.T) {
+	tests := []struct {
+		name           string
+		responses      map[string]mockHTTPResponse
+		wantRuntimeRun bool
+	}{
+		{
+			name: "cancelled envelope is dropped",
+			responses: map[string]mockHTTPResponse{
+				"/envelopes/env-cancel/active": {StatusCode: http.StatusGone, Body: []byte(`{"active":false}`)},
+				"/envelopes/env-cancel":        {StatusCode: http.StatusOK, Body: []byte(`{"status":"failed"}`)},
+			},
+		},
+		{
+			name: "envelope unknown to the gateway is processed",
+			responses: map[string]mockHTTPResponse{
+				"/envelopes/env-cancel/active": {StatusCode: http.StatusGone, Body: []byte(`{"active":false}`)},
+				"/envelopes/env-cancel":        {StatusCode: http.StatusNotFound},
+			},
+			wantRuntimeRun: true,
+		},
+		{
+			name: "gateway error does not block processing",
+			responses: map[string]mockHTTPResponse{
+				"/envelopes/env-cancel/active": {StatusCode: http.StatusInternalServerError},
+			},
+			wantRuntimeRun: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockServer := &mockHTTPServer{responses: tt.responses}
+			mockServer.Start(t)
+			defer mockServer.Close()
+
+			cfg := &config.Config{
+				ActorName:     "test-actor",
+				HappyEndQueue: testQueueHappyEnd,
+				ErrorEndQueue: testQueueErrorEnd,
+				GatewayURL:    mockServer.URL,
+			}
+			tp := &mockTransport{}
+			// No runtime listens on the socket: a runtime call sends the envelope to the error queue
+			router := NewRouter(cfg, tp, runtime.NewClient(fmt.Sprintf("/tmp/test-router-missing-%d.sock", time.Now().UnixNano()), time.Second), nil)
+
+			msgBody, _ := json.Marshal(envelopes.Envelope{
+				ID:      "env-cancel",
+				Route:   envelopes.Route{Actors: []string{"test-actor"}, Current: 0},
+				Payload: json.RawMessage(`{}`),
+			})
+			result, err := router.ProcessEnvelope(context.Background(), transport.QueueMessage{ID: "msg-1", Body: msgBody})
+			if err != nil {
+				t.Fatalf("ProcessEnvelope failed: %v", err)
+			}
+			if result != ProcessAcked {
+				t.Errorf("ProcessEnvelope result = %v, want %v", result, ProcessAcked)
+			}
+
+			if ran := len(tp.sentMessages) > 0; ran != tt.wantRuntimeRun {
+				t.Errorf("Runtime called = %v, want %v (sent %d messages)", ran, tt.wantRuntimeRun, len(tp.sentMessages))
+			}
+		})
+	}
+}
@@ -1,11 +1,14 @@
 package runtime
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net"
 	"time"
 
@@ -19,12 +22,19 @@ type ErrorDetails struct {
 	Traceback string `json:"traceback,omitempty"`
 }
 
-// RuntimeResponse represents the response from the actor runtime
+// RuntimeResponse represents the response from the actor runtime.
+// A successful response may carry warnings (e.g. "used fallback model"): they are
+// passed on with the routed envelope and reported to the gateway, but do not fail it.
+// It may also skip steps of its route: SkipTo continues at the named later actor,
+// SkipNext skips that many actors after the current one.
 type RuntimeResponse struct {
-	Payload json.RawMessage `json:"payload,omitempty"` // payload output from handler
-	Route   envelopes.Route `json:"route,omitempty"`   // route output from handler
-	Error   string          `json:"error,omitempty"`
-	Details ErrorDetails    `json:"details,omitempty"`
+	Payload  json.RawMessage `json:"payload,omitempty"` // payload output from handler
+	Route    envelopes.Route `json:"route,omitempty"`   // route output from handler
+	Error    string          `json:"error,omitempty"`
+	Details  ErrorDetails    `json:"details,omitempty"`
+	Warnings []string        `json:"warnings,omitempty"`  // warnings of a successful handler call
+	SkipTo   string          `json:"skip_to,omitempty"`   // actor to continue at, skipping the ones before it
+	SkipNext int             `json:"skip_next,omitempty"` // number of next actors to skip
 }
 
 // IsError returns true if the response indicates an error
@@ -32,22 +42,72 @@ func (r *RuntimeResponse) IsError() bool {
 	return r.Error != ""
 }
 
+// ApplySkip advances the response route past the actors skipped by SkipTo or SkipNext.
+// The route's current actor is the next one to run; skipping to a later actor or past the
+// last one (straight to happy-end) is allowed, anything else is an error.
+func (r *RuntimeResponse) ApplySkip() error {
+	next := r.Route.Current
+	switch {
+	case r.SkipTo != "" && r.SkipNext != 0:
+		return fmt.Errorf("skip_to and skip_next are mutually exclusive")
+	case r.SkipTo != "":
+		for i := next; i < len(r.Route.Actors); i++ {
+			if r.Route.Actors[i] == r.SkipTo {
+				r.Route.Current = i
+				return nil
+			}
+		}
+		return fmt.Errorf("skip_to %q is not a later step of route %v", r.SkipTo, r.Route.Actors[min(next, len(r.Route.Actors)):])
+	case r.SkipNext < 0:
+		return fmt.Errorf("skip_next must not be negative, got %d", r.SkipNext)
+	case r.SkipNext > len(r.Route.Actors)-next:
+		return fmt.Errorf("skip_next %d exceeds the %d remaining steps of the route", r.SkipNext, max(len(r.Route.Actors)-next, 0))
+	}
+	r.Route.Current = next + r.SkipNext
+	return nil
+}
+
+// Socket framing: a message is a 4-byte big-endian length prefix followed by the data
+// (buffered), or, for large messages, the StreamMarker prefix followed by chunks that each
+// have their own length prefix, ended by an empty chunk (streamed). Receivers accept both,
+// so a side sends streamed messages only above its stream threshold.
+const (
+	// StreamMarker is the length prefix of a streamed message
+	StreamMarker = math.MaxUint32
+
+	// DefaultStreamChunkSize is the chunk size of streamed messages
+	DefaultStreamChunkSize = 1 << 20 // 1 MiB
+)
+
 // Client handles communication with the actor runtime via Unix socket
 type Client struct {
-	socketPath string
-	timeout    time.Duration
+	socketPath      string
+	timeout         time.Duration
+	streamThreshold int // Requests of at least this size are streamed (0 disables)
+	streamChunkSize int
 }
 
 // NewClient creates a new runtime client
 func NewClient(socketPath string, timeout time.Duration) *Client {
 	return &Client{
-		socketPath: socketPath,
-		timeout:    timeout,
+		socketPath:      socketPath,
+		timeout:         timeout,
+		streamChunkSize: DefaultStreamChunkSize,
 	}
 }
 
+// SetStreamThreshold streams requests of at least threshold bytes to the runtime in chunks
+// instead of one length-prefixed message (0 disables streaming)
+func (c *Client) SetStreamThreshold(threshold int) {
+	c.streamThreshold = max(threshold, 0)
+}
+
 // SendSocketData sends a message with length-prefix (4-byte big-endian uint32)
 func SendSocketData(conn net.Conn, data []byte) error {
+	if uint64(len(data)) >= StreamMarker {
+		return fmt.Errorf("message of %d bytes is too large for a length prefix, stream it instead", len(data))
+	}
+
 	// Send length prefix
 	length := make([]byte, 4)
 
@@ -64,16 +124,73 @@ func SendSocketData(conn net.Conn, data []byte) error {
 	return nil
 }
 
-// RecvSocketData receives a message with length-prefix (4-byte big-endian uint32)
+// SendSocketStream sends the data read from r as a streamed message of chunks of at most
+// chunkSize bytes, so the whole message never has to be in memory at once
+func SendSocketStream(conn net.Conn, r io.Reader, chunkSize int) error {
+	if chunkSize <= 0 || uint64(chunkSize) >= StreamMarker {
+		return fmt.Errorf("invalid stream chunk size %d", chunkSize)
+	}
+
+	prefix := make([]byte, 4)
+	binary.BigEndian.PutUint32(prefix, StreamMarker)
+	if _, err := conn.Write(prefix); err != nil {
+		return fmt.Errorf("failed to write stream marker: %w", err)
+	}
+
+	chunk := make([]byte, 4+chunkSize)
+	for {
+		n, err := io.ReadFull(r, chunk[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(chunk[:4], uint32(n))
+			if _, werr := conn.Write(chunk[:4+n]); werr != nil {
+				return fmt.Errorf("failed to write chunk: %w", werr)
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read stream data: %w", err)
+		}
+	}
+
+	// Empty chunk ends the message
+	binary.BigEndian.PutUint32(prefix, 0)
+	if _, err := conn.Write(prefix); err != nil {
+		return fmt.Errorf("failed to write end of stream: %w", err)
+	}
+	return nil
+}
+
+// NewSocketReader reads the length prefix of a message and returns a reader of its data,
+// buffered or streamed
+func NewSocketReader(conn net.Conn) (io.Reader, error) {
+	size, err := readLengthPrefix(conn)
+	if err != nil {
+		return nil, err
+	}
+	if size == StreamMarker {
+		return &chunkReader{conn: conn}, nil
+	}
+	return &exactReader{conn: conn, remaining: size}, nil
+}
+
+// RecvSocketData receives a message, buffered or streamed (see NewSocketReader)
 func RecvSocketData(conn net.Conn) ([]byte, error) {
-	// Read length prefix
-	length := make([]byte, 4)
-	if _, err := io.ReadFull(conn, length); err != nil {
-		return nil, fmt.Errorf("failed to read length prefix: %w", err)
+	size, err := readLengthPrefix(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	if size == StreamMarker {
+		data, err := io.ReadAll(&chunkReader{conn: conn})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read data: %w", err)
+		}
+		return data, nil
 	}
 
 	// Read data
-	size := binary.BigEndian.Uint32(length)
 	data := make([]byte, size)
 	if _, err := io.ReadFull(conn, data); err != nil {
 		return nil, fmt.Errorf("failed to read data: %w", err)
@@ -82,11 +199,100 @@ func RecvSocketData(conn net.Conn) ([]byte, error) {
 	return data, nil
 }
 
+// readLengthPrefix reads the 4-byte big-endian length prefix of a message
+func readLengthPrefix(conn net.Conn) (uint32, error) {
+	length := make([]byte, 4)
+	if _, err := io.ReadFull(conn, length); err != nil {
+		return 0, fmt.Errorf("failed to read length prefix: %w", err)
+	}
+	return binary.BigEndian.Uint32(length), nil
+}
+
+// exactReader reads the data of a buffered message, failing if the connection ends early
+type exactReader struct {
+	conn      net.Conn
+	remaining uint32 // Unread bytes of the message
+}
+
+func (e *exactReader) Read(p []byte) (int, error) {
+	if e.remaining == 0 {
+		return 0, io.EOF
+	}
+	if uint64(len(p)) > uint64(e.remaining) {
+		p = p[:e.remaining]
+	}
+	n, err := e.conn.Read(p)
+	e.remaining -= uint32(n)
+	if err == io.EOF && e.remaining > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// chunkReader reads the data of a streamed message, chunk by chunk
+type chunkReader struct {
+	conn      net.Conn
+	remaining uint32 // Unread bytes of the current chunk
+	done      bool   // End of stream reached
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	if c.done {
+		return 0, io.EOF
+	}
+	if c.remaining == 0 {
+		length := make([]byte, 4)
+		if _, err := io.ReadFull(c.conn, length); err != nil {
+			return 0, fmt.Errorf("failed to read chunk length: %w", err)
+		}
+		c.remaining = binary.BigEndian.Uint32(length)
+		if c.remaining == 0 {
+			c.done = true
+			return 0, io.EOF
+		}
+	}
+
+	if uint64(len(p)) > uint64(c.remaining) {
+		p = p[:c.remaining]
+	}
+	n, err := c.conn.Read(p)
+	c.remaining -= uint32(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// PartialResultFunc receives an intermediate result the handler emits before its response
+type PartialResultFunc func(result json.RawMessage)
+
+// partialResultFrame is a message the runtime sends ahead of its response array for each
+// partial result of the handler
+type partialResultFrame struct {
+	PartialResult json.RawMessage `json:"partial_result"`
+}
+
+// Timeout returns the default timeout of runtime calls
+func (c *Client) Timeout() time.Duration {
+	return c.timeout
+}
+
 // CallRuntime sends a full message (with route and payload) to the runtime and waits for response(s)
 // Returns multiple responses for fan-out, empty slice for abort, or error
 func (c *Client) CallRuntime(ctx context.Context, data []byte) ([]RuntimeResponse, error) {
+	return c.CallRuntimeWithPartials(ctx, data, c.timeout, nil)
+}
+
+// CallRuntimeWithTimeout is like CallRuntime but uses the given timeout instead of the client default
+func (c *Client) CallRuntimeWithTimeout(ctx context.Context, data []byte, timeout time.Duration) ([]RuntimeResponse, error) {
+	return c.CallRuntimeWithPartials(ctx, data, timeout, nil)
+}
+
+// CallRuntimeWithPartials is like CallRuntimeWithTimeout but passes the partial results the
+// handler emits before its response to onPartial, as they arrive (nil drops them)
+func (c *Client) CallRuntimeWithPartials(ctx context.Context, data []byte, timeout time.Duration, onPartial PartialResultFunc) ([]RuntimeResponse, error) {
 	// Apply timeout
-	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	// Connect to Unix socket
@@ -101,22 +307,74 @@ func (c *Client) CallRuntime(ctx context.Context, data []byte) ([]RuntimeRespons
 	deadline, _ := ctx.Deadline()
 	_ = conn.SetDeadline(deadline)
 
-	// Send message with length-prefix
-	if err := SendSocketData(conn, data); err != nil {
+	// Send message with length-prefix, streaming large ones
+	if c.streamThreshold > 0 && len(data) >= c.streamThreshold {
+		err = SendSocketStream(conn, bytes.NewReader(data), c.streamChunkSize)
+	} else {
+		err = SendSocketData(conn, data)
+	}
+	if err != nil {
 		return nil, fmt.Errorf("failed to send message to runtime: %w", err)
 	}
 
-	// Read response with length-prefix
-	responseData, err := RecvSocketData(conn)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response from runtime: %w", err)
+	// Partial result frames (JSON objects) come first, then the response array
+	for {
+		messageReader, err := NewSocketReader(conn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response from runtime: %w", err)
+		}
+		reader := bufio.NewReader(messageReader)
+
+		first, err := firstNonSpace(reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse runtime response: %w", err)
+		}
+		if first != '{' {
+			// Parse response - runtime always returns an array
+			var responses []RuntimeResponse
+			if err := decodeMessage(reader, &responses); err != nil {
+				return nil, fmt.Errorf("failed to parse runtime response: %w", err)
+			}
+			return responses, nil
+		}
+
+		var frame partialResultFrame
+		if err := decodeMessage(reader, &frame); err != nil {
+			return nil, fmt.Errorf("failed to parse runtime partial result: %w", err)
+		}
+		if onPartial != nil && frame.PartialResult != nil {
+			onPartial(frame.PartialResult)
+		}
 	}
+}
 
-	// Parse response - runtime always returns an array
-	var responses []RuntimeResponse
-	if err := json.Unmarshal(responseData, &responses); err != nil {
-		return nil, fmt.Errorf("failed to parse runtime response: %w", err)
+// decodeMessage decodes the JSON value of a message into v, rejecting anything but whitespace
+// after it. The whole message is read, so the next one starts at its length prefix.
+func decodeMessage(r io.Reader, v any) error {
+	decoder := json.NewDecoder(r)
+	if err := decoder.Decode(v); err != nil {
+		return err
 	}
+	if _, err := decoder.Token(); err != io.EOF {
+		if err == nil {
+			err = fmt.Errorf("unexpected data after JSON value at offset %d", decoder.InputOffset())
+		}
+		return err
+	}
+	return nil
+}
 
-	return responses, nil
+// firstNonSpace returns the first byte of r that is not JSON whitespace, leaving it unread
+func firstNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\n', '\r':
+			continue
+		}
+		return b, r.UnreadByte()
+	}
 }
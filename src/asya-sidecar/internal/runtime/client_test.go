@@ -1,10 +1,14 @@
 package runtime
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"io"
 	"net"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -377,6 +381,226 @@ func TestClient_CallRuntime_ConnectionError(t *testing.T) {
 	}
 }
 
+func TestSocketStream_RoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000) // 10000 bytes
+
+	tests := []struct {
+		name      string
+		chunkSize int
+	}{
+		{name: "several chunks with a partial last one", chunkSize: 3000},
+		{name: "exact chunks", chunkSize: 1000},
+		{name: "single chunk", chunkSize: 20000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer func() { _ = client.Close() }()
+			defer func() { _ = server.Close() }()
+
+			errs := make(chan error, 1)
+			go func() { errs <- SendSocketStream(client, bytes.NewReader(data), tt.chunkSize) }()
+
+			got, err := RecvSocketData(server)
+			if err != nil {
+				t.Fatalf("RecvSocketData() error = %v", err)
+			}
+			if err := <-errs; err != nil {
+				t.Fatalf("SendSocketStream() error = %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("received %d bytes, want the %d bytes sent", len(got), len(data))
+			}
+		})
+	}
+
+	t.Run("empty message", func(t *testing.T) {
+		client, server := net.Pipe()
+		defer func() { _ = client.Close() }()
+		defer func() { _ = server.Close() }()
+
+		go func() { _ = SendSocketStream(client, bytes.NewReader(nil), 10) }()
+
+		got, err := RecvSocketData(server)
+		if err != nil || len(got) != 0 {
+			t.Errorf("RecvSocketData() = %q, %v, want empty message", got, err)
+		}
+	})
+
+	t.Run("truncated stream", func(t *testing.T) {
+		client, server := net.Pipe()
+		defer func() { _ = server.Close() }()
+
+		go func() {
+			// Marker and a chunk announcing more bytes than sent
+			_, _ = client.Write([]byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 10, 'a', 'b'})
+			_ = client.Close()
+		}()
+
+		if _, err := RecvSocketData(server); err == nil {
+			t.Error("Expected error for truncated stream")
+		}
+	})
+}
+
+func TestClient_CallRuntime_Streamed(t *testing.T) {
+	socketPath, err := nettest.LocalPath()
+	if err != nil {
+		t.Fatalf("Failed to get local path: %v", err)
+	}
+	defer func() { _ = os.Remove(socketPath) }()
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to create socket: %v", err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	payload := `{"data":"` + strings.Repeat("x", 5000) + `"}`
+	message := []byte(`{"route":{"actors":["test"],"current":0},"payload":` + payload + `}`)
+
+	// Runtime checking the request is streamed and echoing its payload in a streamed response
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+
+		reader, err := NewSocketReader(conn)
+		if err != nil {
+			return
+		}
+		if _, ok := reader.(*chunkReader); !ok {
+			received <- nil
+			return
+		}
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return
+		}
+		received <- data
+
+		responses, _ := json.Marshal([]RuntimeResponse{{Payload: json.RawMessage(payload)}})
+		_ = SendSocketStream(conn, bytes.NewReader(responses), 1000)
+	}()
+
+	client := NewClient(socketPath, 2*time.Second)
+	client.SetStreamThreshold(len(message))
+	client.streamChunkSize = 1000
+
+	results, err := client.CallRuntime(context.Background(), message)
+	if err != nil {
+		t.Fatalf("CallRuntime failed: %v", err)
+	}
+	if got := <-received; !bytes.Equal(got, message) {
+		t.Fatalf("Runtime received %d bytes (nil when not streamed), want %d", len(got), len(message))
+	}
+	if len(results) != 1 || string(results[0].Payload) != payload {
+		t.Errorf("Expected the streamed payload back, got %d results", len(results))
+	}
+}
+
+func TestClient_CallRuntimeWithPartials(t *testing.T) {
+	socketPath, err := nettest.LocalPath()
+	if err != nil {
+		t.Fatalf("Failed to get local path: %v", err)
+	}
+	defer func() { _ = os.Remove(socketPath) }()
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to create socket: %v", err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	// Runtime emitting two partial results, the second one streamed, before its response
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+
+		if _, err := RecvSocketData(conn); err != nil {
+			return
+		}
+		_ = SendSocketData(conn, []byte(`{"partial_result": {"text": "Hel"}}`))
+		_ = SendSocketStream(conn, strings.NewReader(`{"partial_result": {"text": "Hello"}}`), 8)
+		_ = SendSocketData(conn, []byte(`[{"payload": {"text": "Hello!"}}]`))
+	}()
+
+	client := NewClient(socketPath, 2*time.Second)
+
+	var partials []string
+	results, err := client.CallRuntimeWithPartials(context.Background(), []byte(`{}`), time.Second, func(result json.RawMessage) {
+		partials = append(partials, string(result))
+	})
+	if err != nil {
+		t.Fatalf("CallRuntimeWithPartials failed: %v", err)
+	}
+	if want := []string{`{"text": "Hel"}`, `{"text": "Hello"}`}; !reflect.DeepEqual(partials, want) {
+		t.Errorf("Partial results = %v, want %v", partials, want)
+	}
+	if len(results) != 1 || string(results[0].Payload) != `{"text": "Hello!"}` {
+		t.Errorf("Expected the final response after the partial results, got %+v", results)
+	}
+}
+
+// TestClient_CallRuntime_TrailingData tests that messages with data after their JSON value
+// are rejected instead of silently truncated
+func TestClient_CallRuntime_TrailingData(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []string
+		wantErr  bool
+	}{
+		{name: "trailing whitespace", messages: []string{"[{\"payload\": {}}]\n "}},
+		{name: "trailing garbage", messages: []string{`[{"payload": {}}]garbage`}, wantErr: true},
+		{name: "second array", messages: []string{`[{"payload": {}}][]`}, wantErr: true},
+		{name: "partial result with trailing garbage", messages: []string{`{"partial_result": {}} {}`, `[]`}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			socketPath, err := nettest.LocalPath()
+			if err != nil {
+				t.Fatalf("Failed to get local path: %v", err)
+			}
+			defer func() { _ = os.Remove(socketPath) }()
+
+			listener, err := net.Listen("unix", socketPath)
+			if err != nil {
+				t.Fatalf("Failed to create socket: %v", err)
+			}
+			defer func() { _ = listener.Close() }()
+
+			go func() {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				defer func() { _ = conn.Close() }()
+
+				if _, err := RecvSocketData(conn); err != nil {
+					return
+				}
+				for _, message := range tt.messages {
+					_ = SendSocketData(conn, []byte(message))
+				}
+			}()
+
+			client := NewClient(socketPath, 2*time.Second)
+			_, err = client.CallRuntime(context.Background(), []byte(`{}`))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CallRuntime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestResponse_IsError(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -409,3 +633,39 @@ func TestResponse_IsError(t *testing.T) {
 		})
 	}
 }
+
+func TestResponse_ApplySkip(t *testing.T) {
+	route := func(current int) envelopes.Route {
+		return envelopes.Route{Actors: []string{"prep", "infer", "enrich", "post"}, Current: current}
+	}
+
+	tests := []struct {
+		name        string
+		response    RuntimeResponse
+		wantCurrent int
+		wantErr     bool
+	}{
+		{name: "no skip", response: RuntimeResponse{Route: route(1)}, wantCurrent: 1},
+		{name: "skip next step", response: RuntimeResponse{Route: route(1), SkipNext: 1}, wantCurrent: 2},
+		{name: "skip remaining steps", response: RuntimeResponse{Route: route(1), SkipNext: 3}, wantCurrent: 4},
+		{name: "skip to later step", response: RuntimeResponse{Route: route(1), SkipTo: "post"}, wantCurrent: 3},
+		{name: "skip to next step", response: RuntimeResponse{Route: route(1), SkipTo: "infer"}, wantCurrent: 1},
+		{name: "skip to earlier step", response: RuntimeResponse{Route: route(2), SkipTo: "prep"}, wantErr: true},
+		{name: "skip to unknown step", response: RuntimeResponse{Route: route(1), SkipTo: "missing"}, wantErr: true},
+		{name: "skip past route end", response: RuntimeResponse{Route: route(1), SkipNext: 4}, wantErr: true},
+		{name: "negative skip", response: RuntimeResponse{Route: route(1), SkipNext: -1}, wantErr: true},
+		{name: "both skip fields", response: RuntimeResponse{Route: route(1), SkipTo: "post", SkipNext: 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.response.ApplySkip()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplySkip() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && tt.response.Route.Current != tt.wantCurrent {
+				t.Errorf("Route.Current = %d, want %d", tt.response.Route.Current, tt.wantCurrent)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package transport
+
+import "strconv"
+
+// DeliveryCountHeader holds how many times a message has been delivered, the current
+// delivery included. RabbitMQ only counts redeliveries through dead-lettering (x-death),
+// so the sidecar carries the count in this header whenever it republishes a message;
+// SQS reports its approximate receive count instead.
+const DeliveryCountHeader = "x-asya-delivery-count"
+
+// DeliveryCount returns the delivery count of a received message (1 when unknown)
+func DeliveryCount(msg QueueMessage) int {
+	count, err := strconv.Atoi(msg.Headers[DeliveryCountHeader])
+	if err != nil || count < 1 {
+		return 1
+	}
+	return count
+}
@@ -0,0 +1,92 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+)
+
+// publishPool hands out channels used only for publishing. AMQP channels are not
+// safe for concurrent use, and the broker throttles publishing channels under flow
+// control, so keeping publishes off the consume channel lets deliveries and acks
+// proceed while a publish is blocked.
+//
+// The pool is a fixed set of slots; a nil slot is opened lazily, and a channel
+// that failed is closed and its slot reset to nil.
+type publishPool struct {
+	slots chan rabbitmqChannel
+	open  func() (rabbitmqChannel, error)
+}
+
+// newPublishPool opens size publish channels
+func newPublishPool(size int, open func() (rabbitmqChannel, error)) (*publishPool, error) {
+	p := &publishPool{
+		slots: make(chan rabbitmqChannel, size),
+		open:  open,
+	}
+	for i := 0; i < size; i++ {
+		ch, err := open()
+		if err != nil {
+			p.close()
+			return nil, fmt.Errorf("failed to open publish channel %d: %w", i, err)
+		}
+		p.slots <- ch
+	}
+	return p, nil
+}
+
+// get takes a publish channel from the pool, blocking while all are in use
+func (p *publishPool) get(ctx context.Context) (rabbitmqChannel, error) {
+	select {
+	case ch := <-p.slots:
+		if ch != nil {
+			return ch, nil
+		}
+		ch, err := p.open()
+		if err != nil {
+			p.slots <- nil
+			return nil, fmt.Errorf("failed to open publish channel: %w", err)
+		}
+		return ch, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// put returns a channel taken by get. A channel used by a failed operation may have
+// been closed by the broker, so it is discarded and reopened on next use.
+func (p *publishPool) put(ch rabbitmqChannel, opErr error) {
+	if opErr != nil && ch != nil {
+		_ = ch.Close()
+		ch = nil
+	}
+	p.slots <- ch
+}
+
+// reset closes the idle channels, e.g. after the connection was replaced
+func (p *publishPool) reset() {
+	for i := 0; i < cap(p.slots); i++ {
+		select {
+		case ch := <-p.slots:
+			if ch != nil {
+				_ = ch.Close()
+			}
+			p.slots <- nil
+		default:
+			return
+		}
+	}
+}
+
+// close closes the idle channels
+func (p *publishPool) close() {
+	for {
+		select {
+		case ch := <-p.slots:
+			if ch != nil {
+				_ = ch.Close()
+			}
+		default:
+			return
+		}
+	}
+}
@@ -2,10 +2,13 @@ package transport
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
@@ -16,6 +19,8 @@ const (
 
 	defaultQueueRetryMaxAttempts = 10
 	defaultQueueRetryBackoff     = 1 * time.Second
+
+	defaultPublishChannels = 2
 )
 
 // getQueueRetryMaxAttempts returns configured max retry attempts from environment or default
@@ -52,63 +57,61 @@ type rabbitmqChannel interface {
 	QueueDeclarePassive(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
 	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
 	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error)
+	Cancel(consumer string, noWait bool) error
 	PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+	PublishWithDeferredConfirmWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) (*amqp.DeferredConfirmation, error)
 	Ack(tag uint64, multiple bool) error
 	Nack(tag uint64, multiple, requeue bool) error
 	Close() error
 }
 
-// RabbitMQTransport implements Transport interface for RabbitMQ
+// RabbitMQTransport implements Transport interface for RabbitMQ.
+// Deliveries and acks use a dedicated consume channel; publishes go through a small pool
+// of separate channels, so broker flow control on publishing never stalls the consumer.
 type RabbitMQTransport struct {
 	conn          rabbitmqConnection
-	channel       rabbitmqChannel
+	channel       rabbitmqChannel // Consume channel (deliveries, ack/nack)
+	publish       *publishPool    // Publish channels
 	exchange      string
 	prefetchCount int
-	consumer      <-chan amqp.Delivery // Single long-lived consumer
-	consumerQueue string               // Queue name for the consumer
-	amqpChannel   *amqp.Channel        // Store real AMQP channel to monitor errors
-	amqpConn      *amqp.Connection     // Store real AMQP connection to monitor errors
-	url           string               // Store URL for reconnection
+	mu            sync.Mutex                  // Guards the connection, consume channel and consumers
+	consumers     map[string]rabbitmqConsumer // Long-lived consumer per input queue
+	consumerSeq   int                         // Numbers consumer tags, unique per transport
+	amqpChannel   *amqp.Channel               // Store real AMQP channel to monitor errors
+	amqpConn      *amqp.Connection            // Store real AMQP connection to monitor errors
+	urls          []string                    // Broker URLs in failover order, redialed on reconnection
+	retryMu       sync.Mutex                  // Guards retryQueues, Retry runs on any goroutine
+	retryQueues   map[string]bool             // Delay queues declared by Retry
+	autoAck       bool                        // Deliveries are acked by the broker on delivery
+	republishNack bool                        // Nack republishes a copy with an updated delivery count
+}
+
+// rabbitmqConsumer is a consumer on the consume channel
+type rabbitmqConsumer struct {
+	tag        string
+	deliveries <-chan amqp.Delivery
 }
 
 // RabbitMQConfig holds RabbitMQ-specific configuration
 type RabbitMQConfig struct {
-	URL           string
-	Exchange      string
-	PrefetchCount int
-}
-
-// NewRabbitMQTransport creates a new RabbitMQ transport
-func NewRabbitMQTransport(cfg RabbitMQConfig) (*RabbitMQTransport, error) {
-	// Connect to RabbitMQ with retry logic for resilience during startup
-	// This allows the sidecar to wait for RabbitMQ to become available during:
-	// - Initial cluster deployment
-	// - RabbitMQ pod restarts
-	// - Network temporary failures
+	URL             string // Comma-separated broker URLs, tried in order (primary first)
+	Exchange        string
+	PrefetchCount   int
+	PublishChannels int  // Size of the publish channel pool (default 2)
+	AutoAck         bool // Consume with auto-ack (at-most-once); Ack and Nack become no-ops
+	RepublishOnNack bool // Nack acks and republishes the message so its delivery count is kept exactly
+}
+
+// NewRabbitMQTransport creates a new RabbitMQ transport.
+// The connection is retried with backoff until ctx is done, so the sidecar waits for RabbitMQ
+// to become available during cluster deployment, broker restarts and scale-from-zero bursts.
+func NewRabbitMQTransport(ctx context.Context, cfg RabbitMQConfig) (*RabbitMQTransport, error) {
+	urls := splitBrokerURLs(cfg.URL)
 	var conn rabbitmqConnection
 	var err error
-	maxRetries := 5
-	initialBackoff := 1 * time.Second
-
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		conn, err = amqp.Dial(cfg.URL)
-		if err == nil {
-			break
-		}
-
-		if attempt < maxRetries-1 {
-			backoff := initialBackoff * (1 << uint(attempt))
-			slog.Warn("Failed to connect to RabbitMQ, retrying",
-				"attempt", attempt+1,
-				"maxRetries", maxRetries,
-				"backoff", backoff,
-				"error", err)
-			time.Sleep(backoff)
-		}
-	}
-
+	conn, err = dialWithRetry(ctx, urls, amqp.Dial)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to RabbitMQ after %d attempts: %w", maxRetries, err)
+		return nil, err
 	}
 
 	slog.Info("Connected to RabbitMQ successfully")
@@ -148,26 +151,55 @@ func NewRabbitMQTransport(cfg RabbitMQConfig) (*RabbitMQTransport, error) {
 		return nil, fmt.Errorf("failed to cast connection to *amqp.Connection")
 	}
 
-	return &RabbitMQTransport{
+	t := &RabbitMQTransport{
 		conn:          conn,
 		channel:       channel,
 		exchange:      cfg.Exchange,
 		prefetchCount: cfg.PrefetchCount,
 		amqpChannel:   channel,
 		amqpConn:      realConn,
-		url:           cfg.URL,
-	}, nil
+		urls:          urls,
+		autoAck:       cfg.AutoAck,
+		republishNack: cfg.RepublishOnNack,
+	}
+
+	publishChannels := cfg.PublishChannels
+	if publishChannels <= 0 {
+		publishChannels = defaultPublishChannels
+	}
+	t.publish, err = newPublishPool(publishChannels, t.openPublishChannel)
+	if err != nil {
+		_ = channel.Close()
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// openPublishChannel opens a publish channel on the current connection in confirm mode,
+// so copies of a message can be confirmed by the broker before the original is acked
+func (t *RabbitMQTransport) openPublishChannel() (rabbitmqChannel, error) {
+	ch, err := t.conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open channel: %w", err)
+	}
+	if err := ch.Confirm(false); err != nil {
+		_ = ch.Close()
+		return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+	return ch, nil
 }
 
-// ensureQueue checks if queue exists using passive declaration
+// ensureQueue checks if queue exists using passive declaration on ch
 // Does NOT create the queue - operator is responsible for queue creation
-func (t *RabbitMQTransport) ensureQueue(queueName string) error {
-	if t.channel == nil {
+func (t *RabbitMQTransport) ensureQueue(ch rabbitmqChannel, queueName string) error {
+	if ch == nil {
 		return fmt.Errorf("channel is not available")
 	}
 
 	// Use passive declaration to check if queue exists without creating it
-	_, err := t.channel.QueueDeclarePassive(
+	_, err := ch.QueueDeclarePassive(
 		queueName,
 		true,  // durable
 		false, // delete when unused
@@ -182,8 +214,165 @@ func (t *RabbitMQTransport) ensureQueue(queueName string) error {
 	return nil
 }
 
-// Receive receives a message from RabbitMQ
+// Receive receives a message from RabbitMQ.
+// Each queue has its own long-lived consumer on the consume channel, so Receive can be
+// called concurrently for different queues.
 func (t *RabbitMQTransport) Receive(ctx context.Context, queueName string) (QueueMessage, error) {
+	deliveries, err := t.consumer(queueName)
+	if err != nil {
+		return QueueMessage{}, err
+	}
+
+	select {
+	case msg, ok := <-deliveries:
+		if !ok {
+			// Channel closed - drop the consumer to trigger reconnection on next call
+			t.dropConsumer(queueName, deliveries)
+			return QueueMessage{}, fmt.Errorf("channel closed")
+		}
+
+		// Convert AMQP headers to QueueMessage headers
+		headers := make(map[string]string)
+		headers["QueueName"] = queueName
+		for k, v := range msg.Headers {
+			headers[k] = fmt.Sprintf("%v", v)
+		}
+		headers[DeliveryCountHeader] = strconv.Itoa(deliveryCount(msg))
+
+		return QueueMessage{
+			ID:            msg.MessageId,
+			Body:          msg.Body,
+			ReceiptHandle: rabbitmqReceipt{deliveryTag: msg.DeliveryTag, headers: msg.Headers},
+			Headers:       headers,
+		}, nil
+
+	case <-ctx.Done():
+		slog.Info("Context cancelled while waiting for message", "queue", queueName, "err", ctx.Err())
+		return QueueMessage{}, ctx.Err()
+	}
+}
+
+// consumer returns the deliveries of the consumer of queueName, starting it if needed.
+// The queue is retried with backoff, since the operator may still be (re)creating it.
+func (t *RabbitMQTransport) consumer(queueName string) (<-chan amqp.Delivery, error) {
+	maxRetries := getQueueRetryMaxAttempts()
+	initialBackoff := getQueueRetryBackoff()
+
+	var err error
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		var deliveries <-chan amqp.Delivery
+		deliveries, err = t.startConsumer(queueName)
+		if err == nil {
+			return deliveries, nil
+		}
+
+		if attempt < maxRetries-1 {
+			backoff := initialBackoff * (1 << uint(attempt))
+			slog.Warn("Failed to start consuming, retrying",
+				"queue", queueName,
+				"attempt", attempt+1,
+				"maxRetries", maxRetries,
+				"backoff", backoff,
+				"error", err)
+			time.Sleep(backoff)
+		}
+	}
+
+	slog.Error("Failed to start consuming after retries", "queue", queueName, "error", err)
+	return nil, fmt.Errorf("failed to start consuming after %d attempts: %w", maxRetries, err)
+}
+
+// startConsumer returns the consumer of queueName, reconnecting and consuming as needed
+func (t *RabbitMQTransport) startConsumer(queueName string) (<-chan amqp.Delivery, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.reconnect(); err != nil {
+		return nil, err
+	}
+
+	if consumer, ok := t.consumers[queueName]; ok {
+		return consumer.deliveries, nil
+	}
+
+	slog.Info("Initializing consumer", "queue", queueName)
+
+	// Ensure queue exists, it may have been deleted externally (chaos scenarios)
+	if err := t.ensureQueue(t.channel, queueName); err != nil {
+		return nil, err
+	}
+
+	t.consumerSeq++
+	tag := fmt.Sprintf("%s-%d", queueName, t.consumerSeq)
+	deliveries, err := t.channel.Consume(
+		queueName,
+		tag,       // consumer tag
+		t.autoAck, // auto-ack
+		false,     // exclusive
+		false,     // no-local
+		false,     // no-wait
+		nil,       // args
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.consumers == nil {
+		t.consumers = make(map[string]rabbitmqConsumer)
+	}
+	t.consumers[queueName] = rabbitmqConsumer{tag: tag, deliveries: deliveries}
+	slog.Info("Consumer started successfully", "queue", queueName)
+	return deliveries, nil
+}
+
+// dropConsumer forgets the consumer of queueName if it is still the one given
+func (t *RabbitMQTransport) dropConsumer(queueName string, deliveries <-chan amqp.Delivery) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.consumers[queueName].deliveries == deliveries {
+		delete(t.consumers, queueName)
+	}
+}
+
+// CancelConsumers cancels the consumers of all queues, so the broker stops delivering to this
+// sidecar, and requeues the messages prefetched but not yet received. Must only be called
+// while no Receive is in progress; the next Receive starts a new consumer.
+func (t *RabbitMQTransport) CancelConsumers(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var errs []error
+	for queueName, consumer := range t.consumers {
+		delete(t.consumers, queueName)
+
+		if err := t.channel.Cancel(consumer.tag, false); err != nil {
+			errs = append(errs, fmt.Errorf("failed to cancel consumer of %s: %w", queueName, err))
+			continue
+		}
+
+		// Deliveries buffered before the cancellation are still handed out, then the channel closes
+		requeued := 0
+		for msg := range consumer.deliveries {
+			if t.autoAck {
+				slog.Warn("Prefetched message dropped on consumer cancellation, auto-ack is enabled", "queue", queueName, "id", msg.MessageId)
+				continue
+			}
+			if err := t.channel.Nack(msg.DeliveryTag, false, true); err != nil {
+				errs = append(errs, fmt.Errorf("failed to requeue prefetched message of %s: %w", queueName, err))
+				continue
+			}
+			requeued++
+		}
+		slog.Info("Consumer cancelled", "queue", queueName, "requeued", requeued)
+	}
+
+	return errors.Join(errs...)
+}
+
+// reconnect reopens a closed connection or consume channel, dropping the consumers on them.
+// Must be called with t.mu held.
+func (t *RabbitMQTransport) reconnect() error {
 	// Check if AMQP connection is closed and reconnect if needed
 	if t.amqpConn != nil && t.amqpConn.IsClosed() {
 		slog.Warn("AMQP connection is closed, reconnecting to RabbitMQ")
@@ -194,7 +383,7 @@ func (t *RabbitMQTransport) Receive(ctx context.Context, queueName string) (Queu
 		initialBackoff := 1 * time.Second
 
 		for attempt := 0; attempt < maxRetries; attempt++ {
-			newConn, err = amqp.Dial(t.url)
+			newConn, err = dialFirst(t.urls, amqp.Dial)
 			if err == nil {
 				break
 			}
@@ -211,7 +400,7 @@ func (t *RabbitMQTransport) Receive(ctx context.Context, queueName string) (Queu
 		}
 
 		if err != nil {
-			return QueueMessage{}, fmt.Errorf("failed to reconnect to RabbitMQ after %d attempts: %w", maxRetries, err)
+			return fmt.Errorf("failed to reconnect to RabbitMQ after %d attempts: %w", maxRetries, err)
 		}
 
 		slog.Info("Successfully reconnected to RabbitMQ")
@@ -220,22 +409,23 @@ func (t *RabbitMQTransport) Receive(ctx context.Context, queueName string) (Queu
 		t.amqpConn = newConn
 		t.channel = nil
 		t.amqpChannel = nil
-		t.consumer = nil
-		t.consumerQueue = ""
+		t.consumers = nil
+		// Publish channels of the old connection are dead, reopen them on next use
+		t.publish.reset()
 	}
 
 	// Check if AMQP channel is closed and recreate if needed
-	if t.amqpChannel != nil && t.amqpChannel.IsClosed() {
+	if t.channel == nil || (t.amqpChannel != nil && t.amqpChannel.IsClosed()) {
 		slog.Warn("AMQP channel is closed, recreating channel")
 		newChannel, err := t.conn.Channel()
 		if err != nil {
-			return QueueMessage{}, fmt.Errorf("failed to recreate channel: %w", err)
+			return fmt.Errorf("failed to recreate channel: %w", err)
 		}
 
 		// Set QoS (prefetch)
 		if err := newChannel.Qos(t.prefetchCount, 0, false); err != nil {
 			_ = newChannel.Close()
-			return QueueMessage{}, fmt.Errorf("failed to set QoS on new channel: %w", err)
+			return fmt.Errorf("failed to set QoS on new channel: %w", err)
 		}
 
 		// Declare exchange
@@ -249,128 +439,67 @@ func (t *RabbitMQTransport) Receive(ctx context.Context, queueName string) (Queu
 			nil,   // arguments
 		); err != nil {
 			_ = newChannel.Close()
-			return QueueMessage{}, fmt.Errorf("failed to declare exchange on new channel: %w", err)
+			return fmt.Errorf("failed to declare exchange on new channel: %w", err)
 		}
 
 		t.channel = newChannel
 		t.amqpChannel = newChannel
-		t.consumer = nil
-		t.consumerQueue = ""
+		t.consumers = nil
 		slog.Info("Successfully recreated AMQP channel")
 	}
 
-	// Initialize consumer if this is the first call or queue changed
-	if t.consumer == nil || t.consumerQueue != queueName {
-		slog.Info("Initializing consumer", "queue", queueName, "consumer_was_nil", t.consumer == nil)
-
-		// Ensure queue exists and is bound to exchange with retry logic
-		// This handles cases where queue is deleted externally (chaos scenarios)
-		// and operator needs time to recreate it
-		maxRetries := getQueueRetryMaxAttempts()
-		initialBackoff := getQueueRetryBackoff()
-
-		var msgs <-chan amqp.Delivery
-		var err error
-
-		for attempt := 0; attempt < maxRetries; attempt++ {
-			// Try to ensure queue exists
-			if err = t.ensureQueue(queueName); err != nil {
-				if attempt < maxRetries-1 {
-
-					backoff := initialBackoff * (1 << uint(attempt))
-					slog.Warn("Failed to ensure queue exists, retrying",
-						"queue", queueName,
-						"attempt", attempt+1,
-						"maxRetries", maxRetries,
-						"backoff", backoff,
-						"error", err)
-					time.Sleep(backoff)
-					continue
-				}
-				return QueueMessage{}, fmt.Errorf("failed to ensure queue after %d attempts: %w", maxRetries, err)
-			}
+	return nil
+}
 
-			// Try to start consuming
-			msgs, err = t.channel.Consume(
-				queueName,
-				"",    // consumer tag
-				false, // auto-ack
-				false, // exclusive
-				false, // no-local
-				false, // no-wait
-				nil,   // args
-			)
-			if err == nil {
-				break
-			}
+// consumeChannel returns the channel deliveries are acknowledged on
+func (t *RabbitMQTransport) consumeChannel() rabbitmqChannel {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.channel
+}
 
-			if attempt < maxRetries-1 {
+// rabbitmqReceipt is the receipt handle of a RabbitMQ message
+type rabbitmqReceipt struct {
+	deliveryTag uint64
+	headers     amqp.Table // Headers as received, copied with their AMQP types on republish
+}
 
-				backoff := initialBackoff * (1 << uint(attempt))
-				slog.Warn("Failed to start consuming, retrying",
-					"queue", queueName,
-					"attempt", attempt+1,
-					"maxRetries", maxRetries,
-					"backoff", backoff,
-					"error", err)
-				time.Sleep(backoff)
-			}
-		}
+// receipt returns the receipt handle of msg
+func receipt(msg QueueMessage) (rabbitmqReceipt, error) {
+	handle, ok := msg.ReceiptHandle.(rabbitmqReceipt)
+	if !ok {
+		return rabbitmqReceipt{}, fmt.Errorf("invalid receipt handle type for RabbitMQ")
+	}
+	return handle, nil
+}
 
-		if err != nil {
-			slog.Error("Failed to start consuming after retries", "queue", queueName, "error", err)
-			return QueueMessage{}, fmt.Errorf("failed to start consuming after %d attempts: %w", maxRetries, err)
+// deliveryCount returns the number of the current delivery: the deliveries recorded by
+// earlier republishes plus this one, and one more when the broker flags the message as
+// redelivered (requeued as is, e.g. after a consumer crash). Without republishing the
+// count is a lower bound.
+func deliveryCount(msg amqp.Delivery) int {
+	count := 1
+	if v, ok := msg.Headers[DeliveryCountHeader]; ok {
+		if previous, err := strconv.Atoi(fmt.Sprintf("%v", v)); err == nil && previous > 0 {
+			count = previous + 1
 		}
-
-		slog.Info("Consumer started successfully", "queue", queueName, "msgs_chan_nil", msgs == nil)
-
-		t.consumer = msgs
-		t.consumerQueue = queueName
-		slog.Info("Consumer initialization complete", "queue", queueName)
 	}
-
-	// Wait for a message with context support
-	slog.Info("Waiting for message from consumer channel", "queue", queueName, "consumer_nil", t.consumer == nil)
-
-	// Check if consumer channel is nil (should never happen)
-	if t.consumer == nil {
-		return QueueMessage{}, fmt.Errorf("consumer channel is nil")
+	if msg.Redelivered {
+		count++
 	}
+	return count
+}
 
-	select {
-	case msg, ok := <-t.consumer:
-		slog.Info("Received message from consumer channel", "ok", ok, "queue", queueName, "message_id", msg.MessageId)
-		if !ok {
-			// Channel closed - reset consumer to trigger reconnection on next call
-			t.consumer = nil
-			t.consumerQueue = ""
-			return QueueMessage{}, fmt.Errorf("channel closed")
-		}
-
-		// Convert AMQP headers to QueueMessage headers
-		headers := make(map[string]string)
-		headers["QueueName"] = queueName
-		for k, v := range msg.Headers {
-			headers[k] = fmt.Sprintf("%v", v)
-		}
-
-		return QueueMessage{
-			ID:            msg.MessageId,
-			Body:          msg.Body,
-			ReceiptHandle: msg.DeliveryTag,
-			Headers:       headers,
-		}, nil
-
-	case <-ctx.Done():
-		slog.Info("Context cancelled while waiting for message", "queue", queueName, "err", ctx.Err())
-		return QueueMessage{}, ctx.Err()
+// Send sends a message to RabbitMQ on a publish channel
+func (t *RabbitMQTransport) Send(ctx context.Context, queueName string, body []byte) (err error) {
+	ch, err := t.publish.get(ctx)
+	if err != nil {
+		return err
 	}
-}
+	defer func() { t.publish.put(ch, err) }()
 
-// Send sends a message to RabbitMQ
-func (t *RabbitMQTransport) Send(ctx context.Context, queueName string, body []byte) error {
 	// Ensure queue exists
-	if err := t.ensureQueue(queueName); err != nil {
+	if err := t.ensureQueue(ch, queueName); err != nil {
 		return err
 	}
 
@@ -381,7 +510,7 @@ func (t *RabbitMQTransport) Send(ctx context.Context, queueName string, body []b
 	}
 
 	// Publish message
-	err := t.channel.PublishWithContext(
+	err = ch.PublishWithContext(
 		ctx,
 		t.exchange,
 		routingKey, // routing key (actor name without prefix)
@@ -401,38 +530,308 @@ func (t *RabbitMQTransport) Send(ctx context.Context, queueName string, body []b
 	return nil
 }
 
-// Ack acknowledges a message
+// Reply publishes a message directly to a reply queue through the default exchange.
+// Reply queues are exclusive to the requester, so they are not declared here; a reply
+// to a queue that no longer exists is dropped by the broker.
+func (t *RabbitMQTransport) Reply(ctx context.Context, replyTo, correlationID string, body []byte) (err error) {
+	ch, err := t.publish.get(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { t.publish.put(ch, err) }()
+
+	err = ch.PublishWithContext(
+		ctx,
+		"",      // default exchange routes by queue name
+		replyTo, // routing key
+		false,   // mandatory
+		false,   // immediate
+		amqp.Publishing{
+			ContentType:   "application/json",
+			CorrelationId: correlationID,
+			Body:          body,
+			Timestamp:     time.Now(),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to publish reply to RabbitMQ: %w", err)
+	}
+
+	return nil
+}
+
+// Ack acknowledges a message (no-op with auto-ack)
 func (t *RabbitMQTransport) Ack(ctx context.Context, msg QueueMessage) error {
-	deliveryTag, ok := msg.ReceiptHandle.(uint64)
-	if !ok {
-		return fmt.Errorf("invalid receipt handle type for RabbitMQ")
+	if t.autoAck {
+		return nil
 	}
 
-	if err := t.channel.Ack(deliveryTag, false); err != nil {
+	handle, err := receipt(msg)
+	if err != nil {
+		return err
+	}
+
+	if err := t.consumeChannel().Ack(handle.deliveryTag, false); err != nil {
 		return fmt.Errorf("failed to ack message: %w", err)
 	}
 
 	return nil
 }
 
-// Nack negatively acknowledges a message (requeue)
+// Nack negatively acknowledges a message (requeue).
+// With auto-ack the broker already removed the message, so it cannot be requeued.
+// With RepublishOnNack a copy carrying the delivery count is published to the end of the
+// source queue and the message is acked; if the republish fails the message is requeued.
 func (t *RabbitMQTransport) Nack(ctx context.Context, msg QueueMessage) error {
-	deliveryTag, ok := msg.ReceiptHandle.(uint64)
-	if !ok {
-		return fmt.Errorf("invalid receipt handle type for RabbitMQ")
+	if t.autoAck {
+		slog.Warn("Message failed with auto-ack enabled and is not redelivered", "id", msg.ID)
+		return nil
+	}
+
+	if t.republishNack {
+		err := t.republish(ctx, msg)
+		if err == nil {
+			return t.Ack(ctx, msg)
+		}
+		slog.Warn("Failed to republish NACKed message, requeueing it", "id", msg.ID, "error", err)
+	}
+
+	handle, err := receipt(msg)
+	if err != nil {
+		return err
 	}
 
-	if err := t.channel.Nack(deliveryTag, false, true); err != nil {
+	if err := t.consumeChannel().Nack(handle.deliveryTag, false, true); err != nil {
 		return fmt.Errorf("failed to nack message: %w", err)
 	}
 
 	return nil
 }
 
+// republish publishes a copy of msg to the queue it was received from
+func (t *RabbitMQTransport) republish(ctx context.Context, msg QueueMessage) (err error) {
+	queueName := msg.Headers["QueueName"]
+	if queueName == "" {
+		return fmt.Errorf("message has no source queue")
+	}
+
+	ch, err := t.publish.get(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { t.publish.put(ch, err) }()
+
+	if err := publishConfirmed(ctx, ch, queueName, amqp.Publishing{
+		DeliveryMode: amqp.Persistent,
+		ContentType:  "application/json",
+		MessageId:    msg.ID,
+		Headers:      republishHeaders(msg),
+		Body:         msg.Body,
+		Timestamp:    time.Now(),
+	}); err != nil {
+		return fmt.Errorf("failed to republish to %s: %w", queueName, err)
+	}
+
+	return nil
+}
+
+// publishConfirmed publishes msg to queueName through the default exchange and waits for
+// the broker to confirm it, so the message it copies can be acked without being lost.
+// A channel not in confirm mode is not awaited.
+func publishConfirmed(ctx context.Context, ch rabbitmqChannel, queueName string, msg amqp.Publishing) error {
+	confirm, err := ch.PublishWithDeferredConfirmWithContext(ctx, "", queueName, false, false, msg)
+	if err != nil {
+		return err
+	}
+	if confirm == nil {
+		return nil
+	}
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed waiting for publisher confirm: %w", err)
+	}
+	if !acked {
+		return fmt.Errorf("publish was not confirmed by the broker")
+	}
+	return nil
+}
+
+// republishHeaders returns the headers of a copy of msg: user headers, with the AMQP types
+// they were received with, and the delivery count. QueueName is added on receive and x-death
+// by the broker, so neither is kept.
+func republishHeaders(msg QueueMessage) amqp.Table {
+	headers := amqp.Table{}
+	if handle, err := receipt(msg); err == nil && handle.headers != nil {
+		for k, v := range handle.headers {
+			headers[k] = v
+		}
+	} else {
+		for k, v := range msg.Headers {
+			headers[k] = v
+		}
+	}
+	delete(headers, "QueueName")
+	delete(headers, "x-death")
+	headers[DeliveryCountHeader] = int64(DeliveryCount(msg))
+	return headers
+}
+
+// Retry acknowledges msg and publishes a copy to a delay queue whose messages expire after delay
+// and are dead-lettered back to the original queue via the default exchange
+func (t *RabbitMQTransport) Retry(ctx context.Context, msg QueueMessage, delay time.Duration, attempt int) error {
+	queueName := msg.Headers["QueueName"]
+	if queueName == "" {
+		return fmt.Errorf("message has no source queue")
+	}
+
+	if err := t.publishRetry(ctx, msg, queueName, delay, attempt); err != nil {
+		return err
+	}
+
+	return t.Ack(ctx, msg)
+}
+
+// publishRetry declares the delay queue of queueName for delay and publishes a copy of msg to it
+func (t *RabbitMQTransport) publishRetry(ctx context.Context, msg QueueMessage, queueName string, delay time.Duration, attempt int) (err error) {
+	ch, err := t.publish.get(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { t.publish.put(ch, err) }()
+
+	retryQueue := RetryQueueName(queueName, delay)
+	t.retryMu.Lock()
+	declared := t.retryQueues[retryQueue]
+	t.retryMu.Unlock()
+	if !declared {
+		if _, err := ch.QueueDeclare(
+			retryQueue,
+			true,  // durable
+			false, // delete when unused
+			false, // exclusive
+			false, // no-wait
+			amqp.Table{
+				"x-message-ttl":             delay.Milliseconds(),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": queueName,
+			},
+		); err != nil {
+			return fmt.Errorf("failed to declare retry queue %s: %w", retryQueue, err)
+		}
+		t.retryMu.Lock()
+		if t.retryQueues == nil {
+			t.retryQueues = make(map[string]bool)
+		}
+		t.retryQueues[retryQueue] = true
+		t.retryMu.Unlock()
+	}
+
+	headers := republishHeaders(msg)
+	headers[RetryAttemptHeader] = int64(attempt)
+
+	if err := publishConfirmed(ctx, ch, retryQueue, amqp.Publishing{
+		DeliveryMode: amqp.Persistent,
+		ContentType:  "application/json",
+		MessageId:    msg.ID,
+		Headers:      headers,
+		Body:         msg.Body,
+		Timestamp:    time.Now(),
+	}); err != nil {
+		return fmt.Errorf("failed to publish to retry queue %s: %w", retryQueue, err)
+	}
+
+	return nil
+}
+
+// DeadLetter rejects a message without requeue, moving it to the queue's DLQ when one is configured.
+// With auto-ack the message was already removed and is dropped.
+func (t *RabbitMQTransport) DeadLetter(ctx context.Context, msg QueueMessage) error {
+	if t.autoAck {
+		slog.Warn("Message dropped instead of dead-lettered, auto-ack is enabled", "id", msg.ID)
+		return nil
+	}
+
+	handle, err := receipt(msg)
+	if err != nil {
+		return err
+	}
+
+	if err := t.consumeChannel().Nack(handle.deliveryTag, false, false); err != nil {
+		return fmt.Errorf("failed to dead-letter message: %w", err)
+	}
+
+	return nil
+}
+
 // Close closes the RabbitMQ connection
 func (t *RabbitMQTransport) Close() error {
-	if err := t.channel.Close(); err != nil {
+	t.publish.close()
+	if err := t.consumeChannel().Close(); err != nil {
 		return err
 	}
 	return t.conn.Close()
 }
+
+// splitBrokerURLs splits a comma-separated list of broker URLs
+func splitBrokerURLs(urls string) []string {
+	var result []string
+	for _, url := range strings.Split(urls, ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			result = append(result, url)
+		}
+	}
+	return result
+}
+
+var (
+	connectMinBackoff = time.Second
+	connectMaxBackoff = 30 * time.Second
+)
+
+// dialWithRetry connects to the first reachable broker, retrying with exponential backoff
+// until a broker accepts the connection or ctx is done
+func dialWithRetry(ctx context.Context, urls []string, dial func(url string) (*amqp.Connection, error)) (*amqp.Connection, error) {
+	backoff := connectMinBackoff
+	for attempt := 1; ; attempt++ {
+		conn, err := dialFirst(urls, dial)
+		if err == nil {
+			return conn, nil
+		}
+		if len(urls) == 0 {
+			return nil, err
+		}
+
+		slog.Warn("Failed to connect to RabbitMQ, retrying", "attempt", attempt, "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to connect to RabbitMQ after %d attempts: %w", attempt, err)
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, connectMaxBackoff)
+	}
+}
+
+// dialFirst connects to the first reachable broker, trying urls in order, so a lost
+// connection fails over to the next broker and returns to the primary once it is back.
+// Brokers are identified by position in logs, since URLs carry credentials.
+func dialFirst(urls []string, dial func(url string) (*amqp.Connection, error)) (*amqp.Connection, error) {
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("no RabbitMQ URL configured")
+	}
+
+	var errs []error
+	for i, url := range urls {
+		conn, err := dial(url)
+		if err == nil {
+			if i > 0 {
+				slog.Warn("Connected to failover RabbitMQ broker", "broker", i, "brokers", len(urls))
+			}
+			return conn, nil
+		}
+		if len(urls) > 1 {
+			slog.Warn("Failed to connect to RabbitMQ broker", "broker", i, "error", err)
+		}
+		errs = append(errs, fmt.Errorf("broker %d: %w", i, err))
+	}
+	return nil, errors.Join(errs...)
+}
@@ -3,6 +3,9 @@ package transport
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -37,6 +40,7 @@ type mockRabbitMQChannel struct {
 	queueDeclarePassiveFunc  func(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
 	queueBindFunc            func(name, key, exchange string, noWait bool, args amqp.Table) error
 	consumeFunc              func(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error)
+	cancelFunc               func(consumer string, noWait bool) error
 	publishWithContextFunc   func(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
 	ackFunc                  func(tag uint64, multiple bool) error
 	nackFunc                 func(tag uint64, multiple, requeue bool) error
@@ -95,6 +99,13 @@ func (m *mockRabbitMQChannel) Consume(queue, consumer string, autoAck, exclusive
 	return make(<-chan amqp.Delivery), nil
 }
 
+func (m *mockRabbitMQChannel) Cancel(consumer string, noWait bool) error {
+	if m.cancelFunc != nil {
+		return m.cancelFunc(consumer, noWait)
+	}
+	return nil
+}
+
 func (m *mockRabbitMQChannel) PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
 	if m.publishWithContextFunc != nil {
 		return m.publishWithContextFunc(ctx, exchange, key, mandatory, immediate, msg)
@@ -102,6 +113,12 @@ func (m *mockRabbitMQChannel) PublishWithContext(ctx context.Context, exchange,
 	return nil
 }
 
+// PublishWithDeferredConfirmWithContext publishes like PublishWithContext on a channel
+// that is not in confirm mode
+func (m *mockRabbitMQChannel) PublishWithDeferredConfirmWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) (*amqp.DeferredConfirmation, error) {
+	return nil, m.PublishWithContext(ctx, exchange, key, mandatory, immediate, msg)
+}
+
 func (m *mockRabbitMQChannel) Ack(tag uint64, multiple bool) error {
 	if m.ackFunc != nil {
 		return m.ackFunc(tag, multiple)
@@ -131,9 +148,17 @@ func createMockRabbitMQTransport(mockConn rabbitmqConnection, mockChannel rabbit
 		amqpChan = ch
 	}
 
+	// Publishing goes through a single-channel pool backed by the same mock
+	publish := &publishPool{
+		slots: make(chan rabbitmqChannel, 1),
+		open:  func() (rabbitmqChannel, error) { return mockChannel, nil },
+	}
+	publish.slots <- mockChannel
+
 	return &RabbitMQTransport{
 		conn:          mockConn,
 		channel:       mockChannel,
+		publish:       publish,
 		exchange:      "test-exchange",
 		prefetchCount: 1,
 		amqpChannel:   amqpChan,
@@ -161,7 +186,7 @@ func TestRabbitMQTransport_EnsureQueue(t *testing.T) {
 
 		transport := createMockRabbitMQTransport(nil, mockChannel)
 
-		err := transport.ensureQueue(queueName)
+		err := transport.ensureQueue(mockChannel, queueName)
 		if err != nil {
 			t.Errorf("ensureQueue() error = %v, want nil", err)
 		}
@@ -179,7 +204,7 @@ func TestRabbitMQTransport_EnsureQueue(t *testing.T) {
 
 		transport := createMockRabbitMQTransport(nil, mockChannel)
 
-		err := transport.ensureQueue(queueName)
+		err := transport.ensureQueue(mockChannel, queueName)
 		if err == nil {
 			t.Error("ensureQueue() error = nil, want error")
 		}
@@ -220,8 +245,8 @@ func TestRabbitMQTransport_Receive(t *testing.T) {
 		if string(msg.Body) != `{"test":"message"}` {
 			t.Errorf("Body = %v, want {\"test\":\"message\"}", string(msg.Body))
 		}
-		if msg.ReceiptHandle != uint64(42) {
-			t.Errorf("ReceiptHandle = %v, want 42", msg.ReceiptHandle)
+		if handle, ok := msg.ReceiptHandle.(rabbitmqReceipt); !ok || handle.deliveryTag != 42 {
+			t.Errorf("ReceiptHandle = %v, want delivery tag 42", msg.ReceiptHandle)
 		}
 		if msg.Headers["trace_id"] != "trace-xyz" {
 			t.Errorf("Headers[trace_id] = %v, want trace-xyz", msg.Headers["trace_id"])
@@ -229,6 +254,44 @@ func TestRabbitMQTransport_Receive(t *testing.T) {
 		if msg.Headers["QueueName"] != queueName {
 			t.Errorf("Headers[QueueName] = %v, want %v", msg.Headers["QueueName"], queueName)
 		}
+		if DeliveryCount(msg) != 1 {
+			t.Errorf("DeliveryCount() = %d, want 1", DeliveryCount(msg))
+		}
+	})
+
+	t.Run("delivery count", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			header      interface{}
+			redelivered bool
+			want        int
+		}{
+			{name: "first delivery", want: 1},
+			{name: "requeued by the broker", redelivered: true, want: 2},
+			{name: "republished", header: int64(2), want: 3},
+			{name: "republished then requeued", header: int64(2), redelivered: true, want: 4},
+			{name: "invalid header", header: "many", want: 1},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				delivery := amqp.Delivery{DeliveryTag: 1, Redelivered: tt.redelivered, Headers: amqp.Table{}}
+				if tt.header != nil {
+					delivery.Headers[DeliveryCountHeader] = tt.header
+				}
+				deliveryChan := make(chan amqp.Delivery, 1)
+				deliveryChan <- delivery
+
+				transport := createMockRabbitMQTransport(nil, &mockRabbitMQChannel{deliveryChan: deliveryChan})
+				msg, err := transport.Receive(ctx, queueName)
+				if err != nil {
+					t.Fatalf("Receive() error = %v", err)
+				}
+				if got := DeliveryCount(msg); got != tt.want {
+					t.Errorf("DeliveryCount() = %d, want %d", got, tt.want)
+				}
+			})
+		}
 	})
 
 	t.Run("context cancellation", func(t *testing.T) {
@@ -378,7 +441,7 @@ func TestRabbitMQTransport_Ack(t *testing.T) {
 		transport := createMockRabbitMQTransport(nil, mockChannel)
 
 		msg := QueueMessage{
-			ReceiptHandle: deliveryTag,
+			ReceiptHandle: rabbitmqReceipt{deliveryTag: deliveryTag},
 		}
 
 		err := transport.Ack(ctx, msg)
@@ -413,7 +476,7 @@ func TestRabbitMQTransport_Ack(t *testing.T) {
 		transport := createMockRabbitMQTransport(nil, mockChannel)
 
 		msg := QueueMessage{
-			ReceiptHandle: deliveryTag,
+			ReceiptHandle: rabbitmqReceipt{deliveryTag: deliveryTag},
 		}
 
 		err := transport.Ack(ctx, msg)
@@ -449,7 +512,7 @@ func TestRabbitMQTransport_Nack(t *testing.T) {
 		transport := createMockRabbitMQTransport(nil, mockChannel)
 
 		msg := QueueMessage{
-			ReceiptHandle: deliveryTag,
+			ReceiptHandle: rabbitmqReceipt{deliveryTag: deliveryTag},
 		}
 
 		err := transport.Nack(ctx, msg)
@@ -484,7 +547,7 @@ func TestRabbitMQTransport_Nack(t *testing.T) {
 		transport := createMockRabbitMQTransport(nil, mockChannel)
 
 		msg := QueueMessage{
-			ReceiptHandle: deliveryTag,
+			ReceiptHandle: rabbitmqReceipt{deliveryTag: deliveryTag},
 		}
 
 		err := transport.Nack(ctx, msg)
@@ -494,6 +557,88 @@ func TestRabbitMQTransport_Nack(t *testing.T) {
 	})
 }
 
+func TestRabbitMQTransport_NackRepublish(t *testing.T) {
+	ctx := context.Background()
+	msg := QueueMessage{
+		ID:            "msg-1",
+		Body:          []byte(`{"id":"env-1"}`),
+		ReceiptHandle: rabbitmqReceipt{deliveryTag: 7},
+		Headers:       map[string]string{"QueueName": "asya-infer", "trace_id": "abc", DeliveryCountHeader: "2"},
+	}
+
+	t.Run("republishes with delivery count and acks", func(t *testing.T) {
+		var publishedKey string
+		var published amqp.Publishing
+		acked := false
+
+		mockChannel := &mockRabbitMQChannel{
+			publishWithContextFunc: func(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+				if exchange != "" {
+					t.Errorf("exchange = %q, want default exchange", exchange)
+				}
+				publishedKey = key
+				published = msg
+				return nil
+			},
+			ackFunc: func(tag uint64, multiple bool) error {
+				acked = tag == 7
+				return nil
+			},
+			nackFunc: func(tag uint64, multiple, requeue bool) error {
+				t.Error("message must not be requeued")
+				return nil
+			},
+		}
+		tp := createMockRabbitMQTransport(nil, mockChannel)
+		tp.republishNack = true
+
+		if err := tp.Nack(ctx, msg); err != nil {
+			t.Fatalf("Nack() error = %v", err)
+		}
+		if publishedKey != "asya-infer" {
+			t.Errorf("published to %q, want asya-infer", publishedKey)
+		}
+		if published.MessageId != "msg-1" || string(published.Body) != `{"id":"env-1"}` {
+			t.Errorf("published message = %+v", published)
+		}
+		if published.Headers[DeliveryCountHeader] != int64(2) || published.Headers["trace_id"] != "abc" {
+			t.Errorf("published headers = %v", published.Headers)
+		}
+		if _, ok := published.Headers["QueueName"]; ok {
+			t.Error("QueueName header must not be republished")
+		}
+		if !acked {
+			t.Error("original message was not acked")
+		}
+	})
+
+	t.Run("requeues when republish fails", func(t *testing.T) {
+		requeued := false
+		mockChannel := &mockRabbitMQChannel{
+			publishWithContextFunc: func(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+				return errors.New("channel closed")
+			},
+			ackFunc: func(tag uint64, multiple bool) error {
+				t.Error("message must not be acked")
+				return nil
+			},
+			nackFunc: func(tag uint64, multiple, requeue bool) error {
+				requeued = requeue
+				return nil
+			},
+		}
+		tp := createMockRabbitMQTransport(nil, mockChannel)
+		tp.republishNack = true
+
+		if err := tp.Nack(ctx, msg); err != nil {
+			t.Fatalf("Nack() error = %v", err)
+		}
+		if !requeued {
+			t.Error("message was not requeued")
+		}
+	})
+}
+
 func TestRabbitMQTransport_Close(t *testing.T) {
 	t.Run("successful close", func(t *testing.T) {
 		channelClosed := false
@@ -614,6 +759,126 @@ func TestRabbitMQTransport_ReceiveConsumerCaching(t *testing.T) {
 	})
 }
 
+func TestRabbitMQTransport_ReceiveMultipleQueues(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	queues := []string{"asya-source-a", "asya-source-b"}
+	deliveries := make(map[string]chan amqp.Delivery)
+	for _, queue := range queues {
+		deliveries[queue] = make(chan amqp.Delivery, 3)
+		for i := 1; i <= 3; i++ {
+			deliveries[queue] <- amqp.Delivery{
+				MessageId:   fmt.Sprintf("%s-%d", queue, i),
+				DeliveryTag: uint64(i),
+			}
+		}
+	}
+
+	consumed := make(map[string]int)
+	mockChannel := &mockRabbitMQChannel{
+		consumeFunc: func(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+			consumed[queue]++
+			return deliveries[queue], nil
+		},
+	}
+	transport := createMockRabbitMQTransport(nil, mockChannel)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(queues))
+	for _, queue := range queues {
+		wg.Add(1)
+		go func(queue string) {
+			defer wg.Done()
+			for i := 1; i <= 3; i++ {
+				msg, err := transport.Receive(ctx, queue)
+				if err != nil {
+					errs <- fmt.Errorf("Receive(%s) error = %w", queue, err)
+					return
+				}
+				if want := fmt.Sprintf("%s-%d", queue, i); msg.ID != want || msg.Headers["QueueName"] != queue {
+					errs <- fmt.Errorf("Receive(%s) = %s from %s, want %s", queue, msg.ID, msg.Headers["QueueName"], want)
+					return
+				}
+				if err := transport.Ack(ctx, msg); err != nil {
+					errs <- fmt.Errorf("Ack(%s) error = %w", queue, err)
+					return
+				}
+			}
+		}(queue)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+	for _, queue := range queues {
+		if consumed[queue] != 1 {
+			t.Errorf("Consume(%s) calls = %d, want 1", queue, consumed[queue])
+		}
+	}
+}
+
+func TestRabbitMQTransport_CancelConsumers(t *testing.T) {
+	ctx := context.Background()
+
+	var tags []string
+	var cancelled []string
+	var requeued []uint64
+	deliveries := make(map[string]chan amqp.Delivery)
+	mockChannel := &mockRabbitMQChannel{
+		consumeFunc: func(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+			tags = append(tags, consumer)
+			deliveries[consumer] = make(chan amqp.Delivery, 3)
+			deliveries[consumer] <- amqp.Delivery{MessageId: "msg-1", DeliveryTag: 1}
+			deliveries[consumer] <- amqp.Delivery{MessageId: "msg-2", DeliveryTag: 2}
+			deliveries[consumer] <- amqp.Delivery{MessageId: "msg-3", DeliveryTag: 3}
+			return deliveries[consumer], nil
+		},
+		cancelFunc: func(consumer string, noWait bool) error {
+			// Like amqp091, the deliveries channel closes once buffered deliveries are handed out
+			cancelled = append(cancelled, consumer)
+			close(deliveries[consumer])
+			return nil
+		},
+		nackFunc: func(tag uint64, multiple, requeue bool) error {
+			if !requeue {
+				t.Errorf("Nack(%d) requeue = false, want true", tag)
+			}
+			requeued = append(requeued, tag)
+			return nil
+		},
+	}
+	transport := createMockRabbitMQTransport(nil, mockChannel)
+
+	if _, err := transport.Receive(ctx, testQueueName); err != nil {
+		t.Fatalf("Receive() error = %v", err)
+	}
+
+	if err := transport.CancelConsumers(ctx); err != nil {
+		t.Fatalf("CancelConsumers() error = %v", err)
+	}
+	if len(cancelled) != 1 || cancelled[0] != tags[0] {
+		t.Errorf("Cancelled consumers = %v, want [%s]", cancelled, tags[0])
+	}
+	if len(requeued) != 2 || requeued[0] != 2 || requeued[1] != 3 {
+		t.Errorf("Requeued delivery tags = %v, want [2 3]", requeued)
+	}
+
+	// Consumption resumes with a new consumer
+	msg, err := transport.Receive(ctx, testQueueName)
+	if err != nil {
+		t.Fatalf("Receive() after cancel error = %v", err)
+	}
+	if msg.ID != "msg-1" {
+		t.Errorf("Receive() after cancel = %s, want msg-1", msg.ID)
+	}
+	if len(tags) != 2 || tags[0] == tags[1] {
+		t.Errorf("Consumer tags = %v, want two distinct tags", tags)
+	}
+}
+
 func TestRabbitMQTransport_SendTimestamp(t *testing.T) {
 	ctx := context.Background()
 	queueName := testQueueName
@@ -644,3 +909,463 @@ func TestRabbitMQTransport_SendTimestamp(t *testing.T) {
 		}
 	})
 }
+
+func TestRabbitMQTransport_Retry(t *testing.T) {
+	ctx := context.Background()
+
+	var declared []string
+	var declareArgs amqp.Table
+	var publishedKey string
+	var published amqp.Publishing
+	acked := false
+
+	mockChannel := &mockRabbitMQChannel{
+		queueDeclareFunc: func(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
+			declared = append(declared, name)
+			declareArgs = args
+			return amqp.Queue{Name: name}, nil
+		},
+		publishWithContextFunc: func(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+			if exchange != "" {
+				t.Errorf("exchange = %q, want default exchange", exchange)
+			}
+			publishedKey = key
+			published = msg
+			return nil
+		},
+		ackFunc: func(tag uint64, multiple bool) error {
+			acked = tag == 7
+			return nil
+		},
+	}
+	tp := createMockRabbitMQTransport(nil, mockChannel)
+
+	msg := QueueMessage{
+		ID:            "msg-1",
+		Body:          []byte(`{"id":"env-1"}`),
+		ReceiptHandle: rabbitmqReceipt{deliveryTag: 7},
+		Headers:       map[string]string{"QueueName": "asya-infer", "trace_id": "abc", "x-death": "[...]", RetryAttemptHeader: "1"},
+	}
+
+	if err := tp.Retry(ctx, msg, time.Minute, 2); err != nil {
+		t.Fatalf("Retry() error = %v", err)
+	}
+
+	if publishedKey != "asya-infer-retry-1m0s" {
+		t.Errorf("published to %q, want asya-infer-retry-1m0s", publishedKey)
+	}
+	if len(declared) != 1 || declared[0] != "asya-infer-retry-1m0s" {
+		t.Errorf("declared queues = %v", declared)
+	}
+	if declareArgs["x-message-ttl"] != int64(60000) || declareArgs["x-dead-letter-exchange"] != "" || declareArgs["x-dead-letter-routing-key"] != "asya-infer" {
+		t.Errorf("retry queue args = %v", declareArgs)
+	}
+	if string(published.Body) != `{"id":"env-1"}` || published.MessageId != "msg-1" {
+		t.Errorf("published body = %s, message ID = %q", published.Body, published.MessageId)
+	}
+	if published.Headers[RetryAttemptHeader] != int64(2) || published.Headers["trace_id"] != "abc" || published.Headers[DeliveryCountHeader] != int64(1) {
+		t.Errorf("published headers = %v", published.Headers)
+	}
+	if _, ok := published.Headers["QueueName"]; ok {
+		t.Error("QueueName header must not be republished")
+	}
+	if _, ok := published.Headers["x-death"]; ok {
+		t.Error("x-death header must not be republished")
+	}
+	if !acked {
+		t.Error("original message was not acked")
+	}
+
+	// The delay queue is declared once per transport
+	if err := tp.Retry(ctx, msg, time.Minute, 3); err != nil {
+		t.Fatalf("Retry() error = %v", err)
+	}
+	if len(declared) != 1 {
+		t.Errorf("retry queue declared %d times, want 1", len(declared))
+	}
+}
+
+func TestRabbitMQTransport_RetryKeepsHeaderTypes(t *testing.T) {
+	var published amqp.Publishing
+	mockChannel := &mockRabbitMQChannel{
+		publishWithContextFunc: func(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+			published = msg
+			return nil
+		},
+	}
+	tp := createMockRabbitMQTransport(nil, mockChannel)
+
+	received := amqp.Table{
+		"priority": int32(5),
+		"sampled":  true,
+		"x-death":  []interface{}{amqp.Table{"count": int64(1)}},
+	}
+	msg := QueueMessage{
+		ID:            "msg-1",
+		ReceiptHandle: rabbitmqReceipt{deliveryTag: 7, headers: received},
+		Headers:       map[string]string{"QueueName": "asya-infer", "priority": "5", "sampled": "true", "x-death": "[...]"},
+	}
+
+	if err := tp.Retry(context.Background(), msg, time.Minute, 1); err != nil {
+		t.Fatalf("Retry() error = %v", err)
+	}
+
+	if published.Headers["priority"] != int32(5) || published.Headers["sampled"] != true {
+		t.Errorf("published headers = %#v, want the received AMQP types", published.Headers)
+	}
+	if _, ok := published.Headers["x-death"]; ok {
+		t.Error("x-death header must not be republished")
+	}
+}
+
+func TestRabbitMQTransport_RetryConcurrent(t *testing.T) {
+	var mu sync.Mutex
+	declared := 0
+	mockChannel := &mockRabbitMQChannel{
+		queueDeclareFunc: func(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
+			mu.Lock()
+			declared++
+			mu.Unlock()
+			return amqp.Queue{Name: name}, nil
+		},
+	}
+	tp := createMockRabbitMQTransport(nil, mockChannel)
+
+	// Two publish channels, so retries run in parallel
+	tp.publish = &publishPool{
+		slots: make(chan rabbitmqChannel, 2),
+		open:  func() (rabbitmqChannel, error) { return mockChannel, nil },
+	}
+	tp.publish.slots <- mockChannel
+	tp.publish.slots <- mockChannel
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			msg := QueueMessage{
+				ID:            fmt.Sprintf("msg-%d", i),
+				ReceiptHandle: rabbitmqReceipt{deliveryTag: uint64(i + 1)},
+				Headers:       map[string]string{"QueueName": fmt.Sprintf("asya-infer-%d", i%2)},
+			}
+			if err := tp.Retry(context.Background(), msg, time.Minute, 1); err != nil {
+				t.Errorf("Retry() error = %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if declared < 2 {
+		t.Errorf("retry queues declared %d times, want at least one per source queue", declared)
+	}
+}
+
+func TestRabbitMQTransport_Retry_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("missing source queue", func(t *testing.T) {
+		tp := createMockRabbitMQTransport(nil, &mockRabbitMQChannel{})
+		if err := tp.Retry(ctx, QueueMessage{ReceiptHandle: rabbitmqReceipt{deliveryTag: 1}}, time.Second, 1); err == nil {
+			t.Error("Retry() error = nil, want error")
+		}
+	})
+
+	t.Run("publish failure does not ack", func(t *testing.T) {
+		mockChannel := &mockRabbitMQChannel{
+			publishWithContextFunc: func(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+				return errors.New("publish failed")
+			},
+			ackFunc: func(tag uint64, multiple bool) error {
+				t.Error("message acked after failed publish")
+				return nil
+			},
+		}
+		tp := createMockRabbitMQTransport(nil, mockChannel)
+		msg := QueueMessage{ReceiptHandle: rabbitmqReceipt{deliveryTag: 1}, Headers: map[string]string{"QueueName": "asya-infer"}}
+		if err := tp.Retry(ctx, msg, time.Second, 1); err == nil {
+			t.Error("Retry() error = nil, want error")
+		}
+	})
+}
+
+func TestRabbitMQTransport_DeadLetter(t *testing.T) {
+	requeued := true
+	mockChannel := &mockRabbitMQChannel{
+		nackFunc: func(tag uint64, multiple, requeue bool) error {
+			requeued = requeue
+			return nil
+		},
+	}
+	tp := createMockRabbitMQTransport(nil, mockChannel)
+
+	if err := tp.DeadLetter(context.Background(), QueueMessage{ReceiptHandle: rabbitmqReceipt{deliveryTag: 3}}); err != nil {
+		t.Fatalf("DeadLetter() error = %v", err)
+	}
+	if requeued {
+		t.Error("DeadLetter() requeued the message, want reject without requeue")
+	}
+}
+
+func TestRabbitMQTransport_AutoAck(t *testing.T) {
+	ctx := context.Background()
+
+	deliveryChan := make(chan amqp.Delivery, 1)
+	deliveryChan <- amqp.Delivery{MessageId: "msg-1", DeliveryTag: 5, Body: []byte(`{}`)}
+
+	consumedWithAutoAck := false
+	mockChannel := &mockRabbitMQChannel{
+		deliveryChan: deliveryChan,
+		consumeFunc: func(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+			consumedWithAutoAck = autoAck
+			return deliveryChan, nil
+		},
+		ackFunc: func(tag uint64, multiple bool) error {
+			t.Error("Ack() sent an ack with auto-ack enabled")
+			return nil
+		},
+		nackFunc: func(tag uint64, multiple, requeue bool) error {
+			t.Error("Nack() or DeadLetter() sent a nack with auto-ack enabled")
+			return nil
+		},
+	}
+	tp := createMockRabbitMQTransport(nil, mockChannel)
+	tp.autoAck = true
+
+	msg, err := tp.Receive(ctx, testQueueName)
+	if err != nil {
+		t.Fatalf("Receive() error = %v", err)
+	}
+	if !consumedWithAutoAck {
+		t.Error("Receive() consumed without auto-ack")
+	}
+
+	if err := tp.Ack(ctx, msg); err != nil {
+		t.Errorf("Ack() error = %v", err)
+	}
+	if err := tp.Nack(ctx, msg); err != nil {
+		t.Errorf("Nack() error = %v", err)
+	}
+	if err := tp.DeadLetter(ctx, msg); err != nil {
+		t.Errorf("DeadLetter() error = %v", err)
+	}
+}
+
+func TestRabbitMQTransport_PublishChannels(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("publishes off the consume channel", func(t *testing.T) {
+		consumeChannel := &mockRabbitMQChannel{
+			queueDeclarePassiveFunc: func(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
+				t.Error("queue check on consume channel")
+				return amqp.Queue{}, nil
+			},
+			publishWithContextFunc: func(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+				t.Error("publish on consume channel")
+				return nil
+			},
+		}
+		published := 0
+		publishChannel := &mockRabbitMQChannel{
+			publishWithContextFunc: func(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+				published++
+				return nil
+			},
+		}
+
+		transport := createMockRabbitMQTransport(nil, consumeChannel)
+		transport.publish = &publishPool{
+			slots: make(chan rabbitmqChannel, 1),
+			open:  func() (rabbitmqChannel, error) { return publishChannel, nil },
+		}
+		transport.publish.slots <- publishChannel
+
+		if err := transport.Send(ctx, "asya-next", []byte(`{}`)); err != nil {
+			t.Fatalf("Send() error = %v, want nil", err)
+		}
+		if err := transport.Retry(ctx, QueueMessage{ReceiptHandle: rabbitmqReceipt{deliveryTag: 1}, Headers: map[string]string{"QueueName": "asya-next"}}, time.Second, 1); err != nil {
+			t.Fatalf("Retry() error = %v, want nil", err)
+		}
+		if published != 2 {
+			t.Errorf("published = %d, want 2", published)
+		}
+	})
+
+	t.Run("failed channel is replaced", func(t *testing.T) {
+		closed := false
+		broken := &mockRabbitMQChannel{
+			queueDeclarePassiveFunc: func(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
+				return amqp.Queue{}, errors.New("NOT_FOUND - no queue")
+			},
+			closeFunc: func() error {
+				closed = true
+				return nil
+			},
+		}
+		opened := 0
+		fresh := &mockRabbitMQChannel{}
+
+		transport := createMockRabbitMQTransport(nil, &mockRabbitMQChannel{})
+		transport.publish = &publishPool{
+			slots: make(chan rabbitmqChannel, 1),
+			open: func() (rabbitmqChannel, error) {
+				opened++
+				return fresh, nil
+			},
+		}
+		transport.publish.slots <- broken
+
+		if err := transport.Send(ctx, "asya-missing", []byte(`{}`)); err == nil {
+			t.Fatal("Send() error = nil, want error")
+		}
+		if !closed {
+			t.Error("failed publish channel was not closed")
+		}
+		if err := transport.Send(ctx, "asya-next", []byte(`{}`)); err != nil {
+			t.Fatalf("Send() error = %v, want nil", err)
+		}
+		if opened != 1 {
+			t.Errorf("opened = %d, want 1", opened)
+		}
+	})
+
+	t.Run("open failure keeps the slot", func(t *testing.T) {
+		fail := true
+		pool := &publishPool{
+			slots: make(chan rabbitmqChannel, 1),
+			open: func() (rabbitmqChannel, error) {
+				if fail {
+					return nil, errors.New("connection closed")
+				}
+				return &mockRabbitMQChannel{}, nil
+			},
+		}
+		pool.slots <- nil
+
+		if _, err := pool.get(ctx); err == nil {
+			t.Fatal("get() error = nil, want error")
+		}
+		fail = false
+		ch, err := pool.get(ctx)
+		if err != nil || ch == nil {
+			t.Fatalf("get() = %v, %v, want channel", ch, err)
+		}
+	})
+
+	t.Run("waits for a free channel", func(t *testing.T) {
+		pool := &publishPool{slots: make(chan rabbitmqChannel, 1)}
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		if _, err := pool.get(timeoutCtx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("get() error = %v, want context.DeadlineExceeded", err)
+		}
+	})
+}
+
+func TestSplitBrokerURLs(t *testing.T) {
+	got := splitBrokerURLs(" amqp://primary/ , amqp://secondary/,")
+	if len(got) != 2 || got[0] != "amqp://primary/" || got[1] != "amqp://secondary/" {
+		t.Errorf("splitBrokerURLs() = %v, want [amqp://primary/ amqp://secondary/]", got)
+	}
+	if got := splitBrokerURLs("amqp://only/"); len(got) != 1 {
+		t.Errorf("splitBrokerURLs() = %v, want one URL", got)
+	}
+}
+
+func TestDialFirst(t *testing.T) {
+	primary := &amqp.Connection{}
+	secondary := &amqp.Connection{}
+	urls := []string{"amqp://primary/", "amqp://secondary/"}
+
+	tests := []struct {
+		name      string
+		down      map[string]bool
+		want      *amqp.Connection
+		wantTried int
+		wantErr   bool
+	}{
+		{name: "primary up", down: map[string]bool{}, want: primary, wantTried: 1},
+		{name: "fails over to secondary", down: map[string]bool{"amqp://primary/": true}, want: secondary, wantTried: 2},
+		{name: "all brokers down", down: map[string]bool{"amqp://primary/": true, "amqp://secondary/": true}, wantTried: 2, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tried := 0
+			dial := func(url string) (*amqp.Connection, error) {
+				tried++
+				if tt.down[url] {
+					return nil, errors.New("connection refused")
+				}
+				if url == "amqp://primary/" {
+					return primary, nil
+				}
+				return secondary, nil
+			}
+
+			conn, err := dialFirst(urls, dial)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("dialFirst() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if conn != tt.want {
+				t.Errorf("dialFirst() returned wrong connection")
+			}
+			if tried != tt.wantTried {
+				t.Errorf("dial attempts = %d, want %d", tried, tt.wantTried)
+			}
+		})
+	}
+}
+
+func TestDialWithRetry(t *testing.T) {
+	minBackoff, maxBackoff := connectMinBackoff, connectMaxBackoff
+	connectMinBackoff, connectMaxBackoff = time.Millisecond, 4*time.Millisecond
+	t.Cleanup(func() { connectMinBackoff, connectMaxBackoff = minBackoff, maxBackoff })
+
+	broker := &amqp.Connection{}
+	urls := []string{"amqp://broker/"}
+
+	t.Run("waits for the broker to come up", func(t *testing.T) {
+		attempts := 0
+		dial := func(url string) (*amqp.Connection, error) {
+			if attempts++; attempts < 4 {
+				return nil, errors.New("connection refused")
+			}
+			return broker, nil
+		}
+
+		conn, err := dialWithRetry(context.Background(), urls, dial)
+		if err != nil {
+			t.Fatalf("dialWithRetry() error = %v", err)
+		}
+		if conn != broker {
+			t.Errorf("dialWithRetry() returned wrong connection")
+		}
+		if attempts != 4 {
+			t.Errorf("dial attempts = %d, want 4", attempts)
+		}
+	})
+
+	t.Run("gives up when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		dial := func(url string) (*amqp.Connection, error) { return nil, errors.New("connection refused") }
+		_, err := dialWithRetry(ctx, urls, dial)
+		if err == nil || !strings.Contains(err.Error(), "failed to connect to RabbitMQ after") {
+			t.Errorf("dialWithRetry() error = %v, want attempts exhausted", err)
+		}
+	})
+
+	t.Run("no URLs are not retried", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := dialWithRetry(ctx, nil, func(string) (*amqp.Connection, error) { return broker, nil })
+		if err == nil || !strings.Contains(err.Error(), "no RabbitMQ URL configured") {
+			t.Errorf("dialWithRetry() error = %v, want no URL configured", err)
+		}
+	})
+}
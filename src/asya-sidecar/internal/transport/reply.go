@@ -0,0 +1,10 @@
+package transport
+
+import "context"
+
+// Replier is implemented by transports that can publish directly to a reply queue
+// named in an envelope's reply_to (request-reply)
+type Replier interface {
+	// Reply publishes body to the replyTo queue, tagged with correlationID (the envelope ID)
+	Reply(ctx context.Context, replyTo, correlationID string, body []byte) error
+}
@@ -0,0 +1,27 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RetryAttemptHeader counts how many delayed retries a message has been through
+const RetryAttemptHeader = "x-asya-retry-attempt"
+
+// Retrier is implemented by transports that can redeliver a failed message after a delay
+// (retry ladder, ASYA_RETRY_SCHEDULE)
+type Retrier interface {
+	// Retry acknowledges msg and redelivers a copy to its queue after delay,
+	// with RetryAttemptHeader set to attempt
+	Retry(ctx context.Context, msg QueueMessage, delay time.Duration, attempt int) error
+
+	// DeadLetter rejects msg without requeueing, so the queue's dead-letter configuration applies
+	DeadLetter(ctx context.Context, msg QueueMessage) error
+}
+
+// RetryQueueName returns the delay queue holding retries of queueName for the given delay,
+// e.g. "asya-infer-retry-1m0s". Naming by delay keeps queues of different schedules apart.
+func RetryQueueName(queueName string, delay time.Duration) string {
+	return fmt.Sprintf("%s-retry-%s", queueName, delay)
+}
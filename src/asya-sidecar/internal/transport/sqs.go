@@ -11,6 +11,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
 )
 
 // sqsClient defines the interface for SQS operations
@@ -30,6 +31,9 @@ type SQSTransport struct {
 	visibilityTimeout int32
 	waitTimeSeconds   int32
 	queueURLCache     map[string]string
+
+	// How often the visibility of a message in processing is extended (0 disables)
+	heartbeatInterval time.Duration
 }
 
 // SQSConfig holds SQS-specific configuration
@@ -38,6 +42,10 @@ type SQSConfig struct {
 	BaseURL           string
 	VisibilityTimeout int32
 	WaitTimeSeconds   int32
+
+	// Fraction of VisibilityTimeout after which the visibility of a message in processing
+	// is extended by VisibilityTimeout again (0 disables)
+	VisibilityExtension float64
 }
 
 // NewSQSTransport creates a new SQS transport
@@ -80,9 +88,18 @@ func NewSQSTransport(ctx context.Context, cfg SQSConfig) (*SQSTransport, error)
 		visibilityTimeout: visibilityTimeout,
 		waitTimeSeconds:   waitTimeSeconds,
 		queueURLCache:     make(map[string]string),
+		heartbeatInterval: heartbeatInterval(visibilityTimeout, cfg.VisibilityExtension),
 	}, nil
 }
 
+// heartbeatInterval returns the given fraction of the visibility timeout, at least a second
+func heartbeatInterval(visibilityTimeout int32, fraction float64) time.Duration {
+	if fraction <= 0 {
+		return 0
+	}
+	return max(time.Duration(float64(visibilityTimeout)*fraction*float64(time.Second)), time.Second)
+}
+
 // resolveQueueURL resolves the full queue URL from queue name using GetQueueUrl API
 // with retry logic to handle cases where queue is temporarily missing
 func (t *SQSTransport) resolveQueueURL(ctx context.Context, queueName string) (string, error) {
@@ -183,6 +200,9 @@ func (t *SQSTransport) Receive(ctx context.Context, queueName string) (QueueMess
 			WaitTimeSeconds:       t.waitTimeSeconds,
 			VisibilityTimeout:     t.visibilityTimeout,
 			MessageAttributeNames: []string{"All"},
+			MessageSystemAttributeNames: []types.MessageSystemAttributeName{
+				types.MessageSystemAttributeNameApproximateReceiveCount,
+			},
 		})
 		if err != nil {
 			// Invalidate cache if queue no longer exists
@@ -205,6 +225,9 @@ func (t *SQSTransport) Receive(ctx context.Context, queueName string) (QueueMess
 				headers[k] = *v.StringValue
 			}
 		}
+		if count, ok := msg.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)]; ok {
+			headers[DeliveryCountHeader] = count
+		}
 
 		// Store receipt handle as "queueURL|receiptHandle"
 		receiptHandle := fmt.Sprintf("%s|%s", queueURL, aws.ToString(msg.ReceiptHandle))
@@ -260,15 +283,25 @@ func (t *SQSTransport) Ack(ctx context.Context, msg QueueMessage) error {
 // Nack negatively acknowledges a message by setting visibility timeout to 0
 // This makes the message immediately available for redelivery
 func (t *SQSTransport) Nack(ctx context.Context, msg QueueMessage) error {
+	return t.NackAfter(ctx, msg, 0)
+}
+
+// maxVisibilityTimeout is the longest visibility timeout SQS accepts
+const maxVisibilityTimeout = 12 * time.Hour
+
+// NackAfter makes msg visible again once delay (rounded up to whole seconds, at most 12 hours)
+// has passed
+func (t *SQSTransport) NackAfter(ctx context.Context, msg QueueMessage, delay time.Duration) error {
 	queueURL, receiptHandle, err := splitReceiptHandle(msg.ReceiptHandle)
 	if err != nil {
 		return err
 	}
 
+	delay = min(max(delay, 0), maxVisibilityTimeout)
 	_, err = t.client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
 		QueueUrl:          aws.String(queueURL),
 		ReceiptHandle:     aws.String(receiptHandle),
-		VisibilityTimeout: 0,
+		VisibilityTimeout: int32((delay + time.Second - 1) / time.Second),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to nack message: %w", err)
@@ -277,6 +310,52 @@ func (t *SQSTransport) Nack(ctx context.Context, msg QueueMessage) error {
 	return nil
 }
 
+// Heartbeat extends the visibility of msg by the visibility timeout every heartbeat interval,
+// so a message processed longer than the visibility timeout is not redelivered to another consumer
+func (t *SQSTransport) Heartbeat(ctx context.Context, msg QueueMessage) (stop func()) {
+	if t.heartbeatInterval == 0 {
+		return func() {}
+	}
+	queueURL, receiptHandle, err := splitReceiptHandle(msg.ReceiptHandle)
+	if err != nil {
+		slog.Warn("Cannot extend message visibility", "msgID", msg.ID, "error", err)
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(t.heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_, err := t.client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+					QueueUrl:          aws.String(queueURL),
+					ReceiptHandle:     aws.String(receiptHandle),
+					VisibilityTimeout: t.visibilityTimeout,
+				})
+				if err != nil {
+					if ctx.Err() == nil {
+						slog.Warn("Failed to extend message visibility", "msgID", msg.ID, "error", err)
+					}
+					continue
+				}
+				slog.Debug("Extended message visibility", "msgID", msg.ID, "visibilityTimeout", t.visibilityTimeout)
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
 // Close closes the SQS transport (no-op for SQS client)
 func (t *SQSTransport) Close() error {
 	return nil
@@ -2,7 +2,10 @@ package transport
 
 import (
 	"context"
+	"reflect"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
@@ -236,6 +239,9 @@ func TestSQSTransport_Receive(t *testing.T) {
 							MessageId:     aws.String("msg-123"),
 							Body:          aws.String(`{"test":"message"}`),
 							ReceiptHandle: aws.String("receipt-handle-123"),
+							Attributes: map[string]string{
+								string(types.MessageSystemAttributeNameApproximateReceiveCount): "3",
+							},
 							MessageAttributes: map[string]types.MessageAttributeValue{
 								"trace_id": {
 									DataType:    aws.String("String"),
@@ -283,6 +289,9 @@ func TestSQSTransport_Receive(t *testing.T) {
 			if msg.Headers["QueueName"] != queueName {
 				t.Errorf("Headers[QueueName] = %v, want %v", msg.Headers["QueueName"], queueName)
 			}
+			if DeliveryCount(msg) != 3 {
+				t.Errorf("DeliveryCount() = %d, want 3", DeliveryCount(msg))
+			}
 		case err := <-errChan:
 			t.Errorf("Receive() error = %v, want nil", err)
 		case <-ctx.Done():
@@ -393,6 +402,27 @@ func TestSQSTransport_Nack(t *testing.T) {
 		}
 	})
 
+	t.Run("delayed nack rounds up to whole seconds", func(t *testing.T) {
+		var got []int32
+		mockClient := &mockSQSClient{
+			changeMessageVisibilityFunc: func(ctx context.Context, params *sqs.ChangeMessageVisibilityInput, optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
+				got = append(got, params.VisibilityTimeout)
+				return &sqs.ChangeMessageVisibilityOutput{}, nil
+			},
+		}
+		transport := createMockSQSTransport(mockClient)
+		msg := QueueMessage{ReceiptHandle: queueURL + "|" + receiptHandle}
+
+		for _, delay := range []time.Duration{1500 * time.Millisecond, 30 * time.Second, 24 * time.Hour} {
+			if err := transport.NackAfter(ctx, msg, delay); err != nil {
+				t.Errorf("NackAfter(%v) error = %v, want nil", delay, err)
+			}
+		}
+		if want := []int32{2, 30, 43200}; !reflect.DeepEqual(got, want) {
+			t.Errorf("VisibilityTimeout = %v, want %v", got, want)
+		}
+	})
+
 	t.Run("invalid receipt handle", func(t *testing.T) {
 		transport := createMockSQSTransport(nil)
 
@@ -414,3 +444,77 @@ func TestSQSTransport_Close(t *testing.T) {
 		t.Errorf("Close() error = %v, want nil", err)
 	}
 }
+
+func TestHeartbeatInterval(t *testing.T) {
+	tests := []struct {
+		name              string
+		visibilityTimeout int32
+		fraction          float64
+		want              time.Duration
+	}{
+		{name: "disabled", visibilityTimeout: 300, fraction: 0, want: 0},
+		{name: "half the visibility timeout", visibilityTimeout: 300, fraction: 0.5, want: 150 * time.Second},
+		{name: "at least a second", visibilityTimeout: 1, fraction: 0.1, want: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := heartbeatInterval(tt.visibilityTimeout, tt.fraction); got != tt.want {
+				t.Errorf("heartbeatInterval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQSTransport_Heartbeat(t *testing.T) {
+	ctx := context.Background()
+	msg := QueueMessage{ID: "msg-1", ReceiptHandle: testQueueURL + "|receipt-handle-123"}
+
+	t.Run("extends visibility until stopped", func(t *testing.T) {
+		var extensions atomic.Int32
+		mockClient := &mockSQSClient{
+			changeMessageVisibilityFunc: func(ctx context.Context, params *sqs.ChangeMessageVisibilityInput, optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
+				if *params.QueueUrl != testQueueURL || *params.ReceiptHandle != "receipt-handle-123" {
+					t.Errorf("ChangeMessageVisibility for %s|%s, want the received message", *params.QueueUrl, *params.ReceiptHandle)
+				}
+				if params.VisibilityTimeout != 300 {
+					t.Errorf("VisibilityTimeout = %v, want 300", params.VisibilityTimeout)
+				}
+				extensions.Add(1)
+				return &sqs.ChangeMessageVisibilityOutput{}, nil
+			},
+		}
+		transport := createMockSQSTransport(mockClient)
+		transport.heartbeatInterval = 10 * time.Millisecond
+
+		stop := transport.Heartbeat(ctx, msg)
+		deadline := time.Now().Add(5 * time.Second)
+		for extensions.Load() < 2 {
+			if time.Now().After(deadline) {
+				t.Fatalf("got %d visibility extensions, want at least 2", extensions.Load())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		stop()
+
+		stopped := extensions.Load()
+		time.Sleep(50 * time.Millisecond)
+		if got := extensions.Load(); got != stopped {
+			t.Errorf("visibility extended %d times after stop", got-stopped)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		mockClient := &mockSQSClient{
+			changeMessageVisibilityFunc: func(ctx context.Context, params *sqs.ChangeMessageVisibilityInput, optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
+				t.Error("ChangeMessageVisibility called with heartbeat disabled")
+				return &sqs.ChangeMessageVisibilityOutput{}, nil
+			},
+		}
+		transport := createMockSQSTransport(mockClient)
+
+		stop := transport.Heartbeat(ctx, msg)
+		time.Sleep(20 * time.Millisecond)
+		stop()
+	})
+}
@@ -2,6 +2,7 @@ package transport
 
 import (
 	"context"
+	"time"
 )
 
 // QueueMessage represents a message received from a queue
@@ -34,3 +35,26 @@ type Transport interface {
 	// Close closes the transport connection
 	Close() error
 }
+
+// DelayedNacker is implemented by transports that can make a NACKed message available again
+// only after a delay (SQS visibility timeout), without the consumer holding on to it meanwhile
+type DelayedNacker interface {
+	// NackAfter negatively acknowledges msg so it is redelivered once delay has passed
+	NackAfter(ctx context.Context, msg QueueMessage, delay time.Duration) error
+}
+
+// Heartbeater is implemented by transports that redeliver a received message once its lease
+// expires (SQS visibility timeout), even while it is still being processed
+type Heartbeater interface {
+	// Heartbeat keeps extending the lease of msg until the returned stop function is called.
+	// Stop waits for an extension in progress, so msg can be acknowledged right after.
+	Heartbeat(ctx context.Context, msg QueueMessage) (stop func())
+}
+
+// ConsumerCanceller is implemented by transports with long-lived broker consumers (RabbitMQ),
+// which keep prefetching messages to the sidecar until they are cancelled
+type ConsumerCanceller interface {
+	// CancelConsumers cancels all consumers and requeues their prefetched messages.
+	// The next Receive starts a new consumer.
+	CancelConsumers(ctx context.Context) error
+}
@@ -17,17 +17,35 @@ type Route struct {
 // Subsequent fanout envelopes receive suffixed IDs following the pattern: {original_id}-{index}
 //
 // Example fanout from envelope "abc-123" returning 3 items:
-//   - Index 0: ID = "abc-123"      ParentID = nil     (original ID, SSE clients can track this)
-//   - Index 1: ID = "abc-123-1"    ParentID = "abc-123" (fanout child)
-//   - Index 2: ID = "abc-123-2"    ParentID = "abc-123" (fanout child)
+//   - Index 0: ID = "abc-123"      ParentID = nil       BranchIndex = 0 (original ID, SSE clients can track this)
+//   - Index 1: ID = "abc-123-1"    ParentID = "abc-123" BranchIndex = 1 (fanout child)
+//   - Index 2: ID = "abc-123-2"    ParentID = "abc-123" BranchIndex = 2 (fanout child)
 //
-// All fanout children have ParentID set to the original envelope ID for traceability.
+// All fanout children have ParentID set to the original envelope ID and BranchIndex set to
+// their fanout index, so the gateway can reassemble branch results in order. Both fields are
+// carried along unchanged as the child moves through the rest of its route.
+//
+// Tool names the gateway tool that created the envelope. Sidecars copy it to every envelope
+// they route, including fanout children and happy-end/error-end messages.
+//
+// Warnings accumulates the warnings of every actor that succeeded with warnings so far.
+//
+// ReplyTo names a queue waiting for the envelope's outcome (request-reply). It is carried like
+// Tool, and the sidecar that sends the happy-end/error-end message also publishes it there.
 type Envelope struct {
-	ID       string                 `json:"id"`
-	ParentID *string                `json:"parent_id,omitempty"` // Set for fanout children (index > 0)
-	Route    Route                  `json:"route"`
-	Headers  map[string]interface{} `json:"headers,omitempty"`
-	Payload  json.RawMessage        `json:"payload"`
+	ID          string                 `json:"id"`
+	ParentID    *string                `json:"parent_id,omitempty"`    // Set for fanout children (index > 0)
+	BranchIndex int                    `json:"branch_index,omitempty"` // Fanout index for fanout children (index > 0)
+	Tool        string                 `json:"tool,omitempty"`         // Originating gateway tool, empty for envelopes not created by the gateway
+	ReplyTo     string                 `json:"reply_to,omitempty"`     // Queue receiving the terminal message directly (request-reply)
+	Route       Route                  `json:"route"`
+	Headers     map[string]interface{} `json:"headers,omitempty"`
+	Payload     json.RawMessage        `json:"payload"`
+	Warnings    []string               `json:"warnings,omitempty"` // Warnings of previous actors, in route order
+
+	// TimeoutOverrideSeconds overrides the runtime timeout for this message only,
+	// capped by the actor's ASYA_MAX_PROCESSING_TIMEOUT
+	TimeoutOverrideSeconds int `json:"timeout_override_seconds,omitempty"`
 }
 
 // GetCurrentActor returns the current actor name from the route
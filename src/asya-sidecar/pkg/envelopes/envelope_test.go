@@ -2,6 +2,7 @@ package envelopes
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 )
 
@@ -252,3 +253,28 @@ func TestEnvelope_ParentID_Serialization(t *testing.T) {
 func stringPtr(s string) *string {
 	return &s
 }
+
+func TestValidateID(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		wantErr bool
+	}{
+		{name: "uuid", id: "5e6fdb2d-1d6b-4e91-baef-73e825434e7b"},
+		{name: "fanout child", id: "5e6fdb2d-1d6b-4e91-baef-73e825434e7b-3"},
+		{name: "foreign id", id: "Order_42.v1"},
+		{name: "empty", id: "", wantErr: true},
+		{name: "slash", id: "a/b", wantErr: true},
+		{name: "whitespace", id: "a b", wantErr: true},
+		{name: "leading dot", id: ".hidden", wantErr: true},
+		{name: "too long", id: strings.Repeat("a", MaxIDLength+1), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateID(tt.id); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateID(%q) error = %v, wantErr %v", tt.id, err, tt.wantErr)
+			}
+		})
+	}
+}
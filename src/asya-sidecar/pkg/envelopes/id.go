@@ -0,0 +1,28 @@
+package envelopes
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// MaxIDLength is the maximum accepted length of an envelope ID
+const MaxIDLength = 128
+
+// Letters, digits, '.', '_' and '-', starting with a letter or digit
+var idRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ValidateID checks that id is a well-formed envelope ID, using the same rules as the gateway.
+// Gateway-created IDs are UUIDs (fanout children append "-{index}"); IDs from other
+// producers may use up to MaxIDLength letters, digits, '.', '_' and '-'.
+func ValidateID(id string) error {
+	if id == "" {
+		return fmt.Errorf("envelope ID is empty")
+	}
+	if len(id) > MaxIDLength {
+		return fmt.Errorf("envelope ID exceeds %d characters", MaxIDLength)
+	}
+	if !idRegex.MatchString(id) {
+		return fmt.Errorf("envelope ID %q contains invalid characters", id)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package envelopes
+
+import "time"
+
+// TerminalMessageVersion is the current version of the terminal message format.
+// Terminal messages without a version were produced by older sidecars and carry
+// only the plain envelope fields.
+const TerminalMessageVersion = 1
+
+// Terminal message statuses
+const (
+	TerminalStatusSucceeded = "succeeded"
+	TerminalStatusFailed    = "failed"
+)
+
+// TerminalMessage is the message sent to the happy-end and error-end queues.
+//
+// It is a regular Envelope, so end actors process it like any other message, extended
+// with the outcome so result consumers can read it without unwrapping the payload:
+//   - succeeded: Payload is the final result
+//   - failed: Error and Details describe the failure; Payload keeps the legacy
+//     {"error", "details", "original_payload"} structure for end actors
+type TerminalMessage struct {
+	Envelope
+	Version     int       `json:"version"`
+	Status      string    `json:"status"`
+	CompletedAt time.Time `json:"completed_at"`
+	Error       string    `json:"error,omitempty"`
+	Details     any       `json:"details,omitempty"`
+}
+
+// NewSucceededMessage wraps an envelope whose payload is the final result
+func NewSucceededMessage(envelope Envelope) TerminalMessage {
+	return TerminalMessage{
+		Envelope:    envelope,
+		Version:     TerminalMessageVersion,
+		Status:      TerminalStatusSucceeded,
+		CompletedAt: time.Now().UTC(),
+	}
+}
+
+// NewFailedMessage wraps an envelope that ended with an error
+func NewFailedMessage(envelope Envelope, errorMsg string, details any) TerminalMessage {
+	return TerminalMessage{
+		Envelope:    envelope,
+		Version:     TerminalMessageVersion,
+		Status:      TerminalStatusFailed,
+		CompletedAt: time.Now().UTC(),
+		Error:       errorMsg,
+		Details:     details,
+	}
+}
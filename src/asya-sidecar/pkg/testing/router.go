@@ -2,6 +2,7 @@ package testing
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/deliveryhero/asya/asya-sidecar/internal/config"
@@ -11,9 +12,37 @@ import (
 	"github.com/deliveryhero/asya/asya-sidecar/pkg/transport"
 )
 
-// EnvelopeProcessor is the interface for processing envelopes
+// ProcessResult tells what the consumer would do with a message once ProcessEnvelope returns
+type ProcessResult int
+
+const (
+	// ProcessAcked: the message was handled (routed onward or to an end queue) and is acknowledged
+	ProcessAcked ProcessResult = iota
+	// ProcessRequeue: processing failed and may succeed later, the message is retried
+	ProcessRequeue
+	// ProcessDeadLetter: the message can never be processed and could not be sent to the error queue,
+	// it is rejected without requeueing
+	ProcessDeadLetter
+)
+
+func (p ProcessResult) String() string {
+	switch p {
+	case ProcessAcked:
+		return "acked"
+	case ProcessRequeue:
+		return "requeue"
+	case ProcessDeadLetter:
+		return "dead-letter"
+	default:
+		return fmt.Sprintf("ProcessResult(%d)", int(p))
+	}
+}
+
+// EnvelopeProcessor is the interface for processing envelopes.
+// ProcessEnvelope returns what would happen to the message (ack, requeue or dead-letter)
+// and the error that caused a requeue or dead-letter.
 type EnvelopeProcessor interface {
-	ProcessEnvelope(ctx context.Context, msg transport.QueueMessage) error
+	ProcessEnvelope(ctx context.Context, msg transport.QueueMessage) (ProcessResult, error)
 }
 
 // NewTestRouter creates a router for testing with the given configuration
@@ -28,33 +57,76 @@ func NewTestRouter(socketPath string, timeout time.Duration, mockTransport *Mock
 		Timeout:       timeout,
 	}
 
-	adapter := &mockTransportAdapter{mock: mockTransport}
+	adapter := &transportAdapter{tp: mockTransport}
 	r := router.NewRouter(cfg, adapter, runtimeClient, nil)
 	return &envelopeProcessor{router: r}
 }
 
+// TestRouter is a router wired to a public transport, e.g. transport.MemoryTransport
+type TestRouter interface {
+	EnvelopeProcessor
+
+	// Run consumes from the queue named after the actor until the context is cancelled
+	Run(ctx context.Context) error
+}
+
+// NewTestRouterWithTransport creates a router for actorName that receives from and sends to tp,
+// so handlers can be tested end to end without a broker
+func NewTestRouterWithTransport(actorName, socketPath string, timeout time.Duration, tp transport.Transport) TestRouter {
+	runtimeClient := runtime.NewClient(socketPath, timeout)
+
+	cfg := &config.Config{
+		ActorName:     actorName,
+		HappyEndQueue: "happy-end",
+		ErrorEndQueue: "error-end",
+		SocketPath:    socketPath,
+		Timeout:       timeout,
+	}
+
+	r := router.NewRouter(cfg, &transportAdapter{tp: tp}, runtimeClient, nil)
+	return &envelopeProcessor{router: r}
+}
+
 // envelopeProcessor adapts the internal router to the public EnvelopeProcessor interface
 type envelopeProcessor struct {
 	router *router.Router
 }
 
-func (ep *envelopeProcessor) ProcessEnvelope(ctx context.Context, msg transport.QueueMessage) error {
+func (ep *envelopeProcessor) ProcessEnvelope(ctx context.Context, msg transport.QueueMessage) (ProcessResult, error) {
 	internalMsg := internaltransport.QueueMessage{
 		ID:            msg.ID,
 		Body:          msg.Body,
 		ReceiptHandle: msg.ReceiptHandle,
 		Headers:       msg.Headers,
 	}
-	return ep.router.ProcessEnvelope(ctx, internalMsg)
+	result, err := ep.router.ProcessEnvelope(ctx, internalMsg)
+	return toProcessResult(result), err
+}
+
+func toProcessResult(result router.ProcessResult) ProcessResult {
+	switch result {
+	case router.ProcessAcked:
+		return ProcessAcked
+	case router.ProcessRequeue:
+		return ProcessRequeue
+	case router.ProcessDeadLetter:
+		return ProcessDeadLetter
+	default:
+		return ProcessResult(result)
+	}
+}
+
+func (ep *envelopeProcessor) Run(ctx context.Context) error {
+	return ep.router.Run(ctx)
 }
 
-// mockTransportAdapter adapts the public MockTransport to internal transport.Transport
-type mockTransportAdapter struct {
-	mock *MockTransport
+// transportAdapter adapts a public transport.Transport to internal transport.Transport
+type transportAdapter struct {
+	tp transport.Transport
 }
 
-func (mta *mockTransportAdapter) Receive(ctx context.Context, queueName string) (internaltransport.QueueMessage, error) {
-	msg, err := mta.mock.Receive(ctx, queueName)
+func (ta *transportAdapter) Receive(ctx context.Context, queueName string) (internaltransport.QueueMessage, error) {
+	msg, err := ta.tp.Receive(ctx, queueName)
 	if err != nil {
 		return internaltransport.QueueMessage{}, err
 	}
@@ -66,30 +138,30 @@ func (mta *mockTransportAdapter) Receive(ctx context.Context, queueName string)
 	}, nil
 }
 
-func (mta *mockTransportAdapter) Send(ctx context.Context, queueName string, body []byte) error {
-	return mta.mock.Send(ctx, queueName, body)
+func (ta *transportAdapter) Send(ctx context.Context, queueName string, body []byte) error {
+	return ta.tp.Send(ctx, queueName, body)
 }
 
-func (mta *mockTransportAdapter) Ack(ctx context.Context, msg internaltransport.QueueMessage) error {
+func (ta *transportAdapter) Ack(ctx context.Context, msg internaltransport.QueueMessage) error {
 	publicMsg := transport.QueueMessage{
 		ID:            msg.ID,
 		Body:          msg.Body,
 		ReceiptHandle: msg.ReceiptHandle,
 		Headers:       msg.Headers,
 	}
-	return mta.mock.Ack(ctx, publicMsg)
+	return ta.tp.Ack(ctx, publicMsg)
 }
 
-func (mta *mockTransportAdapter) Nack(ctx context.Context, msg internaltransport.QueueMessage) error {
+func (ta *transportAdapter) Nack(ctx context.Context, msg internaltransport.QueueMessage) error {
 	publicMsg := transport.QueueMessage{
 		ID:            msg.ID,
 		Body:          msg.Body,
 		ReceiptHandle: msg.ReceiptHandle,
 		Headers:       msg.Headers,
 	}
-	return mta.mock.Nack(ctx, publicMsg)
+	return ta.tp.Nack(ctx, publicMsg)
 }
 
-func (mta *mockTransportAdapter) Close() error {
-	return mta.mock.Close()
+func (ta *transportAdapter) Close() error {
+	return ta.tp.Close()
 }
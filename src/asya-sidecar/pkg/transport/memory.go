@@ -0,0 +1,198 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrClosed is returned by MemoryTransport operations after Close
+var ErrClosed = errors.New("transport closed")
+
+// MemoryTransport is an in-process Transport for tests. Queues are created on first use;
+// Receive blocks until a message is available, Ack removes a received message and Nack
+// puts it back at the end of its queue.
+type MemoryTransport struct {
+	mu       sync.Mutex
+	queues   map[string][]QueueMessage
+	inFlight map[string]QueueMessage // Received, not yet acked or nacked, by message ID
+	acked    map[string][]QueueMessage
+	notify   chan struct{} // Closed and replaced whenever a message is enqueued
+	nextID   int
+	closed   bool
+}
+
+// NewMemoryTransport creates an empty in-memory transport
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{
+		queues:   make(map[string][]QueueMessage),
+		inFlight: make(map[string]QueueMessage),
+		acked:    make(map[string][]QueueMessage),
+		notify:   make(chan struct{}),
+		nextID:   1,
+	}
+}
+
+// Receive takes the next message from the queue, blocking until one arrives or ctx is done
+func (m *MemoryTransport) Receive(ctx context.Context, queueName string) (QueueMessage, error) {
+	for {
+		m.mu.Lock()
+		if m.closed {
+			m.mu.Unlock()
+			return QueueMessage{}, ErrClosed
+		}
+		if queue := m.queues[queueName]; len(queue) > 0 {
+			msg := queue[0]
+			m.queues[queueName] = queue[1:]
+			m.inFlight[msg.ID] = msg
+			m.mu.Unlock()
+			return msg, nil
+		}
+		notify := m.notify
+		m.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			return QueueMessage{}, ctx.Err()
+		}
+	}
+}
+
+// Send enqueues a message
+func (m *MemoryTransport) Send(ctx context.Context, queueName string, body []byte) error {
+	_, err := m.Inject(queueName, body)
+	return err
+}
+
+// Ack removes a received message
+func (m *MemoryTransport) Ack(ctx context.Context, msg QueueMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	inFlight, ok := m.inFlight[msg.ID]
+	if !ok {
+		return fmt.Errorf("message %s is not in flight", msg.ID)
+	}
+	delete(m.inFlight, msg.ID)
+	queueName := inFlight.Headers["QueueName"]
+	m.acked[queueName] = append(m.acked[queueName], inFlight)
+	return nil
+}
+
+// Nack returns a received message to the end of its queue for redelivery
+func (m *MemoryTransport) Nack(ctx context.Context, msg QueueMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+
+	inFlight, ok := m.inFlight[msg.ID]
+	if !ok {
+		return fmt.Errorf("message %s is not in flight", msg.ID)
+	}
+	delete(m.inFlight, msg.ID)
+	m.enqueueLocked(inFlight.Headers["QueueName"], inFlight)
+	return nil
+}
+
+// Close unblocks pending Receive calls; later operations fail with ErrClosed
+func (m *MemoryTransport) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.closed {
+		m.closed = true
+		close(m.notify)
+	}
+	return nil
+}
+
+// Inject enqueues a message and returns it with its assigned ID
+func (m *MemoryTransport) Inject(queueName string, body []byte) (QueueMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return QueueMessage{}, ErrClosed
+	}
+
+	msg := QueueMessage{
+		ID:            fmt.Sprintf("msg-%d", m.nextID),
+		Body:          append([]byte(nil), body...),
+		ReceiptHandle: m.nextID,
+		Headers:       map[string]string{"QueueName": queueName},
+	}
+	m.nextID++
+	m.enqueueLocked(queueName, msg)
+	return msg, nil
+}
+
+// Drain removes and returns all messages waiting in the queue
+func (m *MemoryTransport) Drain(queueName string) []QueueMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	messages := m.queues[queueName]
+	delete(m.queues, queueName)
+	return messages
+}
+
+// WaitFor blocks until the queue holds at least n messages or ctx is done, then drains it
+func (m *MemoryTransport) WaitFor(ctx context.Context, queueName string, n int) ([]QueueMessage, error) {
+	for {
+		m.mu.Lock()
+		if len(m.queues[queueName]) >= n {
+			messages := m.queues[queueName]
+			delete(m.queues, queueName)
+			m.mu.Unlock()
+			return messages, nil
+		}
+		if m.closed {
+			m.mu.Unlock()
+			return nil, ErrClosed
+		}
+		notify := m.notify
+		m.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Len returns the number of messages waiting in the queue
+func (m *MemoryTransport) Len(queueName string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.queues[queueName])
+}
+
+// InFlight returns the number of received messages not yet acked or nacked
+func (m *MemoryTransport) InFlight() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.inFlight)
+}
+
+// Acked returns the messages received from the queue and acked, in ack order
+func (m *MemoryTransport) Acked(queueName string) []QueueMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]QueueMessage(nil), m.acked[queueName]...)
+}
+
+// enqueueLocked appends msg to the queue and wakes up waiting receivers; m.mu must be held
+func (m *MemoryTransport) enqueueLocked(queueName string, msg QueueMessage) {
+	m.queues[queueName] = append(m.queues[queueName], msg)
+	close(m.notify)
+	m.notify = make(chan struct{})
+}
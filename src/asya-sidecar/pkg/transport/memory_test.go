@@ -0,0 +1,143 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryTransport_SendReceiveAck(t *testing.T) {
+	ctx := context.Background()
+	tp := NewMemoryTransport()
+
+	if err := tp.Send(ctx, "asya-test", []byte(`{"n":1}`)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	msg, err := tp.Receive(ctx, "asya-test")
+	if err != nil {
+		t.Fatalf("Receive() error = %v", err)
+	}
+	if string(msg.Body) != `{"n":1}` {
+		t.Errorf("Body = %s, want {\"n\":1}", msg.Body)
+	}
+	if msg.Headers["QueueName"] != "asya-test" {
+		t.Errorf("Headers[QueueName] = %q, want asya-test", msg.Headers["QueueName"])
+	}
+	if tp.InFlight() != 1 {
+		t.Errorf("InFlight() = %d, want 1", tp.InFlight())
+	}
+
+	if err := tp.Ack(ctx, msg); err != nil {
+		t.Fatalf("Ack() error = %v", err)
+	}
+	if tp.InFlight() != 0 || tp.Len("asya-test") != 0 {
+		t.Errorf("InFlight() = %d, Len() = %d, want 0, 0", tp.InFlight(), tp.Len("asya-test"))
+	}
+	if acked := tp.Acked("asya-test"); len(acked) != 1 || acked[0].ID != msg.ID {
+		t.Errorf("Acked() = %v, want [%s]", acked, msg.ID)
+	}
+	if err := tp.Ack(ctx, msg); err == nil {
+		t.Error("second Ack() error = nil, want error")
+	}
+}
+
+func TestMemoryTransport_NackRequeues(t *testing.T) {
+	ctx := context.Background()
+	tp := NewMemoryTransport()
+
+	first, _ := tp.Inject("q", []byte("1"))
+	_, _ = tp.Inject("q", []byte("2"))
+
+	msg, _ := tp.Receive(ctx, "q")
+	if msg.ID != first.ID {
+		t.Fatalf("Receive() = %s, want %s", msg.ID, first.ID)
+	}
+	if err := tp.Nack(ctx, msg); err != nil {
+		t.Fatalf("Nack() error = %v", err)
+	}
+
+	drained := tp.Drain("q")
+	if len(drained) != 2 || string(drained[0].Body) != "2" || string(drained[1].Body) != "1" {
+		t.Errorf("Drain() = %v, want messages 2 then 1", drained)
+	}
+	if tp.Len("q") != 0 {
+		t.Errorf("Len() after Drain = %d, want 0", tp.Len("q"))
+	}
+}
+
+func TestMemoryTransport_ReceiveBlocks(t *testing.T) {
+	tp := NewMemoryTransport()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_, _ = tp.Inject("q", []byte("late"))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	msg, err := tp.Receive(ctx, "q")
+	if err != nil {
+		t.Fatalf("Receive() error = %v", err)
+	}
+	if string(msg.Body) != "late" {
+		t.Errorf("Body = %s, want late", msg.Body)
+	}
+
+	shortCtx, shortCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer shortCancel()
+	if _, err := tp.Receive(shortCtx, "q"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Receive() on empty queue error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestMemoryTransport_WaitFor(t *testing.T) {
+	tp := NewMemoryTransport()
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			_ = tp.Send(context.Background(), "out", []byte("x"))
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	messages, err := tp.WaitFor(ctx, "out", 3)
+	if err != nil {
+		t.Fatalf("WaitFor() error = %v", err)
+	}
+	if len(messages) != 3 {
+		t.Errorf("WaitFor() returned %d messages, want 3", len(messages))
+	}
+}
+
+func TestMemoryTransport_Close(t *testing.T) {
+	tp := NewMemoryTransport()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := tp.Receive(context.Background(), "q")
+		done <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	if err := tp.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrClosed) {
+			t.Errorf("Receive() error = %v, want ErrClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Receive() not unblocked by Close")
+	}
+
+	if err := tp.Send(context.Background(), "q", nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("Send() after Close error = %v, want ErrClosed", err)
+	}
+}
@@ -14,7 +14,7 @@ import (
 
 func TestChannelPool_Creation(t *testing.T) {
 	url := getRabbitMQURL()
-	pool, err := queue.NewChannelPool(url, "test-exchange", 5)
+	pool, err := queue.NewChannelPool(context.Background(), url, "test-exchange", 5)
 	if err != nil {
 		t.Fatalf("Failed to connect to RabbitMQ: %v", err)
 	}
@@ -31,7 +31,7 @@ func TestChannelPool_Creation(t *testing.T) {
 
 func TestChannelPool_GetReturn(t *testing.T) {
 	url := getRabbitMQURL()
-	pool, err := queue.NewChannelPool(url, "test-exchange", 3)
+	pool, err := queue.NewChannelPool(context.Background(), url, "test-exchange", 3)
 	if err != nil {
 		t.Fatalf("Failed to connect to RabbitMQ: %v", err)
 	}
@@ -66,7 +66,7 @@ func TestChannelPool_GetReturn(t *testing.T) {
 func TestChannelPool_ConcurrentAccess(t *testing.T) {
 	url := getRabbitMQURL()
 	poolSize := 10
-	pool, err := queue.NewChannelPool(url, "test-exchange", poolSize)
+	pool, err := queue.NewChannelPool(context.Background(), url, "test-exchange", poolSize)
 	if err != nil {
 		t.Fatalf("Failed to connect to RabbitMQ: %v", err)
 	}
@@ -116,7 +116,7 @@ func TestChannelPool_ConcurrentAccess(t *testing.T) {
 
 func TestChannelPool_ContextCancellation(t *testing.T) {
 	url := getRabbitMQURL()
-	pool, err := queue.NewChannelPool(url, "test-exchange", 1)
+	pool, err := queue.NewChannelPool(context.Background(), url, "test-exchange", 1)
 	if err != nil {
 		t.Fatalf("Failed to connect to RabbitMQ: %v", err)
 	}
@@ -148,7 +148,7 @@ func TestChannelPool_ContextCancellation(t *testing.T) {
 
 func TestChannelPool_CloseWhileInUse(t *testing.T) {
 	url := getRabbitMQURL()
-	pool, err := queue.NewChannelPool(url, "test-exchange", 2)
+	pool, err := queue.NewChannelPool(context.Background(), url, "test-exchange", 2)
 	if err != nil {
 		t.Fatalf("Failed to connect to RabbitMQ: %v", err)
 	}
@@ -177,7 +177,7 @@ func TestChannelPool_CloseWhileInUse(t *testing.T) {
 func TestChannelPool_BoundedConcurrency(t *testing.T) {
 	url := getRabbitMQURL()
 	poolSize := 5
-	pool, err := queue.NewChannelPool(url, "test-exchange", poolSize)
+	pool, err := queue.NewChannelPool(context.Background(), url, "test-exchange", poolSize)
 	if err != nil {
 		t.Fatalf("Failed to connect to RabbitMQ: %v", err)
 	}
@@ -222,7 +222,7 @@ func TestChannelPool_BoundedConcurrency(t *testing.T) {
 
 func TestChannelPool_AutoRecovery(t *testing.T) {
 	url := getRabbitMQURL()
-	pool, err := queue.NewChannelPool(url, "test-exchange", 2)
+	pool, err := queue.NewChannelPool(context.Background(), url, "test-exchange", 2)
 	if err != nil {
 		t.Fatalf("Failed to connect to RabbitMQ: %v", err)
 	}
@@ -258,7 +258,7 @@ func TestChannelPool_AutoRecovery(t *testing.T) {
 // Benchmark tests
 func BenchmarkChannelPool_GetReturn(b *testing.B) {
 	url := getRabbitMQURL()
-	pool, err := queue.NewChannelPool(url, "bench-exchange", 10)
+	pool, err := queue.NewChannelPool(context.Background(), url, "bench-exchange", 10)
 	if err != nil {
 		b.Fatalf("Failed to connect to RabbitMQ: %v", err)
 	}
@@ -280,7 +280,7 @@ func BenchmarkChannelPool_GetReturn(b *testing.B) {
 
 func BenchmarkChannelPool_PublishWithPool(b *testing.B) {
 	url := getRabbitMQURL()
-	pool, err := queue.NewChannelPool(url, "bench-exchange", 10)
+	pool, err := queue.NewChannelPool(context.Background(), url, "bench-exchange", 10)
 	if err != nil {
 		b.Fatalf("Failed to connect to RabbitMQ: %v", err)
 	}
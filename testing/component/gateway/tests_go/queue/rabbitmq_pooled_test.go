@@ -15,7 +15,7 @@ import (
 
 func TestRabbitMQClientPooled_SendMessage(t *testing.T) {
 	url := getRabbitMQURL()
-	client, err := queue.NewRabbitMQClientPooled(url, "test-exchange", 5)
+	client, err := queue.NewRabbitMQClientPooled(context.Background(), url, "test-exchange", 5)
 	if err != nil {
 		t.Fatalf("Failed to connect to RabbitMQ: %v", err)
 	}
@@ -43,7 +43,7 @@ func TestRabbitMQClientPooled_SendMessage(t *testing.T) {
 func TestRabbitMQClientPooled_ConcurrentSend(t *testing.T) {
 	url := getRabbitMQURL()
 	poolSize := 10
-	client, err := queue.NewRabbitMQClientPooled(url, "test-exchange", poolSize)
+	client, err := queue.NewRabbitMQClientPooled(context.Background(), url, "test-exchange", poolSize)
 	if err != nil {
 		t.Fatalf("Failed to connect to RabbitMQ: %v", err)
 	}
@@ -100,7 +100,7 @@ func TestRabbitMQClientPooled_ConcurrentSend(t *testing.T) {
 
 func TestRabbitMQClientPooled_SendWithDeadline(t *testing.T) {
 	url := getRabbitMQURL()
-	client, err := queue.NewRabbitMQClientPooled(url, "test-exchange", 5)
+	client, err := queue.NewRabbitMQClientPooled(context.Background(), url, "test-exchange", 5)
 	if err != nil {
 		t.Fatalf("Failed to connect to RabbitMQ: %v", err)
 	}
@@ -129,7 +129,7 @@ func TestRabbitMQClientPooled_SendWithDeadline(t *testing.T) {
 
 func TestRabbitMQClientPooled_SendEmptyRoute(t *testing.T) {
 	url := getRabbitMQURL()
-	client, err := queue.NewRabbitMQClientPooled(url, "test-exchange", 5)
+	client, err := queue.NewRabbitMQClientPooled(context.Background(), url, "test-exchange", 5)
 	if err != nil {
 		t.Fatalf("Failed to connect to RabbitMQ: %v", err)
 	}
@@ -154,7 +154,7 @@ func TestRabbitMQClientPooled_SendEmptyRoute(t *testing.T) {
 
 func TestRabbitMQClientPooled_ContextCancellation(t *testing.T) {
 	url := getRabbitMQURL()
-	client, err := queue.NewRabbitMQClientPooled(url, "test-exchange", 1)
+	client, err := queue.NewRabbitMQClientPooled(context.Background(), url, "test-exchange", 1)
 	if err != nil {
 		t.Fatalf("Failed to connect to RabbitMQ: %v", err)
 	}
@@ -182,7 +182,7 @@ func TestRabbitMQClientPooled_ContextCancellation(t *testing.T) {
 // Benchmark: Compare pooled vs mutex-based performance
 func BenchmarkRabbitMQClientPooled_Send(b *testing.B) {
 	url := getRabbitMQURL()
-	client, err := queue.NewRabbitMQClientPooled(url, "bench-exchange", 10)
+	client, err := queue.NewRabbitMQClientPooled(context.Background(), url, "bench-exchange", 10)
 	if err != nil {
 		b.Fatalf("Failed to connect to RabbitMQ: %v", err)
 	}
@@ -183,10 +183,13 @@ func TestSocketIntegration_HappyPath(t *testing.T) {
 	})
 
 	ctx := context.Background()
-	err = r.ProcessEnvelope(ctx, testMsg)
+	result, err := r.ProcessEnvelope(ctx, testMsg)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
+	if result != sidecartesting.ProcessAcked {
+		t.Errorf("Expected message to be %v, got %v", sidecartesting.ProcessAcked, result)
+	}
 
 	// Verify message was sent to happy-end queue
 	sentMessages := mockTransport.GetMessages("happy-end")
@@ -232,10 +235,13 @@ func TestSocketIntegration_Error(t *testing.T) {
 	})
 
 	ctx := context.Background()
-	err = r.ProcessEnvelope(ctx, testMsg)
+	result, err := r.ProcessEnvelope(ctx, testMsg)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
+	if result != sidecartesting.ProcessAcked {
+		t.Errorf("Expected message to be %v, got %v", sidecartesting.ProcessAcked, result)
+	}
 
 	// Verify message was sent to error-end queue
 	sentMessages := mockTransport.GetMessages("error-end")
@@ -284,10 +290,13 @@ func TestSocketIntegration_Timeout(t *testing.T) {
 	})
 
 	ctx := context.Background()
-	err = r.ProcessEnvelope(ctx, testMsg)
+	result, err := r.ProcessEnvelope(ctx, testMsg)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
+	if result != sidecartesting.ProcessAcked {
+		t.Errorf("Expected message to be %v, got %v", sidecartesting.ProcessAcked, result)
+	}
 
 	// Verify message was sent to error-end queue due to timeout
 	sentMessages := mockTransport.GetMessages("error-end")
@@ -336,10 +345,13 @@ func TestSocketIntegration_Fanout(t *testing.T) {
 	})
 
 	ctx := context.Background()
-	err = r.ProcessEnvelope(ctx, testMsg)
+	result, err := r.ProcessEnvelope(ctx, testMsg)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
+	if result != sidecartesting.ProcessAcked {
+		t.Errorf("Expected message to be %v, got %v", sidecartesting.ProcessAcked, result)
+	}
 
 	// Verify 3 messages were sent to happy-end queue
 	sentMessages := mockTransport.GetMessages("happy-end")
@@ -387,10 +399,13 @@ func TestSocketIntegration_EmptyResponse(t *testing.T) {
 	})
 
 	ctx := context.Background()
-	err = r.ProcessEnvelope(ctx, testMsg)
+	result, err := r.ProcessEnvelope(ctx, testMsg)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
+	if result != sidecartesting.ProcessAcked {
+		t.Errorf("Expected message to be %v, got %v", sidecartesting.ProcessAcked, result)
+	}
 
 	// Verify message was sent to happy-end queue (empty response aborts pipeline)
 	sentMessages := mockTransport.GetMessages("happy-end")
@@ -422,10 +437,13 @@ func TestSocketIntegration_LargePayload(t *testing.T) {
 	})
 
 	ctx := context.Background()
-	err = r.ProcessEnvelope(ctx, testMsg)
+	result, err := r.ProcessEnvelope(ctx, testMsg)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
+	if result != sidecartesting.ProcessAcked {
+		t.Errorf("Expected message to be %v, got %v", sidecartesting.ProcessAcked, result)
+	}
 
 	// Verify message was sent to happy-end queue
 	sentMessages := mockTransport.GetMessages("happy-end")
@@ -472,10 +490,13 @@ func TestSocketIntegration_Unicode(t *testing.T) {
 	})
 
 	ctx := context.Background()
-	err = r.ProcessEnvelope(ctx, testMsg)
+	result, err := r.ProcessEnvelope(ctx, testMsg)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
+	if result != sidecartesting.ProcessAcked {
+		t.Errorf("Expected message to be %v, got %v", sidecartesting.ProcessAcked, result)
+	}
 
 	// Verify message was sent to happy-end queue
 	sentMessages := mockTransport.GetMessages("happy-end")